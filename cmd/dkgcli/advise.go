@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"text/tabwriter"
+	"time"
+
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// advise recommends timing settings from the latency observed in a node's last
+// rounds, fetched from its advice handler, or in exported round snapshots.
+func advise(args []string) error {
+	var (
+		flags     = flag.NewFlagSet("advise", flag.ExitOnError)
+		adviceURL = flags.String("url", "", "URL of the node's advice handler, e.g. http://127.0.0.1:26670/dkg/advice")
+		blockTime = flags.Duration("block-time", 0, "block time of the chain; block counts are not advised if zero")
+		output    = outputFlag(flags)
+	)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: dkgcli advise [flags] [file.json...]")
+		fmt.Fprintln(flags.Output(), "The files hold round snapshots, node statuses or forensic bundles.")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if (*adviceURL == "") == (flags.NArg() == 0) {
+		flags.Usage()
+		return fmt.Errorf("either -url or snapshot files are required")
+	}
+
+	var (
+		advice *types.TimingAdvice
+		err    error
+	)
+	if *adviceURL != "" {
+		advice, err = fetchAdvice(*adviceURL, *blockTime)
+	} else {
+		var rounds []*types.RoundInfo
+		for _, path := range flags.Args() {
+			found, err := loadRounds(path)
+			if err != nil {
+				return err
+			}
+			rounds = append(rounds, found...)
+		}
+		advice, err = types.AdviseTimings(rounds, *blockTime)
+	}
+	if err != nil {
+		return err
+	}
+
+	return printOutput(*output, advice, func(w io.Writer) {
+		printAdvice(w, advice)
+	})
+}
+
+func fetchAdvice(rawURL string, blockTime time.Duration) (*types.TimingAdvice, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -url: %v", err)
+	}
+	if blockTime > 0 {
+		query := u.Query()
+		query.Set("block_time", blockTime.String())
+		u.RawQuery = query.Encode()
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch advice: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to fetch advice: %s: %s", resp.Status, body)
+	}
+	var advice types.TimingAdvice
+	if err := json.NewDecoder(resp.Body).Decode(&advice); err != nil {
+		return nil, fmt.Errorf("failed to decode advice: %v", err)
+	}
+	return &advice, nil
+}
+
+// loadRounds returns the round snapshots stored in the JSON file: a round
+// snapshot, a list of them, a node status or a forensic bundle.
+func loadRounds(path string) ([]*types.RoundInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var list []*types.RoundInfo
+	if err := json.Unmarshal(data, &list); err == nil {
+		return list, nil
+	}
+	var document struct {
+		types.RoundInfo
+		Rounds []*types.RoundInfo `json:"rounds"` // types.Status.
+		Round  *types.RoundInfo   `json:"round"`  // types.ForensicBundle.
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %v", path, err)
+	}
+	switch {
+	case document.Rounds != nil:
+		return document.Rounds, nil
+	case document.Round != nil:
+		return []*types.RoundInfo{document.Round}, nil
+	case document.Peers != nil:
+		return []*types.RoundInfo{&document.RoundInfo}, nil
+	}
+	return nil, fmt.Errorf("no round snapshot found in %s", path)
+}
+
+func printAdvice(w io.Writer, advice *types.TimingAdvice) {
+	fmt.Fprintf(w, "sampled rounds: %d\n", advice.Rounds)
+	fmt.Fprintf(w, "peer lag (p95): %s, round duration (p95): %s\n\n", advice.PeerLag, advice.RoundDuration)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PHASE\tP50\tP95\tMAX\tDEADLINE")
+	for _, phase := range advice.Phases {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", phase.Type, phase.P50, phase.P95, phase.Max, phase.Deadline)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w, "\nrecommended settings:")
+	tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "  offChain.WithCatchUp\t%s\n", advice.CatchUpInterval)
+	fmt.Fprintf(tw, "  offChain.WithMaxRoundAge\t%s\n", advice.MaxRoundAge)
+	fmt.Fprintf(tw, "  onChain.WithPollInterval\t%s\n", advice.PollInterval)
+	if advice.BlocksAhead > 0 {
+		fmt.Fprintf(tw, "  offChain.WithBlocksAhead\t%d\n", advice.BlocksAhead)
+		fmt.Fprintf(tw, "  offChain.WithPipelining\t%d\n", advice.PipelineLead)
+	}
+	tw.Flush()
+	for _, note := range advice.Notes {
+		fmt.Fprintf(w, "note: %s\n", note)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// approve shows or approves the verifier staged by a node, through the handler
+// returned by types.NewApprovalHandler.
+func approve(args []string) error {
+	var (
+		flags   = flag.NewFlagSet("approve", flag.ExitOnError)
+		addr    = flags.String("url", "http://127.0.0.1:26670/dkg/approval", "URL of the node's approval handler")
+		roundID = flags.Int("round", -1, "round of the staged verifier to approve; only shows it if negative")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	target, method := *addr, http.MethodGet
+	if *roundID >= 0 {
+		u, err := url.Parse(*addr)
+		if err != nil {
+			return fmt.Errorf("invalid -url: %v", err)
+		}
+		q := u.Query()
+		q.Set("round_id", strconv.Itoa(*roundID))
+		u.RawQuery = q.Encode()
+		target, method = u.String(), http.MethodPost
+	}
+	req, err := http.NewRequest(method, target, nil)
+	if err != nil {
+		return fmt.Errorf("invalid request: %v", err)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, body)
+	}
+	var staged types.StagedVerifier
+	if err := json.Unmarshal(body, &staged); err != nil {
+		return fmt.Errorf("failed to decode staged verifier: %v", err)
+	}
+	return printOutput(*output, &staged, func(w io.Writer) {
+		fmt.Fprintf(w, "round %d\tchange height %d\tapproved %t\n", staged.RoundID, staged.ChangeHeight, staged.Approved)
+		fmt.Fprintf(w, "master public key hash %s\n", staged.MasterPubKeyHash)
+		fmt.Fprintf(w, "staged at %s", staged.StagedAt.Format(time.RFC3339))
+		if !staged.Deadline.IsZero() {
+			fmt.Fprintf(w, ", activated without approval at %s", staged.Deadline.Format(time.RFC3339))
+		}
+		fmt.Fprintln(w)
+	})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// blacklist inspects and overrides the persisted peer blacklist of a node. The
+// node must be stopped, since it rewrites the file on every change.
+func blacklist(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("expected list, ban, allow or remove")
+	}
+	var (
+		flags   = flag.NewFlagSet("blacklist "+args[0], flag.ExitOnError)
+		path    = flags.String("file", "", "path to the blacklist file")
+		addrHex = flags.String("addr", "", "address of the peer")
+		roundID = flags.Int("round", 0, "round the peer is banned after (ban)")
+		expires = flags.Int("expires", 0, "last round the peer is banned from, zero for never (ban)")
+		reason  = flags.String("reason", "banned by the operator", "reason of the ban (ban)")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *path == "" {
+		flags.Usage()
+		return fmt.Errorf("-file is required")
+	}
+	bl, err := types.LoadBlacklist(*path, 0)
+	if err != nil {
+		return err
+	}
+	if args[0] == "list" {
+		entries := bl.Entries()
+		if entries == nil {
+			entries = []*types.BlacklistEntry{}
+		}
+		return printOutput(*output, entries, func(w io.Writer) {
+			for _, entry := range entries {
+				fmt.Fprintf(w, "%s\tround %d\texpires %d\t%s\n", entry.Addr, entry.RoundID, entry.Expires, entry.Reason)
+			}
+		})
+	}
+
+	addr, err := hex.DecodeString(*addrHex)
+	if err != nil || len(addr) != crypto.AddressSize {
+		return fmt.Errorf("invalid -addr %q", *addrHex)
+	}
+	switch args[0] {
+	case "ban":
+		err = bl.Ban(&types.BlacklistEntry{Addr: addr, RoundID: *roundID, Expires: *expires, Reason: *reason})
+	case "allow":
+		err = bl.Allow(addr)
+	case "remove":
+		err = bl.Remove(addr)
+	default:
+		return fmt.Errorf("unknown blacklist command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+	return printOutput(*output, &blacklistOutput{Command: args[0], Addr: addr}, nil)
+}
+
+// blacklistOutput is the result of a change of the blacklist.
+type blacklistOutput struct {
+	Command string         `json:"command"`
+	Addr    crypto.Address `json:"addr"`
+}
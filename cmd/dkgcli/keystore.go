@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// keystore migrates unencrypted BLS share files and rotates passphrases.
+func keystore(args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("expected migrate or passwd")
+	}
+	var (
+		flags  = flag.NewFlagSet("keystore "+args[0], flag.ExitOnError)
+		path   = flags.String("file", "", "path to the BLS share file")
+		kdf    = flags.String("kdf", blsShare.KDFArgon2id, "key derivation function: argon2id or scrypt")
+		output = outputFlag(flags)
+	)
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *path == "" {
+		flags.Usage()
+		return fmt.Errorf("-file is required")
+	}
+	var options []blsShare.KeystoreOption
+	switch *kdf {
+	case blsShare.KDFArgon2id:
+	case blsShare.KDFScrypt:
+		options = append(options, blsShare.WithScrypt(1<<18, 8, 1))
+	default:
+		return fmt.Errorf("unsupported KDF %q", *kdf)
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	switch args[0] {
+	case "migrate":
+		passphrase, err := readPassphrase(stdin, "New passphrase: ")
+		if err != nil {
+			return err
+		}
+		migrated, err := blsShare.MigrateBLSShare(*path, passphrase, options...)
+		if err != nil {
+			return err
+		}
+		return printOutput(*output, &keystoreOutput{Command: args[0], File: *path, Migrated: migrated}, func(w io.Writer) {
+			if !migrated {
+				fmt.Fprintln(w, "keystore is already encrypted")
+			}
+		})
+	case "passwd":
+		oldPassphrase, err := readPassphrase(stdin, "Current passphrase: ")
+		if err != nil {
+			return err
+		}
+		newPassphrase, err := readPassphrase(stdin, "New passphrase: ")
+		if err != nil {
+			return err
+		}
+		if err := blsShare.ChangeBLSSharePassphrase(*path, oldPassphrase, newPassphrase, options...); err != nil {
+			return err
+		}
+		return printOutput(*output, &keystoreOutput{Command: args[0], File: *path}, nil)
+	default:
+		return fmt.Errorf("unknown keystore command %q", args[0])
+	}
+}
+
+// keystoreOutput is the result of a keystore command.
+type keystoreOutput struct {
+	Command  string `json:"command"`
+	File     string `json:"file"`
+	Migrated bool   `json:"migrated"` // False if the file was already encrypted (migrate).
+}
+
+// readPassphrase reads a passphrase without echo from a terminal, or a line from piped input.
+func readPassphrase(stdin *bufio.Reader, prompt string) ([]byte, error) {
+	fd := int(os.Stdin.Fd())
+	if terminal.IsTerminal(fd) {
+		fmt.Fprint(os.Stderr, prompt)
+		passphrase, err := terminal.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read passphrase: %v", err)
+		}
+		return passphrase, nil
+	}
+	line, err := stdin.ReadString('\n')
+	if err != nil && line == "" {
+		return nil, fmt.Errorf("failed to read passphrase: %v", err)
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/roundtest"
+	"github.com/corestario/dkglib/lib/wal"
+	"github.com/tendermint/tendermint/libs/log"
+	"github.com/tendermint/tendermint/privval"
+)
+
+const usage = `Usage: dkgcli <command> [flags]
+
+Every command takes -output text|json|yaml; the JSON and YAML documents have
+the same fields.
+
+Commands:
+  replay     feed a DKG write-ahead log into a fresh dealer
+  bench      measure latency of complete in-memory DKG rounds
+  soak       run consecutive in-process DKG rounds and fail if resource usage grows
+  simulate   run deterministic in-memory DKG rounds, reproducible from their seeds
+  keystore   encrypt (migrate) a BLS share file or change its passphrase (passwd)
+  blacklist  list, ban, allow (override votes) or remove peers of the persisted blacklist
+  approve    show or approve the verifier a node staged for operator approval
+  verify-sig verify an aggregated threshold signature with the group key of an epoch
+  participation show which messages of a validator were included on chain for a round
+  monitor    show the rounds of a node live, from its status handler and event stream
+  advise     recommend phase deadlines and poll intervals from the observed peer latency
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "replay":
+		if err := replay(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "replay failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "bench":
+		if err := bench(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "bench failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "soak":
+		if err := soak(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "soak failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "simulate":
+		if err := simulate(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "simulate failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "keystore":
+		if err := keystore(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "keystore failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "blacklist":
+		if err := blacklist(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "blacklist failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "approve":
+		if err := approve(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "approve failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "verify-sig":
+		if err := verifySig(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "verify-sig failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "participation":
+		if err := participation(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "participation failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "monitor":
+		if err := monitor(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "monitor failed: %v\n", err)
+			os.Exit(1)
+		}
+	case "advise":
+		if err := advise(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "advise failed: %v\n", err)
+			os.Exit(1)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func replay(args []string) error {
+	var (
+		flags   = flag.NewFlagSet("replay", flag.ExitOnError)
+		walPath = flags.String("wal", "", "path to the DKG write-ahead log")
+		keyPath = flags.String("key", "", "path to priv_validator_key.json of the node that wrote the log")
+		roundID = flags.Int("round", -1, "round to replay; the first recorded round if negative")
+		chainID = flags.String("chain-id", "", "chain ID the node signed its messages for")
+		version = flags.Uint("sign-version", uint(alias.SignBytesVersion), "sign bytes version of the node")
+		seedKey = flags.String("seed-key", "", "path to the hex-encoded key the dealers' seeds were recorded with, see wal.WithSeedKey")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *walPath == "" || *keyPath == "" {
+		flags.Usage()
+		return fmt.Errorf("both -wal and -key are required")
+	}
+	if *version > 255 {
+		return fmt.Errorf("invalid sign bytes version %d", *version)
+	}
+	domain, err := alias.NewSignDomain(*chainID, byte(*version))
+	if err != nil {
+		return err
+	}
+
+	reader, err := wal.OpenReader(*walPath)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %v", err)
+	}
+	defer reader.Close()
+
+	pv := privval.LoadFilePVEmptyState(*keyPath, "")
+	logs := os.Stdout
+	if structured(*output) {
+		logs = os.Stderr
+	}
+	logger := logging.NewTMLogger(log.NewTMLogger(logs))
+	replayOptions := []wal.ReplayOption{wal.WithReplaySignDomain(domain)}
+	if *seedKey != "" {
+		key, err := readSeedKey(*seedKey)
+		if err != nil {
+			return err
+		}
+		replayOptions = append(replayOptions, wal.WithReplaySeedKey(key))
+	}
+	info, err := wal.Replay(reader, *roundID, pv, dealer.NewDKGDealer, logger, replayOptions...)
+	if err != nil {
+		return err
+	}
+	for _, corruption := range reader.Corruptions() {
+		fmt.Fprintf(os.Stderr, "skipped: %v\n", corruption)
+	}
+
+	return printOutput(*output, info, func(w io.Writer) {
+		fmt.Fprintf(w, "round %d\tresult %s\tphase %s (%d/%d)\n",
+			info.RoundID, info.Result, info.Phase.Name, info.Phase.Completed, info.Phase.Total)
+		for _, peer := range info.Peers {
+			fmt.Fprintf(w, "%d\t%s\tloser %t\tmessages %v\n", peer.Index, peer.Addr, peer.Loser, peer.Messages)
+		}
+	})
+}
+
+// readSeedKey reads the hex-encoded WAL seed key at the path.
+func readSeedKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seed key: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed key: %v", err)
+	}
+	if len(key) != wal.SeedKeySize {
+		return nil, fmt.Errorf("seed key must have %d bytes, got %d", wal.SeedKeySize, len(key))
+	}
+	return key, nil
+}
+
+func bench(args []string) error {
+	var (
+		flags  = flag.NewFlagSet("bench", flag.ExitOnError)
+		sizes  = flags.String("sizes", "4,16,32,64", "comma-separated numbers of validators")
+		output = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	var results []*benchOutput
+	for _, size := range strings.Split(*sizes, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(size))
+		if err != nil || n < 2 {
+			return fmt.Errorf("invalid number of validators %q", size)
+		}
+		result, err := roundtest.Benchmark(n)
+		if err != nil {
+			return fmt.Errorf("round of %d validators failed: %v", n, err)
+		}
+		if !structured(*output) {
+			fmt.Printf("BenchmarkRound/N=%d\t%s\t%s\n", n, result.String(), result.MemString())
+			continue
+		}
+		results = append(results, newBenchOutput(n, result))
+	}
+	if !structured(*output) {
+		return nil
+	}
+	return printOutput(*output, results, nil)
+}
+
+func soak(args []string) error {
+	var (
+		flags   = flag.NewFlagSet("soak", flag.ExitOnError)
+		n       = flags.Int("n", 4, "number of validators")
+		rounds  = flags.Int("rounds", 200, "number of consecutive rounds")
+		verbose = flags.Bool("v", false, "print the resources in use after every round (text output)")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	var options []roundtest.SoakOption
+	if *verbose && !structured(*output) {
+		options = append(options, roundtest.WithSoakProgress(func(sample roundtest.SoakSample) {
+			fmt.Printf("round %d\theight %d\tgoroutines %d\theap %d\tstate %v\n",
+				sample.Round, sample.Height, sample.Goroutines, sample.HeapAlloc, sample.StateSizes)
+		}))
+	}
+	report, err := roundtest.Soak(*n, *rounds, options...)
+	if report != nil {
+		printErr := printOutput(*output, report, func(w io.Writer) {
+			fmt.Fprintf(w, "Soak/N=%d\t%d rounds\t%s\n", report.Validators, len(report.Samples), report.Duration)
+		})
+		if err == nil {
+			err = printErr
+		}
+	}
+	return err
+}
+
+func simulate(args []string) error {
+	var (
+		flags      = flag.NewFlagSet("simulate", flag.ExitOnError)
+		n          = flags.Int("n", 4, "number of validators")
+		seed       = flags.Int64("seed", 1, "seed of the first round")
+		runs       = flags.Int("runs", 1, "number of rounds, with consecutive seeds")
+		minLatency = flags.Duration("min-latency", 10*time.Millisecond, "lowest virtual latency of a delivery")
+		maxLatency = flags.Duration("max-latency", 200*time.Millisecond, "highest virtual latency of a delivery")
+		verbose    = flags.Bool("v", false, "print every delivery")
+		output     = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+
+	var (
+		results []*simulationOutput
+		steps   []simulationStepOutput
+	)
+	options := []roundtest.SimulationOption{roundtest.WithLatency(*minLatency, *maxLatency)}
+	if *verbose {
+		options = append(options, roundtest.WithSimulationTrace(func(step roundtest.SimulationStep) {
+			if structured(*output) {
+				steps = append(steps, newSimulationStepOutput(step))
+				return
+			}
+			fmt.Printf("%d\t%s\t%d -> %d\t%s\t%s\n", step.Step, step.At, step.From, step.To, step.Type, step.ID)
+		}))
+	}
+	for i := 0; i < *runs; i++ {
+		steps = nil
+		result, err := roundtest.Simulate(*n, *seed+int64(i), options...)
+		if err != nil {
+			err = fmt.Errorf("%v (rerun with -seed %d -runs 1 -v)", err, *seed+int64(i))
+			if structured(*output) && len(results) > 0 {
+				if printErr := printOutput(*output, results, nil); printErr != nil {
+					return printErr
+				}
+			}
+			return err
+		}
+		if !structured(*output) {
+			fmt.Printf("Simulate/N=%d\tseed %d\t%d deliveries\t%s\ttrace %X\tkey %X\n",
+				result.Validators, result.Seed, result.Deliveries, result.Duration, result.Trace, result.MasterPubKey)
+			continue
+		}
+		results = append(results, newSimulationOutput(result, steps))
+	}
+	if !structured(*output) {
+		return nil
+	}
+	return printOutput(*output, results, nil)
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	barWidth         = 24
+	streamRetryDelay = 2 * time.Second
+	ansiClear        = "\x1b[H\x1b[2J"
+	ansiRed          = "\x1b[31m"
+	ansiGreen        = "\x1b[32m"
+	ansiYellow       = "\x1b[33m"
+	ansiReset        = "\x1b[0m"
+)
+
+// monitor shows the rounds of a node live, from the handlers returned by
+// types.NewStatusHandler and the types.EventStream.
+func monitor(args []string) error {
+	var (
+		flags     = flag.NewFlagSet("monitor", flag.ExitOnError)
+		statusURL = flags.String("status", "http://127.0.0.1:26670/dkg/status", "URL of the node's status handler")
+		streamURL = flags.String("stream", "http://127.0.0.1:26670/dkg/stream", "URL of the node's event stream; none if empty")
+		interval  = flags.Duration("interval", time.Second, "status polling interval")
+		events    = flags.Int("events", 8, "number of recent events shown")
+		errors    = flags.Int("errors", 5, "number of recent errors shown")
+		output    = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("-interval must be positive")
+	}
+
+	state := &monitorState{
+		statusURL: *statusURL,
+		streamURL: *streamURL,
+		maxEvents: *events,
+		maxErrors: *errors,
+		changed:   make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+	}
+	if structured(*output) {
+		var printMtx sync.Mutex
+		state.print = func(v *monitorOutput) {
+			printMtx.Lock()
+			defer printMtx.Unlock()
+			printMonitorOutput(*output, v)
+		}
+	}
+	client := &http.Client{Timeout: *interval + 5*time.Second}
+	go state.poll(client, *interval)
+	if *streamURL != "" {
+		go state.stream(&http.Client{})
+	}
+
+	interactive := !structured(*output) && terminal.IsTerminal(int(os.Stdout.Fd()))
+	if interactive && terminal.IsTerminal(int(os.Stdin.Fd())) {
+		old, err := terminal.MakeRaw(int(os.Stdin.Fd()))
+		if err == nil {
+			state.raw = true
+			defer terminal.Restore(int(os.Stdin.Fd()), old)
+			go state.readKeys(os.Stdin)
+		}
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-state.changed:
+			if !structured(*output) {
+				state.render(os.Stdout, interactive)
+			}
+		case <-signals:
+			return nil
+		case <-state.quit:
+			return nil
+		}
+	}
+}
+
+// monitorOutput is an update of the structured output of monitor: a polled
+// status or an item of the event stream.
+type monitorOutput struct {
+	Status *types.Status     `json:"status,omitempty"`
+	Item   *types.StreamItem `json:"item,omitempty"`
+	Error  string            `json:"error,omitempty"` // Failure to reach the node.
+}
+
+// printMonitorOutput prints an update as a line of JSON or a YAML document.
+func printMonitorOutput(format string, v *monitorOutput) {
+	if format == outputYAML {
+		out, err := toYAML(v)
+		if err == nil {
+			fmt.Printf("---\n%s", out)
+		}
+		return
+	}
+	out, err := json.Marshal(v)
+	if err == nil {
+		fmt.Println(string(out))
+	}
+}
+
+// monitorState is what the dashboard shows.
+type monitorState struct {
+	statusURL string
+	streamURL string
+	maxEvents int
+	maxErrors int
+	raw       bool                 // The terminal is in raw mode.
+	print     func(*monitorOutput) // Structured output, nil for the dashboard.
+
+	mtx       sync.Mutex
+	status    *types.Status
+	updated   time.Time
+	statusErr error
+	streamErr error
+	events    []*types.StreamItem
+	errors    []*types.StreamItem
+
+	changed  chan struct{}
+	quit     chan struct{}
+	quitOnce sync.Once
+}
+
+func (s *monitorState) notify() {
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (s *monitorState) stop() {
+	s.quitOnce.Do(func() { close(s.quit) })
+}
+
+// poll fetches the status at every interval.
+func (s *monitorState) poll(client *http.Client, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := fetchStatus(client, s.statusURL)
+		s.mtx.Lock()
+		s.statusErr = err
+		if err == nil {
+			s.status, s.updated = status, time.Now()
+		}
+		s.mtx.Unlock()
+		if s.print != nil {
+			if err != nil {
+				s.print(&monitorOutput{Error: err.Error()})
+			} else {
+				s.print(&monitorOutput{Status: status})
+			}
+		}
+		s.notify()
+
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func fetchStatus(client *http.Client, url string) (*types.Status, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("status request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status request failed: %s", resp.Status)
+	}
+	var status types.Status
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to decode status: %v", err)
+	}
+	return &status, nil
+}
+
+// stream reads the event stream, reconnecting when it ends. The items the
+// stream replays on reconnection are told apart by their time.
+func (s *monitorState) stream(client *http.Client) {
+	var last time.Time
+	for {
+		err := readStream(client, s.streamURL, func(item *types.StreamItem) {
+			if !item.Time.After(last) {
+				return
+			}
+			last = item.Time
+			s.add(item)
+		})
+		s.mtx.Lock()
+		s.streamErr = err
+		s.mtx.Unlock()
+		if s.print != nil && err != nil {
+			s.print(&monitorOutput{Error: err.Error()})
+		}
+		s.notify()
+
+		select {
+		case <-time.After(streamRetryDelay):
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// readStream calls the callback with every item of the server-sent events
+// until the stream ends.
+func readStream(client *http.Client, url string, callback func(*types.StreamItem)) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("stream request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stream request failed: %s", resp.Status)
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		var item types.StreamItem
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &item); err != nil {
+			continue
+		}
+		callback(&item)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream failed: %v", err)
+	}
+	return fmt.Errorf("stream ended")
+}
+
+func (s *monitorState) add(item *types.StreamItem) {
+	s.mtx.Lock()
+	s.streamErr = nil
+	if item.Error != "" {
+		s.errors = appendRecent(s.errors, item, s.maxErrors)
+	} else {
+		s.events = appendRecent(s.events, item, s.maxEvents)
+	}
+	s.mtx.Unlock()
+	if s.print != nil {
+		s.print(&monitorOutput{Item: item})
+	}
+	s.notify()
+}
+
+func appendRecent(items []*types.StreamItem, item *types.StreamItem, max int) []*types.StreamItem {
+	items = append(items, item)
+	if extra := len(items) - max; extra > 0 {
+		items = items[extra:]
+	}
+	return items
+}
+
+// readKeys quits on q or Ctrl-C, which the raw terminal doesn't turn into a
+// signal.
+func (s *monitorState) readKeys(r io.Reader) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := r.Read(buf); err != nil {
+			return
+		}
+		switch buf[0] {
+		case 'q', 'Q', 3:
+			s.stop()
+			return
+		}
+	}
+}
+
+// render draws the dashboard; on a terminal, over the previous one.
+func (s *monitorState) render(w io.Writer, tty bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	var (
+		buf   bytes.Buffer
+		color = func(code, text string) string {
+			if !tty {
+				return text
+			}
+			return code + text + ansiReset
+		}
+	)
+	if tty {
+		buf.WriteString(ansiClear)
+	}
+	fmt.Fprintf(&buf, "DKG monitor  %s  %s", s.statusURL, time.Now().Format("15:04:05"))
+	if s.raw {
+		buf.WriteString("  (q to quit)")
+	}
+	buf.WriteString("\n\n")
+
+	switch {
+	case s.statusErr != nil && s.status == nil:
+		fmt.Fprintf(&buf, "%s\n", color(ansiRed, s.statusErr.Error()))
+	case s.status != nil:
+		if s.statusErr != nil {
+			fmt.Fprintf(&buf, "%s (showing status of %s)\n",
+				color(ansiRed, s.statusErr.Error()), s.updated.Format("15:04:05"))
+		}
+		renderStatus(&buf, s.status, color)
+	default:
+		buf.WriteString("waiting for the node...\n")
+	}
+
+	buf.WriteString("\nRecent events\n")
+	if len(s.events) == 0 {
+		buf.WriteString("  none\n")
+	}
+	for _, item := range s.events {
+		event := item.Event
+		fmt.Fprintf(&buf, "  %s  %-14s round %d  epoch %d  height %d\n",
+			item.Time.Format("15:04:05"), event.Type, event.RoundID, event.Epoch, event.Height)
+	}
+	buf.WriteString("\nErrors\n")
+	if s.streamErr != nil && s.streamURL != "" {
+		fmt.Fprintf(&buf, "  %s\n", color(ansiYellow, s.streamErr.Error()))
+	}
+	if len(s.errors) == 0 {
+		buf.WriteString("  none\n")
+	}
+	for _, item := range s.errors {
+		fmt.Fprintf(&buf, "  %s  round %d  %s\n", item.Time.Format("15:04:05"), item.RoundID, color(ansiRed, item.Error))
+	}
+	if !tty {
+		buf.WriteString("\n")
+	}
+
+	out := buf.Bytes()
+	if s.raw {
+		out = bytes.Replace(out, []byte("\n"), []byte("\r\n"), -1)
+	}
+	w.Write(out)
+}
+
+// renderStatus draws the health and a section per round: the phases' progress
+// and the peers' share of completed phases.
+func renderStatus(buf *bytes.Buffer, status *types.Status, color func(code, text string) string) {
+	health := status.Health
+	verifier := color(ansiRed, "not ready")
+	if health.VerifierReady {
+		verifier = color(ansiGreen, fmt.Sprintf("ready (epoch %d)", health.VerifierRoundID))
+	}
+	fmt.Fprintf(buf, "Verifier %s   Last round %d %s", verifier, health.LastRoundID, health.LastRoundResult)
+	if health.LastMessageAge >= 0 {
+		fmt.Fprintf(buf, "   Last message %.1fs ago", health.LastMessageAge)
+	}
+	if health.Paused {
+		buf.WriteString("   " + color(ansiYellow, "PAUSED"))
+	}
+	buf.WriteString("\n")
+	if len(status.Rounds) == 0 {
+		buf.WriteString("\nNo round in progress\n")
+	}
+
+	for _, round := range status.Rounds {
+		fmt.Fprintf(buf, "\nRound %d  %s  %s  phase %s (%d/%d)\n", round.RoundID, round.CorrelationID,
+			round.Result, round.Phase.Name, round.Phase.Completed, round.Phase.Total)
+		for _, phase := range round.Progress {
+			fmt.Fprintf(buf, "  %-26s %s %d/%d", phase.Phase, bar(phase.Received, phase.Expected), phase.Received, phase.Expected)
+			if !phase.Completed && len(phase.Missing) > 0 {
+				missing := make([]string, len(phase.Missing))
+				for i, addr := range phase.Missing {
+					missing[i] = shortAddr(addr.String())
+				}
+				fmt.Fprintf(buf, "  waiting on %s", strings.Join(missing, ", "))
+			}
+			buf.WriteString("\n")
+		}
+		if len(round.Peers) == 0 {
+			continue
+		}
+		buf.WriteString("  Peers\n")
+		for _, peer := range round.Peers {
+			done := 0
+			for _, phase := range round.Progress {
+				if !missing(phase.Missing, peer.Addr.String()) {
+					done++
+				}
+			}
+			line := fmt.Sprintf("  #%-3d %s %s %d/%d phases  liveness %.2f",
+				peer.Index, shortAddr(peer.Addr.String()), bar(done, len(round.Progress)), done, len(round.Progress), peer.Liveness)
+			if peer.Loser {
+				line = color(ansiRed, line+"  excluded")
+			}
+			buf.WriteString(line + "\n")
+		}
+	}
+}
+
+func missing(addrs []crypto.Address, addr string) bool {
+	for _, a := range addrs {
+		if a.String() == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// bar draws a progress bar of the share done of total.
+func bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = done * barWidth / total
+	}
+	if filled > barWidth {
+		filled = barWidth
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat(".", barWidth-filled) + "]"
+}
+
+func shortAddr(addr string) string {
+	if len(addr) > 8 {
+		return addr[:8]
+	}
+	return addr
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/corestario/dkglib/lib/roundtest"
+	"gopkg.in/yaml.v2"
+)
+
+// Output formats of the commands, see outputFlag.
+const (
+	outputText = "text"
+	outputJSON = "json"
+	outputYAML = "yaml"
+)
+
+// outputFlag registers the -output flag of a command.
+func outputFlag(flags *flag.FlagSet) *string {
+	return flags.String("output", outputText, "output format: text, json or yaml")
+}
+
+func checkOutput(format string) error {
+	switch format {
+	case outputText, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid -output %q, expected text, json or yaml", format)
+}
+
+// structured reports whether the format is a machine-readable one.
+func structured(format string) bool {
+	return format == outputJSON || format == outputYAML
+}
+
+// printOutput writes the command's result to stdout in the format, the text
+// one with the text function. The YAML document has the same fields, in the
+// same order, as the JSON one, named by the result's json tags.
+func printOutput(format string, result interface{}, text func(w io.Writer)) error {
+	switch format {
+	case outputText:
+		if text != nil {
+			text(os.Stdout)
+		}
+		return nil
+	case outputJSON:
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %v", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	case outputYAML:
+		out, err := toYAML(result)
+		if err != nil {
+			return fmt.Errorf("failed to encode output: %v", err)
+		}
+		fmt.Print(string(out))
+		return nil
+	}
+	return checkOutput(format)
+}
+
+// toYAML converts the JSON encoding of v, which is a YAML document, keeping the
+// order of the objects' keys: they are decoded as yaml.MapSlice when nested in
+// one, so v is wrapped into an object first.
+func toYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var wrapper yaml.MapSlice
+	if err := yaml.Unmarshal(append(append([]byte(`{"v": `), data...), '}'), &wrapper); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(wrapper[0].Value)
+}
+
+// benchOutput is the result of a benchmarked round size.
+type benchOutput struct {
+	Validators       int     `json:"validators"`
+	Rounds           int     `json:"rounds"`
+	NsPerRound       int64   `json:"ns_per_round"`
+	AllocsPerRound   int64   `json:"allocs_per_round"`
+	BytesPerRound    int64   `json:"bytes_per_round"`
+	MsgsPerRound     float64 `json:"msgs_per_round"`
+	MsgBytesPerRound float64 `json:"msg_bytes_per_round"`
+}
+
+func newBenchOutput(n int, result testing.BenchmarkResult) *benchOutput {
+	return &benchOutput{
+		Validators:       n,
+		Rounds:           result.N,
+		NsPerRound:       result.NsPerOp(),
+		AllocsPerRound:   result.AllocsPerOp(),
+		BytesPerRound:    result.AllocedBytesPerOp(),
+		MsgsPerRound:     result.Extra["msgs/op"],
+		MsgBytesPerRound: result.Extra["msg-bytes/op"],
+	}
+}
+
+// simulationOutput is the result of a simulated round, with its deliveries
+// if they were traced.
+type simulationOutput struct {
+	Seed         int64                  `json:"seed"`
+	Validators   int                    `json:"validators"`
+	Deliveries   int                    `json:"deliveries"`
+	Duration     time.Duration          `json:"duration"`
+	Trace        string                 `json:"trace"`
+	MasterPubKey string                 `json:"master_pub_key"`
+	Steps        []simulationStepOutput `json:"steps,omitempty"`
+}
+
+func newSimulationOutput(result *roundtest.SimulationResult, steps []simulationStepOutput) *simulationOutput {
+	return &simulationOutput{
+		Seed:         result.Seed,
+		Validators:   result.Validators,
+		Deliveries:   result.Deliveries,
+		Duration:     result.Duration,
+		Trace:        fmt.Sprintf("%X", result.Trace),
+		MasterPubKey: fmt.Sprintf("%X", result.MasterPubKey),
+		Steps:        steps,
+	}
+}
+
+type simulationStepOutput struct {
+	Step int           `json:"step"`
+	At   time.Duration `json:"at"`
+	From int           `json:"from"`
+	To   int           `json:"to"`
+	Type string        `json:"type"`
+	ID   string        `json:"id"`
+}
+
+func newSimulationStepOutput(step roundtest.SimulationStep) simulationStepOutput {
+	return simulationStepOutput{
+		Step: step.Step,
+		At:   step.At,
+		From: step.From,
+		To:   step.To,
+		Type: step.Type.String(),
+		ID:   step.ID,
+	}
+}
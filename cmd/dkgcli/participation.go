@@ -0,0 +1,80 @@
+//go:build !nocosmos
+// +build !nocosmos
+
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/onChain"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// participation shows which messages of a validator were included on chain for a round.
+func participation(args []string) error {
+	var (
+		flags   = flag.NewFlagSet("participation", flag.ExitOnError)
+		node    = flags.String("node", "http://127.0.0.1:26657", "RPC endpoint of the node")
+		chainID = flags.String("chain-id", "", "chain ID")
+		home    = flags.String("home", os.ExpandEnv("$HOME/.dkgcli"), "directory of the light client data")
+		roundID = flags.Int("round", -1, "round to audit")
+		addr    = flags.String("addr", "", "hex address of the validator")
+		asJSON  = flags.Bool("json", false, "same as -output json")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *asJSON {
+		*output = outputJSON
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *roundID < 0 || *addr == "" {
+		flags.Usage()
+		return fmt.Errorf("-round and -addr are required")
+	}
+	validator, err := hex.DecodeString(*addr)
+	if err != nil {
+		return fmt.Errorf("invalid -addr: %v", err)
+	}
+
+	cli, err := client.NewContext(*chainID, *node, *home)
+	if err != nil {
+		return err
+	}
+	queries := onChain.NewQueryClient(cli, onChain.WithQuerySignDomain(alias.DefaultSignDomain(*chainID)))
+	result, err := queries.QueryParticipation(*roundID, validator)
+	if err != nil {
+		return err
+	}
+	out := &participationOutput{
+		RoundID:  result.RoundID,
+		Addr:     result.Addr,
+		Included: make(map[string]int, len(result.Included)),
+		Invalid:  result.Invalid,
+		Height:   result.Height,
+	}
+	for dataType, count := range result.Included {
+		out.Included[dataType.String()] = count
+	}
+	return printOutput(*output, out, func(w io.Writer) {
+		fmt.Fprintln(w, result)
+	})
+}
+
+// participationOutput is types.Participation with the message types named.
+type participationOutput struct {
+	RoundID  int            `json:"round_id"`
+	Addr     crypto.Address `json:"addr"`
+	Included map[string]int `json:"included"`
+	Invalid  int            `json:"invalid"`
+	Height   int64          `json:"height"`
+}
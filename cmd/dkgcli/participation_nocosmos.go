@@ -0,0 +1,11 @@
+//go:build nocosmos
+// +build nocosmos
+
+package main
+
+import "fmt"
+
+// participation needs the chain client, which isn't built with the nocosmos tag.
+func participation(args []string) error {
+	return fmt.Errorf("dkgcli was built with the nocosmos tag, without the chain client")
+}
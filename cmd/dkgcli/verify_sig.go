@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// verifySig verifies an aggregated threshold signature with the group key of
+// an epoch, loaded from an exported state snapshot, a round attestation or a
+// verifier snapshot.
+func verifySig(args []string) error {
+	var (
+		flags   = flag.NewFlagSet("verify-sig", flag.ExitOnError)
+		keyPath = flags.String("key", "", "JSON file with the group key: state snapshot, round attestation or verifier snapshot")
+		epoch   = flags.Int("epoch", -1, "epoch (round ID) of the group key")
+		msgPath = flags.String("msg", "", "file with the signed message")
+		sigHex  = flags.String("sig", "", "hex-encoded aggregated signature")
+		output  = outputFlag(flags)
+	)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if err := checkOutput(*output); err != nil {
+		return err
+	}
+	if *keyPath == "" || *epoch < 0 || *msgPath == "" || *sigHex == "" {
+		flags.Usage()
+		return fmt.Errorf("-key, -epoch, -msg and -sig are required")
+	}
+
+	data, err := ioutil.ReadFile(*keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read group key: %v", err)
+	}
+	snapshot, err := findVerifierSnapshot(data, *epoch)
+	if err != nil {
+		return err
+	}
+	verifier, err := snapshot.Verifier()
+	if err != nil {
+		return fmt.Errorf("failed to load group key: %v", err)
+	}
+
+	msg, err := ioutil.ReadFile(*msgPath)
+	if err != nil {
+		return fmt.Errorf("failed to read message: %v", err)
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(*sigHex), "0x"))
+	if err != nil {
+		return fmt.Errorf("invalid -sig: %v", err)
+	}
+	if err := verifier.VerifyRandomData(msg, sig); err != nil {
+		return fmt.Errorf("invalid signature for epoch %d: %v", *epoch, err)
+	}
+
+	return printOutput(*output, &verifySigOutput{Epoch: *epoch, Valid: true}, func(w io.Writer) {
+		fmt.Fprintf(w, "signature is valid for epoch %d\n", *epoch)
+	})
+}
+
+// verifySigOutput is the result of a verified signature; invalid signatures
+// fail the command.
+type verifySigOutput struct {
+	Epoch int  `json:"epoch"`
+	Valid bool `json:"valid"`
+}
+
+// findVerifierSnapshot returns the verifier snapshot of the epoch stored in the
+// JSON document; the verifier field of state snapshots and attestations share
+// the same key.
+func findVerifierSnapshot(data []byte, epoch int) (*types.VerifierSnapshot, error) {
+	var (
+		state      types.StateSnapshot
+		single     types.VerifierSnapshot
+		candidates []*types.VerifierSnapshot
+	)
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode group key: %v", err)
+	}
+	candidates = append(candidates, state.Verifier, state.NextVerifier)
+	if err := json.Unmarshal(data, &single); err == nil && single.MasterPubKey != "" {
+		candidates = append(candidates, &single)
+	}
+
+	var epochs []int
+	for _, candidate := range candidates {
+		if candidate == nil {
+			continue
+		}
+		if candidate.RoundID == epoch {
+			return candidate, nil
+		}
+		epochs = append(epochs, candidate.RoundID)
+	}
+	if len(epochs) == 0 {
+		return nil, fmt.Errorf("no group key found")
+	}
+	sort.Ints(epochs)
+	return nil, fmt.Errorf("no group key of epoch %d, found epochs %v", epoch, epochs)
+}
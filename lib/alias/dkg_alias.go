@@ -1,20 +1,26 @@
 package alias
 
 import (
-	"os"
+	"bytes"
+	"fmt"
 
 	"github.com/tendermint/go-amino"
 	tmalias "github.com/tendermint/tendermint/alias"
 	"github.com/tendermint/tendermint/crypto"
-	"github.com/tendermint/tendermint/libs/log"
 )
 
 type DKGDataType int
 
 var Cdc = amino.NewCodec()
-var RegisterBlockAmino = tmalias.RegisterBlockAmino
+
+// RegisterBlockAmino registers the block types on the codec.
+func RegisterBlockAmino(cdc *amino.Codec) {
+	tmalias.RegisterBlockAmino(cdc)
+}
 
 const (
+	// DKGPubKey is the unsigned key announcement of older nodes; dealers
+	// ignore it, keys are only taken from a DKGRegistration.
 	DKGPubKey DKGDataType = iota
 	DKGDeal
 	DKGResponse
@@ -22,32 +28,123 @@ const (
 	DKGCommits
 	DKGComplaint
 	DKGReconstructCommit
+	DKGChangeHeight
+	DKGRoundStart
+	DKGRegistration
+	DKGBlacklist
+	DKGRoundParams
+	DKGMigration
+	DKGRequestMissing
+	DKGAbort
+	DKGSignRequest
+	DKGSignShare
+	DKGRefreshKey
+	DKGRefreshDeal
+	DKGRefreshAck
+	DKGRecoveryRequest
+	DKGRecoveryKey
+	DKGRecoveryDeal
+	DKGRecoveryShare
+	DKGRefreshCommit
 )
 
+var dkgDataTypeNames = map[DKGDataType]string{
+	DKGPubKey:            "pub_key",
+	DKGDeal:              "deal",
+	DKGResponse:          "response",
+	DKGJustification:     "justification",
+	DKGCommits:           "commits",
+	DKGComplaint:         "complaint",
+	DKGReconstructCommit: "reconstruct_commit",
+	DKGChangeHeight:      "change_height",
+	DKGRoundStart:        "round_start",
+	DKGRegistration:      "registration",
+	DKGBlacklist:         "blacklist",
+	DKGRoundParams:       "round_params",
+	DKGMigration:         "migration",
+	DKGRequestMissing:    "request_missing",
+	DKGAbort:             "abort",
+	DKGSignRequest:       "sign_request",
+	DKGSignShare:         "sign_share",
+	DKGRefreshKey:        "refresh_key",
+	DKGRefreshDeal:       "refresh_deal",
+	DKGRefreshAck:        "refresh_ack",
+	DKGRecoveryRequest:   "recovery_request",
+	DKGRecoveryKey:       "recovery_key",
+	DKGRecoveryDeal:      "recovery_deal",
+	DKGRecoveryShare:     "recovery_share",
+	DKGRefreshCommit:     "refresh_commit",
+}
+
+func (t DKGDataType) String() string {
+	if name, ok := dkgDataTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
+// ParseDKGDataType returns the type named name, see DKGDataType.String.
+func ParseDKGDataType(name string) (DKGDataType, bool) {
+	for t, typeName := range dkgDataTypeNames {
+		if typeName == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
 type DKGData struct {
 	Type        DKGDataType
 	Addr        []byte
 	RoundID     int
 	Data        []byte // Data is going to keep serialized kyber objects.
-	ToIndex     int    // ID of the participant for whom the message is; might be not set
+	ToIndex     int    // ID of the participant for whom the message is; for justifications, complaints and reconstruct commits, the index of the message among the sender's, so identical nil messages are told apart.
 	NumEntities int    // Number of sub-entities in the Data array, sometimes required for unmarshaling.
 	Signature   []byte //Signature for verifying data
+	ChunkIndex  int    // Index of this chunk if Data was split (see SplitDKGData).
+	NumChunks   int    // Number of chunks the original Data was split into; zero if it was not split.
+	KeyPurpose  string // Group key the message is for, see ValidKeyPurpose; empty for the default key.
 }
 
 func init() {
 	RegisterBlockAmino(Cdc)
 }
 
-func (m DKGData) SignBytes(string) []byte {
-	m.Signature = nil
-	sb, err := Cdc.MarshalBinaryLengthPrefixed(m)
-	if err != nil {
-		logger := log.NewTMLogger(os.Stdout)
-		logger.Error("Codec MarshalBinaryLengthPrefixed error",
-			"DKGData type", m.Type, "RoundID", m.RoundID, "ToIndex", m.ToIndex, "Error", err)
-		panic(err)
+// SignBytes returns the canonical encoding of the message without the signature
+// in the domain of the chain with the latest version, see encodeSignBytes. Use
+// SignDomain to sign and verify with another version.
+func (m DKGData) SignBytes(chainID string) []byte {
+	return encodeSignBytes(&m, chainID, SignBytesVersion)
+}
+
+// ContentBytes returns the canonical encoding of the message without the
+// signature, independent of any domain, so every node derives the same bytes,
+// e.g. for dedup keys and evidence hashes. It is not meant to be signed.
+func (m DKGData) ContentBytes() []byte {
+	return encodeSignBytes(&m, "", SignBytesV2)
+}
+
+// SignBytesIn returns the canonical encoding of the message in the domain of
+// the chain and the sign-byte version, e.g. to verify messages of another chain.
+func (m DKGData) SignBytesIn(chainID string, version byte) []byte {
+	return encodeSignBytes(&m, chainID, version)
+}
+
+// maxKeyPurposeLength limits the length of a key purpose.
+const maxKeyPurposeLength = 32
+
+// ValidKeyPurpose reports whether the purpose can name a group key: it is empty,
+// for the default key, or up to 32 lowercase letters, digits, '_' and '-'.
+func ValidKeyPurpose(purpose string) bool {
+	if len(purpose) > maxKeyPurposeLength {
+		return false
+	}
+	for _, c := range purpose {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
 	}
-	return sb
+	return true
 }
 
 func (m *DKGData) SetSignature(sig []byte) {
@@ -58,6 +155,62 @@ func (m *DKGData) GetAddrString() string {
 	return crypto.Address(m.Addr).String()
 }
 
+// ValidateSigner checks that the message's sender is the validator of the key
+// it is signed with.
+func (m *DKGData) ValidateSigner(signer crypto.PubKey) error {
+	if signer == nil {
+		return fmt.Errorf("empty signer")
+	}
+	if addr := signer.Address(); !bytes.Equal(addr, m.Addr) {
+		return fmt.Errorf("signer %s doesn't match sender %s", addr, m.GetAddrString())
+	}
+	return nil
+}
+
+// Bounds of the fields checked by ValidateBasic.
+const (
+	// MaxDataSize limits the payload of a message; larger payloads are split
+	// into chunks, whose reassembly has the same limit.
+	MaxDataSize = DefaultMaxReassembledSize
+	// MaxSignatureSize limits the signature, of any key type validators use.
+	MaxSignatureSize = 128
+	// MaxRoundID is the largest round ID, far beyond any chain's lifetime.
+	MaxRoundID = 1<<31 - 1
+)
+
+// ValidateBasic runs stateless checks on the message, so obviously invalid
+// messages are rejected before reaching a dealer. The signature is checked by
+// the nodes knowing the sender's key.
 func (m *DKGData) ValidateBasic() error {
+	if _, ok := dkgDataTypeNames[m.Type]; !ok {
+		return fmt.Errorf("unknown data type %d", int(m.Type))
+	}
+	if len(m.Addr) != crypto.AddressSize {
+		return fmt.Errorf("invalid address length %d, want %d", len(m.Addr), crypto.AddressSize)
+	}
+	if len(m.Signature) == 0 {
+		return fmt.Errorf("empty signature")
+	}
+	if len(m.Signature) > MaxSignatureSize {
+		return fmt.Errorf("signature of %d bytes exceeds %d", len(m.Signature), MaxSignatureSize)
+	}
+	if len(m.Data) > MaxDataSize {
+		return fmt.Errorf("payload of %d bytes exceeds %d", len(m.Data), MaxDataSize)
+	}
+	if m.RoundID < 0 || m.RoundID > MaxRoundID {
+		return fmt.Errorf("round ID %d is out of range [0, %d]", m.RoundID, MaxRoundID)
+	}
+	if m.ToIndex < 0 || m.NumEntities < 0 {
+		return fmt.Errorf("negative index %d or number of entities %d", m.ToIndex, m.NumEntities)
+	}
+	if m.NumChunks < 0 || m.NumChunks > MaxDataSize {
+		return fmt.Errorf("invalid number of chunks %d", m.NumChunks)
+	}
+	if m.NumChunks == 0 && m.ChunkIndex != 0 || m.NumChunks > 0 && (m.ChunkIndex < 0 || m.ChunkIndex >= m.NumChunks) {
+		return fmt.Errorf("chunk index %d is out of range for %d chunks", m.ChunkIndex, m.NumChunks)
+	}
+	if !ValidKeyPurpose(m.KeyPurpose) {
+		return fmt.Errorf("invalid key purpose %q", m.KeyPurpose)
+	}
 	return nil
 }
@@ -0,0 +1,233 @@
+package alias
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultMaxReassembledSize limits the size of a message reassembled from chunks.
+const DefaultMaxReassembledSize = 4 << 20
+
+// SplitDKGData splits the message into chunks with at most maxChunkSize bytes of Data each.
+// Messages that fit into a single chunk (or a non-positive maxChunkSize) are returned as is.
+func SplitDKGData(data *DKGData, maxChunkSize int) []*DKGData {
+	if maxChunkSize <= 0 || len(data.Data) <= maxChunkSize {
+		return []*DKGData{data}
+	}
+
+	numChunks := (len(data.Data) + maxChunkSize - 1) / maxChunkSize
+	chunks := make([]*DKGData, 0, numChunks)
+	for i := 0; i < numChunks; i++ {
+		end := (i + 1) * maxChunkSize
+		if end > len(data.Data) {
+			end = len(data.Data)
+		}
+		chunks = append(chunks, &DKGData{
+			Type:        data.Type,
+			Addr:        data.Addr,
+			RoundID:     data.RoundID,
+			Data:        data.Data[i*maxChunkSize : end],
+			ToIndex:     data.ToIndex,
+			NumEntities: data.NumEntities,
+			ChunkIndex:  i,
+			NumChunks:   numChunks,
+			KeyPurpose:  data.KeyPurpose,
+		})
+	}
+
+	return chunks
+}
+
+type chunkKey struct {
+	addr     string
+	roundID  int
+	dataType DKGDataType
+	toIndex  int
+}
+
+type pendingChunks struct {
+	parts     map[int][]byte
+	numChunks int
+	chunkSize int // Size of every chunk but the last, zero until one is received.
+	size      int
+	added     time.Time
+}
+
+// DefaultMaxPendingPerSender limits the messages of a sender being reassembled
+// at once.
+const DefaultMaxPendingPerSender = 256
+
+// ChunkBufferOption configures a ChunkBuffer.
+type ChunkBufferOption func(*ChunkBuffer)
+
+// WithMaxPendingPerSender limits the messages of a sender being reassembled at
+// once; chunks of further messages are rejected.
+func WithMaxPendingPerSender(n int) ChunkBufferOption {
+	return func(b *ChunkBuffer) { b.maxPerSender = n }
+}
+
+// WithMaxChunkAge drops the messages not reassembled within the duration.
+// Buffers whose contents must be the same on every node, such as the on-chain
+// ones, must not use it.
+func WithMaxChunkAge(age time.Duration) ChunkBufferOption {
+	return func(b *ChunkBuffer) { b.maxAge = age }
+}
+
+// ChunkBuffer reassembles messages split by SplitDKGData. Chunks of a message
+// are keyed by sender, round, type and recipient, so a sender is expected to
+// send all chunks of a message before the next message with the same key.
+type ChunkBuffer struct {
+	mtx          sync.Mutex
+	maxSize      int
+	maxPerSender int
+	maxAge       time.Duration
+	pending      map[chunkKey]*pendingChunks
+	perSender    map[string]int
+}
+
+func NewChunkBuffer(maxSize int, options ...ChunkBufferOption) *ChunkBuffer {
+	if maxSize <= 0 {
+		maxSize = DefaultMaxReassembledSize
+	}
+	b := &ChunkBuffer{
+		maxSize:      maxSize,
+		maxPerSender: DefaultMaxPendingPerSender,
+		pending:      make(map[chunkKey]*pendingChunks),
+		perSender:    make(map[string]int),
+	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
+}
+
+// Add stores the chunk and returns the reassembled message once all chunks are received.
+// Messages that were not split are returned immediately.
+func (b *ChunkBuffer) Add(msg *DKGData) (*DKGData, error) {
+	if msg.NumChunks == 0 {
+		return msg, nil
+	}
+	if msg.ChunkIndex < 0 || msg.ChunkIndex >= msg.NumChunks {
+		return nil, fmt.Errorf("chunk index %d is out of range [0, %d)", msg.ChunkIndex, msg.NumChunks)
+	}
+	if len(msg.Data) == 0 {
+		return nil, fmt.Errorf("empty chunk")
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.dropExpired()
+
+	key := chunkKey{addr: msg.GetAddrString(), roundID: msg.RoundID, dataType: msg.Type, toIndex: msg.ToIndex}
+	pending, ok := b.pending[key]
+	if !ok {
+		// Every chunk carries at least a byte.
+		if msg.NumChunks > b.maxSize {
+			return nil, fmt.Errorf("too many chunks: %d", msg.NumChunks)
+		}
+		if b.maxPerSender > 0 && b.perSender[key.addr] >= b.maxPerSender {
+			return nil, fmt.Errorf("too many messages of the sender being reassembled: %d", b.perSender[key.addr])
+		}
+		pending = &pendingChunks{parts: make(map[int][]byte), numChunks: msg.NumChunks, added: time.Now()}
+		b.pending[key] = pending
+		b.perSender[key.addr]++
+	}
+	if pending.numChunks != msg.NumChunks {
+		b.drop(key)
+		return nil, fmt.Errorf("inconsistent number of chunks: %d, expected %d", msg.NumChunks, pending.numChunks)
+	}
+	if pending.parts[msg.ChunkIndex] != nil {
+		return nil, nil
+	}
+
+	if msg.ChunkIndex < msg.NumChunks-1 {
+		// All chunks but the last have the same size, which bounds their number.
+		if pending.chunkSize == 0 {
+			pending.chunkSize = len(msg.Data)
+		}
+		if len(msg.Data) != pending.chunkSize {
+			b.drop(key)
+			return nil, fmt.Errorf("inconsistent chunk size: %d, expected %d", len(msg.Data), pending.chunkSize)
+		}
+		if msg.NumChunks-1 > b.maxSize/pending.chunkSize {
+			b.drop(key)
+			return nil, fmt.Errorf("%d chunks of %d bytes exceed max size %d", msg.NumChunks, pending.chunkSize, b.maxSize)
+		}
+	}
+	pending.size += len(msg.Data)
+	if pending.size > b.maxSize {
+		b.drop(key)
+		return nil, fmt.Errorf("reassembled message exceeds max size %d", b.maxSize)
+	}
+	pending.parts[msg.ChunkIndex] = append([]byte{}, msg.Data...)
+	if len(pending.parts) < msg.NumChunks {
+		return nil, nil
+	}
+	b.drop(key)
+
+	data := make([]byte, 0, pending.size)
+	for i := 0; i < pending.numChunks; i++ {
+		data = append(data, pending.parts[i]...)
+	}
+
+	return &DKGData{
+		Type:        msg.Type,
+		Addr:        msg.Addr,
+		RoundID:     msg.RoundID,
+		Data:        data,
+		ToIndex:     msg.ToIndex,
+		NumEntities: msg.NumEntities,
+		KeyPurpose:  msg.KeyPurpose,
+	}, nil
+}
+
+// DropRoundsBefore drops the chunks of the rounds before the round.
+func (b *ChunkBuffer) DropRoundsBefore(roundID int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for key := range b.pending {
+		if key.roundID < roundID {
+			b.drop(key)
+		}
+	}
+}
+
+// DropRound drops the chunks of the round.
+func (b *ChunkBuffer) DropRound(roundID int) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	for key := range b.pending {
+		if key.roundID == roundID {
+			b.drop(key)
+		}
+	}
+}
+
+// Pending returns the number of messages being reassembled.
+func (b *ChunkBuffer) Pending() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.pending)
+}
+
+func (b *ChunkBuffer) dropExpired() {
+	if b.maxAge <= 0 {
+		return
+	}
+	for key, pending := range b.pending {
+		if time.Since(pending.added) > b.maxAge {
+			b.drop(key)
+		}
+	}
+}
+
+func (b *ChunkBuffer) drop(key chunkKey) {
+	if _, ok := b.pending[key]; !ok {
+		return
+	}
+	delete(b.pending, key)
+	if b.perSender[key.addr]--; b.perSender[key.addr] <= 0 {
+		delete(b.perSender, key.addr)
+	}
+}
@@ -0,0 +1,141 @@
+package alias
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/tendermint/tendermint/crypto"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Versions of the canonical sign-byte encoding of DKGData. The layout of a
+// version must never change; a new layout needs a new version.
+const (
+	// SignBytesV1 encodes the message alone, without its key purpose.
+	SignBytesV1 byte = 1
+	// SignBytesV2 prefixes the message with its domain: the protocol, the
+	// chain ID and the purpose of the message, so its signature can't be
+	// reused by another protocol, on another chain, for another type or for
+	// another group key. Messages of the default key keep the layout they
+	// had before key purposes.
+	SignBytesV2 byte = 2
+
+	// SignBytesVersion is the latest version.
+	SignBytesVersion = SignBytesV2
+)
+
+// SignBytesProtocol tags the sign bytes of DKG messages from version 2 on.
+const SignBytesProtocol = "dkglib/DKGData"
+
+// SignDomain is the domain DKG messages are signed and verified in: the chain
+// ID and the sign-byte version. Signatures made in another domain don't verify,
+// so all nodes of a network must use the same; instances in one process may use
+// different ones.
+type SignDomain struct {
+	ChainID string
+	Version byte
+}
+
+// NewSignDomain returns the domain of the chain and the version, or an error if
+// the version is unsupported.
+func NewSignDomain(chainID string, version byte) (SignDomain, error) {
+	if !ValidSignBytesVersion(version) {
+		return SignDomain{}, fmt.Errorf("unsupported sign bytes version %d", version)
+	}
+	return SignDomain{ChainID: chainID, Version: version}, nil
+}
+
+// DefaultSignDomain returns the domain of the chain with the latest version.
+func DefaultSignDomain(chainID string) SignDomain {
+	return SignDomain{ChainID: chainID, Version: SignBytesVersion}
+}
+
+// SignBytes returns the canonical encoding of the message in the domain.
+func (d SignDomain) SignBytes(m *DKGData) []byte {
+	return encodeSignBytes(m, d.ChainID, d.Version)
+}
+
+// Verify reports whether the message is signed by the key in the domain.
+func (d SignDomain) Verify(key crypto.PubKey, m *DKGData) bool {
+	return key.VerifyBytes(d.SignBytes(m), m.Signature)
+}
+
+// Signer returns the message for PrivValidator.SignData to sign in the domain,
+// whatever the chain ID SignData is given.
+func (d SignDomain) Signer(m *DKGData) tmtypes.DataSigner {
+	return domainSigner{domain: d, m: m}
+}
+
+type domainSigner struct {
+	domain SignDomain
+	m      *DKGData
+}
+
+func (s domainSigner) SignBytes(string) []byte {
+	return s.domain.SignBytes(s.m)
+}
+
+func (s domainSigner) SetSignature(sig []byte) {
+	s.m.SetSignature(sig)
+}
+
+// ValidSignBytesVersion reports whether the version is supported.
+func ValidSignBytesVersion(version byte) bool {
+	return version == SignBytesV1 || version == SignBytesV2
+}
+
+// encodeSignBytes encodes the message in the following layout, independent of
+// the codec and of the build:
+//
+//	version      1 byte
+//	protocol     uint32 length followed by SignBytesProtocol  (version 2)
+//	chain ID     uint32 length followed by the bytes          (version 2)
+//	purpose      uint32 length followed by the type name,     (version 2)
+//	             prefixed with the key purpose and '/' if any
+//	Type         uint32
+//	RoundID      int64, the epoch the round's key serves
+//	ToIndex      int64
+//	NumEntities  int64
+//	ChunkIndex   int64
+//	NumChunks    int64
+//	Addr         uint32 length followed by the bytes
+//	Data         uint32 length followed by the bytes
+//
+// All integers are big-endian; the signature is not included.
+func encodeSignBytes(m *DKGData, chainID string, version byte) []byte {
+	buf := make([]byte, 0, 1+4+5*8+4+len(m.Addr)+4+len(m.Data))
+	buf = append(buf, version)
+	if version >= SignBytesV2 {
+		buf = appendBytes(buf, []byte(SignBytesProtocol))
+		buf = appendBytes(buf, []byte(chainID))
+		purpose := m.Type.String()
+		if m.KeyPurpose != "" {
+			purpose = m.KeyPurpose + "/" + purpose
+		}
+		buf = appendBytes(buf, []byte(purpose))
+	}
+	buf = appendUint32(buf, uint32(m.Type))
+	for _, v := range []int{m.RoundID, m.ToIndex, m.NumEntities, m.ChunkIndex, m.NumChunks} {
+		buf = appendUint64(buf, uint64(int64(v)))
+	}
+	buf = appendBytes(buf, m.Addr)
+	buf = appendBytes(buf, m.Data)
+	return buf
+}
+
+func appendBytes(buf []byte, b []byte) []byte {
+	buf = appendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
@@ -0,0 +1,57 @@
+package alias
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/goldentest"
+)
+
+// signBytesMessage has every field set, so a change of any field's layout
+// changes the sign bytes.
+func signBytesMessage() *DKGData {
+	return &DKGData{
+		Type:        DKGDeal,
+		Addr:        []byte{0x01, 0x02, 0x03, 0x04},
+		RoundID:     7,
+		Data:        []byte("deal"),
+		ToIndex:     2,
+		NumEntities: 3,
+		Signature:   []byte("not signed"),
+		ChunkIndex:  1,
+		NumChunks:   4,
+	}
+}
+
+// TestSignBytesGolden pins the sign bytes of every version; a layout must
+// never change once released.
+func TestSignBytesGolden(t *testing.T) {
+	purposed := signBytesMessage()
+	purposed.KeyPurpose = "beacon"
+	negative := signBytesMessage()
+	negative.RoundID, negative.ToIndex = -1, -2
+
+	for _, tc := range []struct {
+		name  string
+		bytes []byte
+	}{
+		{"sign_bytes_v1", signBytesMessage().SignBytesIn("dkg-chain", SignBytesV1)},
+		{"sign_bytes_v2", signBytesMessage().SignBytesIn("dkg-chain", SignBytesV2)},
+		{"sign_bytes_v2_empty_chain", signBytesMessage().SignBytesIn("", SignBytesV2)},
+		{"sign_bytes_v2_key_purpose", purposed.SignBytesIn("dkg-chain", SignBytesV2)},
+		{"sign_bytes_v2_negative", negative.SignBytesIn("dkg-chain", SignBytesV2)},
+		{"content_bytes", signBytesMessage().ContentBytes()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			goldentest.Check(t, tc.name, tc.bytes)
+		})
+	}
+}
+
+func TestSignBytesIgnoreSignature(t *testing.T) {
+	unsigned := signBytesMessage()
+	unsigned.Signature = nil
+	if !bytes.Equal(signBytesMessage().SignBytes("dkg-chain"), unsigned.SignBytes("dkg-chain")) {
+		t.Fatal("sign bytes depend on the signature")
+	}
+}
@@ -0,0 +1,37 @@
+package basic
+
+import (
+	"github.com/corestario/dkglib/lib/onChain"
+	dkg "github.com/corestario/dkglib/lib/types"
+)
+
+// anchorChain anchors epochs through the on-chain DKG, which is initialized on
+// first use, see blacklistChain.
+type anchorChain struct {
+	basic *DKGBasic
+}
+
+var _ dkg.AnchorChain = &anchorChain{}
+
+func (c *anchorChain) onChain() (*onChain.OnChainDKG, error) {
+	if err := c.basic.initOnChain(); err != nil {
+		return nil, err
+	}
+	return c.basic.onChain, nil
+}
+
+func (c *anchorChain) PublishEpochAnchor(attestation *dkg.RoundAttestation) error {
+	onChainDKG, err := c.onChain()
+	if err != nil {
+		return err
+	}
+	return onChainDKG.PublishEpochAnchor(attestation)
+}
+
+func (c *anchorChain) EpochAnchors(roundID int) ([]*dkg.RoundAttestation, error) {
+	onChainDKG, err := c.onChain()
+	if err != nil {
+		return nil, err
+	}
+	return onChainDKG.EpochAnchors(roundID)
+}
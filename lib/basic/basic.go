@@ -3,18 +3,21 @@ package basic
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/corestario/cosmos-utils/client/authtypes"
-	"github.com/corestario/cosmos-utils/client/context"
 	"github.com/corestario/cosmos-utils/client/utils"
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/logging"
 	"github.com/corestario/dkglib/lib/msgs"
 	"github.com/corestario/dkglib/lib/offChain"
 	"github.com/corestario/dkglib/lib/onChain"
 	dkg "github.com/corestario/dkglib/lib/types"
 	"github.com/cosmos/cosmos-sdk/client/keys"
-	sdk "github.com/cosmos/cosmos-sdk/types"
 	authTypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 	"github.com/tendermint/go-amino"
 	tmtypes "github.com/tendermint/tendermint/alias"
@@ -28,11 +31,12 @@ type DKGBasic struct {
 	offChain      *offChain.OffChainDKG
 	onChain       *onChain.OnChainDKG
 	mtx           sync.RWMutex
+	initMtx       sync.Mutex // Serializes the lazy initialization of the on-chain DKG.
 	isOnChain     bool
-	logger        log.Logger
+	logger        logging.Logger
 	OnChainParams OnChainParams
 	blockNotifier chan bool
-	roundID       int
+	roundCounter  *dkg.RoundCounter
 }
 
 type OnChainParams struct {
@@ -41,10 +45,34 @@ type OnChainParams struct {
 	NodeEndpoint string
 	HomeString   string
 	PassPhrase   string
+
+	// ClientOptions configure the node RPC connection, e.g. TLS and authentication.
+	ClientOptions []client.Option
+	// FeePayer, if set, pays the fees of on-chain DKG transactions.
+	FeePayer *client.FeePayer
+	// WitnessEndpoint, if set, is the RPC node the queries of on-chain rounds
+	// are cross-checked with, see onChain.WithCrossCheck.
+	WitnessEndpoint string
+	// VerifyConcurrency, if positive, is the number of workers verifying the
+	// signatures of on-chain messages, see onChain.WithVerifyConcurrency.
+	VerifyConcurrency int
 }
 
 var _ dkg.DKG = &DKGBasic{}
-
+var _ dkg.Healther = &DKGBasic{}
+var _ dkg.Snapshotter = &DKGBasic{}
+var _ dkg.StateSyncer = &DKGBasic{}
+var _ dkg.AttestationQuerier = &DKGBasic{}
+var _ dkg.VerifierApprover = &DKGBasic{}
+var _ dkg.Pauser = &DKGBasic{}
+var _ dkg.ForensicsProvider = &DKGBasic{}
+var _ dkg.RoundTrigger = &DKGBasic{}
+var _ dkg.LoserReporter = &DKGBasic{}
+var _ dkg.TimingSampler = &DKGBasic{}
+
+// NewDKGBasic creates a DKG that falls back to on-chain rounds. The codec must
+// have the auth and sdk types and the dkglib messages (see msgs.RegisterCodec)
+// registered; a nil codec is replaced with msgs.MakeCodec.
 func NewDKGBasic(
 	evsw events.EventSwitch,
 	cdc *amino.Codec,
@@ -54,9 +82,14 @@ func NewDKGBasic(
 	homeString string,
 	options ...offChain.DKGOption,
 ) (dkg.DKG, error) {
-	logger := log.NewTMLogger(os.Stdout)
+	if cdc == nil {
+		cdc = msgs.MakeCodec()
+	}
+	logger := logging.NewTMLogger(log.NewTMLogger(os.Stdout))
+	offChainDKG := offChain.NewOffChainDKG(evsw, chainID, options...)
 	d := &DKGBasic{
-		offChain:      offChain.NewOffChainDKG(evsw, chainID, options...),
+		offChain:      offChainDKG,
+		roundCounter:  offChainDKG.RoundCounter(),
 		logger:        logger,
 		blockNotifier: make(chan bool, 2),
 		OnChainParams: OnChainParams{
@@ -67,6 +100,12 @@ func NewDKGBasic(
 			PassPhrase:   passPhrase,
 		},
 	}
+	if offChainDKG.Blacklist() != nil && offChainDKG.BlacklistChain() == nil {
+		offChainDKG.SetBlacklistChain(&blacklistChain{basic: d})
+	}
+	if offChainDKG.Anchoring() && offChainDKG.AnchorChain() == nil {
+		offChainDKG.SetAnchorChain(&anchorChain{basic: d})
+	}
 	return d, nil
 }
 
@@ -110,19 +149,25 @@ func (m *DKGBasic) HandleOffChainShare(
 			return false
 		}
 
+		roundID := m.roundCounter.Current() + 1
 		err = m.onChain.StartRound(
 			validators,
 			m.offChain.GetPrivValidator(),
 			&MockFirer{},
 			m.logger,
-			m.roundID,
+			roundID,
 		)
+		if _, ok := err.(*dkg.CommitteeError); ok {
+			m.logger.Error("On-chain DKG round not started", "error", err)
+			m.mtx.Lock()
+			m.isOnChain = false
+			m.mtx.Unlock()
+			return false
+		}
 		if err != nil {
 			m.logger.Info("On-chain DKG start round failed", "error", err)
 			panic(err)
 		}
-		roundID := m.roundID
-		m.roundID++
 
 		go func() {
 			for {
@@ -170,14 +215,145 @@ func (m *DKGBasic) MsgQueue() chan *dkg.DKGDataMessage {
 	return m.offChain.MsgQueue()
 }
 
+// GetLosers returns the losers of the off-chain and on-chain rounds, ordered by
+// address; a validator excluded from both is returned once.
 func (m *DKGBasic) GetLosers() []*tmtypes.Validator {
-	return append(m.offChain.GetLosers(), m.onChain.GetLosers()...)
+	return dkg.LoserValidators(m.GetLosersWithReasons())
+}
+
+func (m *DKGBasic) GetLosersWithReasons() []*dkg.Loser {
+	return dkg.SortLosers(append(m.offChain.GetLosersWithReasons(), m.onChain.GetLosersWithReasons()...))
 }
 
 func (m *DKGBasic) StartDKGRound(validators *tmtypes.ValidatorSet) error {
 	return m.offChain.StartDKGRound(validators)
 }
 
+// TriggerRound starts an off-chain round with the params, see OffChainDKG.TriggerRound.
+func (m *DKGBasic) TriggerRound(params dkg.TriggerParams) (int, error) {
+	return m.offChain.TriggerRound(params)
+}
+
+// RequestMissing asks the peers for missed messages, see OffChainDKG.RequestMissing.
+func (m *DKGBasic) RequestMissing(roundID int, dataType alias.DKGDataType, from crypto.Address) error {
+	return m.offChain.RequestMissing(roundID, dataType, from)
+}
+
+// ApplyAdjudication applies externally adjudicated exclusions to the round,
+// on chain once the node fell back to on-chain rounds.
+func (m *DKGBasic) ApplyAdjudication(roundID int, decisions []dkg.Adjudication) error {
+	m.mtx.RLock()
+	onChainDKG, isOnChain := m.onChain, m.isOnChain
+	m.mtx.RUnlock()
+	if isOnChain && onChainDKG != nil {
+		return onChainDKG.ApplyAdjudication(roundID, decisions)
+	}
+	return m.offChain.ApplyAdjudication(roundID, decisions)
+}
+
+func (m *DKGBasic) Health() dkg.HealthStatus {
+	status := m.offChain.Health()
+	status.OnChain = m.IsOnChain()
+	return status
+}
+
+// Snapshot describes the off-chain rounds followed by the on-chain round.
+func (m *DKGBasic) Snapshot() []*dkg.RoundInfo {
+	out := m.offChain.Snapshot()
+	m.mtx.RLock()
+	onChainDKG := m.onChain
+	m.mtx.RUnlock()
+	if onChainDKG != nil {
+		out = append(out, onChainDKG.Snapshot()...)
+	}
+	return out
+}
+
+// TimingSamples returns the snapshots of the last finished off-chain rounds;
+// on-chain rounds don't measure the peers' latency.
+func (m *DKGBasic) TimingSamples() []*dkg.RoundInfo {
+	return m.offChain.TimingSamples()
+}
+
+// ExportState exports the off-chain DKG state for state sync; on-chain rounds are
+// tracked by the chain itself.
+func (m *DKGBasic) ExportState() ([]byte, error) {
+	return m.offChain.ExportState()
+}
+
+func (m *DKGBasic) RestoreState(data []byte) error {
+	return m.offChain.RestoreState(data)
+}
+
+// ExportUpgradeState exports the off-chain DKG state to carry across a binary
+// upgrade, see OffChainDKG.ExportUpgradeState.
+func (m *DKGBasic) ExportUpgradeState(
+	req *dkg.OperationRequest,
+	signatures []dkg.OperatorSignature,
+	passphrase []byte,
+	options ...blsShare.KeystoreOption,
+) ([]byte, error) {
+	return m.offChain.ExportUpgradeState(req, signatures, passphrase, options...)
+}
+
+func (m *DKGBasic) RestoreUpgradeState(data, passphrase []byte) error {
+	return m.offChain.RestoreUpgradeState(data, passphrase)
+}
+
+// ExportShare exports the key share of the current off-chain verifier, see OffChainDKG.ExportShare.
+func (m *DKGBasic) ExportShare(
+	req *dkg.OperationRequest,
+	signatures []dkg.OperatorSignature,
+	passphrase []byte,
+	options ...blsShare.KeystoreOption,
+) (*blsShare.EncryptedBLSShare, error) {
+	return m.offChain.ExportShare(req, signatures, passphrase, options...)
+}
+
+// GetRoundAttestation returns the attestation of a completed off-chain round;
+// on-chain rounds are attested by the chain itself.
+func (m *DKGBasic) GetRoundAttestation(roundID int) (*dkg.RoundAttestation, error) {
+	return m.offChain.GetRoundAttestation(roundID)
+}
+
+// ForensicBundle returns the forensic bundle of a failed off-chain round, see offChain.WithForensics.
+func (m *DKGBasic) ForensicBundle(roundID int) (*dkg.ForensicBundle, error) {
+	return m.offChain.ForensicBundle(roundID)
+}
+
+// StagedVerifier returns the off-chain verifier awaiting approval, see offChain.WithVerifierApproval.
+func (m *DKGBasic) StagedVerifier() *dkg.StagedVerifier {
+	return m.offChain.StagedVerifier()
+}
+
+func (m *DKGBasic) ApproveVerifier(roundID int) error {
+	return m.offChain.ApproveVerifier(roundID)
+}
+
+// MigrateShare moves the node's off-chain DKG shares to the new private
+// validator, see OffChainDKG.MigrateShare.
+func (m *DKGBasic) MigrateShare(
+	req *dkg.OperationRequest,
+	signatures []dkg.OperatorSignature,
+	newPV tmtypes.PrivValidator,
+) (*dkg.ShareMigration, error) {
+	return m.offChain.MigrateShare(req, signatures, newPV)
+}
+
+// Pause takes the node out of the off-chain rounds started from now on, see
+// OffChainDKG.Pause. An on-chain round already running is not affected.
+func (m *DKGBasic) Pause() {
+	m.offChain.Pause()
+}
+
+func (m *DKGBasic) Resume() {
+	m.offChain.Resume()
+}
+
+func (m *DKGBasic) Paused() bool {
+	return m.offChain.Paused()
+}
+
 func (m *DKGBasic) IsOnChain() bool {
 	m.mtx.RLock()
 	defer m.mtx.RUnlock()
@@ -185,15 +361,18 @@ func (m *DKGBasic) IsOnChain() bool {
 }
 
 func (m *DKGBasic) initOnChain() error {
+	m.initMtx.Lock()
+	defer m.initMtx.Unlock()
 	if m.onChain != nil {
 		return nil
 	}
 
 	m.logger.Info("Init on-chain DKG")
 
-	cliCtx, err := context.NewContextWithDelay(m.OnChainParams.ChainID, m.OnChainParams.NodeEndpoint, m.OnChainParams.HomeString)
+	cliCtx, err := client.NewContext(m.OnChainParams.ChainID, m.OnChainParams.NodeEndpoint, m.OnChainParams.HomeString,
+		append([]client.Option{client.WithLogger(m.logger)}, m.OnChainParams.ClientOptions...)...)
 	if err != nil {
-		m.logger.Error("Init on-chain DKG error", "function", "NewContextWithDelay", "error", err)
+		m.logger.Error("Init on-chain DKG error", "function", "NewContext", "error", err)
 		return err
 	}
 
@@ -217,12 +396,10 @@ func (m *DKGBasic) initOnChain() error {
 		WithPassphrase(m.OnChainParams.PassPhrase).
 		WithFromAddress(keysList[0].GetAddress()).
 		WithFrom(keysList[0].GetName())
-	authTypes.RegisterCodec(m.OnChainParams.Cdc)
-	m.OnChainParams.Cdc.RegisterConcrete(msgs.MsgSendDKGData{}, msgs.MsgSendDKGDataTypeName, nil)
-	sdk.RegisterCodec(m.OnChainParams.Cdc)
 	cliCtx.WithCodec(m.OnChainParams.Cdc)
 
-	accRetriever := authTypes.NewAccountRetriever(cliCtx)
+	// The on-chain DKG's broadcasts start with the account fetched here.
+	accRetriever := client.NewCachedAccountRetriever(authTypes.NewAccountRetriever(cliCtx), client.DefaultAccountCacheTTL)
 	accNumber, accSequence, err := accRetriever.GetAccountNumberSequence(keysList[0].GetAddress())
 	if err != nil {
 		m.logger.Error("Init on-chain DKG error", "function", "GetAccountNumberSequence", "error", err)
@@ -242,7 +419,30 @@ func (m *DKGBasic) initOnChain() error {
 		nil,
 	).WithKeybase(kb)
 
-	m.onChain = onChain.NewOnChainDKG(cliCtx, &txBldr)
+	options := []onChain.OnChainOption{
+		onChain.WithRoundCounter(m.roundCounter),
+		onChain.WithExternalParticipants(m.offChain.ExternalParticipants()...),
+		onChain.WithMinValidators(m.offChain.MinValidators()),
+		onChain.WithMiddleware(m.offChain.Middlewares()...),
+		onChain.WithEventTaps(m.offChain.EventTaps()),
+		onChain.WithAccountRetriever(accRetriever),
+		onChain.WithVerifyConcurrency(m.OnChainParams.VerifyConcurrency),
+		onChain.WithProofHook(m.offChain.ProofHook()),
+		onChain.WithCommitteeLookup(m.offChain.RoundCommittee),
+	}
+	if m.OnChainParams.FeePayer != nil {
+		options = append(options, onChain.WithFeePayer(m.OnChainParams.FeePayer))
+	}
+	if m.OnChainParams.WitnessEndpoint != "" {
+		witness, err := client.NewContext(m.OnChainParams.ChainID, m.OnChainParams.WitnessEndpoint, filepath.Join(m.OnChainParams.HomeString, "witness"),
+			append([]client.Option{client.WithLogger(m.logger)}, m.OnChainParams.ClientOptions...)...)
+		if err != nil {
+			m.logger.Error("Init on-chain DKG error", "function", "NewContext", "witness", m.OnChainParams.WitnessEndpoint, "error", err)
+			return err
+		}
+		options = append(options, onChain.WithCrossCheck(witness))
+	}
+	m.onChain = onChain.NewOnChainDKG(cliCtx, &txBldr, options...)
 	return nil
 }
 
@@ -0,0 +1,44 @@
+package basic
+
+import (
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/onChain"
+	dkg "github.com/corestario/dkglib/lib/types"
+)
+
+// blacklistChain exchanges blacklist votes through the on-chain DKG, which is
+// initialized on first use, so off-chain rounds only connect to the node when
+// a blacklist is configured.
+type blacklistChain struct {
+	mtx   sync.Mutex
+	basic *DKGBasic
+}
+
+var _ dkg.BlacklistChain = &blacklistChain{}
+
+func (c *blacklistChain) onChain() (*onChain.OnChainDKG, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.basic.initOnChain(); err != nil {
+		return nil, err
+	}
+	return c.basic.onChain, nil
+}
+
+func (c *blacklistChain) PublishBlacklistVote(vote *alias.DKGData) error {
+	onChainDKG, err := c.onChain()
+	if err != nil {
+		return err
+	}
+	return onChainDKG.PublishBlacklistVote(vote)
+}
+
+func (c *blacklistChain) BlacklistVotes(roundID int, height int64) ([]*alias.DKGData, error) {
+	onChainDKG, err := c.onChain()
+	if err != nil {
+		return nil, err
+	}
+	return onChainDKG.BlacklistVotes(roundID, height)
+}
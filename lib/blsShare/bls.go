@@ -11,6 +11,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"sync"
+	"time"
 
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing/bn256"
@@ -30,8 +31,10 @@ const (
 	DefaultBLSVerifierPrivKey      = "I/+HAwEBCFByaVNoYXJlAf+IAAECAQFJAQQAAQFWAf+KAAAAEv+JBgEBBlNjYWxhcgH/igAAACX/iAIgTx30WSwv2cXmC0ybf5OhX9RIHMog0dss+ecmfgAeVOIA"
 )
 
-var TestnetMasterPubKey = "Df+DAgEC/4QAAf+CAAAR/4EGAQEFUG9pbnQB/4IAAAD+AYr/hAAD/4BZ1TvrtwmGJAEd7LX/6ywfWPCDstuv+THtNjdGaddQoVMmZ66itgzJ7WKpH9m8zSQrG1KgzpVMgrlUsh9g8n39YUCVYfKap+DCiN0pitOT7RoIYOZf2KVDZN1z4xg8VEsq03/C0PRbIFOFybsBYUhsesA1EPK94Duh/JMgJry2g/+ATxakEQPACdVDV8hf6La7w4KO9uGSt3aXS1Qx0YGQLzUVbBCl13Ii33daGLro8EPK/ItTTadwoGrpFLnpfnZhVmqrFojVMZGX+WePrKy5qPHrp2rIagq0J9AqmGcYAHRCEjWxsuHWotZZRBv0L+wy5zdBIMVgLT40J/7nY6qvUVj/gBJApeCMkB08+wuSKSd9/IsIJ7FfxRS6wM9qazxcKCSgXkvcSRtClB9a7awKkit2aZHYa4y46K9gZdOTWChiXq9oo4HWXGsviadnB612HbrCp9UJLkaWyIRA/tylJGFMDY109FS+Bg6XlyT+QwirN7rd/AB5ju7IU0CkVUsM74tI"
-var TestnetShares = map[int]*BLSShareJSON{
+// TestnetMasterPubKey is the master public key of testnetShares.
+const TestnetMasterPubKey = "Df+DAgEC/4QAAf+CAAAR/4EGAQEFUG9pbnQB/4IAAAD+AYr/hAAD/4BZ1TvrtwmGJAEd7LX/6ywfWPCDstuv+THtNjdGaddQoVMmZ66itgzJ7WKpH9m8zSQrG1KgzpVMgrlUsh9g8n39YUCVYfKap+DCiN0pitOT7RoIYOZf2KVDZN1z4xg8VEsq03/C0PRbIFOFybsBYUhsesA1EPK94Duh/JMgJry2g/+ATxakEQPACdVDV8hf6La7w4KO9uGSt3aXS1Qx0YGQLzUVbBCl13Ii33daGLro8EPK/ItTTadwoGrpFLnpfnZhVmqrFojVMZGX+WePrKy5qPHrp2rIagq0J9AqmGcYAHRCEjWxsuHWotZZRBv0L+wy5zdBIMVgLT40J/7nY6qvUVj/gBJApeCMkB08+wuSKSd9/IsIJ7FfxRS6wM9qazxcKCSgXkvcSRtClB9a7awKkit2aZHYa4y46K9gZdOTWChiXq9oo4HWXGsviadnB612HbrCp9UJLkaWyIRA/tylJGFMDY109FS+Bg6XlyT+QwirN7rd/AB5ju7IU0CkVUsM74tI"
+
+var testnetShares = map[int]BLSShareJSON{
 	0: {
 		Pub:  "I/+FAwEBCFB1YlNoYXJlAf+GAAECAQFJAQQAAQFWAf+CAAAAEf+BBgEBBVBvaW50Af+CAAAA/4b/hgL/gA8DxUCeyJZQHHyK2APRZLugem1ypJ5bnaI1f/RaYUoFexFbjTNxHh5pnG1Y9sKZh7wXo7XYcEfbdgxFwgm/EU5VhyqxQzDdWwF98cUewz38j2rvm+UZVJjqnkKfW0f3r0MK4mfG17g5ohzn4AwwYkCSBk6XrzY7VP0j0q4qPk6gAA==",
 		Priv: "I/+HAwEBCFByaVNoYXJlAf+IAAECAQFJAQQAAQFWAf+KAAAAEv+JBgEBBlNjYWxhcgH/igAAACX/iAIgfHx7085Ms2rxbcI8LcNaljtXkun5cIrAdik7pkd334sA",
@@ -50,6 +53,16 @@ var TestnetShares = map[int]*BLSShareJSON{
 	},
 }
 
+// TestnetShares returns the key shares of the testnet validators by index.
+func TestnetShares() map[int]*BLSShareJSON {
+	shares := make(map[int]*BLSShareJSON, len(testnetShares))
+	for i, share := range testnetShares {
+		share := share
+		shares[i] = &share
+	}
+	return shares
+}
+
 type BLSKeyring struct {
 	T            int               // Threshold
 	N            int               // Number of shares
@@ -225,6 +238,27 @@ func LoadPubKey(base64Key string, numHolders int) (*share.PubPoly, error) {
 	return share.NewPubPoly(bn256.NewSuite().G2(), nil, commits), nil
 }
 
+// VerifierWrapper is implemented by verifiers wrapping another one, e.g.
+// types.UsageVerifier, so UnwrapVerifier finds the BLS verifier beneath.
+type VerifierWrapper interface {
+	UnwrapVerifier() interface{}
+}
+
+// UnwrapVerifier returns the BLS verifier beneath the wrappers of the
+// verifier, see VerifierWrapper, or false if there is none.
+func UnwrapVerifier(verifier interface{}) (*BLSVerifier, bool) {
+	for {
+		switch v := verifier.(type) {
+		case *BLSVerifier:
+			return v, v != nil
+		case VerifierWrapper:
+			verifier = v.UnwrapVerifier()
+		default:
+			return nil, false
+		}
+	}
+}
+
 type BLSVerifier struct {
 	Keypair      *BLSShare // This verifier's BLSShare.
 	masterPubKey *share.PubPoly
@@ -232,6 +266,7 @@ type BLSVerifier struct {
 	suiteG2      *bn256.Suite
 	t            int
 	n            int
+	metrics      VerifierMetrics
 }
 
 func NewBLSVerifier(masterPubKey *share.PubPoly, sh *BLSShare, t, n int) *BLSVerifier {
@@ -245,12 +280,45 @@ func NewBLSVerifier(masterPubKey *share.PubPoly, sh *BLSShare, t, n int) *BLSVer
 	}
 }
 
+// NewBLSPublicVerifier creates a verifier without a key share, which can verify
+// but not produce signatures.
+func NewBLSPublicVerifier(masterPubKey *share.PubPoly, t, n int) *BLSVerifier {
+	return NewBLSVerifier(masterPubKey, nil, t, n)
+}
+
+// SetMetrics sets the sink of the verifier's measurements, nil discarding
+// them. It must be set before the verifier is used.
+func (m *BLSVerifier) SetMetrics(metrics VerifierMetrics) {
+	m.metrics = metrics
+}
+
+func (m *BLSVerifier) sink() VerifierMetrics {
+	if m.metrics == nil {
+		return NopVerifierMetrics{}
+	}
+	return m.metrics
+}
+
 func (m *BLSVerifier) IsNil() bool {
 	return m == nil
 }
 
+func (m *BLSVerifier) MasterPubKey() *share.PubPoly {
+	return m.masterPubKey
+}
+
+// Threshold returns the number of shares required to recover a signature and the total number of shares.
+func (m *BLSVerifier) Threshold() (t, n int) {
+	return m.t, m.n
+}
+
 func (m *BLSVerifier) Sign(data []byte) ([]byte, error) {
+	if m.Keypair == nil {
+		return nil, fmt.Errorf("failed to sign random data: verifier has no key share")
+	}
+	started := time.Now()
 	sig, err := tbls.Sign(m.suiteG1, m.Keypair.Priv, data)
+	m.sink().ObserveSignShare(time.Since(started))
 	if err != nil {
 		return nil, fmt.Errorf("failed to sing random data with key %v %v with error %v", m.Keypair.Pub, data, err)
 	}
@@ -261,6 +329,7 @@ func (m *BLSVerifier) Sign(data []byte) ([]byte, error) {
 func (m *BLSVerifier) VerifyRandomShare(addr string, prevRandomData, currRandomData []byte) error {
 	// Check that the signature itself is correct for this validator.
 	if err := tbls.Verify(m.suiteG1, m.masterPubKey, prevRandomData, currRandomData); err != nil {
+		m.sink().VerifyFailed(FailureInvalidShare)
 		return fmt.Errorf("signature of share is corrupt: %v. prev random: %v; current random: %v", err, prevRandomData, currRandomData)
 	}
 
@@ -269,6 +338,7 @@ func (m *BLSVerifier) VerifyRandomShare(addr string, prevRandomData, currRandomD
 
 func (m *BLSVerifier) VerifyRandomData(prevRandomData, currRandomData []byte) error {
 	if err := bls.Verify(m.suiteG1, m.masterPubKey.Commit(), prevRandomData, currRandomData); err != nil {
+		m.sink().VerifyFailed(FailureInvalidSignature)
 		return fmt.Errorf("signature is corrupt: %v. prev random: %v; current random: %v", err, prevRandomData, currRandomData)
 	}
 
@@ -291,8 +361,15 @@ func (m *BLSVerifier) Recover(msg []byte, precommits []BLSSigner) ([]byte, error
 		sigs = append(sigs, precommit.GetBLSSignature())
 	}
 
+	started := time.Now()
 	aggrSig, err := tbls.Recover(m.suiteG1, m.masterPubKey, msg, sigs, m.t, m.n)
+	m.sink().ObserveRecover(time.Since(started), len(sigs))
 	if err != nil {
+		if len(sigs) < m.t {
+			m.sink().VerifyFailed(FailureNotEnoughShares)
+		} else {
+			m.sink().VerifyFailed(FailureRecover)
+		}
 		return nil, fmt.Errorf("failed to recover aggregate signature: %v", err)
 	}
 
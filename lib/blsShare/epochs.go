@@ -0,0 +1,272 @@
+package blsShare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	storeEpoch = "epoch-%d"
+
+	// DefaultRetainedEpochs is the number of epochs whose shares are kept.
+	DefaultRetainedEpochs = 8
+)
+
+// EpochKeystore keeps the shares of the last epochs in a directory, one
+// epoch-<epoch> subdirectory per epoch laid out as DumpBLSKeyring does, so
+// signatures of past epochs can still be produced and verified. The shares and
+// the master public key of the flat layout written by DumpBLSKeyring are read
+// as epoch 0.
+type EpochKeystore struct {
+	mtx        sync.Mutex
+	dir        string
+	retain     int
+	passphrase []byte
+	options    []KeystoreOption
+
+	integrityKey []byte // Key of the share checksums, see WithIntegrityKey.
+	node         string
+	tamperAlert  func(err *ShareIntegrityError)
+}
+
+// EpochKeystoreOption sets an optional parameter of the EpochKeystore.
+type EpochKeystoreOption func(*EpochKeystore)
+
+// WithRetainedEpochs sets the number of most recent epochs kept when a share is
+// saved; zero keeps every epoch.
+func WithRetainedEpochs(epochs int) EpochKeystoreOption {
+	return func(ks *EpochKeystore) { ks.retain = epochs }
+}
+
+// WithEpochPassphrase encrypts the saved shares with the passphrase, see
+// SaveEncryptedBLSShare, and decrypts the loaded ones.
+func WithEpochPassphrase(passphrase []byte, options ...KeystoreOption) EpochKeystoreOption {
+	return func(ks *EpochKeystore) { ks.passphrase, ks.options = passphrase, options }
+}
+
+func NewEpochKeystore(dir string, options ...EpochKeystoreOption) (*EpochKeystore, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("failed to open keystore directory: %v", err)
+	}
+	ks := &EpochKeystore{dir: dir, retain: DefaultRetainedEpochs}
+	for _, option := range options {
+		option(ks)
+	}
+	if ks.retain < 0 {
+		return nil, fmt.Errorf("retained epochs must not be negative, got %d", ks.retain)
+	}
+	return ks, nil
+}
+
+func (ks *EpochKeystore) epochDir(epoch int) string {
+	return filepath.Join(ks.dir, fmt.Sprintf(storeEpoch, epoch))
+}
+
+func (ks *EpochKeystore) sharePath(epoch, id int) string {
+	return filepath.Join(ks.epochDir(epoch), fmt.Sprintf(storeShare, strconv.Itoa(id)))
+}
+
+// SaveShare stores the share of the node in the epoch and prunes the epochs
+// beyond the retention.
+func (ks *EpochKeystore) SaveShare(epoch, id int, sh *BLSShareJSON) error {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	if err := os.MkdirAll(ks.epochDir(epoch), 0700); err != nil {
+		return fmt.Errorf("failed to create epoch directory: %v", err)
+	}
+	var (
+		path = ks.sharePath(epoch, id)
+		data []byte
+		err  error
+	)
+	if ks.passphrase != nil {
+		encrypted, err := EncryptBLSShare(sh, ks.passphrase, ks.options...)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt share of epoch %d: %v", epoch, err)
+		}
+		data, err = json.MarshalIndent(encrypted, "", "  ")
+	} else {
+		data, err = json.Marshal(sh)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal share of epoch %d: %v", epoch, err)
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to save share of epoch %d: %v", epoch, err)
+	}
+	if err := ks.writeChecksum(epoch, id, path, data); err != nil {
+		return err
+	}
+	return ks.prune()
+}
+
+// SaveKeyring stores the master public key and every share of the keyring as
+// the epoch's, e.g. to set up a test network.
+func (ks *EpochKeystore) SaveKeyring(epoch int, keyring *BLSKeyring) error {
+	masterPubKey, err := DumpMasterPubKey(keyring.MasterPubKey)
+	if err != nil {
+		return err
+	}
+	if err := ks.SaveMasterPubKey(epoch, masterPubKey); err != nil {
+		return err
+	}
+	for id, keypair := range keyring.Shares {
+		skp, err := NewBLSShareJSON(keypair)
+		if err != nil {
+			return fmt.Errorf("failed to serialize keypair #%d: %v", id, err)
+		}
+		if err := ks.SaveShare(epoch, id, skp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveMasterPubKey stores the master public key of the epoch, as returned by
+// DumpMasterPubKey.
+func (ks *EpochKeystore) SaveMasterPubKey(epoch int, masterPubKey string) error {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	if err := os.MkdirAll(ks.epochDir(epoch), 0700); err != nil {
+		return fmt.Errorf("failed to create epoch directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(ks.epochDir(epoch), storeMasterKey), []byte(masterPubKey), 0644); err != nil {
+		return fmt.Errorf("failed to write master public key of epoch %d: %v", epoch, err)
+	}
+	return nil
+}
+
+// LoadShare loads the share of the node in the epoch, checking its checksum if
+// the keystore has an integrity key.
+func (ks *EpochKeystore) LoadShare(epoch, id int) (*BLSShareJSON, error) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	path := ks.loadPath(epoch, id)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share %d of epoch %d: %v", id, epoch, err)
+	}
+	if err := ks.verifyChecksum(epoch, id, path, data); err != nil {
+		return nil, err
+	}
+	sh, _, err := parseBLSShare(data, ks.passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share %d of epoch %d: %v", id, epoch, err)
+	}
+	return sh, nil
+}
+
+// loadPath returns the file of the share, the flat layout's for epoch 0 if the
+// epoch has no directory.
+func (ks *EpochKeystore) loadPath(epoch, id int) string {
+	path := ks.sharePath(epoch, id)
+	if epoch == 0 && !exists(path) {
+		path = filepath.Join(ks.dir, fmt.Sprintf(storeShare, strconv.Itoa(id)))
+	}
+	return path
+}
+
+// LoadMasterPubKey loads the master public key of the epoch.
+func (ks *EpochKeystore) LoadMasterPubKey(epoch int) (string, error) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	path := filepath.Join(ks.epochDir(epoch), storeMasterKey)
+	if epoch == 0 && !exists(path) {
+		path = filepath.Join(ks.dir, storeMasterKey)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load master public key of epoch %d: %v", epoch, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// LoadVerifier creates the verifier of the node's share in the epoch; a share
+// that doesn't match the epoch's master public key fails with a
+// ShareIntegrityError.
+func (ks *EpochKeystore) LoadVerifier(epoch, id, t, n int) (*BLSVerifier, error) {
+	masterPubKey, err := ks.LoadMasterPubKey(epoch)
+	if err != nil {
+		return nil, err
+	}
+	pubPoly, err := LoadPubKey(masterPubKey, t)
+	if err != nil {
+		return nil, err
+	}
+	shJSON, err := ks.LoadShare(epoch, id)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := shJSON.Deserialize()
+	if err != nil {
+		return nil, err
+	}
+	if err := matchShare(pubPoly, sh, id); err != nil {
+		ks.mtx.Lock()
+		defer ks.mtx.Unlock()
+		return nil, ks.tampered(epoch, id, ks.loadPath(epoch, id), err.Error())
+	}
+	sh.ID = id
+	return NewBLSVerifier(pubPoly, sh, t, n), nil
+}
+
+// Epochs returns the stored epochs in ascending order, without the flat layout.
+func (ks *EpochKeystore) Epochs() ([]int, error) {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+	return ks.epochs()
+}
+
+func (ks *EpochKeystore) epochs() ([]int, error) {
+	entries, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list epochs: %v", err)
+	}
+	var epochs []int
+	for _, entry := range entries {
+		var epoch int
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := fmt.Sscanf(entry.Name(), storeEpoch, &epoch); err != nil || fmt.Sprintf(storeEpoch, epoch) != entry.Name() {
+			continue
+		}
+		epochs = append(epochs, epoch)
+	}
+	sort.Ints(epochs)
+	return epochs, nil
+}
+
+// prune removes the oldest epochs beyond the retention.
+func (ks *EpochKeystore) prune() error {
+	if ks.retain == 0 {
+		return nil
+	}
+	epochs, err := ks.epochs()
+	if err != nil {
+		return err
+	}
+	for len(epochs) > ks.retain {
+		if err := os.RemoveAll(ks.epochDir(epochs[0])); err != nil {
+			return fmt.Errorf("failed to remove epoch %d: %v", epochs[0], err)
+		}
+		epochs = epochs[1:]
+	}
+	return nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,133 @@
+package blsShare
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/share"
+)
+
+const (
+	storeChecksum = ".mac"
+
+	integrityDomain = "dkglib/share-integrity/v1"
+)
+
+// ShareIntegrityError is returned instead of a share that fails its integrity
+// check: its file was modified, moved from another epoch, share ID or node, or
+// holds a share that doesn't match the epoch's master public key.
+type ShareIntegrityError struct {
+	Epoch  int
+	ID     int
+	Path   string
+	Reason string
+}
+
+func (e *ShareIntegrityError) Error() string {
+	return fmt.Sprintf("share %d of epoch %d in %s is tampered with or mismatched: %s", e.ID, e.Epoch, e.Path, e.Reason)
+}
+
+// WithIntegrityKey stores an HMAC-SHA256 with the key next to every saved share
+// file, as <file>.mac, binding the file's content to the epoch, the share ID
+// and the node, e.g. the validator's address. Loading a share whose checksum
+// is missing or doesn't match fails with a ShareIntegrityError; Seal adds the
+// checksum of a share saved without it.
+func WithIntegrityKey(key []byte, node string) EpochKeystoreOption {
+	return func(ks *EpochKeystore) { ks.integrityKey, ks.node = key, node }
+}
+
+// WithTamperAlert calls the callback with every ShareIntegrityError, e.g. to
+// alert the operator, before it is returned.
+func WithTamperAlert(alert func(err *ShareIntegrityError)) EpochKeystoreOption {
+	return func(ks *EpochKeystore) { ks.tamperAlert = alert }
+}
+
+// Seal stores the checksum of the epoch's share file as it is now; it must
+// only be called on a file known to be intact.
+func (ks *EpochKeystore) Seal(epoch, id int) error {
+	ks.mtx.Lock()
+	defer ks.mtx.Unlock()
+
+	if ks.integrityKey == nil {
+		return fmt.Errorf("keystore has no integrity key")
+	}
+	path := ks.loadPath(epoch, id)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read share %d of epoch %d: %v", id, epoch, err)
+	}
+	return ks.writeChecksum(epoch, id, path, data)
+}
+
+func (ks *EpochKeystore) checksum(epoch, id int, data []byte) []byte {
+	mac := hmac.New(sha256.New, ks.integrityKey)
+	var header [16]byte
+	binary.BigEndian.PutUint64(header[:8], uint64(epoch))
+	binary.BigEndian.PutUint64(header[8:], uint64(id))
+	mac.Write([]byte(integrityDomain))
+	mac.Write(header[:])
+	mac.Write([]byte(ks.node))
+	mac.Write([]byte{0})
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func (ks *EpochKeystore) writeChecksum(epoch, id int, path string, data []byte) error {
+	if ks.integrityKey == nil {
+		return nil
+	}
+	sum := hex.EncodeToString(ks.checksum(epoch, id, data))
+	if err := writeFileAtomic(path+storeChecksum, []byte(sum)); err != nil {
+		return fmt.Errorf("failed to save checksum of share %d of epoch %d: %v", id, epoch, err)
+	}
+	return nil
+}
+
+// verifyChecksum checks the share file's content against its stored checksum.
+func (ks *EpochKeystore) verifyChecksum(epoch, id int, path string, data []byte) error {
+	if ks.integrityKey == nil {
+		return nil
+	}
+	stored, err := ioutil.ReadFile(path + storeChecksum)
+	if os.IsNotExist(err) {
+		return ks.tampered(epoch, id, path, "checksum is missing")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read checksum of share %d of epoch %d: %v", id, epoch, err)
+	}
+	sum, err := hex.DecodeString(strings.TrimSpace(string(stored)))
+	if err != nil || !hmac.Equal(sum, ks.checksum(epoch, id, data)) {
+		return ks.tampered(epoch, id, path, "checksum doesn't match")
+	}
+	return nil
+}
+
+func (ks *EpochKeystore) tampered(epoch, id int, path, reason string) error {
+	err := &ShareIntegrityError{Epoch: epoch, ID: id, Path: path, Reason: reason}
+	if ks.tamperAlert != nil {
+		ks.tamperAlert(err)
+	}
+	return err
+}
+
+// matchShare checks that the share is the share id of the master public key,
+// so a wrong share can't produce partial signatures nobody accepts. Only the
+// private share is checked: the public one of shares produced by a DKG round
+// holds the dealer's key instead.
+func matchShare(masterPubKey *share.PubPoly, sh *BLSShare, id int) error {
+	suite := bn256.NewSuite().G2()
+	if sh.Priv == nil || sh.Priv.I != id {
+		return fmt.Errorf("share isn't share %d", id)
+	}
+	if !masterPubKey.Eval(id).V.Equal(suite.Point().Mul(sh.Priv.V, nil)) {
+		return fmt.Errorf("private share doesn't match the master public key")
+	}
+	return nil
+}
@@ -0,0 +1,424 @@
+package blsShare
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	KeystoreVersion = 1
+
+	KDFArgon2id = "argon2id"
+	KDFScrypt   = "scrypt"
+
+	cipherAESGCM = "aes-256-gcm"
+	keyLen       = 32
+	saltLen      = 16
+)
+
+// ErrWrongPassphrase is returned when a keystore can not be decrypted with the passphrase.
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted keystore")
+
+// KDFParams are the parameters of the key derivation function; the fields of
+// the other function are left zero.
+type KDFParams struct {
+	Salt []byte `json:"salt"`
+
+	// Argon2id parameters, see RFC 9106.
+	Time    uint32 `json:"time,omitempty"`
+	Memory  uint32 `json:"memory,omitempty"` // In KiB.
+	Threads uint8  `json:"threads,omitempty"`
+
+	// Scrypt parameters.
+	N int `json:"n,omitempty"`
+	R int `json:"r,omitempty"`
+	P int `json:"p,omitempty"`
+}
+
+// KeystoreHeader describes how a keystore is encrypted; it is authenticated
+// along with the ciphertext, so parameters can not be downgraded.
+type KeystoreHeader struct {
+	Version   int       `json:"version"`
+	KDF       string    `json:"kdf"`
+	KDFParams KDFParams `json:"kdf_params"`
+	Cipher    string    `json:"cipher"`
+	Nonce     []byte    `json:"nonce"`
+}
+
+// EncryptedBLSShare is the keystore format of a BLS share. The public share is
+// kept in the clear, so it can be inspected without the passphrase.
+type EncryptedBLSShare struct {
+	KeystoreHeader
+	Pub        string `json:"pub"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+type keystoreConfig struct {
+	kdf    string
+	params KDFParams
+}
+
+// KeystoreOption selects the key derivation function of new keystores.
+type KeystoreOption func(*keystoreConfig)
+
+// WithArgon2id derives keys with argon2id; memory is in KiB.
+func WithArgon2id(time, memory uint32, threads uint8) KeystoreOption {
+	return func(c *keystoreConfig) {
+		c.kdf = KDFArgon2id
+		c.params = KDFParams{Time: time, Memory: memory, Threads: threads}
+	}
+}
+
+// WithScrypt derives keys with scrypt, e.g. for environments where argon2id's
+// memory requirements can't be met.
+func WithScrypt(n, r, p int) KeystoreOption {
+	return func(c *keystoreConfig) {
+		c.kdf = KDFScrypt
+		c.params = KDFParams{N: n, R: r, P: p}
+	}
+}
+
+// defaultKeystoreConfig uses the argon2id parameters recommended by RFC 9106
+// for memory-constrained environments.
+func defaultKeystoreConfig() *keystoreConfig {
+	return &keystoreConfig{
+		kdf:    KDFArgon2id,
+		params: KDFParams{Time: 3, Memory: 64 * 1024, Threads: 4},
+	}
+}
+
+// Bounds of the KDF parameters, so a crafted keystore can't exhaust the memory
+// or the CPU of the node loading it.
+const (
+	maxArgon2Time   = 16
+	maxArgon2Memory = 4 * 1024 * 1024 // 4 GiB in KiB.
+	maxScryptN      = 1 << 20
+	maxScryptR      = 32
+	maxScryptP      = 16
+	maxScryptMemory = 1 << 30 // 128 * N * r bytes.
+)
+
+// checkKDFParams rejects the parameters out of the bounds.
+func checkKDFParams(kdf string, params KDFParams) error {
+	switch kdf {
+	case KDFArgon2id:
+		if params.Time < 1 || params.Time > maxArgon2Time {
+			return fmt.Errorf("argon2id time %d is out of range [1, %d]", params.Time, maxArgon2Time)
+		}
+		if params.Threads < 1 {
+			return fmt.Errorf("argon2id needs at least one thread")
+		}
+		if min := 8 * uint32(params.Threads); params.Memory < min || params.Memory > maxArgon2Memory {
+			return fmt.Errorf("argon2id memory %d KiB is out of range [%d, %d]", params.Memory, min, maxArgon2Memory)
+		}
+	case KDFScrypt:
+		if params.N < 2 || params.N > maxScryptN || params.N&(params.N-1) != 0 {
+			return fmt.Errorf("scrypt N %d must be a power of two within [2, %d]", params.N, maxScryptN)
+		}
+		if params.R < 1 || params.R > maxScryptR {
+			return fmt.Errorf("scrypt r %d is out of range [1, %d]", params.R, maxScryptR)
+		}
+		if params.P < 1 || params.P > maxScryptP {
+			return fmt.Errorf("scrypt p %d is out of range [1, %d]", params.P, maxScryptP)
+		}
+		if memory := 128 * params.N * params.R; memory > maxScryptMemory {
+			return fmt.Errorf("scrypt needs %d bytes, more than %d", memory, maxScryptMemory)
+		}
+	default:
+		return fmt.Errorf("unsupported KDF %q", kdf)
+	}
+	return nil
+}
+
+func deriveKey(kdf string, params KDFParams, passphrase []byte) ([]byte, error) {
+	if err := checkKDFParams(kdf, params); err != nil {
+		return nil, err
+	}
+	switch kdf {
+	case KDFArgon2id:
+		return argon2.IDKey(passphrase, params.Salt, params.Time, params.Memory, params.Threads, keyLen), nil
+	case KDFScrypt:
+		return scrypt.Key(passphrase, params.Salt, params.N, params.R, params.P, keyLen)
+	default:
+		return nil, fmt.Errorf("unsupported KDF %q", kdf)
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newKeystoreHeader derives a key from the passphrase with a new salt and
+// returns the header of a new keystore along with the cipher to seal it with.
+func newKeystoreHeader(passphrase []byte, options []KeystoreOption) (KeystoreHeader, cipher.AEAD, error) {
+	cfg := defaultKeystoreConfig()
+	for _, option := range options {
+		option(cfg)
+	}
+
+	cfg.params.Salt = make([]byte, saltLen)
+	if _, err := rand.Read(cfg.params.Salt); err != nil {
+		return KeystoreHeader{}, nil, fmt.Errorf("failed to generate salt: %v", err)
+	}
+	key, err := deriveKey(cfg.kdf, cfg.params, passphrase)
+	if err != nil {
+		return KeystoreHeader{}, nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return KeystoreHeader{}, nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return KeystoreHeader{}, nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+
+	header := KeystoreHeader{
+		Version:   KeystoreVersion,
+		KDF:       cfg.kdf,
+		KDFParams: cfg.params,
+		Cipher:    cipherAESGCM,
+		Nonce:     nonce,
+	}
+	return header, gcm, nil
+}
+
+// openCipher derives the key of the keystore from the passphrase.
+func (h *KeystoreHeader) openCipher(passphrase []byte) (cipher.AEAD, error) {
+	if h.Version != KeystoreVersion {
+		return nil, fmt.Errorf("unsupported keystore version %d", h.Version)
+	}
+	if h.Cipher != cipherAESGCM {
+		return nil, fmt.Errorf("unsupported keystore cipher %q", h.Cipher)
+	}
+	key, err := deriveKey(h.KDF, h.KDFParams, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length: %d", len(h.Nonce))
+	}
+	return gcm, nil
+}
+
+// EncryptBLSShare encrypts the share with a key derived from the passphrase.
+func EncryptBLSShare(sh *BLSShareJSON, passphrase []byte, options ...KeystoreOption) (*EncryptedBLSShare, error) {
+	header, gcm, err := newKeystoreHeader(passphrase, options)
+	if err != nil {
+		return nil, err
+	}
+	ks := &EncryptedBLSShare{KeystoreHeader: header, Pub: sh.Pub}
+	aad, err := ks.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	ks.Ciphertext = gcm.Seal(nil, header.Nonce, []byte(sh.Priv), aad)
+
+	return ks, nil
+}
+
+// additionalData authenticates the header and the public share.
+func (ks *EncryptedBLSShare) additionalData() ([]byte, error) {
+	aad, err := json.Marshal(struct {
+		KeystoreHeader
+		Pub string `json:"pub"`
+	}{ks.KeystoreHeader, ks.Pub})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keystore header: %v", err)
+	}
+	return aad, nil
+}
+
+func (ks *EncryptedBLSShare) Decrypt(passphrase []byte) (*BLSShareJSON, error) {
+	gcm, err := ks.openCipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := ks.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	priv, err := gcm.Open(nil, ks.Nonce, ks.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	return &BLSShareJSON{Pub: ks.Pub, Priv: string(priv)}, nil
+}
+
+// EncryptedSecret is the keystore format of secrets other than BLS shares,
+// e.g. those of an upgrade state.
+type EncryptedSecret struct {
+	KeystoreHeader
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptSecret encrypts the secret with a key derived from the passphrase.
+func EncryptSecret(secret, passphrase []byte, options ...KeystoreOption) (*EncryptedSecret, error) {
+	header, gcm, err := newKeystoreHeader(passphrase, options)
+	if err != nil {
+		return nil, err
+	}
+	ks := &EncryptedSecret{KeystoreHeader: header}
+	aad, err := json.Marshal(ks.KeystoreHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keystore header: %v", err)
+	}
+	ks.Ciphertext = gcm.Seal(nil, header.Nonce, secret, aad)
+
+	return ks, nil
+}
+
+func (ks *EncryptedSecret) Decrypt(passphrase []byte) ([]byte, error) {
+	gcm, err := ks.openCipher(passphrase)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := json.Marshal(ks.KeystoreHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode keystore header: %v", err)
+	}
+	secret, err := gcm.Open(nil, ks.Nonce, ks.Ciphertext, aad)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return secret, nil
+}
+
+// SaveEncryptedBLSShare encrypts the share into the file, readable only by the owner.
+func SaveEncryptedBLSShare(path string, sh *BLSShareJSON, passphrase []byte, options ...KeystoreOption) error {
+	ks, err := EncryptBLSShare(sh, passphrase, options...)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ks, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode keystore: %v", err)
+	}
+	return writeFileAtomic(path, data)
+}
+
+// LoadBLSShare loads a share from an encrypted keystore or from the legacy
+// unencrypted format, reporting the latter so callers can migrate it.
+func LoadBLSShare(path string, passphrase []byte) (sh *BLSShareJSON, legacy bool, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("could not load bls share: %v", err)
+	}
+	return parseBLSShare(data, passphrase)
+}
+
+func parseBLSShare(data, passphrase []byte) (sh *BLSShareJSON, legacy bool, err error) {
+	var ks EncryptedBLSShare
+	if err := json.Unmarshal(data, &ks); err != nil {
+		return nil, false, fmt.Errorf("could not load bls share: %v", err)
+	}
+	if ks.Version == 0 {
+		var plain BLSShareJSON
+		if err := json.Unmarshal(data, &plain); err != nil || plain.Priv == "" {
+			return nil, false, fmt.Errorf("could not load bls share: unknown format")
+		}
+		return &plain, true, nil
+	}
+
+	sh, err = ks.Decrypt(passphrase)
+	return sh, false, err
+}
+
+// MigrateBLSShare encrypts a legacy unencrypted share file in place; encrypted
+// files are left untouched.
+func MigrateBLSShare(path string, passphrase []byte, options ...KeystoreOption) (migrated bool, err error) {
+	sh, legacy, err := LoadBLSShare(path, passphrase)
+	if err != nil || !legacy {
+		return false, err
+	}
+	if err := SaveEncryptedBLSShare(path, sh, passphrase, options...); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ChangeBLSSharePassphrase re-encrypts the share with a new passphrase and a new
+// salt, using the KDF selected by the options.
+func ChangeBLSSharePassphrase(path string, oldPassphrase, newPassphrase []byte, options ...KeystoreOption) error {
+	sh, _, err := LoadBLSShare(path, oldPassphrase)
+	if err != nil {
+		return err
+	}
+	return SaveEncryptedBLSShare(path, sh, newPassphrase, options...)
+}
+
+// DumpEncryptedBLSKeyring is DumpBLSKeyring with the shares encrypted.
+func DumpEncryptedBLSKeyring(keyring *BLSKeyring, targetDir string, passphrase []byte, options ...KeystoreOption) error {
+	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
+		return fmt.Errorf("failed to dump keyring, directory does not exist")
+	}
+
+	masterPubKey, err := DumpMasterPubKey(keyring.MasterPubKey)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(targetDir, storeMasterKey), []byte(masterPubKey), 0644); err != nil {
+		return fmt.Errorf("failed to write master public key to disk: %v", err)
+	}
+
+	for id, keypair := range keyring.Shares {
+		skp, err := NewBLSShareJSON(keypair)
+		if err != nil {
+			return fmt.Errorf("failed to serialize keypair #%d: %v", id, err)
+		}
+		fileName := fmt.Sprintf(storeShare, fmt.Sprintf("%d", id))
+		if err := SaveEncryptedBLSShare(filepath.Join(targetDir, fileName), skp, passphrase, options...); err != nil {
+			return fmt.Errorf("failed to write key pair for id %d to disk: %v", id, err)
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic replaces the file, so an interrupted write can't lose the share.
+func writeFileAtomic(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create keystore file: %v", err)
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to set keystore permissions: %v", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write keystore: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync keystore: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close keystore: %v", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace keystore: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package blsShare
+
+import "testing"
+
+func TestKeystoreRejectsOutOfRangeKDFParams(t *testing.T) {
+	sh := &BLSShareJSON{Pub: "pub", Priv: "priv"}
+	ks, err := EncryptBLSShare(sh, []byte("passphrase"), WithScrypt(1<<10, 8, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ks.Decrypt([]byte("passphrase")); err != nil || *got != *sh {
+		t.Fatalf("decrypted %v, %v", got, err)
+	}
+
+	for name, params := range map[string]KDFParams{
+		"huge N":               {N: 1 << 30, R: 8, P: 1},
+		"N not a power of two": {N: 1000, R: 8, P: 1},
+		"huge scrypt memory":   {N: 1 << 20, R: 32, P: 1},
+		"zero r":               {N: 1 << 10, R: 0, P: 1},
+		"huge p":               {N: 1 << 10, R: 8, P: 1 << 20},
+		"no threads":           {Time: 1, Memory: 64, Threads: 0},
+		"huge argon2id memory": {Time: 1, Memory: 1 << 31, Threads: 1},
+	} {
+		crafted := *ks
+		crafted.KDFParams = params
+		crafted.KDFParams.Salt = ks.KDFParams.Salt
+		if params.Time != 0 {
+			crafted.KDF = KDFArgon2id
+		}
+		if _, err := crafted.Decrypt([]byte("passphrase")); err == nil || err == ErrWrongPassphrase {
+			t.Errorf("%s: want parameters rejected, got %v", name, err)
+		}
+	}
+
+	if _, err := EncryptBLSShare(sh, []byte("passphrase"), WithArgon2id(100, 64*1024, 4)); err == nil {
+		t.Error("encrypting with out of range parameters didn't fail")
+	}
+}
@@ -0,0 +1,26 @@
+package blsShare
+
+import "time"
+
+// Reasons of the verification failures reported to VerifierMetrics.
+const (
+	FailureInvalidShare     = "invalid_share"     // A signature share doesn't verify.
+	FailureInvalidSignature = "invalid_signature" // A recovered signature doesn't verify.
+	FailureNotEnoughShares  = "not_enough_shares" // Fewer signature shares than the threshold to recover from.
+	FailureRecover          = "recover"           // The signature couldn't be recovered from enough shares.
+)
+
+// VerifierMetrics receives measurements of the verifier's operations, which
+// bound the block time when the verifier is used as a random beacon.
+type VerifierMetrics interface {
+	ObserveSignShare(d time.Duration)
+	ObserveRecover(d time.Duration, shares int)
+	VerifyFailed(reason string)
+}
+
+// NopVerifierMetrics discards the measurements.
+type NopVerifierMetrics struct{}
+
+func (NopVerifierMetrics) ObserveSignShare(time.Duration)    {}
+func (NopVerifierMetrics) ObserveRecover(time.Duration, int) {}
+func (NopVerifierMetrics) VerifyFailed(string)               {}
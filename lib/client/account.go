@@ -0,0 +1,80 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// DefaultAccountCacheTTL is how long CachedAccountRetriever keeps an account,
+// about a block.
+const DefaultAccountCacheTTL = 5 * time.Second
+
+// AccountRetriever returns the account number and sequence of an address, e.g.
+// an auth AccountRetriever querying the node.
+type AccountRetriever interface {
+	GetAccountNumberSequence(addr sdk.AccAddress) (uint64, uint64, error)
+}
+
+type cachedAccount struct {
+	number   uint64
+	sequence uint64
+	expires  time.Time
+}
+
+// CachedAccountRetriever keeps the accounts it retrieved for a short TTL, so
+// the broadcasts of a busy round phase don't query the node for every
+// transaction. Broadcasters report their results, see Broadcasted, since a
+// broadcast changes the sequence.
+type CachedAccountRetriever struct {
+	mtx       sync.Mutex
+	retriever AccountRetriever
+	ttl       time.Duration
+	accounts  map[string]cachedAccount
+}
+
+func NewCachedAccountRetriever(retriever AccountRetriever, ttl time.Duration) *CachedAccountRetriever {
+	return &CachedAccountRetriever{retriever: retriever, ttl: ttl, accounts: make(map[string]cachedAccount)}
+}
+
+func (r *CachedAccountRetriever) GetAccountNumberSequence(addr sdk.AccAddress) (uint64, uint64, error) {
+	r.mtx.Lock()
+	account, ok := r.accounts[addr.String()]
+	r.mtx.Unlock()
+	if ok && time.Now().Before(account.expires) {
+		return account.number, account.sequence, nil
+	}
+
+	number, sequence, err := r.retriever.GetAccountNumberSequence(addr)
+	if err != nil {
+		return 0, 0, err
+	}
+	r.mtx.Lock()
+	r.accounts[addr.String()] = cachedAccount{number: number, sequence: sequence, expires: time.Now().Add(r.ttl)}
+	r.mtx.Unlock()
+	return number, sequence, nil
+}
+
+// Broadcasted invalidates the cached sequence of the account which signed a
+// transaction with the sequence: a successful broadcast replaces it with the
+// next one, as the node's answer lags behind until the transaction is
+// committed, and a failed one drops the account, whose sequence may be off.
+func (r *CachedAccountRetriever) Broadcasted(addr sdk.AccAddress, sequence uint64, err error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	account, ok := r.accounts[addr.String()]
+	if err != nil || !ok {
+		delete(r.accounts, addr.String())
+		return
+	}
+	account.sequence = sequence + 1
+	r.accounts[addr.String()] = account
+}
+
+// Invalidate drops the cached account of the address.
+func (r *CachedAccountRetriever) Invalidate(addr sdk.AccAddress) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	delete(r.accounts, addr.String())
+}
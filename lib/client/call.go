@@ -0,0 +1,140 @@
+package client
+
+import (
+	gocontext "context"
+	"fmt"
+	"time"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	ctypes "github.com/tendermint/tendermint/rpc/core/types"
+)
+
+const (
+	// DefaultQueryTimeout bounds queries to the node.
+	DefaultQueryTimeout = 10 * time.Second
+	// DefaultBroadcastTimeout bounds broadcasts, which may wait for a block.
+	DefaultBroadcastTimeout = 30 * time.Second
+)
+
+// call runs the RPC call until it returns or the context is done. The RPC
+// client takes no context, so a call given up on finishes in the background;
+// WithTimeout bounds it at the transport.
+func call(ctx gocontext.Context, fn func() (interface{}, error)) (interface{}, error) {
+	type result struct {
+		value interface{}
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		done <- result{value, err}
+	}()
+
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// QueryWithData is cli.QueryWithData returning early when the context is done.
+func QueryWithData(ctx gocontext.Context, cli *context.Context, path string, data []byte) ([]byte, int64, error) {
+	type response struct {
+		res    []byte
+		height int64
+	}
+	value, err := call(ctx, func() (interface{}, error) {
+		res, height, err := cli.QueryWithData(path, data)
+		return response{res, height}, err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	res := value.(response)
+	return res.res, res.height, nil
+}
+
+// ABCIQuery queries the path at the height, returning early when the context is done.
+func ABCIQuery(ctx gocontext.Context, cli *context.Context, path string, height int64) (*ctypes.ResultABCIQuery, error) {
+	return ABCIQueryWithData(ctx, cli, path, nil, height)
+}
+
+// ABCIQueryWithData queries the path with the data at the height, e.g. a store
+// key, returning early when the context is done.
+func ABCIQueryWithData(ctx gocontext.Context, cli *context.Context, path string, data []byte, height int64) (*ctypes.ResultABCIQuery, error) {
+	value, err := call(ctx, func() (interface{}, error) {
+		return cli.Client.ABCIQueryWithOptions(path, data, rpcclient.ABCIQueryOptions{Height: height})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*ctypes.ResultABCIQuery), nil
+}
+
+// Validators queries the validator set at the height, the latest one if zero,
+// returning early when the context is done.
+func Validators(ctx gocontext.Context, cli *context.Context, height int64) (*ctypes.ResultValidators, error) {
+	var heightPtr *int64
+	if height > 0 {
+		heightPtr = &height
+	}
+	value, err := call(ctx, func() (interface{}, error) {
+		return cli.Client.Validators(heightPtr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*ctypes.ResultValidators), nil
+}
+
+// Status queries the status of the node, returning early when the context is done.
+func Status(ctx gocontext.Context, cli *context.Context) (*ctypes.ResultStatus, error) {
+	value, err := call(ctx, func() (interface{}, error) {
+		return cli.Client.Status()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*ctypes.ResultStatus), nil
+}
+
+// BroadcastTx broadcasts the transaction in the mode (sync, async or block),
+// returning early when the context is done. As with the context's methods, a
+// failed block mode broadcast returns its populated result along with the error.
+func BroadcastTx(ctx gocontext.Context, cli *context.Context, mode string, txBytes []byte) (sdk.TxResponse, error) {
+	var broadcast func([]byte) (sdk.TxResponse, error)
+	switch mode {
+	case context.BroadcastAsync:
+		broadcast = cli.BroadcastTxAsync
+	case context.BroadcastSync:
+		broadcast = cli.BroadcastTxSync
+	case context.BroadcastBlock:
+		broadcast = cli.BroadcastTxCommit
+	default:
+		return sdk.TxResponse{}, fmt.Errorf("unsupported broadcast mode %s; supported modes: sync, async, block", mode)
+	}
+
+	value, err := call(ctx, func() (interface{}, error) {
+		return broadcast(txBytes)
+	})
+	if res, ok := value.(sdk.TxResponse); ok {
+		return res, err
+	}
+	return sdk.TxResponse{}, err
+}
+
+// TimeoutQuerier runs every query of the context with the timeout, e.g. for
+// account retrievers taking a node querier.
+type TimeoutQuerier struct {
+	Context *context.Context
+	Timeout time.Duration
+}
+
+func (q TimeoutQuerier) QueryWithData(path string, data []byte) ([]byte, int64, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), q.Timeout)
+	defer cancel()
+	return QueryWithData(ctx, q.Context, path, data)
+}
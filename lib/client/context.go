@@ -0,0 +1,212 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/tendermint/tendermint/libs/log"
+	tmliteProxy "github.com/tendermint/tendermint/lite/proxy"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+	rpclib "github.com/tendermint/tendermint/rpc/lib/client"
+)
+
+const (
+	wsEndpoint        = "/websocket"
+	verifierCacheSize = 10
+	statusRetryDelay  = 4 * time.Second
+	dialTimeout       = 10 * time.Second
+)
+
+type config struct {
+	tls       *tls.Config
+	token     string
+	user      string
+	password  string
+	timeout   time.Duration
+	logger    logging.Logger
+	tlsErrors []error
+}
+
+// Option configures the RPC connection of a context.
+type Option func(*config)
+
+// WithCACert accepts only node certificates signed by the CA in the PEM file,
+// pinning the node's CA instead of trusting the system roots. It requires an
+// https:// node endpoint.
+func WithCACert(caFile string) Option {
+	return func(c *config) {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			c.tlsErrors = append(c.tlsErrors, fmt.Errorf("failed to read CA certificate: %v", err))
+			return
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			c.tlsErrors = append(c.tlsErrors, fmt.Errorf("no certificates found in %s", caFile))
+			return
+		}
+		c.tlsConfig().RootCAs = pool
+	}
+}
+
+// WithClientCert presents the certificate to nodes requiring mutual TLS.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(c *config) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			c.tlsErrors = append(c.tlsErrors, fmt.Errorf("failed to load client certificate: %v", err))
+			return
+		}
+		c.tlsConfig().Certificates = append(c.tlsConfig().Certificates, cert)
+	}
+}
+
+// WithTLSConfig uses the TLS configuration as is, e.g. to pin server
+// certificates through VerifyPeerCertificate.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(c *config) { c.tls = tlsConfig }
+}
+
+// WithBearerToken authenticates every RPC request with the token.
+func WithBearerToken(token string) Option {
+	return func(c *config) { c.token = token }
+}
+
+// WithBasicAuth authenticates every RPC request with the credentials.
+func WithBasicAuth(user, password string) Option {
+	return func(c *config) { c.user, c.password = user, password }
+}
+
+// WithTimeout limits the duration of every RPC request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) { c.timeout = timeout }
+}
+
+func WithLogger(logger logging.Logger) Option {
+	return func(c *config) { c.logger = logger }
+}
+
+func (c *config) tlsConfig() *tls.Config {
+	if c.tls == nil {
+		c.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	return c.tls
+}
+
+// NewContext returns a context like context.NewContextWithDelay, whose RPC
+// client and light client verifier use the configured TLS and authentication.
+// The verifier is created in the background once the node is reachable.
+//
+// Credentials are only sent over TLS, so authentication requires an https://
+// endpoint. Websocket subscriptions are not covered by the options.
+func NewContext(chainID, nodeURI, home string, options ...Option) (*context.Context, error) {
+	cfg := &config{logger: logging.NewNopLogger()}
+	for _, option := range options {
+		option(cfg)
+	}
+	if len(cfg.tlsErrors) != 0 {
+		return nil, cfg.tlsErrors[0]
+	}
+	if nodeURI == "" {
+		return nil, fmt.Errorf("no nodeURI specified")
+	}
+
+	secure := strings.HasPrefix(nodeURI, "https://")
+	if !secure && cfg.tls != nil {
+		return nil, fmt.Errorf("TLS requires an https:// node endpoint, got %s", nodeURI)
+	}
+	if !secure && (cfg.token != "" || cfg.user != "") {
+		return nil, fmt.Errorf("RPC authentication requires an https:// node endpoint, got %s", nodeURI)
+	}
+
+	httpClient := cfg.httpClient(nodeURI)
+	ctx := &context.Context{
+		Client:        rpcclient.NewHTTPWithClient(nodeURI, wsEndpoint, httpClient),
+		NodeURI:       nodeURI,
+		AccountStore:  context.AccountStoreKey,
+		Home:          home,
+		BroadcastMode: context.BroadcastSync,
+	}
+
+	go func() {
+		node := rpcclient.NewHTTPWithClient(nodeURI, wsEndpoint, httpClient)
+		for {
+			if _, err := node.Status(); err != nil {
+				cfg.logger.Info("node is not reachable", "node", nodeURI, "error", err)
+				time.Sleep(statusRetryDelay)
+				continue
+			}
+			break
+		}
+
+		verifier, err := tmliteProxy.NewVerifier(
+			chainID, filepath.Join(home, ".gaialite"),
+			node, log.NewNopLogger(), verifierCacheSize,
+		)
+		if err != nil {
+			cfg.logger.Error("could not create verifier", "error", err)
+			return
+		}
+		ctx.WithVerifier(verifier)
+	}()
+
+	return ctx, nil
+}
+
+func (c *config) httpClient(nodeURI string) *http.Client {
+	if !strings.HasPrefix(nodeURI, "https://") {
+		// Plain endpoints may be unix sockets, which only the default client dials.
+		client := rpclib.DefaultHTTPClient(nodeURI)
+		client.Timeout = c.timeout
+		return client
+	}
+
+	// The RPC client maps https:// endpoints to http:// URLs, so TLS is
+	// established by the dialer rather than by the transport.
+	tlsConfig := c.tlsConfig()
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	var transport http.RoundTripper = &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return tls.DialWithDialer(dialer, network, addr, tlsConfig)
+		},
+		// Set to true to prevent GZIP-bomb DoS attacks, like the default client.
+		DisableCompression: true,
+	}
+	if c.token != "" || c.user != "" {
+		transport = &authTransport{next: transport, token: c.token, user: c.user, password: c.password}
+	}
+
+	return &http.Client{Transport: transport, Timeout: c.timeout}
+}
+
+// authTransport adds the credentials to every request.
+type authTransport struct {
+	next           http.RoundTripper
+	token          string
+	user, password string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the request, so headers are set on a copy.
+	r := *req
+	r.Header = make(http.Header, len(req.Header)+1)
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	req = &r
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	} else {
+		req.SetBasicAuth(t.user, t.password)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+
+	authtxb "github.com/corestario/cosmos-utils/client/authtypes"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authTypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Signer signs transactions with a key of the builder's keybase, or with the
+// private key if it is set.
+type Signer struct {
+	Name       string
+	Passphrase string
+	PrivKey    crypto.PrivKey
+}
+
+func (s Signer) sign(txBldr authtxb.TxBuilder, msg authTypes.StdSignMsg) (authTypes.StdSignature, error) {
+	if s.PrivKey != nil {
+		return authtxb.MakeSignatureWithPrivateKey(s.PrivKey, msg)
+	}
+	return authtxb.MakeSignature(txBldr.Keybase(), s.Name, s.Passphrase, msg)
+}
+
+// FeePayer is an account paying the fees of transactions signed by another
+// one, e.g. a validator's operational key without a funded balance. The auth
+// module charges the fees to the first signer of a transaction, so messages
+// must return the payer first from GetSigners.
+type FeePayer struct {
+	Signer
+	Address sdk.AccAddress
+}
+
+// BuildAndSignWithFeePayer builds a transaction of the messages and signs it by
+// the payer and the owner; the builder holds the owner's account number and
+// sequence, while the payer's are passed explicitly.
+func BuildAndSignWithFeePayer(
+	txBldr authtxb.TxBuilder,
+	msgs []sdk.Msg,
+	owner Signer,
+	payer *FeePayer,
+	payerAccNumber, payerSequence uint64,
+) ([]byte, error) {
+	signMsg, err := txBldr.BuildSignMsg(msgs)
+	if err != nil {
+		return nil, err
+	}
+	ownerSig, err := owner.sign(txBldr, signMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx by owner: %v", err)
+	}
+
+	payerMsg := signMsg
+	payerMsg.AccountNumber, payerMsg.Sequence = payerAccNumber, payerSequence
+	payerSig, err := payer.sign(txBldr, payerMsg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign tx by fee payer: %v", err)
+	}
+
+	stdTx := authTypes.NewStdTx(signMsg.Msgs, signMsg.Fee, []authTypes.StdSignature{payerSig, ownerSig}, signMsg.Memo)
+	if signers := stdTx.GetSigners(); len(signers) != 2 || !signers[0].Equals(payer.Address) {
+		return nil, fmt.Errorf("messages must be signed by the fee payer %s first", payer.Address)
+	}
+	return txBldr.TxEncoder()(stdTx)
+}
+
+// BuildTxForSim is TxBuilder.BuildTxForSim with an empty signature for every
+// signer, so transactions with a fee payer can be simulated.
+func BuildTxForSim(txBldr authtxb.TxBuilder, msgs []sdk.Msg) ([]byte, error) {
+	signMsg, err := txBldr.BuildSignMsg(msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// The ante handler populates the signatures with a sentinel pubkey.
+	stdTx := authTypes.NewStdTx(signMsg.Msgs, signMsg.Fee, nil, signMsg.Memo)
+	stdTx.Signatures = make([]authTypes.StdSignature, len(stdTx.GetSigners()))
+	return txBldr.TxEncoder()(stdTx)
+}
@@ -0,0 +1,207 @@
+// Package clienttest creates isolated client accounts for integration tests, so
+// that no pre-provisioned client home directories are required.
+package clienttest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	authtxb "github.com/corestario/cosmos-utils/client/authtypes"
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/cosmos-utils/client/utils"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/msgs"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	"github.com/cosmos/cosmos-sdk/codec"
+	cryptokeys "github.com/cosmos/cosmos-sdk/crypto/keys"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authTypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/cosmos/cosmos-sdk/x/bank"
+)
+
+const (
+	DefaultPassphrase = "12345678"
+	DefaultGas        = 400000
+)
+
+// AccountRetriever returns the account number and sequence of an address.
+type AccountRetriever = client.AccountRetriever
+
+// StaticAccountRetriever is an AccountRetriever that does not need a running node.
+type StaticAccountRetriever struct {
+	AccountNumber uint64
+	Sequence      uint64
+}
+
+func (r StaticAccountRetriever) GetAccountNumberSequence(sdk.AccAddress) (uint64, uint64, error) {
+	return r.AccountNumber, r.Sequence, nil
+}
+
+type Config struct {
+	ChainID      string
+	NodeEndpoint string // If empty, the accounts are not connected to a node.
+	Passphrase   string
+	Gas          uint64
+	Cdc          *codec.Codec
+	// Retriever is used to get account numbers and sequences; if nil, they are queried from the node.
+	Retriever AccountRetriever
+}
+
+// Account is a client account with its own temporary home directory.
+type Account struct {
+	Name    string
+	Address sdk.AccAddress
+	Home    string
+	Ctx     *context.Context
+	TxBldr  *authtxb.TxBuilder
+
+	cfg       Config
+	keybase   cryptokeys.Keybase
+	retriever AccountRetriever
+}
+
+// MakeCodec returns a codec with all the messages used by the accounts registered.
+func MakeCodec() *codec.Codec {
+	var cdc = codec.New()
+	authTypes.RegisterCodec(cdc)
+	bank.RegisterCodec(cdc)
+	msgs.RegisterCodec(cdc)
+	sdk.RegisterCodec(cdc)
+	codec.RegisterCrypto(cdc)
+	return cdc
+}
+
+// NewAccount creates a temporary keybase with a new key and returns the account with
+// a ready Context and TxBuilder. Call Refresh once the account exists on chain.
+func NewAccount(cfg Config, name string) (*Account, error) {
+	if cfg.Passphrase == "" {
+		cfg.Passphrase = DefaultPassphrase
+	}
+	if cfg.Gas == 0 {
+		cfg.Gas = DefaultGas
+	}
+	if cfg.Cdc == nil {
+		cfg.Cdc = MakeCodec()
+	}
+
+	home, err := ioutil.TempDir("", "dkglib-clienttest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create home directory: %v", err)
+	}
+
+	kb, err := keys.NewKeyBaseFromDir(home)
+	if err != nil {
+		os.RemoveAll(home)
+		return nil, fmt.Errorf("failed to create keybase: %v", err)
+	}
+	info, _, err := kb.CreateMnemonic(name, cryptokeys.English, cfg.Passphrase, cryptokeys.Secp256k1)
+	if err != nil {
+		kb.CloseDB()
+		os.RemoveAll(home)
+		return nil, fmt.Errorf("failed to create key: %v", err)
+	}
+
+	var ctx *context.Context
+	if cfg.NodeEndpoint != "" {
+		if ctx, err = context.NewContextWithDelay(cfg.ChainID, cfg.NodeEndpoint, home); err != nil {
+			kb.CloseDB()
+			os.RemoveAll(home)
+			return nil, fmt.Errorf("failed to create context: %v", err)
+		}
+	} else {
+		ctx = &context.Context{Home: home, BroadcastMode: context.BroadcastSync}
+	}
+	ctx.WithCodec(cfg.Cdc).
+		WithFromName(name).
+		WithPassphrase(cfg.Passphrase).
+		WithFromAddress(info.GetAddress()).
+		WithFrom(name)
+
+	a := &Account{
+		Name:      name,
+		Address:   info.GetAddress(),
+		Home:      home,
+		Ctx:       ctx,
+		cfg:       cfg,
+		keybase:   kb,
+		retriever: cfg.Retriever,
+	}
+	if a.retriever == nil {
+		a.retriever = authTypes.NewAccountRetriever(ctx)
+	}
+	a.setTxBuilder(0, 0)
+
+	return a, nil
+}
+
+// NewAccounts creates n accounts named prefix0..prefixN-1; the returned function removes all of them.
+func NewAccounts(cfg Config, prefix string, n int) ([]*Account, func(), error) {
+	var accounts []*Account
+	cleanup := func() {
+		for _, a := range accounts {
+			a.Cleanup()
+		}
+	}
+	for i := 0; i < n; i++ {
+		a, err := NewAccount(cfg, fmt.Sprintf("%s%d", prefix, i))
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		accounts = append(accounts, a)
+	}
+
+	return accounts, cleanup, nil
+}
+
+// Refresh updates the TxBuilder with the account number and sequence from the retriever.
+func (a *Account) Refresh() error {
+	accNumber, accSequence, err := a.retriever.GetAccountNumberSequence(a.Address)
+	if err != nil {
+		return fmt.Errorf("failed to get account number and sequence: %v", err)
+	}
+	a.setTxBuilder(accNumber, accSequence)
+	return nil
+}
+
+// Fund sends the coins from this account to the address.
+func (a *Account) Fund(to sdk.AccAddress, amount sdk.Coins) error {
+	if err := a.Refresh(); err != nil {
+		return err
+	}
+	txBytes, err := a.TxBldr.BuildAndSign(a.Name, a.cfg.Passphrase, []sdk.Msg{bank.NewMsgSend(a.Address, to, amount)})
+	if err != nil {
+		return fmt.Errorf("failed to sign funding tx: %v", err)
+	}
+	res, err := a.Ctx.BroadcastTxCommit(txBytes)
+	if err != nil {
+		return fmt.Errorf("failed to broadcast funding tx: %v", err)
+	}
+	if res.Code != 0 {
+		return fmt.Errorf("funding tx failed: %s", res.RawLog)
+	}
+	return nil
+}
+
+// Cleanup closes the keybase and removes the home directory.
+func (a *Account) Cleanup() error {
+	a.keybase.CloseDB()
+	return os.RemoveAll(a.Home)
+}
+
+func (a *Account) setTxBuilder(accNumber, accSequence uint64) {
+	txBldr := authtxb.NewTxBuilder(
+		utils.GetTxEncoder(a.cfg.Cdc),
+		accNumber,
+		accSequence,
+		a.cfg.Gas,
+		0.0,
+		false,
+		a.cfg.ChainID,
+		"",
+		nil,
+		nil,
+	).WithKeybase(a.keybase)
+	a.TxBldr = &txBldr
+}
@@ -0,0 +1,114 @@
+package dealer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// AbortQuorum returns the number of participants of a round of n that must
+// blame a peer in their Abort messages before the others exclude it; it
+// exceeds the faulty third, so faulty participants can't blame honest ones.
+func AbortQuorum(n int) int {
+	return n/3 + 1
+}
+
+// GetAbort returns the Abort message of the round that can't complete for the
+// reason, listing the peers the dealer excluded with their evidence hashes.
+// The message is signed and broadcast by the caller.
+func (d *DKGDealer) GetAbort(reason error) (*alias.DKGData, error) {
+	claim := &types.AbortClaim{Reason: reason.Error(), Blames: append([]types.Blame(nil), d.blames...)}
+	sort.Slice(claim.Blames, func(i, j int) bool { return bytes.Compare(claim.Blames[i].Addr, claim.Blames[j].Addr) < 0 })
+	data, err := claim.Encode()
+	if err != nil {
+		return nil, err
+	}
+	return &alias.DKGData{
+		Type:    alias.DKGAbort,
+		RoundID: d.roundID,
+		Addr:    d.addrBytes,
+		Data:    data,
+	}, nil
+}
+
+// HandleDKGAbort cross-checks the blames of a participant's Abort message with
+// those of the others: a peer the dealer didn't exclude itself is excluded once
+// AbortQuorum participants blame it, so a single participant's view of the
+// round doesn't make its peers exclude anyone. Malformed claims, including
+// those whose evidence doesn't match its messages or their signatures, are
+// reported; a second claim from the same sender is ignored.
+func (d *DKGDealer) HandleDKGAbort(msg *alias.DKGData) error {
+	claim, err := types.DecodeAbortClaim(msg.Data)
+	if err != nil {
+		d.reportMisbehavior(msg, types.MisbehaviorMalformedMessage, err)
+		return nil
+	}
+	if err := d.verifyEvidence(claim); err != nil {
+		d.reportMisbehavior(msg, types.MisbehaviorMalformedMessage, err)
+		return nil
+	}
+	sender := msg.GetAddrString()
+	if _, ok := d.aborts[sender]; ok {
+		d.logger.Debug("DKGDealer: ignoring repeated abort", "from", sender)
+		return nil
+	}
+	d.aborts[sender] = claim
+	d.logger.Info("DKGDealer: participant aborted the round", "from", sender, "reason", claim.Reason, "blames", len(claim.Blames))
+
+	quorum := AbortQuorum(d.participants.Size())
+	for _, blame := range claim.Blames {
+		if bytes.Equal(blame.Addr, d.addrBytes) {
+			d.logger.Info("DKGDealer: blamed by a participant", "from", sender, "reason", blame.Reason)
+			continue
+		}
+		if d.blame(blame.Addr) != nil {
+			continue
+		}
+		if blamers := d.blamers(blame.Addr); blamers >= quorum {
+			d.exclude(blame.Addr, fmt.Errorf("blamed by %d participants: %s", blamers, blame.Reason), blame.Evidence, blame.Messages...)
+		}
+	}
+	return nil
+}
+
+// verifyEvidence checks the signatures of the claim's evidence messages; the
+// evidence hashes are checked when the claim is decoded, see
+// types.Blame.CheckEvidence.
+func (d *DKGDealer) verifyEvidence(claim *types.AbortClaim) error {
+	for _, blame := range claim.Blames {
+		for _, msg := range blame.Messages {
+			if err := d.verifySignature(msg.Addr, d.signDomain.SignBytes(msg), msg.Signature); err != nil {
+				return fmt.Errorf("invalid evidence of the blame of %s: %v", blame.Addr, err)
+			}
+		}
+	}
+	return nil
+}
+
+// blame returns the dealer's own blame of the peer, or nil.
+func (d *DKGDealer) blame(peer crypto.Address) *types.Blame {
+	for i := range d.blames {
+		if bytes.Equal(d.blames[i].Addr, peer) {
+			return &d.blames[i]
+		}
+	}
+	return nil
+}
+
+// blamers counts the participants whose Abort messages blame the peer.
+func (d *DKGDealer) blamers(peer crypto.Address) int {
+	var count int
+	for _, claim := range d.aborts {
+		for _, blame := range claim.Blames {
+			if bytes.Equal(blame.Addr, peer) {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
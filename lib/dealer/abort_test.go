@@ -0,0 +1,48 @@
+package dealer
+
+import (
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// TestAbortEvidenceIsChecked checks that blames count toward the quorum only
+// if their evidence hashes match their signed messages.
+func TestAbortEvidenceIsChecked(t *testing.T) {
+	pvs, participants := newTestValidators(t, 4)
+	blamed := pvs[3]
+	offending := &alias.DKGData{Type: alias.DKGRegistration, Addr: blamed.GetPubKey().Address(), Data: []byte("not a registration")}
+	if err := blamed.SignData("", offending); err != nil {
+		t.Fatal(err)
+	}
+	forged := *offending
+	forged.Data = []byte("forged")
+
+	for name, tc := range map[string]struct {
+		blame    types.Blame
+		excluded bool
+	}{
+		"matching evidence": {types.Blame{Evidence: types.EvidenceHash(offending), Messages: []*alias.DKGData{offending}}, true},
+		"mismatching hash":  {types.Blame{Evidence: types.EvidenceHash(&forged), Messages: []*alias.DKGData{offending}}, false},
+		"forged message":    {types.Blame{Evidence: types.EvidenceHash(&forged), Messages: []*alias.DKGData{&forged}}, false},
+		"missing messages":  {types.Blame{Evidence: types.EvidenceHash(offending)}, false},
+	} {
+		var own []*alias.DKGData
+		d := newTestDealer(t, participants, pvs[0], &own)
+		tc.blame.Addr, tc.blame.Reason = blamed.GetPubKey().Address(), "malformed registration"
+		data, err := (&types.AbortClaim{Reason: "round failed", Blames: []types.Blame{tc.blame}}).Encode()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, blamer := range pvs[1:3] {
+			abort := &alias.DKGData{Type: alias.DKGAbort, Addr: blamer.GetPubKey().Address(), Data: data}
+			if err := d.HandleDKGAbort(abort); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if excluded := len(d.GetLosers()) == 1; excluded != tc.excluded {
+			t.Errorf("%s: excluded %t, want %t", name, excluded, tc.excluded)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package dealer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// ApplyAdjudication applies the decisions of an external adjudicator on the
+// exclusions of the round. An upheld exclusion excludes the participant, if it
+// wasn't yet, with the adjudicated blame. A dismissed one reinstates it: it is
+// no longer among the losers nor blamed in the dealer's abort claim, and the
+// blames of its peers with the same evidence don't exclude it again. The DKG
+// instance doesn't get back the shares of a reinstated dealer it rejected, so
+// the round may still fail without it. The decisions are checked before any
+// is applied; an error is returned too if the upheld exclusions leave fewer
+// participants than the threshold.
+func (d *DKGDealer) ApplyAdjudication(round int, decisions []types.Adjudication) error {
+	if round != d.roundID {
+		return fmt.Errorf("adjudication of round %d, dealer of round %d", round, d.roundID)
+	}
+	for _, decision := range decisions {
+		if _, participant := d.participants.GetByAddress(decision.Addr); participant == nil {
+			return fmt.Errorf("adjudicated %s is not a participant", decision.Addr)
+		}
+		if decision.Verdict != types.AdjudicationUpheld && decision.Verdict != types.AdjudicationDismissed {
+			return fmt.Errorf("invalid verdict %v for %s", decision.Verdict, decision.Addr)
+		}
+	}
+
+	if d.adjudications == nil {
+		d.adjudications = make(map[string]types.Adjudication)
+	}
+	for _, decision := range decisions {
+		d.adjudications[decision.Addr.String()] = decision
+		switch decision.Verdict {
+		case types.AdjudicationUpheld:
+			d.reinstate(decision.Addr)
+			d.exclude(decision.Addr, fmt.Errorf("exclusion upheld by adjudication: %s", decision.Reason), decision.Evidence)
+		case types.AdjudicationDismissed:
+			d.reinstate(decision.Addr)
+		}
+		d.logger.Info("DKGDealer: applied adjudication", "addr", decision.Addr, "verdict", decision.Verdict, "reason", decision.Reason)
+	}
+
+	if left, threshold := d.participants.Size()-len(d.losers), d.verifierThreshold(); left < threshold {
+		return fmt.Errorf("adjudicated exclusions leave %d participants, %d needed", left, threshold)
+	}
+	return nil
+}
+
+// reinstate removes the peer from the losers and drops its blame.
+func (d *DKGDealer) reinstate(peer crypto.Address) {
+	for i, loser := range d.losers {
+		if bytes.Equal(loser, peer) {
+			d.losers = append(d.losers[:i:i], d.losers[i+1:]...)
+			break
+		}
+	}
+	d.removeBlame(peer)
+}
+
+func (d *DKGDealer) removeBlame(peer crypto.Address) {
+	for i := range d.blames {
+		if bytes.Equal(d.blames[i].Addr, peer) {
+			d.blames = append(d.blames[:i:i], d.blames[i+1:]...)
+			return
+		}
+	}
+}
+
+// dismissed reports whether an adjudicator dismissed the exclusion of the peer
+// with the evidence.
+func (d *DKGDealer) dismissed(peer crypto.Address, evidence []byte) bool {
+	decision, ok := d.adjudications[peer.String()]
+	return ok && decision.Verdict == types.AdjudicationDismissed && bytes.Equal(decision.Evidence, evidence)
+}
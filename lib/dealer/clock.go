@@ -0,0 +1,19 @@
+package dealer
+
+import "time"
+
+// SetClock makes the dealer time its round, phases and message arrivals with
+// the clock instead of the system's, e.g. the virtual clock of a simulation.
+// It restarts the timing of the round, so it should be set before Start.
+func (d *DKGDealer) SetClock(now func() time.Time) {
+	d.clock = now
+	d.roundStarted = d.now()
+	d.phaseStarted = d.now()
+}
+
+func (d *DKGDealer) now() time.Time {
+	if d.clock == nil {
+		return time.Now()
+	}
+	return d.clock()
+}
@@ -2,20 +2,21 @@ package dealer
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"encoding/gob"
-	"encoding/hex"
 	"errors"
 	"fmt"
 	"math"
 	"sort"
+	"time"
 
 	"github.com/corestario/dkglib/lib/alias"
 	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/logging"
 	"github.com/corestario/dkglib/lib/types"
 	tmtypes "github.com/tendermint/tendermint/alias"
 	"github.com/tendermint/tendermint/crypto"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	"go.dedis.ch/kyber/v3"
 	"go.dedis.ch/kyber/v3/pairing/bn256"
 	"go.dedis.ch/kyber/v3/share"
@@ -30,7 +31,9 @@ type Dealer interface {
 	GenerateTransitions()
 	GetLosers() []*tmtypes.Validator
 	PopLosers() []*tmtypes.Validator
-	HandleDKGPubKey(msg *alias.DKGData) error
+	GetLosersWithReasons() []*types.Loser
+	PopLosersWithReasons() []*types.Loser
+	HandleDKGRegistration(msg *alias.DKGData) error
 	SetTransitions(t []transition)
 	SendDeals() (err error, ready bool)
 	IsPubKeysReady() bool
@@ -53,26 +56,62 @@ type Dealer interface {
 	ProcessComplaints() (err error, ready bool)
 	HandleDKGReconstructCommit(msg *alias.DKGData) error
 	ProcessReconstructCommits() (err error, ready bool)
+	GetAbort(reason error) (*alias.DKGData, error)
+	HandleDKGAbort(msg *alias.DKGData) error
+	SetCapabilities(caps types.Capabilities)
+	SetSeed(seed []byte)
+	SetClock(now func() time.Time)
 	GetVerifier() (types.Verifier, error)
 	SendMsgCb([]*alias.DKGData) error
 	VerifyMessage(msg types.DKGDataMessage) error
+	VerifyMessages(msgs []*alias.DKGData) []error
+	SetVerifyConcurrency(workers int)
+	CommitmentMatrix() *types.CommitmentMatrix
+	SetProofHook(hook types.ProofHook)
+	Proofs() []*types.RoundProof
+	SetMisbehaviorSink(sink types.MisbehaviorSink)
+	SetEventTaps(taps EventTaps)
+	SetThreshold(threshold int)
+	SetSignDomain(domain alias.SignDomain)
+	Snapshot() *types.RoundInfo
+	Progress() []types.PhaseProgress
+	CanComplete() (bool, int)
+	MigrateParticipant(migration *types.ShareMigration) error
+	SetHeight(height int64)
+	CheckInvariants() error
+	ApplyAdjudication(round int, decisions []types.Adjudication) error
 }
 
 type DKGDealer struct {
 	DealerState
 	eventFirer events.Fireable
 
-	sendMsgCb func([]*alias.DKGData) error
-	logger    log.Logger
+	sendMsgCb     func([]*alias.DKGData) error
+	logger        logging.Logger
+	privValidator tmtypes.PrivValidator
 
 	pubKey      kyber.Point
 	secKey      kyber.Scalar
 	suiteG1     *bn256.Suite
 	suiteG2     *bn256.Suite
+	random      cipher.Stream // Nil unless the randomness is drawn from a seed, see SetSeed.
 	instance    *dkg.DistKeyGenerator
 	transitions []transition
 
+	phases          []string                     // Names of the transitions, used for round snapshots.
+	progress        func() []types.PhaseProgress // Messages awaited by the transitions, see Progress.
+	completedPhases int
+	checkedPhases   int // Completed phases at the last invariant check.
+	received        map[string]map[alias.DKGDataType]int
+	clock           func() time.Time // Nil uses the system clock, see SetClock.
+	roundStarted    time.Time
+	arrivals        map[string]map[alias.DKGDataType]time.Duration // First arrival of each message type since the round start.
+	phaseStarted    time.Time
+	phaseMessages   map[alias.DKGDataType]int // Messages handled during the current phase.
+	taps            EventTaps
+
 	pubKeys            PKStore
+	dealSeen           bool // A deal of the round was received, so the keys are final, see addPubKey.
 	deals              map[string]*dkg.Deal
 	responses          *messageStore
 	justifications     *messageStore
@@ -80,77 +119,141 @@ type DKGDealer struct {
 	complaints         *messageStore
 	reconstructCommits *messageStore
 
-	losers []crypto.Address
+	threshold        int              // Overrides the threshold of the round if not zero, see SetThreshold.
+	signDomain       alias.SignDomain // See SetSignDomain.
+	verifyWorkers    int              // Workers of VerifyMessages, GOMAXPROCS if not positive.
+	proofHook        types.ProofHook
+	proofs           []*types.RoundProof // Generated by the proof hook.
+	losers           []crypto.Address
+	blames           []types.Blame                 // Why the losers were excluded, see GetAbort.
+	adjudications    map[string]types.Adjudication // Latest decision on each adjudicated peer, see ApplyAdjudication.
+	capabilities     types.Capabilities            // Advertised in the registration, see SetCapabilities.
+	peerCapabilities map[string]types.Capabilities // Advertised by the participants.
+	negotiated       types.Capabilities            // Shared by the participants, once they all registered.
+	aborts           map[string]*types.AbortClaim  // Abort claims of the participants by sender.
+	misbehaviorSink  types.MisbehaviorSink
+	slots            map[string]*alias.DKGData        // First message of every sender per slot, see firstInSlot.
+	migrations       map[string]*types.ShareMigration // By the old and the new address of migrated participants.
+	height           int64                            // Height of the last block, see SetHeight.
 }
 
 type DealerState struct {
-	validators *tmtypes.ValidatorSet
-	addrBytes  []byte
+	participants *types.ParticipantSet
+	addrBytes    []byte
 
 	participantID int
 	roundID       int
 }
 
-func (ds DealerState) GetValidatorsCount() int {
-	if ds.validators == nil {
+func (ds DealerState) GetParticipantsCount() int {
+	if ds.participants == nil {
 		return 0
 	}
-	return ds.validators.Size()
+	return ds.participants.Size()
 }
 
 func (ds DealerState) GetRoundID() int { return ds.roundID }
 
-type DKGDealerConstructor func(validators *tmtypes.ValidatorSet, pv tmtypes.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer
+// GetParticipants returns the committee the round was started with.
+func (ds DealerState) GetParticipants() *types.ParticipantSet { return ds.participants }
+
+// Threshold returns the number of signature shares needed to recover a signature
+// with the verifier of an off-chain round of n participants.
+func Threshold(n int) int {
+	return (n/3)*2 + 1
+}
 
-func NewDKGDealer(validators *tmtypes.ValidatorSet, pv tmtypes.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGDealer{
+// MinParticipants is the smallest committee a round can complete with.
+const MinParticipants = 2
+
+// DealThreshold returns the default threshold of the polynomials dealt in a
+// round of n participants.
+func DealThreshold(n int) int {
+	return (n * 2) / 3
+}
+
+// CheckRound returns a CommitteeError if a round of n participants can't
+// complete with the threshold of the dealt polynomials, zero for the default
+// one, or has fewer participants than minParticipants. A round of 2 needs
+// the threshold 2, as the default one is too low.
+func CheckRound(n, threshold, minParticipants int) error {
+	if threshold == 0 {
+		threshold = DealThreshold(n)
+	}
+	if minParticipants < MinParticipants {
+		minParticipants = MinParticipants
+	}
+	if n < minParticipants {
+		return &types.CommitteeError{Size: n, Threshold: threshold,
+			Reason: fmt.Sprintf("at least %d participants are required", minParticipants)}
+	}
+	lowest := vss.MinimumT(n)
+	if lowest < 2 {
+		lowest = 2
+	}
+	if threshold < lowest || threshold > n {
+		return &types.CommitteeError{Size: n, Threshold: threshold,
+			Reason: fmt.Sprintf("the threshold must be within [%d, %d]", lowest, n)}
+	}
+	return nil
+}
+
+type DKGDealerConstructor func(participants *types.ParticipantSet, pv tmtypes.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer
+
+// NewDKGDealer creates a dealer for the committee; all round messages are
+// verified against it.
+func NewDKGDealer(participants *types.ParticipantSet, pv tmtypes.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	d := &DKGDealer{
 		DealerState: DealerState{
-			validators: validators,
-			addrBytes:  pv.GetPubKey().Address().Bytes(),
-			roundID:    startRound,
+			participants: participants,
+			addrBytes:    pv.GetPubKey().Address().Bytes(),
+			roundID:      startRound,
 		},
-		sendMsgCb:  sendMsgCb,
-		eventFirer: eventFirer,
-		logger:     logger,
-		suiteG1:    bn256.NewSuiteG1(),
-		suiteG2:    bn256.NewSuiteG2(),
-
-		responses:          newMessageStore(validators.Size() - 1),
-		justifications:     newMessageStore(int(math.Pow(float64(validators.Size()-1), 2))),
+		sendMsgCb:     sendMsgCb,
+		eventFirer:    eventFirer,
+		logger:        logger.With(logging.RoundKey, startRound),
+		privValidator: pv,
+		suiteG1:       bn256.NewSuiteG1(),
+		suiteG2:       bn256.NewSuiteG2(),
+
+		responses:          newMessageStore(participants.Size() - 1),
+		justifications:     newMessageStore(int(math.Pow(float64(participants.Size()-1), 2))),
 		commits:            newMessageStore(1),
 		complaints:         newMessageStore(1),
 		reconstructCommits: newMessageStore(1),
 
-		deals: make(map[string]*dkg.Deal),
-	}
+		signDomain:       alias.DefaultSignDomain(""),
+		deals:            make(map[string]*dkg.Deal),
+		misbehaviorSink:  types.NopMisbehaviorSink{},
+		slots:            make(map[string]*alias.DKGData),
+		migrations:       make(map[string]*types.ShareMigration),
+		aborts:           make(map[string]*types.AbortClaim),
+		peerCapabilities: make(map[string]types.Capabilities),
+		phases:           offChainPhases,
+		received:         make(map[string]map[alias.DKGDataType]int),
+		roundStarted:     time.Now(),
+		arrivals:         make(map[string]map[alias.DKGDataType]time.Duration),
+		phaseStarted:     time.Now(),
+		phaseMessages:    make(map[alias.DKGDataType]int),
+	}
+	// The index is known before the deals are, so that deals and responses
+	// arriving before the dealer has all the public keys aren't dropped as
+	// intended for another participant.
+	if index, _ := participants.GetByAddress(d.addrBytes); index >= 0 {
+		d.participantID = index
+	}
+	d.capabilities = types.LocalCapabilities(d.signDomain.Version)
+	d.progress = d.offChainProgress
+	return d
 }
 
 func (d *DKGDealer) Start() error {
-	d.secKey = d.suiteG2.Scalar().Pick(d.suiteG2.RandomStream())
+	d.secKey = d.suiteG2.Scalar().Pick(d.dkgSuite().RandomStream())
 	d.pubKey = d.suiteG2.Point().Mul(d.secKey, nil)
 
 	d.GenerateTransitions()
 
-	var (
-		buf = bytes.NewBuffer(nil)
-		enc = gob.NewEncoder(buf)
-	)
-	if err := enc.Encode(d.pubKey); err != nil {
-		return fmt.Errorf("failed to encode public key: %v", err)
-	}
-
-	d.logger.Info("dkgState: sending pub key", "key", d.pubKey.String())
-	err := d.SendMsgCb([]*alias.DKGData{{
-		Type:    alias.DKGPubKey,
-		RoundID: d.roundID,
-		Addr:    d.addrBytes,
-		Data:    buf.Bytes(),
-	}})
-	if err != nil {
-		return fmt.Errorf("failed to sign message: %v", err)
-	}
-
-	return nil
+	return d.sendRegistration()
 }
 
 func (d *DKGDealer) GetState() DealerState {
@@ -170,12 +273,25 @@ func (d *DKGDealer) Transit() error {
 			return err
 		}
 		d.transitions = d.transitions[1:]
+		d.completePhase()
 	}
 
 	return nil
 }
 
+var offChainPhases = []string{
+	"send_deals",
+	"process_deals",
+	"process_responses",
+	"process_justifications",
+	"process_commits",
+	"process_complaints",
+	"process_reconstruct_commits",
+}
+
 func (d *DKGDealer) GenerateTransitions() {
+	d.completedPhases = 0
+	d.resetPhase()
 	d.transitions = []transition{
 		// Phase I
 		d.SendDeals,
@@ -190,44 +306,216 @@ func (d *DKGDealer) GenerateTransitions() {
 }
 
 func (d *DKGDealer) SetTransitions(t []transition) {
+	d.completedPhases = 0
+	d.resetPhase()
 	d.transitions = t
 }
 
+// GetLosers returns the validators among the losers, ordered by address;
+// external participants can not be punished on chain.
 func (d *DKGDealer) GetLosers() []*tmtypes.Validator {
-	var out []*tmtypes.Validator
+	return types.LoserValidators(d.GetLosersWithReasons())
+}
+
+// GetLosersWithReasons returns the validators among the losers, ordered by
+// address, with the blames they were excluded with.
+func (d *DKGDealer) GetLosersWithReasons() []*types.Loser {
+	var out []*types.Loser
 	for _, loser := range d.losers {
-		_, validator := d.validators.GetByAddress(loser)
+		_, participant := d.participants.GetByAddress(loser)
+		if participant == nil || participant.External {
+			d.logger.Debug("got looser", "address", loser, "external", true)
+			continue
+		}
+		validator := &tmtypes.Validator{Address: participant.Address, PubKey: participant.PubKey}
 		d.logger.Debug("got looser", "address", loser, "validator", validator.String())
-		out = append(out, validator)
+		out = append(out, &types.Loser{Validator: validator, Blame: d.loserBlame(loser)})
 	}
 
-	return out
+	return types.SortLosers(out)
+}
+
+// loserBlame returns the blame the loser was excluded with.
+func (d *DKGDealer) loserBlame(loser crypto.Address) types.Blame {
+	if blame := d.blame(loser); blame != nil {
+		return *blame
+	}
+	return types.Blame{Addr: loser}
 }
 
 func (d *DKGDealer) PopLosers() []*tmtypes.Validator {
-	out := d.GetLosers()
+	return types.LoserValidators(d.PopLosersWithReasons())
+}
+
+func (d *DKGDealer) PopLosersWithReasons() []*types.Loser {
+	out := d.GetLosersWithReasons()
 	d.losers = nil
 	return out
 }
 
+// Phase returns the name of the phase the round is in.
+func (d *DKGDealer) Phase() string {
+	if len(d.transitions) == 0 {
+		return "completed"
+	}
+	if d.completedPhases < len(d.phases) {
+		return d.phases[d.completedPhases]
+	}
+	return "unknown"
+}
+
+// SetThreshold overrides the number of shares needed to recover a signature
+// with the round's verifier; it must be called before the deals are generated.
+func (d *DKGDealer) SetThreshold(threshold int) {
+	d.threshold = threshold
+}
+
+// SetSignDomain sets the domain the messages of the round are verified in, by
+// default the empty chain ID with the latest version, and advertises the
+// capabilities of its version; it must be called before the dealer starts.
+func (d *DKGDealer) SetSignDomain(domain alias.SignDomain) {
+	d.signDomain = domain
+	d.capabilities = types.LocalCapabilities(domain.Version)
+}
+
+// dkgThreshold returns the threshold of the distributed key generator.
+func (d *DKGDealer) dkgThreshold() int {
+	if d.threshold > 0 {
+		return d.threshold
+	}
+	return DealThreshold(d.participants.Size())
+}
+
+// verifierThreshold returns the threshold of the round's verifier.
+func (d *DKGDealer) verifierThreshold() int {
+	if d.threshold > 0 {
+		return d.threshold
+	}
+	return Threshold(d.participants.Size())
+}
+
+func (d *DKGDealer) SetMisbehaviorSink(sink types.MisbehaviorSink) {
+	if sink == nil {
+		return
+	}
+	d.misbehaviorSink = sink
+}
+
+// Snapshot describes the current phase of the round and the messages handled from every participant.
+func (d *DKGDealer) Snapshot() *types.RoundInfo {
+	info := &types.RoundInfo{
+		RoundID:       d.roundID,
+		CorrelationID: types.RoundCorrelationID(d.roundID),
+		Phase: types.PhaseInfo{
+			Name:      d.Phase(),
+			Completed: d.completedPhases,
+			Total:     d.completedPhases + len(d.transitions),
+		},
+	}
+
+	losers := make(map[string]bool)
+	for _, loser := range d.losers {
+		losers[loser.String()] = true
+	}
+	for index, participant := range d.participants.Participants() {
+		peer := types.PeerInfo{
+			Addr:     participant.Address,
+			Index:    index,
+			External: participant.External,
+			Messages: make(map[string]int),
+			Latency:  make(map[string]time.Duration),
+			Loser:    losers[participant.Address.String()],
+		}
+		if caps, ok := d.peerCapabilities[participant.Address.String()]; ok {
+			peer.Capabilities = caps.String()
+		}
+		for dataType, count := range d.received[participant.Address.String()] {
+			peer.Messages[dataType.String()] = count
+		}
+		for dataType, latency := range d.arrivals[participant.Address.String()] {
+			peer.Latency[dataType.String()] = latency
+		}
+		info.Peers = append(info.Peers, peer)
+	}
+	types.ComputeLiveness(info.Peers)
+	info.Progress = d.progress()
+	if d.negotiated != 0 {
+		info.Capabilities = d.negotiated.String()
+	}
+	info.Proofs = d.Proofs()
+
+	return info
+}
+
+func (d *DKGDealer) observeMessage(msg *alias.DKGData) {
+	counts, ok := d.received[msg.GetAddrString()]
+	if !ok {
+		counts = make(map[alias.DKGDataType]int)
+		d.received[msg.GetAddrString()] = counts
+	}
+	counts[msg.Type]++
+	d.phaseMessages[msg.Type]++
+
+	arrivals, ok := d.arrivals[msg.GetAddrString()]
+	if !ok {
+		arrivals = make(map[alias.DKGDataType]time.Duration)
+		d.arrivals[msg.GetAddrString()] = arrivals
+	}
+	if _, ok := arrivals[msg.Type]; !ok {
+		arrivals[msg.Type] = d.now().Sub(d.roundStarted)
+	}
+}
+
+func (d *DKGDealer) reportMisbehavior(msg *alias.DKGData, misbehavior types.MisbehaviorType, err error) {
+	d.misbehaviorSink.ReportMisbehavior(&types.MisbehaviorReport{
+		Type:     misbehavior,
+		Addr:     crypto.Address(msg.Addr),
+		RoundID:  msg.RoundID,
+		DataType: msg.Type,
+		Err:      err,
+		Message:  msg,
+	})
+}
+
+// reportMalformed marks the sender of a message that can not be decoded as a loser.
+func (d *DKGDealer) reportMalformed(msg *alias.DKGData, err error) {
+	d.exclude(crypto.Address(msg.Addr), err, types.EvidenceHash(msg), msg)
+	d.reportMisbehavior(msg, types.MisbehaviorMalformedMessage, err)
+}
+
 //////////////////////////////////////////////////////////////////////////////
 //
 // PHASE I
 //
 //////////////////////////////////////////////////////////////////////////////
 
-func (d *DKGDealer) HandleDKGPubKey(msg *alias.DKGData) error {
-	var (
-		dec    = gob.NewDecoder(bytes.NewBuffer(msg.Data))
-		pubKey = d.suiteG2.Point()
-	)
-	if err := dec.Decode(pubKey); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("dkgState: failed to decode public key from %s: %v", msg.Addr, err)
+// addPubKey stores the key a participant registered. Registrations are told
+// apart by their key: a repeat of the known key is ignored, and a new key
+// replaces the known one until the dealer deals, e.g. after the participant
+// restarted; later ones are ignored, since the deals are encrypted to the known
+// key. A key registered by another participant is rejected.
+func (d *DKGDealer) addPubKey(msg *alias.DKGData, pubKey kyber.Point) error {
+	addr := crypto.Address(msg.Addr)
+	for _, pk := range d.pubKeys {
+		if pk.PK.Equal(pubKey) && !bytes.Equal(pk.Addr, addr) {
+			return types.NewInvalidMessageError(msg, fmt.Errorf("DKG public key of %s registered again by %s", pk.Addr, addr))
+		}
+	}
+	known := d.pubKeys.Get(addr)
+	switch {
+	case known == nil:
+		d.pubKeys.Add(&PK2Addr{PK: pubKey, Addr: addr})
+	case known.Equal(pubKey):
+		return nil
+	case d.instance != nil || d.dealSeen:
+		// Once a participant dealt, its deals are encrypted to the known key.
+		d.logger.Info("dkgState: ignoring registration of a new key after the deals started", "from", addr)
+		return nil
+	default:
+		d.logger.Info("dkgState: participant registered a new key", "from", addr)
+		d.pubKeys.replace(addr, pubKey)
+		return nil
 	}
-	// TODO: check if we want to slash validators who send duplicate keys
-	// (we probably do).
-	d.pubKeys.Add(&PK2Addr{PK: pubKey, Addr: crypto.Address(msg.Addr)})
 
 	if err := d.Transit(); err != nil {
 		return fmt.Errorf("failed to Transit: %v", err)
@@ -241,7 +529,10 @@ func (d *DKGDealer) SendDeals() (error, bool) {
 		d.logger.Debug("DKG send deals: dealer is not ready")
 		return nil, false
 	}
-	d.eventFirer.FireEvent(types.EventDKGPubKeyReceived, nil)
+	d.eventFirer.FireEvent(types.EventDKGPubKeyReceived, d.phaseEvent())
+	if err := d.negotiateCapabilities(); err != nil {
+		return err, true
+	}
 
 	messages, err := d.GetDeals()
 	if err != nil {
@@ -258,14 +549,14 @@ func (d *DKGDealer) SendDeals() (error, bool) {
 }
 
 func (d *DKGDealer) IsPubKeysReady() bool {
-	return len(d.pubKeys) == d.validators.Size()
+	return len(d.pubKeys) == d.participants.Size()
 }
 
 func (d *DKGDealer) GetDeals() ([]*alias.DKGData, error) {
 	d.logger.Debug("DKGDealer get deals start")
 	// It's needed for DistKeyGenerator and for binary search in array
 	sort.Sort(d.pubKeys)
-	dkgInstance, err := dkg.NewDistKeyGenerator(d.suiteG2, d.secKey, d.pubKeys.GetPKs(), (d.validators.Size()*2)/3)
+	dkgInstance, err := dkg.NewDistKeyGenerator(d.dkgSuite(), d.secKey, d.pubKeys.GetPKs(), d.dkgThreshold())
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dkgState instance: %v", err)
 	}
@@ -280,6 +571,9 @@ func (d *DKGDealer) GetDeals() ([]*alias.DKGData, error) {
 		d.participantID = int(deal.Index) // Same for each deal.
 		break
 	}
+	if index, _ := d.participants.GetByAddress(d.addrBytes); len(deals) > 0 && d.participantID != index {
+		return nil, fmt.Errorf("dealt as participant %d instead of %d, see types.AssignIndices", d.participantID, index)
+	}
 
 	var dealMessages []*alias.DKGData
 	for toIndex, deal := range deals {
@@ -308,6 +602,8 @@ func (d *DKGDealer) GetDeals() ([]*alias.DKGData, error) {
 }
 
 func (d *DKGDealer) HandleDKGDeal(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	var (
 		dec  = gob.NewDecoder(bytes.NewBuffer(msg.Data))
 		deal = &dkg.Deal{ // We need to initialize everything down to the kyber.Point to avoid nil panics.
@@ -317,9 +613,10 @@ func (d *DKGDealer) HandleDKGDeal(msg *alias.DKGData) error {
 		}
 	)
 	if err := dec.Decode(deal); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("failed to decode deal: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode deal: %v", err))
 	}
+	d.dealSeen = true
 
 	// We expect to keep N - 1 deals (we don't care about the deals sent to other participants).
 	if d.participantID != msg.ToIndex {
@@ -328,9 +625,12 @@ func (d *DKGDealer) HandleDKGDeal(msg *alias.DKGData) error {
 	}
 
 	d.logger.Info("dkgState: deal is intended for us, storing")
-	if _, exists := d.deals[msg.GetAddrString()]; exists {
-		d.logger.Debug("DKGDealer deals message already exists", "roundID", msg.RoundID, "msgAddr", msg.Addr)
-		return nil
+	if first := d.firstInSlot(msg, fmt.Sprintf("deal/%d", msg.ToIndex)); first != nil {
+		if isRepeat(first, msg) {
+			d.logger.Debug("DKGDealer deals message already exists", "msgAddr", msg.Addr)
+			return nil
+		}
+		return d.equivocate(first, msg, errors.New("conflicting deals"))
 	}
 
 	d.deals[msg.GetAddrString()] = deal
@@ -362,15 +662,15 @@ func (d *DKGDealer) ProcessDeals() (error, bool) {
 }
 
 func (d *DKGDealer) IsDealsReady() bool {
-	return len(d.deals) >= d.validators.Size()-1
+	return len(d.deals) >= d.participants.Size()-1
 }
 
 func (d *DKGDealer) GetResponses() ([]*alias.DKGData, error) {
 	var messages []*alias.DKGData
 	d.logger.Debug("DKGDealer get responses start")
 	// Each deal produces a response for the deal's issuer (that makes N - 1 responses).
-	for _, deal := range d.deals {
-		resp, err := d.instance.ProcessDeal(deal)
+	for _, addr := range sortedAddrs(d.deals) {
+		resp, err := d.instance.ProcessDeal(d.deals[addr])
 		if err != nil {
 			return messages, fmt.Errorf("failed to ProcessDeal: %v", err)
 		}
@@ -389,20 +689,22 @@ func (d *DKGDealer) GetResponses() ([]*alias.DKGData, error) {
 			Data:    buf.Bytes(),
 		})
 	}
-	d.eventFirer.FireEvent(types.EventDKGDealsProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGDealsProcessed, d.phaseEvent())
 
 	d.logger.Debug("DKGDealer get responses finish")
 	return messages, nil
 }
 
 func (d *DKGDealer) HandleDKGResponse(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	var (
 		dec  = gob.NewDecoder(bytes.NewBuffer(msg.Data))
 		resp = &dkg.Response{}
 	)
 	if err := dec.Decode(resp); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("failed to response deal: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to response deal: %v", err))
 	}
 
 	// Unlike the procedure for deals, with responses we do care about other
@@ -415,6 +717,12 @@ func (d *DKGDealer) HandleDKGResponse(msg *alias.DKGData) error {
 	}
 
 	d.logger.Info("dkgState: response is intended for us, storing")
+	if first := d.firstInSlot(msg, fmt.Sprintf("response/%d", resp.Index)); first != nil {
+		if isRepeat(first, msg) {
+			return nil
+		}
+		return d.equivocate(first, msg, errors.New("conflicting responses"))
+	}
 
 	d.responses.add(msg.GetAddrString(), 0, resp)
 
@@ -445,7 +753,7 @@ func (d *DKGDealer) ProcessResponses() (error, bool) {
 }
 
 func (d *DKGDealer) IsResponsesReady() bool {
-	return d.responses.messagesCount >= int(math.Pow(float64(d.validators.Size()-1), 2))
+	return d.responses.messagesCount >= int(math.Pow(float64(d.participants.Size()-1), 2))
 }
 
 func (d *DKGDealer) processResponse(resp *dkg.Response) ([]byte, error) {
@@ -476,13 +784,15 @@ func (d *DKGDealer) processResponse(resp *dkg.Response) ([]byte, error) {
 func (d *DKGDealer) GetJustifications() ([]*alias.DKGData, error) {
 	var messages []*alias.DKGData
 	d.logger.Debug("DKG delaer get justification start")
-	for _, peerResponses := range d.responses.addrToData {
+	for _, addr := range d.responses.addrs() {
+		peerResponses := d.responses.addrToData[addr]
 		for _, response := range peerResponses {
 			resp := response.(*dkg.Response)
 			var msg = &alias.DKGData{
 				Type:    alias.DKGJustification,
 				RoundID: d.roundID,
 				Addr:    d.addrBytes,
+				ToIndex: len(messages), // Tells nil justifications apart, see DKGData.ToIndex.
 			}
 
 			// Each of (N - 1) ^ 2 received response generates a (possibly nil) justification.
@@ -501,18 +811,20 @@ func (d *DKGDealer) GetJustifications() ([]*alias.DKGData, error) {
 	}
 
 	d.logger.Debug("DKG dealer get justification finish")
-	d.eventFirer.FireEvent(types.EventDKGResponsesProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGResponsesProcessed, d.phaseEvent())
 	return messages, nil
 }
 
 func (d *DKGDealer) HandleDKGJustification(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	var justification *dkg.Justification
 	if msg.Data != nil {
 		dec := gob.NewDecoder(bytes.NewBuffer(msg.Data))
 		justification = &dkg.Justification{}
 		if err := dec.Decode(justification); err != nil {
-			d.losers = append(d.losers, crypto.Address(msg.Addr))
-			return fmt.Errorf("failed to decode justification: %v", err)
+			d.reportMalformed(msg, err)
+			return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode justification: %v", err))
 		}
 	}
 
@@ -566,7 +878,7 @@ func (d *DKGDealer) ProcessJustifications() (error, bool) {
 
 func (d *DKGDealer) IsJustificationsReady() bool {
 	// N * (N - 1) ^ 2.
-	return d.justifications.messagesCount >= d.validators.Size()*int(math.Pow(float64(d.validators.Size()-1), 2))
+	return d.justifications.messagesCount >= d.participants.Size()*int(math.Pow(float64(d.participants.Size()-1), 2))
 }
 
 func (d DKGDealer) GetCommits() (*dkg.SecretCommits, error) {
@@ -583,16 +895,16 @@ func (d DKGDealer) GetCommits() (*dkg.SecretCommits, error) {
 			}
 		}
 	}
-	d.eventFirer.FireEvent(types.EventDKGJustificationsProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGJustificationsProcessed, d.phaseEvent())
 
 	if !d.instance.Certified() {
 		return nil, errors.New("instance is not certified")
 	}
-	d.eventFirer.FireEvent(types.EventDKGInstanceCertified, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGInstanceCertified, d.phaseEvent())
 
 	qual := d.instance.QUAL()
 	d.logger.Info("dkgState: got the QUAL set", "qual", qual)
-	if len(qual) < d.validators.Size() {
+	if len(qual) < d.participants.Size() {
 		qualSet := map[int]bool{}
 		for _, idx := range qual {
 			qualSet[idx] = true
@@ -600,7 +912,7 @@ func (d DKGDealer) GetCommits() (*dkg.SecretCommits, error) {
 
 		for idx, pk2addr := range d.pubKeys {
 			if !qualSet[idx] {
-				d.losers = append(d.losers, pk2addr.Addr)
+				d.exclude(pk2addr.Addr, errors.New("not qualified after phase I"), nil)
 			}
 		}
 
@@ -622,14 +934,22 @@ func (d DKGDealer) GetCommits() (*dkg.SecretCommits, error) {
 //////////////////////////////////////////////////////////////////////////////
 
 func (d *DKGDealer) HandleDKGCommit(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	dec := gob.NewDecoder(bytes.NewBuffer(msg.Data))
 	commits := &dkg.SecretCommits{}
 	for i := 0; i < msg.NumEntities; i++ {
 		commits.Commitments = append(commits.Commitments, d.suiteG2.Point())
 	}
 	if err := dec.Decode(commits); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("failed to decode commit: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode commit: %v", err))
+	}
+	first := d.firstInSlot(msg, "commits")
+	for _, c := range d.commits.addrToData[msg.GetAddrString()] {
+		if !equalCommits(c.(*dkg.SecretCommits), commits) {
+			return d.equivocate(first, msg, errors.New("conflicting secret commits"))
+		}
 	}
 	d.commits.add(msg.GetAddrString(), 0, commits)
 
@@ -649,13 +969,15 @@ func (d *DKGDealer) ProcessCommits() (error, bool) {
 
 	var alreadyFinished = true
 	var messages []*alias.DKGData
-	for _, commitsFromAddr := range d.commits.addrToData {
+	for _, addr := range d.commits.addrs() {
+		commitsFromAddr := d.commits.addrToData[addr]
 		for _, c := range commitsFromAddr {
 			commits := c.(*dkg.SecretCommits)
 			var msg = &alias.DKGData{
 				Type:    alias.DKGComplaint,
 				RoundID: d.roundID,
 				Addr:    d.addrBytes,
+				ToIndex: len(messages),
 			}
 			complaint, err := d.instance.ProcessSecretCommits(commits)
 			if err != nil {
@@ -677,7 +999,7 @@ func (d *DKGDealer) ProcessCommits() (error, bool) {
 			messages = append(messages, msg)
 		}
 	}
-	d.eventFirer.FireEvent(types.EventDKGCommitsProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGCommitsProcessed, d.phaseEvent())
 
 	if !alreadyFinished {
 		for _, msg := range messages {
@@ -693,6 +1015,8 @@ func (d *DKGDealer) ProcessCommits() (error, bool) {
 }
 
 func (d *DKGDealer) HandleDKGComplaint(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	var complaint *dkg.ComplaintCommits
 	if msg.Data != nil {
 		dec := gob.NewDecoder(bytes.NewBuffer(msg.Data))
@@ -703,8 +1027,8 @@ func (d *DKGDealer) HandleDKGComplaint(msg *alias.DKGData) error {
 			complaint.Deal.Commitments = append(complaint.Deal.Commitments, d.suiteG2.Point())
 		}
 		if err := dec.Decode(complaint); err != nil {
-			d.losers = append(d.losers, crypto.Address(msg.Addr))
-			return fmt.Errorf("failed to decode complaint: %v", err)
+			d.reportMalformed(msg, err)
+			return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode complaint: %v", err))
 		}
 	}
 
@@ -724,14 +1048,18 @@ func (d *DKGDealer) ProcessComplaints() (error, bool) {
 	}
 	d.logger.Info("dkgState: processing commits")
 
-	for _, peerComplaints := range d.complaints.addrToData {
+	var index int
+	for _, addr := range d.complaints.addrs() {
+		peerComplaints := d.complaints.addrToData[addr]
 		for _, c := range peerComplaints {
 			complaint := c.(*dkg.ComplaintCommits)
 			var msg = &alias.DKGData{
 				Type:    alias.DKGReconstructCommit,
 				RoundID: d.roundID,
 				Addr:    d.addrBytes,
+				ToIndex: index,
 			}
+			index++
 			if complaint != nil {
 				reconstructionMsg, err := d.instance.ProcessComplaintCommits(complaint)
 				if err != nil {
@@ -756,18 +1084,20 @@ func (d *DKGDealer) ProcessComplaints() (error, bool) {
 		}
 	}
 	d.logger.Debug("DKG process complaints success")
-	d.eventFirer.FireEvent(types.EventDKGComplaintProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGComplaintProcessed, d.phaseEvent())
 	return nil, true
 }
 
 func (d *DKGDealer) HandleDKGReconstructCommit(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	var rc *dkg.ReconstructCommits
 	if msg.Data != nil {
 		dec := gob.NewDecoder(bytes.NewBuffer(msg.Data))
 		rc = &dkg.ReconstructCommits{}
 		if err := dec.Decode(rc); err != nil {
-			d.losers = append(d.losers, crypto.Address(msg.Addr))
-			return fmt.Errorf("failed to decode complaint: %v", err)
+			d.reportMalformed(msg, err)
+			return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode complaint: %v", err))
 		}
 	}
 
@@ -787,7 +1117,8 @@ func (d *DKGDealer) ProcessReconstructCommits() (error, bool) {
 		return nil, false
 	}
 
-	for _, peerReconstructCommits := range d.reconstructCommits.addrToData {
+	for _, addr := range d.reconstructCommits.addrs() {
+		peerReconstructCommits := d.reconstructCommits.addrToData[addr]
 		for _, reconstructCommit := range peerReconstructCommits {
 			rc := reconstructCommit.(*dkg.ReconstructCommits)
 			if rc == nil {
@@ -798,7 +1129,7 @@ func (d *DKGDealer) ProcessReconstructCommits() (error, bool) {
 			}
 		}
 	}
-	d.eventFirer.FireEvent(types.EventDKGReconstructCommitsProcessed, d.roundID)
+	d.eventFirer.FireEvent(types.EventDKGReconstructCommitsProcessed, d.phaseEvent())
 
 	if !d.instance.Finished() {
 		return errors.New("dkgState round is finished, but dkgState instance is not ready"), true
@@ -824,7 +1155,7 @@ func (d *DKGDealer) GetVerifier() (types.Verifier, error) {
 			Pub:  &share.PubShare{I: d.participantID, V: d.pubKey},
 			Priv: distKeyShare.PriShare(),
 		}
-		t, n = (d.validators.Size() / 3) * 2 + 1, d.validators.Size()
+		t, n = d.verifierThreshold(), d.participants.Size()
 	)
 
 	return blsShare.NewBLSVerifier(masterPubKey, newShare, t, n), nil
@@ -832,19 +1163,7 @@ func (d *DKGDealer) GetVerifier() (types.Verifier, error) {
 
 // VerifyMessage verify message by signature
 func (d *DKGDealer) VerifyMessage(msg types.DKGDataMessage) error {
-	var (
-		signBytes []byte
-	)
-	_, validator := d.validators.GetByAddress(msg.Data.Addr)
-	if validator == nil {
-		return fmt.Errorf("can't find validator by address: %s", msg.Data.GetAddrString())
-	}
-
-	signBytes = msg.Data.SignBytes("")
-	if !validator.PubKey.VerifyBytes(signBytes, msg.Data.Signature) {
-		return fmt.Errorf("invalid DKG message signature: %s", hex.EncodeToString(msg.Data.Signature))
-	}
-	return nil
+	return d.verifySignature(msg.Data.Addr, d.signDomain.SignBytes(msg.Data), msg.Data.Signature)
 }
 
 func (d *DKGDealer) SendMsgCb(msg []*alias.DKGData) error {
@@ -869,9 +1188,28 @@ func (s *PKStore) Add(newPk *PK2Addr) bool {
 	return true
 }
 
+// replace sets the key of the address.
+func (s PKStore) replace(addr crypto.Address, pk kyber.Point) {
+	for _, stored := range s {
+		if bytes.Equal(stored.Addr, addr) {
+			stored.PK = pk
+		}
+	}
+}
+
+// Get returns the key registered for the address, if any.
+func (s PKStore) Get(addr crypto.Address) kyber.Point {
+	for _, pk := range s {
+		if bytes.Equal(pk.Addr, addr) {
+			return pk.PK
+		}
+	}
+	return nil
+}
+
 func (s PKStore) Len() int           { return len(s) }
 func (s PKStore) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
-func (s PKStore) Less(i, j int) bool { return s[i].Addr.String() < s[j].Addr.String() }
+func (s PKStore) Less(i, j int) bool { return bytes.Compare(s[i].Addr, s[j].Addr) < 0 }
 func (s PKStore) GetPKs() []kyber.Point {
 	var out = make([]kyber.Point, len(s))
 	for idx, val := range s {
@@ -880,6 +1218,18 @@ func (s PKStore) GetPKs() []kyber.Point {
 	return out
 }
 
+func equalCommits(a, b *dkg.SecretCommits) bool {
+	if a.Index != b.Index || len(a.Commitments) != len(b.Commitments) {
+		return false
+	}
+	for i := range a.Commitments {
+		if !a.Commitments[i].Equal(b.Commitments[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 type transition func() (error, bool)
 
 type Justification struct {
@@ -910,6 +1260,27 @@ func newMessageStore(n int) *messageStore {
 	}
 }
 
+// addrs returns the addresses of the peers in order, so a seeded dealer
+// processes their messages, and draws its randomness, alike on every run.
+func (ms *messageStore) addrs() []string {
+	addrs := make([]string, 0, len(ms.addrToData))
+	for addr := range ms.addrToData {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
+// sortedAddrs returns the senders of the deals in order, see messageStore.addrs.
+func sortedAddrs(deals map[string]*dkg.Deal) []string {
+	addrs := make([]string, 0, len(deals))
+	for addr := range deals {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
 func (ms *messageStore) add(addr string, index int, val interface{}) {
 	data := ms.addrToData[addr]
 	if len(data) == ms.maxMessagesFromPeer {
@@ -6,8 +6,9 @@ import (
 	"fmt"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/types"
 	dkg "go.dedis.ch/kyber/v3/share/dkg/rabin"
 )
@@ -16,8 +17,8 @@ type DKGMockDontSendOneCommit struct {
 	Dealer
 }
 
-func NewDKGMockDealerNoCommit(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendOneCommit{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound)}
+func NewDKGMockDealerNoCommit(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendOneCommit{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound)}
 }
 
 func (m *DKGMockDontSendOneCommit) Start() error {
@@ -86,11 +87,11 @@ func (m *DKGMockDontSendOneCommit) GetCommits() (*dkg.SecretCommits, error) {
 
 type DKGMockDontSendAnyCommits struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerAnyCommits(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendAnyCommits{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerAnyCommits(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendAnyCommits{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendAnyCommits) Start() error {
@@ -4,18 +4,19 @@ import (
 	"errors"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/types"
 )
 
 type DKGMockDontSendOneDeal struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerNoDeal(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendOneDeal{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerNoDeal(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendOneDeal{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendOneDeal) Start() error {
@@ -76,11 +77,11 @@ func (m *DKGMockDontSendOneDeal) GetDeals() ([]*alias.DKGData, error) {
 
 type DKGMockDontSendAnyDeal struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerAnyDeal(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendAnyDeal{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerAnyDeal(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendAnyDeal{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendAnyDeal) Start() error {
@@ -4,18 +4,19 @@ import (
 	"errors"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/types"
 )
 
 type DKGMockDontSendOneJustification struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerNoJustification(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendOneJustification{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerNoJustification(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendOneJustification{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendOneJustification) Start() error {
@@ -78,11 +79,11 @@ func (m *DKGMockDontSendOneJustification) GetResponses() ([]*alias.DKGData, erro
 
 type DKGMockDontSendAnyJustifications struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerAnyJustifications(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendAnyJustifications{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerAnyJustifications(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendAnyJustifications{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendAnyJustifications) Start() error {
@@ -4,18 +4,19 @@ import (
 	"errors"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	"github.com/tendermint/tendermint/types"
 )
 
 type DKGMockDontSendOneResponse struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerNoResponse(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendOneResponse{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerNoResponse(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendOneResponse{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendOneResponse) Start() error {
@@ -76,11 +77,11 @@ func (m *DKGMockDontSendOneResponse) GetResponses() ([]*alias.DKGData, error) {
 
 type DKGMockDontSendAnyResponses struct {
 	Dealer
-	logger log.Logger
+	logger logging.Logger
 }
 
-func NewDKGMockDealerAnyResponses(validators *types.ValidatorSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger log.Logger, startRound int) Dealer {
-	return &DKGMockDontSendAnyResponses{NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound), logger}
+func NewDKGMockDealerAnyResponses(participants *dkgtypes.ParticipantSet, pv types.PrivValidator, sendMsgCb func([]*alias.DKGData) error, eventFirer events.Fireable, logger logging.Logger, startRound int) Dealer {
+	return &DKGMockDontSendAnyResponses{NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound), logger}
 }
 
 func (m *DKGMockDontSendAnyResponses) Start() error {
@@ -0,0 +1,60 @@
+package dealer
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// firstInSlot returns the message the sender sent first for the slot, e.g.
+// the deal to a participant, or records msg and returns nil if it is the
+// first one.
+func (d *DKGDealer) firstInSlot(msg *alias.DKGData, slot string) *alias.DKGData {
+	key := msg.GetAddrString() + "/" + slot
+	if first, ok := d.slots[key]; ok {
+		return first
+	}
+	d.slots[key] = msg
+	return nil
+}
+
+// isRepeat reports whether msg repeats the first message of its slot.
+func isRepeat(first, msg *alias.DKGData) bool {
+	return bytes.Equal(first.ContentBytes(), msg.ContentBytes())
+}
+
+// equivocate excludes the sender of the conflicting messages, keeping the
+// first one, and reports them with the evidence. The round goes on without
+// the sender unless too few participants are left to reach the threshold.
+func (d *DKGDealer) equivocate(first, second *alias.DKGData, reason error) error {
+	addr := crypto.Address(second.Addr)
+	if first != nil {
+		d.exclude(addr, reason, types.EvidenceHash(first, second), first, second)
+	} else {
+		d.exclude(addr, reason, types.EvidenceHash(second), second)
+	}
+	report := &types.MisbehaviorReport{
+		Type:     types.MisbehaviorEquivocation,
+		Addr:     addr,
+		RoundID:  second.RoundID,
+		DataType: second.Type,
+		Err:      reason,
+	}
+	if first != nil {
+		report.Evidence = &types.EquivocationEvidence{First: first, Second: second}
+	}
+	d.misbehaviorSink.ReportMisbehavior(report)
+
+	var (
+		size      = d.participants.Size()
+		threshold = d.verifierThreshold()
+	)
+	if left := size - len(d.losers); left < threshold {
+		return fmt.Errorf("equivocation of %s leaves %d participants, %d needed: %v", addr, left, threshold, reason)
+	}
+	d.logger.Info("DKGDealer: excluded equivocating participant", "addr", addr, "type", second.Type, "error", reason)
+	return nil
+}
@@ -0,0 +1,189 @@
+package dealer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/rabin"
+)
+
+// InvariantError lists the dealer invariants violated by a round, with a dump
+// of the dealer's state for debugging.
+type InvariantError struct {
+	RoundID    int
+	Violations []string
+	Dump       string
+}
+
+func (e *InvariantError) Error() string {
+	return fmt.Sprintf("round %d violates dealer invariants: %s", e.RoundID, strings.Join(e.Violations, "; "))
+}
+
+// CheckInvariants validates the round state: stored messages come from the
+// participants and don't exceed the expected counts, phases never regress and
+// the commit matrix is consistent with the participants. It returns an
+// *InvariantError on violation. The checks are meant for development.
+func (d *DKGDealer) CheckInvariants() error {
+	var (
+		violations   []string
+		participants = make(map[string]bool)
+		size         = d.participants.Size()
+	)
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+	for _, participant := range d.participants.Participants() {
+		participants[participant.Address.String()] = true
+	}
+
+	if len(d.pubKeys) > size {
+		violate("%d public keys for %d participants", len(d.pubKeys), size)
+	}
+	seen := make(map[string]bool)
+	for _, pk := range d.pubKeys {
+		if !participants[pk.Addr.String()] {
+			violate("public key of non-participant %s", pk.Addr)
+		}
+		if seen[pk.Addr.String()] {
+			violate("several public keys of %s", pk.Addr)
+		}
+		seen[pk.Addr.String()] = true
+	}
+
+	if len(d.deals) > size-1 {
+		violate("%d deals for %d participants", len(d.deals), size)
+	}
+	for addr := range d.deals {
+		if !participants[addr] {
+			violate("deal of non-participant %s", addr)
+		}
+	}
+
+	for _, stored := range []struct {
+		name  string
+		store *messageStore
+	}{
+		{"responses", d.responses},
+		{"justifications", d.justifications},
+		{"commits", d.commits},
+		{"complaints", d.complaints},
+		{"reconstruct commits", d.reconstructCommits},
+	} {
+		name, store := stored.name, stored.store
+		var total int
+		for addr, data := range store.addrToData {
+			if !participants[addr] {
+				violate("%s of non-participant %s", name, addr)
+			}
+			if len(data) > store.maxMessagesFromPeer {
+				violate("%d %s of %s, at most %d expected", len(data), name, addr, store.maxMessagesFromPeer)
+			}
+			total += len(data)
+		}
+		if total != store.messagesCount {
+			violate("%d %s stored, %d counted", total, name, store.messagesCount)
+		}
+	}
+
+	d.checkCommitMatrix(violate)
+
+	if d.completedPhases < d.checkedPhases {
+		violate("phase regressed from %d to %d", d.checkedPhases, d.completedPhases)
+	}
+	d.checkedPhases = d.completedPhases
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &InvariantError{RoundID: d.roundID, Violations: violations, Dump: d.dump()}
+}
+
+// checkCommitMatrix checks that the secret commits of the off-chain protocol
+// form a matrix with one row per dealer, indexed like the sorted public keys.
+func (d *DKGDealer) checkCommitMatrix(violate func(format string, args ...interface{})) {
+	if d.instance == nil {
+		return
+	}
+	indices := make(map[string]int)
+	for index, pk := range d.pubKeys {
+		indices[pk.Addr.String()] = index
+	}
+	var (
+		width = -1
+		rows  = make(map[uint32]string)
+	)
+	for addr, data := range d.commits.addrToData {
+		for _, c := range data {
+			commits, ok := c.(*dkg.SecretCommits)
+			if !ok {
+				continue
+			}
+			if index, ok := indices[addr]; !ok || uint32(index) != commits.Index {
+				violate("commits of %s have index %d", addr, commits.Index)
+			}
+			if other, ok := rows[commits.Index]; ok && other != addr {
+				violate("commits of %s and %s have the same index %d", other, addr, commits.Index)
+			}
+			rows[commits.Index] = addr
+			if width >= 0 && len(commits.Commitments) != width {
+				violate("commits of %s have %d commitments, others have %d", addr, len(commits.Commitments), width)
+			}
+			width = len(commits.Commitments)
+		}
+	}
+}
+
+func (d *DKGDealer) dump() string {
+	state := struct {
+		Snapshot        *types.RoundInfo `json:"snapshot"`
+		PendingPhases   int              `json:"pending_phases"`
+		PubKeys         int              `json:"pub_keys"`
+		Deals           int              `json:"deals"`
+		StoredMessages  map[string]int   `json:"stored_messages"`
+		ParticipantID   int              `json:"participant_id"`
+		InstanceCreated bool             `json:"instance_created"`
+	}{
+		Snapshot:      d.Snapshot(),
+		PendingPhases: len(d.transitions),
+		PubKeys:       len(d.pubKeys),
+		Deals:         len(d.deals),
+		StoredMessages: map[string]int{
+			"responses":           d.responses.messagesCount,
+			"justifications":      d.justifications.messagesCount,
+			"commits":             d.commits.messagesCount,
+			"complaints":          d.complaints.messagesCount,
+			"reconstruct_commits": d.reconstructCommits.messagesCount,
+		},
+		ParticipantID:   d.participantID,
+		InstanceCreated: d.instance != nil,
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("failed to dump dealer state: %v", err)
+	}
+	return string(data)
+}
+
+// InvariantMiddleware checks the invariants of the dealer after every message
+// it handles and panics with a dump of its state on violation, so protocol bugs
+// fail loudly during development.
+func InvariantMiddleware(d Dealer, logger logging.Logger) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg *alias.DKGData) error {
+			handleErr := next(msg)
+			if err := d.CheckInvariants(); err != nil {
+				var dump string
+				if invariantErr, ok := err.(*InvariantError); ok {
+					dump = invariantErr.Dump
+				}
+				logger.Error("DKGDealer invariants violated", "type", msg.Type, "from", msg.GetAddrString(), "error", err, "dump", dump)
+				panic(fmt.Sprintf("%v after handling %s from %s\n%s", err, msg.Type, msg.GetAddrString(), dump))
+			}
+			return handleErr
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package dealer
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+func newTestValidators(t testing.TB, n int) ([]tmtypes.PrivValidator, *types.ParticipantSet) {
+	var (
+		pvs        = make([]tmtypes.PrivValidator, n)
+		validators = make([]*tmtypes.Validator, n)
+	)
+	for i := range pvs {
+		pvs[i] = tmtypes.NewMockPVWithParams(ed25519.GenPrivKey(), false, false)
+		pubKey := pvs[i].GetPubKey()
+		validators[i] = &tmtypes.Validator{Address: pubKey.Address(), PubKey: pubKey, VotingPower: 1}
+	}
+	return pvs, types.NewParticipantSet(tmtypes.NewValidatorSet(validators), nil)
+}
+
+// newTestDealer returns a started dealer of the validator whose messages,
+// signed, are appended to sent.
+func newTestDealer(t testing.TB, participants *types.ParticipantSet, pv tmtypes.PrivValidator, sent *[]*alias.DKGData) Dealer {
+	sendMsgCb := func(data []*alias.DKGData) error {
+		for _, item := range data {
+			if err := pv.SignData("", item); err != nil {
+				return err
+			}
+			*sent = append(*sent, item)
+		}
+		return nil
+	}
+	d := NewDKGDealer(participants, pv, sendMsgCb, nopFirer{}, logging.NewNopLogger(), 0)
+	if err := d.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+// TestLosersIgnoreMessageOrder feeds the same messages, two of them malformed
+// and one of those repeated, in shuffled orders and checks the losers are the
+// same every time.
+func TestLosersIgnoreMessageOrder(t *testing.T) {
+	const n = 5
+	pvs, participants := newTestValidators(t, n)
+
+	var messages []*alias.DKGData
+	for _, pv := range pvs[1:3] {
+		newTestDealer(t, participants, pv, &messages)
+	}
+	var malformed []*alias.DKGData
+	for _, pv := range pvs[3:] {
+		msg := &alias.DKGData{Type: alias.DKGRegistration, Addr: pv.GetPubKey().Address(), Data: []byte("not a registration")}
+		if err := pv.SignData("", msg); err != nil {
+			t.Fatal(err)
+		}
+		malformed = append(malformed, msg)
+	}
+	messages = append(messages, malformed...)
+	messages = append(messages, malformed[0])
+
+	var want []*types.Loser
+	for seed := int64(0); seed < 20; seed++ {
+		var own []*alias.DKGData
+		d := newTestDealer(t, participants, pvs[0], &own)
+		shuffled := append([]*alias.DKGData(nil), messages...)
+		rand.New(rand.NewSource(seed)).Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		for _, msg := range shuffled {
+			// Malformed messages fail; the losers are what matters.
+			_ = Handler(d, msg.Type)(msg)
+		}
+
+		losers := d.GetLosersWithReasons()
+		if seed == 0 {
+			want = losers
+			continue
+		}
+		if !reflect.DeepEqual(losers, want) {
+			t.Fatalf("seed %d: losers %v, want %v", seed, losers, want)
+		}
+	}
+
+	if len(want) != len(malformed) {
+		t.Fatalf("%d losers, want %d", len(want), len(malformed))
+	}
+	for i, loser := range want {
+		if i > 0 && bytes.Compare(want[i-1].Validator.Address, loser.Validator.Address) >= 0 {
+			t.Fatalf("losers are not sorted by address")
+		}
+		if loser.Blame.Reason == "" || len(loser.Blame.Evidence) == 0 {
+			t.Fatalf("loser %s has no reason or evidence", loser.Validator.Address)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package dealer
+
+import (
+	"github.com/corestario/dkglib/lib/alias"
+)
+
+// HandlerFunc handles a single DKG message.
+type HandlerFunc func(msg *alias.DKGData) error
+
+// Middleware wraps a handler, e.g. to log, measure, validate or rate limit
+// messages; it may call next or reject the message by returning an error.
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Chain wraps the handler into the middlewares, the first one being the outermost.
+func Chain(handler HandlerFunc, middlewares ...Middleware) HandlerFunc {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
+
+// Handler returns the dealer's handler of the message type, or nil if the
+// type isn't handled by dealers.
+func Handler(d Dealer, dataType alias.DKGDataType) HandlerFunc {
+	switch dataType {
+	case alias.DKGRegistration:
+		return d.HandleDKGRegistration
+	case alias.DKGDeal:
+		return d.HandleDKGDeal
+	case alias.DKGResponse:
+		return d.HandleDKGResponse
+	case alias.DKGJustification:
+		return d.HandleDKGJustification
+	case alias.DKGCommits:
+		return d.HandleDKGCommit
+	case alias.DKGComplaint:
+		return d.HandleDKGComplaint
+	case alias.DKGReconstructCommit:
+		return d.HandleDKGReconstructCommit
+	case alias.DKGAbort:
+		return d.HandleDKGAbort
+	}
+	return nil
+}
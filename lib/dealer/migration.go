@@ -0,0 +1,72 @@
+package dealer
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// MigrateParticipant lets a participant that rotated its consensus key sign
+// the round's messages with the new key. It keeps its index in the committee
+// and the address of its dealer messages; messages signed with the old key are
+// still accepted up to the migration's height, see SetHeight.
+func (d *DKGDealer) MigrateParticipant(migration *types.ShareMigration) error {
+	if err := migration.Verify(d.signDomain.ChainID); err != nil {
+		return err
+	}
+	_, participant := d.participants.GetByAddress(migration.OldAddr())
+	if participant == nil || !participant.PubKey.Equals(migration.OldPubKey) {
+		return fmt.Errorf("%s is not a participant of round %d", migration.OldAddr(), d.roundID)
+	}
+	if _, other := d.participants.GetByAddress(migration.NewAddr()); other != nil {
+		return fmt.Errorf("%s is already a participant of round %d", migration.NewAddr(), d.roundID)
+	}
+
+	d.migrations[migration.OldAddr().String()] = migration
+	d.migrations[migration.NewAddr().String()] = migration
+	d.logger.Info("dkgState: participant migrated to a new key",
+		"old_addr", migration.OldAddr(), "new_addr", migration.NewAddr())
+	return nil
+}
+
+// SetHeight sets the height of the last block, which retires the old keys of
+// the migrated participants after their migration's height. The old keys are
+// accepted until it is set.
+func (d *DKGDealer) SetHeight(height int64) {
+	d.height = height
+}
+
+// verifySignature checks the signature of a message from the address against
+// the participant's key and, if it migrated, against its new key; the new
+// address is accepted as the sender as well. The old key is only accepted up
+// to the migration's height.
+func (d *DKGDealer) verifySignature(addr []byte, signBytes, signature []byte) error {
+	keys := d.signerKeys(addr)
+	if len(keys) == 0 {
+		return fmt.Errorf("can't find participant by address: %s", crypto.Address(addr))
+	}
+	for _, key := range keys {
+		if key.VerifyBytes(signBytes, signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid DKG message signature: %s", hex.EncodeToString(signature))
+}
+
+// signerKeys returns the keys the messages from the address may be signed
+// with, see verifySignature.
+func (d *DKGDealer) signerKeys(addr []byte) []crypto.PubKey {
+	migration, migrated := d.migrations[crypto.Address(addr).String()]
+	var keys []crypto.PubKey
+	if _, participant := d.participants.GetByAddress(addr); participant != nil {
+		if !migrated || d.height <= migration.Height {
+			keys = append(keys, participant.PubKey)
+		}
+	}
+	if migrated {
+		keys = append(keys, migration.NewPubKey)
+	}
+	return keys
+}
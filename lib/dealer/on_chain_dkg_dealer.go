@@ -16,10 +16,10 @@ import (
 	"github.com/tendermint/tendermint/crypto"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
 	"github.com/corestario/dkglib/lib/types"
 	tmtypes "github.com/tendermint/tendermint/alias"
 	"github.com/tendermint/tendermint/libs/events"
-	"github.com/tendermint/tendermint/libs/log"
 	dkg "go.dedis.ch/kyber/v3/share/dkg/pedersen"
 	vss "go.dedis.ch/kyber/v3/share/vss/pedersen"
 )
@@ -30,7 +30,15 @@ type onChainDealer struct {
 	deals    map[string]*dkg.Deal
 }
 
+var onChainPhases = []string{
+	"send_commits",
+	"send_deals",
+	"process_deals",
+	"process_responses",
+}
+
 func (d *onChainDealer) GenerateTransitions() {
+	d.completedPhases = 0
 	d.transitions = []transition{
 		d.SendCommits,
 		d.SendDeals,
@@ -40,45 +48,29 @@ func (d *onChainDealer) GenerateTransitions() {
 }
 
 func NewOnChainDKGDealer(
-	validators *tmtypes.ValidatorSet,
+	participants *types.ParticipantSet,
 	pv tmtypes.PrivValidator,
 	sendMsgCb func([]*alias.DKGData) error,
 	eventFirer events.Fireable,
-	logger log.Logger,
+	logger logging.Logger,
 	startRound int,
 ) Dealer {
-	return &onChainDealer{
+	dealer := &onChainDealer{
 		deals:     make(map[string]*dkg.Deal),
-		DKGDealer: NewDKGDealer(validators, pv, sendMsgCb, eventFirer, logger, startRound).(*DKGDealer),
+		DKGDealer: NewDKGDealer(participants, pv, sendMsgCb, eventFirer, logger, startRound).(*DKGDealer),
 	}
+	dealer.phases = onChainPhases
+	dealer.progress = dealer.onChainProgress
+	return dealer
 }
 
 func (d *onChainDealer) Start() error {
-	d.secKey = d.suiteG2.Scalar().Pick(d.suiteG2.RandomStream())
+	d.secKey = d.suiteG2.Scalar().Pick(d.dkgSuite().RandomStream())
 	d.pubKey = d.suiteG2.Point().Mul(d.secKey, nil)
 
 	d.GenerateTransitions()
 
-	var (
-		buf = bytes.NewBuffer(nil)
-		enc = gob.NewEncoder(buf)
-	)
-	if err := enc.Encode(d.pubKey); err != nil {
-		return fmt.Errorf("failed to encode public key: %v", err)
-	}
-
-	d.logger.Info("dkgState: sending pub key", "key", d.pubKey.String())
-	err := d.SendMsgCb([]*alias.DKGData{{
-		Type:    alias.DKGPubKey,
-		RoundID: d.roundID,
-		Addr:    d.addrBytes,
-		Data:    buf.Bytes(),
-	}})
-	if err != nil {
-		return fmt.Errorf("failed to sign message: %v", err)
-	}
-
-	return nil
+	return d.sendRegistration()
 }
 
 func (d *onChainDealer) SendCommits() (error, bool) {
@@ -89,7 +81,7 @@ func (d *onChainDealer) SendCommits() (error, bool) {
 
 	// TODO: fire event.
 
-	instance, err := dkg.NewDistKeyGenerator(d.suiteG2, d.secKey, d.pubKeys.GetPKs(), d.validators.Size())
+	instance, err := dkg.NewDistKeyGenerator(d.dkgSuite(), d.secKey, d.pubKeys.GetPKs(), d.participants.Size())
 	if err != nil {
 		return fmt.Errorf("failed to execute NewDistKeyGenerator: %w", err), false
 	}
@@ -124,12 +116,14 @@ func (d *onChainDealer) SendCommits() (error, bool) {
 }
 
 func (d *onChainDealer) HandleDKGCommit(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	dec := gob.NewDecoder(bytes.NewBuffer(msg.Data))
 	commit := d.suiteG2.Point()
 
 	if err := dec.Decode(commit); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("failed to decode commit: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode commit: %v", err))
 	}
 	d.commits.add(msg.GetAddrString(), 0, commit)
 
@@ -141,12 +135,12 @@ func (d *onChainDealer) HandleDKGCommit(msg *alias.DKGData) error {
 }
 
 func (d *onChainDealer) SendDeals() (error, bool) {
-	d.logger.Debug("SendDeals, awaiting commits", "have", len(d.commits.addrToData), "want", d.validators.Size()-1)
-	if len(d.commits.addrToData) != d.validators.Size()-1 {
+	d.logger.Debug("SendDeals, awaiting commits", "have", len(d.commits.addrToData), "want", d.participants.Size()-1)
+	if len(d.commits.addrToData) != d.participants.Size()-1 {
 		d.logger.Debug("DKG send deals: dealer is not ready", "have", len(d.commits.addrToData))
 		return nil, false
 	}
-	d.eventFirer.FireEvent(types.EventDKGPubKeyReceived, nil)
+	d.eventFirer.FireEvent(types.EventDKGPubKeyReceived, d.phaseEvent())
 
 	deals, err := d.instance.Deals()
 	if err != nil {
@@ -189,11 +183,13 @@ func (d *onChainDealer) SendDeals() (error, bool) {
 }
 
 func (d *onChainDealer) HandleDKGDeal(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
 	d.logger.Info("HandleDKGDeal: received Deal message", "from", msg.GetAddrString())
 	var deal = &dkg.Deal{}
 	if err := deal.Decode(msg.Data); err != nil {
-		d.losers = append(d.losers, msg.Addr)
-		return fmt.Errorf("HandleDKGDeal: failed to decode deal: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("HandleDKGDeal: failed to decode deal: %v", err))
 	}
 
 	// We expect to keep N - 1 deals (we don't care about the deals sent to other participants).
@@ -204,7 +200,7 @@ func (d *onChainDealer) HandleDKGDeal(msg *alias.DKGData) error {
 
 	d.logger.Info("dkgState: deal is intended for us, storing")
 	if _, exists := d.deals[msg.GetAddrString()]; exists {
-		d.logger.Debug("HandleDKGDeal: deals message already exists", "roundID", msg.RoundID, "msgAddr", msg.Addr)
+		d.logger.Debug("HandleDKGDeal: deals message already exists", "msgAddr", msg.Addr)
 		return nil
 	}
 
@@ -217,11 +213,11 @@ func (d *onChainDealer) HandleDKGDeal(msg *alias.DKGData) error {
 }
 
 func (d *onChainDealer) IsDealsReady() bool {
-	return len(d.deals) >= d.validators.Size()-1
+	return len(d.deals) >= d.participants.Size()-1
 }
 
 func (d *onChainDealer) ProcessDeals() (error, bool) {
-	d.logger.Debug("onChainDealer: ProcessDeals: awaiting deals", "have", len(d.deals), "want", d.validators.Size()-1)
+	d.logger.Debug("onChainDealer: ProcessDeals: awaiting deals", "have", len(d.deals), "want", d.participants.Size()-1)
 	if !d.IsDealsReady() {
 		d.logger.Debug("onChainDealer: ProcessDeals: process deals, deals are not ready")
 		return nil, false
@@ -253,7 +249,7 @@ func (d *onChainDealer) ProcessDeals() (error, bool) {
 			if err := loserAddress.Unmarshal(addrBytes); err != nil {
 				return fmt.Errorf("failed to unmarshal loser address: %w", err), false
 			}
-			d.losers = append(d.losers, loserAddress)
+			d.exclude(loserAddress, errors.New("complained about by a participant"), nil)
 		}
 
 		var (
@@ -322,8 +318,8 @@ func (d *onChainDealer) HandleDKGResponse(msg *alias.DKGData) error {
 		resp = &dkg.Response{}
 	)
 	if err := dec.Decode(resp); err != nil {
-		d.losers = append(d.losers, crypto.Address(msg.Addr))
-		return fmt.Errorf("failed to response deal: %v", err)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to response deal: %v", err))
 	}
 
 	// Unlike the procedure for deals, with responses we do care about other
@@ -347,7 +343,7 @@ func (d *onChainDealer) HandleDKGResponse(msg *alias.DKGData) error {
 }
 
 func (d *onChainDealer) ProcessResponses() (error, bool) {
-	d.logger.Debug("onChainDealer: ProcessResponses: awaiting responses", "have", d.responses.messagesCount, "want", int(math.Pow(float64(d.validators.Size()-1), 2)))
+	d.logger.Debug("onChainDealer: ProcessResponses: awaiting responses", "have", d.responses.messagesCount, "want", int(math.Pow(float64(d.participants.Size()-1), 2)))
 
 	if !d.IsResponsesReady() {
 		d.logger.Debug("DKGDealer process responses: responses are not ready")
@@ -392,7 +388,7 @@ func (d *onChainDealer) GetVerifier() (types.Verifier, error) {
 		Pub:  &share.PubShare{I: d.participantID, V: d.pubKey},
 		Priv: distKeyShare.PriShare(),
 	}
-	t, n := (d.validators.Size()/3)*2, d.validators.Size()
+	t, n := (d.participants.Size()/3)*2, d.participants.Size()
 
 	verificationKey := masterPubKey.Eval(distKeyShare.PriShare().I)
 	if verificationKey == nil {
@@ -0,0 +1,142 @@
+package dealer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+)
+
+// DefaultProfileWindow is how long the CPU profile of a slow round is captured.
+const DefaultProfileWindow = 30 * time.Second
+
+// Profiler labels the dealer handlers for pprof and captures profiles of slow
+// rounds, so the cost of the cryptographic handling can be attributed to the
+// rounds, phases and message types it comes from.
+type Profiler struct {
+	mtx       sync.Mutex
+	dir       string
+	slowRound time.Duration
+	window    time.Duration
+	capturing bool // Whether a CPU profile is being captured; there is one per process.
+	logger    logging.Logger
+}
+
+// ProfilerOption sets an optional parameter of the Profiler.
+type ProfilerOption func(*Profiler)
+
+// WithSlowRoundCapture captures a heap profile and a CPU profile of the
+// following window into dir once a round runs longer than slowRound, as
+// dkg-round-<round ID>-heap.pprof and dkg-round-<round ID>-cpu.pprof.
+func WithSlowRoundCapture(dir string, slowRound, window time.Duration) ProfilerOption {
+	return func(p *Profiler) { p.dir, p.slowRound, p.window = dir, slowRound, window }
+}
+
+// NewProfiler creates a profiler labeling the handlers; nothing is captured
+// without WithSlowRoundCapture.
+func NewProfiler(logger logging.Logger, options ...ProfilerOption) *Profiler {
+	p := &Profiler{logger: logger}
+	for _, option := range options {
+		option(p)
+	}
+	if p.window <= 0 {
+		p.window = DefaultProfileWindow
+	}
+	if p.logger == nil {
+		p.logger = logging.NewNopLogger()
+	}
+	return p
+}
+
+// Middleware runs the dealer's handlers with the pprof labels dkg_round,
+// dkg_phase and dkg_msg_type, and captures the profiles of the round if it
+// becomes slow. The round is timed from the creation of the middleware.
+func (p *Profiler) Middleware(d Dealer) Middleware {
+	var (
+		started  = time.Now()
+		captured bool
+	)
+	return func(next HandlerFunc) HandlerFunc {
+		return func(msg *alias.DKGData) error {
+			var err error
+			labels := pprof.Labels(
+				"dkg_round", strconv.Itoa(msg.RoundID),
+				"dkg_phase", phase(d),
+				"dkg_msg_type", msg.Type.String(),
+			)
+			pprof.Do(context.Background(), labels, func(context.Context) {
+				err = next(msg)
+			})
+			if p.slowRound > 0 && !captured && time.Since(started) > p.slowRound {
+				captured = true
+				p.capture(msg.RoundID)
+			}
+			return err
+		}
+	}
+}
+
+// phase returns the phase of the dealer if it tells it.
+func phase(d Dealer) string {
+	if phaser, ok := d.(interface{ Phase() string }); ok {
+		return phaser.Phase()
+	}
+	return "unknown"
+}
+
+// capture writes the heap profile and starts the CPU profile of the round.
+func (p *Profiler) capture(roundID int) {
+	p.logger.Info("DKGDealer: round is slow, capturing profiles", "round_id", roundID, "dir", p.dir)
+	if err := p.writeHeapProfile(roundID); err != nil {
+		p.logger.Error("DKGDealer: failed to capture heap profile", "round_id", roundID, "error", err)
+	}
+	if err := p.startCPUProfile(roundID); err != nil {
+		p.logger.Error("DKGDealer: failed to capture CPU profile", "round_id", roundID, "error", err)
+	}
+}
+
+func (p *Profiler) profilePath(roundID int, kind string) string {
+	return filepath.Join(p.dir, fmt.Sprintf("dkg-round-%d-%s.pprof", roundID, kind))
+}
+
+func (p *Profiler) writeHeapProfile(roundID int) error {
+	f, err := os.Create(p.profilePath(roundID, "heap"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return pprof.Lookup("heap").WriteTo(f, 0)
+}
+
+func (p *Profiler) startCPUProfile(roundID int) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.capturing {
+		return fmt.Errorf("a CPU profile is already being captured")
+	}
+	f, err := os.Create(p.profilePath(roundID, "cpu"))
+	if err != nil {
+		return err
+	}
+	if err := pprof.StartCPUProfile(f); err != nil {
+		f.Close()
+		return err
+	}
+	p.capturing = true
+	time.AfterFunc(p.window, func() {
+		pprof.StopCPUProfile()
+		f.Close()
+		p.mtx.Lock()
+		p.capturing = false
+		p.mtx.Unlock()
+		p.logger.Info("DKGDealer: captured CPU profile", "round_id", roundID, "file", f.Name())
+	})
+	return nil
+}
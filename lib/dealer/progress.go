@@ -0,0 +1,162 @@
+package dealer
+
+import (
+	"bytes"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Progress returns, for every phase of the round, how many messages it
+// expects and has received and which participants it waits for.
+func (d *DKGDealer) Progress() []types.PhaseProgress {
+	return d.progress()
+}
+
+// CanComplete reports whether enough participants are left, after the
+// exclusions, for the round to reach the threshold, and how many of them the
+// current phase still waits for. The phases wait for every participant left,
+// so a round whose missing participants never send stalls until it times out,
+// but one that can't complete never will; orchestration can abort it at once.
+func (d *DKGDealer) CanComplete() (bool, int) {
+	excluded := make(map[string]bool)
+	for _, loser := range d.losers {
+		excluded[loser.String()] = true
+	}
+	var missing int
+	for _, phase := range d.Progress() {
+		if phase.Completed {
+			continue
+		}
+		for _, addr := range phase.Missing {
+			if !excluded[addr.String()] {
+				missing++
+			}
+		}
+		break
+	}
+	return d.participants.Size()-len(excluded) >= d.verifierThreshold(), missing
+}
+
+// offChainProgress describes the phases of the off-chain protocol. The commit
+// phases wait for the qualified participants, which are known once the deals
+// are processed; every participant is expected until then.
+func (d *DKGDealer) offChainProgress() []types.PhaseProgress {
+	var (
+		n      = d.participants.Size()
+		all    = d.participantAddrs(false)
+		others = d.participantAddrs(true)
+		qual   = all
+	)
+	if d.instance != nil {
+		qual = nil
+		for _, index := range d.instance.QUAL() {
+			if index < len(d.pubKeys) {
+				qual = append(qual, d.pubKeys[index].Addr)
+			}
+		}
+	}
+	qualOthers := d.withoutSelf(qual)
+
+	return d.phaseProgress([]phaseRequirement{
+		{alias.DKGPubKey, n, len(d.pubKeys), all, d.sentPubKey},
+		{alias.DKGDeal, n - 1, len(d.deals), others, d.sentDeal},
+		{alias.DKGResponse, (n - 1) * (n - 1), d.responses.messagesCount, others, d.responses.sentAll},
+		{alias.DKGJustification, n * (n - 1) * (n - 1), d.justifications.messagesCount, all, d.justifications.sentAll},
+		{alias.DKGCommits, len(qual), d.commits.messagesCount, qual, d.commits.sentAll},
+		{alias.DKGComplaint, len(qualOthers), d.complaints.messagesCount, qualOthers, d.complaints.sentAll},
+		{alias.DKGReconstructCommit, len(qualOthers), d.reconstructCommits.messagesCount, qualOthers, d.reconstructCommits.sentAll},
+	})
+}
+
+// onChainProgress describes the phases of the on-chain protocol.
+func (d *onChainDealer) onChainProgress() []types.PhaseProgress {
+	var (
+		n      = d.participants.Size()
+		all    = d.participantAddrs(false)
+		others = d.participantAddrs(true)
+	)
+	return d.phaseProgress([]phaseRequirement{
+		{alias.DKGPubKey, n, len(d.pubKeys), all, d.sentPubKey},
+		{alias.DKGCommits, n - 1, len(d.commits.addrToData), others, d.commits.sentAny},
+		{alias.DKGDeal, n - 1, len(d.deals), others, func(addr crypto.Address) bool {
+			_, ok := d.deals[addr.String()]
+			return ok
+		}},
+		{alias.DKGResponse, (n - 1) * (n - 1), d.responses.messagesCount, others, d.responses.sentAll},
+	})
+}
+
+// phaseRequirement describes the messages a phase waits for.
+type phaseRequirement struct {
+	dataType alias.DKGDataType
+	expected int
+	received int
+	senders  []crypto.Address
+	sent     func(addr crypto.Address) bool // Whether the sender has sent all its messages.
+}
+
+func (d *DKGDealer) phaseProgress(requirements []phaseRequirement) []types.PhaseProgress {
+	out := make([]types.PhaseProgress, len(requirements))
+	for i, req := range requirements {
+		phase := "unknown"
+		if i < len(d.phases) {
+			phase = d.phases[i]
+		}
+		out[i] = types.PhaseProgress{
+			Phase:     phase,
+			Type:      req.dataType.String(),
+			Expected:  req.expected,
+			Received:  req.received,
+			Completed: i < d.completedPhases,
+		}
+		if out[i].Completed {
+			continue
+		}
+		for _, addr := range req.senders {
+			if !req.sent(addr) {
+				out[i].Missing = append(out[i].Missing, addr)
+			}
+		}
+	}
+	return out
+}
+
+func (d *DKGDealer) participantAddrs(withoutSelf bool) []crypto.Address {
+	var out []crypto.Address
+	for _, participant := range d.participants.Participants() {
+		out = append(out, participant.Address)
+	}
+	if withoutSelf {
+		return d.withoutSelf(out)
+	}
+	return out
+}
+
+func (d *DKGDealer) withoutSelf(addrs []crypto.Address) []crypto.Address {
+	var out []crypto.Address
+	for _, addr := range addrs {
+		if !bytes.Equal(addr, d.addrBytes) {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+func (d *DKGDealer) sentPubKey(addr crypto.Address) bool {
+	return d.pubKeys.Get(addr) != nil
+}
+
+func (d *DKGDealer) sentDeal(addr crypto.Address) bool {
+	_, ok := d.deals[addr.String()]
+	return ok
+}
+
+func (ms *messageStore) sentAll(addr crypto.Address) bool {
+	return len(ms.addrToData[addr.String()]) >= ms.maxMessagesFromPeer
+}
+
+func (ms *messageStore) sentAny(addr crypto.Address) bool {
+	return len(ms.addrToData[addr.String()]) > 0
+}
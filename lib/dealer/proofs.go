@@ -0,0 +1,78 @@
+package dealer
+
+import (
+	"github.com/corestario/dkglib/lib/types"
+	"go.dedis.ch/kyber/v3"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/rabin"
+)
+
+// CommitmentMatrix returns the commitments the dealer received from the
+// participants, the node's own included once it handled them.
+func (d *DKGDealer) CommitmentMatrix() *types.CommitmentMatrix {
+	matrix := &types.CommitmentMatrix{RoundID: d.roundID}
+	for index, participant := range d.participants.Participants() {
+		data, ok := d.commits.addrToData[participant.Address.String()]
+		if !ok {
+			continue
+		}
+		row := types.CommitmentRow{Index: index, Addr: participant.Address}
+		for _, commit := range commitPoints(data) {
+			point, err := commit.MarshalBinary()
+			if err != nil {
+				d.logger.Error("DKGDealer: failed to marshal commitment", "from", participant.Address, "error", err)
+				continue
+			}
+			row.Commitments = append(row.Commitments, point)
+		}
+		matrix.Rows = append(matrix.Rows, row)
+	}
+	return matrix
+}
+
+// commitPoints returns the points of a participant's commits messages: the
+// secret commits of off-chain rounds or the single points of on-chain ones.
+func commitPoints(data []interface{}) []kyber.Point {
+	var points []kyber.Point
+	for _, commit := range data {
+		switch commit := commit.(type) {
+		case *dkg.SecretCommits:
+			points = append(points, commit.Commitments...)
+		case kyber.Point:
+			points = append(points, commit)
+		}
+	}
+	return points
+}
+
+// SetProofHook sets the hook generating external proofs after every completed
+// phase, see types.ProofHook. The proofs are returned by Proofs and Snapshot.
+func (d *DKGDealer) SetProofHook(hook types.ProofHook) {
+	d.proofHook = hook
+}
+
+func (d *DKGDealer) Proofs() []*types.RoundProof {
+	return append([]*types.RoundProof(nil), d.proofs...)
+}
+
+// runProofHook passes the commitments to the proof hook once the current
+// phase is completed. A failing hook doesn't fail the round.
+func (d *DKGDealer) runProofHook() {
+	if d.proofHook == nil {
+		return
+	}
+	phase := "unknown"
+	if d.completedPhases < len(d.phases) {
+		phase = d.phases[d.completedPhases]
+	}
+	proofs, err := d.proofHook(phase, d.CommitmentMatrix())
+	if err != nil {
+		d.logger.Error("DKGDealer: proof hook failed", "phase", phase, "error", err)
+		return
+	}
+	for _, proof := range proofs {
+		if proof.Phase == "" {
+			proof.Phase = phase
+		}
+		d.proofs = append(d.proofs, proof)
+	}
+}
@@ -0,0 +1,149 @@
+package dealer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Registration binds the DKG encryption key a participant generated for a round
+// to its address. It is signed by the participant's consensus key, so
+// the binding holds even where the enclosing message signature is not checked
+// (e.g. messages relayed through the chain).
+type Registration struct {
+	Addr         crypto.Address
+	RoundID      int
+	PubKey       []byte // Gob-encoded kyber point on G2.
+	Signature    []byte
+	Capabilities uint64 // See types.Capabilities; zero for types.LegacyCapabilities.
+}
+
+// registrationDomain separates the sign bytes of registrations from those of
+// any other message signed by the consensus key.
+const registrationDomain = "dkglib/Registration"
+
+// SignBytes encodes the registration without its signature after the domain
+// separator and the chain ID, each length-prefixed.
+func (r Registration) SignBytes(chainID string) []byte {
+	r.Signature = nil
+	var buf bytes.Buffer
+	for _, b := range [][]byte{[]byte(registrationDomain), []byte(chainID)} {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		buf.Write(n[:])
+		buf.Write(b)
+	}
+	buf.Write(alias.Cdc.MustMarshalBinaryLengthPrefixed(r))
+	return buf.Bytes()
+}
+
+func (r *Registration) SetSignature(sig []byte) {
+	r.Signature = sig
+}
+
+// sendRegistration publishes the dealer's encryption key; it opens the round.
+func (d *DKGDealer) sendRegistration() error {
+	var (
+		buf = bytes.NewBuffer(nil)
+		enc = gob.NewEncoder(buf)
+	)
+	if err := enc.Encode(d.pubKey); err != nil {
+		return fmt.Errorf("failed to encode public key: %v", err)
+	}
+
+	reg := &Registration{Addr: d.addrBytes, RoundID: d.roundID, PubKey: buf.Bytes(), Capabilities: uint64(d.capabilities)}
+	if err := d.privValidator.SignData(d.signDomain.ChainID, reg); err != nil {
+		return fmt.Errorf("failed to sign registration: %v", err)
+	}
+	data, err := alias.Cdc.MarshalBinaryBare(reg)
+	if err != nil {
+		return fmt.Errorf("failed to encode registration: %v", err)
+	}
+
+	d.logger.Info("dkgState: sending registration", "key", d.pubKey.String())
+	err = d.SendMsgCb([]*alias.DKGData{{
+		Type:    alias.DKGRegistration,
+		RoundID: d.roundID,
+		Addr:    d.addrBytes,
+		Data:    data,
+	}})
+	if err != nil {
+		return fmt.Errorf("failed to sign message: %v", err)
+	}
+
+	return nil
+}
+
+func (d *DKGDealer) HandleDKGRegistration(msg *alias.DKGData) error {
+	d.observeMessage(msg)
+
+	var reg Registration
+	if err := alias.Cdc.UnmarshalBinaryBare(msg.Data, &reg); err != nil {
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("failed to decode registration: %v", err))
+	}
+	if !bytes.Equal(reg.Addr, msg.Addr) || reg.RoundID != msg.RoundID {
+		err := fmt.Errorf("registration of %s for round %d sent by %s for round %d",
+			reg.Addr, reg.RoundID, msg.GetAddrString(), msg.RoundID)
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, err)
+	}
+	_, participant := d.participants.GetByAddress(reg.Addr)
+	if participant == nil {
+		return types.NewInvalidMessageError(msg, fmt.Errorf("registration from unknown participant %s", reg.Addr))
+	}
+	if !participant.PubKey.VerifyBytes(reg.SignBytes(d.signDomain.ChainID), reg.Signature) {
+		err := errors.New("invalid registration signature")
+		d.reportMisbehavior(msg, types.MisbehaviorInvalidSignature, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("%v from %s", err, reg.Addr))
+	}
+
+	pubKey := d.suiteG2.Point()
+	if err := gob.NewDecoder(bytes.NewBuffer(reg.PubKey)).Decode(pubKey); err != nil {
+		d.reportMalformed(msg, err)
+		return types.NewInvalidMessageError(msg, fmt.Errorf("dkgState: failed to decode public key from %s: %v", reg.Addr, err))
+	}
+
+	if err := d.addPubKey(msg, pubKey); err != nil {
+		return err
+	}
+	if _, ok := d.peerCapabilities[reg.Addr.String()]; !ok && reg.Capabilities != 0 {
+		d.peerCapabilities[reg.Addr.String()] = types.Capabilities(reg.Capabilities)
+	}
+	return nil
+}
+
+// SetCapabilities overrides the capabilities the dealer advertises in its
+// registration, by default those of the version of its sign domain, see
+// SetSignDomain; it must be called before the dealer starts.
+func (d *DKGDealer) SetCapabilities(caps types.Capabilities) {
+	d.capabilities = caps
+}
+
+// negotiateCapabilities picks the capabilities shared by the participants once
+// all of them registered; participants that didn't advertise theirs are
+// assumed to have types.LegacyCapabilities.
+func (d *DKGDealer) negotiateCapabilities() error {
+	caps := make(map[string]types.Capabilities)
+	for _, pk := range d.pubKeys {
+		addr := pk.Addr.String()
+		if advertised, ok := d.peerCapabilities[addr]; ok {
+			caps[addr] = advertised
+		} else {
+			caps[addr] = types.LegacyCapabilities
+		}
+	}
+	negotiated, err := types.NegotiateCapabilities(caps)
+	if err != nil {
+		return err
+	}
+	d.negotiated = negotiated
+	d.logger.Info("dkgState: negotiated capabilities", "capabilities", negotiated.String())
+	return nil
+}
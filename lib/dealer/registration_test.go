@@ -0,0 +1,104 @@
+package dealer
+
+import (
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// registrationOf returns the registration among the sent messages.
+func registrationOf(t *testing.T, sent []*alias.DKGData) *alias.DKGData {
+	for _, msg := range sent {
+		if msg.Type == alias.DKGRegistration {
+			return msg
+		}
+	}
+	t.Fatal("no registration sent")
+	return nil
+}
+
+// TestReregistration checks that a participant restarting with a new key
+// before the deals isn't taken for an equivocator, and that the repeat of a
+// registration is ignored.
+func TestReregistration(t *testing.T) {
+	pvs, participants := newTestValidators(t, 4)
+
+	var own, first, second []*alias.DKGData
+	d := newTestDealer(t, participants, pvs[0], &own)
+	newTestDealer(t, participants, pvs[1], &first)
+	newTestDealer(t, participants, pvs[1], &second) // Restarted with a new key.
+
+	for _, msg := range []*alias.DKGData{registrationOf(t, first), registrationOf(t, first), registrationOf(t, second)} {
+		if err := d.HandleDKGRegistration(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if losers := d.GetLosers(); len(losers) != 0 {
+		t.Fatalf("re-registration made losers %v", losers)
+	}
+	key := d.(*DKGDealer).pubKeys.Get(pvs[1].GetPubKey().Address())
+	if key == nil || len(d.(*DKGDealer).pubKeys) != 1 {
+		t.Fatalf("want one key of the re-registered participant, have %d", len(d.(*DKGDealer).pubKeys))
+	}
+}
+
+// TestReregistrationAfterDeals checks that a new key registered once a deal
+// of the round was received is ignored, as the dealers may have encrypted
+// their deals to the known key.
+func TestReregistrationAfterDeals(t *testing.T) {
+	pvs, participants := newTestValidators(t, 3)
+
+	var own, dealer, first, second []*alias.DKGData
+	d := newTestDealer(t, participants, pvs[0], &own)
+	other := newTestDealer(t, participants, pvs[1], &dealer)
+	newTestDealer(t, participants, pvs[2], &first)
+	newTestDealer(t, participants, pvs[2], &second) // Restarted with a new key.
+
+	registrations := []*alias.DKGData{registrationOf(t, own), registrationOf(t, dealer), registrationOf(t, first)}
+	for _, msg := range registrations {
+		if err := other.HandleDKGRegistration(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var deal *alias.DKGData
+	for _, msg := range dealer {
+		if msg.Type == alias.DKGDeal {
+			deal = msg
+		}
+	}
+	if deal == nil {
+		t.Fatal("no deal sent")
+	}
+
+	addr := pvs[2].GetPubKey().Address()
+	if err := d.HandleDKGRegistration(registrationOf(t, first)); err != nil {
+		t.Fatal(err)
+	}
+	known := d.(*DKGDealer).pubKeys.Get(addr)
+	if err := d.HandleDKGDeal(deal); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.HandleDKGRegistration(registrationOf(t, second)); err != nil {
+		t.Fatal(err)
+	}
+	if key := d.(*DKGDealer).pubKeys.Get(addr); !key.Equal(known) {
+		t.Fatal("new key registered after a deal replaced the known one")
+	}
+}
+
+// TestRegistrationOfAnotherChain checks that a registration signed for
+// another chain is rejected.
+func TestRegistrationOfAnotherChain(t *testing.T) {
+	pvs, participants := newTestValidators(t, 4)
+
+	var own, sent []*alias.DKGData
+	d := newTestDealer(t, participants, pvs[0], &own)
+	d.SetSignDomain(alias.DefaultSignDomain("chain-a"))
+	newTestDealer(t, participants, pvs[1], &sent)
+
+	err := d.HandleDKGRegistration(registrationOf(t, sent))
+	if _, ok := err.(*types.InvalidMessageError); !ok {
+		t.Fatalf("registration of another chain: want invalid message error, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package dealer
+
+import (
+	"crypto/cipher"
+
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	vss "go.dedis.ch/kyber/v3/share/vss/rabin"
+	"go.dedis.ch/kyber/v3/xof/blake2xb"
+)
+
+// SetSeed draws all of the dealer's randomness, its ephemeral key and secret
+// polynomial included, from the seed instead of the system's randomness, so a
+// dealer rebuilt with the same seed and fed the same messages in the same order
+// reaches the same state. The seed must be secret and unique to the round, and
+// be set before Start.
+func (d *DKGDealer) SetSeed(seed []byte) {
+	d.random = blake2xb.New(seed)
+}
+
+// seededSuite is the curve suite of the dealer with its randomness drawn from
+// the seed.
+type seededSuite struct {
+	*bn256.Suite
+	random cipher.Stream
+}
+
+func (s seededSuite) RandomStream() cipher.Stream {
+	return s.random
+}
+
+// dkgSuite returns the suite the dealer's DKG instance draws its randomness
+// from, see SetSeed.
+func (d *DKGDealer) dkgSuite() vss.Suite {
+	if d.random == nil {
+		return d.suiteG2
+	}
+	return seededSuite{Suite: d.suiteG2, random: d.random}
+}
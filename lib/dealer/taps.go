@@ -0,0 +1,90 @@
+package dealer
+
+import (
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// PhaseStats describes a completed phase of a round.
+type PhaseStats struct {
+	RoundID  int
+	Index    int            // Index of the phase among the phases of the round.
+	Duration time.Duration  // Time since the previous phase completed or the round started.
+	Messages map[string]int // Number of messages handled during the phase by type.
+}
+
+// EventTaps are optional callbacks notifying the application of round progress,
+// e.g. for alerting or adaptive scheduling, without polling Snapshot. They are
+// called synchronously from the dealer's handlers, so they must not block or
+// call back into the dealer.
+type EventTaps struct {
+	OnPhaseComplete func(phase string, stats PhaseStats)
+	OnPeerExcluded  func(peer crypto.Address, reason error)
+	// OnVerifyWait is called with the time a sender's messages waited for a
+	// worker of VerifyMessages, concurrently from the workers.
+	OnVerifyWait func(wait time.Duration)
+}
+
+func (d *DKGDealer) SetEventTaps(taps EventTaps) {
+	d.taps = taps
+}
+
+// phaseEvent is the payload of the events fired as the round's phases complete.
+func (d *DKGDealer) phaseEvent() types.DKGPhaseEvent {
+	return types.DKGPhaseEvent{RoundID: d.roundID, CorrelationID: types.RoundCorrelationID(d.roundID)}
+}
+
+// completePhase notifies the taps and the proof hook of the completed phase
+// and starts the next one.
+func (d *DKGDealer) completePhase() {
+	d.runProofHook()
+	if d.taps.OnPhaseComplete != nil {
+		stats := PhaseStats{
+			RoundID:  d.roundID,
+			Index:    d.completedPhases,
+			Duration: d.now().Sub(d.phaseStarted),
+			Messages: make(map[string]int),
+		}
+		for dataType, count := range d.phaseMessages {
+			stats.Messages[dataType.String()] = count
+		}
+		phase := "unknown"
+		if d.completedPhases < len(d.phases) {
+			phase = d.phases[d.completedPhases]
+		}
+		d.taps.OnPhaseComplete(phase, stats)
+	}
+	d.completedPhases++
+	d.resetPhase()
+}
+
+func (d *DKGDealer) resetPhase() {
+	d.phaseStarted = d.now()
+	d.phaseMessages = make(map[alias.DKGDataType]int)
+}
+
+// exclude marks the peer as a loser of the round, blamed with the evidence
+// hash of the messages, if any, see types.EvidenceHash, and notifies the taps
+// the first time it happens.
+func (d *DKGDealer) exclude(peer crypto.Address, reason error, evidence []byte, messages ...*alias.DKGData) {
+	if d.dismissed(peer, evidence) {
+		d.logger.Info("DKGDealer: not excluding participant, adjudication dismissed it", "addr", peer, "reason", reason)
+		return
+	}
+	for _, loser := range d.losers {
+		if loser.String() == peer.String() {
+			return
+		}
+	}
+	d.losers = append(d.losers, peer)
+	if d.blame(peer) == nil {
+		d.blames = append(d.blames, types.Blame{Addr: peer, Reason: reason.Error(), Evidence: evidence, Messages: messages})
+	}
+	if d.taps.OnPeerExcluded != nil {
+		d.taps.OnPeerExcluded(peer, reason)
+	}
+}
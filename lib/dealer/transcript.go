@@ -0,0 +1,102 @@
+package dealer
+
+import (
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+type transcriptConfig struct {
+	newDealer func(participants *types.ParticipantSet, roundID int) Dealer
+	logger    logging.Logger
+}
+
+// TranscriptOption sets an optional parameter of NewDealerFromTranscript.
+type TranscriptOption func(*transcriptConfig)
+
+// WithTranscriptDealer creates the dealer with the function, e.g. to set it up
+// like the dealers of live rounds. By default the dealer is created with
+// NewDKGDealer, its messages are dropped and its events aren't fired.
+func WithTranscriptDealer(newDealer func(participants *types.ParticipantSet, roundID int) Dealer) TranscriptOption {
+	return func(c *transcriptConfig) { c.newDealer = newDealer }
+}
+
+// WithTranscriptLogger logs the messages of the transcript that can't be
+// verified or handled to the logger.
+func WithTranscriptLogger(logger logging.Logger) TranscriptOption {
+	return func(c *transcriptConfig) { c.logger = logger }
+}
+
+// NewDealerFromTranscript rebuilds the dealer of the round from its transcript:
+// a dealer created with the committee, the seed, if any, see SetSeed, and the
+// threshold is started and fed the messages, in order. Messages that can't be
+// verified or handled are logged and skipped, since the live dealer dropped
+// them too; messages of a type the dealer doesn't handle are ignored. It is the
+// code path shared by crash recovery, forensic replay of the WAL and catching
+// up with a recorded round.
+//
+// Without the seed the dealer picks a new ephemeral key, so the outcome of
+// handling deals that were encrypted to the original key differs from the
+// recorded run.
+func NewDealerFromTranscript(transcript *types.RoundState, pv tmtypes.PrivValidator, options ...TranscriptOption) (Dealer, error) {
+	config := &transcriptConfig{logger: logging.NewNopLogger()}
+	config.newDealer = func(participants *types.ParticipantSet, roundID int) Dealer {
+		sendMsgCb := func([]*alias.DKGData) error { return nil }
+		return NewDKGDealer(participants, pv, sendMsgCb, nopFirer{}, config.logger, roundID)
+	}
+	for _, option := range options {
+		option(config)
+	}
+
+	if err := CheckRound(len(transcript.Participants), transcript.Threshold, 0); err != nil {
+		return nil, fmt.Errorf("invalid transcript of round %d: %v", transcript.RoundID, err)
+	}
+	for _, msg := range transcript.Messages {
+		if msg.RoundID != transcript.RoundID {
+			return nil, fmt.Errorf("invalid transcript of round %d: %v message of round %d", transcript.RoundID, msg.Type, msg.RoundID)
+		}
+	}
+
+	d := config.newDealer(types.NewParticipantSetFromList(transcript.Participants), transcript.RoundID)
+	if len(transcript.Seed) > 0 {
+		d.SetSeed(transcript.Seed)
+	}
+	if transcript.Threshold > 0 {
+		d.SetThreshold(transcript.Threshold)
+	}
+	if err := d.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start dealer: %v", err)
+	}
+
+	chunks := alias.NewChunkBuffer(alias.DefaultMaxReassembledSize)
+	for _, data := range transcript.Messages {
+		if err := d.VerifyMessage(types.DKGDataMessage{Data: data}); err != nil {
+			config.logger.Info("transcript: can't verify message", "type", data.Type, "from", data.GetAddrString(), "error", err)
+			continue
+		}
+		msg, err := chunks.Add(data)
+		if err != nil {
+			config.logger.Info("transcript: failed to reassemble message", "type", data.Type, "from", data.GetAddrString(), "error", err)
+			continue
+		}
+		if msg == nil {
+			continue
+		}
+		handler := Handler(d, msg.Type)
+		if handler == nil {
+			continue
+		}
+		if err := handler(msg); err != nil {
+			config.logger.Error("transcript: failed to handle message", "type", msg.Type, "from", msg.GetAddrString(), "error", err)
+		}
+	}
+	return d, nil
+}
+
+type nopFirer struct{}
+
+func (nopFirer) FireEvent(event string, data events.EventData) {}
@@ -0,0 +1,151 @@
+package dealer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// HandleResult tells what a dealer did with a message.
+type HandleResult int
+
+const (
+	HandleAccepted  HandleResult = iota // The message was handled.
+	HandleDuplicate                     // The same message was handled before.
+	HandleIgnored                       // The message wasn't handled, e.g. dealers don't handle its type or its sender was excluded for it.
+	HandleFatal                         // The message failed the round.
+)
+
+var handleResultNames = map[HandleResult]string{
+	HandleAccepted:  "accepted",
+	HandleDuplicate: "duplicate",
+	HandleIgnored:   "ignored",
+	HandleFatal:     "fatal",
+}
+
+func (r HandleResult) String() string {
+	if name, ok := handleResultNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", int(r))
+}
+
+// DealerV2 is the dealer interface whose handlers take contexts and report
+// what they did with a message. Dealer implementations are adapted to it with
+// AdaptDealer.
+type DealerV2 interface {
+	Start(ctx context.Context) error
+	// Handle handles a message of any type; the error explains a fatal or
+	// ignored message.
+	Handle(ctx context.Context, msg *alias.DKGData) (HandleResult, error)
+	Verifier() (types.Verifier, error)
+	State() DealerState
+	Snapshot() *types.RoundInfo
+	Progress() []types.PhaseProgress
+	PopLosers() []*tmtypes.Validator
+	PopLosersWithReasons() []*types.Loser
+	// V1 returns the adapted dealer.
+	V1() Dealer
+}
+
+// dealerAdapter implements DealerV2 on top of a Dealer.
+type dealerAdapter struct {
+	mtx         sync.Mutex
+	dealer      Dealer
+	middlewares []Middleware
+	handled     map[string]bool // Keys of the handled messages, see messageKey.
+}
+
+// AdaptDealer adapts the dealer to DealerV2, so existing implementations,
+// e.g. set with a DKGDealerConstructor, keep working with code using the new
+// interface. Handlers are wrapped into the middlewares, the first one being
+// the outermost; messages are duplicates if they have the same sign bytes
+// and signature as a handled one.
+func AdaptDealer(d Dealer, middlewares ...Middleware) DealerV2 {
+	return &dealerAdapter{dealer: d, middlewares: middlewares, handled: make(map[string]bool)}
+}
+
+func (a *dealerAdapter) Start(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.dealer.Start()
+}
+
+func (a *dealerAdapter) Handle(ctx context.Context, msg *alias.DKGData) (HandleResult, error) {
+	if err := ctx.Err(); err != nil {
+		return HandleIgnored, err
+	}
+	handler := Handler(a.dealer, msg.Type)
+	if handler == nil {
+		return HandleIgnored, fmt.Errorf("dealers don't handle %v messages", msg.Type)
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	key := messageKey(msg)
+	if a.handled[key] {
+		return HandleDuplicate, nil
+	}
+	excludedBefore := a.excluded(msg.Addr)
+	if err := Chain(handler, a.middlewares...)(msg); err != nil {
+		// The dealer excluded the sender of an invalid message; whether the
+		// round still completes is up to the remaining participants.
+		if _, ok := err.(*types.InvalidMessageError); ok || (!excludedBefore && a.excluded(msg.Addr)) {
+			a.handled[key] = true
+			return HandleIgnored, err
+		}
+		return HandleFatal, err
+	}
+	a.handled[key] = true
+	return HandleAccepted, nil
+}
+
+// excluded reports whether the dealer excluded the participant.
+func (a *dealerAdapter) excluded(addr []byte) bool {
+	for _, loser := range a.dealer.GetLosersWithReasons() {
+		if bytes.Equal(loser.Addr, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *dealerAdapter) Verifier() (types.Verifier, error) {
+	return a.dealer.GetVerifier()
+}
+
+func (a *dealerAdapter) State() DealerState {
+	return a.dealer.GetState()
+}
+
+func (a *dealerAdapter) Snapshot() *types.RoundInfo {
+	return a.dealer.Snapshot()
+}
+
+func (a *dealerAdapter) Progress() []types.PhaseProgress {
+	return a.dealer.Progress()
+}
+
+func (a *dealerAdapter) PopLosers() []*tmtypes.Validator {
+	return a.dealer.PopLosers()
+}
+
+func (a *dealerAdapter) PopLosersWithReasons() []*types.Loser {
+	return a.dealer.PopLosersWithReasons()
+}
+
+func (a *dealerAdapter) V1() Dealer {
+	return a.dealer
+}
+
+// messageKey identifies a message by its content and signature.
+func messageKey(msg *alias.DKGData) string {
+	return string(tmhash.Sum(append(msg.ContentBytes(), msg.Signature...)))
+}
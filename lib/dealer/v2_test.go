@@ -0,0 +1,40 @@
+package dealer
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+func TestMalformedDealIsIgnored(t *testing.T) {
+	const n = 5
+	pvs, participants := newTestValidators(t, n)
+	var sent []*alias.DKGData
+	d := AdaptDealer(newTestDealer(t, participants, pvs[0], &sent))
+
+	msg := &alias.DKGData{Type: alias.DKGDeal, Addr: pvs[1].GetPubKey().Address(), Data: []byte("not a deal")}
+	if err := pvs[1].SignData("", msg); err != nil {
+		t.Fatal(err)
+	}
+	result, err := d.Handle(context.Background(), msg)
+	if result != HandleIgnored {
+		t.Fatalf("malformed deal: want %v, got %v (%v)", HandleIgnored, result, err)
+	}
+	if _, ok := err.(*types.InvalidMessageError); !ok {
+		t.Fatalf("want an InvalidMessageError, got %v", err)
+	}
+	if result, _ := d.Handle(context.Background(), msg); result != HandleDuplicate {
+		t.Fatalf("repeated deal: want %v, got %v", HandleDuplicate, result)
+	}
+
+	losers := d.V1().GetLosersWithReasons()
+	if len(losers) != 1 || !bytes.Equal(losers[0].Addr, pvs[1].GetPubKey().Address()) {
+		t.Fatalf("want the sender excluded, got %v", losers)
+	}
+	if ok, missing := d.V1().CanComplete(); !ok {
+		t.Fatalf("round can't complete without one of %d participants (%d awaited)", n, missing)
+	}
+}
@@ -0,0 +1,107 @@
+package dealer
+
+import (
+	"crypto/sha512"
+
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+// batchZBits is the size of the random coefficients of a batch's equations.
+const batchZBits = 128
+
+var batchCurve = new(edwards25519.Curve)
+
+// signedBytes is a message's sign bytes and signature.
+type signedBytes struct {
+	signBytes []byte
+	signature []byte
+}
+
+// BatchVerifier verifies the ed25519 signatures of a consensus key together.
+// It checks a random linear combination of their verification equations,
+// [8](Σ z·s·B - Σ z·R - (Σ z·k)·A) = 0, so the key is decoded and multiplied
+// once for all the signatures. The equations are cofactored, so a signature
+// verifies the same way in any batch, alone included: all the nodes accept
+// the same messages whatever batches they fetched them in.
+type BatchVerifier struct {
+	pubKey ed25519.PubKeyEd25519
+	items  []signedBytes
+}
+
+func NewBatchVerifier(pubKey ed25519.PubKeyEd25519) *BatchVerifier {
+	return &BatchVerifier{pubKey: pubKey}
+}
+
+// Add queues the signature of the sign bytes for Verify.
+func (v *BatchVerifier) Add(signBytes, signature []byte) {
+	v.items = append(v.items, signedBytes{signBytes: signBytes, signature: signature})
+}
+
+// Verify reports whether all the queued signatures are valid; it doesn't tell
+// the invalid ones apart, see VerifyOne.
+func (v *BatchVerifier) Verify() bool {
+	return verifyEd25519(v.pubKey, v.items)
+}
+
+// VerifyOne reports whether the i-th queued signature is valid.
+func (v *BatchVerifier) VerifyOne(i int) bool {
+	return verifyEd25519(v.pubKey, v.items[i:i+1])
+}
+
+func verifyEd25519(pubKey ed25519.PubKeyEd25519, items []signedBytes) bool {
+	a := varTimePoint()
+	if err := a.UnmarshalBinary(pubKey[:]); err != nil {
+		return false
+	}
+	var (
+		sumS   = batchCurve.Scalar().Zero()
+		sumK   = batchCurve.Scalar().Zero()
+		sumR   = batchCurve.Point().Null()
+		stream = random.New()
+	)
+	for _, item := range items {
+		if len(item.signature) != ed25519.SignatureSize {
+			return false
+		}
+		r := varTimePoint()
+		if err := r.UnmarshalBinary(item.signature[:32]); err != nil {
+			return false
+		}
+		s := batchCurve.Scalar()
+		if err := s.UnmarshalBinary(item.signature[32:]); err != nil {
+			return false
+		}
+		// Like ed25519.Verify, reject the encodings of s not reduced mod L.
+		if !s.Equal(batchCurve.Scalar().SetBytes(item.signature[32:])) {
+			return false
+		}
+		h := sha512.New()
+		h.Write(item.signature[:32])
+		h.Write(pubKey[:])
+		h.Write(item.signBytes)
+		k := batchCurve.Scalar().SetBytes(h.Sum(nil))
+
+		z := batchCurve.Scalar().One()
+		if len(items) > 1 {
+			z.SetBytes(random.Bits(batchZBits, false, stream))
+		}
+		sumS.Add(sumS, s.Mul(s, z))
+		sumK.Add(sumK, k.Mul(k, z))
+		sumR.Add(sumR, varTimePoint().Mul(z, r))
+	}
+	check := batchCurve.Point().Mul(sumS, nil)
+	check.Sub(check, sumR)
+	check.Sub(check, varTimePoint().Mul(sumK, a))
+	return varTimePoint().Mul(batchCurve.Scalar().SetInt64(8), check).Equal(batchCurve.Point().Null())
+}
+
+// varTimePoint returns a point multiplied in variable time, which only public
+// values may be.
+func varTimePoint() kyber.Point {
+	p := batchCurve.Point()
+	p.(interface{ AllowVarTime(bool) }).AllowVarTime(true)
+	return p
+}
@@ -0,0 +1,116 @@
+package dealer
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// TestVerifyMessagesFindsInvalidSignature checks the messages of a sender
+// with a tampered signature are still verified one by one after their batch
+// fails.
+func TestVerifyMessagesFindsInvalidSignature(t *testing.T) {
+	pvs, participants := newTestValidators(t, 4)
+	var messages []*alias.DKGData
+	for _, pv := range pvs[1:] {
+		newTestDealer(t, participants, pv, &messages)
+	}
+	if len(messages) < 3 {
+		t.Fatalf("got %d messages", len(messages))
+	}
+	var own []*alias.DKGData
+	d := newTestDealer(t, participants, pvs[0], &own)
+
+	for i, err := range d.VerifyMessages(messages) {
+		if err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+	}
+
+	tampered := *messages[1]
+	tampered.Signature = append([]byte(nil), tampered.Signature...)
+	tampered.Signature[0] ^= 1
+	messages[1] = &tampered
+	for i, err := range d.VerifyMessages(messages) {
+		if (err != nil) != (i == 1) {
+			t.Fatalf("message %d: got error %v", i, err)
+		}
+	}
+}
+
+// TestBatchVerifier checks a batch verifies if all its signatures are valid.
+func TestBatchVerifier(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	batch := NewBatchVerifier(key.PubKey().(ed25519.PubKeyEd25519))
+	var messages, signatures [][]byte
+	for i := 0; i < 8; i++ {
+		msg := []byte{byte(i)}
+		sig, err := key.Sign(msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch.Add(msg, sig)
+		messages, signatures = append(messages, msg), append(signatures, sig)
+	}
+	if !batch.Verify() {
+		t.Fatal("valid batch rejected")
+	}
+
+	batch = NewBatchVerifier(key.PubKey().(ed25519.PubKeyEd25519))
+	for i := range messages {
+		if i == 3 {
+			batch.Add(messages[i], signatures[i+1])
+			continue
+		}
+		batch.Add(messages[i], signatures[i])
+	}
+	if batch.Verify() {
+		t.Fatal("batch with an invalid signature accepted")
+	}
+	for i := range messages {
+		if batch.VerifyOne(i) != (i != 3) {
+			t.Fatalf("signature %d: got valid %v", i, i == 3)
+		}
+	}
+}
+
+// TestBatchVerifierRejectsNonCanonicalSignature checks a signature whose s
+// isn't reduced mod L is rejected, as ed25519.Verify rejects it.
+func TestBatchVerifierRejectsNonCanonicalSignature(t *testing.T) {
+	key := ed25519.GenPrivKey()
+	msg := []byte("message")
+	sig, err := key.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	batch := NewBatchVerifier(key.PubKey().(ed25519.PubKeyEd25519))
+	batch.Add(msg, sig)
+	if !batch.Verify() {
+		t.Fatal("valid signature rejected")
+	}
+
+	// s + L encodes the same scalar, unreduced.
+	order, _ := new(big.Int).SetString("7237005577332262213973186563042994240857116359379907606001950938285454250989", 10)
+	s := new(big.Int).SetBytes(reverse(sig[32:]))
+	s.Add(s, order)
+	malleated := append(append([]byte(nil), sig[:32]...), reverse(leftPad(s.Bytes(), 32))...)
+	batch = NewBatchVerifier(key.PubKey().(ed25519.PubKeyEd25519))
+	batch.Add(msg, malleated)
+	if batch.Verify() || key.PubKey().VerifyBytes(msg, malleated) {
+		t.Fatal("non-canonical signature accepted")
+	}
+}
+
+func reverse(b []byte) []byte {
+	out := make([]byte, len(b))
+	for i := range b {
+		out[len(b)-1-i] = b[i]
+	}
+	return out
+}
+
+func leftPad(b []byte, size int) []byte {
+	return append(make([]byte, size-len(b)), b...)
+}
@@ -0,0 +1,105 @@
+package dealer
+
+import (
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// VerifyMessages verifies the signatures of the messages in parallel and
+// returns one error per message, nil for valid ones. Messages are grouped per
+// sender and the groups are spread over the workers, see SetVerifyConcurrency.
+// The signatures of a sender with an ed25519 key are verified in a batch, see
+// BatchVerifier; if the batch fails, they are verified one by one to find the
+// invalid ones.
+func (d *DKGDealer) VerifyMessages(msgs []*alias.DKGData) []error {
+	var (
+		errs   = make([]error, len(msgs))
+		groups = make(map[string][]int)
+		order  []string
+	)
+	for i, msg := range msgs {
+		addr := msg.GetAddrString()
+		if _, ok := groups[addr]; !ok {
+			order = append(order, addr)
+		}
+		groups[addr] = append(groups[addr], i)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		jobs    = make(chan string)
+		queued  = d.now()
+		workers = d.verifyWorkers
+	)
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(order) {
+		workers = len(order)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				if d.taps.OnVerifyWait != nil {
+					d.taps.OnVerifyWait(d.now().Sub(queued))
+				}
+				d.verifyGroup(msgs, groups[addr], errs)
+			}
+		}()
+	}
+	for _, addr := range order {
+		jobs <- addr
+	}
+	close(jobs)
+	wg.Wait()
+
+	return errs
+}
+
+// verifyGroup verifies messages of a single sender; each goroutine writes only
+// to the errors of its own group.
+func (d *DKGDealer) verifyGroup(msgs []*alias.DKGData, indices []int, errs []error) {
+	keys := d.signerKeys(msgs[indices[0]].Addr)
+	if pubKey, ok := batchKey(keys); ok {
+		batch := NewBatchVerifier(pubKey)
+		for _, i := range indices {
+			batch.Add(d.signDomain.SignBytes(msgs[i]), msgs[i].Signature)
+		}
+		if batch.Verify() {
+			return
+		}
+		for j, i := range indices {
+			if !batch.VerifyOne(j) {
+				errs[i] = fmt.Errorf("invalid DKG message signature: %s", hex.EncodeToString(msgs[i].Signature))
+			}
+		}
+		return
+	}
+	for _, i := range indices {
+		errs[i] = d.verifySignature(msgs[i].Addr, d.signDomain.SignBytes(msgs[i]), msgs[i].Signature)
+	}
+}
+
+// batchKey returns the ed25519 key of a sender that may sign with a single
+// key, the only one its messages can be verified in a batch against.
+func batchKey(keys []crypto.PubKey) (ed25519.PubKeyEd25519, bool) {
+	if len(keys) != 1 {
+		return ed25519.PubKeyEd25519{}, false
+	}
+	pubKey, ok := keys[0].(ed25519.PubKeyEd25519)
+	return pubKey, ok
+}
+
+// SetVerifyConcurrency sets the number of workers of VerifyMessages; zero, the
+// default, uses GOMAXPROCS workers.
+func (d *DKGDealer) SetVerifyConcurrency(workers int) {
+	d.verifyWorkers = workers
+}
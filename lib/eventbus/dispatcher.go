@@ -0,0 +1,169 @@
+package eventbus
+
+import (
+	"sync"
+
+	"github.com/corestario/dkglib/lib/metrics"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+// DefaultDispatcherSize is the number of events the dispatcher queues before
+// dropping the events with the DispatchDrop policy.
+const DefaultDispatcherSize = 1024
+
+// DispatchPolicy tells what happens to an event fired while the dispatcher's
+// queue is full.
+type DispatchPolicy int
+
+const (
+	// DispatchDrop drops the event.
+	DispatchDrop DispatchPolicy = iota
+	// DispatchPark queues the event beyond the limit; it is delivered in
+	// order once the subscribers catch up.
+	DispatchPark
+)
+
+type firedEvent struct {
+	event string
+	data  events.EventData
+}
+
+// Dispatcher fires events on the event switch from its own goroutine, so a
+// slow subscriber doesn't stall DKG message handling, which fires events
+// under the DKG mutex. Events are delivered in the order they were fired.
+// EventDKGData carries outgoing DKG messages and is parked by default, the
+// other events are dropped when the queue is full.
+type Dispatcher struct {
+	mtx      sync.Mutex
+	fireable events.Fireable
+	size     int
+	policies map[string]DispatchPolicy
+	policy   DispatchPolicy // For events without a policy of their own.
+	pending  []firedEvent
+	metrics  *metrics.Metrics
+
+	signal chan struct{}
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+var _ events.Fireable = &Dispatcher{}
+
+// DispatcherOption sets an optional parameter on the Dispatcher.
+type DispatcherOption func(*Dispatcher)
+
+// WithDispatcherSize sets the number of events queued before the policies apply.
+func WithDispatcherSize(size int) DispatcherOption {
+	return func(d *Dispatcher) {
+		if size > 0 {
+			d.size = size
+		}
+	}
+}
+
+// WithDispatchPolicy sets the policy of the event.
+func WithDispatchPolicy(event string, policy DispatchPolicy) DispatcherOption {
+	return func(d *Dispatcher) { d.policies[event] = policy }
+}
+
+// WithDefaultDispatchPolicy sets the policy of the events without one of their own.
+func WithDefaultDispatchPolicy(policy DispatchPolicy) DispatcherOption {
+	return func(d *Dispatcher) { d.policy = policy }
+}
+
+// WithDispatcherMetrics counts the dropped events and tracks the queue length.
+func WithDispatcherMetrics(m *metrics.Metrics) DispatcherOption {
+	return func(d *Dispatcher) { d.metrics = m }
+}
+
+// NewDispatcher creates a dispatcher firing events on the fireable; Start it
+// before firing events.
+func NewDispatcher(fireable events.Fireable, options ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		fireable: fireable,
+		size:     DefaultDispatcherSize,
+		policies: map[string]DispatchPolicy{dkgtypes.EventDKGData: DispatchPark},
+		metrics:  metrics.NopMetrics(),
+		signal:   make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+func (d *Dispatcher) Start() {
+	go d.run()
+}
+
+// Stop stops the dispatcher once the queued events are delivered.
+func (d *Dispatcher) Stop() {
+	close(d.quit)
+	<-d.done
+}
+
+// FireEvent queues the event without waiting for the subscribers.
+func (d *Dispatcher) FireEvent(event string, data events.EventData) {
+	d.mtx.Lock()
+	if len(d.pending) >= d.size && d.policyOf(event) == DispatchDrop {
+		d.mtx.Unlock()
+		d.metrics.DroppedEvents.With("event", event).Add(1)
+		return
+	}
+	d.pending = append(d.pending, firedEvent{event: event, data: data})
+	d.metrics.PendingEvents.Set(float64(len(d.pending)))
+	d.mtx.Unlock()
+
+	select {
+	case d.signal <- struct{}{}:
+	default:
+	}
+}
+
+// Pending returns the number of queued events.
+func (d *Dispatcher) Pending() int {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	return len(d.pending)
+}
+
+func (d *Dispatcher) policyOf(event string) DispatchPolicy {
+	if policy, ok := d.policies[event]; ok {
+		return policy
+	}
+	return d.policy
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for {
+		select {
+		case <-d.signal:
+			d.deliver()
+		case <-d.quit:
+			d.deliver()
+			return
+		}
+	}
+}
+
+// deliver fires the queued events one by one.
+func (d *Dispatcher) deliver() {
+	for {
+		d.mtx.Lock()
+		if len(d.pending) == 0 {
+			d.mtx.Unlock()
+			return
+		}
+		next := d.pending[0]
+		d.pending[0] = firedEvent{}
+		d.pending = d.pending[1:]
+		d.metrics.PendingEvents.Set(float64(len(d.pending)))
+		d.mtx.Unlock()
+
+		d.fireable.FireEvent(next.event, next.data)
+	}
+}
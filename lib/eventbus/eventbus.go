@@ -0,0 +1,75 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/go-amino"
+	tmpubsub "github.com/tendermint/tendermint/libs/pubsub"
+	"github.com/tendermint/tendermint/libs/pubsub/query"
+	tmtypes "github.com/tendermint/tendermint/types"
+)
+
+// Attributes of published DKG events, which subscribers can query, e.g.
+// "tm.event = 'DKGSuccessful' AND dkg.epoch > 3".
+const (
+	RoundKey       = "dkg.round"
+	CorrelationKey = "dkg.correlation_id"
+	EpochKey       = "dkg.epoch"
+	HeightKey      = "dkg.height"
+)
+
+// EventDataDKG is the data of DKG events published on Tendermint buses.
+type EventDataDKG dkgtypes.DKGEvent
+
+// RegisterEventData registers the event data with the codec of the RPC server
+// delivering the events to websocket subscribers.
+func RegisterEventData(cdc *amino.Codec) {
+	cdc.RegisterConcrete(EventDataDKG{}, "dkglib/event/DKG", nil)
+}
+
+// Bridge publishes DKG events to a Tendermint EventBus. The EventBus only
+// indexes the event type, so subscribers can filter by tm.event but not by the
+// round, epoch or height; use NewPubSubBridge for that.
+type Bridge struct {
+	bus *tmtypes.EventBus
+}
+
+var _ dkgtypes.EventPublisher = &Bridge{}
+
+func NewBridge(bus *tmtypes.EventBus) *Bridge {
+	return &Bridge{bus: bus}
+}
+
+func (b *Bridge) PublishDKGEvent(event dkgtypes.DKGEvent) error {
+	return b.bus.Publish(event.Type, EventDataDKG(event))
+}
+
+// PubSubBridge publishes DKG events to a Tendermint pubsub server with the
+// round, correlation ID, epoch and height attributes.
+type PubSubBridge struct {
+	server *tmpubsub.Server
+}
+
+var _ dkgtypes.EventPublisher = &PubSubBridge{}
+
+func NewPubSubBridge(server *tmpubsub.Server) *PubSubBridge {
+	return &PubSubBridge{server: server}
+}
+
+func (b *PubSubBridge) PublishDKGEvent(event dkgtypes.DKGEvent) error {
+	return b.server.PublishWithEvents(context.Background(), EventDataDKG(event), map[string][]string{
+		tmtypes.EventTypeKey: {event.Type},
+		RoundKey:             {strconv.Itoa(event.RoundID)},
+		CorrelationKey:       {event.CorrelationID},
+		EpochKey:             {strconv.Itoa(event.Epoch)},
+		HeightKey:            {strconv.FormatInt(event.Height, 10)},
+	})
+}
+
+// QueryForEpoch matches the events of the given type that switch to or happen in the epoch.
+func QueryForEpoch(eventType string, epoch int) tmpubsub.Query {
+	return query.MustParse(fmt.Sprintf("%s='%s' AND %s=%d", tmtypes.EventTypeKey, eventType, EpochKey, epoch))
+}
@@ -0,0 +1,7 @@
+//go:build !faultinject
+// +build !faultinject
+
+package faultinject
+
+// Enabled reports whether the binary is built with the faultinject tag.
+const Enabled = false
@@ -0,0 +1,177 @@
+// Package faultinject injects faults into the DKG messages a node sends, so
+// staging networks can rehearse failure handling before it happens on mainnet.
+// Faults are only injected by binaries built with the faultinject tag; New
+// fails in other builds, so a stray configuration can't disturb production.
+package faultinject
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	dkg "go.dedis.ch/kyber/v3/share/dkg/rabin"
+	vss "go.dedis.ch/kyber/v3/share/vss/rabin"
+)
+
+// The environment variables FromEnv reads.
+const (
+	EnvDropPercent  = "DKG_FAULT_DROP_PERCENT"  // e.g. 10 to drop 10% of the messages.
+	EnvDelayPhase   = "DKG_FAULT_DELAY_PHASE"   // Message type to delay, e.g. response.
+	EnvDelayBlocks  = "DKG_FAULT_DELAY_BLOCKS"  // Blocks to delay it by.
+	EnvCorruptShare = "DKG_FAULT_CORRUPT_SHARE" // true to corrupt one share per round.
+	EnvSeed         = "DKG_FAULT_SEED"          // Seed of the drops.
+)
+
+// Config sets the faults to inject into the node's outgoing messages.
+type Config struct {
+	DropPercent int // Percentage of the messages not broadcast to the peers.
+	// DelayPhase is the name of the message type whose messages are held for
+	// DelayBlocks blocks, see alias.DKGDataType.String.
+	DelayPhase  string
+	DelayBlocks int64
+	// CorruptShare corrupts the first deal of every round, so its recipient
+	// can't decrypt its share.
+	CorruptShare bool
+	Seed         int64 // Seed of the drops; zero seeds with the time.
+}
+
+// FromEnv returns the configuration set in the environment, or nil if none
+// is set.
+func FromEnv() (*Config, error) {
+	var (
+		cfg = &Config{}
+		set bool
+		err error
+	)
+	if v := os.Getenv(EnvDropPercent); v != "" {
+		if cfg.DropPercent, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvDropPercent, err)
+		}
+		set = true
+	}
+	if v := os.Getenv(EnvDelayPhase); v != "" {
+		cfg.DelayPhase, set = v, true
+	}
+	if v := os.Getenv(EnvDelayBlocks); v != "" {
+		if cfg.DelayBlocks, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvDelayBlocks, err)
+		}
+		set = true
+	}
+	if v := os.Getenv(EnvCorruptShare); v != "" {
+		if cfg.CorruptShare, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvCorruptShare, err)
+		}
+		set = true
+	}
+	if v := os.Getenv(EnvSeed); v != "" {
+		if cfg.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", EnvSeed, err)
+		}
+	}
+	if !set {
+		return nil, nil
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration.
+func (c *Config) Validate() error {
+	if c.DropPercent < 0 || c.DropPercent > 100 {
+		return fmt.Errorf("drop percentage must be within [0, 100], got %d", c.DropPercent)
+	}
+	if c.DelayBlocks < 0 {
+		return fmt.Errorf("delay must not be negative, got %d blocks", c.DelayBlocks)
+	}
+	if c.DelayBlocks > 0 {
+		if _, ok := alias.ParseDKGDataType(c.DelayPhase); !ok {
+			return fmt.Errorf("unknown phase to delay %q", c.DelayPhase)
+		}
+	}
+	return nil
+}
+
+// Fault is the fault injected into an outgoing message.
+type Fault struct {
+	Drop        bool  // Don't broadcast the message.
+	DelayBlocks int64 // Broadcast the message that many blocks later.
+	Corrupted   bool  // The message was corrupted.
+}
+
+// Injector decides the faults of the outgoing messages.
+type Injector struct {
+	mtx            sync.Mutex
+	cfg            Config
+	delayType      alias.DKGDataType
+	rand           *rand.Rand
+	corruptedRound int // Last round a share was corrupted in.
+}
+
+// New returns the injector of the configuration. It fails in builds without
+// the faultinject tag.
+func New(cfg *Config) (*Injector, error) {
+	if !Enabled {
+		return nil, fmt.Errorf("fault injection requires building with the faultinject tag")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	delayType, _ := alias.ParseDKGDataType(cfg.DelayPhase)
+	return &Injector{
+		cfg:            *cfg,
+		delayType:      delayType,
+		rand:           rand.New(rand.NewSource(seed)),
+		corruptedRound: -1,
+	}, nil
+}
+
+// Outgoing returns the fault to inject into the message before it is signed.
+// A corrupted message is changed in place.
+func (i *Injector) Outgoing(data *alias.DKGData) Fault {
+	i.mtx.Lock()
+	defer i.mtx.Unlock()
+
+	var fault Fault
+	if i.cfg.CorruptShare && data.Type == alias.DKGDeal && data.RoundID > i.corruptedRound {
+		if err := corruptDeal(data); err == nil {
+			i.corruptedRound, fault.Corrupted = data.RoundID, true
+		}
+	}
+	if i.cfg.DelayBlocks > 0 && data.Type == i.delayType {
+		fault.DelayBlocks = i.cfg.DelayBlocks
+	}
+	if i.cfg.DropPercent > 0 && i.rand.Intn(100) < i.cfg.DropPercent {
+		fault.Drop = true
+	}
+	return fault
+}
+
+// corruptDeal flips a bit of the encrypted share of the deal, which stays well
+// formed.
+func corruptDeal(data *alias.DKGData) error {
+	deal := &dkg.Deal{Deal: &vss.EncryptedDeal{DHKey: bn256.NewSuiteG2().Point()}}
+	if err := gob.NewDecoder(bytes.NewReader(data.Data)).Decode(deal); err != nil {
+		return fmt.Errorf("failed to decode deal: %v", err)
+	}
+	if len(deal.Deal.Cipher) == 0 {
+		return fmt.Errorf("deal has no encrypted share")
+	}
+	deal.Deal.Cipher[0] ^= 1
+	buf := bytes.NewBuffer(nil)
+	if err := gob.NewEncoder(buf).Encode(deal); err != nil {
+		return fmt.Errorf("failed to encode deal: %v", err)
+	}
+	data.Data = buf.Bytes()
+	return nil
+}
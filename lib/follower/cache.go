@@ -0,0 +1,84 @@
+package follower
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// Cache keeps the verified attestations of the anchored epochs.
+type Cache interface {
+	// Load returns the saved attestations, by change height.
+	Load() ([]*types.RoundAttestation, error)
+	Save(attestation *types.RoundAttestation) error
+}
+
+// FileCache keeps the attestations of the last epochs in a file, encoded in
+// JSON with the amino codec.
+type FileCache struct {
+	mtx    sync.Mutex
+	path   string
+	retain int
+}
+
+var _ Cache = &FileCache{}
+
+// NewFileCache caches the attestations of the last retain epochs in the file;
+// zero keeps DefaultHistory epochs.
+func NewFileCache(path string, retain int) *FileCache {
+	if retain <= 0 {
+		retain = DefaultHistory
+	}
+	return &FileCache{path: path, retain: retain}
+}
+
+func (c *FileCache) Load() ([]*types.RoundAttestation, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.load()
+}
+
+func (c *FileCache) load() ([]*types.RoundAttestation, error) {
+	data, err := ioutil.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read epoch cache: %v", err)
+	}
+	var attestations []*types.RoundAttestation
+	if err := alias.Cdc.UnmarshalJSON(data, &attestations); err != nil {
+		return nil, fmt.Errorf("failed to decode epoch cache: %v", err)
+	}
+	return attestations, nil
+}
+
+func (c *FileCache) Save(attestation *types.RoundAttestation) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	attestations, err := c.load()
+	if err != nil {
+		return err
+	}
+	attestations = append(attestations, attestation)
+	if extra := len(attestations) - c.retain; extra > 0 {
+		attestations = attestations[extra:]
+	}
+	data, err := alias.Cdc.MarshalJSON(attestations)
+	if err != nil {
+		return fmt.Errorf("failed to encode epoch cache: %v", err)
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write epoch cache: %v", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("failed to write epoch cache: %v", err)
+	}
+	return nil
+}
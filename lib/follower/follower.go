@@ -0,0 +1,306 @@
+// Package follower keeps the group key of the current epoch on full nodes that
+// don't take part in DKG rounds, so they can validate the blocks' threshold
+// signatures. The keys are learned from the epoch anchors on chain, see
+// offChain.WithEpochAnchoring, and checked against the committees the node
+// trusts.
+package follower
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+const (
+	// DefaultLookahead is the number of rounds after the latest anchored one
+	// polled for anchors, see WithLookahead.
+	DefaultLookahead = 4
+	// DefaultHistory is the number of epochs kept, see WithHistory.
+	DefaultHistory = 16
+)
+
+// AnchorSource returns the anchors of a round, e.g. onChain.QueryClient; every
+// types.AnchorChain is one.
+type AnchorSource interface {
+	EpochAnchors(roundID int) ([]*types.RoundAttestation, error)
+}
+
+// CommitteeSource returns the committee trusted to run a round, e.g. the
+// participant set of the validator set the round was started with.
+type CommitteeSource interface {
+	Committee(roundID int) (*types.ParticipantSet, error)
+}
+
+// CommitteeFunc adapts a function to CommitteeSource.
+type CommitteeFunc func(roundID int) (*types.ParticipantSet, error)
+
+func (f CommitteeFunc) Committee(roundID int) (*types.ParticipantSet, error) {
+	return f(roundID)
+}
+
+// epoch is an anchored round with a verified attestation.
+type epoch struct {
+	attestation *types.RoundAttestation
+	verifier    types.Verifier
+}
+
+// Follower tracks the epochs anchored on chain and the verifier in use at
+// every height.
+type Follower struct {
+	anchors    AnchorSource
+	committees CommitteeSource
+	cache      Cache
+	firer      events.Fireable
+	logger     logging.Logger
+	lookahead  int
+	history    int
+	startRound int
+
+	mtx     sync.RWMutex
+	epochs  []*epoch // By change height, ascending.
+	current int      // Index of the epoch in use, -1 if none.
+}
+
+// Option sets an optional parameter on the Follower.
+type Option func(*Follower)
+
+// WithCache restores the epochs from the cache and saves the ones anchored
+// later, so a restarted node doesn't query them again.
+func WithCache(cache Cache) Option {
+	return func(f *Follower) { f.cache = cache }
+}
+
+// WithEventFirer fires EventDKGKeyChange with a DKGKeyChangeEvent when the
+// verifier in use changes, like the nodes running the rounds do.
+func WithEventFirer(firer events.Fireable) Option {
+	return func(f *Follower) { f.firer = firer }
+}
+
+func WithLogger(logger logging.Logger) Option {
+	return func(f *Follower) { f.logger = logger }
+}
+
+// WithLookahead sets the number of rounds after the latest anchored one that
+// are polled for anchors. Rounds that fail aren't anchored, so it must exceed
+// the number of consecutive failed rounds.
+func WithLookahead(rounds int) Option {
+	return func(f *Follower) { f.lookahead = rounds }
+}
+
+// WithHistory sets the number of epochs kept to answer VerifierAt.
+func WithHistory(epochs int) Option {
+	return func(f *Follower) { f.history = epochs }
+}
+
+// WithStartRound sets the first round polled when no epoch is known, e.g. the
+// round of the state sync snapshot the node started from.
+func WithStartRound(roundID int) Option {
+	return func(f *Follower) { f.startRound = roundID }
+}
+
+// NewFollower creates a follower of the anchors of the source, verified against
+// the committees of the source.
+func NewFollower(anchors AnchorSource, committees CommitteeSource, options ...Option) (*Follower, error) {
+	f := &Follower{
+		anchors:    anchors,
+		committees: committees,
+		firer:      nopFirer{},
+		logger:     logging.NewNopLogger(),
+		lookahead:  DefaultLookahead,
+		history:    DefaultHistory,
+		current:    -1,
+	}
+	for _, option := range options {
+		option(f)
+	}
+	if f.lookahead < 1 {
+		return nil, fmt.Errorf("lookahead must be positive, got %d", f.lookahead)
+	}
+	if f.history < 1 {
+		return nil, fmt.Errorf("history must be positive, got %d", f.history)
+	}
+	if f.cache != nil {
+		attestations, err := f.cache.Load()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load cached epochs: %v", err)
+		}
+		// Cached attestations were verified before they were saved.
+		for _, attestation := range attestations {
+			if err := f.add(attestation); err != nil {
+				f.logger.Error("follower: dropping cached epoch", "round_id", attestation.RoundID, "error", err)
+			}
+		}
+	}
+	return f, nil
+}
+
+// OnBlock polls the anchors of the rounds following the latest anchored one
+// and switches to the verifier of the latest epoch whose change height is
+// reached. The host application calls it for every committed block.
+func (f *Follower) OnBlock(height int64) error {
+	if err := f.poll(); err != nil {
+		return err
+	}
+	f.activate(height)
+	return nil
+}
+
+// poll verifies and adds the anchors of the rounds after the latest epoch.
+func (f *Follower) poll() error {
+	f.mtx.RLock()
+	next := f.startRound
+	if len(f.epochs) > 0 {
+		next = f.epochs[len(f.epochs)-1].attestation.RoundID + 1
+	}
+	f.mtx.RUnlock()
+
+	for roundID := next; roundID < next+f.lookahead; roundID++ {
+		attestation, err := f.fetch(roundID)
+		if err != nil {
+			return err
+		}
+		if attestation == nil {
+			continue
+		}
+		f.mtx.Lock()
+		err = f.add(attestation)
+		f.mtx.Unlock()
+		if err != nil {
+			return fmt.Errorf("failed to add epoch %d: %v", roundID, err)
+		}
+		if f.cache != nil {
+			if err := f.cache.Save(attestation); err != nil {
+				f.logger.Error("follower: failed to cache epoch", "round_id", roundID, "error", err)
+			}
+		}
+		f.logger.Info("follower: epoch anchored", "round_id", roundID, "change_height", attestation.ChangeHeight)
+		// Later rounds are polled from this one on at the next block.
+		return nil
+	}
+	return nil
+}
+
+// fetch returns the first anchor of the round valid for the round's trusted
+// committee, nil if there is none yet.
+func (f *Follower) fetch(roundID int) (*types.RoundAttestation, error) {
+	attestations, err := f.anchors.EpochAnchors(roundID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch anchors of round %d: %v", roundID, err)
+	}
+	if len(attestations) == 0 {
+		return nil, nil
+	}
+	committee, err := f.committees.Committee(roundID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get committee of round %d: %v", roundID, err)
+	}
+	for _, attestation := range attestations {
+		if attestation.RoundID != roundID {
+			f.logger.Error("follower: invalid epoch anchor", "round_id", roundID, "error",
+				fmt.Sprintf("anchor is of round %d", attestation.RoundID))
+			continue
+		}
+		if err := attestation.Verify(committee); err != nil {
+			f.logger.Error("follower: invalid epoch anchor", "round_id", roundID, "error", err)
+			continue
+		}
+		return attestation, nil
+	}
+	return nil, nil
+}
+
+// add appends the epoch of the attestation; f.mtx must be held.
+func (f *Follower) add(attestation *types.RoundAttestation) error {
+	if n := len(f.epochs); n > 0 {
+		last := f.epochs[n-1].attestation
+		if attestation.RoundID <= last.RoundID || attestation.ChangeHeight <= last.ChangeHeight {
+			return fmt.Errorf("epoch %d at height %d doesn't follow epoch %d at height %d",
+				attestation.RoundID, attestation.ChangeHeight, last.RoundID, last.ChangeHeight)
+		}
+	}
+	verifier, err := attestation.Verifier.Verifier()
+	if err != nil {
+		return fmt.Errorf("failed to load master public key: %v", err)
+	}
+	f.epochs = append(f.epochs, &epoch{attestation: attestation, verifier: verifier})
+	if extra := len(f.epochs) - f.history; extra > 0 {
+		f.epochs = f.epochs[extra:]
+		f.current -= extra
+		if f.current < -1 {
+			f.current = -1
+		}
+	}
+	return nil
+}
+
+// activate switches to the latest epoch whose change height is reached.
+func (f *Follower) activate(height int64) {
+	f.mtx.Lock()
+	current := f.find(height)
+	if current <= f.current {
+		f.mtx.Unlock()
+		return
+	}
+	f.current = current
+	epoch := f.epochs[current]
+	f.mtx.Unlock()
+
+	f.logger.Info("follower: key changed", "height", height, "epoch", epoch.attestation.RoundID)
+	f.firer.FireEvent(types.EventDKGKeyChange, types.DKGKeyChangeEvent{
+		Height:   height,
+		Epoch:    epoch.attestation.RoundID,
+		GroupKey: epoch.attestation.Verifier,
+	})
+}
+
+// find returns the index of the epoch in use at the height, -1 if none; f.mtx
+// must be held.
+func (f *Follower) find(height int64) int {
+	return sort.Search(len(f.epochs), func(i int) bool {
+		return f.epochs[i].attestation.ChangeHeight > height
+	}) - 1
+}
+
+// Verifier returns the verifier in use and its epoch, nil and -1 until the
+// first change height is reached.
+func (f *Follower) Verifier() (types.Verifier, int) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	if f.current < 0 {
+		return nil, -1
+	}
+	epoch := f.epochs[f.current]
+	return epoch.verifier, epoch.attestation.RoundID
+}
+
+// VerifierAt returns the verifier in use at the height and its epoch, among
+// the epochs kept.
+func (f *Follower) VerifierAt(height int64) (types.Verifier, int, error) {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	i := f.find(height)
+	if i < 0 {
+		return nil, -1, fmt.Errorf("no known epoch at height %d", height)
+	}
+	epoch := f.epochs[i]
+	return epoch.verifier, epoch.attestation.RoundID, nil
+}
+
+// Epochs returns the attestations of the epochs kept, by change height.
+func (f *Follower) Epochs() []*types.RoundAttestation {
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	out := make([]*types.RoundAttestation, len(f.epochs))
+	for i, epoch := range f.epochs {
+		out[i] = epoch.attestation
+	}
+	return out
+}
+
+type nopFirer struct{}
+
+func (nopFirer) FireEvent(event string, data events.EventData) {}
@@ -0,0 +1,39 @@
+// Package goldentest compares encodings with the golden files of a package's
+// testdata directory. Running the tests with -update rewrites the files.
+package goldentest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+// Check compares got with the hex-encoded testdata/<name>.hex, or rewrites the
+// file with -update.
+func Check(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".hex")
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(hex.EncodeToString(got)+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := hex.DecodeString(strings.TrimSpace(string(golden)))
+	if err != nil {
+		t.Fatalf("invalid golden file %s: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s changed:\ngot  %x\nwant %x", name, got, want)
+	}
+}
@@ -0,0 +1,197 @@
+// Package keyring runs several independent off-chain DKGs in one process, each
+// maintaining the group key of a purpose, e.g. randomness, bridging and
+// governance.
+package keyring
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/offChain"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Keyring routes the DKG messages to the instance of their key purpose. It is
+// a DKG itself, so the host and the reactor use it like a single instance;
+// Verifier and SetVerifier are those of the default key.
+type Keyring struct {
+	mtx    sync.RWMutex
+	queue  chan *dkgtypes.DKGDataMessage
+	keys   map[string]*offChain.OffChainDKG
+	logger logging.Logger
+}
+
+var _ dkgtypes.DKG = &Keyring{}
+var _ dkgtypes.Snapshotter = &Keyring{}
+
+// Option sets an optional parameter on the Keyring.
+type Option func(*Keyring)
+
+// WithLogging logs to the logger; nothing is logged by default.
+func WithLogging(l logging.Logger) Option {
+	return func(k *Keyring) { k.logger = l }
+}
+
+// NewKeyring creates an empty keyring. Its instances must be created with
+// offChain.WithMsgQueue(keyring.MsgQueue()) and a distinct
+// offChain.WithKeyPurpose each, and fire their messages on the same event
+// switch.
+func NewKeyring(options ...Option) *Keyring {
+	k := &Keyring{
+		queue:  make(chan *dkgtypes.DKGDataMessage, tmtypes.MsgQueueSize),
+		keys:   make(map[string]*offChain.OffChainDKG),
+		logger: logging.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(k)
+	}
+	return k
+}
+
+// Add adds the DKG maintaining the key of its purpose.
+func (k *Keyring) Add(dkg *offChain.OffChainDKG) error {
+	if dkg.MsgQueue() != k.queue {
+		return fmt.Errorf("DKG of key %q doesn't use the keyring's message queue", dkg.KeyPurpose())
+	}
+	k.mtx.Lock()
+	defer k.mtx.Unlock()
+	if _, ok := k.keys[dkg.KeyPurpose()]; ok {
+		return fmt.Errorf("keyring already has a DKG of key %q", dkg.KeyPurpose())
+	}
+	k.keys[dkg.KeyPurpose()] = dkg
+	return nil
+}
+
+// DKG returns the instance of the key purpose, or nil.
+func (k *Keyring) DKG(purpose string) *offChain.OffChainDKG {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	return k.keys[purpose]
+}
+
+// Purposes returns the key purposes of the instances, sorted.
+func (k *Keyring) Purposes() []string {
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	purposes := make([]string, 0, len(k.keys))
+	for purpose := range k.keys {
+		purposes = append(purposes, purpose)
+	}
+	sort.Strings(purposes)
+	return purposes
+}
+
+// VerifierOf returns the verifier in use for the key purpose, or nil if the
+// keyring has no instance of it.
+func (k *Keyring) VerifierOf(purpose string) dkgtypes.Verifier {
+	dkg := k.DKG(purpose)
+	if dkg == nil {
+		return nil
+	}
+	return dkg.Verifier()
+}
+
+// instances returns the instances ordered by key purpose.
+func (k *Keyring) instances() []*offChain.OffChainDKG {
+	purposes := k.Purposes()
+	k.mtx.RLock()
+	defer k.mtx.RUnlock()
+	out := make([]*offChain.OffChainDKG, 0, len(purposes))
+	for _, purpose := range purposes {
+		out = append(out, k.keys[purpose])
+	}
+	return out
+}
+
+func (k *Keyring) HandleOffChainShare(
+	dkgMsg *dkgtypes.DKGDataMessage,
+	height int64,
+	validators *tmtypes.ValidatorSet,
+	pubKey crypto.PubKey,
+) bool {
+	dkg := k.DKG(dkgMsg.Data.KeyPurpose)
+	if dkg == nil {
+		k.logger.Debug("keyring: dropping message of unknown key", "key_purpose", dkgMsg.Data.KeyPurpose,
+			"type", dkgMsg.Data.Type, "from", dkgMsg.Data.GetAddrString())
+		return false
+	}
+	// Off-chain instances never switch to on-chain rounds.
+	return dkg.HandleOffChainShare(dkgMsg, height, validators, pubKey)
+}
+
+func (k *Keyring) CheckDKGTime(height int64, validators *tmtypes.ValidatorSet) {
+	for _, dkg := range k.instances() {
+		dkg.CheckDKGTime(height, validators)
+	}
+}
+
+// StartDKGRound starts a round of every key.
+func (k *Keyring) StartDKGRound(validators *tmtypes.ValidatorSet) error {
+	for _, dkg := range k.instances() {
+		if err := dkg.StartDKGRound(validators); err != nil {
+			return fmt.Errorf("failed to start round of key %q: %v", dkg.KeyPurpose(), err)
+		}
+	}
+	return nil
+}
+
+func (k *Keyring) SetVerifier(verifier dkgtypes.Verifier) {
+	if dkg := k.DKG(""); dkg != nil {
+		dkg.SetVerifier(verifier)
+	}
+}
+
+func (k *Keyring) Verifier() dkgtypes.Verifier {
+	return k.VerifierOf("")
+}
+
+func (k *Keyring) MsgQueue() chan *dkgtypes.DKGDataMessage {
+	return k.queue
+}
+
+// GetLosers pops the validators excluded from the current rounds of all keys,
+// each once, ordered by address.
+func (k *Keyring) GetLosers() []*tmtypes.Validator {
+	var (
+		losers []*tmtypes.Validator
+		seen   = make(map[string]bool)
+	)
+	for _, dkg := range k.instances() {
+		for _, loser := range dkg.GetLosers() {
+			if !seen[loser.Address.String()] {
+				seen[loser.Address.String()] = true
+				losers = append(losers, loser)
+			}
+		}
+	}
+	sort.Slice(losers, func(i, j int) bool { return losers[i].Address.String() < losers[j].Address.String() })
+	return losers
+}
+
+func (k *Keyring) IsOnChain() bool {
+	return false
+}
+
+func (k *Keyring) NewBlockNotify() {
+	for _, dkg := range k.instances() {
+		dkg.NewBlockNotify()
+	}
+}
+
+func (k *Keyring) ProcessBlock(roundID int) (error, bool) {
+	return nil, true
+}
+
+// Snapshot describes the rounds of all keys, ordered by key purpose and round
+// ID.
+func (k *Keyring) Snapshot() []*dkgtypes.RoundInfo {
+	var out []*dkgtypes.RoundInfo
+	for _, dkg := range k.instances() {
+		out = append(out, dkg.Snapshot()...)
+	}
+	return out
+}
@@ -0,0 +1,62 @@
+package logging
+
+// RoundKey is the log key of round IDs.
+const RoundKey = "round_id"
+
+type correlationLogger struct {
+	Logger
+	key string
+	id  func(roundID int) string
+}
+
+// WithCorrelation returns a logger adding the key with the round's correlation
+// ID, as computed by the function, to the entries with a RoundKey, unless the
+// entry already has the key.
+func WithCorrelation(logger Logger, key string, id func(roundID int) string) Logger {
+	return correlationLogger{Logger: logger, key: key, id: id}
+}
+
+func (l correlationLogger) Debug(msg string, keyvals ...interface{}) {
+	l.Logger.Debug(msg, l.correlate(keyvals)...)
+}
+
+func (l correlationLogger) Info(msg string, keyvals ...interface{}) {
+	l.Logger.Info(msg, l.correlate(keyvals)...)
+}
+
+func (l correlationLogger) Error(msg string, keyvals ...interface{}) {
+	l.Logger.Error(msg, l.correlate(keyvals)...)
+}
+
+// With stops decorating the entries once the key/value pairs fix the round.
+func (l correlationLogger) With(keyvals ...interface{}) Logger {
+	correlated := l.correlate(keyvals)
+	if len(correlated) != len(keyvals) || hasKey(keyvals, l.key) {
+		return l.Logger.With(correlated...)
+	}
+	return correlationLogger{Logger: l.Logger.With(keyvals...), key: l.key, id: l.id}
+}
+
+func (l correlationLogger) correlate(keyvals []interface{}) []interface{} {
+	if hasKey(keyvals, l.key) {
+		return keyvals
+	}
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] != RoundKey {
+			continue
+		}
+		if roundID, ok := keyvals[i+1].(int); ok {
+			return append(keyvals[:len(keyvals):len(keyvals)], l.key, l.id(roundID))
+		}
+	}
+	return keyvals
+}
+
+func hasKey(keyvals []interface{}, key string) bool {
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		if keyvals[i] == key {
+			return true
+		}
+	}
+	return false
+}
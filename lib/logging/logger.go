@@ -0,0 +1,64 @@
+// Package logging defines the logger used by dkglib, so applications can plug
+// in their own logging library instead of Tendermint's.
+package logging
+
+import (
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+// Logger is a leveled logger taking a message and alternating keys and values,
+// like Tendermint's log.Logger.
+type Logger interface {
+	Debug(msg string, keyvals ...interface{})
+	Info(msg string, keyvals ...interface{})
+	Error(msg string, keyvals ...interface{})
+
+	// With returns a logger adding the key/value pairs to every entry.
+	With(keyvals ...interface{}) Logger
+}
+
+type nopLogger struct{}
+
+func NewNopLogger() Logger { return nopLogger{} }
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+func (l nopLogger) With(...interface{}) Logger { return l }
+
+type tmLogger struct {
+	logger log.Logger
+}
+
+// NewTMLogger adapts a Tendermint logger.
+func NewTMLogger(logger log.Logger) Logger {
+	if logger == nil {
+		return NewNopLogger()
+	}
+	return tmLogger{logger: logger}
+}
+
+func (l tmLogger) Debug(msg string, keyvals ...interface{}) { l.logger.Debug(msg, keyvals...) }
+func (l tmLogger) Info(msg string, keyvals ...interface{})  { l.logger.Info(msg, keyvals...) }
+func (l tmLogger) Error(msg string, keyvals ...interface{}) { l.logger.Error(msg, keyvals...) }
+
+func (l tmLogger) With(keyvals ...interface{}) Logger {
+	return tmLogger{logger: l.logger.With(keyvals...)}
+}
+
+type tmAdapter struct {
+	Logger
+}
+
+// ToTMLogger adapts the logger to Tendermint's interface, e.g. to pass it to
+// Tendermint services; adapted Tendermint loggers are unwrapped.
+func ToTMLogger(logger Logger) log.Logger {
+	if l, ok := logger.(tmLogger); ok {
+		return l.logger
+	}
+	return tmAdapter{Logger: logger}
+}
+
+func (l tmAdapter) With(keyvals ...interface{}) log.Logger {
+	return tmAdapter{Logger: l.Logger.With(keyvals...)}
+}
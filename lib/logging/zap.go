@@ -0,0 +1,26 @@
+//go:build zap
+// +build zap
+
+package logging
+
+import (
+	"go.uber.org/zap"
+)
+
+type zapLogger struct {
+	logger *zap.SugaredLogger
+}
+
+// NewZapLogger adapts a zap logger. It requires building with the zap tag and
+// go.uber.org/zap in the application's module.
+func NewZapLogger(logger *zap.Logger) Logger {
+	return zapLogger{logger: logger.Sugar()}
+}
+
+func (l zapLogger) Debug(msg string, keyvals ...interface{}) { l.logger.Debugw(msg, keyvals...) }
+func (l zapLogger) Info(msg string, keyvals ...interface{})  { l.logger.Infow(msg, keyvals...) }
+func (l zapLogger) Error(msg string, keyvals ...interface{}) { l.logger.Errorw(msg, keyvals...) }
+
+func (l zapLogger) With(keyvals ...interface{}) Logger {
+	return zapLogger{logger: l.logger.With(keyvals...)}
+}
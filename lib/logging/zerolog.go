@@ -0,0 +1,34 @@
+//go:build zerolog
+// +build zerolog
+
+package logging
+
+import (
+	"github.com/rs/zerolog"
+)
+
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+// NewZerologLogger adapts a zerolog logger. It requires building with the
+// zerolog tag and github.com/rs/zerolog in the application's module.
+func NewZerologLogger(logger zerolog.Logger) Logger {
+	return zerologLogger{logger: logger}
+}
+
+func (l zerologLogger) Debug(msg string, keyvals ...interface{}) {
+	l.logger.Debug().Fields(keyvals).Msg(msg)
+}
+
+func (l zerologLogger) Info(msg string, keyvals ...interface{}) {
+	l.logger.Info().Fields(keyvals).Msg(msg)
+}
+
+func (l zerologLogger) Error(msg string, keyvals ...interface{}) {
+	l.logger.Error().Fields(keyvals).Msg(msg)
+}
+
+func (l zerologLogger) With(keyvals ...interface{}) Logger {
+	return zerologLogger{logger: l.logger.With().Fields(keyvals).Logger()}
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/go-kit/kit/metrics"
+	"github.com/go-kit/kit/metrics/discard"
+
+	prometheus "github.com/go-kit/kit/metrics/prometheus"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	// MetricsSubsystem is a subsystem shared by all metrics exposed by this
+	// package.
+	MetricsSubsystem = "dkg"
+)
+
+// Metrics contains metrics exposed by the DKG library.
+type Metrics struct {
+	// Number of misbehavior reports, labeled by type.
+	MisbehaviorReports metrics.Counter
+	// Number of threshold signature shares produced, labeled by epoch.
+	SignatureShares metrics.Counter
+	// Time between broadcasting a DKG message and seeing it on chain, labeled by message type.
+	TxConfirmationSeconds metrics.Histogram
+	// Time since the round start at which the first message of a type arrived from a peer, labeled by peer and message type.
+	PeerMessageLatencySeconds metrics.Histogram
+	// Liveness score of a peer in the last finished round, labeled by peer.
+	PeerLiveness metrics.Gauge
+	// Height up to which the on-chain DKG transactions of the current round have been ingested.
+	IngestWatermarkHeight metrics.Gauge
+	// Messages the current phase of a round still waits for, labeled by phase.
+	PhaseMessagesPending metrics.Gauge
+	// Number of events dropped by the event dispatcher, labeled by event.
+	DroppedEvents metrics.Counter
+	// Events queued by the event dispatcher.
+	PendingEvents metrics.Gauge
+	// Time to produce a threshold signature share.
+	SignShareSeconds metrics.Histogram
+	// Time to recover a threshold signature from the shares.
+	RecoverSeconds metrics.Histogram
+	// Number of failed signature verifications and recoveries, labeled by reason.
+	VerifyFailures metrics.Counter
+	// Number of on-chain DKG queries whose responses differ between the node and the witness.
+	QueryDivergences metrics.Counter
+	// Time the messages of a sender waited for a signature verification worker.
+	VerifyQueueWaitSeconds metrics.Histogram
+}
+
+// PrometheusMetrics returns Metrics build using Prometheus client library.
+// Optionally, labels can be provided along with their values ("foo",
+// "fooValue").
+func PrometheusMetrics(namespace string, labelsAndValues ...string) *Metrics {
+	labels := []string{}
+	for i := 0; i < len(labelsAndValues); i += 2 {
+		labels = append(labels, labelsAndValues[i])
+	}
+	return &Metrics{
+		MisbehaviorReports: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "misbehavior_reports",
+			Help:      "Number of peer misbehavior reports.",
+		}, append(labels, "type")).With(labelsAndValues...),
+		SignatureShares: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "signature_shares",
+			Help:      "Number of threshold signature shares produced.",
+		}, append(labels, "epoch")).With(labelsAndValues...),
+		TxConfirmationSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "tx_confirmation_seconds",
+			Help:      "Time between broadcasting a DKG message and its on-chain commitment.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.5, 2, 10),
+		}, append(labels, "type")).With(labelsAndValues...),
+		PeerMessageLatencySeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_message_latency_seconds",
+			Help:      "Time since the round start at which the first message of a type arrived from a peer.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.5, 2, 10),
+		}, append(labels, "peer", "type")).With(labelsAndValues...),
+		PeerLiveness: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "peer_liveness",
+			Help:      "Liveness score of a peer in the last finished round, from 0 (slowest) to 1 (fastest).",
+		}, append(labels, "peer")).With(labelsAndValues...),
+		IngestWatermarkHeight: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "ingest_watermark_height",
+			Help:      "Height up to which the on-chain DKG transactions of the current round have been ingested.",
+		}, labels).With(labelsAndValues...),
+		PhaseMessagesPending: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "phase_messages_pending",
+			Help:      "Messages the current phase of a round still waits for.",
+		}, append(labels, "phase")).With(labelsAndValues...),
+		DroppedEvents: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "dropped_events",
+			Help:      "Number of events dropped by the event dispatcher because subscribers lag behind.",
+		}, append(labels, "event")).With(labelsAndValues...),
+		PendingEvents: prometheus.NewGaugeFrom(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "pending_events",
+			Help:      "Events queued by the event dispatcher.",
+		}, labels).With(labelsAndValues...),
+		SignShareSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "sign_share_seconds",
+			Help:      "Time to produce a threshold signature share.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0005, 2, 12),
+		}, labels).With(labelsAndValues...),
+		RecoverSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "recover_seconds",
+			Help:      "Time to recover a threshold signature from the shares.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0005, 2, 12),
+		}, labels).With(labelsAndValues...),
+		VerifyFailures: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "verify_failures",
+			Help:      "Number of failed threshold signature verifications and recoveries.",
+		}, append(labels, "reason")).With(labelsAndValues...),
+		QueryDivergences: prometheus.NewCounterFrom(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "query_divergences",
+			Help:      "Number of on-chain DKG queries whose responses differ between the node and the witness.",
+		}, labels).With(labelsAndValues...),
+		VerifyQueueWaitSeconds: prometheus.NewHistogramFrom(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: MetricsSubsystem,
+			Name:      "verify_queue_wait_seconds",
+			Help:      "Time the messages of a sender waited for a signature verification worker.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.0001, 2, 14),
+		}, labels).With(labelsAndValues...),
+	}
+}
+
+// NopMetrics returns no-op Metrics.
+func NopMetrics() *Metrics {
+	return &Metrics{
+		MisbehaviorReports:        discard.NewCounter(),
+		SignatureShares:           discard.NewCounter(),
+		TxConfirmationSeconds:     discard.NewHistogram(),
+		PeerMessageLatencySeconds: discard.NewHistogram(),
+		PeerLiveness:              discard.NewGauge(),
+		IngestWatermarkHeight:     discard.NewGauge(),
+		PhaseMessagesPending:      discard.NewGauge(),
+		DroppedEvents:             discard.NewCounter(),
+		PendingEvents:             discard.NewGauge(),
+		SignShareSeconds:          discard.NewHistogram(),
+		RecoverSeconds:            discard.NewHistogram(),
+		VerifyFailures:            discard.NewCounter(),
+		QueryDivergences:          discard.NewCounter(),
+		VerifyQueueWaitSeconds:    discard.NewHistogram(),
+	}
+}
+
+// ObserveRound records the message latencies and liveness scores of the peers of a finished round.
+func (m *Metrics) ObserveRound(info *types.RoundInfo) {
+	for _, peer := range info.Peers {
+		for dataType, latency := range peer.Latency {
+			m.PeerMessageLatencySeconds.With("peer", peer.Addr.String(), "type", dataType).Observe(latency.Seconds())
+		}
+		m.PeerLiveness.With("peer", peer.Addr.String()).Set(peer.Liveness)
+	}
+}
+
+// ObserveProgress records the messages still awaited by the phases of a round.
+func (m *Metrics) ObserveProgress(progress []types.PhaseProgress) {
+	for _, phase := range progress {
+		pending := phase.Expected - phase.Received
+		if phase.Completed || pending < 0 {
+			pending = 0
+		}
+		m.PhaseMessagesPending.With("phase", phase.Phase).Set(float64(pending))
+	}
+}
+
+// MisbehaviorSink counts misbehavior reports by type.
+type MisbehaviorSink struct {
+	metrics *Metrics
+}
+
+var _ types.MisbehaviorSink = &MisbehaviorSink{}
+
+func NewMisbehaviorSink(m *Metrics) *MisbehaviorSink {
+	return &MisbehaviorSink{metrics: m}
+}
+
+func (s *MisbehaviorSink) ReportMisbehavior(report *types.MisbehaviorReport) {
+	s.metrics.MisbehaviorReports.With("type", report.Type.String()).Add(1)
+}
+
+// VerifierSink records the measurements of the verifier of an epoch.
+type VerifierSink struct {
+	metrics *Metrics
+	epoch   string
+}
+
+var _ blsShare.VerifierMetrics = &VerifierSink{}
+
+func NewVerifierSink(m *Metrics, epoch int) *VerifierSink {
+	return &VerifierSink{metrics: m, epoch: strconv.Itoa(epoch)}
+}
+
+func (s *VerifierSink) ObserveSignShare(d time.Duration) {
+	s.metrics.SignShareSeconds.Observe(d.Seconds())
+	s.metrics.SignatureShares.With("epoch", s.epoch).Add(1)
+}
+
+func (s *VerifierSink) ObserveRecover(d time.Duration, shares int) {
+	s.metrics.RecoverSeconds.Observe(d.Seconds())
+}
+
+func (s *VerifierSink) VerifyFailed(reason string) {
+	s.metrics.VerifyFailures.With("reason", reason).Add(1)
+}
@@ -0,0 +1,27 @@
+package msgs
+
+import (
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authTypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+)
+
+// RegisterCodec registers the messages dkglib sends onto the codec of the app
+// running the DKG module. DKGData is only carried as a field of the messages,
+// so it needs no registration of its own.
+func RegisterCodec(cdc *codec.Codec) {
+	cdc.RegisterConcrete(MsgSendDKGData{}, MsgSendDKGDataTypeName, nil)
+	cdc.RegisterConcrete(MsgReportDKGMisbehavior{}, MsgReportDKGMisbehaviorTypeName, nil)
+	cdc.RegisterConcrete(MsgDKGEpochAnchor{}, MsgDKGEpochAnchorTypeName, nil)
+}
+
+// MakeCodec returns a codec with everything the on-chain DKG client needs to
+// build, sign and broadcast transactions, for apps that don't pass their own.
+func MakeCodec() *codec.Codec {
+	cdc := codec.New()
+	authTypes.RegisterCodec(cdc)
+	sdk.RegisterCodec(cdc)
+	codec.RegisterCrypto(cdc)
+	RegisterCodec(cdc)
+	return cdc
+}
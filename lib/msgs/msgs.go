@@ -1,11 +1,16 @@
 package msgs
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
 )
 
 const (
@@ -13,17 +18,32 @@ const (
 )
 
 type MsgSendDKGData struct {
-	Data  *alias.DKGData `json:"data"`
-	Owner sdk.AccAddress `json:"owner"`
+	Data *alias.DKGData `json:"data"`
+	// Signer is the validator key the data is signed with; it must be the
+	// key of the data's sender.
+	Signer   crypto.PubKey  `json:"signer"`
+	Owner    sdk.AccAddress `json:"owner"`
+	DedupKey []byte         `json:"dedup_key"`
+	// FeePayer, if set, pays the fees of the transaction instead of the owner.
+	FeePayer sdk.AccAddress `json:"fee_payer,omitempty"`
 }
 
-func NewMsgSendDKGData(data *alias.DKGData, owner sdk.AccAddress) MsgSendDKGData {
+func NewMsgSendDKGData(data *alias.DKGData, signer crypto.PubKey, owner sdk.AccAddress) MsgSendDKGData {
 	return MsgSendDKGData{
-		Data:  data,
-		Owner: owner,
+		Data:     data,
+		Signer:   signer,
+		Owner:    owner,
+		DedupKey: DedupKey(data),
 	}
 }
 
+// DedupKey identifies a DKG message by its round, type, sender and payload,
+// so rebroadcasts of the same message share the key. It doesn't depend on the
+// sign domain, so the chain app derives the same key as the sender.
+func DedupKey(data *alias.DKGData) []byte {
+	return tmhash.Sum(data.ContentBytes())
+}
+
 func (msg MsgSendDKGData) String() string {
 	return fmt.Sprintf("Data: %+v, Owner: %s", msg.Data, msg.Owner.String())
 }
@@ -39,9 +59,26 @@ func (msg MsgSendDKGData) ValidateBasic() error {
 	if msg.Owner.Empty() {
 		return fmt.Errorf("data validation failed: empty owner")
 	}
+	if err := sdk.VerifyAddressFormat(msg.Owner); err != nil {
+		return fmt.Errorf("data validation failed: invalid owner: %v", err)
+	}
+	if !msg.FeePayer.Empty() {
+		if err := sdk.VerifyAddressFormat(msg.FeePayer); err != nil {
+			return fmt.Errorf("data validation failed: invalid fee payer: %v", err)
+		}
+	}
+	if msg.Data == nil {
+		return fmt.Errorf("data validation failed: empty data")
+	}
 	if err := msg.Data.ValidateBasic(); err != nil {
 		return fmt.Errorf("data validation failed: %v", err)
 	}
+	if err := msg.Data.ValidateSigner(msg.Signer); err != nil {
+		return fmt.Errorf("data validation failed: %v", err)
+	}
+	if len(msg.DedupKey) != 0 && !bytes.Equal(msg.DedupKey, DedupKey(msg.Data)) {
+		return fmt.Errorf("data validation failed: dedup key mismatch")
+	}
 	return nil
 }
 
@@ -54,7 +91,188 @@ func (msg MsgSendDKGData) GetSignBytes() []byte {
 	return sdk.MustSortJSON(b)
 }
 
-// GetSigners defines whose signature is required
+// GetSigners defines whose signature is required; the fee payer signs first,
+// so the auth module charges the fees to it.
 func (msg MsgSendDKGData) GetSigners() []sdk.AccAddress {
-	return []sdk.AccAddress{msg.Owner}
+	return signers(msg.Owner, msg.FeePayer)
+}
+
+const (
+	MsgReportDKGMisbehaviorTypeName = "randapp/ReportDKGMisbehavior"
+)
+
+// MsgReportDKGMisbehavior submits evidence of a peer misbehavior to the chain.
+type MsgReportDKGMisbehavior struct {
+	Kind     int               `json:"kind"`
+	Addr     []byte            `json:"addr"`
+	RoundID  int               `json:"round_id"`
+	DataType alias.DKGDataType `json:"data_type"`
+	Reason   string            `json:"reason"`
+	Owner    sdk.AccAddress    `json:"owner"`
+	FeePayer sdk.AccAddress    `json:"fee_payer,omitempty"`
+	// Evidence holds the conflicting signed messages of an equivocation, or
+	// the signed offending message of other misbehaviors.
+	Evidence []*alias.DKGData `json:"evidence,omitempty"`
+}
+
+func NewMsgReportDKGMisbehavior(report *types.MisbehaviorReport, owner sdk.AccAddress) MsgReportDKGMisbehavior {
+	var reason string
+	if report.Err != nil {
+		reason = report.Err.Error()
+	}
+	msg := MsgReportDKGMisbehavior{
+		Kind:     int(report.Type),
+		Addr:     report.Addr,
+		RoundID:  report.RoundID,
+		DataType: report.DataType,
+		Reason:   reason,
+		Owner:    owner,
+	}
+	if report.Evidence != nil {
+		msg.Evidence = []*alias.DKGData{report.Evidence.First, report.Evidence.Second}
+	} else if report.Message != nil {
+		msg.Evidence = []*alias.DKGData{report.Message}
+	}
+	return msg
+}
+
+// Report returns the misbehavior report the message carries.
+func (msg MsgReportDKGMisbehavior) Report() *types.MisbehaviorReport {
+	report := &types.MisbehaviorReport{
+		Type:     types.MisbehaviorType(msg.Kind),
+		Addr:     msg.Addr,
+		RoundID:  msg.RoundID,
+		DataType: msg.DataType,
+	}
+	if msg.Reason != "" {
+		report.Err = errors.New(msg.Reason)
+	}
+	switch len(msg.Evidence) {
+	case 1:
+		report.Message = msg.Evidence[0]
+	case 2:
+		report.Evidence = &types.EquivocationEvidence{First: msg.Evidence[0], Second: msg.Evidence[1]}
+	}
+	return report
+}
+
+// VerifyEvidence checks the evidence of the report against the key of the
+// reported address, see types.MisbehaviorReport.VerifyEvidence. The app must
+// check it before acting on the report.
+func (msg MsgReportDKGMisbehavior) VerifyEvidence(pubKey crypto.PubKey, domain alias.SignDomain) error {
+	return msg.Report().VerifyEvidence(pubKey, domain)
+}
+
+func (msg MsgReportDKGMisbehavior) String() string {
+	return fmt.Sprintf("Kind: %d, Addr: %X, RoundID: %d, Reason: %s, Owner: %s",
+		msg.Kind, msg.Addr, msg.RoundID, msg.Reason, msg.Owner.String())
+}
+
+// Route should return the name of the module
+func (msg MsgReportDKGMisbehavior) Route() string { return "randapp" }
+
+// Type should return the action
+func (msg MsgReportDKGMisbehavior) Type() string { return "report_dkg_misbehavior" }
+
+// ValidateBasic runs stateless checks on the message
+func (msg MsgReportDKGMisbehavior) ValidateBasic() error {
+	if msg.Owner.Empty() {
+		return fmt.Errorf("report validation failed: empty owner")
+	}
+	if len(msg.Addr) == 0 {
+		return fmt.Errorf("report validation failed: empty address")
+	}
+	want := 1
+	if types.MisbehaviorType(msg.Kind) == types.MisbehaviorEquivocation {
+		want = 2
+	}
+	if len(msg.Evidence) != want {
+		return fmt.Errorf("report validation failed: %d evidence messages, expected %d", len(msg.Evidence), want)
+	}
+	for _, data := range msg.Evidence {
+		if data == nil || !bytes.Equal(data.Addr, msg.Addr) {
+			return fmt.Errorf("report validation failed: evidence is not from the reported address")
+		}
+	}
+	return nil
+}
+
+// GetSignBytes encodes the message for signing.
+func (msg MsgReportDKGMisbehavior) GetSignBytes() []byte {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return sdk.MustSortJSON(b)
+}
+
+// GetSigners defines whose signature is required, see MsgSendDKGData.GetSigners.
+func (msg MsgReportDKGMisbehavior) GetSigners() []sdk.AccAddress {
+	return signers(msg.Owner, msg.FeePayer)
+}
+
+const (
+	MsgDKGEpochAnchorTypeName = "randapp/DKGEpochAnchor"
+)
+
+// MsgDKGEpochAnchor anchors the transition to the verifier of a round: the
+// attestation carries the group key and the change height it takes over at.
+type MsgDKGEpochAnchor struct {
+	Attestation *types.RoundAttestation `json:"attestation"`
+	Owner       sdk.AccAddress          `json:"owner"`
+	FeePayer    sdk.AccAddress          `json:"fee_payer,omitempty"`
+}
+
+func NewMsgDKGEpochAnchor(attestation *types.RoundAttestation, owner sdk.AccAddress) MsgDKGEpochAnchor {
+	return MsgDKGEpochAnchor{Attestation: attestation, Owner: owner}
+}
+
+func (msg MsgDKGEpochAnchor) String() string {
+	if msg.Attestation == nil {
+		return fmt.Sprintf("Attestation: <nil>, Owner: %s", msg.Owner.String())
+	}
+	return fmt.Sprintf("RoundID: %d, ChangeHeight: %d, Owner: %s",
+		msg.Attestation.RoundID, msg.Attestation.ChangeHeight, msg.Owner.String())
+}
+
+// Route should return the name of the module
+func (msg MsgDKGEpochAnchor) Route() string { return "randapp" }
+
+// Type should return the action
+func (msg MsgDKGEpochAnchor) Type() string { return "dkg_epoch_anchor" }
+
+// ValidateBasic runs stateless checks on the message; the attestation is
+// verified against the committee of the round by the nodes reading it.
+func (msg MsgDKGEpochAnchor) ValidateBasic() error {
+	if msg.Owner.Empty() {
+		return fmt.Errorf("anchor validation failed: empty owner")
+	}
+	if msg.Attestation == nil || msg.Attestation.Verifier == nil {
+		return fmt.Errorf("anchor validation failed: no group key")
+	}
+	if msg.Attestation.ChangeHeight <= 0 {
+		return fmt.Errorf("anchor validation failed: invalid change height %d", msg.Attestation.ChangeHeight)
+	}
+	return nil
+}
+
+// GetSignBytes encodes the message for signing.
+func (msg MsgDKGEpochAnchor) GetSignBytes() []byte {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		panic(err)
+	}
+	return sdk.MustSortJSON(b)
+}
+
+// GetSigners defines whose signature is required, see MsgSendDKGData.GetSigners.
+func (msg MsgDKGEpochAnchor) GetSigners() []sdk.AccAddress {
+	return signers(msg.Owner, msg.FeePayer)
+}
+
+func signers(owner, feePayer sdk.AccAddress) []sdk.AccAddress {
+	if feePayer.Empty() || feePayer.Equals(owner) {
+		return []sdk.AccAddress{owner}
+	}
+	return []sdk.AccAddress{feePayer, owner}
 }
@@ -0,0 +1,24 @@
+package offChain
+
+import (
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+)
+
+// broadcastAbort sends the Abort message of the failed round, so the peers
+// still running it can cross-check the participants its dealer blamed, see
+// Dealer.HandleDKGAbort.
+func (m *OffChainDKG) broadcastAbort(roundID int, reason error) {
+	dealer := m.dkgRoundToDealer[roundID]
+	if dealer == nil {
+		return
+	}
+	abort, err := dealer.GetAbort(reason)
+	if err != nil {
+		m.Logger.Error("dkgState: failed to build abort", "error", err, "round_id", roundID)
+		return
+	}
+	m.Logger.Info("dkgState: aborting round", "round_id", roundID, "reason", reason)
+	if err := m.sendSignedMessage([]*dkgalias.DKGData{abort}); err != nil {
+		m.Logger.Error("dkgState: failed to send abort", "error", err, "round_id", roundID)
+	}
+}
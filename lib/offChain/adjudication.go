@@ -0,0 +1,27 @@
+package offChain
+
+import (
+	"fmt"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+var _ dkgtypes.AdjudicationApplier = &OffChainDKG{}
+
+// ApplyAdjudication applies externally adjudicated exclusions to the dealer of
+// the round, see dealer.Dealer.ApplyAdjudication; the round's dealer must not
+// have been dropped yet.
+func (m *OffChainDKG) ApplyAdjudication(roundID int, decisions []dkgtypes.Adjudication) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	dealer, ok := m.dkgRoundToDealer[roundID]
+	if !ok {
+		return fmt.Errorf("no dealer of round %d", roundID)
+	}
+	if err := dealer.ApplyAdjudication(roundID, decisions); err != nil {
+		m.Logger.Error("dkgState: failed to apply adjudication", "round_id", roundID, "error", err)
+		return err
+	}
+	return nil
+}
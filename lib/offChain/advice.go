@@ -0,0 +1,35 @@
+package offChain
+
+import (
+	"time"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// maxTimingSamples is the number of finished rounds whose snapshots are kept
+// for the timing advice.
+const maxTimingSamples = 32
+
+var _ dkgtypes.TimingSampler = &OffChainDKG{}
+
+// sampleTimings keeps the snapshot of a finished round.
+func (m *OffChainDKG) sampleTimings(info *dkgtypes.RoundInfo) {
+	if len(m.timingSamples) >= maxTimingSamples {
+		m.timingSamples = m.timingSamples[1:]
+	}
+	m.timingSamples = append(m.timingSamples, info)
+}
+
+// TimingSamples returns the snapshots of the last finished rounds, with the
+// latency of the peers' messages.
+func (m *OffChainDKG) TimingSamples() []*dkgtypes.RoundInfo {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return append([]*dkgtypes.RoundInfo(nil), m.timingSamples...)
+}
+
+// AdviseTimings recommends timing settings for the network from the last
+// finished rounds, see types.AdviseTimings.
+func (m *OffChainDKG) AdviseTimings(blockTime time.Duration) (*dkgtypes.TimingAdvice, error) {
+	return dkgtypes.AdviseTimings(m.TimingSamples(), blockTime)
+}
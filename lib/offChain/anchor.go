@@ -0,0 +1,163 @@
+package offChain
+
+import (
+	"bytes"
+	"fmt"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithEpochAnchoring activates the verifiers of successful rounds in two
+// phases: once the participants agreed on the change height, the proposer of
+// the round (see dkgtypes.RoundProposer) anchors the round's attestation on
+// chain, and every node activates the verifier at the change height of the
+// anchored attestation once it sees it, instead of at the one it computed.
+// Anchors are verified against the committee and the master public key of the
+// round. A round whose proposer doesn't anchor it is never activated; the
+// next round replaces it. All nodes must use the same setting. A nil chain is
+// set later with SetAnchorChain; DKGBasic anchors through its on-chain DKG.
+func WithEpochAnchoring(chain dkgtypes.AnchorChain) DKGOption {
+	return func(d *OffChainDKG) { d.anchoring, d.anchorChain = true, chain }
+}
+
+func (m *OffChainDKG) Anchoring() bool {
+	return m.anchoring
+}
+
+func (m *OffChainDKG) AnchorChain() dkgtypes.AnchorChain {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.anchorChain
+}
+
+func (m *OffChainDKG) SetAnchorChain(chain dkgtypes.AnchorChain) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.anchorChain = chain
+}
+
+// pendingAnchor is the round whose verifier awaits its anchor.
+type pendingAnchor struct {
+	roundID      int
+	participants *dkgtypes.ParticipantSet
+	keyHash      []byte
+	changeHeight int64 // Anchored change height, zero until the anchor is seen.
+	queriedAt    int64 // Height the chain was last queried at.
+}
+
+// anchorRound awaits the anchor of the successful round and publishes it if
+// the node is the round's proposer.
+func (m *OffChainDKG) anchorRound(roundID int, participants *dkgtypes.ParticipantSet, snapshot *dkgtypes.VerifierSnapshot) {
+	if !m.anchoring {
+		return
+	}
+	anchor := &pendingAnchor{roundID: roundID, participants: participants}
+	if snapshot != nil {
+		anchor.keyHash = dkgtypes.MasterPubKeyHash(snapshot)
+	}
+	m.anchor = anchor
+	if _, attested := m.attestations[roundID]; attested {
+		m.publishAnchor(roundID)
+	}
+}
+
+// publishAnchor publishes the attestation of the awaited round if the node is
+// the round's proposer. The attestation may be stored after the round is
+// scheduled, once the last participant confirmed it.
+func (m *OffChainDKG) publishAnchor(roundID int) {
+	if m.anchor == nil || m.anchor.roundID != roundID {
+		return
+	}
+	proposer := dkgtypes.RoundProposer(m.anchor.participants, roundID)
+	if proposer == nil || !bytes.Equal(proposer.Address, m.privValidator.GetPubKey().Address()) {
+		return
+	}
+	attestation, ok := m.attestations[roundID]
+	if !ok || m.anchorChain == nil {
+		m.Logger.Error("dkgState: round can't be anchored", "round_id", roundID, "attested", ok)
+		return
+	}
+	chain := m.anchorChain
+	go func() {
+		if err := chain.PublishEpochAnchor(attestation); err != nil {
+			m.Logger.Error("dkgState: failed to anchor epoch", "round_id", roundID, "error", err)
+		}
+	}()
+}
+
+// anchoredActivation reports whether the next verifier is anchored and its
+// anchored change height is reached. The anchor is queried at most once per
+// height, without holding the lock, and cached once seen.
+func (m *OffChainDKG) anchoredActivation(height int64) bool {
+	m.mtx.Lock()
+	anchor, chain := m.anchor, m.anchorChain
+	if anchor == nil || m.nextVerifier == nil || anchor.roundID != m.nextVerifierRoundID {
+		m.mtx.Unlock()
+		return false
+	}
+	changeHeight, queried := anchor.changeHeight, anchor.queriedAt == height
+	anchor.queriedAt = height
+	m.mtx.Unlock()
+	if changeHeight != 0 {
+		return height >= changeHeight
+	}
+	if queried {
+		return false
+	}
+
+	changeHeight, err := m.fetchAnchor(chain, anchor)
+	if err != nil {
+		m.Logger.Error("dkgState: failed to fetch epoch anchor", "round_id", anchor.roundID, "error", err)
+		return false
+	}
+	if changeHeight == 0 {
+		return false
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.anchor != anchor {
+		// A later round replaced the anchored one meanwhile.
+		return false
+	}
+	m.Logger.Info("dkgState: epoch anchored", "round_id", anchor.roundID,
+		"change_height", changeHeight, "proposed_change_height", m.changeHeight)
+	anchor.changeHeight, m.changeHeight = changeHeight, changeHeight
+	return height >= changeHeight
+}
+
+// fetchAnchor returns the change height of the first valid anchor of the
+// round on the chain, or zero if the round isn't anchored yet.
+func (m *OffChainDKG) fetchAnchor(chain dkgtypes.AnchorChain, anchor *pendingAnchor) (int64, error) {
+	if anchor.keyHash == nil {
+		return 0, fmt.Errorf("the round's master public key can't be exported")
+	}
+	if chain == nil {
+		return 0, fmt.Errorf("no anchor chain")
+	}
+	attestations, err := chain.EpochAnchors(anchor.roundID)
+	if err != nil {
+		return 0, err
+	}
+	for _, attestation := range attestations {
+		if err := verifyAnchor(attestation, anchor); err != nil {
+			m.Logger.Error("dkgState: invalid epoch anchor", "round_id", anchor.roundID, "error", err)
+			continue
+		}
+		return attestation.ChangeHeight, nil
+	}
+	return 0, nil
+}
+
+func verifyAnchor(attestation *dkgtypes.RoundAttestation, anchor *pendingAnchor) error {
+	if attestation.RoundID != anchor.roundID {
+		return fmt.Errorf("anchor is of round %d", attestation.RoundID)
+	}
+	if err := attestation.Verify(anchor.participants); err != nil {
+		return err
+	}
+	if !bytes.Equal(dkgtypes.MasterPubKeyHash(attestation.Verifier), anchor.keyHash) {
+		return fmt.Errorf("anchored master public key differs from the round's")
+	}
+	return nil
+}
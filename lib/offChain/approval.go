@@ -0,0 +1,83 @@
+package offChain
+
+import (
+	"fmt"
+	"time"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+var _ dkgtypes.VerifierApprover = &OffChainDKG{}
+
+// WithVerifierApproval stages the verifiers of successful rounds until an operator
+// approves them with ApproveVerifier, giving operators a window to check the epoch
+// is consistent across nodes. Verifiers approved after the change height are
+// activated at the next block. A non-zero timeout activates the verifier without
+// approval once the timeout has passed since it was staged.
+func WithVerifierApproval(timeout time.Duration) DKGOption {
+	return func(d *OffChainDKG) { d.requireApproval, d.approvalTimeout = true, timeout }
+}
+
+// stageVerifier starts the approval window of the next verifier.
+func (m *OffChainDKG) stageVerifier(roundID int, changeHeight int64, snapshot *dkgtypes.VerifierSnapshot) {
+	if !m.requireApproval {
+		return
+	}
+	staged := &dkgtypes.StagedVerifier{
+		RoundID:      roundID,
+		ChangeHeight: changeHeight,
+		StagedAt:     time.Now(),
+	}
+	if snapshot != nil {
+		staged.MasterPubKeyHash = dkgtypes.MasterPubKeyHash(snapshot)
+	}
+	if m.approvalTimeout > 0 {
+		staged.Deadline = staged.StagedAt.Add(m.approvalTimeout)
+	}
+	m.staged = staged
+	m.Logger.Info("dkgState: verifier staged for approval", "round_id", roundID,
+		"master_pub_key_hash", staged.MasterPubKeyHash, "deadline", staged.Deadline)
+}
+
+func (m *OffChainDKG) StagedVerifier() *dkgtypes.StagedVerifier {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	if m.staged == nil {
+		return nil
+	}
+	staged := *m.staged
+	return &staged
+}
+
+// ApproveVerifier approves the staged verifier of the round.
+func (m *OffChainDKG) ApproveVerifier(roundID int) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.staged == nil {
+		return fmt.Errorf("no verifier awaits approval")
+	}
+	if m.staged.RoundID != roundID {
+		return fmt.Errorf("staged verifier is of round %d, not %d", m.staged.RoundID, roundID)
+	}
+	m.staged.Approved = true
+	m.Logger.Info("dkgState: verifier approved", "round_id", roundID)
+	return nil
+}
+
+// activationAllowed reports whether the next verifier may be activated; the
+// approval window ends when it is.
+func (m *OffChainDKG) activationAllowed() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.staged == nil {
+		return true
+	}
+	if !m.staged.Approved && (m.staged.Deadline.IsZero() || time.Now().Before(m.staged.Deadline)) {
+		return false
+	}
+	if !m.staged.Approved {
+		m.Logger.Info("dkgState: verifier approval timed out", "round_id", m.staged.RoundID)
+	}
+	m.staged = nil
+	return true
+}
@@ -0,0 +1,73 @@
+package offChain
+
+import (
+	"bytes"
+	"fmt"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// maxAttestations is the number of completed rounds whose attestations are kept.
+const maxAttestations = 16
+
+// storeAttestation keeps the confirmations of a round every participant has
+// confirmed, provided they all confirmed the key this node computed, and
+// reports whether it did. The round is usually scheduled before the last
+// confirmations arrive, so it is called again for every later one.
+func (m *OffChainDKG) storeAttestation(roundID int, agreement *changeHeightAgreement, participants *dkgtypes.ParticipantSet) bool {
+	if agreement.snapshot == nil {
+		return false
+	}
+	attestation := &dkgtypes.RoundAttestation{
+		RoundID:      roundID,
+		Verifier:     agreement.snapshot,
+		ChangeHeight: agreement.changeHeight,
+		Participants: participants.Participants(),
+		ChainID:      m.chainID,
+		SignVersion:  m.signBytesVersion,
+		KeyPurpose:   m.keyPurpose,
+	}
+	if dealer := m.dkgRoundToDealer[roundID]; dealer != nil {
+		attestation.Proofs = dealer.Proofs()
+	}
+	keyHash := dkgtypes.MasterPubKeyHash(agreement.snapshot)
+	for _, participant := range attestation.Participants {
+		confirmation := agreement.confirmations[participant.Address.String()]
+		if confirmation == nil {
+			return false
+		}
+		if _, hash, _ := dkgtypes.DecodeConfirmation(confirmation.Data); !bytes.Equal(hash, keyHash) {
+			m.Logger.Error("dkgState: participant confirmed a different master public key",
+				"round_id", roundID, "addr", participant.Address)
+			return false
+		}
+		attestation.Confirmations = append(attestation.Confirmations, confirmation)
+		if migration, ok := m.migrations[participant.Address.String()]; ok {
+			attestation.Migrations = append(attestation.Migrations, migration)
+		}
+	}
+
+	m.attestations[roundID] = attestation
+	for len(m.attestations) > maxAttestations {
+		oldest := roundID
+		for id := range m.attestations {
+			if id < oldest {
+				oldest = id
+			}
+		}
+		delete(m.attestations, oldest)
+	}
+	return true
+}
+
+// GetRoundAttestation returns the attestation of one of the last completed rounds.
+func (m *OffChainDKG) GetRoundAttestation(roundID int) (*dkgtypes.RoundAttestation, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	attestation, ok := m.attestations[roundID]
+	if !ok {
+		return nil, fmt.Errorf("no attestation of round %d", roundID)
+	}
+	return attestation, nil
+}
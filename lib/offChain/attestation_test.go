@@ -0,0 +1,27 @@
+package offChain
+
+import "testing"
+
+func TestRoundAttestation(t *testing.T) {
+	cluster := newTestCluster(t, 4)
+	cluster.runRound(t, 1)
+	// Let the confirmations sent after the round was scheduled arrive.
+	cluster.deliver()
+
+	for i, node := range cluster.nodes {
+		attestation, err := node.GetRoundAttestation(1)
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		committee, err := node.Committee(1)
+		if err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		if err := attestation.Verify(node.newParticipantSet(cluster.validators)); err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+		if committee.Size() != len(cluster.validators.Validators) {
+			t.Fatalf("node %d: committee of %d, want %d", i, committee.Size(), len(cluster.validators.Validators))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package offChain
+
+import (
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkglib "github.com/corestario/dkglib/lib/dealer"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// WithBlacklist excludes blacklisted peers from new rounds. At the end of every
+// round the node votes on chain against the peers its dealer excluded, and the
+// peers voted against by more than a third of the participants are blacklisted
+// when the next round starts. Votes are read at the height before the round
+// start, so honest nodes agree on the participants. Without a chain, only the
+// manual entries of the blacklist are applied.
+func WithBlacklist(blacklist *dkgtypes.Blacklist, chain dkgtypes.BlacklistChain) DKGOption {
+	return func(d *OffChainDKG) { d.blacklist, d.blacklistChain = blacklist, chain }
+}
+
+func (m *OffChainDKG) Blacklist() *dkgtypes.Blacklist {
+	return m.blacklist
+}
+
+func (m *OffChainDKG) BlacklistChain() dkgtypes.BlacklistChain {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.blacklistChain
+}
+
+func (m *OffChainDKG) SetBlacklistChain(chain dkgtypes.BlacklistChain) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	m.blacklistChain = chain
+}
+
+// dealerTaps records the peers excluded by the dealer of the round to vote against them.
+func (m *OffChainDKG) dealerTaps(roundID int) dkglib.EventTaps {
+	if m.blacklist == nil {
+		return m.taps
+	}
+	taps := m.taps
+	taps.OnPeerExcluded = func(peer crypto.Address, reason error) {
+		m.roundLosers[roundID] = append(m.roundLosers[roundID], peer)
+		if m.taps.OnPeerExcluded != nil {
+			m.taps.OnPeerExcluded(peer, reason)
+		}
+	}
+	return taps
+}
+
+// voteBlacklist publishes the vote against the losers of the finished round.
+func (m *OffChainDKG) voteBlacklist(roundID int) {
+	losers, ok := m.roundLosers[roundID]
+	delete(m.roundLosers, roundID)
+	if !ok || m.blacklistChain == nil {
+		return
+	}
+
+	vote := &dkgalias.DKGData{
+		Type:    dkgalias.DKGBlacklist,
+		RoundID: roundID,
+		Addr:    m.privValidator.GetPubKey().Address().Bytes(),
+		Data:    dkgtypes.EncodeBlacklistVote(losers),
+	}
+	if err := m.Sign(vote); err != nil {
+		m.Logger.Error("dkgState: failed to sign blacklist vote", "round_id", roundID, "error", err)
+		return
+	}
+	go func() {
+		if err := m.blacklistChain.PublishBlacklistVote(vote); err != nil {
+			m.Logger.Error("dkgState: failed to publish blacklist vote", "round_id", roundID, "error", err)
+		}
+	}()
+}
+
+// blacklistVotes fetches the votes on the round before roundID, tallied at the
+// block before the round's start height, so every node adopts the same
+// entries. It queries the chain, so it must be called without holding m.mtx;
+// it returns nil if the votes can't be fetched.
+func (m *OffChainDKG) blacklistVotes(roundID int, startHeight int64) []*dkgalias.DKGData {
+	if m.blacklist == nil || m.blacklistChain == nil || roundID == 0 {
+		return nil
+	}
+	var height int64
+	if startHeight > 1 {
+		height = startHeight - 1
+	}
+	votes, err := m.blacklistChain.BlacklistVotes(roundID-1, height)
+	if err != nil {
+		m.Logger.Error("dkgState: failed to fetch blacklist votes", "round_id", roundID-1, "height", height, "error", err)
+		return nil
+	}
+	return votes
+}
+
+// filterParticipants adopts the votes on the previous round and removes the
+// blacklisted peers from the participants of the new one.
+func (m *OffChainDKG) filterParticipants(roundID int, participants *dkgtypes.ParticipantSet, votes []*dkgalias.DKGData) *dkgtypes.ParticipantSet {
+	if m.blacklist == nil {
+		return participants
+	}
+
+	if len(votes) > 0 {
+		adopted, err := m.blacklist.Adopt(roundID-1, votes, m.blacklist.Filter(participants, roundID-1), m.signDomain)
+		if err != nil {
+			m.Logger.Error("dkgState: failed to persist blacklist", "error", err)
+		}
+		for _, entry := range adopted {
+			m.Logger.Info("dkgState: peer blacklisted", "addr", entry.Addr, "reason", entry.Reason, "expires", entry.Expires)
+		}
+	}
+
+	return m.blacklist.Filter(participants, roundID)
+}
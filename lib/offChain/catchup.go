@@ -0,0 +1,176 @@
+package offChain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// DefaultTranscriptSize is the number of messages kept per round for catch-up.
+const DefaultTranscriptSize = 1 << 16
+
+// WithCatchUp lets a node pull the gossip messages it missed. Every node keeps
+// the verified messages of its active rounds, at most DefaultTranscriptSize per
+// round, and serves them again when asked. A round that hasn't accepted a
+// message for the interval requests the messages its dealer waits for, see
+// Dealer.Progress, from the peers, at most once per interval; a node answers
+// the same request at most once per interval too. Zero disables catch-up.
+func WithCatchUp(interval time.Duration) DKGOption {
+	return func(d *OffChainDKG) { d.catchUpInterval = interval }
+}
+
+// transcriptKey identifies the messages of a type sent by a participant.
+type transcriptKey struct {
+	dataType dkgalias.DKGDataType
+	from     string
+}
+
+// roundTranscript keeps the verified messages of a round, as received, so the
+// chunks of split messages are served with their own signatures.
+type roundTranscript struct {
+	messages     map[transcriptKey][]*dkgalias.DKGData
+	size         int
+	lastAccepted time.Time                   // Last message accepted by the round's dealer.
+	lastRequest  time.Time                   // Last request for the round's missing messages.
+	served       map[transcriptKey]time.Time // Last time the messages were served again.
+}
+
+func (m *OffChainDKG) transcript(roundID int) *roundTranscript {
+	t, ok := m.transcripts[roundID]
+	if !ok {
+		t = &roundTranscript{
+			messages:     make(map[transcriptKey][]*dkgalias.DKGData),
+			lastAccepted: time.Now(),
+			served:       make(map[transcriptKey]time.Time),
+		}
+		m.transcripts[roundID] = t
+	}
+	return t
+}
+
+// recordTranscript keeps the verified message for catch-up.
+func (m *OffChainDKG) recordTranscript(msg *dkgalias.DKGData) {
+	if m.catchUpInterval <= 0 || msg.Type == dkgalias.DKGRequestMissing {
+		return
+	}
+	t := m.transcript(msg.RoundID)
+	if t.size >= DefaultTranscriptSize {
+		return
+	}
+	key := transcriptKey{dataType: msg.Type, from: msg.GetAddrString()}
+	t.messages[key] = append(t.messages[key], msg)
+	t.size++
+}
+
+// noteAccepted records the round's progress, delaying its catch-up requests.
+func (m *OffChainDKG) noteAccepted(roundID int) {
+	if m.catchUpInterval > 0 {
+		m.transcript(roundID).lastAccepted = time.Now()
+	}
+}
+
+// RequestMissing asks the peers to send again the messages of the type sent by
+// the participant in the round.
+func (m *OffChainDKG) RequestMissing(roundID int, dataType dkgalias.DKGDataType, from crypto.Address) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.requestMissing(roundID, dataType, from)
+}
+
+func (m *OffChainDKG) requestMissing(roundID int, dataType dkgalias.DKGDataType, from crypto.Address) error {
+	m.Logger.Debug("dkgState: requesting missing messages", "round_id", roundID, "type", dataType, "from", from.String())
+	if err := m.sendSignedMessage([]*dkgalias.DKGData{{
+		Type:    dkgalias.DKGRequestMissing,
+		RoundID: roundID,
+		Addr:    m.privValidator.GetPubKey().Address().Bytes(),
+		Data:    encodeMissingRequest(dataType, from),
+	}}); err != nil {
+		return fmt.Errorf("failed to request missing messages: %v", err)
+	}
+	return nil
+}
+
+// requestGaps requests the missing messages of the rounds that stopped
+// progressing.
+func (m *OffChainDKG) requestGaps() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	now := time.Now()
+	self := m.privValidator.GetPubKey().Address()
+	for roundID, dealer := range m.dkgRoundToDealer {
+		if dealer == nil {
+			continue
+		}
+		t := m.transcript(roundID)
+		if now.Sub(t.lastAccepted) < m.catchUpInterval || now.Sub(t.lastRequest) < m.catchUpInterval {
+			continue
+		}
+		waiting := dkgtypes.Waiting(dealer.Progress())
+		if waiting == nil {
+			continue
+		}
+		dataType, ok := dkgalias.ParseDKGDataType(waiting.Type)
+		if !ok {
+			continue
+		}
+		t.lastRequest = now
+		for _, addr := range waiting.Missing {
+			if bytes.Equal(addr, self) {
+				continue
+			}
+			if err := m.requestMissing(roundID, dataType, addr); err != nil {
+				m.Logger.Error("dkgState: failed to request missing messages", "error", err, "round_id", roundID)
+				return
+			}
+		}
+	}
+}
+
+// serveMissing gossips again the messages asked for by the verified request.
+func (m *OffChainDKG) serveMissing(msg *dkgalias.DKGData) error {
+	if m.catchUpInterval <= 0 || bytes.Equal(msg.Addr, m.privValidator.GetPubKey().Address()) {
+		return nil
+	}
+	dataType, from, err := decodeMissingRequest(msg.Data)
+	if err != nil {
+		return err
+	}
+	t, ok := m.transcripts[msg.RoundID]
+	if !ok {
+		return nil
+	}
+	key := transcriptKey{dataType: dataType, from: from.String()}
+	if served, ok := t.served[key]; ok && time.Since(served) < m.catchUpInterval {
+		return nil
+	}
+	messages := t.messages[key]
+	if len(messages) == 0 {
+		return nil
+	}
+	t.served[key] = time.Now()
+	m.Logger.Debug("dkgState: serving missing messages", "round_id", msg.RoundID, "type", dataType,
+		"from", from.String(), "count", len(messages), "to", msg.GetAddrString())
+	for _, data := range messages {
+		m.firer.FireEvent(dkgtypes.EventDKGData, data)
+	}
+	return nil
+}
+
+func encodeMissingRequest(dataType dkgalias.DKGDataType, from crypto.Address) []byte {
+	data := make([]byte, 4, 4+len(from))
+	binary.BigEndian.PutUint32(data, uint32(dataType))
+	return append(data, from...)
+}
+
+func decodeMissingRequest(data []byte) (dkgalias.DKGDataType, crypto.Address, error) {
+	if len(data) <= 4 {
+		return 0, nil, fmt.Errorf("malformed missing messages request of %d bytes", len(data))
+	}
+	return dkgalias.DKGDataType(binary.BigEndian.Uint32(data)), crypto.Address(data[4:]), nil
+}
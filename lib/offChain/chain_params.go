@@ -0,0 +1,84 @@
+package offChain
+
+import (
+	"bytes"
+	"sort"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/alias"
+)
+
+// WithParamsSource reads the DKG parameters decided on chain, e.g. with
+// onChain.QueryClient, at the start of every round. The round interval and the
+// blocks ahead replace the node's, and the threshold formula and the
+// committee size apply to the round, unless it is triggered with its own. The
+// parameters are read at the height before the round start, so all nodes read
+// the same ones; a node failing to read them keeps the last ones, and
+// WithParamsNegotiation aborts the round if they differ from its proposer's.
+func WithParamsSource(source dkgtypes.ParamsSource) DKGOption {
+	return func(d *OffChainDKG) { d.paramsSource = source }
+}
+
+// ChainParams returns the parameters read at the last round start, or nil.
+func (m *OffChainDKG) ChainParams() *dkgtypes.DKGParams {
+	return m.chainParams
+}
+
+// applyChainParams reads the parameters at the block before the round's start
+// height from the source and applies them.
+func (m *OffChainDKG) applyChainParams(startHeight int64) {
+	if m.paramsSource == nil {
+		return
+	}
+	var height int64
+	if startHeight > 1 {
+		height = startHeight - 1
+	}
+	params, err := m.paramsSource.DKGParams(height)
+	if err == nil {
+		err = params.Validate()
+	}
+	if err != nil {
+		m.Logger.Error("dkgState: failed to read chain params, keeping the last ones", "height", height, "error", err)
+		return
+	}
+
+	numBlocks, blocksAhead := m.dkgNumBlocks, m.blocksAhead
+	if params.NumBlocks > 0 {
+		numBlocks = params.NumBlocks
+	}
+	if params.BlocksAhead > 0 {
+		blocksAhead = params.BlocksAhead
+	}
+	if blocksAhead >= numBlocks || (m.pipelineLead != 0 && m.pipelineLead >= numBlocks) {
+		m.Logger.Error("dkgState: chain params don't fit the node's configuration, keeping the last ones",
+			"num_blocks", numBlocks, "blocks_ahead", blocksAhead, "pipeline_lead", m.pipelineLead)
+		return
+	}
+	if numBlocks != m.dkgNumBlocks || blocksAhead != m.blocksAhead {
+		m.Logger.Info("dkgState: timing changed by chain params", "num_blocks", numBlocks, "blocks_ahead", blocksAhead)
+	}
+	m.mtx.Lock()
+	m.dkgNumBlocks, m.blocksAhead, m.chainParams = numBlocks, blocksAhead, params
+	m.mtx.Unlock()
+}
+
+// limitCommittee returns the validators with the most voting power, as many as
+// the committee size of the chain's parameters; ties go to the lowest address.
+func (m *OffChainDKG) limitCommittee(validators *alias.ValidatorSet) *alias.ValidatorSet {
+	if validators == nil || m.chainParams == nil || m.chainParams.CommitteeSize == 0 ||
+		int64(validators.Size()) <= m.chainParams.CommitteeSize {
+		return validators
+	}
+	list := make([]*alias.Validator, 0, validators.Size())
+	for _, validator := range validators.Validators {
+		list = append(list, validator.Copy())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].VotingPower != list[j].VotingPower {
+			return list[i].VotingPower > list[j].VotingPower
+		}
+		return bytes.Compare(list[i].Address, list[j].Address) < 0
+	})
+	return alias.NewValidatorSet(list[:m.chainParams.CommitteeSize])
+}
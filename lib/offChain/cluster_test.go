@@ -0,0 +1,156 @@
+package offChain
+
+import (
+	"sync"
+	"testing"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+const (
+	testRoundBlocks = 30
+	testListener    = "offchain-test"
+)
+
+// testCluster delivers the messages gossiped by in-process nodes to the other nodes.
+type testCluster struct {
+	mtx        sync.Mutex
+	nodes      []*OffChainDKG
+	switches   []events.EventSwitch
+	queue      []testDelivery
+	epochs     []int // Epoch of every node's verifier.
+	validators *tmtypes.ValidatorSet
+	height     int64
+	committee  int  // Number of nodes taking part in the rounds, all by default.
+	lagging    bool // The nodes but the first see every block after the first's messages.
+}
+
+type testDelivery struct {
+	to  int
+	msg *dkgalias.DKGData
+}
+
+func newTestCluster(t *testing.T, n int, options ...DKGOption) *testCluster {
+	t.Helper()
+	var (
+		pvs        = make([]tmtypes.PrivValidator, n)
+		validators = make([]*tmtypes.Validator, n)
+	)
+	for i := range pvs {
+		pvs[i] = tmtypes.NewMockPVWithParams(ed25519.GenPrivKey(), false, false)
+		pubKey := pvs[i].GetPubKey()
+		validators[i] = &tmtypes.Validator{Address: pubKey.Address(), PubKey: pubKey, VotingPower: 1}
+	}
+	c := &testCluster{validators: tmtypes.NewValidatorSet(validators), epochs: make([]int, n), committee: n}
+	t.Cleanup(c.stop)
+	for i, pv := range pvs {
+		evsw := events.NewEventSwitch()
+		if err := evsw.Start(); err != nil {
+			t.Fatal(err)
+		}
+		nodeOptions := append([]DKGOption{
+			WithPVKey(pv),
+			WithLogger(log.NewNopLogger()),
+			WithDKGNumBlocks(testRoundBlocks),
+		}, options...)
+		c.nodes = append(c.nodes, NewOffChainDKG(evsw, "", nodeOptions...))
+		c.switches = append(c.switches, evsw)
+		c.listen(i, evsw)
+	}
+	return c
+}
+
+func (c *testCluster) listen(from int, evsw events.EventSwitch) {
+	evsw.AddListenerForEvent(testListener, dkgtypes.EventDKGData, func(data events.EventData) {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		for to := range c.nodes {
+			if to != from {
+				c.queue = append(c.queue, testDelivery{to: to, msg: data.(*dkgalias.DKGData)})
+			}
+		}
+	})
+	evsw.AddListenerForEvent(testListener, dkgtypes.EventDKGKeyChange, func(data events.EventData) {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		c.epochs[from] = data.(dkgtypes.DKGKeyChangeEvent).Epoch
+	})
+}
+
+// runRound advances the chain until the verifier of every node of the
+// committee comes from the round.
+func (c *testCluster) runRound(t *testing.T, round int) {
+	t.Helper()
+	deadline := c.height + 2*testRoundBlocks + c.nodes[0].BlocksAhead()
+	for c.height < deadline {
+		c.height++
+		for i, node := range c.nodes {
+			if i == 1 && c.lagging {
+				c.deliver()
+			}
+			node.CheckDKGTime(c.height, c.validators)
+		}
+		c.deliver()
+		if c.switched(round) {
+			return
+		}
+	}
+	t.Fatalf("round %d didn't complete by height %d", round, deadline)
+}
+
+// deliver handles the queued messages until no node has any left.
+func (c *testCluster) deliver() {
+	for {
+		delivered := false
+		for _, node := range c.nodes {
+			select {
+			case msg := <-node.MsgQueue():
+				node.HandleOffChainShare(msg, c.height, c.validators, nil)
+				delivered = true
+			default:
+			}
+			if next, ok := c.next(); ok {
+				c.nodes[next.to].HandleOffChainShare(&dkgtypes.DKGDataMessage{Data: next.msg}, c.height, c.validators, nil)
+				delivered = true
+			}
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+func (c *testCluster) next() (testDelivery, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.queue) == 0 {
+		return testDelivery{}, false
+	}
+	next := c.queue[0]
+	c.queue = c.queue[1:]
+	return next, true
+}
+
+func (c *testCluster) switched(round int) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	var switched int
+	for _, epoch := range c.epochs {
+		if epoch == round {
+			switched++
+		}
+	}
+	return switched == c.committee
+}
+
+func (c *testCluster) stop() {
+	for _, evsw := range c.switches {
+		evsw.RemoveListener(testListener)
+		evsw.Stop()
+	}
+}
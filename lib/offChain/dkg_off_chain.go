@@ -1,14 +1,22 @@
 package offChain
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"sync"
+	"time"
 
 	dkgalias "github.com/corestario/dkglib/lib/alias"
 	"github.com/corestario/dkglib/lib/blsShare"
 	dkglib "github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/eventbus"
+	"github.com/corestario/dkglib/lib/faultinject"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/metrics"
 	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/corestario/dkglib/lib/wal"
 	"github.com/tendermint/tendermint/alias"
 	tmtypes "github.com/tendermint/tendermint/alias"
 	"github.com/tendermint/tendermint/crypto"
@@ -17,48 +25,203 @@ import (
 )
 
 const (
-	BlocksAhead         = 20  // Agree to swap verifier after around this number of blocks.
-	DefaultDKGNumBlocks = 100 //DefaultDKGNumBlocks sets how often node should make DKG(in blocks)
+	DefaultBlocksAhead  = 20  // Agree to swap verifier after around this number of blocks, unless WithBlocksAhead is set.
+	DefaultDKGNumBlocks = 100 //DefaultDKGNumBlocks sets how often node should make DKG(in blocks), unless WithDKGNumBlocks is set.
+	changeHeightAlign   = 5   // Change heights are aligned to this number of blocks.
+
+	chunkMaxAge = 10 * time.Minute // Messages not reassembled within this time are dropped.
 )
 
 type OffChainDKG struct {
 	mtx sync.RWMutex
 
-	verifier     dkgtypes.Verifier
-	nextVerifier dkgtypes.Verifier
-	changeHeight int64
+	verifier            dkgtypes.Verifier
+	nextVerifier        dkgtypes.Verifier
+	changeHeight        int64
+	verifierRoundID     int
+	nextVerifierRoundID int
+
+	lastRoundID     int
+	lastRoundResult dkgtypes.RoundResult
+	lastMessageTime time.Time
 
 	dkgMsgQueue      chan *dkgtypes.DKGDataMessage // message queue used for dkgState-related messages.
 	dkgRoundToDealer map[int]dkglib.Dealer
-	dkgRoundID       int
+	roundDealers     map[int]dkglib.DealerV2 // Adapters of the dealers, see dealerV2.
+	roundCounter     *dkgtypes.RoundCounter
 	dkgNumBlocks     int64
 	newDKGDealer     dkglib.DKGDealerConstructor
 	privValidator    alias.PrivValidator
+	misbehaviorSink  dkgtypes.MisbehaviorSink
+
+	blocksAhead  int64
+	agreements   map[int]*changeHeightAgreement
+	attestations map[int]*dkgtypes.RoundAttestation
+
+	lastHeight    int64
+	maxHeightSkew int64
+	roundStarts   map[int]map[string]*roundStart
+
+	pipelineLead   int64
+	roundEpochEnds map[int]int64 // Epoch boundaries at which the rounds' verifiers take over, when pipelining.
+
+	negotiateParams bool
+	roundParams     map[int]*roundParamsState
+
+	maxActiveRounds    int
+	evictionPolicy     EvictionPolicy
+	maxRoundAge        time.Duration
+	roundStartTimes    map[int]time.Time
+	lastEvictedRoundID int
+
+	maxChunkSize int
+	chunks       *dkgalias.ChunkBuffer
+
+	usageOptions []dkgtypes.UsageOption
+
+	externalParticipants []*dkgtypes.Participant
+
+	wal *wal.WAL
+
+	middlewares []dkglib.Middleware
+	profiler    *dkglib.Profiler
+	taps        dkglib.EventTaps
+	proofHook   dkgtypes.ProofHook
+
+	operationPolicy *dkgtypes.CoSignPolicy
+	eventPublisher  dkgtypes.EventPublisher
+
+	requireApproval    bool
+	approvalTimeout    time.Duration
+	staged             *dkgtypes.StagedVerifier // Next verifier awaiting approval.
+	activationDeferred bool                     // The change height passed while awaiting approval or the adoption gater.
+
+	roundGater     dkgtypes.RoundGater
+	roundDeferred  bool  // A due round start was vetoed by the gater.
+	roundDueHeight int64 // Height the last scheduled round was due at.
+	adoptionGater  dkgtypes.AdoptionGater
+
+	messageHandlers map[dkgalias.DKGDataType]MessageHandler // See SetMessageHandler.
+
+	metrics *metrics.Metrics
+	seen    *seenFilter // Nil unless echo suppression is enabled.
+
+	indexStore *dkgtypes.IndexStore
+
+	resumable  bool
+	replaying  bool                        // Resuming rounds, whose messages were sent before the upgrade.
+	roundSeeds map[int][]byte              // Seeds of the resumable rounds' dealers, see WithResumableRounds.
+	journals   map[int][]*dkgalias.DKGData // Verified messages of the resumable rounds, in order.
+
+	catchUpInterval time.Duration
+	transcripts     map[int]*roundTranscript // Verified messages of the active rounds, see WithCatchUp.
+
+	checkInvariants bool
 
-	Logger  log.Logger
-	evsw    events.EventSwitch
-	chainID string
+	blacklist      *dkgtypes.Blacklist
+	blacklistChain dkgtypes.BlacklistChain
+	roundLosers    map[int][]crypto.Address // Peers excluded by the dealers of unfinished rounds.
+
+	anchoring   bool
+	anchorChain dkgtypes.AnchorChain
+	anchor      *pendingAnchor // Round whose verifier awaits its anchor, see WithEpochAnchoring.
+
+	validators      *alias.ValidatorSet // Last validator set passed to CheckDKGTime, see TriggerRound.
+	minValidators   int                 // Smallest committee a round is started with.
+	roundThresholds map[int]int         // Thresholds of triggered rounds overriding the default.
+
+	paramsSource dkgtypes.ParamsSource
+	chainParams  *dkgtypes.DKGParams // Parameters read at the last round start, see WithParamsSource.
+
+	paused         bool
+	observedRounds map[int]bool // Rounds started or first seen while paused.
+
+	migrations map[string]*dkgtypes.ShareMigration // By the old and the new address of migrated participants.
+
+	forensics       bool
+	forensicsDir    string
+	roundErrors     map[int][]string
+	forensicBundles map[int]*dkgtypes.ForensicBundle
+
+	refresh             *pendingRefresh // Refreshed shares of the epoch in use, see ScheduleRefresh.
+	authorizedRefreshes map[int]bool    // Epochs whose refresh is authorized, see AuthorizeRefresh.
+
+	timingSamples []*dkgtypes.RoundInfo // Snapshots of the last finished rounds, see TimingSamples.
+
+	faultConfig *faultinject.Config // See WithFaultInjection.
+	faults      *faultinject.Injector
+	faultErr    error // Reported by Validate.
+	faultsMtx   sync.Mutex
+	delayed     []delayedMessage
+
+	Logger           logging.Logger
+	evsw             events.EventSwitch
+	firer            events.Fireable // Fires the events on evsw, see WithEventDispatcher.
+	chainID          string
+	signBytesVersion byte
+	signDomain       dkgalias.SignDomain // Of chainID and signBytesVersion.
+	signDomainErr    error               // Returned by Sign and reported by Validate.
+	keyPurpose       string              // See WithKeyPurpose.
 }
 
 var _ dkgtypes.DKG = &OffChainDKG{}
+var _ dkgtypes.Healther = &OffChainDKG{}
+var _ dkgtypes.AttestationQuerier = &OffChainDKG{}
+var _ dkgtypes.LoserReporter = &OffChainDKG{}
 
 func NewOffChainDKG(evsw events.EventSwitch, chainID string, options ...DKGOption) *OffChainDKG {
 	dkg := &OffChainDKG{
-		evsw:             evsw,
-		dkgMsgQueue:      make(chan *dkgtypes.DKGDataMessage, alias.MsgQueueSize),
-		dkgRoundToDealer: make(map[int]dkglib.Dealer),
-		newDKGDealer:     dkglib.NewDKGDealer,
-		dkgNumBlocks:     DefaultDKGNumBlocks,
-		chainID:          chainID,
+		evsw:               evsw,
+		dkgMsgQueue:        make(chan *dkgtypes.DKGDataMessage, alias.MsgQueueSize),
+		dkgRoundToDealer:   make(map[int]dkglib.Dealer),
+		roundDealers:       make(map[int]dkglib.DealerV2),
+		newDKGDealer:       dkglib.NewDKGDealer,
+		dkgNumBlocks:       DefaultDKGNumBlocks,
+		blocksAhead:        DefaultBlocksAhead,
+		misbehaviorSink:    dkgtypes.NopMisbehaviorSink{},
+		agreements:         make(map[int]*changeHeightAgreement),
+		attestations:       make(map[int]*dkgtypes.RoundAttestation),
+		roundStartTimes:    make(map[int]time.Time),
+		roundStarts:        make(map[int]map[string]*roundStart),
+		roundParams:        make(map[int]*roundParamsState),
+		roundEpochEnds:     make(map[int]int64),
+		roundLosers:        make(map[int][]crypto.Address),
+		roundThresholds:    make(map[int]int),
+		transcripts:        make(map[int]*roundTranscript),
+		roundSeeds:         make(map[int][]byte),
+		journals:           make(map[int][]*dkgalias.DKGData),
+		observedRounds:     make(map[int]bool),
+		migrations:         make(map[string]*dkgtypes.ShareMigration),
+		roundErrors:        make(map[int][]string),
+		forensicBundles:    make(map[int]*dkgtypes.ForensicBundle),
+		lastEvictedRoundID: -1,
+		chunks:             dkgalias.NewChunkBuffer(dkgalias.DefaultMaxReassembledSize, dkgalias.WithMaxChunkAge(chunkMaxAge)),
+		chainID:            chainID,
+		signBytesVersion:   dkgalias.SignBytesVersion,
+		roundCounter:       dkgtypes.NewRoundCounter(0),
+		verifierRoundID:    -1,
+		lastRoundResult:    dkgtypes.RoundResultNone,
+		metrics:            metrics.NopMetrics(),
 	}
 
 	for _, option := range options {
 		option(dkg)
 	}
 
+	if dkg.firer == nil {
+		dkg.firer = evsw
+	}
 	if dkg.dkgNumBlocks == 0 {
 		dkg.dkgNumBlocks = DefaultDKGNumBlocks // We do not want to panic if the value is not provided.
 	}
+	if dkg.blocksAhead <= 0 {
+		dkg.blocksAhead = DefaultBlocksAhead
+	}
+	dkg.setupFaults()
+	dkg.signDomain, dkg.signDomainErr = dkgalias.NewSignDomain(chainID, dkg.signBytesVersion)
+	if dkg.Logger != nil {
+		dkg.Logger = logging.WithCorrelation(dkg.Logger, dkgtypes.CorrelationKey, dkgtypes.RoundCorrelationID)
+	}
 
 	return dkg
 }
@@ -74,7 +237,192 @@ func WithDKGNumBlocks(numBlocks int64) DKGOption {
 	return func(d *OffChainDKG) { d.dkgNumBlocks = numBlocks }
 }
 
+// WithBlocksAhead sets the number of blocks between a successful round and the verifier swap.
+func WithBlocksAhead(blocksAhead int64) DKGOption {
+	return func(d *OffChainDKG) { d.blocksAhead = blocksAhead }
+}
+
+// NumBlocks returns the number of blocks between rounds in effect.
+func (m *OffChainDKG) NumBlocks() int64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.dkgNumBlocks
+}
+
+// BlocksAhead returns the number of blocks between a successful round and the
+// verifier swap in effect.
+func (m *OffChainDKG) BlocksAhead() int64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.blocksAhead
+}
+
+// WithMinValidators sets the smallest committee a round is started with,
+// dealer.MinParticipants by default; rounds of smaller committees are skipped.
+func WithMinValidators(minValidators int) DKGOption {
+	return func(d *OffChainDKG) { d.minValidators = minValidators }
+}
+
+// WithSignBytesVersion sets the layout of the bytes DKG messages are signed
+// over, alias.SignBytesVersion by default. Nodes only accept signatures made
+// with their own version, so a network upgrades all its nodes at once; use
+// alias.SignBytesV1 to stay compatible with nodes not separating domains.
+func WithSignBytesVersion(version byte) DKGOption {
+	return func(d *OffChainDKG) { d.signBytesVersion = version }
+}
+
+// EvictionPolicy defines which round is evicted when the number of active rounds exceeds the limit.
+type EvictionPolicy int
+
+const (
+	EvictLowestRoundID EvictionPolicy = iota // Evict the round with the lowest ID.
+	EvictOldestRound                         // Evict the round whose dealer was created first.
+)
+
+// WithMaxActiveRounds limits the number of dealers kept simultaneously; zero means no limit.
+func WithMaxActiveRounds(maxActiveRounds int) DKGOption {
+	return func(d *OffChainDKG) { d.maxActiveRounds = maxActiveRounds }
+}
+
+func WithEvictionPolicy(policy EvictionPolicy) DKGOption {
+	return func(d *OffChainDKG) { d.evictionPolicy = policy }
+}
+
+// WithMaxRoundAge evicts dealers that were created more than maxAge ago; zero means no limit.
+func WithMaxRoundAge(maxAge time.Duration) DKGOption {
+	return func(d *OffChainDKG) { d.maxRoundAge = maxAge }
+}
+
+// WithMaxChunkSize splits outgoing messages with Data larger than maxChunkSize into chunks;
+// zero disables chunking.
+func WithMaxChunkSize(maxChunkSize int) DKGOption {
+	return func(d *OffChainDKG) { d.maxChunkSize = maxChunkSize }
+}
+
+// WithMaxReassembledSize limits the size of a message reassembled from received chunks.
+func WithMaxReassembledSize(maxSize int) DKGOption {
+	return func(d *OffChainDKG) {
+		d.chunks = dkgalias.NewChunkBuffer(maxSize, dkgalias.WithMaxChunkAge(chunkMaxAge))
+	}
+}
+
+// WithVerifierUsage wraps every verifier produced by a round into a UsageVerifier
+// that accounts signature shares of that round's epoch.
+func WithVerifierUsage(options ...dkgtypes.UsageOption) DKGOption {
+	return func(d *OffChainDKG) { d.usageOptions = append(d.usageOptions, options...) }
+}
+
+// WithRoundCounter sets the counter issuing round IDs, e.g. to share it with OnChainDKG.
+func WithRoundCounter(counter *dkgtypes.RoundCounter) DKGOption {
+	return func(d *OffChainDKG) {
+		if counter == nil {
+			return
+		}
+		d.roundCounter = counter
+	}
+}
+
+// WithExternalParticipants adds non-validator participants to every round, so that
+// validators and external parties share the threshold key.
+func WithExternalParticipants(participants ...*dkgtypes.Participant) DKGOption {
+	return func(d *OffChainDKG) { d.externalParticipants = participants }
+}
+
+// WithMiddleware wraps the dealer handlers of incoming messages into the
+// middlewares, the first one being the outermost.
+func WithMiddleware(middlewares ...dkglib.Middleware) DKGOption {
+	return func(d *OffChainDKG) { d.middlewares = append(d.middlewares, middlewares...) }
+}
+
+// WithMetrics records the message latencies and liveness scores of the peers of every finished round.
+func WithMetrics(m *metrics.Metrics) DKGOption {
+	return func(d *OffChainDKG) { d.metrics = m }
+}
+
+// WithInvariantChecks validates the dealer invariants after every handled
+// message and panics with a dump of the dealer's state on violation. It is a
+// debugging aid, not meant for production.
+func WithInvariantChecks() DKGOption {
+	return func(d *OffChainDKG) { d.checkInvariants = true }
+}
+
+// dealerV2 returns the adapter of the round's dealer handling its messages.
+func (m *OffChainDKG) dealerV2(roundID int, dealer dkglib.Dealer) dkglib.DealerV2 {
+	adapter, ok := m.roundDealers[roundID]
+	if !ok || adapter.V1() != dealer {
+		adapter = dkglib.AdaptDealer(dealer, m.dealerMiddlewares(dealer)...)
+		m.roundDealers[roundID] = adapter
+	}
+	return adapter
+}
+
+// WithProfiling runs the dealer handlers under the profiler, see dealer.Profiler.
+func WithProfiling(profiler *dkglib.Profiler) DKGOption {
+	return func(d *OffChainDKG) { d.profiler = profiler }
+}
+
+// dealerMiddlewares returns the middlewares wrapping the dealer's handlers; the
+// profiler is the outermost one and the invariant checks the innermost one.
+func (m *OffChainDKG) dealerMiddlewares(dealer dkglib.Dealer) []dkglib.Middleware {
+	var middlewares []dkglib.Middleware
+	if m.profiler != nil {
+		middlewares = append(middlewares, m.profiler.Middleware(dealer))
+	}
+	middlewares = append(middlewares, m.middlewares...)
+	if m.checkInvariants {
+		middlewares = append(middlewares, dkglib.InvariantMiddleware(dealer, m.Logger))
+	}
+	return middlewares
+}
+
+// WithEventTaps sets the callbacks notified of the progress of every round.
+func WithEventTaps(taps dkglib.EventTaps) DKGOption {
+	return func(d *OffChainDKG) { d.taps = taps }
+}
+
+// WithProofHook sets the hook generating external proofs of every round, e.g.
+// a SNARK of correct dealing, from the dealer's commitment matrix after every
+// phase. The proofs are part of the round's snapshot and attestation.
+func WithProofHook(hook dkgtypes.ProofHook) DKGOption {
+	return func(d *OffChainDKG) { d.proofHook = hook }
+}
+
+// WithEventDispatcher fires the events of the DKG and its dealers through the
+// dispatcher, which must fire them on the DKG's event switch, so that slow
+// subscribers don't stall message handling.
+func WithEventDispatcher(dispatcher *eventbus.Dispatcher) DKGOption {
+	return func(d *OffChainDKG) { d.firer = dispatcher }
+}
+
+// WithEventPublisher publishes round and key change events to the publisher,
+// see the eventbus package.
+func WithEventPublisher(publisher dkgtypes.EventPublisher) DKGOption {
+	return func(d *OffChainDKG) { d.eventPublisher = publisher }
+}
+
+// WithWAL records every incoming and outgoing DKG message to the write-ahead log,
+// and tells it about the successful rounds, see wal.WithRetainRounds.
+func WithWAL(w *wal.WAL) DKGOption {
+	return func(d *OffChainDKG) { d.wal = w }
+}
+
+// WithMisbehaviorSink sets the sink receiving reports about misbehaving peers.
+func WithMisbehaviorSink(sink dkgtypes.MisbehaviorSink) DKGOption {
+	return func(d *OffChainDKG) {
+		if sink == nil {
+			return
+		}
+		d.misbehaviorSink = sink
+	}
+}
+
+// WithLogger logs to the Tendermint logger; see WithLogging for other loggers.
 func WithLogger(l log.Logger) DKGOption {
+	return func(d *OffChainDKG) { d.Logger = logging.NewTMLogger(l) }
+}
+
+// WithLogging logs to the logger, e.g. one of the logging package adapters.
+func WithLogging(l logging.Logger) DKGOption {
 	return func(d *OffChainDKG) { d.Logger = l }
 }
 
@@ -101,108 +449,654 @@ func (m *OffChainDKG) HandleOffChainShare(
 	validators *alias.ValidatorSet,
 	pubKey crypto.PubKey,
 ) (switchToOnChain bool) {
+	logger := m.Logger.With(logging.RoundKey, dkgMsg.Data.RoundID,
+		dkgtypes.MessageCorrelationKey, dkgtypes.MessageCorrelationID(dkgMsg.Data))
+	if m.seen != nil && m.seen.observe(dkgMsg.Data) {
+		logger.Debug("dkgState: dropping duplicate message", "type", dkgMsg.Data.Type)
+		return false
+	}
+	// Joining a round on a peer's message queries the chain like starting it,
+	// which must be done without holding the lock.
+	var votes []*dkgalias.DKGData
+	if m.joinsRound(dkgMsg.Data) {
+		startHeight := m.roundStartHeight(height)
+		m.applyChainParams(startHeight)
+		votes = m.blacklistVotes(dkgMsg.Data.RoundID, startHeight)
+	}
+
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
 	var msg = dkgMsg.Data
+	if msg.KeyPurpose != m.keyPurpose {
+		logger.Debug("dkgState: dropping message of another key", "key_purpose", msg.KeyPurpose)
+		return false
+	}
+	if m.observing(msg.RoundID) {
+		logger.Debug("dkgState: received message for observed round", "type", msg.Type)
+		return false
+	}
+	m.writeWAL(wal.EntryIncoming, msg)
+	if msg.Type == dkgalias.DKGMigration {
+		// Migrations refer to an epoch rather than a round in progress, so
+		// they carry their own proof instead of being verified by a dealer.
+		logger.Info("dkgState: received Migration message", "from", msg.GetAddrString())
+		if err := m.handleMigration(msg); err != nil {
+			logger.Error("dkgState: failed to handle migration", "error", err, "from", msg.GetAddrString())
+		}
+		return false
+	}
+	if handled, err := m.handleExternal(msg, validators); handled {
+		if err != nil {
+			logger.Info("dkgState: failed to handle message", "type", msg.Type, "from", msg.GetAddrString(), "error", err)
+		}
+		return false
+	}
 	dealer, ok := m.dkgRoundToDealer[msg.RoundID]
 	if !ok {
-		m.Logger.Debug("dkgState: dealer not found, creating a new dealer", "round_id", msg.RoundID)
-		dealer = m.newDKGDealer(validators, m.privValidator, m.sendSignedMessage, m.evsw, m.Logger, msg.RoundID)
-		m.dkgRoundToDealer[msg.RoundID] = dealer
+		if msg.RoundID <= m.lastEvictedRoundID {
+			logger.Debug("dkgState: received message for evicted round")
+			return false
+		}
+		if msg.Type == dkgalias.DKGAbort {
+			logger.Debug("dkgState: received abort for unknown round")
+			return false
+		}
+		logger.Debug("dkgState: dealer not found, creating a new dealer")
+		var params dkgtypes.TriggerParams
+		participants, err := m.roundParticipants(msg.RoundID, validators, &params, votes)
+		if err == nil {
+			if _, own := participants.GetByAddress(m.privValidator.GetPubKey().Address()); own == nil {
+				err = fmt.Errorf("node is blacklisted or outside the committee")
+			}
+		}
+		if err == nil {
+			err = m.persistIndices(msg.RoundID, participants)
+		}
+		if err != nil {
+			logger.Error("dkgState: not joining round", "error", err)
+			m.dkgRoundToDealer[msg.RoundID] = nil
+			return false
+		}
+		dealer = m.newDealer(participants, msg.RoundID)
+		if params.Threshold > 0 {
+			dealer.SetThreshold(params.Threshold)
+			m.roundThresholds[msg.RoundID] = params.Threshold
+		}
+		m.addDealer(msg.RoundID, dealer)
+		// Report our start of the round joined through a peer's message too,
+		// or the others never see all the round starts to compare.
+		if err := m.sendRoundStart(msg.RoundID, participants, m.roundStartHeight(height)); err != nil {
+			logger.Error("dkgState: failed to send round start", "error", err)
+		}
 		if err := dealer.Start(); err != nil {
-			m.Logger.Debug("dealer start failed, panic", "error", err.Error())
-			panic(fmt.Sprintf("failed to start a dealer (round %d): %v", m.dkgRoundID, err))
+			logger.Debug("dealer start failed, panic", "error", err.Error())
+			panic(fmt.Sprintf("failed to start a dealer (round %d): %v", msg.RoundID, err))
 		}
 	}
 	if dealer == nil {
-		m.Logger.Debug("dkgState: received message for inactive round:", "round", msg.RoundID)
+		logger.Debug("dkgState: received message for inactive round:")
 		return false
 	}
-	m.Logger.Debug("dkgState: received message with signature:", "signature", hex.EncodeToString(dkgMsg.Data.Signature))
+	// Use the committee of the round instead of the current validator set.
+	participants := dealer.GetState().GetParticipants()
+	if participants == nil {
+		participants = m.newParticipantSet(validators)
+	}
+	logger.Debug("dkgState: received message with signature:", "signature", hex.EncodeToString(dkgMsg.Data.Signature))
 
 	if err := dealer.VerifyMessage(*dkgMsg); err != nil {
-		m.Logger.Info("DKG: can't verify message:", "error", err.Error())
+		logger.Info("DKG: can't verify message:", "error", err.Error())
+		m.noteRoundError(msg.RoundID, fmt.Errorf("can't verify %v message from %s: %v", msg.Type, msg.GetAddrString(), err))
+		m.misbehaviorSink.ReportMisbehavior(&dkgtypes.MisbehaviorReport{
+			Type:     dkgtypes.MisbehaviorInvalidSignature,
+			Addr:     crypto.Address(msg.Addr),
+			RoundID:  msg.RoundID,
+			DataType: msg.Type,
+			Err:      err,
+			Message:  msg,
+		})
 		return false
 	}
-	m.Logger.Info("DKG: message verified")
+	logger.Info("DKG: message verified")
+	m.lastMessageTime = time.Now()
 
 	fromAddr := crypto.Address(msg.Addr).String()
 
-	var err error
-	switch msg.Type {
-	case dkgalias.DKGPubKey:
-		m.Logger.Info("dkgState: received PubKey message", "from", fromAddr, "own", m.privValidator.GetPubKey().Address())
-		err = dealer.HandleDKGPubKey(msg)
-	case dkgalias.DKGDeal:
-		m.Logger.Info("dkgState: received Deal message", "from", fromAddr)
-		err = dealer.HandleDKGDeal(msg)
-	case dkgalias.DKGResponse:
-		m.Logger.Info("dkgState: received Response message", "from", fromAddr)
-		err = dealer.HandleDKGResponse(msg)
-	case dkgalias.DKGJustification:
-		m.Logger.Info("dkgState: received Justification message", "from", fromAddr)
-		err = dealer.HandleDKGJustification(msg)
-	case dkgalias.DKGCommits:
-		m.Logger.Info("dkgState: received Commit message", "from", fromAddr)
-		err = dealer.HandleDKGCommit(msg)
-	case dkgalias.DKGComplaint:
-		m.Logger.Info("dkgState: received Complaint message", "from", fromAddr)
-		err = dealer.HandleDKGComplaint(msg)
-	case dkgalias.DKGReconstructCommit:
-		m.Logger.Info("dkgState: received ReconstructCommit message", "from", fromAddr)
-		err = dealer.HandleDKGReconstructCommit(msg)
+	if msg.Type == dkgalias.DKGRequestMissing {
+		if err := m.serveMissing(msg); err != nil {
+			logger.Info("dkgState: failed to serve missing messages", "error", err, "from", fromAddr)
+		}
+		return false
 	}
+	m.recordTranscript(msg)
+	m.recordJournal(msg)
+
+	msg, err := m.chunks.Add(msg)
 	if err != nil {
+		logger.Info("DKG: can't reassemble message:", "error", err.Error(), "from", fromAddr)
+		m.noteRoundError(dkgMsg.Data.RoundID, fmt.Errorf("can't reassemble message from %s: %v", fromAddr, err))
+		m.misbehaviorSink.ReportMisbehavior(&dkgtypes.MisbehaviorReport{
+			Type:     dkgtypes.MisbehaviorMalformedMessage,
+			Addr:     crypto.Address(dkgMsg.Data.Addr),
+			RoundID:  dkgMsg.Data.RoundID,
+			DataType: dkgMsg.Data.Type,
+			Err:      err,
+			Message:  dkgMsg.Data,
+		})
+		return false
+	}
+	if msg == nil {
+		logger.Debug("dkgState: waiting for more chunks", "from", fromAddr)
+		return false
+	}
+
+	if msg.Type == dkgalias.DKGChangeHeight {
+		logger.Info("dkgState: received ChangeHeight message", "from", fromAddr)
+		if err := m.handleChangeHeight(msg, participants); err != nil {
+			logger.Error("dkgState: failed to handle change height", "error", err, "from", fromAddr)
+			m.noteRoundError(msg.RoundID, fmt.Errorf("failed to handle change height from %s: %v", fromAddr, err))
+		}
+		return false
+	}
+
+	if msg.Type == dkgalias.DKGRoundStart {
+		logger.Info("dkgState: received RoundStart message", "from", fromAddr)
+		if err := m.handleRoundStart(msg, participants); err != nil {
+			logger.Error("dkgState: failed to handle round start", "error", err, "from", fromAddr)
+			m.noteRoundError(msg.RoundID, fmt.Errorf("failed to handle round start from %s: %v", fromAddr, err))
+		}
+		return false
+	}
+
+	if msg.Type == dkgalias.DKGRoundParams {
+		logger.Info("dkgState: received RoundParams message", "from", fromAddr)
+		held, err := m.handleRoundParams(msg, participants)
+		if err != nil {
+			logger.Error("dkgState: failed to handle round params", "error", err, "from", fromAddr)
+			m.noteRoundError(msg.RoundID, fmt.Errorf("failed to handle round params from %s: %v", fromAddr, err))
+			return false
+		}
+		for _, heldMsg := range held {
+			if m.handleDealerMessage(dealer, heldMsg, height) {
+				switchToOnChain = true
+			}
+			if m.dkgRoundToDealer[msg.RoundID] == nil {
+				break
+			}
+		}
+		return switchToOnChain
+	}
+
+	if m.holdForParams(msg) {
+		logger.Debug("dkgState: holding message until round params are agreed", "type", msg.Type, "from", fromAddr)
+		return false
+	}
+
+	return m.handleDealerMessage(dealer, msg, height)
+}
+
+// handleDealerMessage passes the message to the dealer and proposes a change
+// height once the dealer's verifier is ready.
+func (m *OffChainDKG) handleDealerMessage(dealer dkglib.Dealer, msg *dkgalias.DKGData, height int64) (switchToOnChain bool) {
+	fromAddr := msg.GetAddrString()
+
+	result, err := m.dealerV2(msg.RoundID, dealer).Handle(context.Background(), msg)
+	switch result {
+	case dkglib.HandleAccepted:
+		m.Logger.Info("dkgState: received message", "type", msg.Type, "from", fromAddr)
+		m.noteAccepted(msg.RoundID)
+	case dkglib.HandleDuplicate:
+		m.Logger.Debug("dkgState: dropping message handled before", "type", msg.Type, "from", fromAddr)
+		return false
+	case dkglib.HandleIgnored:
+		m.Logger.Info("dkgState: message ignored by dealer", "type", msg.Type, "from", fromAddr, "reason", err)
+	case dkglib.HandleFatal:
 		m.Logger.Error("dkgState: failed to handle message", "error", err, "type", msg.Type)
-		m.dkgRoundToDealer[msg.RoundID] = nil
+		m.failRound(msg.RoundID, fmt.Errorf("failed to handle %v message from %s: %v", msg.Type, fromAddr, err))
 		return false
 	}
 
 	verifier, err := dealer.GetVerifier()
 	if err == dkgtypes.ErrDKGVerifierNotReady {
-		m.Logger.Debug("dkgState: verifier not ready")
+		progress := dealer.Progress()
+		m.metrics.ObserveProgress(progress)
+		if waiting := dkgtypes.Waiting(progress); waiting != nil {
+			m.Logger.Debug("dkgState: verifier not ready", "round_id", msg.RoundID, "progress", waiting.String())
+		}
+		if ok, missing := dealer.CanComplete(); !ok {
+			m.failRound(msg.RoundID, fmt.Errorf("too few participants left to complete the round (%d awaited)", missing))
+		}
 		return false
 	}
 	if err != nil {
 		m.Logger.Debug("dkgState: verifier should be ready, but it's not ready:", "error", err)
-		m.dkgRoundToDealer[msg.RoundID] = nil
+		m.failRound(msg.RoundID, fmt.Errorf("failed to get verifier: %v", err))
 		return true
 	}
+	agreement := m.getAgreement(msg.RoundID)
+	if agreement.verifier != nil {
+		// We have already proposed a change height for this round.
+		return false
+	}
 	m.Logger.Info("dkgState: verifier is ready, killing older rounds")
 	for roundID := range m.dkgRoundToDealer {
 		if roundID < msg.RoundID {
-			m.dkgRoundToDealer[msg.RoundID] = nil
+			// Late messages of the killed rounds are dropped as evicted ones.
+			delete(m.dkgRoundToDealer, roundID)
+			delete(m.roundStartTimes, roundID)
+			if roundID > m.lastEvictedRoundID {
+				m.lastEvictedRoundID = roundID
+			}
+			delete(m.agreements, roundID)
+			delete(m.roundParams, roundID)
+			delete(m.roundThresholds, roundID)
+			delete(m.roundEpochEnds, roundID)
+			delete(m.roundDealers, roundID)
+			delete(m.transcripts, roundID)
+			m.forgetJournal(roundID)
 		}
 	}
-	m.nextVerifier = verifier
-	m.changeHeight = (height + BlocksAhead) - ((height + BlocksAhead) % 5)
-	m.evsw.FireEvent(dkgtypes.EventDKGSuccessful, m.changeHeight)
+	m.chunks.DropRoundsBefore(msg.RoundID)
+	agreement.verifier = verifier
+	var keyHash []byte
+	if snapshot, err := dkgtypes.NewVerifierSnapshot(verifier, msg.RoundID); err == nil {
+		agreement.snapshot = snapshot
+		keyHash = dkgtypes.MasterPubKeyHash(snapshot)
+	} else {
+		m.Logger.Debug("dkgState: round can't be attested", "round_id", msg.RoundID, "error", err)
+	}
+
+	changeHeight := m.proposedChangeHeight(msg.RoundID, height)
+	m.Logger.Info("dkgState: proposing change height", "round_id", msg.RoundID, "change_height", changeHeight)
+	if err := m.sendSignedMessage([]*dkgalias.DKGData{{
+		Type:    dkgalias.DKGChangeHeight,
+		RoundID: msg.RoundID,
+		Addr:    m.privValidator.GetPubKey().Address().Bytes(),
+		Data:    dkgtypes.EncodeConfirmation(changeHeight, keyHash),
+	}}); err != nil {
+		m.Logger.Error("dkgState: failed to send change height", "error", err)
+		return false
+	}
 
 	m.Logger.Info("handle off-chain share success")
 
 	return false
 }
 
-func (m *OffChainDKG) startRound(validators *alias.ValidatorSet) error {
-	m.dkgRoundID++
-	m.Logger.Info("OffChainDKG: starting round", "round_id", m.dkgRoundID)
-	_, ok := m.dkgRoundToDealer[m.dkgRoundID]
+// changeHeightAgreement collects the change heights proposed by the participants
+// of a successful round. The verifier is swapped once a quorum proposes the same
+// height, or every participant has proposed one.
+type changeHeightAgreement struct {
+	verifier      dkgtypes.Verifier
+	snapshot      *dkgtypes.VerifierSnapshot // Nil if the verifier's key can't be exported.
+	heights       map[string]int64
+	confirmations map[string]*dkgalias.DKGData
+	scheduled     bool
+	changeHeight  int64 // Agreed change height, set once scheduled.
+	deadline      int64 // Height the round fails at if the proposals don't agree.
+	expired       bool
+}
+
+func (m *OffChainDKG) getAgreement(roundID int) *changeHeightAgreement {
+	agreement, ok := m.agreements[roundID]
 	if !ok {
-		dealer := m.newDKGDealer(validators, m.privValidator, m.sendSignedMessage, m.evsw, m.Logger, m.dkgRoundID)
-		m.dkgRoundToDealer[m.dkgRoundID] = dealer
-		m.evsw.FireEvent(dkgtypes.EventDKGStart, m.dkgRoundID)
-		return dealer.Start()
+		agreement = &changeHeightAgreement{
+			heights:       make(map[string]int64),
+			confirmations: make(map[string]*dkgalias.DKGData),
+		}
+		m.agreements[roundID] = agreement
 	}
+	return agreement
+}
 
+// checkChangeHeight rejects the change heights that already passed or lie
+// further ahead than the participants propose, so a single participant can't
+// postpone the key change.
+func (m *OffChainDKG) checkChangeHeight(roundID int, height int64) error {
+	if end, ok := m.roundEpochEnds[roundID]; ok && height == end {
+		return nil
+	}
+	if height <= m.lastHeight {
+		return fmt.Errorf("change height %d is not above the current height %d", height, m.lastHeight)
+	}
+	// Peers may be a few blocks ahead of us.
+	if limit := m.lastHeight + m.blocksAhead + changeHeightAlign; height > limit {
+		return fmt.Errorf("change height %d is beyond the limit %d", height, limit)
+	}
 	return nil
 }
 
+func (m *OffChainDKG) handleChangeHeight(msg *dkgalias.DKGData, participants *dkgtypes.ParticipantSet) error {
+	height, _, err := dkgtypes.DecodeConfirmation(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	agreement := m.getAgreement(msg.RoundID)
+	if agreement.expired {
+		return nil
+	}
+	// Once the change height is agreed, late proposals only complete the attestation.
+	if !agreement.scheduled {
+		if err := m.checkChangeHeight(msg.RoundID, height); err != nil {
+			return err
+		}
+	}
+	sender := m.participantAddr(msg.Addr).String()
+	if proposed, ok := agreement.heights[sender]; ok && proposed != height {
+		err := fmt.Errorf("conflicting change heights %d and %d", proposed, height)
+		m.misbehaviorSink.ReportMisbehavior(&dkgtypes.MisbehaviorReport{
+			Type:     dkgtypes.MisbehaviorEquivocation,
+			Addr:     crypto.Address(msg.Addr),
+			RoundID:  msg.RoundID,
+			DataType: msg.Type,
+			Err:      err,
+			Evidence: &dkgtypes.EquivocationEvidence{First: agreement.confirmations[sender], Second: msg},
+		})
+		return err
+	}
+	agreement.heights[sender] = height
+	agreement.confirmations[sender] = msg
+	if agreement.deadline == 0 {
+		agreement.deadline = m.lastHeight + m.blocksAhead
+	}
+
+	if agreement.scheduled {
+		// The round was scheduled before all participants confirmed it.
+		if _, attested := m.attestations[msg.RoundID]; !attested && m.storeAttestation(msg.RoundID, agreement, participants) {
+			m.publishAnchor(msg.RoundID)
+		}
+		return nil
+	}
+	if agreement.verifier == nil {
+		return nil
+	}
+	changeHeight, ok := dkgtypes.AgreedChangeHeight(agreement.heights, participants.Size())
+	if !ok {
+		return nil
+	}
+	agreement.scheduled, agreement.changeHeight = true, changeHeight
+
+	m.Logger.Info("dkgState: participants agreed on change height", "round_id", msg.RoundID, "change_height", changeHeight)
+	m.nextVerifier = agreement.verifier
+	m.nextVerifierRoundID = msg.RoundID
+	m.changeHeight = changeHeight
+	m.setRoundResult(msg.RoundID, dkgtypes.RoundResultSuccess)
+	m.storeAttestation(msg.RoundID, agreement, participants)
+	m.anchorRound(msg.RoundID, participants, agreement.snapshot)
+	m.stageVerifier(msg.RoundID, changeHeight, agreement.snapshot)
+	m.firer.FireEvent(dkgtypes.EventDKGSuccessful, dkgtypes.DKGSuccessfulEvent{RoundID: msg.RoundID, CorrelationID: dkgtypes.RoundCorrelationID(msg.RoundID), ChangeHeight: changeHeight})
+	m.publishEvent(dkgtypes.EventDKGSuccessful, msg.RoundID, msg.RoundID, changeHeight)
+
+	return nil
+}
+
+// expireAgreements fails the rounds whose participants didn't agree on a change
+// height in time, so a silent participant can't stall the key change.
+func (m *OffChainDKG) expireAgreements(height int64) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	for roundID, agreement := range m.agreements {
+		if agreement.scheduled || agreement.expired || agreement.deadline == 0 || height <= agreement.deadline {
+			continue
+		}
+		agreement.expired = true
+		m.failRound(roundID, fmt.Errorf("participants didn't agree on a change height by height %d (%d proposals)",
+			agreement.deadline, len(agreement.heights)))
+	}
+}
+
+func (m *OffChainDKG) startRound(validators *alias.ValidatorSet) error {
+	_, err := m.startRoundWith(validators, dkgtypes.TriggerParams{})
+	return err
+}
+
+// startRoundWith starts the next round with the params, see TriggerRound.
+func (m *OffChainDKG) startRoundWith(validators *alias.ValidatorSet, params dkgtypes.TriggerParams) (int, error) {
+	if err := m.gateRound(); err != nil {
+		return 0, err
+	}
+	startHeight := params.Height
+	if startHeight == 0 {
+		startHeight = m.lastHeight
+	}
+	m.applyChainParams(startHeight)
+	roundID, err := m.roundCounter.Next()
+	if err != nil {
+		return 0, fmt.Errorf("failed to issue round ID: %v", err)
+	}
+	m.Logger.Info("OffChainDKG: starting round", "round_id", roundID)
+	votes := m.blacklistVotes(roundID, startHeight)
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.observing(roundID) {
+		return roundID, nil
+	}
+	participants, err := m.roundParticipants(roundID, validators, &params, votes)
+	if err != nil {
+		return 0, err
+	}
+	if dealer, ok := m.dkgRoundToDealer[roundID]; ok {
+		// The round was started on a peer's message.
+		if dealer == nil {
+			return roundID, nil
+		}
+		if params.Threshold > 0 {
+			dealer.SetThreshold(params.Threshold)
+			m.roundThresholds[roundID] = params.Threshold
+		}
+		return roundID, checkTriggered(roundID, dealer.GetState().GetParticipants(), participants)
+	}
+
+	if _, own := participants.GetByAddress(m.privValidator.GetPubKey().Address()); own == nil {
+		m.Logger.Error("OffChainDKG: node is blacklisted or outside the committee, not participating in the round", "round_id", roundID)
+		return roundID, nil
+	}
+	if err := m.persistIndices(roundID, participants); err != nil {
+		return 0, err
+	}
+	dealer := m.newDealer(participants, roundID)
+	if params.Threshold > 0 {
+		dealer.SetThreshold(params.Threshold)
+		m.roundThresholds[roundID] = params.Threshold
+	}
+	m.addDealer(roundID, dealer)
+	m.firer.FireEvent(dkgtypes.EventDKGStart, dkgtypes.DKGStartEvent{RoundID: roundID, CorrelationID: dkgtypes.RoundCorrelationID(roundID)})
+	m.publishEvent(dkgtypes.EventDKGStart, roundID, m.verifierRoundID, m.lastHeight)
+	if err := m.sendRoundStart(roundID, participants, m.lastHeight); err != nil {
+		return 0, fmt.Errorf("failed to send round start: %v", err)
+	}
+	if err := m.sendRoundParams(roundID, participants); err != nil {
+		return 0, fmt.Errorf("failed to send round params: %v", err)
+	}
+	return roundID, dealer.Start()
+}
+
+func (m *OffChainDKG) ExternalParticipants() []*dkgtypes.Participant {
+	return m.externalParticipants
+}
+
+func (m *OffChainDKG) MinValidators() int {
+	return m.minValidators
+}
+
+func (m *OffChainDKG) Middlewares() []dkglib.Middleware {
+	return m.middlewares
+}
+
+func (m *OffChainDKG) EventTaps() dkglib.EventTaps {
+	return m.taps
+}
+
+func (m *OffChainDKG) ProofHook() dkgtypes.ProofHook {
+	return m.proofHook
+}
+
+func (m *OffChainDKG) newParticipantSet(validators *alias.ValidatorSet) *dkgtypes.ParticipantSet {
+	return dkgtypes.NewParticipantSet(validators, m.externalParticipants)
+}
+
+func (m *OffChainDKG) newDealer(participants *dkgtypes.ParticipantSet, roundID int) dkglib.Dealer {
+	dealer := m.newDKGDealer(participants, m.privValidator, m.sendSignedMessage, m.firer, m.Logger, roundID)
+	dealer.SetMisbehaviorSink(m.misbehaviorSink)
+	dealer.SetSignDomain(m.signDomain)
+	dealer.SetHeight(m.lastHeight)
+	dealer.SetEventTaps(m.dealerTaps(roundID))
+	if m.proofHook != nil {
+		dealer.SetProofHook(m.proofHook)
+	}
+	seed := m.seedDealer(roundID, dealer)
+	for addr, migration := range m.migrations {
+		if addr != migration.OldAddr().String() {
+			continue
+		}
+		if _, participant := participants.GetByAddress(migration.OldAddr()); participant != nil {
+			if err := dealer.MigrateParticipant(migration); err != nil {
+				m.Logger.Error("dkgState: failed to migrate participant", "round_id", roundID, "error", err)
+			}
+		}
+	}
+	if m.wal != nil && !m.replaying {
+		if err := m.wal.WriteRoundStart(roundID, participants, seed); err != nil {
+			m.Logger.Error("dkgState: failed to write WAL", "error", err)
+		}
+	}
+	return dealer
+}
+
+func (m *OffChainDKG) writeWAL(kind wal.EntryKind, msg *dkgalias.DKGData) {
+	if m.wal == nil || m.replaying {
+		return
+	}
+	if err := m.wal.WriteMessage(kind, msg); err != nil {
+		m.Logger.Error("dkgState: failed to write WAL", "error", err)
+	}
+}
+
+func (m *OffChainDKG) addDealer(roundID int, dealer dkglib.Dealer) {
+	m.evictRounds()
+	m.dkgRoundToDealer[roundID] = dealer
+	m.roundStartTimes[roundID] = time.Now()
+	for observedID := range m.observedRounds {
+		if observedID < roundID {
+			delete(m.observedRounds, observedID)
+		}
+	}
+	if m.pipelining() {
+		m.roundEpochEnds[roundID] = m.epochEnd(m.lastHeight)
+	}
+	if roundID >= m.lastRoundID {
+		m.lastRoundID, m.lastRoundResult = roundID, dkgtypes.RoundResultInProgress
+	}
+}
+
+func (m *OffChainDKG) setRoundResult(roundID int, result dkgtypes.RoundResult) {
+	if roundID == m.lastRoundID {
+		m.lastRoundResult = result
+	}
+	if dealer := m.dkgRoundToDealer[roundID]; dealer != nil {
+		info := dealer.Snapshot()
+		info.KeyPurpose = m.keyPurpose
+		info.Result = result
+		m.metrics.ObserveRound(info)
+		m.sampleTimings(info)
+	}
+	m.voteBlacklist(roundID)
+	if result == dkgtypes.RoundResultSuccess {
+		delete(m.roundErrors, roundID)
+		if m.wal != nil {
+			if err := m.wal.RoundCompleted(roundID); err != nil {
+				m.Logger.Error("dkgState: failed to prune WAL", "error", err)
+			}
+		}
+	}
+	if result == dkgtypes.RoundResultFailed {
+		m.recordForensics(roundID)
+		m.firer.FireEvent(dkgtypes.EventDKGFailed, dkgtypes.DKGFailedEvent{RoundID: roundID, CorrelationID: dkgtypes.RoundCorrelationID(roundID)})
+		m.publishEvent(dkgtypes.EventDKGFailed, roundID, m.verifierRoundID, m.lastHeight)
+	}
+}
+
+func (m *OffChainDKG) publishEvent(eventType string, roundID, epoch int, height int64) {
+	if m.eventPublisher == nil {
+		return
+	}
+	event := dkgtypes.DKGEvent{Type: eventType, RoundID: roundID, CorrelationID: dkgtypes.RoundCorrelationID(roundID), Epoch: epoch, Height: height, KeyPurpose: m.keyPurpose}
+	if err := m.eventPublisher.PublishDKGEvent(event); err != nil {
+		m.Logger.Error("dkgState: failed to publish event", "type", eventType, "round_id", roundID, "error", err)
+	}
+}
+
+// evictRounds frees space for a new dealer according to the configured limits.
+func (m *OffChainDKG) evictRounds() {
+	if m.maxRoundAge > 0 {
+		for roundID, started := range m.roundStartTimes {
+			if time.Since(started) > m.maxRoundAge {
+				m.evictRound(roundID)
+			}
+		}
+	}
+	if m.maxActiveRounds <= 0 {
+		return
+	}
+	for len(m.dkgRoundToDealer) >= m.maxActiveRounds {
+		m.evictRound(m.roundToEvict())
+	}
+}
+
+func (m *OffChainDKG) roundToEvict() int {
+	var (
+		evictID = -1
+		evictAt time.Time
+	)
+	for roundID := range m.dkgRoundToDealer {
+		started := m.roundStartTimes[roundID]
+		switch {
+		case evictID == -1:
+		case m.evictionPolicy == EvictOldestRound && started.Before(evictAt):
+		case m.evictionPolicy == EvictLowestRoundID && roundID < evictID:
+		default:
+			continue
+		}
+		evictID, evictAt = roundID, started
+	}
+	return evictID
+}
+
+func (m *OffChainDKG) evictRound(roundID int) {
+	m.Logger.Info("dkgState: evicting round", "round_id", roundID)
+	delete(m.dkgRoundToDealer, roundID)
+	delete(m.roundStartTimes, roundID)
+	delete(m.agreements, roundID)
+	delete(m.roundStarts, roundID)
+	delete(m.roundLosers, roundID)
+	delete(m.roundParams, roundID)
+	delete(m.roundThresholds, roundID)
+	delete(m.roundEpochEnds, roundID)
+	delete(m.roundErrors, roundID)
+	delete(m.roundDealers, roundID)
+	delete(m.transcripts, roundID)
+	m.forgetJournal(roundID)
+	m.chunks.DropRound(roundID)
+	if roundID > m.lastEvictedRoundID {
+		m.lastEvictedRoundID = roundID
+	}
+	m.firer.FireEvent(dkgtypes.EventDKGRoundEvicted, dkgtypes.DKGRoundEvictedEvent{RoundID: roundID, CorrelationID: dkgtypes.RoundCorrelationID(roundID)})
+}
+
 func (m *OffChainDKG) sendDKGMessage(msg *dkgalias.DKGData) {
 	// Broadcast to peers. This will not lead to processing the message
 	// on the sending node, we need to send it manually (see below).
-	m.evsw.FireEvent(dkgtypes.EventDKGData, msg)
-	mi := &dkgtypes.DKGDataMessage{msg}
+	m.firer.FireEvent(dkgtypes.EventDKGData, msg)
+	m.queueDKGMessage(msg)
+}
+
+// queueDKGMessage passes the node's own message to the node.
+func (m *OffChainDKG) queueDKGMessage(msg *dkgalias.DKGData) {
+	mi := &dkgtypes.DKGDataMessage{Data: msg}
 	select {
 	case m.dkgMsgQueue <- mi:
 	default:
@@ -217,13 +1111,22 @@ func (m *OffChainDKG) sendSignedMessage(data []*dkgalias.DKGData) error {
 	}
 
 	for _, v := range data {
-		item := v
-		if err := m.Sign(item); err != nil {
-			m.Logger.Debug("Off-chain DKG: failed to sign data", "error", err)
-			return err
+		fault := m.injectFault(v)
+		for _, item := range dkgalias.SplitDKGData(v, m.maxChunkSize) {
+			if err := m.Sign(item); err != nil {
+				m.Logger.Debug("Off-chain DKG: failed to sign data", "error", err)
+				return err
+			}
+			m.Logger.Info("DKG: msg signed with signature", "signature", hex.EncodeToString(item.Signature),
+				"type", item.Type, logging.RoundKey, item.RoundID, dkgtypes.MessageCorrelationKey, dkgtypes.MessageCorrelationID(item))
+			if m.replaying {
+				// The peers got it before the upgrade, the node may not have.
+				m.queueDKGMessage(item)
+				continue
+			}
+			m.writeWAL(wal.EntryOutgoing, item)
+			m.sendWithFault(item, fault)
 		}
-		m.Logger.Info("DKG: msg signed with signature", "signature", hex.EncodeToString(item.Signature))
-		m.sendDKGMessage(item)
 	}
 
 	return nil
@@ -231,30 +1134,106 @@ func (m *OffChainDKG) sendSignedMessage(data []*dkgalias.DKGData) error {
 
 // Sign sign message by dealer's secret key
 func (m *OffChainDKG) Sign(data *dkgalias.DKGData) error {
-	if err := m.privValidator.SignData(m.chainID, data); err != nil {
+	if m.signDomainErr != nil {
+		return m.signDomainErr
+	}
+	data.KeyPurpose = m.keyPurpose
+	if err := m.privValidator.SignData(m.chainID, m.signDomain.Signer(data)); err != nil {
 		return fmt.Errorf("failed to sign data: %v", err)
 	}
 	return nil
 }
 
 func (m *OffChainDKG) CheckDKGTime(height int64, validators *alias.ValidatorSet) {
+	m.mtx.Lock()
+	if height > m.lastHeight {
+		m.lastHeight = height
+	}
+	m.validators = validators
+	if height != -1 {
+		for _, dealer := range m.dkgRoundToDealer {
+			if dealer != nil {
+				dealer.SetHeight(height)
+			}
+		}
+	}
+	m.mtx.Unlock()
+	m.releaseDelayed(height)
+	if height != -1 {
+		m.expireAgreements(height)
+	}
+
 	if (height == -1) && m.nextVerifier == nil {
 		return
 	}
 
-	if (height == -1) || m.changeHeight == height {
+	due := (height == -1) || m.changeHeight == height || (m.activationDeferred && height > m.changeHeight)
+	if m.anchoring && height != -1 {
+		due = m.anchoredActivation(height)
+	}
+	if due && height != -1 && !m.activationAllowed() {
+		if !m.activationDeferred {
+			m.Logger.Info("dkgState: verifier awaits operator approval", "change_height", m.changeHeight)
+		}
+		m.activationDeferred, due = true, false
+	}
+	if due && height != -1 {
+		due = m.gateAdoption(height)
+	}
+	if due {
+		m.activationDeferred = false
 		m.Logger.Info("dkgState: time to update verifier", m.changeHeight, height)
+		m.mtx.Lock()
 		m.verifier, m.nextVerifier = m.nextVerifier, nil
+		m.verifierRoundID = m.nextVerifierRoundID
+		m.instrumentVerifier(m.verifier, m.verifierRoundID)
+		if m.verifier != nil && m.usageOptions != nil {
+			m.verifier = dkgtypes.NewUsageVerifier(m.verifier, m.verifierRoundID, m.Logger, m.usageOptions...)
+		}
 		m.changeHeight = 0
-		m.evsw.FireEvent(dkgtypes.EventDKGKeyChange, height)
+		event := dkgtypes.DKGKeyChangeEvent{Height: height, Epoch: m.verifierRoundID}
+		if m.verifier != nil {
+			event.GroupKey, _ = dkgtypes.NewVerifierSnapshot(m.verifier, m.verifierRoundID)
+		}
+		m.mtx.Unlock()
+		m.firer.FireEvent(dkgtypes.EventDKGKeyChange, event)
+		m.publishEvent(dkgtypes.EventDKGKeyChange, event.Epoch, event.Epoch, height)
+	}
+	if height != -1 {
+		m.mtx.Lock()
+		m.applyRefresh(height)
+		m.mtx.Unlock()
 	}
 
-	if height > 1 && height%m.dkgNumBlocks == 0 {
-		if err := m.startRound(validators); err != nil {
-			m.Logger.Debug("failed to start a dealer", "round", m.dkgRoundID, "error", err)
-			panic(fmt.Sprintf("failed to start a dealer (round %d): %v", m.dkgRoundID, err))
+	if m.roundStartDue(height) {
+		m.roundDueHeight = height
+	}
+	if m.roundStartDue(height) || (m.roundDeferred && height != -1) {
+		// A deferred round keeps the height it was due at, which all nodes share.
+		_, err := m.startRoundWith(validators, dkgtypes.TriggerParams{Height: m.roundDueHeight})
+		_, vetoed := err.(*dkgtypes.RoundVetoError)
+		if vetoed && !m.roundDeferred {
+			m.Logger.Info("dkgState: round start delayed", "error", err)
+		}
+		m.roundDeferred = vetoed
+		if skipRound(err) {
+			m.Logger.Error("dkgState: skipping round", "round_id", m.roundCounter.Current(), "error", err)
+		} else if err != nil && !vetoed {
+			m.Logger.Debug("failed to start a dealer", "round_id", m.roundCounter.Current(), "error", err)
+			panic(fmt.Sprintf("failed to start a dealer (round %d): %v", m.roundCounter.Current(), err))
 		}
 	}
+	if m.catchUpInterval > 0 && height != -1 {
+		m.requestGaps()
+	}
+}
+
+// instrumentVerifier reports the measurements of the epoch's verifier to the
+// metrics, see blsShare.VerifierMetrics.
+func (m *OffChainDKG) instrumentVerifier(verifier dkgtypes.Verifier, epoch int) {
+	if v, ok := verifier.(*blsShare.BLSVerifier); ok {
+		v.SetMetrics(metrics.NewVerifierSink(m.metrics, epoch))
+	}
 }
 
 func (m *OffChainDKG) StartDKGRound(validators *alias.ValidatorSet) error {
@@ -273,6 +1252,79 @@ func (m *OffChainDKG) SetVerifier(v dkgtypes.Verifier) {
 	m.verifier = v
 }
 
+// Health reports the verifier readiness and the outcome of the last round.
+func (m *OffChainDKG) Health() dkgtypes.HealthStatus {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	status := dkgtypes.HealthStatus{
+		VerifierReady:   m.verifier != nil && !m.verifier.IsNil(),
+		VerifierRoundID: m.verifierRoundID,
+		LastRoundID:     m.lastRoundID,
+		LastRoundResult: m.lastRoundResult,
+		LastMessageAge:  -1,
+		Paused:          m.paused,
+	}
+	if !m.lastMessageTime.IsZero() {
+		status.LastMessageAge = time.Since(m.lastMessageTime).Seconds()
+	}
+
+	return status
+}
+
+// Snapshot describes the rounds that still have a dealer, ordered by round ID.
+func (m *OffChainDKG) Snapshot() []*dkgtypes.RoundInfo {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	var out []*dkgtypes.RoundInfo
+	for roundID, dealer := range m.dkgRoundToDealer {
+		if dealer == nil {
+			continue
+		}
+		info := dealer.Snapshot()
+		info.KeyPurpose = m.keyPurpose
+		info.Result = dkgtypes.RoundResultInProgress
+		if roundID == m.lastRoundID {
+			info.Result = m.lastRoundResult
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].RoundID < out[j].RoundID })
+
+	return out
+}
+
+// StateSizes returns the number of entries of the per-round state by name,
+// e.g. to check that finished rounds are released.
+func (m *OffChainDKG) StateSizes() map[string]int {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return map[string]int{
+		"dealers":           len(m.dkgRoundToDealer),
+		"dealer_adapters":   len(m.roundDealers),
+		"agreements":        len(m.agreements),
+		"chunks":            m.chunks.Pending(),
+		"attestations":      len(m.attestations),
+		"round_starts":      len(m.roundStarts),
+		"round_start_times": len(m.roundStartTimes),
+		"round_params":      len(m.roundParams),
+		"round_thresholds":  len(m.roundThresholds),
+		"round_epoch_ends":  len(m.roundEpochEnds),
+		"round_losers":      len(m.roundLosers),
+		"round_errors":      len(m.roundErrors),
+		"forensic_bundles":  len(m.forensicBundles),
+		"observed_rounds":   len(m.observedRounds),
+		"transcripts":       len(m.transcripts),
+		"migrations":        len(m.migrations),
+	}
+}
+
+func (m *OffChainDKG) RoundCounter() *dkgtypes.RoundCounter {
+	return m.roundCounter
+}
+
 func (m *OffChainDKG) GetPrivValidator() alias.PrivValidator {
 	return m.privValidator
 }
@@ -281,17 +1333,34 @@ func (m *OffChainDKG) ProcessBlock(roundID int) (error, bool) {
 	return nil, true
 }
 
+// GetLosers pops the validators excluded from the current round, ordered by
+// address.
 func (m *OffChainDKG) GetLosers() []*tmtypes.Validator {
+	return dkgtypes.LoserValidators(m.GetLosersWithReasons())
+}
+
+// GetLosersWithReasons pops the validators excluded from the current round,
+// ordered by address, with the blames they were excluded with.
+func (m *OffChainDKG) GetLosersWithReasons() []*dkgtypes.Loser {
 	m.mtx.Lock()
 	defer m.mtx.Unlock()
 
-	dealer, ok := m.dkgRoundToDealer[m.dkgRoundID]
+	roundID := m.roundCounter.Current()
+	dealer, ok := m.dkgRoundToDealer[roundID]
+	if !ok && m.observedRounds[roundID] {
+		m.Logger.Debug("current round is observed, no losers", "round_id", roundID)
+		return nil
+	}
+	if !ok && roundID <= m.lastEvictedRoundID {
+		m.Logger.Debug("current round was evicted, no losers", "round_id", roundID)
+		return nil
+	}
 	if !ok {
-		m.Logger.Debug("failed to get dealer for current", "roundID", m.dkgRoundID)
-		panic(fmt.Sprintf("failed to get dealer for current round ID (%d)", m.dkgRoundID))
+		m.Logger.Debug("failed to get dealer for current", "round_id", roundID)
+		panic(fmt.Sprintf("failed to get dealer for current round ID (%d)", roundID))
 	}
 
-	return dealer.PopLosers()
+	return dealer.PopLosersWithReasons()
 }
 
 type verifierFunc func(s string, i int) dkgtypes.Verifier
@@ -0,0 +1,57 @@
+package offChain
+
+import (
+	"container/list"
+	"sync"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// WithEchoSuppression drops messages delivered again through another gossip
+// path before locking and verifying them. The hashes of the last size messages
+// are remembered; zero disables the filter.
+func WithEchoSuppression(size int) DKGOption {
+	return func(d *OffChainDKG) {
+		if size > 0 {
+			d.seen = newSeenFilter(size)
+		}
+	}
+}
+
+// seenFilter is a bounded set of message hashes evicting the least recently seen one.
+type seenFilter struct {
+	mtx     sync.Mutex
+	size    int
+	order   *list.List // Hashes, the most recently seen first.
+	entries map[string]*list.Element
+}
+
+func newSeenFilter(size int) *seenFilter {
+	return &seenFilter{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// observe records the message and reports whether it was seen before. The
+// signature is hashed too, so a forged copy can't suppress the genuine message.
+func (f *seenFilter) observe(msg *dkgalias.DKGData) bool {
+	key := string(tmhash.Sum(append(msg.ContentBytes(), msg.Signature...)))
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if elem, ok := f.entries[key]; ok {
+		f.order.MoveToFront(elem)
+		return true
+	}
+	f.entries[key] = f.order.PushFront(key)
+	if f.order.Len() > f.size {
+		oldest := f.order.Back()
+		f.order.Remove(oldest)
+		delete(f.entries, oldest.Value.(string))
+	}
+	return false
+}
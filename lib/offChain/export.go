@@ -0,0 +1,69 @@
+package offChain
+
+import (
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithOperationPolicy enables sensitive operations such as ExportShare,
+// MigrateShare and share refreshes, each authorized by co-signatures of the
+// policy's operators.
+func WithOperationPolicy(policy *dkgtypes.CoSignPolicy) DKGOption {
+	return func(d *OffChainDKG) { d.operationPolicy = policy }
+}
+
+// authorizeOperation checks the request is for the operation on the current
+// verifier and authorizes it with the operation policy.
+func (m *OffChainDKG) authorizeOperation(op dkgtypes.Operation, req *dkgtypes.OperationRequest, signatures []dkgtypes.OperatorSignature) error {
+	if m.operationPolicy == nil {
+		return dkgtypes.ErrOperationDisabled
+	}
+	if req.Operation != op {
+		return fmt.Errorf("request is for %s, not %s", req.Operation, op)
+	}
+	if req.RoundID != m.verifierRoundID {
+		return fmt.Errorf("request is for round %d, the current verifier is of round %d", req.RoundID, m.verifierRoundID)
+	}
+	return m.operationPolicy.Authorize(req, signatures)
+}
+
+// ExportShare exports the key share of the current verifier encrypted with the
+// passphrase, e.g. to move it to a new machine. The request must be signed by
+// the operators of the operation policy and name the round of the verifier.
+func (m *OffChainDKG) ExportShare(
+	req *dkgtypes.OperationRequest,
+	signatures []dkgtypes.OperatorSignature,
+	passphrase []byte,
+	options ...blsShare.KeystoreOption,
+) (*blsShare.EncryptedBLSShare, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if m.operationPolicy == nil {
+		return nil, dkgtypes.ErrOperationDisabled
+	}
+	if req.Operation != dkgtypes.OperationExportShare {
+		return nil, fmt.Errorf("request is for %s, not %s", req.Operation, dkgtypes.OperationExportShare)
+	}
+	if req.RoundID != m.verifierRoundID {
+		return nil, fmt.Errorf("request is for round %d, the current verifier is of round %d", req.RoundID, m.verifierRoundID)
+	}
+
+	bls, ok := blsShare.UnwrapVerifier(m.verifier)
+	if !ok || bls.Keypair == nil {
+		return nil, fmt.Errorf("current verifier has no key share")
+	}
+
+	if err := m.operationPolicy.Authorize(req, signatures); err != nil {
+		return nil, err
+	}
+	m.Logger.Info("dkgState: exporting key share", "round_id", req.RoundID)
+
+	sh, err := blsShare.NewBLSShareJSON(bls.Keypair)
+	if err != nil {
+		return nil, err
+	}
+	return blsShare.EncryptBLSShare(sh, passphrase, options...)
+}
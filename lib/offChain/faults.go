@@ -0,0 +1,88 @@
+package offChain
+
+import (
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/faultinject"
+	"github.com/corestario/dkglib/lib/logging"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithFaultInjection injects the faults into the node's outgoing messages, see
+// package faultinject. Without it, binaries built with the faultinject tag read
+// the faults from the environment. Validate reports faults the build can't
+// inject.
+func WithFaultInjection(cfg *faultinject.Config) DKGOption {
+	return func(d *OffChainDKG) { d.faultConfig = cfg }
+}
+
+// delayedMessage is a message held by fault injection until the height.
+type delayedMessage struct {
+	height int64
+	data   *dkgalias.DKGData
+}
+
+// setupFaults creates the fault injector, if faults are set.
+func (m *OffChainDKG) setupFaults() {
+	cfg := m.faultConfig
+	if cfg == nil && faultinject.Enabled {
+		cfg, m.faultErr = faultinject.FromEnv()
+	}
+	if cfg == nil || m.faultErr != nil {
+		return
+	}
+	m.faults, m.faultErr = faultinject.New(cfg)
+}
+
+// injectFault returns the fault to inject into the message, corrupting it if
+// need be.
+func (m *OffChainDKG) injectFault(data *dkgalias.DKGData) faultinject.Fault {
+	if m.faults == nil {
+		return faultinject.Fault{}
+	}
+	fault := m.faults.Outgoing(data)
+	if fault != (faultinject.Fault{}) {
+		m.Logger.Info("dkgState: injecting fault", "type", data.Type, logging.RoundKey, data.RoundID,
+			"drop", fault.Drop, "delay_blocks", fault.DelayBlocks, "corrupted", fault.Corrupted)
+	}
+	return fault
+}
+
+// sendWithFault sends the message with the fault. A dropped message is still
+// handled by the node, as if only the network lost it.
+func (m *OffChainDKG) sendWithFault(data *dkgalias.DKGData, fault faultinject.Fault) {
+	switch {
+	case fault.Drop:
+		m.queueDKGMessage(data)
+	case fault.DelayBlocks > 0:
+		m.faultsMtx.Lock()
+		m.delayed = append(m.delayed, delayedMessage{height: m.lastHeight + fault.DelayBlocks, data: data})
+		m.faultsMtx.Unlock()
+	default:
+		m.sendDKGMessage(data)
+	}
+}
+
+// releaseDelayed sends the messages delayed until the height.
+func (m *OffChainDKG) releaseDelayed(height int64) {
+	if m.faults == nil {
+		return
+	}
+	var due []*dkgalias.DKGData
+	m.faultsMtx.Lock()
+	held := m.delayed[:0]
+	for _, msg := range m.delayed {
+		if msg.height <= height {
+			due = append(due, msg.data)
+		} else {
+			held = append(held, msg)
+		}
+	}
+	m.delayed = held
+	m.faultsMtx.Unlock()
+
+	for _, data := range due {
+		m.Logger.Info("dkgState: sending delayed message", "type", data.Type, logging.RoundKey, data.RoundID,
+			dkgtypes.MessageCorrelationKey, dkgtypes.MessageCorrelationID(data))
+		m.sendDKGMessage(data)
+	}
+}
@@ -0,0 +1,134 @@
+package offChain
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+const (
+	maxForensicBundles = 8    // Bundles of the last failed rounds kept for ForensicBundle.
+	maxRoundErrors     = 32   // Errors kept per round, the latest ones.
+	forensicWALEntries = 1000 // WAL entries of the round included in a bundle.
+)
+
+var _ dkgtypes.ForensicsProvider = &OffChainDKG{}
+
+// WithForensics produces a forensic bundle whenever a round fails: the round
+// snapshot with the peers' progress, the errors reported during the round and
+// the round's messages recorded in the WAL, if any. The bundles of the last
+// failed rounds are returned by ForensicBundle and, if dir is not empty,
+// written to it as dkg-round-<round ID>.json.
+func WithForensics(dir string) DKGOption {
+	return func(d *OffChainDKG) { d.forensics, d.forensicsDir = true, dir }
+}
+
+// ForensicBundle returns the forensic bundle of one of the last failed rounds.
+func (m *OffChainDKG) ForensicBundle(roundID int) (*dkgtypes.ForensicBundle, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	bundle, ok := m.forensicBundles[roundID]
+	if !ok {
+		return nil, fmt.Errorf("no forensic bundle of round %d", roundID)
+	}
+	return bundle, nil
+}
+
+// noteRoundError records an error reported while running the round and
+// publishes it, if the event publisher is a types.ErrorPublisher.
+func (m *OffChainDKG) noteRoundError(roundID int, err error) {
+	if publisher, ok := m.eventPublisher.(dkgtypes.ErrorPublisher); ok {
+		if pubErr := publisher.PublishDKGError(roundID, err); pubErr != nil {
+			m.Logger.Error("dkgState: failed to publish round error", "round_id", roundID, "error", pubErr)
+		}
+	}
+	if !m.forensics {
+		return
+	}
+	errs := m.roundErrors[roundID]
+	if len(errs) >= maxRoundErrors {
+		errs = errs[1:]
+	}
+	m.roundErrors[roundID] = append(errs, err.Error())
+}
+
+// failRound marks the round failed because of the error and drops its dealer.
+func (m *OffChainDKG) failRound(roundID int, err error) {
+	m.noteRoundError(roundID, err)
+	m.broadcastAbort(roundID, err)
+	m.setRoundResult(roundID, dkgtypes.RoundResultFailed)
+	m.dkgRoundToDealer[roundID] = nil
+	delete(m.transcripts, roundID)
+	m.forgetJournal(roundID)
+}
+
+// recordForensics builds the bundle of the failed round while its dealer is
+// still around.
+func (m *OffChainDKG) recordForensics(roundID int) {
+	if !m.forensics {
+		return
+	}
+	bundle := &dkgtypes.ForensicBundle{
+		RoundID:       roundID,
+		CorrelationID: dkgtypes.RoundCorrelationID(roundID),
+		CreatedAt:     time.Now().UTC(),
+		Height:        m.lastHeight,
+		Errors:        m.roundErrors[roundID],
+	}
+	delete(m.roundErrors, roundID)
+	if dealer := m.dkgRoundToDealer[roundID]; dealer != nil {
+		bundle.Round = dealer.Snapshot()
+		bundle.Round.Result = dkgtypes.RoundResultFailed
+		for _, phase := range bundle.Round.Progress {
+			bundle.Progress = append(bundle.Progress, phase.String())
+		}
+	}
+	if m.wal != nil {
+		entries, err := m.wal.Excerpt(roundID, forensicWALEntries)
+		if err != nil {
+			bundle.Errors = append(bundle.Errors, fmt.Sprintf("failed to read WAL: %v", err))
+		}
+		for _, entry := range entries {
+			msg := &dkgtypes.ForensicMessage{Time: entry.Time, Direction: entry.Kind.String()}
+			if entry.Data != nil {
+				msg.Type = entry.Data.Type.String()
+				msg.From = entry.Data.GetAddrString()
+				msg.ToIndex = entry.Data.ToIndex
+				msg.Size = len(entry.Data.Data)
+				msg.NumChunks = entry.Data.NumChunks
+			}
+			bundle.Messages = append(bundle.Messages, msg)
+		}
+	}
+
+	m.forensicBundles[roundID] = bundle
+	for len(m.forensicBundles) > maxForensicBundles {
+		oldest := roundID
+		for id := range m.forensicBundles {
+			if id < oldest {
+				oldest = id
+			}
+		}
+		delete(m.forensicBundles, oldest)
+	}
+
+	if m.forensicsDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		m.Logger.Error("dkgState: failed to encode forensic bundle", "round_id", roundID, "error", err)
+		return
+	}
+	path := filepath.Join(m.forensicsDir, fmt.Sprintf("dkg-round-%d.json", roundID))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		m.Logger.Error("dkgState: failed to write forensic bundle", "round_id", roundID, "error", err)
+		return
+	}
+	m.Logger.Info("dkgState: forensic bundle written", "round_id", roundID, "path", path)
+}
@@ -0,0 +1,56 @@
+package offChain
+
+import (
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithRoundGater consults the gater before the node starts a round. A vetoed
+// scheduled round is retried at every block until the gater allows it, and a
+// vetoed TriggerRound fails with a RoundVetoError. Rounds started by peers are
+// still joined, as the window is expected to be the same on every node.
+func WithRoundGater(gater dkgtypes.RoundGater) DKGOption {
+	return func(d *OffChainDKG) { d.roundGater = gater }
+}
+
+// gateRound returns a RoundVetoError if the gater vetoes the next round.
+func (m *OffChainDKG) gateRound() error {
+	if m.roundGater == nil {
+		return nil
+	}
+	roundID := m.roundCounter.Current() + 1
+	if err := m.roundGater.AllowRound(roundID, m.lastHeight); err != nil {
+		return &dkgtypes.RoundVetoError{RoundID: roundID, Height: m.lastHeight, Reason: err}
+	}
+	return nil
+}
+
+// WithAdoptionGater consults the gater before the node activates the verifier
+// of a successful round, after the operator approval, if any, see
+// WithVerifierApproval. A delayed verifier is retried at every block until the
+// gater allows it, and a vetoed one is dropped, keeping the current verifier.
+func WithAdoptionGater(gater dkgtypes.AdoptionGater) DKGOption {
+	return func(d *OffChainDKG) { d.adoptionGater = gater }
+}
+
+// gateAdoption reports whether the gater allows activating the next verifier
+// at the height; a vetoed verifier is dropped.
+func (m *OffChainDKG) gateAdoption(height int64) bool {
+	if m.adoptionGater == nil {
+		return true
+	}
+	err := m.adoptionGater.AllowAdoption(m.nextVerifierRoundID, height)
+	if err == nil {
+		return true
+	}
+	if veto, ok := err.(*dkgtypes.AdoptionVetoError); ok {
+		m.Logger.Error("dkgState: verifier adoption vetoed", "round_id", m.nextVerifierRoundID, "error", veto.Reason)
+		m.nextVerifier, m.changeHeight, m.activationDeferred = nil, 0, false
+		return false
+	}
+	if !m.activationDeferred {
+		m.Logger.Info("dkgState: verifier adoption delayed", "round_id", m.nextVerifierRoundID,
+			"change_height", m.changeHeight, "error", err)
+	}
+	m.activationDeferred = true
+	return false
+}
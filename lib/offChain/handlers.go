@@ -0,0 +1,76 @@
+package offChain
+
+import (
+	"fmt"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/alias"
+)
+
+// MessageHandler handles gossip messages outside of the DKG rounds, e.g. those
+// of the lib/signer service.
+type MessageHandler interface {
+	HandleMessage(msg *dkgalias.DKGData)
+}
+
+// SetMessageHandler passes the messages of the types to the handler instead of
+// the rounds' dealers, once their signatures verify against the validator set
+// and their chunks are reassembled. The node's own messages are passed too. The
+// handler is called with the DKG's lock held, so it must not call back into it.
+func (m *OffChainDKG) SetMessageHandler(handler MessageHandler, dataTypes ...dkgalias.DKGDataType) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.messageHandlers == nil {
+		m.messageHandlers = make(map[dkgalias.DKGDataType]MessageHandler)
+	}
+	for _, dataType := range dataTypes {
+		m.messageHandlers[dataType] = handler
+	}
+}
+
+// Broadcast signs the message as the node's and gossips it to the peers and
+// the node itself, like the messages of the rounds.
+func (m *OffChainDKG) Broadcast(msg *dkgalias.DKGData) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	msg.Addr = m.privValidator.GetPubKey().Address().Bytes()
+	return m.sendSignedMessage([]*dkgalias.DKGData{msg})
+}
+
+// EpochVerifier returns the verifier in use and the round it was generated in,
+// -1 if unknown.
+func (m *OffChainDKG) EpochVerifier() (dkgtypes.Verifier, int) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.verifier, m.verifierRoundID
+}
+
+// handleExternal passes the message to its MessageHandler, if it has one.
+func (m *OffChainDKG) handleExternal(msg *dkgalias.DKGData, validators *alias.ValidatorSet) (bool, error) {
+	handler, ok := m.messageHandlers[msg.Type]
+	if !ok {
+		return false, nil
+	}
+	if validators == nil {
+		validators = m.validators
+	}
+	if validators == nil {
+		return true, fmt.Errorf("no validator set to verify %v message with", msg.Type)
+	}
+	_, validator := validators.GetByAddress(msg.Addr)
+	if validator == nil {
+		return true, fmt.Errorf("%v message from %s, which is not a validator", msg.Type, msg.GetAddrString())
+	}
+	if !m.signDomain.Verify(validator.PubKey, msg) {
+		return true, fmt.Errorf("invalid %v message signature of %s", msg.Type, msg.GetAddrString())
+	}
+	reassembled, err := m.chunks.Add(msg)
+	if err != nil {
+		return true, fmt.Errorf("can't reassemble %v message: %v", msg.Type, err)
+	}
+	if reassembled != nil {
+		handler.HandleMessage(reassembled)
+	}
+	return true, nil
+}
@@ -0,0 +1,51 @@
+package offChain
+
+import (
+	"fmt"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithIndexStore persists the index assignment of every round the node takes
+// part in, see types.AssignIndices, and refuses to run a round whose committee
+// would assign other indices than the persisted ones, e.g. after a restart with
+// another validator set.
+func WithIndexStore(store *dkgtypes.IndexStore) DKGOption {
+	return func(d *OffChainDKG) { d.indexStore = store }
+}
+
+// ParticipantIndices returns the DKG indices of the participants of the round,
+// from its dealer if it is in progress or from the index store otherwise.
+func (m *OffChainDKG) ParticipantIndices(roundID int) (*dkgtypes.IndexAssignment, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if dealer := m.dkgRoundToDealer[roundID]; dealer != nil {
+		if participants := dealer.GetState().GetParticipants(); participants != nil {
+			return dkgtypes.AssignIndices(roundID, participants), nil
+		}
+	}
+	if m.indexStore == nil {
+		return nil, fmt.Errorf("round %d is not in progress", roundID)
+	}
+	return m.indexStore.Load(roundID)
+}
+
+// persistIndices saves the round's index assignment, failing with an
+// IndexMismatchError if another one was persisted for the round.
+func (m *OffChainDKG) persistIndices(roundID int, participants *dkgtypes.ParticipantSet) error {
+	if m.indexStore == nil {
+		return nil
+	}
+	return m.indexStore.Save(dkgtypes.AssignIndices(roundID, participants))
+}
+
+// skipRound reports whether the scheduled round is skipped rather than the
+// node stopped because of the error.
+func skipRound(err error) bool {
+	switch err.(type) {
+	case *dkgtypes.CommitteeError, *dkgtypes.IndexMismatchError, *CommitteeMismatchError:
+		return true
+	}
+	return false
+}
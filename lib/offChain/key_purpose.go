@@ -0,0 +1,31 @@
+package offChain
+
+import (
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// WithKeyPurpose makes the DKG maintain the group key of the purpose, e.g.
+// "bridge", next to the instances of other purposes in the same process, see
+// the keyring package. Its messages carry the purpose, which they are signed
+// with, and messages of other purposes are dropped. The default key has the
+// empty purpose. Every purpose needs its own storage: WAL, share files,
+// blacklist and forensics directory, see types.KeyPurposePath.
+func WithKeyPurpose(purpose string) DKGOption {
+	return func(d *OffChainDKG) { d.keyPurpose = purpose }
+}
+
+// WithMsgQueue makes the DKG pass the node's own messages to the queue, e.g.
+// one shared by the instances of a keyring, instead of a queue of its own.
+func WithMsgQueue(queue chan *dkgtypes.DKGDataMessage) DKGOption {
+	return func(d *OffChainDKG) {
+		if queue == nil {
+			return
+		}
+		d.dkgMsgQueue = queue
+	}
+}
+
+// KeyPurpose returns the purpose of the group key the DKG maintains.
+func (m *OffChainDKG) KeyPurpose() string {
+	return m.keyPurpose
+}
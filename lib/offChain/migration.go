@@ -0,0 +1,137 @@
+package offChain
+
+import (
+	"bytes"
+	"fmt"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/corestario/dkglib/lib/wal"
+	"github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// migrationGraceBlocks is the number of blocks peers still accept messages
+// signed with the old key of a migrated share, so messages in flight aren't
+// lost.
+const migrationGraceBlocks = 10
+
+// MigrateShare moves the node's DKG shares to the new private validator after
+// its consensus key was rotated, without a resharing round: the migration,
+// signed by both keys, is broadcast and the node signs its DKG messages with
+// the new key from now on. Peers accept the new key for the epoch's share and
+// the rounds in progress, where the node keeps its index, and the old key only
+// for migrationGraceBlocks more blocks; later rounds are run with the
+// validator set, which must have the new key by then. Like ExportShare, the
+// request must be signed by the operators of the operation policy and name the
+// round of the verifier.
+func (m *OffChainDKG) MigrateShare(
+	req *dkgtypes.OperationRequest,
+	signatures []dkgtypes.OperatorSignature,
+	newPV alias.PrivValidator,
+) (*dkgtypes.ShareMigration, error) {
+	m.mtx.Lock()
+	if err := m.authorizeOperation(dkgtypes.OperationMigrateShare, req, signatures); err != nil {
+		m.mtx.Unlock()
+		return nil, err
+	}
+	migration, err := dkgtypes.NewShareMigration(m.signDomain.ChainID, m.verifierRoundID,
+		m.lastHeight+migrationGraceBlocks, m.privValidator, newPV)
+	if err != nil {
+		m.mtx.Unlock()
+		return nil, err
+	}
+	if err := migration.Verify(m.signDomain.ChainID); err != nil {
+		m.mtx.Unlock()
+		return nil, err
+	}
+	data, err := dkgtypes.EncodeShareMigration(migration)
+	if err != nil {
+		m.mtx.Unlock()
+		return nil, fmt.Errorf("failed to encode share migration: %v", err)
+	}
+	m.privValidator = newPV
+	m.mtx.Unlock()
+
+	msg := &dkgalias.DKGData{
+		Type:    dkgalias.DKGMigration,
+		RoundID: migration.Epoch,
+		Addr:    migration.NewAddr(),
+		Data:    data,
+	}
+	if err := m.Sign(msg); err != nil {
+		return nil, err
+	}
+	m.Logger.Info("dkgState: migrating share to a new key", "epoch", migration.Epoch,
+		"old_addr", migration.OldAddr(), "new_addr", migration.NewAddr())
+	m.writeWAL(wal.EntryOutgoing, msg)
+	m.sendDKGMessage(msg)
+
+	return migration, nil
+}
+
+// handleMigration applies a share migration to the epoch's attestation and the
+// dealers of the rounds in progress the participant takes part in.
+func (m *OffChainDKG) handleMigration(msg *dkgalias.DKGData) error {
+	migration, err := dkgtypes.DecodeShareMigration(msg.Data)
+	if err != nil {
+		return err
+	}
+	if err := migration.Verify(m.signDomain.ChainID); err != nil {
+		return err
+	}
+	if !bytes.Equal(msg.Addr, migration.NewAddr()) || msg.RoundID != migration.Epoch {
+		return fmt.Errorf("migration of %s for epoch %d sent by %s for round %d",
+			migration.NewAddr(), migration.Epoch, msg.GetAddrString(), msg.RoundID)
+	}
+	if !m.signDomain.Verify(migration.NewPubKey, msg) {
+		return fmt.Errorf("invalid migration message signature of %s", msg.GetAddrString())
+	}
+	if known, ok := m.migrations[migration.OldAddr().String()]; ok && known.NewPubKey.Equals(migration.NewPubKey) {
+		return nil
+	}
+
+	var applied bool
+	if attestation, ok := m.attestations[migration.Epoch]; ok {
+		if _, holder := attestation.Holders().GetByAddress(migration.OldAddr()); holder != nil {
+			attestation.Migrations = append(attestation.Migrations, migration)
+			applied = true
+		}
+	}
+	for roundID, dealer := range m.dkgRoundToDealer {
+		if dealer == nil || roundID < migration.Epoch {
+			continue
+		}
+		if _, participant := dealer.GetState().GetParticipants().GetByAddress(migration.OldAddr()); participant == nil {
+			continue
+		}
+		if err := dealer.MigrateParticipant(migration); err != nil {
+			m.Logger.Error("dkgState: failed to migrate participant", "round_id", roundID, "error", err)
+			continue
+		}
+		applied = true
+	}
+	if !applied {
+		return fmt.Errorf("%s holds no share of epoch %d or of a round in progress", migration.OldAddr(), migration.Epoch)
+	}
+
+	m.migrations[migration.OldAddr().String()] = migration
+	m.migrations[migration.NewAddr().String()] = migration
+	m.Logger.Info("dkgState: share migrated", "epoch", migration.Epoch,
+		"old_addr", migration.OldAddr(), "new_addr", migration.NewAddr())
+	m.firer.FireEvent(dkgtypes.EventDKGShareMigrated, dkgtypes.DKGShareMigratedEvent{
+		Epoch:   migration.Epoch,
+		OldAddr: migration.OldAddr(),
+		NewAddr: migration.NewAddr(),
+	})
+	return nil
+}
+
+// participantAddr returns the address the sender has in the rounds started
+// before it migrated its share.
+func (m *OffChainDKG) participantAddr(addr []byte) crypto.Address {
+	if migration, ok := m.migrations[crypto.Address(addr).String()]; ok {
+		return migration.OldAddr()
+	}
+	return addr
+}
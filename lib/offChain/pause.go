@@ -0,0 +1,58 @@
+package offChain
+
+import (
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+var _ dkgtypes.Pauser = &OffChainDKG{}
+
+// Pause takes the node out of DKG participation without stopping the
+// validator. Rounds started or first seen while paused are only observed:
+// the node creates no dealer and sends nothing in them, so the other
+// participants see it as silent. Rounds already running go on.
+func (m *OffChainDKG) Pause() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if !m.paused {
+		m.Logger.Info("dkgState: DKG participation paused", "last_round_id", m.lastRoundID)
+	}
+	m.paused = true
+}
+
+// Resume makes the node participate again in the rounds started from now on;
+// the rounds observed while paused stay observed.
+func (m *OffChainDKG) Resume() {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if m.paused {
+		m.Logger.Info("dkgState: DKG participation resumed", "last_round_id", m.lastRoundID)
+	}
+	m.paused = false
+}
+
+func (m *OffChainDKG) Paused() bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.paused
+}
+
+// observeRound records that the node only observes the round.
+func (m *OffChainDKG) observeRound(roundID int) {
+	if !m.observedRounds[roundID] {
+		m.Logger.Info("dkgState: DKG participation paused, observing round", "round_id", roundID)
+	}
+	m.observedRounds[roundID] = true
+}
+
+// observing reports whether the node only observes the round; the caller
+// holds the lock.
+func (m *OffChainDKG) observing(roundID int) bool {
+	if m.observedRounds[roundID] {
+		return true
+	}
+	if _, ok := m.dkgRoundToDealer[roundID]; ok || !m.paused {
+		return false
+	}
+	m.observeRound(roundID)
+	return true
+}
@@ -0,0 +1,57 @@
+package offChain
+
+// WithPipelining runs the round for the next epoch in the background while
+// the current verifier is active. Epochs span dkgNumBlocks blocks and end at
+// multiples of it; the round for the next epoch starts leadBlocks before the
+// end of the current one and its verifier takes over exactly at the boundary,
+// so key rotation never waits for a round. leadBlocks must be shorter than
+// dkgNumBlocks and long enough for a round to complete; a round finishing too
+// close to or after its boundary falls back to a change height blocksAhead
+// after completion. All nodes must use the same settings.
+func WithPipelining(leadBlocks int64) DKGOption {
+	return func(d *OffChainDKG) { d.pipelineLead = leadBlocks }
+}
+
+func (m *OffChainDKG) pipelining() bool {
+	return m.pipelineLead > 0 && m.pipelineLead < m.dkgNumBlocks
+}
+
+// roundStartDue reports whether a round starts at the height.
+func (m *OffChainDKG) roundStartDue(height int64) bool {
+	if m.pipelining() {
+		return height%m.dkgNumBlocks == m.dkgNumBlocks-m.pipelineLead
+	}
+	return height > 1 && height%m.dkgNumBlocks == 0
+}
+
+// roundStartHeight returns the height the scheduled round seen at the height
+// was due at: the closest one, since peers may be a few blocks ahead of or
+// behind the node.
+func (m *OffChainDKG) roundStartHeight(height int64) int64 {
+	var offset int64
+	if m.pipelining() {
+		offset = m.dkgNumBlocks - m.pipelineLead
+	}
+	last := (height-offset)/m.dkgNumBlocks*m.dkgNumBlocks + offset
+	if next := last + m.dkgNumBlocks; next-height < height-last {
+		return next
+	}
+	return last
+}
+
+// epochEnd returns the first epoch boundary after the height. A round belongs
+// to the epoch starting at the boundary following the height it was started
+// or first seen at.
+func (m *OffChainDKG) epochEnd(height int64) int64 {
+	return (height/m.dkgNumBlocks + 1) * m.dkgNumBlocks
+}
+
+// proposedChangeHeight returns the change height proposed for the round
+// completed at the height: its epoch boundary when pipelining and the boundary
+// leaves time for the agreement, blocksAhead blocks later otherwise.
+func (m *OffChainDKG) proposedChangeHeight(roundID int, height int64) int64 {
+	if end, ok := m.roundEpochEnds[roundID]; ok && end-height >= changeHeightAlign {
+		return end
+	}
+	return (height + m.blocksAhead) - ((height + m.blocksAhead) % changeHeightAlign)
+}
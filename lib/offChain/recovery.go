@@ -0,0 +1,56 @@
+package offChain
+
+import (
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// RecoverShare replaces the verifier of the epoch in use or the next one, which
+// holds no share, e.g. after state sync, with the verifier holding the share
+// recovered from the other participants, see lib/recovery. The verifier must
+// have the same group key.
+func (m *OffChainDKG) RecoverShare(epoch int, verifier dkgtypes.Verifier) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var current *dkgtypes.Verifier
+	switch epoch {
+	case m.verifierRoundID:
+		current = &m.verifier
+	case m.nextVerifierRoundID:
+		current = &m.nextVerifier
+	default:
+		return fmt.Errorf("epoch %d is neither in use (%d) nor next (%d)", epoch, m.verifierRoundID, m.nextVerifierRoundID)
+	}
+	if holdsShare(*current) {
+		return fmt.Errorf("node already holds a share of epoch %d", epoch)
+	}
+	have, err := dkgtypes.NewVerifierSnapshot(*current, epoch)
+	if err != nil {
+		return fmt.Errorf("failed to export the key of epoch %d: %v", epoch, err)
+	}
+	recovered, err := dkgtypes.NewVerifierSnapshot(verifier, epoch)
+	if err != nil {
+		return fmt.Errorf("failed to export the recovered key: %v", err)
+	}
+	if have.MasterPubKey != recovered.MasterPubKey {
+		return fmt.Errorf("recovered share is of another group key than epoch %d's", epoch)
+	}
+
+	m.instrumentVerifier(verifier, epoch)
+	if m.usageOptions != nil {
+		verifier = dkgtypes.NewUsageVerifier(verifier, epoch, m.Logger, m.usageOptions...)
+	}
+	*current = verifier
+	m.Logger.Info("dkgState: share recovered", "epoch", epoch)
+	return nil
+}
+
+// holdsShare reports whether the verifier, which may be wrapped into a
+// UsageVerifier, holds a BLS key share.
+func holdsShare(verifier dkgtypes.Verifier) bool {
+	bls, ok := blsShare.UnwrapVerifier(verifier)
+	return ok && bls.Keypair != nil
+}
@@ -0,0 +1,138 @@
+package offChain
+
+import (
+	"fmt"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// pendingRefresh is a verifier with refreshed shares awaiting its height.
+type pendingRefresh struct {
+	epoch    int
+	verifier dkgtypes.Verifier
+	height   int64
+}
+
+// Committee returns the participants of one of the last completed rounds, the
+// holders of its key shares in the order of their indices.
+func (m *OffChainDKG) Committee(roundID int) (*dkgtypes.ParticipantSet, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	attestation, ok := m.attestations[roundID]
+	if !ok {
+		return nil, fmt.Errorf("no committee of round %d", roundID)
+	}
+	return dkgtypes.NewParticipantSetFromList(attestation.Participants), nil
+}
+
+// RoundCommittee returns the participants of a round in progress or of one of
+// the last completed rounds.
+func (m *OffChainDKG) RoundCommittee(roundID int) (*dkgtypes.ParticipantSet, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if dealer := m.dkgRoundToDealer[roundID]; dealer != nil {
+		if participants := dealer.GetState().GetParticipants(); participants != nil {
+			return participants, nil
+		}
+	}
+	attestation, ok := m.attestations[roundID]
+	if !ok {
+		return nil, fmt.Errorf("no committee of round %d", roundID)
+	}
+	return dkgtypes.NewParticipantSetFromList(attestation.Participants), nil
+}
+
+// LastHeight returns the largest height passed to CheckDKGTime.
+func (m *OffChainDKG) LastHeight() int64 {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.lastHeight
+}
+
+// AuthorizeRefresh authorizes a single refresh of the shares of the epoch in
+// use, which ScheduleRefresh requires. The request must be for
+// dkgtypes.OperationReshare on the epoch and signed by the operators of the
+// operation policy.
+func (m *OffChainDKG) AuthorizeRefresh(req *dkgtypes.OperationRequest, signatures []dkgtypes.OperatorSignature) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if err := m.authorizeOperation(dkgtypes.OperationReshare, req, signatures); err != nil {
+		return err
+	}
+	if m.authorizedRefreshes == nil {
+		m.authorizedRefreshes = make(map[int]bool)
+	}
+	m.authorizedRefreshes[req.RoundID] = true
+	m.Logger.Info("dkgState: share refresh authorized", "epoch", req.RoundID)
+	return nil
+}
+
+// CheckRefresh returns the error ScheduleRefresh would fail with for the epoch
+// whatever the height, so that a holder doesn't agree to a refresh it can't
+// schedule.
+func (m *OffChainDKG) CheckRefresh(epoch int) error {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+	return m.checkRefresh(epoch)
+}
+
+// ScheduleRefresh replaces the verifier of the epoch in use with one holding
+// refreshed shares of the same group key at the height, see lib/refresh. The
+// refresh must be authorized with AuthorizeRefresh, and consumes the
+// authorization. It is dropped if another epoch's verifier is activated first.
+func (m *OffChainDKG) ScheduleRefresh(epoch int, verifier dkgtypes.Verifier, height int64) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if err := m.checkRefresh(epoch); err != nil {
+		return err
+	}
+	if height <= m.lastHeight {
+		return fmt.Errorf("refresh height %d has passed", height)
+	}
+	delete(m.authorizedRefreshes, epoch)
+	m.refresh = &pendingRefresh{epoch: epoch, verifier: verifier, height: height}
+	m.Logger.Info("dkgState: share refresh scheduled", "epoch", epoch, "height", height)
+	return nil
+}
+
+// checkRefresh returns why a refresh of the epoch can't be scheduled; m.mtx
+// must be held.
+func (m *OffChainDKG) checkRefresh(epoch int) error {
+	if m.operationPolicy == nil {
+		return dkgtypes.ErrOperationDisabled
+	}
+	if epoch != m.verifierRoundID {
+		return fmt.Errorf("refresh is of epoch %d, the verifier in use of epoch %d", epoch, m.verifierRoundID)
+	}
+	if !m.authorizedRefreshes[epoch] {
+		return fmt.Errorf("refresh of epoch %d is not authorized, see AuthorizeRefresh", epoch)
+	}
+	if m.refresh != nil {
+		return fmt.Errorf("a refresh of epoch %d is already scheduled at height %d", m.refresh.epoch, m.refresh.height)
+	}
+	return nil
+}
+
+// applyRefresh activates the scheduled refresh once its height is reached;
+// m.mtx must be held.
+func (m *OffChainDKG) applyRefresh(height int64) {
+	if m.refresh == nil || height < m.refresh.height {
+		return
+	}
+	refresh := m.refresh
+	m.refresh = nil
+	if refresh.epoch != m.verifierRoundID {
+		m.Logger.Info("dkgState: dropping share refresh of a past epoch", "epoch", refresh.epoch)
+		return
+	}
+	m.verifier = refresh.verifier
+	m.instrumentVerifier(m.verifier, m.verifierRoundID)
+	if m.usageOptions != nil {
+		m.verifier = dkgtypes.NewUsageVerifier(m.verifier, m.verifierRoundID, m.Logger, m.usageOptions...)
+	}
+	m.Logger.Info("dkgState: shares refreshed", "epoch", refresh.epoch, "height", height)
+}
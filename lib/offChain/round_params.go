@@ -0,0 +1,125 @@
+package offChain
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkglib "github.com/corestario/dkglib/lib/dealer"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// roundParamsState holds the dealer messages of a round until its parameters are agreed on.
+type roundParamsState struct {
+	agreed   bool
+	buffered []*dkgalias.DKGData
+}
+
+// ParamsMismatchError is returned when the parameters proposed for a round differ from ours.
+type ParamsMismatchError struct {
+	RoundID  int
+	Proposer string
+	Diffs    []string
+}
+
+func (e *ParamsMismatchError) Error() string {
+	return fmt.Sprintf("round %d aborted, parameters proposed by %s differ: %s", e.RoundID, e.Proposer, strings.Join(e.Diffs, ", "))
+}
+
+// WithParamsNegotiation makes the proposer of every round sign and broadcast
+// its parameters (see dkgtypes.RoundParams). Participants hold the dealer
+// messages of the round, so no secrets are distributed, until the proposal
+// matches their own parameters, and abort the round otherwise. All nodes must
+// enable it; a round whose proposer is offline stalls until it is evicted.
+func WithParamsNegotiation() DKGOption {
+	return func(d *OffChainDKG) { d.negotiateParams = true }
+}
+
+func (m *OffChainDKG) localRoundParams(roundID int, participants *dkgtypes.ParticipantSet) *dkgtypes.RoundParams {
+	threshold, ok := m.roundThresholds[roundID]
+	if !ok {
+		threshold = dkglib.Threshold(participants.Size())
+	}
+	return &dkgtypes.RoundParams{
+		ProtocolVersion:  dkgtypes.RoundProtocolVersion,
+		Threshold:        threshold,
+		ParticipantsHash: participants.Hash(),
+		IndexHash:        dkgtypes.AssignIndices(roundID, participants).Hash(),
+		NumBlocks:        m.dkgNumBlocks,
+		BlocksAhead:      m.blocksAhead,
+	}
+}
+
+// sendRoundParams proposes the parameters of the round if the node is its proposer.
+func (m *OffChainDKG) sendRoundParams(roundID int, participants *dkgtypes.ParticipantSet) error {
+	if !m.negotiateParams {
+		return nil
+	}
+	addr := m.privValidator.GetPubKey().Address()
+	if proposer := dkgtypes.RoundProposer(participants, roundID); proposer == nil || !bytes.Equal(proposer.Address, addr) {
+		return nil
+	}
+	return m.sendSignedMessage([]*dkgalias.DKGData{{
+		Type:    dkgalias.DKGRoundParams,
+		RoundID: roundID,
+		Addr:    addr.Bytes(),
+		Data:    m.localRoundParams(roundID, participants).Encode(),
+	}})
+}
+
+// holdForParams buffers the dealer message if the parameters of its round are
+// not agreed on yet and reports whether it did.
+func (m *OffChainDKG) holdForParams(msg *dkgalias.DKGData) bool {
+	if !m.negotiateParams {
+		return false
+	}
+	state := m.roundParamsState(msg.RoundID)
+	if state.agreed {
+		return false
+	}
+	state.buffered = append(state.buffered, msg)
+	return true
+}
+
+func (m *OffChainDKG) roundParamsState(roundID int) *roundParamsState {
+	state, ok := m.roundParams[roundID]
+	if !ok {
+		state = &roundParamsState{}
+		m.roundParams[roundID] = state
+	}
+	return state
+}
+
+// handleRoundParams checks the proposal against our parameters and returns the
+// dealer messages held until the agreement. On mismatch the round is aborted
+// with a ParamsMismatchError.
+func (m *OffChainDKG) handleRoundParams(msg *dkgalias.DKGData, participants *dkgtypes.ParticipantSet) ([]*dkgalias.DKGData, error) {
+	if !m.negotiateParams {
+		return nil, nil
+	}
+	proposer := dkgtypes.RoundProposer(participants, msg.RoundID)
+	if proposer == nil || !bytes.Equal(proposer.Address, msg.Addr) {
+		return nil, fmt.Errorf("round params from %s, not the proposer of round %d", msg.GetAddrString(), msg.RoundID)
+	}
+	proposed, err := dkgtypes.DecodeRoundParams(msg.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	state := m.roundParamsState(msg.RoundID)
+	if state.agreed {
+		return nil, nil
+	}
+	if diffs := m.localRoundParams(msg.RoundID, participants).Diff(proposed); len(diffs) > 0 {
+		delete(m.roundParams, msg.RoundID)
+		err := &ParamsMismatchError{RoundID: msg.RoundID, Proposer: msg.GetAddrString(), Diffs: diffs}
+		m.failRound(msg.RoundID, err)
+		return nil, err
+	}
+
+	state.agreed = true
+	buffered := state.buffered
+	state.buffered = nil
+	return buffered, nil
+}
@@ -0,0 +1,106 @@
+package offChain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+// roundStart is the view of the chain a participant had when it started a round.
+type roundStart struct {
+	height int64
+	hash   []byte // Hash of the validator set the round was started with.
+}
+
+// DesyncError is returned when some participants started a round with a different view of the chain.
+type DesyncError struct {
+	RoundID int
+	Peers   []string
+}
+
+func (e *DesyncError) Error() string {
+	return fmt.Sprintf("round %d aborted, desynchronized peers: %s", e.RoundID, strings.Join(e.Peers, ", "))
+}
+
+// WithMaxHeightSkew sets how far the start heights reported by the participants may diverge.
+func WithMaxHeightSkew(skew int64) DKGOption {
+	return func(d *OffChainDKG) { d.maxHeightSkew = skew }
+}
+
+// sendRoundStart reports the height the node started the round at, or the
+// height it was due at if the node joined it on a peer's message.
+func (m *OffChainDKG) sendRoundStart(roundID int, participants *dkgtypes.ParticipantSet, height int64) error {
+	return m.sendSignedMessage([]*dkgalias.DKGData{{
+		Type:    dkgalias.DKGRoundStart,
+		RoundID: roundID,
+		Addr:    m.privValidator.GetPubKey().Address().Bytes(),
+		Data:    encodeRoundStart(&roundStart{height: height, hash: participants.Hash()}),
+	}})
+}
+
+// handleRoundStart compares the participants' views of the chain once all of them have
+// reported it and returns a DesyncError if some of them differ from ours.
+func (m *OffChainDKG) handleRoundStart(msg *dkgalias.DKGData, participants *dkgtypes.ParticipantSet) error {
+	start, err := decodeRoundStart(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	starts, ok := m.roundStarts[msg.RoundID]
+	if !ok {
+		starts = make(map[string]*roundStart)
+		m.roundStarts[msg.RoundID] = starts
+	}
+	starts[msg.GetAddrString()] = start
+	if len(starts) < participants.Size() {
+		return nil
+	}
+	delete(m.roundStarts, msg.RoundID)
+
+	own, ok := starts[m.privValidator.GetPubKey().Address().String()]
+	if !ok {
+		// We did not start this round ourselves, nothing to compare with.
+		return nil
+	}
+
+	var desynced []string
+	for addr, start := range starts {
+		skew := start.height - own.height
+		if skew < 0 {
+			skew = -skew
+		}
+		if skew > m.maxHeightSkew || !bytes.Equal(start.hash, own.hash) {
+			desynced = append(desynced, fmt.Sprintf("%s (height %d)", addr, start.height))
+		}
+	}
+	if len(desynced) == 0 {
+		return nil
+	}
+	sort.Strings(desynced)
+
+	err = &DesyncError{RoundID: msg.RoundID, Peers: desynced}
+	m.failRound(msg.RoundID, err)
+
+	return err
+}
+
+func encodeRoundStart(start *roundStart) []byte {
+	buf := make([]byte, 8, 8+len(start.hash))
+	binary.BigEndian.PutUint64(buf, uint64(start.height))
+	return append(buf, start.hash...)
+}
+
+func decodeRoundStart(data []byte) (*roundStart, error) {
+	if len(data) < 8 {
+		return nil, fmt.Errorf("invalid round start length: %d", len(data))
+	}
+	return &roundStart{
+		height: int64(binary.BigEndian.Uint64(data[:8])),
+		hash:   data[8:],
+	}, nil
+}
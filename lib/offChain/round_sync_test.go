@@ -0,0 +1,11 @@
+package offChain
+
+import "testing"
+
+func TestJoinedRoundStart(t *testing.T) {
+	// The nodes but the first join the rounds on its messages.
+	cluster := newTestCluster(t, 4)
+	cluster.lagging = true
+	cluster.runRound(t, 1)
+	cluster.runRound(t, 2)
+}
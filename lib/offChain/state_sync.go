@@ -0,0 +1,107 @@
+package offChain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+var _ dkgtypes.StateSyncer = &OffChainDKG{}
+
+// ExportState encodes the public DKG state for the host application's state sync snapshot.
+func (m *OffChainDKG) ExportState() ([]byte, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	snapshot, err := m.stateSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(snapshot)
+}
+
+func (m *OffChainDKG) stateSnapshot() (*dkgtypes.StateSnapshot, error) {
+	snapshot := &dkgtypes.StateSnapshot{
+		Version:      dkgtypes.StateSnapshotVersion,
+		ChangeHeight: m.changeHeight,
+		RoundID:      m.roundCounter.Current(),
+		KeyPurpose:   m.keyPurpose,
+	}
+	var err error
+	if m.verifier != nil && !m.verifier.IsNil() {
+		if snapshot.Verifier, err = dkgtypes.NewVerifierSnapshot(m.verifier, m.verifierRoundID); err != nil {
+			return nil, fmt.Errorf("failed to export verifier: %v", err)
+		}
+	}
+	if m.nextVerifier != nil && !m.nextVerifier.IsNil() {
+		if snapshot.NextVerifier, err = dkgtypes.NewVerifierSnapshot(m.nextVerifier, m.nextVerifierRoundID); err != nil {
+			return nil, fmt.Errorf("failed to export next verifier: %v", err)
+		}
+	}
+	for roundID, dealer := range m.dkgRoundToDealer {
+		if dealer != nil {
+			snapshot.ActiveRounds = append(snapshot.ActiveRounds, roundID)
+		}
+	}
+	sort.Ints(snapshot.ActiveRounds)
+	return snapshot, nil
+}
+
+// RestoreState loads a state exported by ExportState. Verifiers are restored
+// without key shares, and rounds that were in progress are ignored.
+func (m *OffChainDKG) RestoreState(data []byte) error {
+	var snapshot dkgtypes.StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode DKG state: %v", err)
+	}
+	if snapshot.Version != dkgtypes.StateSnapshotVersion {
+		return fmt.Errorf("unsupported DKG state version %d", snapshot.Version)
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if err := m.restoreSnapshot(&snapshot); err != nil {
+		return err
+	}
+	// The node has no history of the rounds in progress and can not take part in them.
+	for _, roundID := range snapshot.ActiveRounds {
+		if roundID > m.lastEvictedRoundID {
+			m.lastEvictedRoundID = roundID
+		}
+	}
+
+	return nil
+}
+
+// restoreSnapshot loads the verifiers and the round counter of the snapshot,
+// which must be of the DKG's key.
+func (m *OffChainDKG) restoreSnapshot(snapshot *dkgtypes.StateSnapshot) error {
+	if snapshot.KeyPurpose != m.keyPurpose {
+		return fmt.Errorf("DKG state of key %q can't be restored into key %q", snapshot.KeyPurpose, m.keyPurpose)
+	}
+	if snapshot.Verifier != nil {
+		verifier, err := snapshot.Verifier.Verifier()
+		if err != nil {
+			return fmt.Errorf("failed to restore verifier: %v", err)
+		}
+		m.verifier, m.verifierRoundID = verifier, snapshot.Verifier.RoundID
+		m.instrumentVerifier(m.verifier, m.verifierRoundID)
+	}
+	if snapshot.NextVerifier != nil {
+		verifier, err := snapshot.NextVerifier.Verifier()
+		if err != nil {
+			return fmt.Errorf("failed to restore next verifier: %v", err)
+		}
+		m.nextVerifier, m.nextVerifierRoundID = verifier, snapshot.NextVerifier.RoundID
+		m.changeHeight = snapshot.ChangeHeight
+	}
+	if snapshot.RoundID > m.roundCounter.Current() {
+		if err := m.roundCounter.Advance(snapshot.RoundID); err != nil {
+			return fmt.Errorf("failed to restore round counter: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package offChain
+
+import (
+	"bytes"
+	"fmt"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkglib "github.com/corestario/dkglib/lib/dealer"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/alias"
+)
+
+var _ dkgtypes.RoundTrigger = &OffChainDKG{}
+
+// TriggerRound starts a round with the committee and threshold of the params
+// and returns its ID. Like scheduled rounds, a triggered round must be started
+// by every participant with the same params, e.g. when processing the block
+// that decided it; participants that started the round with another committee
+// abort it with a DesyncError, and with another threshold with a
+// ParamsMismatchError if the parameters are negotiated.
+func (m *OffChainDKG) TriggerRound(params dkgtypes.TriggerParams) (int, error) {
+	m.mtx.RLock()
+	validators := m.validators
+	m.mtx.RUnlock()
+	if len(params.Participants) == 0 && validators == nil {
+		return 0, fmt.Errorf("no committee given and no validator set known yet")
+	}
+	return m.startRoundWith(validators, params)
+}
+
+// roundParticipants returns the committee of a round started with the params,
+// or joined on a peer's message with empty ones. The threshold of the chain's parameters is set on the params if they have none.
+func (m *OffChainDKG) roundParticipants(roundID int, validators *alias.ValidatorSet, params *dkgtypes.TriggerParams, votes []*dkgalias.DKGData) (*dkgtypes.ParticipantSet, error) {
+	participants := dkgtypes.NewParticipantSetFromList(params.Participants)
+	if len(params.Participants) == 0 {
+		participants = m.newParticipantSet(m.limitCommittee(validators))
+	}
+	participants = m.filterParticipants(roundID, participants, votes)
+	if params.Threshold == 0 && m.chainParams != nil {
+		params.Threshold = m.chainParams.Threshold(participants.Size())
+	}
+	if err := dkglib.CheckRound(participants.Size(), params.Threshold, m.minValidators); err != nil {
+		return nil, err
+	}
+	return participants, nil
+}
+
+// joinsRound reports whether the node joins a round on the message, i.e. it
+// is the first one seen of a round the node didn't start yet.
+func (m *OffChainDKG) joinsRound(msg *dkgalias.DKGData) bool {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if msg.KeyPurpose != m.keyPurpose || msg.Type == dkgalias.DKGMigration || msg.Type == dkgalias.DKGAbort ||
+		m.paused || m.observedRounds[msg.RoundID] || msg.RoundID <= m.lastEvictedRoundID {
+		return false
+	}
+	_, ok := m.dkgRoundToDealer[msg.RoundID]
+	return !ok
+}
+
+// CommitteeMismatchError is returned when a round the node joined on a peer's
+// message is started with another committee than the one it was joined with.
+// The node keeps taking part in the round with the committee it joined it with.
+type CommitteeMismatchError struct {
+	RoundID int
+}
+
+func (e *CommitteeMismatchError) Error() string {
+	return fmt.Sprintf("round %d already started with another committee", e.RoundID)
+}
+
+// checkTriggered returns a *CommitteeMismatchError if the round was already
+// started with another committee than the one it was triggered with.
+func checkTriggered(roundID int, started, triggered *dkgtypes.ParticipantSet) error {
+	if started == nil || bytes.Equal(started.Hash(), triggered.Hash()) {
+		return nil
+	}
+	return &CommitteeMismatchError{RoundID: roundID}
+}
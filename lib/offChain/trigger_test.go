@@ -0,0 +1,87 @@
+package offChain
+
+import (
+	"testing"
+
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+type testParamsSource dkgtypes.DKGParams
+
+func (s *testParamsSource) DKGParams(int64) (*dkgtypes.DKGParams, error) {
+	params := dkgtypes.DKGParams(*s)
+	return &params, nil
+}
+
+func TestJoinedRoundCommittee(t *testing.T) {
+	cluster := newTestCluster(t, 5, WithParamsSource(&testParamsSource{CommitteeSize: 4}))
+	cluster.committee, cluster.lagging = 4, true
+	cluster.runRound(t, 1)
+	cluster.deliver()
+
+	var outside int
+	for i, node := range cluster.nodes {
+		committee, err := node.Committee(1)
+		if err != nil {
+			outside++
+			continue
+		}
+		if committee.Size() != 4 {
+			t.Fatalf("node %d: committee of %d, want 4", i, committee.Size())
+		}
+	}
+	if outside != 1 {
+		t.Fatalf("%d nodes outside the committee, want 1", outside)
+	}
+}
+
+func TestTriggerRoundWhileHandlingMessages(t *testing.T) {
+	cluster := newTestCluster(t, 4)
+	cluster.runRound(t, 1)
+
+	done := make(chan error)
+	go func() {
+		for _, node := range cluster.nodes {
+			if _, err := node.TriggerRound(dkgtypes.TriggerParams{}); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	for triggered := false; !triggered; {
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatal(err)
+			}
+			triggered = true
+		default:
+			cluster.deliver()
+		}
+	}
+	cluster.deliver()
+	for i, node := range cluster.nodes {
+		if _, err := node.GetRoundAttestation(2); err != nil {
+			t.Fatalf("node %d: %v", i, err)
+		}
+	}
+}
+
+func TestCheckTriggeredCommitteeMismatch(t *testing.T) {
+	cluster := newTestCluster(t, 4)
+	node := cluster.nodes[0]
+	all := node.newParticipantSet(cluster.validators)
+	fewer := dkgtypes.NewParticipantSetFromList(all.Participants()[1:])
+
+	if err := checkTriggered(1, all, all); err != nil {
+		t.Fatal(err)
+	}
+	err := checkTriggered(1, all, fewer)
+	if _, ok := err.(*CommitteeMismatchError); !ok {
+		t.Fatalf("want a CommitteeMismatchError, got %v", err)
+	}
+	if !skipRound(err) {
+		t.Fatal("a committee mismatch stops the node")
+	}
+}
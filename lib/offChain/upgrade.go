@@ -0,0 +1,244 @@
+package offChain
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"sort"
+
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	dkglib "github.com/corestario/dkglib/lib/dealer"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+)
+
+const roundSeedSize = 32
+
+var _ dkgtypes.Upgrader = &OffChainDKG{}
+
+// WithResumableRounds lets the rounds in progress survive a binary upgrade, see
+// ExportUpgradeState: the dealers draw their randomness from a seed kept for
+// the round, see Dealer.SetSeed, and the verified messages of every round are
+// kept in order, at most DefaultTranscriptSize per round. A round exceeding it
+// can't be resumed.
+func WithResumableRounds() DKGOption {
+	return func(d *OffChainDKG) { d.resumable = true }
+}
+
+// seedDealer sets the seed of the round's new dealer, which is picked unless
+// the round is being resumed, and returns it. Dealers are seeded for resumable
+// rounds and for WALs recording the seeds, see wal.WithSeedKey.
+func (m *OffChainDKG) seedDealer(roundID int, dealer dkglib.Dealer) []byte {
+	if !m.resumable && (m.wal == nil || !m.wal.RecordsSeeds()) {
+		return nil
+	}
+	seed, ok := m.roundSeeds[roundID]
+	if !ok {
+		seed = make([]byte, roundSeedSize)
+		if _, err := rand.Read(seed); err != nil {
+			m.Logger.Error("dkgState: failed to pick round seed, round can't be resumed or replayed", "round_id", roundID, "error", err)
+			return nil
+		}
+		if m.resumable {
+			m.roundSeeds[roundID] = seed
+		}
+	}
+	dealer.SetSeed(seed)
+	return seed
+}
+
+// recordJournal keeps the verified message of a resumable round.
+func (m *OffChainDKG) recordJournal(msg *dkgalias.DKGData) {
+	if _, ok := m.roundSeeds[msg.RoundID]; !ok || msg.Type == dkgalias.DKGRequestMissing {
+		return
+	}
+	if len(m.journals[msg.RoundID]) >= DefaultTranscriptSize {
+		m.Logger.Error("dkgState: round journal is full, round can't be resumed", "round_id", msg.RoundID)
+		m.forgetJournal(msg.RoundID)
+		return
+	}
+	m.journals[msg.RoundID] = append(m.journals[msg.RoundID], msg)
+}
+
+func (m *OffChainDKG) forgetJournal(roundID int) {
+	delete(m.roundSeeds, roundID)
+	delete(m.journals, roundID)
+}
+
+// ExportUpgradeState encodes the DKG state to carry across a binary upgrade,
+// see dkgtypes.UpgradeState; the node must not handle messages afterwards.
+// Only the rounds started with WithResumableRounds can be resumed; the node
+// sits out the other rounds in progress after the upgrade, as well as the
+// successful ones, whose verifiers are part of the state. Like ExportShare,
+// the request must be signed by the operators of the operation policy and name
+// the round of the verifier; the key shares and the round seeds are encrypted
+// with the passphrase.
+func (m *OffChainDKG) ExportUpgradeState(
+	req *dkgtypes.OperationRequest,
+	signatures []dkgtypes.OperatorSignature,
+	passphrase []byte,
+	options ...blsShare.KeystoreOption,
+) ([]byte, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	if err := m.authorizeOperation(dkgtypes.OperationExportUpgradeState, req, signatures); err != nil {
+		return nil, err
+	}
+	m.Logger.Info("dkgState: exporting upgrade state", "round_id", req.RoundID)
+
+	snapshot, err := m.stateSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	state := &dkgtypes.UpgradeState{State: snapshot, Height: m.lastHeight}
+	if snapshot.Verifier != nil {
+		if state.VerifierShare, err = dkgtypes.NewShareSnapshot(m.verifier); err != nil {
+			return nil, fmt.Errorf("failed to export verifier share: %v", err)
+		}
+	}
+	if snapshot.NextVerifier != nil {
+		if state.NextVerifierShare, err = dkgtypes.NewShareSnapshot(m.nextVerifier); err != nil {
+			return nil, fmt.Errorf("failed to export next verifier share: %v", err)
+		}
+	}
+	if m.staged != nil {
+		staged := *m.staged
+		state.Staged = &staged
+	}
+	for addr, migration := range m.migrations {
+		if addr == migration.OldAddr().String() {
+			state.Migrations = append(state.Migrations, migration)
+		}
+	}
+	sort.Slice(state.Migrations, func(i, j int) bool {
+		return bytes.Compare(state.Migrations[i].OldAddr(), state.Migrations[j].OldAddr()) < 0
+	})
+	for roundID, dealer := range m.dkgRoundToDealer {
+		seed, resumable := m.roundSeeds[roundID]
+		agreement := m.agreements[roundID]
+		if dealer == nil || !resumable || (agreement != nil && agreement.scheduled) {
+			state.InactiveRounds = append(state.InactiveRounds, roundID)
+			continue
+		}
+		state.Rounds = append(state.Rounds, &dkgtypes.RoundState{
+			RoundID:      roundID,
+			Participants: dealer.GetState().GetParticipants().Participants(),
+			Threshold:    m.roundThresholds[roundID],
+			Seed:         seed,
+			Messages:     m.journals[roundID],
+		})
+	}
+	sort.Ints(state.InactiveRounds)
+	sort.Slice(state.Rounds, func(i, j int) bool { return state.Rounds[i].RoundID < state.Rounds[j].RoundID })
+
+	if err := state.Seal(passphrase, options...); err != nil {
+		return nil, fmt.Errorf("failed to seal upgrade state: %v", err)
+	}
+	return dkgtypes.EncodeUpgradeState(state)
+}
+
+// RestoreUpgradeState loads a state exported by ExportUpgradeState, possibly by
+// an older version of the library, before the node handles any message; the
+// passphrase decrypts its secrets. The
+// rounds in progress are resumed by feeding their new dealers the recorded
+// messages; the messages the dealers send meanwhile were sent to the peers
+// before the upgrade and are only passed to the node, see MsgQueue.
+func (m *OffChainDKG) RestoreUpgradeState(data, passphrase []byte) error {
+	state, err := dkgtypes.DecodeUpgradeState(data)
+	if err != nil {
+		return err
+	}
+	if err := state.Unseal(passphrase); err != nil {
+		return err
+	}
+
+	m.mtx.Lock()
+	if len(m.dkgRoundToDealer) > 0 {
+		m.mtx.Unlock()
+		return fmt.Errorf("upgrade state must be restored before rounds start")
+	}
+	m.replaying = true
+	err = m.restoreUpgradeState(state)
+	m.mtx.Unlock()
+
+	if err == nil {
+		for _, round := range state.Rounds {
+			for _, msg := range round.Messages {
+				m.HandleOffChainShare(&dkgtypes.DKGDataMessage{Data: msg}, state.Height, nil, nil)
+			}
+			m.Logger.Info("dkgState: round resumed", "round_id", round.RoundID, "messages", len(round.Messages))
+		}
+	}
+
+	m.mtx.Lock()
+	m.replaying = false
+	m.mtx.Unlock()
+	return err
+}
+
+func (m *OffChainDKG) restoreUpgradeState(state *dkgtypes.UpgradeState) error {
+	if snapshot := state.State; snapshot != nil {
+		if err := m.restoreSnapshot(snapshot); err != nil {
+			return err
+		}
+		if snapshot.Verifier != nil && state.VerifierShare != nil {
+			verifier, err := snapshot.Verifier.SignerVerifier(state.VerifierShare)
+			if err != nil {
+				return fmt.Errorf("failed to restore verifier share: %v", err)
+			}
+			m.verifier = verifier
+			m.instrumentVerifier(m.verifier, m.verifierRoundID)
+		}
+		if snapshot.NextVerifier != nil && state.NextVerifierShare != nil {
+			verifier, err := snapshot.NextVerifier.SignerVerifier(state.NextVerifierShare)
+			if err != nil {
+				return fmt.Errorf("failed to restore next verifier share: %v", err)
+			}
+			m.nextVerifier = verifier
+		}
+	}
+	if state.Height > m.lastHeight {
+		m.lastHeight = state.Height
+	}
+	m.staged = state.Staged
+	for _, migration := range state.Migrations {
+		if err := migration.Verify(m.signDomain.ChainID); err != nil {
+			return fmt.Errorf("failed to restore share migration: %v", err)
+		}
+		m.migrations[migration.OldAddr().String()] = migration
+		m.migrations[migration.NewAddr().String()] = migration
+	}
+
+	resumed := make(map[int]bool)
+	for _, round := range state.Rounds {
+		resumed[round.RoundID] = true
+		m.roundSeeds[round.RoundID] = round.Seed
+		if round.Threshold > 0 {
+			m.roundThresholds[round.RoundID] = round.Threshold
+		}
+		// The journal holds the round's off-chain protocol messages as well,
+		// so RestoreUpgradeState feeds it through the node's handling.
+		start := *round
+		start.Messages = nil
+		dealer, err := dkglib.NewDealerFromTranscript(&start, m.privValidator,
+			dkglib.WithTranscriptLogger(m.Logger),
+			dkglib.WithTranscriptDealer(m.newDealer))
+		if err != nil {
+			return fmt.Errorf("failed to resume round %d: %v", round.RoundID, err)
+		}
+		m.addDealer(round.RoundID, dealer)
+	}
+	// States migrated from a StateSnapshot list the rounds in progress only
+	// as active ones, and can't resume them.
+	inactive := state.InactiveRounds
+	if state.State != nil {
+		inactive = append(inactive, state.State.ActiveRounds...)
+	}
+	for _, roundID := range inactive {
+		if !resumed[roundID] {
+			m.dkgRoundToDealer[roundID] = nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package offChain
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+func TestUpgradeStateIsAuthorizedAndSealed(t *testing.T) {
+	operator := ed25519.GenPrivKey()
+	policy, err := dkgtypes.NewCoSignPolicy(1, operator.PubKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := newTestCluster(t, 4, WithOperationPolicy(policy))
+	cluster.runRound(t, 1)
+	node := cluster.nodes[0]
+
+	req := &dkgtypes.OperationRequest{
+		Operation: dkgtypes.OperationExportUpgradeState,
+		RoundID:   1,
+		Nonce:     []byte("nonce"),
+		Expires:   time.Now().Add(time.Hour),
+	}
+	signature, err := operator.Sign(req.SignBytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	signatures := []dkgtypes.OperatorSignature{{PubKey: operator.PubKey(), Signature: signature}}
+	passphrase, kdf := []byte("passphrase"), blsShare.WithScrypt(1024, 8, 1)
+
+	if _, err := node.ExportUpgradeState(req, nil, passphrase, kdf); err == nil {
+		t.Fatal("exported the upgrade state without the operators' signatures")
+	}
+	data, err := node.ExportUpgradeState(req, signatures, passphrase, kdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	share, err := dkgtypes.NewShareSnapshot(node.Verifier())
+	if err != nil || share == nil {
+		t.Fatalf("node holds no share: %v", err)
+	}
+	if bytes.Contains(data, []byte(share.Priv)) {
+		t.Fatal("upgrade state holds the key share in the clear")
+	}
+
+	restore := func(passphrase []byte) (*OffChainDKG, error) {
+		evsw := events.NewEventSwitch()
+		restored := NewOffChainDKG(evsw, "", WithPVKey(node.GetPrivValidator()), WithLogger(log.NewNopLogger()))
+		return restored, restored.RestoreUpgradeState(data, passphrase)
+	}
+	if _, err := restore([]byte("wrong")); err == nil {
+		t.Fatal("restored the upgrade state with a wrong passphrase")
+	}
+	restored, err := restore(passphrase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := node.Verifier().Sign([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := restored.Verifier().Sign([]byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("restored verifier signs with another share")
+	}
+}
@@ -0,0 +1,82 @@
+package offChain
+
+import (
+	dkgalias "github.com/corestario/dkglib/lib/alias"
+	dkglib "github.com/corestario/dkglib/lib/dealer"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/alias"
+)
+
+// Validate checks the configuration, and the committee of the validators if
+// they are not nil, and returns a *dkgtypes.ConfigError listing every problem
+// found.
+func (m *OffChainDKG) Validate(validators *alias.ValidatorSet) error {
+	problems := &dkgtypes.ConfigError{}
+	if m.privValidator == nil {
+		problems.Add("no private validator; set it with WithPVKey")
+	}
+	if m.dkgNumBlocks < 0 {
+		problems.Add("round interval must be positive, got %d blocks", m.dkgNumBlocks)
+	}
+	if m.blocksAhead >= m.dkgNumBlocks {
+		problems.Add("blocks ahead (%d) must be less than the round interval (%d), or the next round starts before the key changes", m.blocksAhead, m.dkgNumBlocks)
+	}
+	if m.pipelineLead != 0 && (m.pipelineLead <= changeHeightAlign || m.pipelineLead >= m.dkgNumBlocks) {
+		problems.Add("pipelining lead (%d) must be within (%d, %d) blocks", m.pipelineLead, changeHeightAlign, m.dkgNumBlocks)
+	}
+	if m.signDomainErr != nil {
+		problems.Add("%v", m.signDomainErr)
+	}
+	if m.faultErr != nil {
+		problems.Add("fault injection: %v", m.faultErr)
+	}
+	if !dkgalias.ValidKeyPurpose(m.keyPurpose) {
+		problems.Add("invalid key purpose %q: up to 32 lowercase letters, digits, '_' and '-'", m.keyPurpose)
+	}
+	if m.keyPurpose != "" && m.signBytesVersion == dkgalias.SignBytesV1 {
+		problems.Add("key purpose %q requires sign bytes version %d or later", m.keyPurpose, dkgalias.SignBytesV2)
+	}
+	if m.maxHeightSkew < 0 {
+		problems.Add("max height skew must not be negative, got %d", m.maxHeightSkew)
+	}
+	if m.minValidators < 0 {
+		problems.Add("min validators must not be negative, got %d", m.minValidators)
+	}
+	if m.maxActiveRounds < 0 {
+		problems.Add("max active rounds must not be negative, got %d", m.maxActiveRounds)
+	}
+	if m.maxRoundAge < 0 {
+		problems.Add("max round age must not be negative, got %s", m.maxRoundAge)
+	}
+	if m.maxChunkSize < 0 {
+		problems.Add("max chunk size must not be negative, got %d", m.maxChunkSize)
+	}
+	if m.requireApproval && m.approvalTimeout < 0 {
+		problems.Add("approval timeout must not be negative, got %s", m.approvalTimeout)
+	}
+	if validators != nil {
+		m.validateCommittee(m.newParticipantSet(validators), problems)
+	}
+
+	return problems.Err()
+}
+
+// validateCommittee checks the threshold against the participants.
+func (m *OffChainDKG) validateCommittee(participants *dkgtypes.ParticipantSet, problems *dkgtypes.ConfigError) {
+	var (
+		size      = participants.Size()
+		threshold = dkglib.Threshold(size)
+		external  = len(participants.External())
+	)
+	if err := dkglib.CheckRound(size, 0, m.minValidators); err != nil {
+		problems.Add("%v", err)
+	}
+	if external >= threshold {
+		problems.Add("%d external participants reach the threshold of %d out of %d participants without any validator", external, threshold, size)
+	}
+	if m.privValidator != nil {
+		if _, own := participants.GetByAddress(m.privValidator.GetPubKey().Address()); own == nil {
+			problems.Add("the node's key %s is not a participant", m.privValidator.GetPubKey().Address())
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package onChain
+
+import (
+	gocontext "context"
+	"fmt"
+	"time"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/msgs"
+	"github.com/corestario/dkglib/lib/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var _ types.AnchorChain = &OnChainDKG{}
+
+// PublishEpochAnchor submits the attestation of a completed round to the chain.
+func (m *OnChainDKG) PublishEpochAnchor(attestation *types.RoundAttestation) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	msg := msgs.NewMsgDKGEpochAnchor(attestation, m.cli.GetFromAddress())
+	if err := msg.ValidateBasic(); err != nil {
+		return fmt.Errorf("failed to validate basic: %v", err)
+	}
+	return m.broadcastMsgs(m.cli.BroadcastMode, []sdk.Msg{msg})
+}
+
+// EpochAnchors queries the anchors of the round, encoded in JSON with the
+// codec of the context.
+func (m *OnChainDKG) EpochAnchors(roundID int) ([]*types.RoundAttestation, error) {
+	return queryEpochAnchors(m.cli, m.queryTimeout, roundID)
+}
+
+// EpochAnchors queries the anchors of the round, e.g. for a follower.Follower
+// on a full node.
+func (c *QueryClient) EpochAnchors(roundID int) ([]*types.RoundAttestation, error) {
+	return queryEpochAnchors(c.cli, c.queryTimeout, roundID)
+}
+
+func queryEpochAnchors(cli *context.Context, timeout time.Duration, roundID int) ([]*types.RoundAttestation, error) {
+	path := fmt.Sprintf("custom/randapp/epochAnchor/%d", roundID)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+
+	res, err := client.ABCIQuery(ctx, cli, path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for epoch anchors: %v", err)
+	}
+	if !res.Response.IsOK() {
+		return nil, fmt.Errorf("failed to query for epoch anchors: %s", res.Response.Log)
+	}
+	if len(res.Response.Value) == 0 {
+		return nil, nil
+	}
+
+	var anchors []msgs.MsgDKGEpochAnchor
+	if err := cli.Codec.UnmarshalJSON(res.Response.Value, &anchors); err != nil {
+		return nil, fmt.Errorf("failed to decode epoch anchors: %v", err)
+	}
+	out := make([]*types.RoundAttestation, 0, len(anchors))
+	for _, anchor := range anchors {
+		if anchor.Attestation != nil {
+			out = append(out, anchor.Attestation)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,126 @@
+package onChain
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+// DefaultPollInterval is the time between the blocks processed by a round
+// started with StartRoundAsync.
+const DefaultPollInterval = 3 * time.Second
+
+// ErrRoundCanceled is the error of a round canceled with RoundHandle.Cancel.
+var ErrRoundCanceled = errors.New("round canceled")
+
+// AsyncOption sets an optional parameter of StartRoundAsync.
+type AsyncOption func(*RoundHandle)
+
+// WithPollInterval sets the time between processed blocks, DefaultPollInterval
+// by default.
+func WithPollInterval(interval time.Duration) AsyncOption {
+	return func(h *RoundHandle) { h.interval = interval }
+}
+
+// RoundHandle follows a round started with StartRoundAsync.
+type RoundHandle struct {
+	dkg      *OnChainDKG
+	roundID  int
+	interval time.Duration
+
+	done     chan types.RoundResult
+	canceled chan struct{}
+	cancel   sync.Once
+
+	mtx sync.Mutex
+	err error
+}
+
+// StartRoundAsync starts the round like StartRound and processes a block every
+// poll interval in the background until the round succeeds, fails or is
+// canceled. The OnChainDKG must not be driven by other calls meanwhile.
+func (m *OnChainDKG) StartRoundAsync(
+	validators *tmtypes.ValidatorSet,
+	pv tmtypes.PrivValidator,
+	eventFirer events.Fireable,
+	logger logging.Logger,
+	startRound int,
+	options ...AsyncOption) (*RoundHandle, error) {
+	h := &RoundHandle{
+		dkg:      m,
+		roundID:  startRound,
+		interval: DefaultPollInterval,
+		done:     make(chan types.RoundResult, 1),
+		canceled: make(chan struct{}),
+	}
+	for _, option := range options {
+		option(h)
+	}
+	if err := m.StartRound(validators, pv, eventFirer, logger, startRound); err != nil {
+		return nil, err
+	}
+	go h.run()
+	return h, nil
+}
+
+func (h *RoundHandle) run() {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-h.canceled:
+			h.finish(types.RoundResultFailed, ErrRoundCanceled)
+			return
+		case <-ticker.C:
+			if err, ok := h.dkg.ProcessBlock(h.roundID); err != nil {
+				h.finish(types.RoundResultFailed, err)
+				return
+			} else if ok {
+				h.finish(types.RoundResultSuccess, nil)
+				return
+			}
+		}
+	}
+}
+
+func (h *RoundHandle) finish(result types.RoundResult, err error) {
+	h.mtx.Lock()
+	h.err = err
+	h.mtx.Unlock()
+	h.done <- result
+	close(h.done)
+}
+
+// RoundID returns the ID of the round.
+func (h *RoundHandle) RoundID() int { return h.roundID }
+
+// Done delivers the result of the round, success or failed, once it is over,
+// and is closed afterwards.
+func (h *RoundHandle) Done() <-chan types.RoundResult { return h.done }
+
+// Err returns the error that failed the round, ErrRoundCanceled if it was
+// canceled, or nil if it succeeded or is still in progress.
+func (h *RoundHandle) Err() error {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.err
+}
+
+// Cancel stops processing the round's blocks; the round fails with
+// ErrRoundCanceled unless it is already over.
+func (h *RoundHandle) Cancel() {
+	h.cancel.Do(func() { close(h.canceled) })
+}
+
+// Snapshot describes the progress of the round.
+func (h *RoundHandle) Snapshot() *types.RoundInfo {
+	if rounds := h.dkg.Snapshot(); len(rounds) > 0 {
+		return rounds[0]
+	}
+	return nil
+}
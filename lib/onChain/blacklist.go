@@ -0,0 +1,52 @@
+package onChain
+
+import (
+	gocontext "context"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/msgs"
+	"github.com/corestario/dkglib/lib/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+var _ types.BlacklistChain = &OnChainDKG{}
+
+// PublishBlacklistVote submits a vote signed by the off-chain participant to the chain.
+func (m *OnChainDKG) PublishBlacklistVote(vote *alias.DKGData) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	msg := msgs.NewMsgSendDKGData(vote, m.privValidator.GetPubKey(), m.cli.GetFromAddress())
+	if err := msg.ValidateBasic(); err != nil {
+		return fmt.Errorf("failed to validate basic: %v", err)
+	}
+	return m.broadcastMsgs(m.broadcastMode(alias.DKGBlacklist), []sdk.Msg{msg})
+}
+
+// BlacklistVotes queries the votes at the height rather than through the context,
+// which pins the height of its first query.
+func (m *OnChainDKG) BlacklistVotes(roundID int, height int64) ([]*alias.DKGData, error) {
+	path := fmt.Sprintf("custom/randapp/dkgData/%d/%d", alias.DKGBlacklist, roundID)
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), m.queryTimeout)
+	defer cancel()
+
+	res, err := client.ABCIQuery(ctx, m.cli, path, height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for blacklist votes: %v", err)
+	}
+	if !res.Response.IsOK() {
+		return nil, fmt.Errorf("failed to query for blacklist votes: %s", res.Response.Log)
+	}
+
+	messages, err := decodeDKGMessages(res.Response.Value)
+	if err != nil {
+		return nil, err
+	}
+	var votes []*alias.DKGData
+	for _, msg := range messages {
+		votes = append(votes, msg.Data)
+	}
+	return votes, nil
+}
@@ -0,0 +1,59 @@
+package onChain
+
+import (
+	gocontext "context"
+	"fmt"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// defaultBroadcastModes broadcasts the messages every participant waits for
+// synchronously, so CheckTx failures surface immediately, and bulk responses
+// and justifications asynchronously. Types not listed use the context's mode.
+func defaultBroadcastModes() map[alias.DKGDataType]string {
+	return map[alias.DKGDataType]string{
+		alias.DKGRegistration:  context.BroadcastSync,
+		alias.DKGCommits:       context.BroadcastSync,
+		alias.DKGDeal:          context.BroadcastSync,
+		alias.DKGResponse:      context.BroadcastAsync,
+		alias.DKGJustification: context.BroadcastAsync,
+		alias.DKGBlacklist:     context.BroadcastSync,
+	}
+}
+
+// WithBroadcastMode sets the broadcast mode (sync, async or block) of transactions
+// carrying messages of the given type.
+func WithBroadcastMode(dataType alias.DKGDataType, mode string) OnChainOption {
+	return func(d *OnChainDKG) { d.broadcastModes[dataType] = mode }
+}
+
+func (m *OnChainDKG) broadcastMode(dataType alias.DKGDataType) string {
+	if mode, ok := m.broadcastModes[dataType]; ok {
+		return mode
+	}
+	return m.cli.BroadcastMode
+}
+
+// broadcastTx broadcasts the transaction in the given mode and checks the result,
+// whose shape depends on the mode: async results carry only the tx hash, sync
+// results carry the CheckTx code and block results carry the DeliverTx code,
+// height and gas used. The broadcast is abandoned when the context is done.
+func (m *OnChainDKG) broadcastTx(ctx gocontext.Context, mode string, txBytes []byte) (sdk.TxResponse, error) {
+	res, err := client.BroadcastTx(ctx, m.cli, mode, txBytes)
+	switch {
+	case mode == context.BroadcastBlock && res.Code != 0:
+		// BroadcastTxCommit reports CheckTx and DeliverTx failures as errors
+		// along with a populated result.
+		return res, fmt.Errorf("tx %s failed at height %d: code %d: %s", res.TxHash, res.Height, res.Code, res.RawLog)
+	case err != nil:
+		return res, err
+	case mode == context.BroadcastSync && res.Code != 0:
+		return res, fmt.Errorf("tx %s failed CheckTx: code %d: %s", res.TxHash, res.Code, res.RawLog)
+	}
+
+	m.logger.Debug("on-chain DKG tx broadcast", "mode", mode, "hash", res.TxHash, "height", res.Height)
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package onChain
+
+import (
+	"bytes"
+	gocontext "context"
+	"fmt"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// WithCrossCheck queries the DKG messages from the witness, an RPC node
+// independent of the context's one, as well, at the height the context's node
+// answered at. Responses whose hashes differ fail the block's processing with
+// a DivergenceError, so a single compromised query endpoint can't feed the
+// dealer forged messages or withhold some. It is a stopgap until responses are
+// verified with light client proofs; a witness lagging behind the height fails
+// the query like an unreachable node.
+func WithCrossCheck(witness *context.Context) OnChainOption {
+	return func(d *OnChainDKG) { d.witness = witness }
+}
+
+// DivergenceError is returned when the witness of WithCrossCheck answers a
+// query differently from the context's node.
+type DivergenceError struct {
+	Path    string
+	Height  int64
+	Primary []byte // Hash of the context's node response.
+	Witness []byte // Hash of the witness response.
+}
+
+func (e *DivergenceError) Error() string {
+	return fmt.Sprintf("query %s at height %d diverges: node returned %X, witness returned %X", e.Path, e.Height, e.Primary, e.Witness)
+}
+
+// crossCheck compares the response of the context's node to the path at the
+// height with the witness's one.
+func (m *OnChainDKG) crossCheck(ctx gocontext.Context, path string, height int64, res []byte) error {
+	if m.witness == nil {
+		return nil
+	}
+	witnessRes, err := client.ABCIQuery(ctx, m.witness, path, height)
+	if err != nil {
+		return fmt.Errorf("failed to query witness: %v", err)
+	}
+	if !witnessRes.Response.IsOK() {
+		return fmt.Errorf("failed to query witness: %s", witnessRes.Response.Log)
+	}
+
+	primary, witness := tmhash.Sum(res), tmhash.Sum(witnessRes.Response.Value)
+	if bytes.Equal(primary, witness) {
+		return nil
+	}
+	err = &DivergenceError{Path: path, Height: height, Primary: primary, Witness: witness}
+	m.metrics.QueryDivergences.Add(1)
+	m.logger.Error("on-chain DKG: query endpoints diverge", "path", path, "height", height, "node", fmt.Sprintf("%X", primary), "witness", fmt.Sprintf("%X", witness))
+	return err
+}
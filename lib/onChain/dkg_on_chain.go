@@ -2,15 +2,21 @@ package onChain
 
 import (
 	"bytes"
+	gocontext "context"
 	"encoding/gob"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	authtxb "github.com/corestario/cosmos-utils/client/authtypes"
 	"github.com/corestario/cosmos-utils/client/context"
-	"github.com/corestario/cosmos-utils/client/utils"
 	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/client"
 	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/metrics"
 	"github.com/corestario/dkglib/lib/msgs"
 	"github.com/corestario/dkglib/lib/types"
 	"github.com/cosmos/cosmos-sdk/client/keys"
@@ -22,20 +28,220 @@ import (
 )
 
 type OnChainDKG struct {
+	mtx sync.Mutex // Guards the dealer and the round result against concurrent snapshots.
+
 	cli             *context.Context
 	txBldr          *authtxb.TxBuilder
 	dealer          dealer.Dealer
+	privValidator   tmtypes.PrivValidator
 	typesList       []alias.DKGDataType
-	logger          log.Logger
-	lastAccSequence int
+	roundResult     types.RoundResult
+	logger          logging.Logger
+	nextAccSequence uint64 // Sequence after the last broadcast tx, which may not be committed yet.
+
+	maxChunkSize       int
+	maxReassembledSize int
+	roundCounter       *types.RoundCounter
+
+	metrics               *metrics.Metrics
+	confirmationThreshold time.Duration
+	pending               map[string]time.Time // Broadcast times of messages not yet seen on chain.
+	confirmed             map[string]bool      // Dedup keys of own messages seen on chain.
+	verified              map[string]bool      // Messages with a valid signature, keyed by dedup key and signature.
+
+	gasAdjuster    *GasAdjuster
+	external       []*types.Participant
+	minValidators  int
+	broadcastModes map[alias.DKGDataType]string
+	middlewares    []dealer.Middleware
+	taps           dealer.EventTaps
+
+	checkInvariants bool
+	verifyWorkers   int
+	proofHook       types.ProofHook
+
+	staggerWindow int
+	staggerOffset int          // Blocks the node holds its messages for, see WithBroadcastStagger.
+	roundBlocks   int          // Blocks processed since the round started.
+	staged        []stagedMsgs // Messages held until their block, in the order of sending.
+
+	incremental bool
+	watermarks  map[alias.DKGDataType]int64 // Heights up to which messages of the round have been ingested, by type.
+	roundChunks *alias.ChunkBuffer          // Chunks of the round pending reassembly across blocks.
+	roundSeen   map[string]bool             // Dedup keys of the round's ingested messages.
+
+	quarantine  map[string]bool // Evidence hashes of the round's quarantined messages.
+	quarantined []*QuarantinedMessage
+
+	queryTimeout     time.Duration
+	broadcastTimeout time.Duration
+
+	feePayer          *client.FeePayer
+	nextPayerSequence uint64 // Payer's sequence after the last broadcast tx.
+
+	accRetriever *client.CachedAccountRetriever
+	witness      *context.Context // Node the queries are cross-checked with, see WithCrossCheck.
+
+	signDomain alias.SignDomain // See WithSignDomain.
+
+	broadcastMtx    sync.Mutex // Guards the tx builder and the sequences.
+	reports         chan *types.MisbehaviorReport
+	startReports    sync.Once
+	stopReports     chan struct{}
+	stopOnce        sync.Once
+	committees      map[int]*types.ParticipantSet // Of the last rounds, by round ID, see participantKey.
+	committeeLookup func(roundID int) (*types.ParticipantSet, error)
 }
 
-func NewOnChainDKG(cli *context.Context, txBldr *authtxb.TxBuilder) *OnChainDKG {
-	return &OnChainDKG{
-		cli:    cli,
-		txBldr: txBldr,
-		logger: log.NewTMLogger(os.Stdout),
+var _ types.MisbehaviorSink = &OnChainDKG{}
+
+func NewOnChainDKG(cli *context.Context, txBldr *authtxb.TxBuilder, options ...OnChainOption) *OnChainDKG {
+	dkg := &OnChainDKG{
+		cli:                cli,
+		txBldr:             txBldr,
+		logger:             logging.NewTMLogger(log.NewTMLogger(os.Stdout)),
+		maxReassembledSize: alias.DefaultMaxReassembledSize,
+		metrics:            metrics.NopMetrics(),
+		pending:            make(map[string]time.Time),
+		confirmed:          make(map[string]bool),
+		verified:           make(map[string]bool),
+		quarantine:         make(map[string]bool),
+		broadcastModes:     defaultBroadcastModes(),
+		queryTimeout:       client.DefaultQueryTimeout,
+		broadcastTimeout:   client.DefaultBroadcastTimeout,
+		signDomain:         alias.DefaultSignDomain(""),
+		reports:            make(chan *types.MisbehaviorReport, DefaultReportQueueSize),
+		stopReports:        make(chan struct{}),
+		committees:         make(map[int]*types.ParticipantSet),
+	}
+
+	for _, option := range options {
+		option(dkg)
+	}
+	dkg.logger = logging.WithCorrelation(dkg.logger, types.CorrelationKey, types.RoundCorrelationID)
+	if dkg.accRetriever == nil {
+		querier := client.TimeoutQuerier{Context: cli, Timeout: dkg.broadcastTimeout}
+		dkg.accRetriever = client.NewCachedAccountRetriever(authTypes.NewAccountRetriever(querier), client.DefaultAccountCacheTTL)
 	}
+	dkg.resetWatermarks()
+
+	return dkg
+}
+
+// OnChainOption sets an optional parameter on the OnChainDKG.
+type OnChainOption func(*OnChainDKG)
+
+// WithMaxChunkSize splits messages with Data larger than maxChunkSize into chunks
+// to fit into the chain's transaction size limit; zero disables chunking.
+func WithMaxChunkSize(maxChunkSize int) OnChainOption {
+	return func(d *OnChainDKG) { d.maxChunkSize = maxChunkSize }
+}
+
+// WithRoundCounter validates round IDs passed to StartRound against the counter.
+func WithRoundCounter(counter *types.RoundCounter) OnChainOption {
+	return func(d *OnChainDKG) { d.roundCounter = counter }
+}
+
+func WithMetrics(m *metrics.Metrics) OnChainOption {
+	return func(d *OnChainDKG) { d.metrics = m }
+}
+
+// WithConfirmationThreshold logs a warning for every message confirmed later than the threshold.
+func WithConfirmationThreshold(threshold time.Duration) OnChainOption {
+	return func(d *OnChainDKG) { d.confirmationThreshold = threshold }
+}
+
+// WithAdaptiveGas simulates every transaction and applies the adjuster's multiplier to the estimate.
+func WithAdaptiveGas(adjuster *GasAdjuster) OnChainOption {
+	return func(d *OnChainDKG) { d.gasAdjuster = adjuster }
+}
+
+// WithExternalParticipants adds non-validator participants to the round.
+func WithExternalParticipants(participants ...*types.Participant) OnChainOption {
+	return func(d *OnChainDKG) { d.external = participants }
+}
+
+// WithMinValidators sets the smallest committee a round is started with,
+// dealer.MinParticipants by default.
+func WithMinValidators(minValidators int) OnChainOption {
+	return func(d *OnChainDKG) { d.minValidators = minValidators }
+}
+
+// WithMiddleware wraps the dealer handlers of messages fetched from the chain
+// into the middlewares, the first one being the outermost.
+func WithMiddleware(middlewares ...dealer.Middleware) OnChainOption {
+	return func(d *OnChainDKG) { d.middlewares = append(d.middlewares, middlewares...) }
+}
+
+// WithEventTaps sets the callbacks notified of the progress of every round.
+func WithEventTaps(taps dealer.EventTaps) OnChainOption {
+	return func(d *OnChainDKG) { d.taps = taps }
+}
+
+// WithInvariantChecks validates the dealer invariants after every handled
+// message and panics with a dump of the dealer's state on violation. It is a
+// debugging aid, not meant for production.
+func WithInvariantChecks() OnChainOption {
+	return func(d *OnChainDKG) { d.checkInvariants = true }
+}
+
+// WithVerifyConcurrency sets the number of workers verifying the signatures of
+// the messages fetched from the chain; zero, the default, uses GOMAXPROCS
+// workers. The time the messages wait for a worker is recorded in the
+// VerifyQueueWaitSeconds metric.
+func WithVerifyConcurrency(workers int) OnChainOption {
+	return func(d *OnChainDKG) { d.verifyWorkers = workers }
+}
+
+// WithProofHook sets the hook generating external proofs of every round from
+// the dealer's commitment matrix, see types.ProofHook. The proofs are part of
+// the round's snapshot.
+func WithProofHook(hook types.ProofHook) OnChainOption {
+	return func(d *OnChainDKG) { d.proofHook = hook }
+}
+
+// WithQueryTimeout bounds every query to the node, so a hung node fails the
+// query instead of stalling the round loop.
+func WithQueryTimeout(timeout time.Duration) OnChainOption {
+	return func(d *OnChainDKG) { d.queryTimeout = timeout }
+}
+
+// WithBroadcastTimeout bounds every broadcast, including the simulation and
+// account queries preceding it.
+func WithBroadcastTimeout(timeout time.Duration) OnChainOption {
+	return func(d *OnChainDKG) { d.broadcastTimeout = timeout }
+}
+
+// WithAccountRetriever retrieves the account numbers and sequences of the
+// broadcast transactions with the retriever, e.g. one shared with the code
+// that created the TxBuilder. By default accounts are queried from the node
+// and cached for client.DefaultAccountCacheTTL.
+func WithAccountRetriever(retriever *client.CachedAccountRetriever) OnChainOption {
+	return func(d *OnChainDKG) { d.accRetriever = retriever }
+}
+
+// WithSignDomain sets the domain the messages are signed and verified in, by
+// default the empty chain ID with the latest version; see alias.NewSignDomain.
+func WithSignDomain(domain alias.SignDomain) OnChainOption {
+	return func(d *OnChainDKG) { d.signDomain = domain }
+}
+
+// WithReportQueueSize sets the number of misbehavior reports awaiting
+// submission, DefaultReportQueueSize by default. Reports beyond it are dropped.
+func WithReportQueueSize(size int) OnChainOption {
+	return func(d *OnChainDKG) { d.reports = make(chan *types.MisbehaviorReport, size) }
+}
+
+// WithCommitteeLookup sets how the committees of the reported rounds the
+// on-chain DKG didn't run are looked up, e.g. OffChainDKG.RoundCommittee when
+// it is the off-chain DKG's misbehavior sink.
+func WithCommitteeLookup(lookup func(roundID int) (*types.ParticipantSet, error)) OnChainOption {
+	return func(d *OnChainDKG) { d.committeeLookup = lookup }
+}
+
+// WithMaxReassembledSize limits the size of a message reassembled from chunks.
+func WithMaxReassembledSize(maxSize int) OnChainOption {
+	return func(d *OnChainDKG) { d.maxReassembledSize = maxSize }
 }
 
 func (m *OnChainDKG) GetVerifier() (types.Verifier, error) {
@@ -43,35 +249,82 @@ func (m *OnChainDKG) GetVerifier() (types.Verifier, error) {
 }
 
 func (m *OnChainDKG) ProcessBlock(roundID int) (error, bool) {
-	for _, dataType := range []alias.DKGDataType{
-		alias.DKGPubKey,
-		alias.DKGCommits,
-		alias.DKGDeal,
-		alias.DKGResponse,
-	} {
-		messages, err := m.getDKGMessages(dataType, roundID)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	m.roundBlocks++
+	err, ok := m.processBlock(roundID)
+	if err == nil {
+		if flushErr := m.flushStaged(); flushErr != nil {
+			err = fmt.Errorf("failed to broadcast staged messages: %v", flushErr)
+		}
+	}
+	if m.roundResult == types.RoundResultInProgress && (err != nil || ok) {
+		m.metrics.ObserveRound(m.dealer.Snapshot())
+	}
+	if err != nil {
+		m.roundResult = types.RoundResultFailed
+	} else if ok {
+		m.roundResult = types.RoundResultSuccess
+	}
+	return types.NewRoundError(roundID, err), ok
+}
+
+func (m *OnChainDKG) processBlock(roundID int) (error, bool) {
+	// Without incremental fetching all messages of the round are fetched on
+	// every block, so chunks are reassembled from scratch.
+	chunks := alias.NewChunkBuffer(m.maxReassembledSize)
+	seen := make(map[string]bool)
+	if m.incremental {
+		chunks, seen = m.roundChunks, m.roundSeen
+	}
+	for _, dataType := range roundDataTypes {
+		messages, height, err := m.fetchDKGMessages(dataType, roundID)
 		if err != nil {
 			return fmt.Errorf("failed to getDKGMessages: %v", err), false
 		}
-		var handler func(msg *alias.DKGData) error
-		switch dataType {
-		case alias.DKGPubKey:
-			handler = m.dealer.HandleDKGPubKey
-		case alias.DKGCommits:
-			handler = m.dealer.HandleDKGCommit
-		case alias.DKGDeal:
-			handler = m.dealer.HandleDKGDeal
-		case alias.DKGResponse:
-			handler = m.dealer.HandleDKGResponse
-		}
-		for _, msg := range messages {
-			if err := handler(msg.Data); err != nil {
-				return fmt.Errorf("failed to handle message: %v", err), false
+		middlewares := m.middlewares
+		if m.checkInvariants {
+			middlewares = append(append([]dealer.Middleware(nil), middlewares...), dealer.InvariantMiddleware(m.dealer, m.logger))
+		}
+		handler := dealer.Chain(dealer.Handler(m.dealer, dataType), middlewares...)
+		for _, msg := range m.verifyMessages(messages, seen) {
+			if msg.Owner.Equals(m.cli.GetFromAddress()) {
+				m.observeConfirmation(dedupKey(*msg), msg.Data)
+			}
+			if m.isQuarantined(msg.Data) {
+				continue
+			}
+			data, err := chunks.Add(msg.Data)
+			if err != nil {
+				// A chunk that doesn't fit the others is as invalid on every node.
+				if err := m.quarantineMessage(msg, msg.Data, fmt.Errorf("failed to reassemble message: %v", err)); err != nil {
+					return err, false
+				}
+				continue
+			}
+			if data == nil || m.isQuarantined(data) {
+				continue
+			}
+			excluded := m.isExcluded(data.Addr)
+			if err := handler(data); err != nil {
+				if m.classifyHandlerError(data, err, excluded) == handlerErrorFatal {
+					return fmt.Errorf("failed to handle message: %v", err), false
+				}
+				if err := m.quarantineMessage(msg, data, err); err != nil {
+					return err, false
+				}
 			}
 		}
+		m.advanceWatermark(dataType, height)
 	}
 
 	if _, err := m.dealer.GetVerifier(); err == types.ErrDKGVerifierNotReady {
+		progress := m.dealer.Progress()
+		m.metrics.ObserveProgress(progress)
+		if waiting := types.Waiting(progress); waiting != nil {
+			m.logger.Debug("on-chain DKG: verifier not ready", "round_id", roundID, "progress", waiting.String())
+		}
 		return nil, false
 	} else if err != nil {
 		return fmt.Errorf("DKG round failed: %v", err), false
@@ -84,9 +337,40 @@ func (m *OnChainDKG) StartRound(
 	validators *tmtypes.ValidatorSet,
 	pv tmtypes.PrivValidator,
 	eventFirer events.Fireable,
-	logger log.Logger,
+	logger logging.Logger,
 	startRound int) error {
-	m.dealer = dealer.NewOnChainDKGDealer(validators, pv, m.sendMsg, eventFirer, logger, startRound)
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	participants := types.NewParticipantSet(validators, m.external)
+	if err := dealer.CheckRound(participants.Size(), 0, m.minValidators); err != nil {
+		return err
+	}
+	if m.roundCounter != nil {
+		if err := m.roundCounter.Advance(startRound); err != nil {
+			return fmt.Errorf("invalid start round: %v", err)
+		}
+	}
+	m.pending = make(map[string]time.Time)
+	m.confirmed = make(map[string]bool)
+	m.verified = make(map[string]bool)
+	m.quarantine = make(map[string]bool)
+	m.quarantined = nil
+	m.resetWatermarks()
+	m.privValidator = pv
+	m.staggerOffset = staggerOffset(participants, pv.GetPubKey().Address(), m.staggerWindow)
+	m.roundBlocks = 0
+	m.staged = nil
+	logger = logging.WithCorrelation(logger, types.CorrelationKey, types.RoundCorrelationID)
+	m.dealer = dealer.NewOnChainDKGDealer(participants, pv, m.sendMsg, eventFirer, logger, startRound)
+	m.recordCommittee(startRound, participants)
+	m.dealer.SetSignDomain(m.signDomain)
+	m.dealer.SetEventTaps(m.dealerTaps())
+	m.dealer.SetVerifyConcurrency(m.verifyWorkers)
+	if m.proofHook != nil {
+		m.dealer.SetProofHook(m.proofHook)
+	}
+	m.roundResult = types.RoundResultInProgress
 	if err := m.dealer.Start(); err != nil {
 		m.logger.Debug("Start on-chain dkg")
 		return fmt.Errorf("failed to start dealer: %v", err)
@@ -95,21 +379,175 @@ func (m *OnChainDKG) StartRound(
 	return nil
 }
 
+// Snapshot describes the current on-chain round, if any.
+func (m *OnChainDKG) Snapshot() []*types.RoundInfo {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.dealer == nil {
+		return nil
+	}
+	info := m.dealer.Snapshot()
+	info.OnChain = true
+	info.Result = m.roundResult
+	return []*types.RoundInfo{info}
+}
+
 func (m *OnChainDKG) GetLosers() []*tmtypes.Validator {
 	return m.dealer.GetLosers()
 }
 
-func (m *OnChainDKG) sendMsg(data []*alias.DKGData) error {
-	var messages []sdk.Msg
-	for _, item := range data {
-		item := item
-		msg := msgs.NewMsgSendDKGData(item, m.cli.GetFromAddress())
-		if err := msg.ValidateBasic(); err != nil {
-			return fmt.Errorf("failed to validate basic: %v", err)
+func (m *OnChainDKG) GetLosersWithReasons() []*types.Loser {
+	return m.dealer.GetLosersWithReasons()
+}
+
+// ApplyAdjudication applies externally adjudicated exclusions to the dealer of
+// the current round, see dealer.Dealer.ApplyAdjudication.
+func (m *OnChainDKG) ApplyAdjudication(roundID int, decisions []types.Adjudication) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.dealer == nil {
+		return fmt.Errorf("no on-chain round in progress")
+	}
+	return m.dealer.ApplyAdjudication(roundID, decisions)
+}
+
+func (m *OnChainDKG) broadcastData(data []*alias.DKGData) error {
+	// Messages are batched into one transaction per broadcast mode.
+	var (
+		modes    []string
+		messages = make(map[string][]sdk.Msg)
+	)
+	for _, v := range data {
+		mode := m.broadcastMode(v.Type)
+		if _, ok := messages[mode]; !ok {
+			modes = append(modes, mode)
+		}
+		for _, item := range alias.SplitDKGData(v, m.maxChunkSize) {
+			if err := m.privValidator.SignData(m.signDomain.ChainID, m.signDomain.Signer(item)); err != nil {
+				return fmt.Errorf("failed to sign data: %v", err)
+			}
+			msg := msgs.NewMsgSendDKGData(item, m.privValidator.GetPubKey(), m.cli.GetFromAddress())
+			if m.confirmed[dedupKey(msg)] {
+				m.logger.Debug("on-chain DKG message already confirmed, skipping", "type", item.Type, "round_id", item.RoundID,
+					types.MessageCorrelationKey, types.MessageCorrelationID(item))
+				continue
+			}
+			if err := msg.ValidateBasic(); err != nil {
+				return fmt.Errorf("failed to validate basic: %v", err)
+			}
+			messages[mode] = append(messages[mode], msg)
+		}
+	}
+
+	for _, mode := range modes {
+		if len(messages[mode]) == 0 {
+			continue
+		}
+		if err := m.broadcastMsgs(mode, messages[mode]); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, msg := range messages[mode] {
+			m.pending[dedupKey(msg.(msgs.MsgSendDKGData))] = now
+		}
+	}
+
+	return nil
+}
+
+// dealerTaps adds the recording of the verification queue wait to the taps.
+func (m *OnChainDKG) dealerTaps() dealer.EventTaps {
+	taps := m.taps
+	taps.OnVerifyWait = func(wait time.Duration) {
+		m.metrics.VerifyQueueWaitSeconds.Observe(wait.Seconds())
+		if m.taps.OnVerifyWait != nil {
+			m.taps.OnVerifyWait(wait)
+		}
+	}
+	return taps
+}
+
+// verifyMessages drops duplicates and messages with invalid signatures. Signatures
+// are verified in parallel, and only once per round since all messages of the
+// round are fetched on every block.
+func (m *OnChainDKG) verifyMessages(messages []*msgs.MsgSendDKGData, seen map[string]bool) []*msgs.MsgSendDKGData {
+	var (
+		unique     []*msgs.MsgSendDKGData
+		unverified []*alias.DKGData
+		indices    []int
+	)
+	for _, msg := range messages {
+		key := dedupKey(*msg)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		if !m.verified[verifiedKey(msg)] {
+			unverified = append(unverified, msg.Data)
+			indices = append(indices, len(unique))
+		}
+		unique = append(unique, msg)
+	}
+	if len(unverified) == 0 {
+		return unique
+	}
+
+	invalid := make(map[int]bool)
+	for i, err := range m.dealer.VerifyMessages(unverified) {
+		if err != nil {
+			m.logger.Info("on-chain DKG: can't verify message", "type", unverified[i].Type, "round_id", unverified[i].RoundID,
+				types.MessageCorrelationKey, types.MessageCorrelationID(unverified[i]), "from", unverified[i].GetAddrString(), "error", err)
+			invalid[indices[i]] = true
+			continue
+		}
+		m.verified[verifiedKey(unique[indices[i]])] = true
+	}
+	var out []*msgs.MsgSendDKGData
+	for i, msg := range unique {
+		if !invalid[i] {
+			out = append(out, msg)
 		}
-		messages = append(messages, msg)
 	}
 
+	return out
+}
+
+func verifiedKey(msg *msgs.MsgSendDKGData) string {
+	return dedupKey(*msg) + hex.EncodeToString(msg.Data.Signature)
+}
+
+func (m *OnChainDKG) observeConfirmation(key string, data *alias.DKGData) {
+	m.confirmed[key] = true
+	sent, ok := m.pending[key]
+	if !ok {
+		return
+	}
+	delete(m.pending, key)
+
+	latency := time.Since(sent)
+	m.metrics.TxConfirmationSeconds.With("type", data.Type.String()).Observe(latency.Seconds())
+	if m.confirmationThreshold > 0 && latency > m.confirmationThreshold {
+		m.logger.Error("on-chain DKG message confirmation is slow", "type", data.Type,
+			"round_id", data.RoundID, "latency", latency, "threshold", m.confirmationThreshold)
+	}
+}
+
+// dedupKey falls back to computing the key for messages sent without one.
+func dedupKey(msg msgs.MsgSendDKGData) string {
+	if len(msg.DedupKey) != 0 {
+		return hex.EncodeToString(msg.DedupKey)
+	}
+	return hex.EncodeToString(msgs.DedupKey(msg.Data))
+}
+
+func (m *OnChainDKG) broadcastMsgs(mode string, messages []sdk.Msg) error {
+	// Reports are broadcast from their queue, next to the round's messages.
+	m.broadcastMtx.Lock()
+	defer m.broadcastMtx.Unlock()
+
 	kb, err := keys.NewKeyBaseFromDir(m.cli.Home)
 	if err != nil {
 		m.logger.Error("on-chain DKG send msg error", "function", "NewKeyBaseFromDir", "error", err)
@@ -126,30 +564,107 @@ func (m *OnChainDKG) sendMsg(data []*alias.DKGData) error {
 		return err
 	}
 
-	accRetriever := authTypes.NewAccountRetriever(m.cli)
-	_, accSequence, err := accRetriever.GetAccountNumberSequence(keysList[0].GetAddress())
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), m.broadcastTimeout)
+	defer cancel()
+
+	_, accSequence, err := m.accRetriever.GetAccountNumberSequence(keysList[0].GetAddress())
 	if err != nil {
 		m.logger.Error("on-chain DKG send msg error", "function", "GetAccountNumberSequence", "error", err)
 		return err
 	}
 
+	// Txs broadcast in sync or async mode may still be in the mempool.
+	if m.nextAccSequence > accSequence {
+		accSequence = m.nextAccSequence
+	}
 	tmpTxBldr := m.txBldr.WithSequence(accSequence)
 	m.txBldr = &tmpTxBldr
 
-	err = utils.GenerateOrBroadcastMsgs(*m.cli, *m.txBldr, messages, false)
+	messages, payFees := m.payFees(messages)
+	var payerAccNumber, payerSequence uint64
+	if payFees {
+		payerAccNumber, payerSequence, err = m.accRetriever.GetAccountNumberSequence(m.feePayer.Address)
+		if err != nil {
+			m.logger.Error("on-chain DKG send msg error", "function", "GetAccountNumberSequence", "fee_payer", m.feePayer.Address, "error", err)
+			return err
+		}
+		if m.nextPayerSequence > payerSequence {
+			payerSequence = m.nextPayerSequence
+		}
+	}
+
+	txBldr := *m.txBldr
+	var gasEstimate uint64
+	if m.gasAdjuster != nil {
+		if txBldr, gasEstimate, err = m.gasAdjuster.Enrich(ctx, txBldr, m.cli, messages); err != nil {
+			m.logger.Error("on-chain DKG send msg error", "function", "Enrich", "error", err)
+			return err
+		}
+	}
+
+	var txBytes []byte
+	if payFees {
+		owner := client.Signer{Name: m.cli.GetFromName(), Passphrase: m.cli.Passphrase}
+		if m.cli.PrivKey != nil && len(m.cli.PrivKey.Bytes()) != 0 {
+			owner.PrivKey = m.cli.PrivKey
+		}
+		txBytes, err = client.BuildAndSignWithFeePayer(txBldr, messages, owner, m.feePayer, payerAccNumber, payerSequence)
+	} else if m.cli.PrivKey != nil && len(m.cli.PrivKey.Bytes()) != 0 {
+		txBytes, err = txBldr.BuildAndSignWithPrivKey(m.cli.PrivKey, messages)
+	} else {
+		txBytes, err = txBldr.BuildAndSign(m.cli.GetFromName(), m.cli.Passphrase, messages)
+	}
 	if err != nil {
+		return fmt.Errorf("failed to sign tx: %v", err)
+	}
+
+	res, err := m.broadcastTx(ctx, mode, txBytes)
+	if m.gasAdjuster != nil {
+		m.gasAdjuster.Update(gasEstimate, res)
+	}
+	m.accRetriever.Broadcasted(keysList[0].GetAddress(), accSequence, err)
+	if payFees {
+		m.accRetriever.Broadcasted(m.feePayer.Address, payerSequence, err)
+	}
+	if err != nil {
+		// The sequence is refetched from the chain on the next broadcast.
+		m.nextAccSequence, m.nextPayerSequence = 0, 0
 		return fmt.Errorf("failed to broadcast msg: %v", err)
 	}
+	m.nextAccSequence = accSequence + 1
+	if payFees {
+		m.nextPayerSequence = payerSequence + 1
+	}
 
 	return nil
 }
 
-func (m *OnChainDKG) getDKGMessages(dataType alias.DKGDataType, roundID int) ([]*msgs.MsgSendDKGData, error) {
-	res, _, err := m.cli.QueryWithData(fmt.Sprintf("custom/randapp/dkgData/%d/%d", dataType, roundID), nil)
+// fetchDKGMessages returns the messages of the type included in the round, or
+// only those included after the type's watermark with incremental fetching,
+// along with the height of the query.
+func (m *OnChainDKG) fetchDKGMessages(dataType alias.DKGDataType, roundID int) ([]*msgs.MsgSendDKGData, int64, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), m.queryTimeout)
+	defer cancel()
+
+	path := fmt.Sprintf("custom/randapp/dkgData/%d/%d", dataType, roundID)
+	if m.incremental {
+		path = fmt.Sprintf("custom/randapp/dkgDataSince/%d/%d/%d", dataType, roundID, m.watermarks[dataType])
+	}
+	res, height, err := client.QueryWithData(ctx, m.cli, path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query for DKG data: %v", err)
+		return nil, 0, fmt.Errorf("failed to query for DKG data: %v", err)
 	}
+	if err := m.crossCheck(ctx, path, height, res); err != nil {
+		return nil, 0, err
+	}
+	messages, err := decodeDKGMessages(res)
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, height, nil
+}
 
+func decodeDKGMessages(res []byte) ([]*msgs.MsgSendDKGData, error) {
 	var data []*msgs.MsgSendDKGData
 	var dec = gob.NewDecoder(bytes.NewBuffer(res))
 	if err := dec.Decode(&data); err != nil {
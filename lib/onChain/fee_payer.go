@@ -0,0 +1,38 @@
+package onChain
+
+import (
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/msgs"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// WithFeePayer makes the payer co-sign every transaction and pay its fees, so
+// the operational account of the validator needs no balance.
+func WithFeePayer(payer *client.FeePayer) OnChainOption {
+	return func(d *OnChainDKG) { d.feePayer = payer }
+}
+
+// payFees sets the fee payer on the messages; it reports false if there is no
+// payer distinct from the owner.
+func (m *OnChainDKG) payFees(messages []sdk.Msg) ([]sdk.Msg, bool) {
+	if m.feePayer == nil || m.feePayer.Address.Equals(m.cli.GetFromAddress()) {
+		return messages, false
+	}
+	out := make([]sdk.Msg, 0, len(messages))
+	for _, msg := range messages {
+		switch msg := msg.(type) {
+		case msgs.MsgSendDKGData:
+			msg.FeePayer = m.feePayer.Address
+			out = append(out, msg)
+		case msgs.MsgReportDKGMisbehavior:
+			msg.FeePayer = m.feePayer.Address
+			out = append(out, msg)
+		case msgs.MsgDKGEpochAnchor:
+			msg.FeePayer = m.feePayer.Address
+			out = append(out, msg)
+		default:
+			out = append(out, msg)
+		}
+	}
+	return out, true
+}
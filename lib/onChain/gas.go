@@ -0,0 +1,119 @@
+package onChain
+
+import (
+	gocontext "context"
+	"fmt"
+	"sync"
+
+	authtxb "github.com/corestario/cosmos-utils/client/authtypes"
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/client"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	sdkerrors "github.com/cosmos/cosmos-sdk/types/errors"
+)
+
+const (
+	DefaultGasAdjustment    = 1.5
+	DefaultGasWindow        = 20
+	DefaultGasSafetyMargin  = 1.1
+	MinGasAdjustment        = 1.0
+	MaxGasAdjustment        = 5.0
+	outOfGasAdjustmentScale = 1.5
+)
+
+// GasAdjuster tunes the multiplier applied to simulated gas by tracking the ratio of
+// actually consumed to simulated gas of recent DKG transactions.
+type GasAdjuster struct {
+	mtx        sync.Mutex
+	adjustment float64
+	window     int
+	ratios     []float64
+}
+
+func NewGasAdjuster(initial float64, window int) *GasAdjuster {
+	if initial < MinGasAdjustment {
+		initial = DefaultGasAdjustment
+	}
+	if window <= 0 {
+		window = DefaultGasWindow
+	}
+	return &GasAdjuster{adjustment: initial, window: window}
+}
+
+// Adjustment returns the current multiplier.
+func (a *GasAdjuster) Adjustment() float64 {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	return a.adjustment
+}
+
+// Observe records the gas consumed by a transaction whose simulation estimated the given amount.
+func (a *GasAdjuster) Observe(estimated, used uint64) {
+	if estimated == 0 || used == 0 {
+		return
+	}
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.ratios = append(a.ratios, float64(used)/float64(estimated))
+	if len(a.ratios) > a.window {
+		a.ratios = a.ratios[len(a.ratios)-a.window:]
+	}
+
+	// The largest recent ratio plus a safety margin covers all recent transactions
+	// while not overpaying when simulations become accurate.
+	var maxRatio float64
+	for _, r := range a.ratios {
+		if r > maxRatio {
+			maxRatio = r
+		}
+	}
+	a.setAdjustment(maxRatio * DefaultGasSafetyMargin)
+}
+
+// ObserveOutOfGas increases the multiplier after a transaction ran out of gas.
+func (a *GasAdjuster) ObserveOutOfGas() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+	a.setAdjustment(a.adjustment * outOfGasAdjustmentScale)
+}
+
+func (a *GasAdjuster) setAdjustment(adjustment float64) {
+	if adjustment < MinGasAdjustment {
+		adjustment = MinGasAdjustment
+	}
+	if adjustment > MaxGasAdjustment {
+		adjustment = MaxGasAdjustment
+	}
+	a.adjustment = adjustment
+}
+
+// Enrich simulates the transaction and sets the adjusted gas on the builder.
+// It returns the simulated gas estimate, or an error if the context is done first.
+func (a *GasAdjuster) Enrich(ctx gocontext.Context, txBldr authtxb.TxBuilder, cli *context.Context, msgs []sdk.Msg) (authtxb.TxBuilder, uint64, error) {
+	txBytes, err := client.BuildTxForSim(txBldr, msgs)
+	if err != nil {
+		return txBldr, 0, fmt.Errorf("failed to build tx for simulation: %v", err)
+	}
+	rawRes, _, err := client.QueryWithData(ctx, cli, "/app/simulate", txBytes)
+	if err != nil {
+		return txBldr, 0, fmt.Errorf("failed to simulate tx: %v", err)
+	}
+	var estimate uint64
+	if err := cli.Codec.UnmarshalBinaryLengthPrefixed(rawRes, &estimate); err != nil {
+		return txBldr, 0, fmt.Errorf("failed to decode simulation result: %v", err)
+	}
+
+	return txBldr.WithGas(uint64(a.Adjustment() * float64(estimate))), estimate, nil
+}
+
+// Update feeds the result of a broadcast transaction to the adjuster.
+func (a *GasAdjuster) Update(estimate uint64, res sdk.TxResponse) {
+	if res.Codespace == sdkerrors.RootCodespace && res.Code == sdkerrors.ErrOutOfGas.ABCICode() {
+		a.ObserveOutOfGas()
+		return
+	}
+	if res.Code == 0 && res.GasUsed > 0 {
+		a.Observe(estimate, uint64(res.GasUsed))
+	}
+}
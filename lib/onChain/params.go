@@ -0,0 +1,96 @@
+package onChain
+
+import (
+	gocontext "context"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/cosmos/cosmos-sdk/x/params/subspace"
+)
+
+// DefaultParamsSubspace is the params subspace the DKG parameters are stored in.
+const DefaultParamsSubspace = "dkg"
+
+// The keys of the DKG parameters in the params subspace.
+const (
+	ParamKeyNumBlocks            = "NumBlocks"
+	ParamKeyBlocksAhead          = "BlocksAhead"
+	ParamKeyThresholdNumerator   = "ThresholdNumerator"
+	ParamKeyThresholdDenominator = "ThresholdDenominator"
+	ParamKeyCommitteeSize        = "CommitteeSize"
+)
+
+var _ types.ParamsSource = &QueryClient{}
+
+// ParamSet registers the DKG parameters in the app's params subspace, so
+// governance can change them with parameter change proposals:
+//
+//	space := paramsKeeper.Subspace(onChain.DefaultParamsSubspace).WithKeyTable(onChain.ParamKeyTable())
+type ParamSet struct {
+	types.DKGParams
+}
+
+var _ subspace.ParamSet = &ParamSet{}
+
+func (p *ParamSet) ParamSetPairs() subspace.ParamSetPairs {
+	return subspace.ParamSetPairs{
+		subspace.NewParamSetPair([]byte(ParamKeyNumBlocks), &p.NumBlocks, validateNonNegative),
+		subspace.NewParamSetPair([]byte(ParamKeyBlocksAhead), &p.BlocksAhead, validateNonNegative),
+		subspace.NewParamSetPair([]byte(ParamKeyThresholdNumerator), &p.ThresholdNumerator, validateNonNegative),
+		subspace.NewParamSetPair([]byte(ParamKeyThresholdDenominator), &p.ThresholdDenominator, validateNonNegative),
+		subspace.NewParamSetPair([]byte(ParamKeyCommitteeSize), &p.CommitteeSize, validateNonNegative),
+	}
+}
+
+// ParamKeyTable returns the key table of the DKG parameters.
+func ParamKeyTable() subspace.KeyTable {
+	return subspace.NewKeyTable().RegisterParamSet(&ParamSet{})
+}
+
+func validateNonNegative(value interface{}) error {
+	v, ok := value.(int64)
+	if !ok {
+		return fmt.Errorf("invalid parameter type: %T", value)
+	}
+	if v < 0 {
+		return fmt.Errorf("parameter must not be negative, got %d", v)
+	}
+	return nil
+}
+
+// WithParamsSubspace sets the params subspace the DKG parameters are read
+// from, DefaultParamsSubspace by default.
+func WithParamsSubspace(name string) QueryOption {
+	return func(c *QueryClient) { c.paramsSubspace = name }
+}
+
+// DKGParams reads the DKG parameters from the params store at the height. The
+// parameters missing from the subspace are left zero. The parameters are
+// checked with Validate, since the store may hold values set separately.
+func (c *QueryClient) DKGParams(height int64) (*types.DKGParams, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), c.queryTimeout)
+	defer cancel()
+
+	set := &ParamSet{}
+	for _, pair := range set.ParamSetPairs() {
+		key := append([]byte(c.paramsSubspace+"/"), pair.Key...)
+		res, err := client.ABCIQueryWithData(ctx, c.cli, "/store/"+subspace.StoreKey+"/key", key, height)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query for parameter %s: %v", pair.Key, err)
+		}
+		if !res.Response.IsOK() {
+			return nil, fmt.Errorf("failed to query for parameter %s: %s", pair.Key, res.Response.Log)
+		}
+		if len(res.Response.Value) == 0 {
+			continue
+		}
+		if err := c.cli.Codec.UnmarshalJSON(res.Response.Value, pair.Value); err != nil {
+			return nil, fmt.Errorf("failed to decode parameter %s: %v", pair.Key, err)
+		}
+	}
+	if err := set.DKGParams.Validate(); err != nil {
+		return nil, err
+	}
+	return &set.DKGParams, nil
+}
@@ -0,0 +1,87 @@
+package onChain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/msgs"
+	"github.com/corestario/dkglib/lib/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+// handlerErrorClass tells how ProcessBlock treats the error of a message's
+// handler.
+type handlerErrorClass int
+
+const (
+	// handlerErrorFatal fails the round.
+	handlerErrorFatal handlerErrorClass = iota
+	// handlerErrorPoisoned quarantines the message: it fails because of its
+	// content, so it fails on every node and on every block it is fetched
+	// again. The round goes on without it.
+	handlerErrorPoisoned
+)
+
+// QuarantinedMessage is a message of the round skipped since its handling
+// failed because of its content. The message is signed by its sender, and
+// Owner included it on chain, so it is evidence against both.
+type QuarantinedMessage struct {
+	Data  *alias.DKGData
+	Owner sdk.AccAddress
+	Err   string
+}
+
+// Quarantined returns the messages of the current round quarantined so far,
+// in the order they were.
+func (m *OnChainDKG) Quarantined() []*QuarantinedMessage {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return append([]*QuarantinedMessage(nil), m.quarantined...)
+}
+
+// classifyHandlerError classifies the error of the handler of the message.
+// The messages the dealer found invalid, or whose sender it excluded while
+// handling them, are poisoned. Errors that middlewares wrap lose their type.
+func (m *OnChainDKG) classifyHandlerError(data *alias.DKGData, err error, excludedBefore bool) handlerErrorClass {
+	if _, ok := err.(*types.InvalidMessageError); ok {
+		return handlerErrorPoisoned
+	}
+	if !excludedBefore && m.isExcluded(data.Addr) {
+		return handlerErrorPoisoned
+	}
+	return handlerErrorFatal
+}
+
+// isExcluded reports whether the dealer excluded the participant.
+func (m *OnChainDKG) isExcluded(addr []byte) bool {
+	for _, loser := range m.dealer.GetLosersWithReasons() {
+		if bytes.Equal(loser.Addr, addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuarantined reports whether the message was quarantined.
+func (m *OnChainDKG) isQuarantined(data *alias.DKGData) bool {
+	return m.quarantine[quarantineKey(data)]
+}
+
+// quarantineMessage skips the message from now on and returns an error if the
+// round can't complete without its sender.
+func (m *OnChainDKG) quarantineMessage(msg *msgs.MsgSendDKGData, data *alias.DKGData, err error) error {
+	m.quarantine[quarantineKey(data)] = true
+	m.quarantined = append(m.quarantined, &QuarantinedMessage{Data: data, Owner: msg.Owner, Err: err.Error()})
+	m.logger.Error("on-chain DKG: quarantined message", "type", data.Type, "round_id", data.RoundID,
+		types.MessageCorrelationKey, types.MessageCorrelationID(data), "from", data.GetAddrString(), "owner", msg.Owner, "error", err)
+	if ok, _ := m.dealer.CanComplete(); !ok {
+		return fmt.Errorf("round can't complete after quarantining message from %s: %v", data.GetAddrString(), err)
+	}
+	return nil
+}
+
+func quarantineKey(data *alias.DKGData) string {
+	return hex.EncodeToString(types.EvidenceHash(data))
+}
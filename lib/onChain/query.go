@@ -0,0 +1,130 @@
+package onChain
+
+import (
+	"bytes"
+	gocontext "context"
+	"fmt"
+	"time"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// roundDataTypes are the types of the messages of on-chain rounds, in the
+// order they are processed.
+var roundDataTypes = []alias.DKGDataType{
+	alias.DKGRegistration,
+	alias.DKGCommits,
+	alias.DKGDeal,
+	alias.DKGResponse,
+}
+
+// QueryClient reads the DKG messages included on chain and the DKG parameters
+// without keys, e.g. for monitoring and reward systems.
+type QueryClient struct {
+	cli            *context.Context
+	queryTimeout   time.Duration
+	paramsSubspace string
+	signDomain     alias.SignDomain
+}
+
+// QueryOption sets an optional parameter of the QueryClient.
+type QueryOption func(*QueryClient)
+
+// WithQuerySignDomain sets the domain the signatures of the queried messages
+// are verified in, see WithSignDomain.
+func WithQuerySignDomain(domain alias.SignDomain) QueryOption {
+	return func(c *QueryClient) { c.signDomain = domain }
+}
+
+// WithQueryClientTimeout bounds every query of the client.
+func WithQueryClientTimeout(timeout time.Duration) QueryOption {
+	return func(c *QueryClient) { c.queryTimeout = timeout }
+}
+
+func NewQueryClient(cli *context.Context, options ...QueryOption) *QueryClient {
+	c := &QueryClient{
+		cli:            cli,
+		queryTimeout:   client.DefaultQueryTimeout,
+		paramsSubspace: DefaultParamsSubspace,
+		signDomain:     alias.DefaultSignDomain(""),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// QueryParticipation returns which messages of the validator were included on
+// chain for the round: the messages of on-chain rounds and the blacklist votes.
+// Signatures are verified with the validator's key in the latest validator set.
+func (c *QueryClient) QueryParticipation(roundID int, addr crypto.Address) (*types.Participation, error) {
+	pubKey, err := c.validatorKey(addr)
+	if err != nil {
+		return nil, err
+	}
+	participation := &types.Participation{
+		RoundID:  roundID,
+		Addr:     addr,
+		Included: make(map[alias.DKGDataType]int),
+	}
+	dataTypes := append(append([]alias.DKGDataType(nil), roundDataTypes...), alias.DKGBlacklist)
+	for _, dataType := range dataTypes {
+		messages, height, err := c.queryDKGMessages(dataType, roundID)
+		if err != nil {
+			return nil, err
+		}
+		if height > participation.Height {
+			participation.Height = height
+		}
+		for _, data := range messages {
+			if data == nil || data.RoundID != roundID || !bytes.Equal(data.Addr, addr) {
+				continue
+			}
+			if !c.signDomain.Verify(pubKey, data) {
+				participation.Invalid++
+				continue
+			}
+			participation.Included[data.Type]++
+		}
+	}
+	return participation, nil
+}
+
+func (c *QueryClient) validatorKey(addr crypto.Address) (crypto.PubKey, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), c.queryTimeout)
+	defer cancel()
+
+	res, err := client.Validators(ctx, c.cli, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query for validators: %v", err)
+	}
+	for _, validator := range res.Validators {
+		if bytes.Equal(validator.Address, addr) {
+			return validator.PubKey, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a validator at height %d", addr, res.BlockHeight)
+}
+
+func (c *QueryClient) queryDKGMessages(dataType alias.DKGDataType, roundID int) ([]*alias.DKGData, int64, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), c.queryTimeout)
+	defer cancel()
+
+	res, height, err := client.QueryWithData(ctx, c.cli, fmt.Sprintf("custom/randapp/dkgData/%d/%d", dataType, roundID), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to query for %s messages: %v", dataType, err)
+	}
+	messages, err := decodeDKGMessages(res)
+	if err != nil {
+		return nil, 0, err
+	}
+	out := make([]*alias.DKGData, 0, len(messages))
+	for _, msg := range messages {
+		out = append(out, msg.Data)
+	}
+	return out, height, nil
+}
@@ -0,0 +1,111 @@
+package onChain
+
+import (
+	"github.com/corestario/dkglib/lib/msgs"
+	"github.com/corestario/dkglib/lib/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// DefaultReportQueueSize is the number of misbehavior reports awaiting
+// submission, unless WithReportQueueSize is set.
+const DefaultReportQueueSize = 64
+
+// retainedCommittees is the number of the last on-chain rounds whose
+// committees are kept to check the reports about.
+const retainedCommittees = 16
+
+// ReportMisbehavior queues the report for submission to the chain, which makes
+// OnChainDKG usable as a types.MisbehaviorSink. It never blocks: reports are
+// submitted in the background, and dropped if the queue is full. Only reports
+// whose evidence verifies against the key of the reported participant are
+// submitted, see types.MisbehaviorReport.VerifyEvidence.
+func (m *OnChainDKG) ReportMisbehavior(report *types.MisbehaviorReport) {
+	select {
+	case <-m.stopReports:
+		m.logger.Debug("on-chain DKG is stopped, dropping report", "type", report.Type, "addr", report.Addr, "round_id", report.RoundID)
+		return
+	default:
+	}
+	m.startReports.Do(func() { go m.submitReports() })
+	select {
+	case m.reports <- report:
+	default:
+		m.logger.Error("on-chain DKG report queue is full, dropping report", "type", report.Type,
+			"addr", report.Addr, "round_id", report.RoundID)
+	}
+}
+
+// Stop stops submitting the misbehavior reports; the queued ones are dropped,
+// as are those reported after.
+func (m *OnChainDKG) Stop() {
+	m.stopOnce.Do(func() { close(m.stopReports) })
+}
+
+func (m *OnChainDKG) submitReports() {
+	for {
+		select {
+		case report := <-m.reports:
+			m.submitReport(report)
+		case <-m.stopReports:
+			return
+		}
+	}
+}
+
+func (m *OnChainDKG) submitReport(report *types.MisbehaviorReport) {
+	pubKey := m.participantKey(report.RoundID, report.Addr)
+	if pubKey == nil {
+		m.logger.Error("on-chain DKG report misbehavior error", "error", "reported address is not a participant", "addr", report.Addr)
+		return
+	}
+	if err := report.VerifyEvidence(pubKey, m.signDomain); err != nil {
+		m.logger.Debug("on-chain DKG misbehavior not reported", "type", report.Type, "addr", report.Addr, "reason", err)
+		return
+	}
+	msg := msgs.NewMsgReportDKGMisbehavior(report, m.cli.GetFromAddress())
+	if err := msg.ValidateBasic(); err != nil {
+		m.logger.Error("on-chain DKG report misbehavior error", "function", "ValidateBasic", "error", err)
+		return
+	}
+	if err := m.broadcastMsgs(m.cli.BroadcastMode, []sdk.Msg{msg}); err != nil {
+		m.logger.Error("on-chain DKG report misbehavior error", "function", "broadcastMsgs", "error", err)
+	}
+}
+
+// participantKey returns the key of the participant of the round, or nil if
+// the address isn't one or the round's committee is unknown.
+func (m *OnChainDKG) participantKey(roundID int, addr crypto.Address) crypto.PubKey {
+	m.mtx.Lock()
+	participants := m.committees[roundID]
+	m.mtx.Unlock()
+	if participants == nil && m.committeeLookup != nil {
+		var err error
+		if participants, err = m.committeeLookup(roundID); err != nil {
+			m.logger.Debug("on-chain DKG: unknown committee of reported round", "round_id", roundID, "error", err)
+			return nil
+		}
+	}
+	if participants == nil {
+		return nil
+	}
+	if _, participant := participants.GetByAddress(addr); participant != nil {
+		return participant.PubKey
+	}
+	return nil
+}
+
+// recordCommittee keeps the committee of an on-chain round, forgetting the
+// oldest beyond retainedCommittees; m.mtx must be held.
+func (m *OnChainDKG) recordCommittee(roundID int, participants *types.ParticipantSet) {
+	m.committees[roundID] = participants
+	for len(m.committees) > retainedCommittees {
+		oldest := roundID
+		for id := range m.committees {
+			if id < oldest {
+				oldest = id
+			}
+		}
+		delete(m.committees, oldest)
+	}
+}
@@ -0,0 +1,59 @@
+package onChain
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// TestParticipantKeyOfReportedRound checks the key of a reported participant
+// is looked up in the committee of the reported round.
+func TestParticipantKeyOfReportedRound(t *testing.T) {
+	onChainKey, offChainKey := ed25519.GenPrivKey().PubKey(), ed25519.GenPrivKey().PubKey()
+	offChainRound := types.NewParticipantSetFromList([]*types.Participant{{Address: offChainKey.Address(), PubKey: offChainKey}})
+	m := NewOnChainDKG(nil, nil, WithCommitteeLookup(func(roundID int) (*types.ParticipantSet, error) {
+		if roundID != 7 {
+			return nil, fmt.Errorf("no committee of round %d", roundID)
+		}
+		return offChainRound, nil
+	}))
+	m.recordCommittee(3, types.NewParticipantSetFromList([]*types.Participant{{Address: onChainKey.Address(), PubKey: onChainKey}}))
+
+	if key := m.participantKey(3, onChainKey.Address()); key == nil || !key.Equals(onChainKey) {
+		t.Fatalf("participant of on-chain round 3: got key %v", key)
+	}
+	if key := m.participantKey(7, offChainKey.Address()); key == nil || !key.Equals(offChainKey) {
+		t.Fatalf("participant of off-chain round 7: got key %v", key)
+	}
+	if key := m.participantKey(7, onChainKey.Address()); key != nil {
+		t.Fatalf("participant of round 3 found in round 7")
+	}
+	if key := m.participantKey(8, offChainKey.Address()); key != nil {
+		t.Fatalf("participant found in unknown round 8")
+	}
+}
+
+// TestStopReports checks reports aren't queued once the on-chain DKG stopped.
+func TestStopReports(t *testing.T) {
+	m := NewOnChainDKG(nil, nil, WithReportQueueSize(1))
+	m.Stop()
+	m.Stop()
+	m.ReportMisbehavior(&types.MisbehaviorReport{RoundID: 1})
+
+	done := make(chan struct{})
+	go func() {
+		m.submitReports()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("report submission didn't stop")
+	}
+	if len(m.reports) != 0 {
+		t.Fatalf("%d reports queued after stopping", len(m.reports))
+	}
+}
@@ -0,0 +1,57 @@
+package onChain
+
+import (
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+// stagedMsgs are messages held until the round reaches the block.
+type stagedMsgs struct {
+	block int
+	data  []*alias.DKGData
+}
+
+// WithBroadcastStagger spreads the transactions of large committees over a
+// window of blocks: every message is held for an offset of 0 to window-1
+// blocks, derived from the node's index among the participants, after the
+// dealer produces it. Messages are still broadcast in order. Rounds must last
+// long enough for the extra window-1 blocks of every phase.
+func WithBroadcastStagger(window int) OnChainOption {
+	return func(d *OnChainDKG) { d.staggerWindow = window }
+}
+
+// staggerOffset returns the number of blocks the participant holds its
+// messages for; participants with consecutive indices broadcast in
+// consecutive blocks.
+func staggerOffset(participants *types.ParticipantSet, addr []byte, window int) int {
+	if window <= 1 {
+		return 0
+	}
+	index, _ := participants.GetByAddress(addr)
+	if index < 0 {
+		return 0
+	}
+	return index % window
+}
+
+// sendMsg broadcasts the messages of the dealer, or stages them until the
+// node's offset has passed.
+func (m *OnChainDKG) sendMsg(data []*alias.DKGData) error {
+	if m.staggerOffset == 0 {
+		return m.broadcastData(data)
+	}
+	m.staged = append(m.staged, stagedMsgs{block: m.roundBlocks + m.staggerOffset, data: data})
+	return nil
+}
+
+// flushStaged broadcasts the staged messages due by the current block.
+func (m *OnChainDKG) flushStaged() error {
+	for len(m.staged) > 0 && m.staged[0].block <= m.roundBlocks {
+		next := m.staged[0]
+		m.staged = m.staged[1:]
+		if err := m.broadcastData(next.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
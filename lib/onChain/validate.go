@@ -0,0 +1,106 @@
+package onChain
+
+import (
+	gocontext "context"
+
+	"github.com/corestario/cosmos-utils/client/context"
+	"github.com/corestario/dkglib/lib/client"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+)
+
+// Validate checks the configuration, including the keyring and the
+// reachability of the node, and returns a *types.ConfigError listing every
+// problem found.
+func (m *OnChainDKG) Validate() error {
+	problems := &types.ConfigError{}
+	if m.cli == nil {
+		problems.Add("no client context")
+		return problems
+	}
+	if m.txBldr == nil {
+		problems.Add("no tx builder")
+	} else {
+		if m.txBldr.Gas() == 0 && m.gasAdjuster == nil {
+			problems.Add("gas limit is zero; set it on the tx builder or use WithAdaptiveGas")
+		}
+		if !m.txBldr.Fees().IsZero() && !m.txBldr.GasPrices().IsZero() {
+			problems.Add("both fees and gas prices are set on the tx builder; set one of them")
+		}
+	}
+
+	for dataType, mode := range m.broadcastModes {
+		switch mode {
+		case context.BroadcastSync, context.BroadcastAsync, context.BroadcastBlock:
+		default:
+			problems.Add("unsupported broadcast mode %q for %s messages; supported modes: sync, async, block", mode, dataType)
+		}
+	}
+	if m.queryTimeout <= 0 {
+		problems.Add("query timeout must be positive, got %s", m.queryTimeout)
+	}
+	if m.broadcastTimeout <= 0 {
+		problems.Add("broadcast timeout must be positive, got %s", m.broadcastTimeout)
+	}
+	if m.maxChunkSize < 0 {
+		problems.Add("max chunk size must not be negative, got %d", m.maxChunkSize)
+	}
+	if m.maxReassembledSize < m.maxChunkSize {
+		problems.Add("max reassembled size %d is less than the max chunk size %d", m.maxReassembledSize, m.maxChunkSize)
+	}
+	if m.staggerWindow < 0 {
+		problems.Add("broadcast stagger window must not be negative, got %d", m.staggerWindow)
+	}
+	if m.feePayer != nil && m.feePayer.Address.Empty() {
+		problems.Add("fee payer has no address")
+	}
+
+	m.validateKeyring(problems)
+	m.validateNode(problems)
+
+	return problems.Err()
+}
+
+// validateKeyring checks that the transactions can be signed.
+func (m *OnChainDKG) validateKeyring(problems *types.ConfigError) {
+	if m.cli.PrivKey != nil && len(m.cli.PrivKey.Bytes()) != 0 {
+		return
+	}
+	kb, err := keys.NewKeyBaseFromDir(m.cli.Home)
+	if err != nil {
+		problems.Add("keyring at %s is not available: %v", m.cli.Home, err)
+		return
+	}
+	keysList, err := kb.List()
+	if err != nil {
+		problems.Add("keyring at %s can't be listed: %v", m.cli.Home, err)
+		return
+	}
+	if len(keysList) == 0 {
+		problems.Add("keyring at %s has no keys", m.cli.Home)
+		return
+	}
+	if name := m.cli.GetFromName(); name != "" {
+		if _, err := kb.Get(name); err != nil {
+			problems.Add("key %q not found in keyring at %s: %v", name, m.cli.Home, err)
+		}
+	}
+}
+
+// validateNode checks that the node answers within the query timeout.
+func (m *OnChainDKG) validateNode(problems *types.ConfigError) {
+	if m.cli.Client == nil {
+		problems.Add("no RPC client for node %s", m.cli.NodeURI)
+		return
+	}
+	timeout := m.queryTimeout
+	if timeout <= 0 {
+		timeout = client.DefaultQueryTimeout
+	}
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), timeout)
+	defer cancel()
+
+	if _, err := client.Status(ctx, m.cli); err != nil {
+		problems.Add("node %s is not reachable: %v", m.cli.NodeURI, err)
+	}
+}
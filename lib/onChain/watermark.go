@@ -0,0 +1,53 @@
+package onChain
+
+import (
+	"github.com/corestario/dkglib/lib/alias"
+)
+
+// WithIncrementalFetch makes ProcessBlock query only the messages included
+// after the height it has ingested up to, the watermark, instead of all
+// messages of the round on every block. The chain must serve
+// custom/randapp/dkgDataSince/<type>/<round>/<height>, returning the messages
+// like custom/randapp/dkgData but only those included after the height.
+func WithIncrementalFetch() OnChainOption {
+	return func(d *OnChainDKG) { d.incremental = true }
+}
+
+// Watermark returns the height up to which the DKG transactions of the current
+// round have been ingested, or zero before the first block is processed or
+// without incremental fetching.
+func (m *OnChainDKG) Watermark() int64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.watermark()
+}
+
+func (m *OnChainDKG) watermark() int64 {
+	if len(m.watermarks) == 0 {
+		return 0
+	}
+	var lowest int64 = -1
+	for _, height := range m.watermarks {
+		if lowest == -1 || height < lowest {
+			lowest = height
+		}
+	}
+	return lowest
+}
+
+// advanceWatermark records that the messages of the type have been ingested up
+// to the height.
+func (m *OnChainDKG) advanceWatermark(dataType alias.DKGDataType, height int64) {
+	if !m.incremental || height <= m.watermarks[dataType] {
+		return
+	}
+	m.watermarks[dataType] = height
+	m.metrics.IngestWatermarkHeight.Set(float64(m.watermark()))
+}
+
+func (m *OnChainDKG) resetWatermarks() {
+	m.watermarks = make(map[alias.DKGDataType]int64)
+	m.roundChunks = alias.NewChunkBuffer(m.maxReassembledSize)
+	m.roundSeen = make(map[string]bool)
+}
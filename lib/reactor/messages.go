@@ -0,0 +1,58 @@
+package reactor
+
+import (
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	amino "github.com/tendermint/go-amino"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Message is an envelope sent on DKGChannel.
+type Message interface {
+	ValidateBasic() error
+}
+
+// DataMessage carries a signed DKG message.
+type DataMessage struct {
+	Data *alias.DKGData
+}
+
+// ValidateBasic checks the message is well-formed; the signature is verified
+// by the DKG once the message is delivered.
+func (m *DataMessage) ValidateBasic() error {
+	if m.Data == nil {
+		return fmt.Errorf("empty DKG data")
+	}
+	if len(m.Data.Addr) != crypto.AddressSize {
+		return fmt.Errorf("invalid address length: %d", len(m.Data.Addr))
+	}
+	if len(m.Data.Signature) == 0 {
+		return fmt.Errorf("unsigned DKG data")
+	}
+	return nil
+}
+
+func (m *DataMessage) String() string {
+	return fmt.Sprintf("[DataMessage %v round %d from %X]", m.Data.Type, m.Data.RoundID, m.Data.Addr)
+}
+
+var cdc = amino.NewCodec()
+
+func init() {
+	RegisterMessages(cdc)
+}
+
+// RegisterMessages registers the envelopes of DKGChannel onto the codec.
+func RegisterMessages(cdc *amino.Codec) {
+	cdc.RegisterInterface((*Message)(nil), nil)
+	cdc.RegisterConcrete(&DataMessage{}, "dkglib/reactor/DataMessage", nil)
+}
+
+func decodeMsg(bz []byte) (msg Message, err error) {
+	if len(bz) > maxMsgSize {
+		return msg, fmt.Errorf("msg exceeds max size (%d > %d)", len(bz), maxMsgSize)
+	}
+	err = cdc.UnmarshalBinaryBare(bz, &msg)
+	return
+}
@@ -0,0 +1,74 @@
+package reactor
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// PeerStateKey is the key the peer state is stored under on p2p.Peer.
+const PeerStateKey = "dkglib.reactor.peerState"
+
+// PeerState keeps the hashes of the DKG messages a peer is known to have,
+// because it sent them or they were sent to it, so they aren't sent again.
+type PeerState struct {
+	known *knownSet
+}
+
+func newPeerState(size int) *PeerState {
+	return &PeerState{known: newKnownSet(size)}
+}
+
+// HasMessage reports whether the peer is known to have the message.
+func (ps *PeerState) HasMessage(data *alias.DKGData) bool {
+	return ps.known.has(messageKey(data))
+}
+
+// messageKey identifies a message by its sign bytes and signature, so a
+// forged copy can't pass for the genuine message.
+func messageKey(data *alias.DKGData) string {
+	return string(tmhash.Sum(append(data.ContentBytes(), data.Signature...)))
+}
+
+// knownSet is a bounded set of message keys evicting the least recently
+// added one.
+type knownSet struct {
+	mtx     sync.Mutex
+	size    int
+	order   *list.List // Keys, the most recently added first.
+	entries map[string]*list.Element
+}
+
+func newKnownSet(size int) *knownSet {
+	return &knownSet{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (s *knownSet) has(key string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	_, ok := s.entries[key]
+	return ok
+}
+
+// add records the key and reports whether it was already known.
+func (s *knownSet) add(key string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if _, ok := s.entries[key]; ok {
+		return true
+	}
+	s.entries[key] = s.order.PushFront(key)
+	if s.order.Len() > s.size {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(string))
+	}
+	return false
+}
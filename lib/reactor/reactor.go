@@ -0,0 +1,172 @@
+// Package reactor transports DKG messages directly between peers on a
+// dedicated p2p channel, so Tendermint forks don't have to gossip them
+// through their consensus reactor.
+package reactor
+
+import (
+	"reflect"
+
+	"github.com/corestario/dkglib/lib/alias"
+	dkgtypes "github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/p2p"
+)
+
+const (
+	// DKGChannel is the p2p channel of DKG messages.
+	DKGChannel = byte(0x70)
+
+	maxMsgSize = 4 * 1048576 // Deals and justifications of large committees don't fit into 1MB.
+
+	// DefaultKnownMessages is the number of message hashes remembered per peer
+	// and for the node itself.
+	DefaultKnownMessages = 10000
+
+	subscriber = "dkg-reactor"
+)
+
+// Reactor broadcasts the DKG messages fired as EventDKGData on the event
+// switch to the peers and delivers the messages received from them to the
+// DKG message queue. The consensus reactor must not gossip EventDKGData as
+// well, or every message is sent twice.
+type Reactor struct {
+	p2p.BaseReactor
+
+	evsw          events.EventSwitch
+	queue         chan *dkgtypes.DKGDataMessage
+	relay         bool
+	knownMessages int
+	seen          *knownSet // Messages sent or delivered by the node.
+}
+
+// ReactorOption sets an optional parameter on the Reactor.
+type ReactorOption func(*Reactor)
+
+// WithRelay forwards the messages received from a peer to the peers that
+// don't have them yet, for networks where validators aren't fully connected.
+func WithRelay() ReactorOption {
+	return func(r *Reactor) { r.relay = true }
+}
+
+// WithKnownMessages sets the number of message hashes remembered per peer.
+func WithKnownMessages(size int) ReactorOption {
+	return func(r *Reactor) {
+		if size > 0 {
+			r.knownMessages = size
+		}
+	}
+}
+
+// NewReactor creates a reactor for the DKG, whose messages are fired on evsw.
+func NewReactor(dkg dkgtypes.DKG, evsw events.EventSwitch, options ...ReactorOption) *Reactor {
+	r := &Reactor{
+		evsw:          evsw,
+		queue:         dkg.MsgQueue(),
+		knownMessages: DefaultKnownMessages,
+	}
+	for _, option := range options {
+		option(r)
+	}
+	r.seen = newKnownSet(r.knownMessages)
+	r.BaseReactor = *p2p.NewBaseReactor("DKGReactor", r)
+	return r
+}
+
+// OnStart implements cmn.Service.
+func (r *Reactor) OnStart() error {
+	return r.evsw.AddListenerForEvent(subscriber, dkgtypes.EventDKGData, func(data events.EventData) {
+		if msg, ok := data.(*alias.DKGData); ok {
+			r.seen.add(messageKey(msg))
+			r.broadcast(msg)
+		}
+	})
+}
+
+// OnStop implements cmn.Service.
+func (r *Reactor) OnStop() {
+	r.evsw.RemoveListener(subscriber)
+}
+
+// GetChannels implements p2p.Reactor.
+func (r *Reactor) GetChannels() []*p2p.ChannelDescriptor {
+	return []*p2p.ChannelDescriptor{
+		{
+			ID:                  DKGChannel,
+			Priority:            5,
+			SendQueueCapacity:   100,
+			RecvMessageCapacity: maxMsgSize,
+		},
+	}
+}
+
+// InitPeer implements p2p.Reactor.
+func (r *Reactor) InitPeer(peer p2p.Peer) p2p.Peer {
+	peer.Set(PeerStateKey, newPeerState(r.knownMessages))
+	return peer
+}
+
+// Receive implements p2p.Reactor.
+func (r *Reactor) Receive(chID byte, src p2p.Peer, msgBytes []byte) {
+	msg, err := decodeMsg(msgBytes)
+	if err != nil {
+		r.Logger.Error("Error decoding message", "src", src, "chId", chID, "err", err)
+		r.Switch.StopPeerForError(src, err)
+		return
+	}
+	if err = msg.ValidateBasic(); err != nil {
+		r.Logger.Error("Peer sent us invalid msg", "peer", src, "msg", msg, "err", err)
+		r.Switch.StopPeerForError(src, err)
+		return
+	}
+
+	switch msg := msg.(type) {
+	case *DataMessage:
+		key := messageKey(msg.Data)
+		if ps, ok := src.Get(PeerStateKey).(*PeerState); ok {
+			ps.known.add(key)
+		}
+		if r.seen.add(key) {
+			return
+		}
+		r.deliver(msg.Data)
+		if r.relay {
+			r.broadcast(msg.Data)
+		}
+	default:
+		r.Logger.Error("Unknown message type", "type", reflect.TypeOf(msg))
+	}
+}
+
+// deliver puts the message onto the DKG queue without blocking the peer's
+// receive routine.
+func (r *Reactor) deliver(data *alias.DKGData) {
+	mi := &dkgtypes.DKGDataMessage{Data: data}
+	select {
+	case r.queue <- mi:
+	default:
+		r.Logger.Info("DKG message queue is full, using a goroutine")
+		go func() { r.queue <- mi }()
+	}
+}
+
+// broadcast sends the message to the peers not known to have it.
+func (r *Reactor) broadcast(data *alias.DKGData) {
+	if r.Switch == nil {
+		return
+	}
+	key := messageKey(data)
+	bz := cdc.MustMarshalBinaryBare(&DataMessage{Data: data})
+	for _, peer := range r.Switch.Peers().List() {
+		ps, ok := peer.Get(PeerStateKey).(*PeerState)
+		if ok && ps.known.has(key) {
+			continue
+		}
+		if !peer.TrySend(DKGChannel, bz) {
+			r.Logger.Debug("Failed to send DKG message", "peer", peer.ID(), "type", data.Type, "round_id", data.RoundID)
+			continue
+		}
+		if ok {
+			ps.known.add(key)
+		}
+	}
+}
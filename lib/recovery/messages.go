@@ -0,0 +1,77 @@
+package recovery
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// header identifies the recovery session a message belongs to.
+type header struct {
+	Epoch     int    `json:"epoch"`
+	Requester int    `json:"requester"` // Share index of the participant recovering its share.
+	Nonce     string `json:"nonce"`     // Tells apart the attempts of the requester.
+}
+
+func (h header) id() sessionID {
+	return sessionID{epoch: h.Epoch, requester: h.Requester, nonce: h.Nonce}
+}
+
+// recoveryRequest is the content of a RecoveryRequest message: the helpers the
+// requester asks for its share, the hash of the committee it trusts and the
+// ephemeral key the helpers encrypt their contributions to.
+type recoveryRequest struct {
+	header
+	Helpers   []int  `json:"helpers"`
+	Committee []byte `json:"committee"`
+	Key       []byte `json:"key"`
+}
+
+// recoveryKey is the content of a RecoveryKey message, a helper's ephemeral key
+// the other helpers encrypt its evaluations of their masks to.
+type recoveryKey struct {
+	header
+	Key []byte `json:"key"`
+}
+
+// recoveryDeal is the content of a RecoveryDeal message, the evaluations of a
+// helper's mask, a random polynomial vanishing at the requester's index,
+// encrypted to the helpers in the order of the request.
+type recoveryDeal struct {
+	header
+	Shares [][]byte `json:"shares"`
+}
+
+// recoveryShare is the content of a RecoveryShare message, a helper's share
+// plus the evaluations of all the masks at its index, encrypted to the
+// requester.
+type recoveryShare struct {
+	header
+	Share []byte `json:"share"`
+}
+
+// sessionID identifies an attempt of a participant to recover its share of an
+// epoch.
+type sessionID struct {
+	epoch     int
+	requester int
+	nonce     string
+}
+
+func (id sessionID) String() string {
+	return fmt.Sprintf("%d/%d/%s", id.epoch, id.requester, id.nonce)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %v", v, err)
+	}
+	return data, nil
+}
+
+func decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %T: %v", v, err)
+	}
+	return nil
+}
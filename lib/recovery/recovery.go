@@ -0,0 +1,709 @@
+// Package recovery lets a participant of an epoch that lacks its key share,
+// e.g. because it was offline when the round completed, restored its state
+// from a snapshot or lost its share file, get it back from T other holders
+// without a new round. The helpers re-share their shares to the requester's
+// index: the requester learns its own share and nothing about the others', and
+// the group key and the other shares stay the same.
+package recovery
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/service"
+	"github.com/corestario/dkglib/lib/types"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/encrypt/ecies"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+const (
+	// DefaultQueueSize is the number of received messages awaiting handling,
+	// see HandleMessage.
+	DefaultQueueSize = service.DefaultQueueSize
+	// DefaultTimeout is the time a recovery may take before it is abandoned.
+	DefaultTimeout = time.Minute
+	// DefaultMaxSessions is the number of recoveries a node helps with at once.
+	DefaultMaxSessions = 16
+
+	// maxHelpers bounds the messages kept per session received before its
+	// request, see stashEarly.
+	maxHelpers = 256
+)
+
+// Transport gossips the messages of the service between the participants.
+type Transport = service.Transport
+
+// Keeper holds the shares; offChain.OffChainDKG implements it.
+type Keeper interface {
+	// EpochVerifier returns the verifier in use and its epoch.
+	EpochVerifier() (types.Verifier, int)
+	// Committee returns the holders of the epoch's shares.
+	Committee(epoch int) (*types.ParticipantSet, error)
+	// RecoverShare replaces the epoch's verifier, which holds no share, with
+	// one holding the recovered share.
+	RecoverShare(epoch int, verifier types.Verifier) error
+}
+
+// Service recovers the node's share of the current epoch, see Recover, and
+// helps the other participants recover theirs. Every participant runs one,
+// registered as the transport's handler of the DKGRecoveryRequest,
+// DKGRecoveryKey, DKGRecoveryDeal and DKGRecoveryShare messages.
+//
+// A recovery takes four steps: the requester broadcasts a request naming T
+// helpers, with an ephemeral key; every helper broadcasts an ephemeral key,
+// then a mask, a random polynomial of degree T-1 vanishing at the requester's
+// index, whose evaluations are encrypted to the other helpers; then its share
+// plus the evaluations of all the masks at its index, encrypted to the
+// requester. These T points lie on a polynomial whose value at the requester's
+// index is the requester's share, and whose other values are random. The
+// requester interpolates it and checks it against the epoch's public key. All
+// the helpers must take part; a requester whose recovery fails or times out
+// tries again with other helpers.
+type Service struct {
+	transport   Transport
+	keeper      Keeper
+	logger      logging.Logger
+	timeout     time.Duration
+	maxSessions int
+
+	mtx      sync.Mutex
+	sessions map[sessionID]*session
+	early    map[sessionID]*earlyMessages // Of sessions the node hasn't joined yet.
+
+	*service.Loop
+}
+
+// Option sets an optional parameter on the Service.
+type Option func(*Service)
+
+// WithTimeout sets the time a recovery may take before it is abandoned.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Service) { s.timeout = timeout }
+}
+
+// WithMaxSessions sets the number of recoveries the node helps with at once;
+// further requests are dropped.
+func WithMaxSessions(sessions int) Option {
+	return func(s *Service) { s.maxSessions = sessions }
+}
+
+func WithLogger(logger logging.Logger) Option {
+	return func(s *Service) { s.logger = logger }
+}
+
+func NewService(transport Transport, keeper Keeper, options ...Option) *Service {
+	s := &Service{
+		transport:   transport,
+		keeper:      keeper,
+		logger:      logging.NewNopLogger(),
+		timeout:     DefaultTimeout,
+		maxSessions: DefaultMaxSessions,
+		sessions:    make(map[sessionID]*session),
+		early:       make(map[sessionID]*earlyMessages),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.Loop = service.NewLoop("recovery", s.handle, s.logger)
+	return s
+}
+
+// OnBlock abandons the recoveries that timed out. The host application calls
+// it for every committed block.
+func (s *Service) OnBlock(height int64) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for id, sess := range s.sessions {
+		if time.Since(sess.started) < s.timeout {
+			continue
+		}
+		if !sess.finished() {
+			sess.finish(fmt.Errorf("recovery %s timed out", id))
+			s.logger.Error("recovery: recovery timed out", "epoch", id.epoch, "requester", id.requester)
+		}
+		delete(s.sessions, id)
+	}
+	for id, early := range s.early {
+		if time.Since(early.received) >= s.timeout {
+			delete(s.early, id)
+		}
+	}
+}
+
+// Recover asks the helpers, share indices of the committee, for the node's
+// share of the current epoch and waits until the verifier holding it replaced
+// the keeper's one or the context is done. The committee is the one the node
+// trusts to have run the epoch's round, e.g. the validator set the round
+// started with; the helpers check it is theirs. Nil helpers pick the first T
+// participants other than the node.
+func (s *Service) Recover(ctx context.Context, committee *types.ParticipantSet, helpers []int) error {
+	verifier, epoch := s.keeper.EpochVerifier()
+	base, err := blsVerifier(verifier)
+	if err != nil {
+		return fmt.Errorf("can't recover a share of epoch %d: %v", epoch, err)
+	}
+	if base.Keypair != nil {
+		return fmt.Errorf("node already holds share %d of epoch %d", base.Keypair.Priv.I, epoch)
+	}
+	index, participant := committee.GetByAddress(s.address())
+	if participant == nil {
+		return fmt.Errorf("node is not a participant of the committee")
+	}
+	t, n := base.Threshold()
+	if committee.Size() != n {
+		return fmt.Errorf("committee has %d participants, the key %d shares", committee.Size(), n)
+	}
+	if helpers == nil {
+		for i := 0; i < n && len(helpers) < t; i++ {
+			if i != index {
+				helpers = append(helpers, i)
+			}
+		}
+	}
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to pick nonce: %v", err)
+	}
+	id := sessionID{epoch: epoch, requester: index, nonce: hex.EncodeToString(nonce)}
+	sess, err := newSession(id, base, committee, helpers)
+	if err != nil {
+		return fmt.Errorf("can't recover a share of epoch %d: %v", epoch, err)
+	}
+	key, err := sess.key.Public.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode ephemeral key: %v", err)
+	}
+
+	s.mtx.Lock()
+	s.sessions[id] = sess
+	s.mtx.Unlock()
+	request := &recoveryRequest{header: sess.header(), Helpers: helpers, Committee: committee.Hash(), Key: key}
+	if err := s.broadcast(alias.DKGRecoveryRequest, epoch, request); err != nil {
+		s.mtx.Lock()
+		sess.finish(err)
+		s.mtx.Unlock()
+		return err
+	}
+	s.logger.Info("recovery: recovery requested", "epoch", epoch, "index", index, "helpers", fmt.Sprint(helpers))
+
+	select {
+	case <-sess.done:
+		return sess.err
+	case <-ctx.Done():
+		return fmt.Errorf("recovery %s didn't complete: %v", id, ctx.Err())
+	}
+}
+
+func (s *Service) handle(msg *alias.DKGData) error {
+	switch msg.Type {
+	case alias.DKGRecoveryRequest:
+		var request recoveryRequest
+		if err := decode(msg.Data, &request); err != nil {
+			return err
+		}
+		if err := s.handleRequest(msg, &request); err != nil {
+			return err
+		}
+		// The helpers' messages may have arrived before the request.
+		for _, early := range s.takeEarly(request.id()) {
+			if err := s.handle(early); err != nil {
+				s.logger.Info("recovery: failed to handle message", "type", early.Type, "from", early.GetAddrString(), "error", err)
+			}
+		}
+		return nil
+	case alias.DKGRecoveryKey:
+		var key recoveryKey
+		if err := decode(msg.Data, &key); err != nil {
+			return err
+		}
+		return s.withHelper(key.id(), msg, func(sess *session, position int) error {
+			return s.handleKey(sess, position, &key)
+		})
+	case alias.DKGRecoveryDeal:
+		var deal recoveryDeal
+		if err := decode(msg.Data, &deal); err != nil {
+			return err
+		}
+		return s.withHelper(deal.id(), msg, func(sess *session, position int) error {
+			return s.handleDeal(sess, position, &deal)
+		})
+	case alias.DKGRecoveryShare:
+		var contribution recoveryShare
+		if err := decode(msg.Data, &contribution); err != nil {
+			return err
+		}
+		return s.withHelper(contribution.id(), msg, func(sess *session, position int) error {
+			return s.handleShare(sess, position, &contribution)
+		})
+	}
+	return nil
+}
+
+// handleRequest joins the recovery if the node is one of its helpers and
+// broadcasts its ephemeral key.
+func (s *Service) handleRequest(msg *alias.DKGData, request *recoveryRequest) error {
+	id := request.id()
+	verifier, epoch := s.keeper.EpochVerifier()
+	if id.epoch != epoch {
+		return nil
+	}
+	committee, err := s.keeper.Committee(epoch)
+	if err != nil {
+		return nil // The node didn't complete the epoch's round.
+	}
+	index, _ := committee.GetByAddress(s.address())
+	if !contains(request.Helpers, index) {
+		return nil
+	}
+	if requester, participant := committee.GetByAddress(msg.Addr); participant == nil || requester != id.requester {
+		return fmt.Errorf("%s is not participant %d of epoch %d", msg.GetAddrString(), id.requester, epoch)
+	}
+	if !bytes.Equal(request.Committee, committee.Hash()) {
+		return fmt.Errorf("recovery %s trusts another committee than epoch %d's", id, epoch)
+	}
+	base, err := blsVerifier(verifier)
+	if err != nil {
+		return err
+	}
+	if base.Keypair == nil || base.Keypair.Priv.I != index {
+		return fmt.Errorf("node holds no share %d of epoch %d", index, epoch)
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.sessions[id]; ok {
+		return nil
+	}
+	var active int
+	for _, sess := range s.sessions {
+		if !sess.finished() {
+			active++
+		}
+	}
+	if active >= s.maxSessions {
+		return fmt.Errorf("helping with %d recoveries already, dropping %s", active, id)
+	}
+	sess, err := newSession(id, base, committee, request.Helpers)
+	if err != nil {
+		return fmt.Errorf("invalid recovery %s: %v", id, err)
+	}
+	sess.requesterKey = sess.suite.Point()
+	if err := sess.requesterKey.UnmarshalBinary(request.Key); err != nil {
+		return fmt.Errorf("invalid ephemeral key of requester %d: %v", id.requester, err)
+	}
+	key, err := sess.key.Public.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode ephemeral key: %v", err)
+	}
+	if err := s.broadcast(alias.DKGRecoveryKey, epoch, &recoveryKey{header: sess.header(), Key: key}); err != nil {
+		return err
+	}
+	s.sessions[id] = sess
+	s.logger.Info("recovery: helping recovery", "epoch", epoch, "requester", id.requester)
+	return nil
+}
+
+// withHelper runs the handler with the session of a message and the position of
+// its sender among the session's helpers.
+func (s *Service) withHelper(id sessionID, msg *alias.DKGData, handler func(*session, int) error) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		s.stashEarly(id, msg)
+		return nil
+	}
+	if sess.finished() {
+		return nil
+	}
+	index, participant := sess.committee.GetByAddress(msg.Addr)
+	if participant == nil {
+		return fmt.Errorf("%s is not a participant of epoch %d", msg.GetAddrString(), id.epoch)
+	}
+	position := sess.position(index)
+	if position < 0 {
+		return fmt.Errorf("participant %d is not a helper of recovery %s", index, id)
+	}
+	return handler(sess, position)
+}
+
+// earlyMessages are the messages of a session received before its request.
+type earlyMessages struct {
+	received time.Time
+	msgs     []*alias.DKGData
+}
+
+// stashEarly keeps the message of a session the node hasn't joined, in case
+// its request comes next; s.mtx must be held. The messages of as many
+// sessions as the node helps with are kept, at most two per helper and
+// session.
+func (s *Service) stashEarly(id sessionID, msg *alias.DKGData) {
+	early, ok := s.early[id]
+	if !ok {
+		if len(s.early) >= s.maxSessions {
+			return
+		}
+		early = &earlyMessages{received: time.Now()}
+		s.early[id] = early
+	}
+	if len(early.msgs) < 2*maxHelpers {
+		early.msgs = append(early.msgs, msg)
+	}
+}
+
+func (s *Service) takeEarly(id sessionID) []*alias.DKGData {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	early, ok := s.early[id]
+	if !ok {
+		return nil
+	}
+	delete(s.early, id)
+	return early.msgs
+}
+
+// handleKey records a helper's ephemeral key and deals the node's mask once
+// all are known.
+func (s *Service) handleKey(sess *session, position int, msg *recoveryKey) error {
+	if sess.requesting() || sess.keys[position] != nil {
+		return nil
+	}
+	key := sess.suite.Point()
+	if err := key.UnmarshalBinary(msg.Key); err != nil {
+		return fmt.Errorf("invalid ephemeral key of helper %d: %v", sess.helpers[position], err)
+	}
+	sess.keys[position] = key
+	if sess.dealt || !sess.allKeys() {
+		return nil
+	}
+	deal, err := sess.deal()
+	if err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	sess.dealt = true
+	return s.broadcast(alias.DKGRecoveryDeal, sess.id.epoch, deal)
+}
+
+// handleDeal records the node's evaluation of a helper's mask and sends the
+// node's contribution to the requester once all the masks are in.
+func (s *Service) handleDeal(sess *session, position int, msg *recoveryDeal) error {
+	if sess.requesting() || sess.masks[position] != nil {
+		return nil
+	}
+	if err := sess.addDeal(position, msg); err != nil {
+		s.fail(sess, fmt.Errorf("invalid deal of helper %d: %v", sess.helpers[position], err))
+		return nil
+	}
+	if len(sess.masks) < len(sess.helpers) {
+		return nil
+	}
+	contribution, err := sess.contribution()
+	if err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	if err := s.broadcast(alias.DKGRecoveryShare, sess.id.epoch, contribution); err != nil {
+		return err
+	}
+	sess.finish(nil)
+	s.logger.Info("recovery: share sent", "epoch", sess.id.epoch, "requester", sess.id.requester)
+	return nil
+}
+
+// handleShare records a helper's contribution and, once all are in, recovers
+// the node's share.
+func (s *Service) handleShare(sess *session, position int, msg *recoveryShare) error {
+	if !sess.requesting() || sess.contributions[position] != nil {
+		return nil
+	}
+	data, err := ecies.Decrypt(sess.suite, sess.key.Private, msg.Share, nil)
+	if err != nil {
+		s.fail(sess, fmt.Errorf("failed to decrypt contribution of helper %d: %v", sess.helpers[position], err))
+		return nil
+	}
+	contribution := bn256.NewSuiteG2().Scalar()
+	if err := contribution.UnmarshalBinary(data); err != nil {
+		s.fail(sess, fmt.Errorf("invalid contribution of helper %d: %v", sess.helpers[position], err))
+		return nil
+	}
+	sess.contributions[position] = contribution
+	if len(sess.contributions) < len(sess.helpers) {
+		return nil
+	}
+	verifier, err := sess.recover()
+	if err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	if err := s.keeper.RecoverShare(sess.id.epoch, verifier); err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	sess.finish(nil)
+	s.logger.Info("recovery: share recovered", "epoch", sess.id.epoch, "index", sess.id.requester)
+	return nil
+}
+
+// fail abandons the session; s.mtx must be held.
+func (s *Service) fail(sess *session, err error) {
+	sess.finish(fmt.Errorf("recovery %s failed: %v", sess.id, err))
+	s.logger.Error("recovery: recovery failed", "epoch", sess.id.epoch, "requester", sess.id.requester, "error", err)
+}
+
+func (s *Service) broadcast(dataType alias.DKGDataType, epoch int, content interface{}) error {
+	data, err := encode(content)
+	if err != nil {
+		return err
+	}
+	if err := s.transport.Broadcast(&alias.DKGData{Type: dataType, RoundID: epoch, Data: data}); err != nil {
+		return fmt.Errorf("failed to broadcast %v: %v", dataType, err)
+	}
+	return nil
+}
+
+func (s *Service) address() []byte {
+	return s.transport.GetPrivValidator().GetPubKey().Address()
+}
+
+// session is the node's state of a recovery, as the requester or a helper.
+type session struct {
+	id        sessionID
+	started   time.Time
+	committee *types.ParticipantSet
+	base      *blsShare.BLSVerifier
+	t, n      int
+	helpers   []int // Share indices of the helpers.
+
+	suite *edwards25519.SuiteEd25519 // Of the ephemeral keys.
+	key   *ephemeralKey
+
+	// Helper state.
+	requesterKey kyber.Point
+	keys         []kyber.Point // Of the helpers, by position.
+	dealt        bool
+	masks        map[int]kyber.Scalar // Evaluations of the helpers' masks at the node's index, by position.
+
+	// Requester state.
+	contributions map[int]kyber.Scalar // By position.
+
+	err  error
+	done chan struct{}
+}
+
+type ephemeralKey struct {
+	Private kyber.Scalar
+	Public  kyber.Point
+}
+
+func newSession(id sessionID, base *blsShare.BLSVerifier, committee *types.ParticipantSet, helpers []int) (*session, error) {
+	t, n := base.Threshold()
+	if t < 2 {
+		return nil, fmt.Errorf("threshold %d is too low to hide the helpers' shares", t)
+	}
+	if committee.Size() != n {
+		return nil, fmt.Errorf("committee has %d participants, the key %d shares", committee.Size(), n)
+	}
+	if id.requester < 0 || id.requester >= n {
+		return nil, fmt.Errorf("requester index %d is out of range", id.requester)
+	}
+	if len(helpers) != t {
+		return nil, fmt.Errorf("%d helpers, want %d", len(helpers), t)
+	}
+	seen := make(map[int]bool)
+	for _, helper := range helpers {
+		if helper < 0 || helper >= n || helper == id.requester || seen[helper] {
+			return nil, fmt.Errorf("invalid helper %d", helper)
+		}
+		seen[helper] = true
+	}
+	suite := edwards25519.NewBlakeSHA256Ed25519()
+	private := suite.Scalar().Pick(random.New())
+	return &session{
+		id:            id,
+		started:       time.Now(),
+		committee:     committee,
+		base:          base,
+		t:             t,
+		n:             n,
+		helpers:       append([]int(nil), helpers...),
+		suite:         suite,
+		key:           &ephemeralKey{Private: private, Public: suite.Point().Mul(private, nil)},
+		keys:          make([]kyber.Point, len(helpers)),
+		masks:         make(map[int]kyber.Scalar),
+		contributions: make(map[int]kyber.Scalar),
+		done:          make(chan struct{}),
+	}, nil
+}
+
+func (sess *session) header() header {
+	return header{Epoch: sess.id.epoch, Requester: sess.id.requester, Nonce: sess.id.nonce}
+}
+
+// requesting reports whether the node is the session's requester.
+func (sess *session) requesting() bool {
+	return sess.base.Keypair == nil
+}
+
+// position returns the position of the helper among the session's, -1 if it
+// is none.
+func (sess *session) position(index int) int {
+	for position, helper := range sess.helpers {
+		if helper == index {
+			return position
+		}
+	}
+	return -1
+}
+
+func (sess *session) allKeys() bool {
+	for _, key := range sess.keys {
+		if key == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// deal picks the node's mask, a random polynomial of degree T-1 vanishing at
+// the requester's index, and encrypts its evaluations to the helpers.
+func (sess *session) deal() (*recoveryDeal, error) {
+	g2 := bn256.NewSuiteG2()
+	// The mask is (x - x_requester) * p(x), p of degree T-2.
+	poly := share.NewPriPoly(g2, sess.t-1, nil, random.New())
+	deal := &recoveryDeal{header: sess.header()}
+	for position, helper := range sess.helpers {
+		evaluation := g2.Scalar().Sub(x(helper), x(sess.id.requester))
+		evaluation.Mul(evaluation, poly.Eval(helper).V)
+		data, err := evaluation.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode mask: %v", err)
+		}
+		encrypted, err := ecies.Encrypt(sess.suite, sess.keys[position], data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt mask of helper %d: %v", helper, err)
+		}
+		deal.Shares = append(deal.Shares, encrypted)
+	}
+	return deal, nil
+}
+
+// addDeal decrypts the node's evaluation of a helper's mask.
+func (sess *session) addDeal(position int, msg *recoveryDeal) error {
+	if len(msg.Shares) != len(sess.helpers) {
+		return fmt.Errorf("%d evaluations, want %d", len(msg.Shares), len(sess.helpers))
+	}
+	own := sess.position(sess.base.Keypair.Priv.I)
+	data, err := ecies.Decrypt(sess.suite, sess.key.Private, msg.Shares[own], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt mask: %v", err)
+	}
+	evaluation := bn256.NewSuiteG2().Scalar()
+	if err := evaluation.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("invalid mask: %v", err)
+	}
+	sess.masks[position] = evaluation
+	return nil
+}
+
+// contribution adds up the node's share and the masks and encrypts the sum to
+// the requester.
+func (sess *session) contribution() (*recoveryShare, error) {
+	sum := bn256.NewSuiteG2().Scalar().Set(sess.base.Keypair.Priv.V)
+	for _, mask := range sess.masks {
+		sum.Add(sum, mask)
+	}
+	data, err := sum.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode contribution: %v", err)
+	}
+	encrypted, err := ecies.Encrypt(sess.suite, sess.requesterKey, data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt contribution: %v", err)
+	}
+	return &recoveryShare{header: sess.header(), Share: encrypted}, nil
+}
+
+// recover interpolates the contributions at the node's index and checks the
+// share against the epoch's public key.
+func (sess *session) recover() (*blsShare.BLSVerifier, error) {
+	var (
+		g2    = bn256.NewSuiteG2()
+		index = sess.id.requester
+		priv  = g2.Scalar().Zero()
+	)
+	for position, helper := range sess.helpers {
+		// Lagrange basis polynomial of the helper evaluated at the node's index.
+		coefficient := g2.Scalar().One()
+		for _, other := range sess.helpers {
+			if other == helper {
+				continue
+			}
+			numerator := g2.Scalar().Sub(x(index), x(other))
+			denominator := g2.Scalar().Sub(x(helper), x(other))
+			coefficient.Mul(coefficient, numerator.Div(numerator, denominator))
+		}
+		priv.Add(priv, coefficient.Mul(coefficient, sess.contributions[position]))
+	}
+	masterPubKey := sess.base.MasterPubKey()
+	pub := masterPubKey.Eval(index)
+	if !g2.Point().Mul(priv, nil).Equal(pub.V) {
+		return nil, fmt.Errorf("recovered share doesn't match the group key")
+	}
+	keypair := &blsShare.BLSShare{
+		ID:   index,
+		Pub:  pub,
+		Priv: &share.PriShare{I: index, V: priv},
+	}
+	return blsShare.NewBLSVerifier(masterPubKey, keypair, sess.t, sess.n), nil
+}
+
+func (sess *session) finished() bool {
+	select {
+	case <-sess.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sess *session) finish(err error) {
+	sess.err = err
+	close(sess.done)
+}
+
+// x returns the evaluation point of the share index.
+func x(index int) kyber.Scalar {
+	return bn256.NewSuiteG2().Scalar().SetInt64(int64(index + 1))
+}
+
+// blsVerifier returns the BLS verifier, which may be wrapped into a
+// UsageVerifier.
+func blsVerifier(verifier types.Verifier) (*blsShare.BLSVerifier, error) {
+	base, ok := blsShare.UnwrapVerifier(verifier)
+	if !ok || base.MasterPubKey() == nil {
+		return nil, fmt.Errorf("verifier holds no BLS group key")
+	}
+	return base, nil
+}
+
+func contains(indices []int, index int) bool {
+	for _, i := range indices {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package refresh
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// refreshKey is the content of a RefreshKey message, a holder's ephemeral key
+// the others encrypt its shares of their deals to.
+type refreshKey struct {
+	Epoch  int    `json:"epoch"`
+	Height int64  `json:"height"`
+	Key    []byte `json:"key"`
+}
+
+// refreshDeal is the content of a RefreshDeal message, the commitments of a
+// holder's zero polynomial and its encrypted evaluations, by share index.
+type refreshDeal struct {
+	Epoch   int      `json:"epoch"`
+	Height  int64    `json:"height"`
+	Commits [][]byte `json:"commits"`
+	Shares  [][]byte `json:"shares"`
+}
+
+// refreshAck is the content of a RefreshAck message, the hash of the master
+// public key a holder computed from all the deals and the height it proposes
+// to switch to its refreshed share at, signed with the holder's consensus key
+// so that the other holders can relay it in a refreshCommit.
+type refreshAck struct {
+	Epoch      int    `json:"epoch"`
+	Height     int64  `json:"height"`
+	KeyHash    []byte `json:"key_hash"`
+	SwitchedAt int64  `json:"switched_at"`
+	Signature  []byte `json:"signature"`
+}
+
+// refreshAckDomain separates the sign bytes of acknowledgements from those of
+// other messages signed with the consensus keys.
+const refreshAckDomain = "dkglib/RefreshAck"
+
+// SignBytes encodes the acknowledgement without its signature after the
+// domain separator. Acknowledgements are bound to the epoch's key rather than
+// a chain, so the chain ID is ignored.
+func (a refreshAck) SignBytes(string) []byte {
+	a.Signature = nil
+	data, err := json.Marshal(a)
+	if err != nil {
+		panic(err)
+	}
+	return append([]byte(refreshAckDomain+"\x00"), data...)
+}
+
+func (a *refreshAck) SetSignature(sig []byte) {
+	a.Signature = sig
+}
+
+// refreshCommit is the content of a RefreshCommit message, the acknowledgements
+// of all the holders by share index, which a holder broadcasts once it
+// scheduled its refreshed share. A holder that missed some of the
+// acknowledgements schedules its own with them.
+type refreshCommit struct {
+	Epoch  int           `json:"epoch"`
+	Height int64         `json:"height"`
+	Acks   []*refreshAck `json:"acks"`
+}
+
+// sessionID identifies the refresh of an epoch started at a height.
+type sessionID struct {
+	epoch  int
+	height int64
+}
+
+func (id sessionID) String() string {
+	return fmt.Sprintf("%d/%d", id.epoch, id.height)
+}
+
+func encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %T: %v", v, err)
+	}
+	return data, nil
+}
+
+func decode(data []byte, v interface{}) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode %T: %v", v, err)
+	}
+	return nil
+}
@@ -0,0 +1,640 @@
+// Package refresh re-randomizes the key shares of the current epoch without a
+// DKG round: the holders add up random polynomials with a zero secret to their
+// shares, so the group key stays the same while the shares an attacker stole
+// before the refresh can't be combined with those it steals after. Refreshes are
+// far cheaper than rounds and can be run much more often.
+package refresh
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/service"
+	"github.com/corestario/dkglib/lib/types"
+	"go.dedis.ch/kyber/v3"
+	"go.dedis.ch/kyber/v3/encrypt/ecies"
+	"go.dedis.ch/kyber/v3/group/edwards25519"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+	"go.dedis.ch/kyber/v3/share"
+	"go.dedis.ch/kyber/v3/util/random"
+)
+
+const (
+	// DefaultQueueSize is the number of received messages awaiting handling,
+	// see HandleMessage.
+	DefaultQueueSize = service.DefaultQueueSize
+	// DefaultDelay is the number of blocks after the last one the holders
+	// propose to switch to their refreshed shares at.
+	DefaultDelay = 5
+	// DefaultMaxSwitchLead is the number of blocks after the refresh's start
+	// height the holders may propose to switch at, see WithMaxSwitchLead.
+	DefaultMaxSwitchLead = 100
+	// DefaultTimeout is the time a refresh may take before it is abandoned.
+	DefaultTimeout = time.Minute
+)
+
+// Transport gossips the messages of the service between the share holders.
+type Transport = service.Transport
+
+// Keeper holds the shares being refreshed; offChain.OffChainDKG implements it.
+type Keeper interface {
+	// EpochVerifier returns the verifier in use and its epoch.
+	EpochVerifier() (types.Verifier, int)
+	// Committee returns the holders of the epoch's shares.
+	Committee(epoch int) (*types.ParticipantSet, error)
+	LastHeight() int64
+	// CheckRefresh returns the error ScheduleRefresh would fail with for the
+	// epoch whatever the height, e.g. if the refresh isn't authorized.
+	CheckRefresh(epoch int) error
+	// ScheduleRefresh replaces the epoch's verifier at the height.
+	ScheduleRefresh(epoch int, verifier types.Verifier, height int64) error
+}
+
+// Service refreshes the shares of the current epoch together with the other
+// holders. Every share holder runs one, registered as the transport's handler
+// of the DKGRefreshKey, DKGRefreshDeal, DKGRefreshAck and DKGRefreshCommit
+// messages.
+//
+// A refresh takes three steps: every holder broadcasts an ephemeral key, then
+// a deal of a random polynomial with a zero secret whose evaluations are
+// encrypted to the others' ephemeral keys, then the signed hash of the master
+// public key it computed from all the deals. A holder only acknowledges the
+// key once it checked it can schedule the refreshed share. Once all the
+// holders acknowledged the same key, each schedules its refreshed share at the
+// largest proposed height, which must be within WithMaxSwitchLead blocks of
+// the refresh's start, and broadcasts all the acknowledgements, so that a
+// holder that missed some schedules its share too. All the holders must take
+// part; a refresh that doesn't complete leaves the shares as they were.
+type Service struct {
+	transport Transport
+	keeper    Keeper
+	logger    logging.Logger
+	interval  int64
+	delay     int64
+	maxLead   int64
+	timeout   time.Duration
+
+	mtx      sync.Mutex
+	sessions map[sessionID]*session
+
+	*service.Loop
+}
+
+// Option sets an optional parameter on the Service.
+type Option func(*Service)
+
+// WithInterval starts a refresh at every height that is a multiple of the
+// interval, see OnBlock.
+func WithInterval(blocks int64) Option {
+	return func(s *Service) { s.interval = blocks }
+}
+
+// WithDelay sets the number of blocks after the last one the node proposes to
+// switch to its refreshed share at.
+func WithDelay(blocks int64) Option {
+	return func(s *Service) { s.delay = blocks }
+}
+
+// WithMaxSwitchLead sets the number of blocks after the refresh's start height
+// the holders may propose to switch at; a refresh with a proposal beyond it, or
+// not after the start height, fails, so a holder can't postpone the switch at
+// will.
+func WithMaxSwitchLead(blocks int64) Option {
+	return func(s *Service) { s.maxLead = blocks }
+}
+
+// WithTimeout sets the time a refresh may take before it is abandoned.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Service) { s.timeout = timeout }
+}
+
+func WithLogger(logger logging.Logger) Option {
+	return func(s *Service) { s.logger = logger }
+}
+
+func NewService(transport Transport, keeper Keeper, options ...Option) *Service {
+	s := &Service{
+		transport: transport,
+		keeper:    keeper,
+		logger:    logging.NewNopLogger(),
+		delay:     DefaultDelay,
+		maxLead:   DefaultMaxSwitchLead,
+		timeout:   DefaultTimeout,
+		sessions:  make(map[sessionID]*session),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.Loop = service.NewLoop("refresh", s.handle, s.logger)
+	return s
+}
+
+// OnBlock starts a refresh at the heights that are multiples of the interval,
+// see WithInterval, and abandons the refreshes that timed out. The host
+// application calls it for every committed block.
+func (s *Service) OnBlock(height int64) {
+	s.expire()
+	if s.interval <= 0 || height%s.interval != 0 {
+		return
+	}
+	if _, err := s.start(height); err != nil {
+		s.logger.Error("refresh: failed to start refresh", "height", height, "error", err)
+	}
+}
+
+// Refresh starts a refresh of the current epoch's shares at the last height,
+// or joins the one the other holders started at it, and waits until the
+// refreshed shares are scheduled or the context is done.
+func (s *Service) Refresh(ctx context.Context) (int64, error) {
+	sess, err := s.start(s.keeper.LastHeight())
+	if err != nil {
+		return 0, err
+	}
+	select {
+	case <-sess.done:
+		return sess.switchHeight, sess.err
+	case <-ctx.Done():
+		return 0, fmt.Errorf("refresh %s didn't complete: %v", sess.id, ctx.Err())
+	}
+}
+
+// start returns the session of the current epoch at the height, creating it
+// and broadcasting the node's ephemeral key if needed.
+func (s *Service) start(height int64) (*session, error) {
+	verifier, epoch := s.keeper.EpochVerifier()
+	id := sessionID{epoch: epoch, height: height}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sess, ok := s.sessions[id]; ok {
+		return sess, nil
+	}
+	for other, sess := range s.sessions {
+		if !sess.finished() {
+			return nil, fmt.Errorf("refresh %s is in progress", other)
+		}
+	}
+	committee, err := s.keeper.Committee(epoch)
+	if err != nil {
+		return nil, err
+	}
+	sess, err := newSession(id, verifier, committee, s.address())
+	if err != nil {
+		return nil, fmt.Errorf("can't refresh epoch %d: %v", epoch, err)
+	}
+	key, err := sess.key.Public.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ephemeral key: %v", err)
+	}
+	if err := s.broadcast(alias.DKGRefreshKey, epoch, &refreshKey{Epoch: epoch, Height: height, Key: key}); err != nil {
+		return nil, err
+	}
+	s.sessions[id] = sess
+	s.logger.Info("refresh: refresh started", "epoch", epoch, "height", height)
+	return sess, nil
+}
+
+// expire abandons the unfinished sessions that timed out and forgets the
+// finished ones.
+func (s *Service) expire() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for id, sess := range s.sessions {
+		if time.Since(sess.started) < s.timeout {
+			continue
+		}
+		if !sess.finished() {
+			sess.finish(0, fmt.Errorf("refresh %s timed out", id))
+			s.logger.Error("refresh: refresh timed out", "epoch", id.epoch, "height", id.height)
+		}
+		delete(s.sessions, id)
+	}
+}
+
+func (s *Service) handle(msg *alias.DKGData) error {
+	switch msg.Type {
+	case alias.DKGRefreshKey:
+		var key refreshKey
+		if err := decode(msg.Data, &key); err != nil {
+			return err
+		}
+		sess, index, err := s.session(sessionID{epoch: key.Epoch, height: key.Height}, msg)
+		if err != nil {
+			return err
+		}
+		return s.handleKey(sess, index, &key)
+	case alias.DKGRefreshDeal:
+		var deal refreshDeal
+		if err := decode(msg.Data, &deal); err != nil {
+			return err
+		}
+		sess, index, err := s.session(sessionID{epoch: deal.Epoch, height: deal.Height}, msg)
+		if err != nil {
+			return err
+		}
+		return s.handleDeal(sess, index, &deal)
+	case alias.DKGRefreshAck:
+		var ack refreshAck
+		if err := decode(msg.Data, &ack); err != nil {
+			return err
+		}
+		sess, index, err := s.session(sessionID{epoch: ack.Epoch, height: ack.Height}, msg)
+		if err != nil {
+			return err
+		}
+		return s.handleAck(sess, index, &ack)
+	case alias.DKGRefreshCommit:
+		var commit refreshCommit
+		if err := decode(msg.Data, &commit); err != nil {
+			return err
+		}
+		sess, _, err := s.session(sessionID{epoch: commit.Epoch, height: commit.Height}, msg)
+		if err != nil {
+			return err
+		}
+		return s.handleCommit(sess, &commit)
+	}
+	return nil
+}
+
+// session returns the session of a message, joining it if the node hasn't,
+// and the sender's share index.
+func (s *Service) session(id sessionID, msg *alias.DKGData) (*session, int, error) {
+	if _, epoch := s.keeper.EpochVerifier(); id.epoch != epoch {
+		return nil, 0, fmt.Errorf("refresh %s is not of the current epoch %d", id, epoch)
+	}
+	sess, err := s.start(id.height)
+	if err != nil {
+		return nil, 0, err
+	}
+	index, holder := sess.committee.GetByAddress(msg.Addr)
+	if holder == nil {
+		return nil, 0, fmt.Errorf("%s is not a holder of epoch %d", msg.GetAddrString(), id.epoch)
+	}
+	return sess, index, nil
+}
+
+// handleKey records a holder's ephemeral key and deals once all are known.
+func (s *Service) handleKey(sess *session, index int, msg *refreshKey) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sess.finished() || sess.keys[index] != nil {
+		return nil
+	}
+	key := sess.suite.Point()
+	if err := key.UnmarshalBinary(msg.Key); err != nil {
+		return fmt.Errorf("invalid ephemeral key of holder %d: %v", index, err)
+	}
+	sess.keys[index] = key
+	if sess.dealt || !sess.allKeys() {
+		return nil
+	}
+	deal, err := sess.deal()
+	if err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	sess.dealt = true
+	return s.broadcast(alias.DKGRefreshDeal, sess.id.epoch, deal)
+}
+
+// handleDeal verifies and records a holder's deal and acknowledges the
+// refreshed key once all the deals are in, if the node can schedule it.
+func (s *Service) handleDeal(sess *session, index int, msg *refreshDeal) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sess.finished() || sess.deals[index] != nil {
+		return nil
+	}
+	if err := sess.addDeal(index, msg); err != nil {
+		s.fail(sess, fmt.Errorf("invalid deal of holder %d: %v", index, err))
+		return nil
+	}
+	if sess.refreshed != nil || len(sess.deals) < sess.committee.Size() {
+		return nil
+	}
+	if err := sess.combine(); err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	// Without the node's acknowledgement no holder schedules its share, so
+	// the shares can't diverge if the node can't schedule its own.
+	if err := s.keeper.CheckRefresh(sess.id.epoch); err != nil {
+		s.fail(sess, fmt.Errorf("can't schedule refreshed share: %v", err))
+		return nil
+	}
+	ack := &refreshAck{
+		Epoch:      sess.id.epoch,
+		Height:     sess.id.height,
+		KeyHash:    sess.keyHash,
+		SwitchedAt: s.keeper.LastHeight() + s.delay,
+	}
+	if err := s.transport.GetPrivValidator().SignData("", ack); err != nil {
+		s.fail(sess, fmt.Errorf("failed to sign acknowledgement: %v", err))
+		return nil
+	}
+	if err := s.broadcast(alias.DKGRefreshAck, sess.id.epoch, ack); err != nil {
+		return err
+	}
+	return s.complete(sess)
+}
+
+// handleAck records a holder's acknowledgement.
+func (s *Service) handleAck(sess *session, index int, msg *refreshAck) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sess.finished() || sess.acks[index] != nil {
+		return nil
+	}
+	if err := sess.verifyAck(index, msg); err != nil {
+		return err
+	}
+	sess.acks[index] = msg
+	return s.complete(sess)
+}
+
+// handleCommit records the acknowledgements the node missed from a holder's
+// commit.
+func (s *Service) handleCommit(sess *session, msg *refreshCommit) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if sess.finished() {
+		return nil
+	}
+	if len(msg.Acks) != sess.n {
+		return fmt.Errorf("commit of refresh %s has %d acknowledgements, want %d", sess.id, len(msg.Acks), sess.n)
+	}
+	for index, ack := range msg.Acks {
+		if sess.acks[index] != nil {
+			continue
+		}
+		if err := sess.verifyAck(index, ack); err != nil {
+			return fmt.Errorf("invalid commit of refresh %s: %v", sess.id, err)
+		}
+		sess.acks[index] = ack
+	}
+	return s.complete(sess)
+}
+
+// complete schedules the refreshed share once all the holders acknowledged
+// the key the node computed, and broadcasts the acknowledgements.
+func (s *Service) complete(sess *session) error {
+	if sess.refreshed == nil || len(sess.acks) < sess.committee.Size() {
+		return nil
+	}
+	var height int64
+	for index, ack := range sess.acks {
+		if !bytes.Equal(ack.KeyHash, sess.keyHash) {
+			s.fail(sess, fmt.Errorf("holder %d computed master public key %X, the node %X", index, ack.KeyHash, sess.keyHash))
+			return nil
+		}
+		if ack.SwitchedAt <= sess.id.height || ack.SwitchedAt > sess.id.height+s.maxLead {
+			s.fail(sess, fmt.Errorf("holder %d proposed switch height %d out of range (%d, %d]",
+				index, ack.SwitchedAt, sess.id.height, sess.id.height+s.maxLead))
+			return nil
+		}
+		if ack.SwitchedAt > height {
+			height = ack.SwitchedAt
+		}
+	}
+	if err := s.keeper.ScheduleRefresh(sess.id.epoch, sess.refreshed, height); err != nil {
+		s.fail(sess, err)
+		return nil
+	}
+	sess.finish(height, nil)
+	s.logger.Info("refresh: shares refreshed", "epoch", sess.id.epoch, "height", sess.id.height, "switch_height", height)
+	commit := &refreshCommit{Epoch: sess.id.epoch, Height: sess.id.height, Acks: make([]*refreshAck, sess.n)}
+	for index, ack := range sess.acks {
+		commit.Acks[index] = ack
+	}
+	return s.broadcast(alias.DKGRefreshCommit, sess.id.epoch, commit)
+}
+
+// fail abandons the session; s.mtx must be held.
+func (s *Service) fail(sess *session, err error) {
+	sess.finish(0, fmt.Errorf("refresh %s failed: %v", sess.id, err))
+	s.logger.Error("refresh: refresh failed", "epoch", sess.id.epoch, "height", sess.id.height, "error", err)
+}
+
+func (s *Service) broadcast(dataType alias.DKGDataType, epoch int, content interface{}) error {
+	data, err := encode(content)
+	if err != nil {
+		return err
+	}
+	if err := s.transport.Broadcast(&alias.DKGData{Type: dataType, RoundID: epoch, Data: data}); err != nil {
+		return fmt.Errorf("failed to broadcast %v: %v", dataType, err)
+	}
+	return nil
+}
+
+func (s *Service) address() []byte {
+	return s.transport.GetPrivValidator().GetPubKey().Address()
+}
+
+// session is the node's state of a refresh.
+type session struct {
+	id        sessionID
+	started   time.Time
+	committee *types.ParticipantSet
+	base      *blsShare.BLSVerifier
+	t, n      int
+
+	suite *edwards25519.SuiteEd25519 // Of the ephemeral keys.
+	key   *ephemeralKey
+	keys  []kyber.Point // By share index.
+	dealt bool
+
+	deals  map[int]*share.PubPoly // Of the holders, by share index.
+	shares map[int]kyber.Scalar   // Evaluations of the holders' polynomials at the node's index.
+
+	refreshed *blsShare.BLSVerifier
+	keyHash   []byte
+	acks      map[int]*refreshAck
+
+	switchHeight int64
+	err          error
+	done         chan struct{}
+}
+
+type ephemeralKey struct {
+	Private kyber.Scalar
+	Public  kyber.Point
+}
+
+func newSession(id sessionID, verifier types.Verifier, committee *types.ParticipantSet, addr []byte) (*session, error) {
+	base, ok := blsShare.UnwrapVerifier(verifier)
+	if !ok || base.Keypair == nil {
+		return nil, fmt.Errorf("verifier holds no BLS share")
+	}
+	t, n := base.Threshold()
+	if committee.Size() != n {
+		return nil, fmt.Errorf("committee has %d holders, the key %d shares", committee.Size(), n)
+	}
+	index, holder := committee.GetByAddress(addr)
+	if holder == nil {
+		return nil, fmt.Errorf("node is not a holder")
+	}
+	if base.Keypair.Priv.I != index {
+		return nil, fmt.Errorf("node holds share %d, its index is %d", base.Keypair.Priv.I, index)
+	}
+	suite := edwards25519.NewBlakeSHA256Ed25519()
+	private := suite.Scalar().Pick(random.New())
+	return &session{
+		id:        id,
+		started:   time.Now(),
+		committee: committee,
+		base:      base,
+		t:         t,
+		n:         n,
+		suite:     suite,
+		key:       &ephemeralKey{Private: private, Public: suite.Point().Mul(private, nil)},
+		keys:      make([]kyber.Point, n),
+		deals:     make(map[int]*share.PubPoly),
+		shares:    make(map[int]kyber.Scalar),
+		acks:      make(map[int]*refreshAck),
+		done:      make(chan struct{}),
+	}, nil
+}
+
+func (sess *session) index() int {
+	return sess.base.Keypair.Priv.I
+}
+
+func (sess *session) allKeys() bool {
+	for _, key := range sess.keys {
+		if key == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// deal picks a random polynomial with a zero secret and encrypts its
+// evaluations to the holders' ephemeral keys.
+func (sess *session) deal() (*refreshDeal, error) {
+	g2 := bn256.NewSuiteG2()
+	poly := share.NewPriPoly(g2, sess.t, g2.Scalar().Zero(), random.New())
+	_, commits := poly.Commit(nil).Info()
+	deal := &refreshDeal{Epoch: sess.id.epoch, Height: sess.id.height}
+	for _, commit := range commits {
+		data, err := commit.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode commitment: %v", err)
+		}
+		deal.Commits = append(deal.Commits, data)
+	}
+	for i, evaluation := range poly.Shares(sess.n) {
+		data, err := evaluation.V.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode share: %v", err)
+		}
+		encrypted, err := ecies.Encrypt(sess.suite, sess.keys[i], data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt share of holder %d: %v", i, err)
+		}
+		deal.Shares = append(deal.Shares, encrypted)
+	}
+	return deal, nil
+}
+
+// addDeal checks the deal commits to a polynomial of the threshold's degree
+// with a zero secret, and that the node's evaluation matches it.
+func (sess *session) addDeal(index int, msg *refreshDeal) error {
+	g2 := bn256.NewSuiteG2()
+	if len(msg.Commits) != sess.t {
+		return fmt.Errorf("%d commitments, want %d", len(msg.Commits), sess.t)
+	}
+	if len(msg.Shares) != sess.n {
+		return fmt.Errorf("%d shares, want %d", len(msg.Shares), sess.n)
+	}
+	commits := make([]kyber.Point, len(msg.Commits))
+	for i, data := range msg.Commits {
+		commits[i] = g2.Point()
+		if err := commits[i].UnmarshalBinary(data); err != nil {
+			return fmt.Errorf("invalid commitment %d: %v", i, err)
+		}
+	}
+	if !commits[0].Equal(g2.Point().Null()) {
+		return fmt.Errorf("polynomial has a non-zero secret")
+	}
+	poly := share.NewPubPoly(g2, nil, commits)
+
+	data, err := ecies.Decrypt(sess.suite, sess.key.Private, msg.Shares[sess.index()], nil)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt share: %v", err)
+	}
+	evaluation := g2.Scalar()
+	if err := evaluation.UnmarshalBinary(data); err != nil {
+		return fmt.Errorf("invalid share: %v", err)
+	}
+	if !g2.Point().Mul(evaluation, nil).Equal(poly.Eval(sess.index()).V) {
+		return fmt.Errorf("share doesn't match the commitments")
+	}
+	sess.deals[index] = poly
+	sess.shares[index] = evaluation
+	return nil
+}
+
+// combine adds up the deals into the refreshed share and master public key.
+func (sess *session) combine() error {
+	g2 := bn256.NewSuiteG2()
+	var (
+		masterPubKey = sess.base.MasterPubKey()
+		priv         = g2.Scalar().Set(sess.base.Keypair.Priv.V)
+		err          error
+	)
+	for index := 0; index < sess.n; index++ {
+		if masterPubKey, err = masterPubKey.Add(sess.deals[index]); err != nil {
+			return fmt.Errorf("failed to add deal of holder %d: %v", index, err)
+		}
+		priv.Add(priv, sess.shares[index])
+	}
+	if !masterPubKey.Commit().Equal(sess.base.MasterPubKey().Commit()) {
+		return fmt.Errorf("refresh changed the group key")
+	}
+	keypair := &blsShare.BLSShare{
+		ID:   sess.index(),
+		Pub:  masterPubKey.Eval(sess.index()),
+		Priv: &share.PriShare{I: sess.index(), V: priv},
+	}
+	refreshed := blsShare.NewBLSVerifier(masterPubKey, keypair, sess.t, sess.n)
+	snapshot, err := types.NewVerifierSnapshot(refreshed, sess.id.epoch)
+	if err != nil {
+		return err
+	}
+	sess.refreshed, sess.keyHash = refreshed, types.MasterPubKeyHash(snapshot)
+	return nil
+}
+
+// verifyAck checks the acknowledgement is of the session and signed by the
+// holder of the share index.
+func (sess *session) verifyAck(index int, ack *refreshAck) error {
+	if ack == nil || ack.Epoch != sess.id.epoch || ack.Height != sess.id.height {
+		return fmt.Errorf("acknowledgement of holder %d is not of refresh %s", index, sess.id)
+	}
+	holder := sess.committee.Participants()[index]
+	if !holder.PubKey.VerifyBytes(ack.SignBytes(""), ack.Signature) {
+		return fmt.Errorf("invalid acknowledgement signature of holder %d", index)
+	}
+	return nil
+}
+
+func (sess *session) finished() bool {
+	select {
+	case <-sess.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (sess *session) finish(switchHeight int64, err error) {
+	sess.switchHeight, sess.err = switchHeight, err
+	close(sess.done)
+}
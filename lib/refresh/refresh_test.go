@@ -0,0 +1,153 @@
+package refresh
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+type testKeeper struct {
+	verifier  types.Verifier
+	committee *types.ParticipantSet
+	denied    error
+
+	scheduled    types.Verifier
+	switchHeight int64
+}
+
+func (k *testKeeper) EpochVerifier() (types.Verifier, int) { return k.verifier, 1 }
+
+func (k *testKeeper) Committee(int) (*types.ParticipantSet, error) { return k.committee, nil }
+
+func (k *testKeeper) LastHeight() int64 { return 10 }
+
+func (k *testKeeper) CheckRefresh(int) error { return k.denied }
+
+func (k *testKeeper) ScheduleRefresh(epoch int, verifier types.Verifier, height int64) error {
+	if k.denied != nil {
+		return k.denied
+	}
+	k.scheduled, k.switchHeight = verifier, height
+	return nil
+}
+
+// testNetwork delivers the broadcast messages to all the holders' services,
+// but those drop returns true for.
+type testNetwork struct {
+	keepers  []*testKeeper
+	services []*Service
+	queue    []*alias.DKGData
+	drop     func(msg *alias.DKGData, to int) bool
+}
+
+type testTransport struct {
+	network *testNetwork
+	pv      tmtypes.PrivValidator
+}
+
+func (tr *testTransport) Broadcast(msg *alias.DKGData) error {
+	msg.Addr = tr.pv.GetPubKey().Address()
+	tr.network.queue = append(tr.network.queue, msg)
+	return nil
+}
+
+func (tr *testTransport) GetPrivValidator() tmtypes.PrivValidator { return tr.pv }
+
+func newTestNetwork(t *testing.T, n int) *testNetwork {
+	keyring, err := blsShare.NewBLSKeyring(n/2+1, n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		pvs          = make(map[string]tmtypes.PrivValidator, n)
+		participants []*types.Participant
+	)
+	for i := 0; i < n; i++ {
+		pv := tmtypes.NewMockPVWithParams(ed25519.GenPrivKey(), false, false)
+		pubKey := pv.GetPubKey()
+		pvs[string(pubKey.Address())] = pv
+		participants = append(participants, &types.Participant{Address: pubKey.Address(), PubKey: pubKey})
+	}
+	committee := types.NewParticipantSetFromList(participants)
+
+	network := &testNetwork{}
+	for i, participant := range committee.Participants() {
+		keeper := &testKeeper{
+			verifier:  blsShare.NewBLSVerifier(keyring.MasterPubKey, keyring.Shares[i], keyring.T, keyring.N),
+			committee: committee,
+		}
+		transport := &testTransport{network: network, pv: pvs[string(participant.Address)]}
+		network.keepers = append(network.keepers, keeper)
+		network.services = append(network.services, NewService(transport, keeper))
+	}
+	return network
+}
+
+func (n *testNetwork) deliver(t *testing.T) {
+	for len(n.queue) > 0 {
+		msg := n.queue[0]
+		n.queue = n.queue[1:]
+		for i, s := range n.services {
+			if n.drop != nil && n.drop(msg, i) {
+				continue
+			}
+			if err := s.handle(msg); err != nil {
+				t.Fatalf("holder %d failed to handle %v: %v", i, msg.Type, err)
+			}
+		}
+	}
+}
+
+// TestRefreshCompletesWithDroppedAck checks a holder that missed an
+// acknowledgement schedules its refreshed share from the others' commits.
+func TestRefreshCompletesWithDroppedAck(t *testing.T) {
+	network := newTestNetwork(t, 4)
+	sender := network.services[0].address()
+	network.drop = func(msg *alias.DKGData, to int) bool {
+		return msg.Type == alias.DKGRefreshAck && to == 3 && bytes.Equal(msg.Addr, sender)
+	}
+	if _, err := network.services[0].start(10); err != nil {
+		t.Fatal(err)
+	}
+	network.deliver(t)
+
+	for i, keeper := range network.keepers {
+		if keeper.scheduled == nil {
+			t.Fatalf("holder %d didn't schedule its refreshed share", i)
+		}
+		if keeper.switchHeight != network.keepers[0].switchHeight {
+			t.Fatalf("holder %d switches at %d, holder 0 at %d", i, keeper.switchHeight, network.keepers[0].switchHeight)
+		}
+		refreshed, _ := blsShare.UnwrapVerifier(keeper.scheduled)
+		base, _ := blsShare.UnwrapVerifier(keeper.verifier)
+		if !refreshed.MasterPubKey().Commit().Equal(base.MasterPubKey().Commit()) {
+			t.Fatalf("holder %d refreshed share is of another group key", i)
+		}
+		if refreshed.Keypair.Priv.V.Equal(base.Keypair.Priv.V) {
+			t.Fatalf("holder %d share wasn't refreshed", i)
+		}
+	}
+}
+
+// TestRefreshNotScheduledByAnyHolder checks no holder schedules its refreshed
+// share if one of them can't.
+func TestRefreshNotScheduledByAnyHolder(t *testing.T) {
+	network := newTestNetwork(t, 4)
+	network.keepers[2].denied = errors.New("refresh is not authorized")
+	if _, err := network.services[0].start(10); err != nil {
+		t.Fatal(err)
+	}
+	network.deliver(t)
+
+	for i, keeper := range network.keepers {
+		if keeper.scheduled != nil {
+			t.Fatalf("holder %d scheduled its refreshed share", i)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package roundtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+// RoundStats describes a completed in-memory DKG round.
+type RoundStats struct {
+	Validators int
+	Duration   time.Duration
+	Messages   map[alias.DKGDataType]int // Number of broadcast messages by type.
+	Bytes      int                       // Total payload size of the broadcast messages.
+	Verifiers  []types.Verifier          // Of the dealers, ordered by participant index.
+}
+
+func (s *RoundStats) TotalMessages() int {
+	var total int
+	for _, count := range s.Messages {
+		total += count
+	}
+	return total
+}
+
+// network delivers every broadcast message to all dealers in the order it was sent.
+// Dealers handle a message concurrently, like the nodes of a real network would.
+type network struct {
+	mtx     sync.Mutex
+	dealers []dealer.Dealer
+	queue   []*alias.DKGData
+	stats   *RoundStats
+}
+
+// RunRound runs a complete off-chain DKG round between n in-memory dealers with
+// fresh keys and returns once every dealer has a verifier.
+func RunRound(n int) (*RoundStats, error) {
+	pvs, validators := newValidators(n)
+	participants := types.NewParticipantSet(validators, nil)
+
+	net := &network{stats: &RoundStats{Validators: n, Messages: make(map[alias.DKGDataType]int)}}
+	for _, pv := range pvs {
+		pv := pv
+		sendMsgCb := func(data []*alias.DKGData) error {
+			for _, item := range data {
+				if err := pv.SignData("", item); err != nil {
+					return err
+				}
+				net.mtx.Lock()
+				net.stats.Messages[item.Type]++
+				net.stats.Bytes += len(item.Data)
+				net.queue = append(net.queue, item)
+				net.mtx.Unlock()
+			}
+			return nil
+		}
+		net.dealers = append(net.dealers, dealer.NewDKGDealer(participants, pv, sendMsgCb, nopFirer{}, logging.NewNopLogger(), 0))
+	}
+
+	started := time.Now()
+	for _, d := range net.dealers {
+		if err := d.Start(); err != nil {
+			return nil, fmt.Errorf("failed to start dealer: %v", err)
+		}
+	}
+	if err := net.run(); err != nil {
+		return nil, err
+	}
+	net.stats.Verifiers = make([]types.Verifier, n)
+	for i, d := range net.dealers {
+		verifier, err := d.GetVerifier()
+		if err != nil {
+			return nil, fmt.Errorf("dealer %d has no verifier: %v", i, err)
+		}
+		index, _ := participants.GetByAddress(pvs[i].GetPubKey().Address())
+		net.stats.Verifiers[index] = verifier
+	}
+	net.stats.Duration = time.Since(started)
+
+	return net.stats, nil
+}
+
+// newValidators creates n validators with fresh keys.
+func newValidators(n int) ([]tmtypes.PrivValidator, *tmtypes.ValidatorSet) {
+	var (
+		pvs        = make([]tmtypes.PrivValidator, n)
+		validators = make([]*tmtypes.Validator, n)
+	)
+	for i := range pvs {
+		pvs[i] = tmtypes.NewMockPVWithParams(ed25519.GenPrivKey(), false, false)
+		pubKey := pvs[i].GetPubKey()
+		validators[i] = &tmtypes.Validator{Address: pubKey.Address(), PubKey: pubKey, VotingPower: 1}
+	}
+	return pvs, tmtypes.NewValidatorSet(validators)
+}
+
+func (net *network) run() error {
+	for {
+		net.mtx.Lock()
+		if len(net.queue) == 0 {
+			net.mtx.Unlock()
+			return nil
+		}
+		msg := net.queue[0]
+		net.queue = net.queue[1:]
+		net.mtx.Unlock()
+
+		var (
+			wg   sync.WaitGroup
+			errs = make([]error, len(net.dealers))
+		)
+		for i, d := range net.dealers {
+			wg.Add(1)
+			go func(i int, d dealer.Dealer) {
+				defer wg.Done()
+				if err := d.VerifyMessage(types.DKGDataMessage{Data: msg}); err != nil {
+					errs[i] = fmt.Errorf("failed to verify %s message: %v", msg.Type, err)
+					return
+				}
+				if err := handle(d, msg); err != nil {
+					errs[i] = fmt.Errorf("failed to handle %s message: %v", msg.Type, err)
+				}
+			}(i, d)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func handle(d dealer.Dealer, msg *alias.DKGData) error {
+	if handler := dealer.Handler(d, msg.Type); handler != nil {
+		return handler(msg)
+	}
+	return nil
+}
+
+// Benchmark runs rounds of n validators under the testing package's benchmark
+// driver, reporting allocations as well as the round latency and message count
+// per round, so results can be tracked outside of `go test`.
+func Benchmark(n int) (testing.BenchmarkResult, error) {
+	var runErr error
+	result := testing.Benchmark(func(b *testing.B) {
+		if runErr = BenchmarkRounds(b, n); runErr != nil {
+			b.FailNow()
+		}
+	})
+
+	return result, runErr
+}
+
+// BenchmarkRounds runs b.N rounds of n validators, reporting allocations and
+// the messages per round; benchmarks of `go test -bench` call it.
+func BenchmarkRounds(b *testing.B, n int) error {
+	b.ReportAllocs()
+	var messages, bytes int
+	for i := 0; i < b.N; i++ {
+		stats, err := RunRound(n)
+		if err != nil {
+			return err
+		}
+		messages += stats.TotalMessages()
+		bytes += stats.Bytes
+	}
+	b.ReportMetric(float64(messages)/float64(b.N), "msgs/op")
+	b.ReportMetric(float64(bytes)/float64(b.N), "msg-bytes/op")
+	return nil
+}
+
+type nopFirer struct{}
+
+func (nopFirer) FireEvent(event string, data events.EventData) {}
@@ -0,0 +1,24 @@
+package roundtest
+
+import "testing"
+
+func TestRunRound(t *testing.T) {
+	stats, err := RunRound(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.TotalMessages() == 0 {
+		t.Fatal("round sent no messages")
+	}
+}
+
+func benchmarkRound(b *testing.B, n int) {
+	if err := BenchmarkRounds(b, n); err != nil {
+		b.Fatal(err)
+	}
+}
+
+func BenchmarkRound4(b *testing.B)  { benchmarkRound(b, 4) }
+func BenchmarkRound16(b *testing.B) { benchmarkRound(b, 16) }
+func BenchmarkRound32(b *testing.B) { benchmarkRound(b, 32) }
+func BenchmarkRound64(b *testing.B) { benchmarkRound(b, 64) }
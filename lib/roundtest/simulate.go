@@ -0,0 +1,264 @@
+package roundtest
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// SimulationStep is a message delivery of a simulated round.
+type SimulationStep struct {
+	Step int
+	At   time.Duration // Virtual time since the round start.
+	From int           // Indexes of the dealers in the validator set.
+	To   int
+	Type alias.DKGDataType
+	ID   string // See types.MessageCorrelationID.
+}
+
+// SimulationResult describes a simulated round. Simulations with the same
+// parameters and seed have the same result.
+type SimulationResult struct {
+	Seed         int64
+	Validators   int
+	Deliveries   int
+	Duration     time.Duration // Virtual time the round took.
+	Trace        []byte        // Hash of the delivery schedule.
+	MasterPubKey []byte        // Hash of the group key, see types.MasterPubKeyHash.
+}
+
+type simulationConfig struct {
+	minLatency time.Duration
+	maxLatency time.Duration
+	trace      func(SimulationStep)
+	logger     logging.Logger
+}
+
+// SimulationOption sets an optional parameter of Simulate.
+type SimulationOption func(*simulationConfig)
+
+// WithLatency sets the range of the virtual latency of a delivery, 10ms to
+// 200ms by default.
+func WithLatency(min, max time.Duration) SimulationOption {
+	return func(c *simulationConfig) { c.minLatency, c.maxLatency = min, max }
+}
+
+// WithSimulationTrace calls the callback before every delivery.
+func WithSimulationTrace(trace func(SimulationStep)) SimulationOption {
+	return func(c *simulationConfig) { c.trace = trace }
+}
+
+// WithSimulationLogger logs the dealers to the logger, with the dealer's index
+// under the "dealer" key.
+func WithSimulationLogger(logger logging.Logger) SimulationOption {
+	return func(c *simulationConfig) { c.logger = logger }
+}
+
+// Simulate runs a complete off-chain DKG round between n in-memory dealers in a
+// single goroutine, with everything derived from the seed: the validator keys,
+// the dealers' randomness, see Dealer.SetSeed, and the latency of every
+// delivery. Deliveries happen in the order of their virtual arrival time and the
+// dealers time the round with the virtual clock, see Dealer.SetClock, so a
+// failing round is reproduced exactly by simulating it with the same seed.
+func Simulate(n int, seed int64, options ...SimulationOption) (*SimulationResult, error) {
+	config := &simulationConfig{
+		minLatency: 10 * time.Millisecond,
+		maxLatency: 200 * time.Millisecond,
+		logger:     logging.NewNopLogger(),
+	}
+	for _, option := range options {
+		option(config)
+	}
+	if config.minLatency < 0 || config.maxLatency < config.minLatency {
+		return nil, fmt.Errorf("invalid latency range %s-%s", config.minLatency, config.maxLatency)
+	}
+
+	sim := &simulation{
+		config:  config,
+		rng:     rand.New(rand.NewSource(seed)),
+		start:   time.Unix(0, 0).UTC(),
+		trace:   sha256.New(),
+		index:   make(map[string]int),
+		dealers: make([]dealer.Dealer, n),
+	}
+	sim.now = sim.start
+
+	pvs, validators := sim.validators(n)
+	participants := types.NewParticipantSet(validators, nil)
+	for i, validator := range validators.Validators {
+		sim.index[validator.Address.String()] = i
+	}
+	for _, pv := range pvs {
+		var (
+			pv = pv
+			i  = sim.index[pv.GetPubKey().Address().String()]
+		)
+		sendMsgCb := func(data []*alias.DKGData) error {
+			for _, item := range data {
+				if err := pv.SignData("", item); err != nil {
+					return err
+				}
+				sim.outbox = append(sim.outbox, item)
+			}
+			return nil
+		}
+		d := dealer.NewDKGDealer(participants, pv, sendMsgCb, nopFirer{}, config.logger.With("dealer", i), 0)
+		d.SetSeed(sim.bytes(32))
+		d.SetClock(func() time.Time { return sim.now })
+		sim.dealers[i] = d
+	}
+
+	result := &SimulationResult{Seed: seed, Validators: n}
+	for i, d := range sim.dealers {
+		if err := d.Start(); err != nil {
+			return nil, fmt.Errorf("seed %d: failed to start dealer %d: %v", seed, i, err)
+		}
+		sim.schedule()
+	}
+	if err := sim.run(); err != nil {
+		return nil, fmt.Errorf("seed %d: %v", seed, err)
+	}
+
+	for i, d := range sim.dealers {
+		verifier, err := d.GetVerifier()
+		if err != nil {
+			return nil, fmt.Errorf("seed %d: dealer %d has no verifier: %v", seed, i, err)
+		}
+		snapshot, err := types.NewVerifierSnapshot(verifier, 0)
+		if err != nil {
+			return nil, fmt.Errorf("seed %d: %v", seed, err)
+		}
+		masterPubKey := types.MasterPubKeyHash(snapshot)
+		if result.MasterPubKey != nil && !bytes.Equal(result.MasterPubKey, masterPubKey) {
+			return nil, fmt.Errorf("seed %d: dealer %d generated master public key %X, dealer 0 %X",
+				seed, i, masterPubKey, result.MasterPubKey)
+		}
+		result.MasterPubKey = masterPubKey
+	}
+	result.Deliveries = sim.steps
+	result.Duration = sim.now.Sub(sim.start)
+	result.Trace = sim.trace.Sum(nil)
+
+	return result, nil
+}
+
+// inFlight is a message on its way to a dealer.
+type inFlight struct {
+	at  time.Time
+	to  int
+	msg *alias.DKGData
+}
+
+// simulation is the scheduler of a simulated round: it delivers the messages
+// one at a time in the order of their arrival time, drawn from the PRNG when
+// they are sent.
+type simulation struct {
+	config  *simulationConfig
+	rng     *rand.Rand
+	start   time.Time
+	now     time.Time
+	dealers []dealer.Dealer
+	index   map[string]int // Of the dealers by address.
+
+	outbox  []*alias.DKGData // Sent by the dealer handling the current delivery.
+	pending []*inFlight      // In the order they were sent.
+	steps   int
+	trace   hash.Hash
+}
+
+// validators creates n validators with keys drawn from the PRNG.
+func (sim *simulation) validators(n int) ([]tmtypes.PrivValidator, *tmtypes.ValidatorSet) {
+	var (
+		pvs        = make([]tmtypes.PrivValidator, n)
+		validators = make([]*tmtypes.Validator, n)
+	)
+	for i := range pvs {
+		pvs[i] = tmtypes.NewMockPVWithParams(ed25519.GenPrivKeyFromSecret(sim.bytes(32)), false, false)
+		pubKey := pvs[i].GetPubKey()
+		validators[i] = &tmtypes.Validator{Address: pubKey.Address(), PubKey: pubKey, VotingPower: 1}
+	}
+	return pvs, tmtypes.NewValidatorSet(validators)
+}
+
+func (sim *simulation) bytes(size int) []byte {
+	b := make([]byte, size)
+	sim.rng.Read(b)
+	return b
+}
+
+// schedule sends the messages of the outbox to every dealer. Dealers may send
+// a batch in any order, e.g. that of a map, so the messages are sorted before
+// their latencies are drawn.
+func (sim *simulation) schedule() {
+	outbox := sim.outbox
+	sim.outbox = nil
+	sort.SliceStable(outbox, func(i, j int) bool {
+		return types.MessageCorrelationID(outbox[i]) < types.MessageCorrelationID(outbox[j])
+	})
+	for _, msg := range outbox {
+		for to := range sim.dealers {
+			latency := sim.config.minLatency
+			if spread := int64(sim.config.maxLatency - sim.config.minLatency); spread > 0 {
+				latency += time.Duration(sim.rng.Int63n(spread + 1))
+			}
+			sim.pending = append(sim.pending, &inFlight{at: sim.now.Add(latency), to: to, msg: msg})
+		}
+	}
+}
+
+// next removes the message arriving first from the pending ones; of those
+// arriving at the same time, the one sent first.
+func (sim *simulation) next() *inFlight {
+	first := 0
+	for i, d := range sim.pending {
+		if d.at.Before(sim.pending[first].at) {
+			first = i
+		}
+	}
+	d := sim.pending[first]
+	sim.pending = append(sim.pending[:first], sim.pending[first+1:]...)
+	return d
+}
+
+func (sim *simulation) run() error {
+	for len(sim.pending) > 0 {
+		next := sim.next()
+		sim.now = next.at
+		step := SimulationStep{
+			Step: sim.steps,
+			At:   sim.now.Sub(sim.start),
+			From: sim.index[next.msg.GetAddrString()],
+			To:   next.to,
+			Type: next.msg.Type,
+			ID:   types.MessageCorrelationID(next.msg),
+		}
+		sim.steps++
+		fmt.Fprintf(sim.trace, "%d/%d/%d/%s\n", step.At, step.From, step.To, step.ID)
+		if sim.config.trace != nil {
+			sim.config.trace(step)
+		}
+
+		d := sim.dealers[next.to]
+		if err := d.VerifyMessage(types.DKGDataMessage{Data: next.msg}); err != nil {
+			return fmt.Errorf("step %d: dealer %d failed to verify %s message of dealer %d: %v",
+				step.Step, step.To, step.Type, step.From, err)
+		}
+		if err := handle(d, next.msg); err != nil {
+			return fmt.Errorf("step %d: dealer %d failed to handle %s message of dealer %d: %v",
+				step.Step, step.To, step.Type, step.From, err)
+		}
+		sim.schedule()
+	}
+	return nil
+}
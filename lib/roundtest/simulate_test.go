@@ -0,0 +1,40 @@
+package roundtest
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSimulateIsDeterministic(t *testing.T) {
+	var steps []SimulationStep
+	first, err := Simulate(4, 42, WithSimulationTrace(func(step SimulationStep) {
+		steps = append(steps, step)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != first.Deliveries {
+		t.Fatalf("traced %d steps of %d deliveries", len(steps), first.Deliveries)
+	}
+	second, err := Simulate(4, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Trace, second.Trace) {
+		t.Fatalf("traces of seed 42 differ: %X and %X", first.Trace, second.Trace)
+	}
+	if !bytes.Equal(first.MasterPubKey, second.MasterPubKey) {
+		t.Fatalf("master keys of seed 42 differ: %X and %X", first.MasterPubKey, second.MasterPubKey)
+	}
+	if first.Duration != second.Duration || first.Deliveries != second.Deliveries {
+		t.Fatalf("rounds of seed 42 differ: %+v and %+v", first, second)
+	}
+
+	other, err := Simulate(4, 43)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(first.Trace, other.Trace) {
+		t.Fatal("seeds 42 and 43 have the same trace")
+	}
+}
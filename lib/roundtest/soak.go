@@ -0,0 +1,294 @@
+package roundtest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/offChain"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/libs/events"
+	"github.com/tendermint/tendermint/libs/log"
+)
+
+const (
+	// SoakRoundBlocks is the number of blocks between the rounds of a soak.
+	SoakRoundBlocks = 30
+
+	soakListener = "roundtest-soak"
+)
+
+// SoakSample describes the resources in use after a round of a soak.
+type SoakSample struct {
+	Round      int            `json:"round"`
+	Height     int64          `json:"height"`
+	Goroutines int            `json:"goroutines"`
+	HeapAlloc  uint64         `json:"heap_alloc"`
+	StateSizes map[string]int `json:"state_sizes"` // The largest among the nodes, see OffChainDKG.StateSizes.
+}
+
+// SoakReport describes a completed soak.
+type SoakReport struct {
+	Validators int           `json:"validators"`
+	Rounds     int           `json:"rounds"`
+	Duration   time.Duration `json:"duration"`
+	Samples    []SoakSample  `json:"samples"`
+}
+
+type soakConfig struct {
+	goroutineSlack  int
+	heapGrowth      float64
+	stateSlack      int
+	offChainOptions []offChain.DKGOption
+	progress        func(SoakSample)
+}
+
+// SoakOption sets an optional parameter of Soak.
+type SoakOption func(*soakConfig)
+
+// WithGoroutineSlack sets how many goroutines may be added to the baseline, 10
+// by default. The baseline is the sample of the round halfway through the soak,
+// so bounded histories have filled up by then.
+func WithGoroutineSlack(goroutines int) SoakOption {
+	return func(c *soakConfig) { c.goroutineSlack = goroutines }
+}
+
+// WithHeapGrowth sets how many times the heap may grow past the baseline, 2 by
+// default.
+func WithHeapGrowth(ratio float64) SoakOption {
+	return func(c *soakConfig) { c.heapGrowth = ratio }
+}
+
+// WithStateSlack sets how many entries may be added to the baseline of every
+// per-round state, 2 by default.
+func WithStateSlack(entries int) SoakOption {
+	return func(c *soakConfig) { c.stateSlack = entries }
+}
+
+// WithSoakNodeOptions sets options of the nodes, e.g. to soak a feature.
+func WithSoakNodeOptions(options ...offChain.DKGOption) SoakOption {
+	return func(c *soakConfig) { c.offChainOptions = options }
+}
+
+// WithSoakProgress calls the callback with the sample of every round.
+func WithSoakProgress(progress func(SoakSample)) SoakOption {
+	return func(c *soakConfig) { c.progress = progress }
+}
+
+// soakCluster delivers the messages gossiped by the nodes to the other nodes.
+type soakCluster struct {
+	mtx        sync.Mutex
+	nodes      []*offChain.OffChainDKG
+	switches   []events.EventSwitch
+	queue      []delivery
+	keyChanges []int // Epoch of every node's verifier.
+	validators *tmtypes.ValidatorSet
+	lastHeight int64
+}
+
+type delivery struct {
+	to  int
+	msg *alias.DKGData
+}
+
+// Soak runs the rounds between n in-process off-chain nodes, scheduled every
+// SoakRoundBlocks blocks, and samples the goroutines, the heap and the
+// per-round state of the nodes after every round. It fails if a round doesn't
+// complete or if the resources in use at the end exceed those halfway through
+// by more than the slack, i.e. grow with the number of rounds; the report is
+// returned in both cases.
+func Soak(n, rounds int, options ...SoakOption) (*SoakReport, error) {
+	config := &soakConfig{goroutineSlack: 10, heapGrowth: 2, stateSlack: 2}
+	for _, option := range options {
+		option(config)
+	}
+	if n < 2 {
+		return nil, fmt.Errorf("a soak needs at least 2 validators, got %d", n)
+	}
+	if rounds < 2 {
+		return nil, fmt.Errorf("a soak needs at least 2 rounds, got %d", rounds)
+	}
+
+	cluster, err := newSoakCluster(n, config.offChainOptions)
+	if err != nil {
+		return nil, err
+	}
+	defer cluster.stop()
+
+	report := &SoakReport{Validators: n, Rounds: rounds}
+	started := time.Now()
+	for round := 1; round <= rounds; round++ {
+		if err := cluster.runRound(round); err != nil {
+			report.Duration = time.Since(started)
+			return report, err
+		}
+		sample := cluster.sample(round)
+		report.Samples = append(report.Samples, sample)
+		if config.progress != nil {
+			config.progress(sample)
+		}
+	}
+	report.Duration = time.Since(started)
+
+	return report, config.check(report)
+}
+
+// check compares the last sample with the one halfway through the soak.
+func (c *soakConfig) check(report *SoakReport) error {
+	var (
+		base     = report.Samples[(len(report.Samples)-1)/2]
+		last     = report.Samples[len(report.Samples)-1]
+		problems []string
+	)
+	if last.Goroutines > base.Goroutines+c.goroutineSlack {
+		problems = append(problems, fmt.Sprintf("goroutines grew from %d to %d", base.Goroutines, last.Goroutines))
+	}
+	if float64(last.HeapAlloc) > float64(base.HeapAlloc)*c.heapGrowth {
+		problems = append(problems, fmt.Sprintf("heap grew from %d to %d bytes", base.HeapAlloc, last.HeapAlloc))
+	}
+	for name, size := range last.StateSizes {
+		if size > base.StateSizes[name]+c.stateSlack {
+			problems = append(problems, fmt.Sprintf("%s grew from %d to %d entries", name, base.StateSizes[name], size))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("resource usage grows between rounds %d and %d: %s",
+			base.Round, last.Round, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func newSoakCluster(n int, nodeOptions []offChain.DKGOption) (*soakCluster, error) {
+	pvs, validators := newValidators(n)
+	cluster := &soakCluster{
+		validators: validators,
+		keyChanges: make([]int, n),
+	}
+	for i, pv := range pvs {
+		evsw := events.NewEventSwitch()
+		if err := evsw.Start(); err != nil {
+			cluster.stop()
+			return nil, fmt.Errorf("failed to start event switch: %v", err)
+		}
+		options := append([]offChain.DKGOption{
+			offChain.WithPVKey(pv),
+			offChain.WithLogger(log.NewNopLogger()),
+			offChain.WithDKGNumBlocks(SoakRoundBlocks),
+		}, nodeOptions...)
+		cluster.nodes = append(cluster.nodes, offChain.NewOffChainDKG(evsw, "", options...))
+		cluster.switches = append(cluster.switches, evsw)
+		cluster.listen(i, evsw)
+	}
+	return cluster, nil
+}
+
+func (c *soakCluster) listen(from int, evsw events.EventSwitch) {
+	evsw.AddListenerForEvent(soakListener, types.EventDKGData, func(data events.EventData) {
+		msg := data.(*alias.DKGData)
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		for to := range c.nodes {
+			if to != from {
+				c.queue = append(c.queue, delivery{to: to, msg: msg})
+			}
+		}
+	})
+	evsw.AddListenerForEvent(soakListener, types.EventDKGKeyChange, func(data events.EventData) {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
+		c.keyChanges[from] = data.(types.DKGKeyChangeEvent).Epoch
+	})
+}
+
+// runRound advances the chain until every node's verifier comes from the round.
+func (c *soakCluster) runRound(round int) error {
+	deadline := c.lastHeight + 2*SoakRoundBlocks + c.nodes[0].BlocksAhead()
+	for height := c.lastHeight + 1; height <= deadline; height++ {
+		c.lastHeight = height
+		for _, node := range c.nodes {
+			node.CheckDKGTime(height, c.validators)
+		}
+		c.deliver(height)
+		if c.switched(round) {
+			return nil
+		}
+	}
+	return fmt.Errorf("round %d didn't complete by height %d", round, deadline)
+}
+
+// deliver handles the queued messages until no node has any left.
+func (c *soakCluster) deliver(height int64) {
+	for {
+		delivered := false
+		for _, node := range c.nodes {
+			select {
+			case msg := <-node.MsgQueue():
+				node.HandleOffChainShare(msg, height, c.validators, nil)
+				delivered = true
+			default:
+			}
+			if next, ok := c.next(); ok {
+				c.nodes[next.to].HandleOffChainShare(&types.DKGDataMessage{Data: next.msg}, height, c.validators, nil)
+				delivered = true
+			}
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+func (c *soakCluster) next() (delivery, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if len(c.queue) == 0 {
+		return delivery{}, false
+	}
+	next := c.queue[0]
+	c.queue = c.queue[1:]
+	return next, true
+}
+
+func (c *soakCluster) switched(round int) bool {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for _, epoch := range c.keyChanges {
+		if epoch != round {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *soakCluster) sample(round int) SoakSample {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+
+	sample := SoakSample{
+		Round:      round,
+		Height:     c.lastHeight,
+		Goroutines: runtime.NumGoroutine(),
+		HeapAlloc:  stats.HeapAlloc,
+		StateSizes: make(map[string]int),
+	}
+	for _, node := range c.nodes {
+		for name, size := range node.StateSizes() {
+			if size > sample.StateSizes[name] {
+				sample.StateSizes[name] = size
+			}
+		}
+	}
+	return sample
+}
+
+func (c *soakCluster) stop() {
+	for _, evsw := range c.switches {
+		evsw.RemoveListener(soakListener)
+		evsw.Stop()
+	}
+}
@@ -0,0 +1,36 @@
+package roundtest
+
+import "testing"
+
+func TestSoak(t *testing.T) {
+	if testing.Short() {
+		t.Skip("soaks run several rounds")
+	}
+	// The nodes keep the attestations of the last 16 rounds, so the bounded
+	// histories fill up by the halfway sample.
+	const rounds = 34
+	var progress []SoakSample
+	report, err := Soak(4, rounds, WithSoakProgress(func(sample SoakSample) {
+		progress = append(progress, sample)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Samples) != rounds || len(progress) != rounds {
+		t.Fatalf("%d samples and %d progress calls, want %d", len(report.Samples), len(progress), rounds)
+	}
+	for i, sample := range report.Samples {
+		if sample.Round != i+1 {
+			t.Fatalf("sample %d is of round %d", i, sample.Round)
+		}
+	}
+}
+
+func TestSoakRejectsShortSoaks(t *testing.T) {
+	if _, err := Soak(1, 2); err == nil {
+		t.Fatal("soak of one validator didn't fail")
+	}
+	if _, err := Soak(4, 1); err == nil {
+		t.Fatal("soak of one round didn't fail")
+	}
+}
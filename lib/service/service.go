@@ -0,0 +1,84 @@
+// Package service holds the skeleton shared by the services exchanging their
+// messages through the off-chain DKG, e.g. the signer, the share refresh and
+// the share recovery: the transport and the queue of received messages,
+// handled one at a time.
+package service
+
+import (
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	tmtypes "github.com/tendermint/tendermint/alias"
+)
+
+// DefaultQueueSize is the number of received messages awaiting handling, see
+// Loop.HandleMessage.
+const DefaultQueueSize = 1024
+
+// Transport gossips the messages of a service between the participants;
+// offChain.OffChainDKG implements it, see its SetMessageHandler.
+type Transport interface {
+	// Broadcast signs the message as the node's and sends it to all the
+	// participants, the node included.
+	Broadcast(msg *alias.DKGData) error
+	GetPrivValidator() tmtypes.PrivValidator
+}
+
+// Loop queues the messages received by a service and handles them one at a
+// time, from Start until Stop. Services embed it, so it is the transport's
+// message handler.
+type Loop struct {
+	name   string
+	handle func(msg *alias.DKGData) error
+	logger logging.Logger
+
+	incoming chan *alias.DKGData
+	quit     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewLoop returns the loop handling the messages with handle; name prefixes
+// its logs.
+func NewLoop(name string, handle func(msg *alias.DKGData) error, logger logging.Logger) *Loop {
+	return &Loop{
+		name:     name,
+		handle:   handle,
+		logger:   logger,
+		incoming: make(chan *alias.DKGData, DefaultQueueSize),
+		quit:     make(chan struct{}),
+	}
+}
+
+// Start handles the received messages until Stop is called.
+func (l *Loop) Start() {
+	go l.run()
+}
+
+func (l *Loop) Stop() {
+	l.stopOnce.Do(func() { close(l.quit) })
+}
+
+func (l *Loop) run() {
+	for {
+		select {
+		case msg := <-l.incoming:
+			if err := l.handle(msg); err != nil {
+				l.logger.Info(l.name+": failed to handle message", "type", msg.Type, "from", msg.GetAddrString(), "error", err)
+			}
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// HandleMessage queues the message for handling; messages received while the
+// queue is full are dropped. It doesn't block, so the transport may call it
+// with its locks held.
+func (l *Loop) HandleMessage(msg *alias.DKGData) {
+	select {
+	case l.incoming <- msg:
+	default:
+		l.logger.Error(l.name+": queue is full, dropping message", "type", msg.Type, "from", msg.GetAddrString())
+	}
+}
@@ -0,0 +1,100 @@
+package signer
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName is the content subtype of the gRPC service, whose messages are
+// encoded as JSON rather than protobuf.
+const CodecName = "json"
+
+// ServiceName is the name of the gRPC service.
+const ServiceName = "dkglib.signer.Signer"
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                               { return CodecName }
+
+// SignRequest is the request of the gRPC Sign method.
+type SignRequest struct {
+	Message   []byte     `json:"message"`
+	Requester string     `json:"requester"`
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+// signServer is the gRPC service.
+type signServer interface {
+	Sign(ctx context.Context, req *SignRequest) (*Signature, error)
+}
+
+type grpcServer struct {
+	service *Service
+}
+
+func (s *grpcServer) Sign(ctx context.Context, req *SignRequest) (*Signature, error) {
+	return s.service.Sign(ctx, req.Message, req.Requester, req.Approvals...)
+}
+
+// RegisterGRPC serves the service on the gRPC server. The requester is taken
+// from the request as is; authenticating callers is up to the server's
+// interceptors.
+func RegisterGRPC(server *grpc.Server, service *Service) {
+	server.RegisterService(&serviceDesc, &grpcServer{service: service})
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*signServer)(nil),
+	Methods: []grpc.MethodDesc{{
+		MethodName: "Sign",
+		Handler:    signHandler,
+	}},
+	Metadata: "lib/signer/grpc.go",
+}
+
+func signHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	req := new(SignRequest)
+	if err := dec(req); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(signServer).Sign(ctx, req)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Sign"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(signServer).Sign(ctx, req.(*SignRequest))
+	}
+	return interceptor(ctx, req, info, handler)
+}
+
+// Client calls the gRPC service of a share holder.
+type Client struct {
+	conn *grpc.ClientConn
+}
+
+func NewClient(conn *grpc.ClientConn) *Client {
+	return &Client{conn: conn}
+}
+
+// Sign has the group sign the message on behalf of the requester, see
+// Service.Sign.
+func (c *Client) Sign(ctx context.Context, message []byte, requester string, approvals ...Approval) (*Signature, error) {
+	out := new(Signature)
+	req := &SignRequest{Message: message, Requester: requester, Approvals: approvals}
+	err := c.conn.Invoke(ctx, "/"+ServiceName+"/Sign", req, out,
+		grpc.CallContentSubtype(CodecName))
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
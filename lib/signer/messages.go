@@ -0,0 +1,58 @@
+package signer
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// signRequest is the content of a SignRequest message, broadcast by the node a
+// message was submitted to.
+type signRequest struct {
+	ID        []byte     `json:"id"`
+	Epoch     int        `json:"epoch"`
+	Message   []byte     `json:"message"`
+	Requester string     `json:"requester"`
+	Approvals []Approval `json:"approvals,omitempty"`
+}
+
+func (r *signRequest) Encode() ([]byte, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sign request: %v", err)
+	}
+	return data, nil
+}
+
+func decodeSignRequest(data []byte) (*signRequest, error) {
+	var r signRequest
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to decode sign request: %v", err)
+	}
+	if len(r.ID) == 0 {
+		return nil, fmt.Errorf("sign request has no ID")
+	}
+	return &r, nil
+}
+
+// signShare is the content of a SignShare message, a share holder's signature
+// share of a requested message.
+type signShare struct {
+	ID    []byte `json:"id"`
+	Share []byte `json:"share"`
+}
+
+func (s *signShare) Encode() ([]byte, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode sign share: %v", err)
+	}
+	return data, nil
+}
+
+func decodeSignShare(data []byte) (*signShare, error) {
+	var s signShare
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode sign share: %v", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,205 @@
+package signer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto"
+	cryptoamino "github.com/tendermint/tendermint/crypto/encoding/amino"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// Request is a sign request as seen by a Policy.
+type Request struct {
+	ID        []byte
+	Epoch     int
+	Message   []byte
+	Requester string
+	Origin    crypto.Address // Share holder the request was submitted to.
+	Approvals []Approval
+}
+
+// Approval is an approver's signature of the ApprovalSignBytes of a request,
+// see RequireApprovals.
+type Approval struct {
+	PubKey    []byte `json:"pub_key"` // Amino encoded, see crypto.PubKey.Bytes.
+	Signature []byte `json:"signature"`
+}
+
+// NewApproval signs the approval of the requester's message in the epoch.
+func NewApproval(key crypto.PrivKey, epoch int, requester string, message []byte) (Approval, error) {
+	sig, err := key.Sign(ApprovalSignBytes(epoch, requester, message))
+	if err != nil {
+		return Approval{}, fmt.Errorf("failed to sign approval: %v", err)
+	}
+	return Approval{PubKey: key.PubKey().Bytes(), Signature: sig}, nil
+}
+
+// Policy decides whether the share holder contributes its signature share to a
+// request. Every holder evaluates its own policy before signing, the holder the
+// request was submitted to included, so a message is only signed if enough
+// holders allow it. Holders should run the same policy: stateful policies,
+// like rate limits, count the requests every holder sees alike, but their
+// windows follow the holder's clock.
+type Policy interface {
+	Allow(request *Request) error
+}
+
+// PolicyFunc adapts a function to Policy.
+type PolicyFunc func(request *Request) error
+
+func (f PolicyFunc) Allow(request *Request) error {
+	return f(request)
+}
+
+// AllPolicies allows the requests all of the policies allow, evaluated in
+// order until one refuses; stateless policies should come first, so refused
+// requests don't count against rate limits.
+func AllPolicies(policies ...Policy) Policy {
+	return PolicyFunc(func(request *Request) error {
+		for _, policy := range policies {
+			if err := policy.Allow(request); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AllowPrefixes allows the messages starting with one of the prefixes.
+func AllowPrefixes(prefixes ...[]byte) Policy {
+	return PolicyFunc(func(request *Request) error {
+		for _, prefix := range prefixes {
+			if bytes.HasPrefix(request.Message, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("message has none of the allowed prefixes")
+	})
+}
+
+// ApprovalSignBytes returns the statement approvers sign to approve the
+// requester's message in the epoch, see RequireApprovals.
+func ApprovalSignBytes(epoch int, requester string, message []byte) []byte {
+	var buf bytes.Buffer
+	writeBytes := func(b []byte) {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		buf.Write(n[:])
+		buf.Write(b)
+	}
+	writeBytes([]byte("dkglib/signer/Approval"))
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(epoch))
+	buf.Write(n[:])
+	writeBytes([]byte(requester))
+	writeBytes(tmhash.Sum(message))
+	return buf.Bytes()
+}
+
+// RequireApprovals allows the requests approved by threshold distinct
+// approvers, see ApprovalSignBytes.
+func RequireApprovals(threshold int, approvers ...crypto.PubKey) (Policy, error) {
+	keys := make(map[string]crypto.PubKey)
+	for _, approver := range approvers {
+		keys[approver.Address().String()] = approver
+	}
+	if threshold < 1 || threshold > len(keys) {
+		return nil, fmt.Errorf("threshold %d out of range for %d approvers", threshold, len(keys))
+	}
+	return PolicyFunc(func(request *Request) error {
+		var (
+			signBytes = ApprovalSignBytes(request.Epoch, request.Requester, request.Message)
+			signers   = make(map[string]bool)
+		)
+		for _, approval := range request.Approvals {
+			pubKey, err := cryptoamino.PubKeyFromBytes(approval.PubKey)
+			if err != nil {
+				continue
+			}
+			addr := pubKey.Address().String()
+			approver, ok := keys[addr]
+			if !ok || signers[addr] || !approver.Equals(pubKey) {
+				continue
+			}
+			if approver.VerifyBytes(signBytes, approval.Signature) {
+				signers[addr] = true
+			}
+		}
+		if len(signers) < threshold {
+			return fmt.Errorf("%d approvals required, got %d valid", threshold, len(signers))
+		}
+		return nil
+	}), nil
+}
+
+// DefaultRateWindow is the window of rate limits and quotas set without a
+// positive one, see RateLimit and RequesterQuota.
+const DefaultRateWindow = time.Minute
+
+// RateLimit allows at most limit requests per window, DefaultRateWindow if it
+// isn't positive.
+func RateLimit(limit int, window time.Duration) Policy {
+	return newWindowLimiter(limit, window, func(*Request) string { return "" }, "requests")
+}
+
+// RequesterQuota allows at most quota requests per window, DefaultRateWindow if
+// it isn't positive, for each requester of each share holder, as the holders
+// name their requesters independently.
+func RequesterQuota(quota int, window time.Duration) Policy {
+	return newWindowLimiter(quota, window, func(request *Request) string {
+		return request.Origin.String() + "/" + request.Requester
+	}, "requests of the requester")
+}
+
+// windowLimiter counts the requests it allowed in a sliding window by key.
+type windowLimiter struct {
+	limit  int
+	window time.Duration
+	key    func(*Request) string
+	what   string
+
+	mtx      sync.Mutex
+	requests map[string][]time.Time
+	now      func() time.Time
+}
+
+func newWindowLimiter(limit int, window time.Duration, key func(*Request) string, what string) *windowLimiter {
+	if window <= 0 {
+		window = DefaultRateWindow
+	}
+	return &windowLimiter{
+		limit:    limit,
+		window:   window,
+		key:      key,
+		what:     what,
+		requests: make(map[string][]time.Time),
+		now:      time.Now,
+	}
+}
+
+func (l *windowLimiter) Allow(request *Request) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.now()
+	for key, times := range l.requests {
+		for len(times) > 0 && now.Sub(times[0]) >= l.window {
+			times = times[1:]
+		}
+		if len(times) == 0 {
+			delete(l.requests, key)
+		} else {
+			l.requests[key] = times
+		}
+	}
+	key := l.key(request)
+	if len(l.requests[key]) >= l.limit {
+		return fmt.Errorf("more than %d %s per %s", l.limit, l.what, l.window)
+	}
+	l.requests[key] = append(l.requests[key], now)
+	return nil
+}
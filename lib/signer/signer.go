@@ -0,0 +1,324 @@
+// Package signer turns the key shares generated by DKG rounds into a threshold
+// signing backend: messages submitted to any share holder are signed by the
+// group once enough holders contributed their signature shares.
+package signer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/service"
+	"github.com/corestario/dkglib/lib/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+	"go.dedis.ch/kyber/v3/sign/tbls"
+)
+
+// Domain prefixes the messages the service signs, so its signatures can't be
+// passed off as the group's signatures of the chain's random data.
+const Domain = "dkglib/signer/v1\x00"
+
+// DefaultQueueSize is the number of received messages awaiting handling, see
+// HandleMessage.
+const DefaultQueueSize = service.DefaultQueueSize
+
+// Transport gossips the messages of the service between the share holders.
+type Transport = service.Transport
+
+// VerifierSource returns the verifier the service signs with and its epoch, the
+// round it was generated in.
+type VerifierSource interface {
+	EpochVerifier() (types.Verifier, int)
+}
+
+// Signature is the group's signature of a message.
+type Signature struct {
+	ID        []byte `json:"id"`
+	Epoch     int    `json:"epoch"`
+	Message   []byte `json:"message"`
+	Signature []byte `json:"signature"` // Of Payload(Message), verifiable with the epoch's master public key.
+	Shares    int    `json:"shares"`    // Number of signature shares collected.
+}
+
+// Payload returns the data the service signs for the message.
+func Payload(message []byte) []byte {
+	return append([]byte(Domain), message...)
+}
+
+// Service signs messages with the group key of the current epoch. Every share
+// holder runs one, registered as the transport's handler of the DKGSignRequest
+// and DKGSignShare messages, and serves the requests of the others.
+type Service struct {
+	transport Transport
+	verifiers VerifierSource
+	policy    Policy // Nil allows every request.
+	logger    logging.Logger
+
+	mtx     sync.Mutex
+	pending map[string]*pendingRequest // By request ID.
+
+	*service.Loop
+}
+
+// Option sets an optional parameter on the Service.
+type Option func(*Service)
+
+// WithPolicy contributes the node's signature shares only to the requests the
+// policy allows; the node's own requests are refused before they are sent.
+func WithPolicy(policy Policy) Option {
+	return func(s *Service) { s.policy = policy }
+}
+
+func WithLogger(logger logging.Logger) Option {
+	return func(s *Service) { s.logger = logger }
+}
+
+func NewService(transport Transport, verifiers VerifierSource, options ...Option) *Service {
+	s := &Service{
+		transport: transport,
+		verifiers: verifiers,
+		logger:    logging.NewNopLogger(),
+		pending:   make(map[string]*pendingRequest),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.Loop = service.NewLoop("signer", s.handle, s.logger)
+	return s
+}
+
+// Sign has the group sign the message with the current epoch's key, on behalf
+// of the requester, and waits until enough share holders contributed or the
+// context is done. The approvals are passed to the holders' policies, see
+// RequireApprovals.
+func (s *Service) Sign(ctx context.Context, message []byte, requester string, approvals ...Approval) (*Signature, error) {
+	verifier, epoch := s.verifiers.EpochVerifier()
+	if verifier == nil || verifier.IsNil() {
+		return nil, fmt.Errorf("no verifier to sign with")
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to pick request nonce: %v", err)
+	}
+	request := &signRequest{
+		ID:        tmhash.Sum(append(append(nonce, s.address()...), message...)),
+		Epoch:     epoch,
+		Message:   message,
+		Requester: requester,
+		Approvals: approvals,
+	}
+	if err := s.allow(request, s.address()); err != nil {
+		return nil, err
+	}
+
+	p := newPendingRequest(request, verifier)
+	s.mtx.Lock()
+	s.pending[string(request.ID)] = p
+	s.mtx.Unlock()
+	defer func() {
+		s.mtx.Lock()
+		delete(s.pending, string(request.ID))
+		s.mtx.Unlock()
+	}()
+
+	own, err := verifier.Sign(p.payload)
+	if err != nil {
+		return nil, err
+	}
+	p.add(own)
+	data, err := request.Encode()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.transport.Broadcast(&alias.DKGData{Type: alias.DKGSignRequest, RoundID: epoch, Data: data}); err != nil {
+		return nil, fmt.Errorf("failed to broadcast sign request: %v", err)
+	}
+	s.logger.Debug("signer: sign request sent", "id", fmt.Sprintf("%X", request.ID), "epoch", epoch, "requester", requester)
+
+	select {
+	case <-p.done:
+		return p.signature, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to collect signature shares (%d): %v", p.count(), ctx.Err())
+	}
+}
+
+func (s *Service) handle(msg *alias.DKGData) error {
+	switch msg.Type {
+	case alias.DKGSignRequest:
+		if bytes.Equal(msg.Addr, s.address()) {
+			return nil
+		}
+		request, err := decodeSignRequest(msg.Data)
+		if err != nil {
+			return err
+		}
+		return s.handleRequest(request, msg.Addr)
+	case alias.DKGSignShare:
+		share, err := decodeSignShare(msg.Data)
+		if err != nil {
+			return err
+		}
+		return s.handleShare(msg, share)
+	}
+	return nil
+}
+
+// handleRequest contributes the node's signature share to the request.
+func (s *Service) handleRequest(request *signRequest, origin crypto.Address) error {
+	verifier, epoch := s.verifiers.EpochVerifier()
+	if verifier == nil || verifier.IsNil() {
+		return fmt.Errorf("no verifier to sign with")
+	}
+	if request.Epoch != epoch {
+		return fmt.Errorf("request %X is for epoch %d, not %d", request.ID, request.Epoch, epoch)
+	}
+	if err := s.allow(request, origin); err != nil {
+		return err
+	}
+	sig, err := verifier.Sign(Payload(request.Message))
+	if err != nil {
+		return err
+	}
+	data, err := (&signShare{ID: request.ID, Share: sig}).Encode()
+	if err != nil {
+		return err
+	}
+	if err := s.transport.Broadcast(&alias.DKGData{Type: alias.DKGSignShare, RoundID: epoch, Data: data}); err != nil {
+		return fmt.Errorf("failed to broadcast sign share: %v", err)
+	}
+	s.logger.Debug("signer: signature share sent", "id", fmt.Sprintf("%X", request.ID), "requester", request.Requester)
+	return nil
+}
+
+// handleShare collects the share of a request submitted to the node.
+func (s *Service) handleShare(msg *alias.DKGData, share *signShare) error {
+	s.mtx.Lock()
+	p, ok := s.pending[string(share.ID)]
+	s.mtx.Unlock()
+	if !ok {
+		return nil
+	}
+	if err := p.verifier.VerifyRandomShare(msg.GetAddrString(), p.payload, share.Share); err != nil {
+		return fmt.Errorf("invalid share of request %X: %v", share.ID, err)
+	}
+	p.add(share.Share)
+	return nil
+}
+
+// allow evaluates the policy for the request submitted to the origin.
+func (s *Service) allow(request *signRequest, origin crypto.Address) error {
+	if s.policy == nil {
+		return nil
+	}
+	err := s.policy.Allow(&Request{
+		ID:        request.ID,
+		Epoch:     request.Epoch,
+		Message:   request.Message,
+		Requester: request.Requester,
+		Origin:    origin,
+		Approvals: request.Approvals,
+	})
+	if err != nil {
+		return fmt.Errorf("request %X of %s refused by policy: %v", request.ID, request.Requester, err)
+	}
+	return nil
+}
+
+func (s *Service) address() crypto.Address {
+	return s.transport.GetPrivValidator().GetPubKey().Address()
+}
+
+// pendingRequest collects the signature shares of a request submitted to the
+// node.
+type pendingRequest struct {
+	mtx       sync.Mutex
+	request   *signRequest
+	payload   []byte
+	verifier  types.Verifier
+	shares    map[int][]byte // By share index.
+	signature *Signature
+	done      chan struct{}
+}
+
+func newPendingRequest(request *signRequest, verifier types.Verifier) *pendingRequest {
+	return &pendingRequest{
+		request:  request,
+		payload:  Payload(request.Message),
+		verifier: verifier,
+		shares:   make(map[int][]byte),
+		done:     make(chan struct{}),
+	}
+}
+
+// add records the verified share and recovers the signature once there are
+// enough of them.
+func (p *pendingRequest) add(sig []byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.signature != nil {
+		return
+	}
+	index, err := tbls.SigShare(sig).Index()
+	if err != nil {
+		return
+	}
+	if _, ok := p.shares[index]; ok {
+		return
+	}
+	p.shares[index] = sig
+	if t, ok := threshold(p.verifier); ok && len(p.shares) < t {
+		return
+	}
+
+	var signers []blsShare.BLSSigner
+	for _, share := range p.shares {
+		signers = append(signers, &shareSigner{sig: share, hash: p.request.ID})
+	}
+	sig, err = p.verifier.Recover(p.payload, signers)
+	if err != nil {
+		return
+	}
+	if err := p.verifier.VerifyRandomData(p.payload, sig); err != nil {
+		return
+	}
+	p.signature = &Signature{
+		ID:        p.request.ID,
+		Epoch:     p.request.Epoch,
+		Message:   p.request.Message,
+		Signature: sig,
+		Shares:    len(p.shares),
+	}
+	close(p.done)
+}
+
+func (p *pendingRequest) count() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.shares)
+}
+
+// threshold returns the number of shares the verifier recovers signatures
+// from, if it tells.
+func threshold(verifier types.Verifier) (int, bool) {
+	if v, ok := verifier.(interface{ Threshold() (t, n int) }); ok {
+		t, _ := v.Threshold()
+		return t, true
+	}
+	return 0, false
+}
+
+// shareSigner passes a signature share to Verifier.Recover.
+type shareSigner struct {
+	sig  []byte
+	hash []byte
+}
+
+func (s *shareSigner) GetBLSSignature() []byte { return s.sig }
+func (s *shareSigner) GetHash() []byte         { return s.hash }
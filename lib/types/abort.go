@@ -0,0 +1,88 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// Blame names a participant a dealer excluded from a round and why.
+type Blame struct {
+	Addr     crypto.Address `json:"addr"`
+	Reason   string         `json:"reason"`
+	Evidence []byte         `json:"evidence,omitempty"` // EvidenceHash of the offending messages, if any.
+	// Messages are the offending messages, so peers can check the evidence.
+	Messages []*alias.DKGData `json:"messages,omitempty"`
+}
+
+// CheckEvidence checks that the evidence hash is that of the blame's messages,
+// all sent by the blamed participant. Their signatures are checked by the
+// nodes knowing the participant's key.
+func (b *Blame) CheckEvidence() error {
+	if len(b.Evidence) == 0 {
+		if len(b.Messages) > 0 {
+			return fmt.Errorf("blame of %s has messages but no evidence", b.Addr)
+		}
+		return nil
+	}
+	if len(b.Messages) == 0 {
+		return fmt.Errorf("blame of %s has evidence %X but no messages", b.Addr, b.Evidence)
+	}
+	for _, msg := range b.Messages {
+		if msg == nil || !bytes.Equal(msg.Addr, b.Addr) {
+			return fmt.Errorf("blame of %s has evidence sent by another participant", b.Addr)
+		}
+	}
+	if hash := EvidenceHash(b.Messages...); !bytes.Equal(hash, b.Evidence) {
+		return fmt.Errorf("evidence %X of the blame of %s doesn't match its messages' %X", b.Evidence, b.Addr, hash)
+	}
+	return nil
+}
+
+// AbortClaim is the content of an Abort message, broadcast by a participant
+// whose dealer concluded the round can't complete.
+type AbortClaim struct {
+	Reason string  `json:"reason"`
+	Blames []Blame `json:"blames"`
+}
+
+func (c *AbortClaim) Encode() ([]byte, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode abort claim: %v", err)
+	}
+	return data, nil
+}
+
+func DecodeAbortClaim(data []byte) (*AbortClaim, error) {
+	var claim AbortClaim
+	if err := json.Unmarshal(data, &claim); err != nil {
+		return nil, fmt.Errorf("failed to decode abort claim: %v", err)
+	}
+	for _, blame := range claim.Blames {
+		if len(blame.Addr) != crypto.AddressSize {
+			return nil, fmt.Errorf("invalid blamed address length: %d", len(blame.Addr))
+		}
+		if err := blame.CheckEvidence(); err != nil {
+			return nil, err
+		}
+	}
+	return &claim, nil
+}
+
+// EvidenceHash hashes the signed messages, so peers holding the same ones can
+// match the evidence of a blame without the messages being sent again.
+func EvidenceHash(msgs ...*alias.DKGData) []byte {
+	if len(msgs) == 0 {
+		return nil
+	}
+	var data []byte
+	for _, msg := range msgs {
+		data = append(data, tmhash.Sum(append(msg.ContentBytes(), msg.Signature...))...)
+	}
+	return tmhash.Sum(data)
+}
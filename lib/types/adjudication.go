@@ -0,0 +1,44 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// AdjudicationVerdict is the outcome of the external adjudication of a
+// participant's exclusion from a round.
+type AdjudicationVerdict int
+
+const (
+	// AdjudicationUpheld confirms the exclusion of the participant.
+	AdjudicationUpheld AdjudicationVerdict = iota + 1
+	// AdjudicationDismissed finds the complaints against the participant
+	// bogus and reinstates it.
+	AdjudicationDismissed
+)
+
+func (v AdjudicationVerdict) String() string {
+	switch v {
+	case AdjudicationUpheld:
+		return "upheld"
+	case AdjudicationDismissed:
+		return "dismissed"
+	}
+	return fmt.Sprintf("AdjudicationVerdict(%d)", int(v))
+}
+
+// Adjudication is the decision of an external adjudicator, e.g. the chain's
+// governance, on the exclusion of a participant from a round.
+type Adjudication struct {
+	Addr     crypto.Address      `json:"addr"`
+	Verdict  AdjudicationVerdict `json:"verdict"`
+	Reason   string              `json:"reason,omitempty"`
+	Evidence []byte              `json:"evidence,omitempty"` // EvidenceHash of the adjudicated messages, if any.
+}
+
+// AdjudicationApplier is implemented by DKG instances that take adjudicated
+// exclusions into account, see dealer.Dealer.ApplyAdjudication.
+type AdjudicationApplier interface {
+	ApplyAdjudication(roundID int, decisions []Adjudication) error
+}
@@ -0,0 +1,235 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// adviceMargin multiplies the observed latencies into the recommended
+	// deadlines, so that rounds slower than the observed ones still fit.
+	adviceMargin = 2
+	// Bounds of the recommended intervals.
+	minCatchUpInterval = time.Second
+	minPollInterval    = 500 * time.Millisecond
+	maxPollInterval    = 10 * time.Second
+	// adviceBlockAlign is the alignment of the recommended block counts, that of
+	// the change heights.
+	adviceBlockAlign = 5
+)
+
+// PhaseTiming is the time since the round start at which the last participant's
+// message of a type arrived, over the sampled rounds.
+type PhaseTiming struct {
+	Type string        `json:"type"`
+	P50  time.Duration `json:"p50"`
+	P95  time.Duration `json:"p95"`
+	Max  time.Duration `json:"max"`
+	// Deadline is the recommended time since the round start to wait for the
+	// messages of the type before deeming their senders late.
+	Deadline time.Duration `json:"deadline"`
+}
+
+// TimingAdvice recommends timing settings for the network the sampled rounds
+// ran on, instead of the defaults.
+type TimingAdvice struct {
+	Rounds    int           `json:"rounds"` // Sampled rounds with latency data.
+	BlockTime time.Duration `json:"block_time,omitempty"`
+	Phases    []PhaseTiming `json:"phases"`
+	// PeerLag is the 95th percentile of the delay between the first and a later
+	// participant's message of the same type.
+	PeerLag time.Duration `json:"peer_lag"`
+	// RoundDuration is the 95th percentile of the time the last message of a
+	// round arrived at.
+	RoundDuration time.Duration `json:"round_duration"`
+
+	CatchUpInterval time.Duration `json:"catch_up_interval"` // offChain.WithCatchUp.
+	MaxRoundAge     time.Duration `json:"max_round_age"`     // offChain.WithMaxRoundAge.
+	PollInterval    time.Duration `json:"poll_interval"`     // onChain.WithPollInterval.
+	// BlocksAhead and PipelineLead are set if the block time is known.
+	BlocksAhead  int64    `json:"blocks_ahead,omitempty"`  // offChain.WithBlocksAhead.
+	PipelineLead int64    `json:"pipeline_lead,omitempty"` // offChain.WithPipelining.
+	Notes        []string `json:"notes,omitempty"`
+}
+
+// AdviseTimings recommends timing settings from the latency of the peers'
+// messages in the finished rounds' snapshots, see PeerInfo.Latency. The block
+// time, if not zero, is used for the settings counted in blocks.
+func AdviseTimings(rounds []*RoundInfo, blockTime time.Duration) (*TimingAdvice, error) {
+	var (
+		advice      = &TimingAdvice{BlockTime: blockTime}
+		completions = make(map[string][]time.Duration) // By message type, one per round.
+		durations   []time.Duration
+		lags        []time.Duration
+	)
+	for _, round := range rounds {
+		// The latency of a round in progress is that of its phases so far.
+		if round == nil || round.Result == RoundResultInProgress {
+			continue
+		}
+		var (
+			first    = make(map[string]time.Duration)
+			last     = make(map[string]time.Duration)
+			duration time.Duration
+		)
+		for _, peer := range round.Peers {
+			for dataType, latency := range peer.Latency {
+				if earliest, ok := first[dataType]; !ok || latency < earliest {
+					first[dataType] = latency
+				}
+				if latency > last[dataType] {
+					last[dataType] = latency
+				}
+			}
+		}
+		if len(last) == 0 {
+			continue
+		}
+		for _, peer := range round.Peers {
+			for dataType, latency := range peer.Latency {
+				lags = append(lags, latency-first[dataType])
+			}
+		}
+		for dataType, latency := range last {
+			completions[dataType] = append(completions[dataType], latency)
+			if latency > duration {
+				duration = latency
+			}
+		}
+		durations = append(durations, duration)
+		advice.Rounds++
+	}
+	if advice.Rounds == 0 {
+		return nil, fmt.Errorf("no round with latency data among %d", len(rounds))
+	}
+
+	for dataType, latencies := range completions {
+		sortDurations(latencies)
+		timing := PhaseTiming{
+			Type: dataType,
+			P50:  percentile(latencies, 50),
+			P95:  percentile(latencies, 95),
+			Max:  latencies[len(latencies)-1],
+		}
+		timing.Deadline = roundUp(adviceMargin * timing.P95)
+		advice.Phases = append(advice.Phases, timing)
+	}
+	sort.Slice(advice.Phases, func(i, j int) bool {
+		if advice.Phases[i].P50 != advice.Phases[j].P50 {
+			return advice.Phases[i].P50 < advice.Phases[j].P50
+		}
+		return advice.Phases[i].Type < advice.Phases[j].Type
+	})
+	sortDurations(lags)
+	sortDurations(durations)
+	advice.PeerLag = percentile(lags, 95)
+	advice.RoundDuration = percentile(durations, 95)
+
+	// A round that accepted nothing for twice the lag of late peers is
+	// missing messages rather than waiting for them.
+	advice.CatchUpInterval = roundUp(adviceMargin * advice.PeerLag)
+	if advice.CatchUpInterval < minCatchUpInterval {
+		advice.CatchUpInterval = minCatchUpInterval
+	}
+	advice.MaxRoundAge = roundUp(3 * durations[len(durations)-1])
+
+	// Messages land on chain once per block, so polling faster than half the
+	// block time is wasted; without it, a poll every half lag keeps up with
+	// the peers.
+	if blockTime > 0 {
+		advice.PollInterval = blockTime / 2
+	} else {
+		advice.PollInterval = advice.PeerLag / 2
+		advice.Notes = append(advice.Notes, "block time unknown: poll interval derived from the peer lag, block counts not advised")
+	}
+	if advice.PollInterval < minPollInterval {
+		advice.PollInterval = minPollInterval
+	}
+	if advice.PollInterval > maxPollInterval {
+		advice.PollInterval = maxPollInterval
+	}
+	advice.PollInterval = advice.PollInterval.Round(100 * time.Millisecond)
+
+	if blockTime > 0 {
+		// The change height agreement waits for the confirmations of the late
+		// peers; a pipelined round must complete within its lead.
+		advice.BlocksAhead = blocks(adviceMargin*advice.PeerLag, blockTime)
+		advice.PipelineLead = blocks(adviceMargin*advice.RoundDuration, blockTime) + advice.BlocksAhead
+	}
+	if advice.Rounds < 5 {
+		advice.Notes = append(advice.Notes, fmt.Sprintf("only %d rounds sampled, the percentiles are rough", advice.Rounds))
+	}
+	return advice, nil
+}
+
+// blocks returns the number of blocks spanning the duration, at least and
+// aligned to adviceBlockAlign.
+func blocks(d, blockTime time.Duration) int64 {
+	n := int64(math.Ceil(float64(d) / float64(blockTime)))
+	if rem := n % adviceBlockAlign; rem != 0 || n == 0 {
+		n += adviceBlockAlign - rem
+	}
+	return n
+}
+
+func sortDurations(ds []time.Duration) {
+	sort.Slice(ds, func(i, j int) bool { return ds[i] < ds[j] })
+}
+
+// percentile returns the nearest-rank percentile of the sorted durations.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(p)/100*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// roundUp rounds the duration up to a readable precision.
+func roundUp(d time.Duration) time.Duration {
+	precision := 100 * time.Millisecond
+	if d >= 10*time.Second {
+		precision = time.Second
+	}
+	if rem := d % precision; rem != 0 {
+		d += precision - rem
+	}
+	return d
+}
+
+// TimingSampler is implemented by DKG instances that keep the snapshots of
+// their last rounds, e.g. offChain.OffChainDKG.
+type TimingSampler interface {
+	TimingSamples() []*RoundInfo
+}
+
+// NewAdviceHandler returns an HTTP handler that responds with the TimingAdvice
+// for the sampler's rounds. The block time may be overridden with the
+// block_time query parameter, e.g. ?block_time=2s.
+func NewAdviceHandler(s TimingSampler, blockTime time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bt := blockTime
+		if value := r.URL.Query().Get("block_time"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed < 0 {
+				http.Error(w, "invalid block_time", http.StatusBadRequest)
+				return
+			}
+			bt = parsed
+		}
+		advice, err := AdviseTimings(s.TimingSamples(), bt)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(advice)
+	})
+}
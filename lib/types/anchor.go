@@ -0,0 +1,11 @@
+package types
+
+// AnchorChain anchors the epoch transitions on chain: the attestation of a
+// completed round is published once, and every node activates the round's
+// verifier at the change height of the anchored attestation.
+type AnchorChain interface {
+	PublishEpochAnchor(attestation *RoundAttestation) error
+	// EpochAnchors returns the attestations anchored for the round, none if
+	// it isn't anchored yet.
+	EpochAnchors(roundID int) ([]*RoundAttestation, error)
+}
@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// StagedVerifier describes a verifier awaiting operator approval.
+type StagedVerifier struct {
+	RoundID      int   `json:"round_id"`
+	ChangeHeight int64 `json:"change_height"`
+	// MasterPubKeyHash lets operators check that all nodes computed the same key;
+	// empty if the verifier's key can't be exported.
+	MasterPubKeyHash cmn.HexBytes `json:"master_pub_key_hash"`
+	StagedAt         time.Time    `json:"staged_at"`
+	Deadline         time.Time    `json:"deadline"` // The verifier is activated without approval after the deadline, if set.
+	Approved         bool         `json:"approved"`
+}
+
+// VerifierApprover is implemented by DKG instances that stage verifiers until
+// an operator approves them.
+type VerifierApprover interface {
+	// StagedVerifier returns the verifier awaiting approval, or nil if there is none.
+	StagedVerifier() *StagedVerifier
+	ApproveVerifier(roundID int) error
+}
+
+// NewApprovalHandler returns an HTTP handler that responds to GET requests with
+// the StagedVerifier and approves it on POST requests with the round_id query
+// parameter. It must only be served to operators, e.g. on a loopback address.
+func NewApprovalHandler(a VerifierApprover) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			roundID, err := strconv.Atoi(r.URL.Query().Get("round_id"))
+			if err != nil {
+				http.Error(w, "invalid round_id", http.StatusBadRequest)
+				return
+			}
+			if err := a.ApproveVerifier(roundID); err != nil {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		staged := a.StagedVerifier()
+		if staged == nil {
+			http.Error(w, "no staged verifier", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(staged)
+	})
+}
@@ -0,0 +1,205 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// RoundAttestation proves that every participant of a round completed it with
+// the same master public key, so external services (bridges, oracles) can trust
+// an epoch transition without running the protocol.
+type RoundAttestation struct {
+	RoundID      int               `json:"round_id"`
+	Verifier     *VerifierSnapshot `json:"verifier"`
+	ChangeHeight int64             `json:"change_height"`
+	Participants []*Participant    `json:"participants"`
+	// Confirmations are the signed change height proposals of the participants,
+	// which commit to the hash of the master public key.
+	Confirmations []*alias.DKGData `json:"confirmations"`
+	// Migrations move the shares of participants that rotated their keys
+	// after the round, see ShareMigration.
+	Migrations []*ShareMigration `json:"migrations,omitempty"`
+	// ChainID and SignVersion are the domain the confirmations are signed in,
+	// see alias.SignBytesV2. Attestations without a version are verified in
+	// the domain of the process.
+	ChainID     string `json:"chain_id,omitempty"`
+	SignVersion byte   `json:"sign_version,omitempty"`
+	// KeyPurpose is the group key the round generated, which the
+	// confirmations are signed for; empty for the default key.
+	KeyPurpose string `json:"key_purpose,omitempty"`
+	// Proofs were generated by the attesting node's proof hook; Verify
+	// doesn't check them.
+	Proofs []*RoundProof `json:"proofs,omitempty"`
+}
+
+// AttestationQuerier is implemented by DKG instances keeping the attestations
+// of completed rounds.
+type AttestationQuerier interface {
+	GetRoundAttestation(roundID int) (*RoundAttestation, error)
+}
+
+const confirmationHeightSize = 8
+
+// EncodeConfirmation encodes a change height proposal; keyHash is the
+// MasterPubKeyHash of the round's key, or nil if the key can't be exported.
+func EncodeConfirmation(changeHeight int64, keyHash []byte) []byte {
+	buf := make([]byte, confirmationHeightSize, confirmationHeightSize+len(keyHash))
+	binary.BigEndian.PutUint64(buf, uint64(changeHeight))
+	return append(buf, keyHash...)
+}
+
+func DecodeConfirmation(data []byte) (changeHeight int64, keyHash []byte, err error) {
+	if len(data) != confirmationHeightSize && len(data) != confirmationHeightSize+tmhash.Size {
+		return 0, nil, fmt.Errorf("invalid confirmation length: %d", len(data))
+	}
+	changeHeight = int64(binary.BigEndian.Uint64(data))
+	if len(data) > confirmationHeightSize {
+		keyHash = data[confirmationHeightSize:]
+	}
+	return changeHeight, keyHash, nil
+}
+
+// ChangeHeightQuorum returns the number of a round's n participants that must
+// propose the same change height for it to be agreed on. Two heights can't
+// both reach it.
+func ChangeHeightQuorum(n int) int {
+	return n*2/3 + 1
+}
+
+// AgreedChangeHeight returns the change height proposed by a quorum of the n
+// participants, or the largest proposal once all of them have proposed one.
+// The proposals are keyed by participant; every participant computes the same
+// value from them.
+func AgreedChangeHeight(heights map[string]int64, n int) (int64, bool) {
+	votes := make(map[int64]int)
+	for _, h := range heights {
+		votes[h]++
+		if votes[h] >= ChangeHeightQuorum(n) {
+			return h, true
+		}
+	}
+	if len(heights) < n {
+		return 0, false
+	}
+	var changeHeight int64
+	for _, h := range heights {
+		if h > changeHeight {
+			changeHeight = h
+		}
+	}
+	return changeHeight, true
+}
+
+func MasterPubKeyHash(verifier *VerifierSnapshot) []byte {
+	return tmhash.Sum([]byte(verifier.MasterPubKey))
+}
+
+// Verify checks the attestation against the committee the caller trusts to run
+// the round: every participant must have signed a confirmation of the attested
+// master public key, and the change height must be the one they agreed on, see
+// AgreedChangeHeight.
+// Participants that migrated their share may have confirmed with the new key.
+func (a *RoundAttestation) Verify(trusted *ParticipantSet) error {
+	if a.Verifier == nil {
+		return fmt.Errorf("attestation has no master public key")
+	}
+	if !bytes.Equal(NewParticipantSetFromList(a.Participants).Hash(), trusted.Hash()) {
+		return fmt.Errorf("attested participants differ from the trusted ones")
+	}
+	keys, err := a.verifyMigrations(trusted)
+	if err != nil {
+		return err
+	}
+
+	var (
+		keyHash = MasterPubKeyHash(a.Verifier)
+		heights = make(map[string]int64)
+	)
+	for _, data := range a.Confirmations {
+		if data.Type != alias.DKGChangeHeight || data.RoundID != a.RoundID {
+			return fmt.Errorf("confirmation from %s is not a change height of round %d", data.GetAddrString(), a.RoundID)
+		}
+		if data.KeyPurpose != a.KeyPurpose {
+			return fmt.Errorf("confirmation from %s is for key %q, not %q", data.GetAddrString(), data.KeyPurpose, a.KeyPurpose)
+		}
+		signer, ok := keys[data.GetAddrString()]
+		if !ok {
+			return fmt.Errorf("confirmation from unknown participant %s", data.GetAddrString())
+		}
+		if !signer.key.VerifyBytes(a.signBytes(data), data.Signature) {
+			return fmt.Errorf("invalid confirmation signature of %s", data.GetAddrString())
+		}
+		height, hash, err := DecodeConfirmation(data.Data)
+		if err != nil {
+			return fmt.Errorf("invalid confirmation of %s: %v", data.GetAddrString(), err)
+		}
+		if !bytes.Equal(hash, keyHash) {
+			return fmt.Errorf("participant %s confirmed a different master public key", data.GetAddrString())
+		}
+		heights[signer.participant.String()] = height
+	}
+
+	if len(heights) != trusted.Size() {
+		return fmt.Errorf("%d of %d participants confirmed the round", len(heights), trusted.Size())
+	}
+	if changeHeight, _ := AgreedChangeHeight(heights, trusted.Size()); changeHeight != a.ChangeHeight {
+		return fmt.Errorf("attested change height %d, confirmed %d", a.ChangeHeight, changeHeight)
+	}
+
+	return nil
+}
+
+func (a *RoundAttestation) signBytes(data *alias.DKGData) []byte {
+	if a.SignVersion == 0 {
+		return data.SignBytes(a.ChainID)
+	}
+	return data.SignBytesIn(a.ChainID, a.SignVersion)
+}
+
+// attestedKey is a key a participant of the round signed with.
+type attestedKey struct {
+	participant crypto.Address // Address of the participant in the round.
+	key         crypto.PubKey
+}
+
+// verifyMigrations checks the migrations apply in order to the holders of
+// the shares and returns the keys of the participants by address.
+func (a *RoundAttestation) verifyMigrations(trusted *ParticipantSet) (map[string]attestedKey, error) {
+	keys := make(map[string]attestedKey)
+	for _, participant := range trusted.Participants() {
+		keys[participant.Address.String()] = attestedKey{participant.Address, participant.PubKey}
+	}
+	holders := trusted
+	for i, migration := range a.Migrations {
+		if migration.Epoch > a.RoundID {
+			return nil, fmt.Errorf("migration %d is of later epoch %d", i, migration.Epoch)
+		}
+		if err := migration.Verify(a.ChainID); err != nil {
+			return nil, fmt.Errorf("invalid migration %d: %v", i, err)
+		}
+		if _, holder := holders.GetByAddress(migration.OldAddr()); holder == nil || !holder.PubKey.Equals(migration.OldPubKey) {
+			return nil, fmt.Errorf("migration %d is from %s, which holds no share", i, migration.OldAddr())
+		}
+		if _, holder := holders.GetByAddress(migration.NewAddr()); holder != nil {
+			return nil, fmt.Errorf("migration %d is to %s, which already holds a share", i, migration.NewAddr())
+		}
+		holders = holders.migrate(migration)
+		keys[migration.NewAddr().String()] = attestedKey{keys[migration.OldAddr().String()].participant, migration.NewPubKey}
+	}
+	return keys, nil
+}
+
+// Holders returns the participants with the keys currently holding their
+// shares, i.e. with the migrations applied in order.
+func (a *RoundAttestation) Holders() *ParticipantSet {
+	holders := NewParticipantSetFromList(a.Participants)
+	for _, migration := range a.Migrations {
+		holders = holders.migrate(migration)
+	}
+	return holders
+}
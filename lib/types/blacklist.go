@@ -0,0 +1,267 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto"
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// BlacklistChain publishes the blacklist votes of a node and reads the votes of
+// all participants back from the chain, so every honest node tallies the same votes.
+type BlacklistChain interface {
+	PublishBlacklistVote(vote *alias.DKGData) error
+	// BlacklistVotes returns the votes on the round included up to the height;
+	// zero means the latest height.
+	BlacklistVotes(roundID int, height int64) ([]*alias.DKGData, error)
+}
+
+// BlacklistEntry excludes a peer from the rounds after the one it was blacklisted
+// in, up to and including the Expires round; zero never expires.
+type BlacklistEntry struct {
+	Addr    crypto.Address `json:"addr"`
+	RoundID int            `json:"round_id"`
+	Expires int            `json:"expires"`
+	Reason  string         `json:"reason"`
+}
+
+func (e *BlacklistEntry) activeIn(roundID int) bool {
+	return roundID > e.RoundID && (e.Expires == 0 || roundID <= e.Expires)
+}
+
+// Blacklist keeps the peers excluded from subsequent rounds. Manual overrides
+// are local to the node, so operators must apply them on every node; otherwise
+// the round is aborted as desynchronized.
+type Blacklist struct {
+	mtx       sync.Mutex
+	entries   map[string]*BlacklistEntry
+	overrides map[string]crypto.Address // Peers that are never blacklisted by votes.
+	ttl       int
+	path      string // If set, the blacklist is persisted to this file after every change.
+}
+
+type blacklistJSON struct {
+	Entries   []*BlacklistEntry `json:"entries"`
+	Overrides []crypto.Address  `json:"overrides"`
+}
+
+// NewBlacklist creates a blacklist that is not persisted; entries adopted from
+// votes expire after ttl rounds, or never if ttl is zero.
+func NewBlacklist(ttl int) *Blacklist {
+	return &Blacklist{
+		entries:   make(map[string]*BlacklistEntry),
+		overrides: make(map[string]crypto.Address),
+		ttl:       ttl,
+	}
+}
+
+// LoadBlacklist loads the blacklist from the file, or creates a new one if the file does not exist.
+func LoadBlacklist(path string, ttl int) (*Blacklist, error) {
+	b := NewBlacklist(ttl)
+	b.path = path
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blacklist: %v", err)
+	}
+
+	var state blacklistJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode blacklist: %v", err)
+	}
+	for _, entry := range state.Entries {
+		b.entries[entry.Addr.String()] = entry
+	}
+	for _, addr := range state.Overrides {
+		b.overrides[addr.String()] = addr
+	}
+
+	return b, nil
+}
+
+// Entries returns the entries ordered by address.
+func (b *Blacklist) Entries() []*BlacklistEntry {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.sortedEntries()
+}
+
+func (b *Blacklist) sortedEntries() []*BlacklistEntry {
+	var out []*BlacklistEntry
+	for _, entry := range b.entries {
+		e := *entry
+		out = append(out, &e)
+	}
+	sort.Slice(out, func(i, j int) bool { return bytes.Compare(out[i].Addr, out[j].Addr) < 0 })
+	return out
+}
+
+// Ban blacklists the peer manually, replacing its entry.
+func (b *Blacklist) Ban(entry *BlacklistEntry) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	e := *entry
+	b.entries[e.Addr.String()] = &e
+	delete(b.overrides, e.Addr.String())
+	return b.save()
+}
+
+// Allow removes the peer from the blacklist and ignores votes against it until Remove is called.
+func (b *Blacklist) Allow(addr crypto.Address) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	delete(b.entries, addr.String())
+	b.overrides[addr.String()] = addr
+	return b.save()
+}
+
+// Remove deletes the entry and the override of the peer.
+func (b *Blacklist) Remove(addr crypto.Address) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	delete(b.entries, addr.String())
+	delete(b.overrides, addr.String())
+	return b.save()
+}
+
+// Adopt blacklists the peers voted against by more than a third of the round's
+// participants, i.e. by at least one honest participant. Invalid votes and votes
+// of non-participants are ignored, so nodes tallying the same votes adopt the
+// same entries. The votes are verified in the domain.
+func (b *Blacklist) Adopt(roundID int, votes []*alias.DKGData, participants *ParticipantSet, domain alias.SignDomain) ([]*BlacklistEntry, error) {
+	var (
+		voted  = make(map[string]bool)
+		counts = make(map[string]int)
+		addrs  = make(map[string]crypto.Address)
+	)
+	for _, vote := range votes {
+		if vote.Type != alias.DKGBlacklist || vote.RoundID != roundID || voted[vote.GetAddrString()] {
+			continue
+		}
+		_, participant := participants.GetByAddress(vote.Addr)
+		if participant == nil || !domain.Verify(participant.PubKey, vote) {
+			continue
+		}
+		peers, err := DecodeBlacklistVote(vote.Data)
+		if err != nil {
+			continue
+		}
+		voted[vote.GetAddrString()] = true
+		// A vote counts once against every peer it lists, however many times.
+		listed := make(map[string]bool, len(peers))
+		for _, peer := range peers {
+			if listed[peer.String()] {
+				continue
+			}
+			listed[peer.String()] = true
+			counts[peer.String()]++
+			addrs[peer.String()] = peer
+		}
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	var adopted []*BlacklistEntry
+	for key, count := range counts {
+		if _, ok := b.overrides[key]; ok || count*3 <= participants.Size() {
+			continue
+		}
+		entry := &BlacklistEntry{
+			Addr:    addrs[key],
+			RoundID: roundID,
+			Reason:  fmt.Sprintf("voted by %d of %d participants of round %d", count, participants.Size(), roundID),
+		}
+		if b.ttl > 0 {
+			entry.Expires = roundID + b.ttl
+		}
+		b.entries[key] = entry
+		adopted = append(adopted, entry)
+	}
+	if len(adopted) == 0 {
+		return nil, nil
+	}
+	sort.Slice(adopted, func(i, j int) bool { return bytes.Compare(adopted[i].Addr, adopted[j].Addr) < 0 })
+
+	return adopted, b.save()
+}
+
+// Filter removes the peers blacklisted for the round from the participants;
+// expired entries are dropped.
+func (b *Blacklist) Filter(participants *ParticipantSet, roundID int) *ParticipantSet {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	var expired bool
+	for key, entry := range b.entries {
+		if entry.Expires != 0 && roundID > entry.Expires {
+			delete(b.entries, key)
+			expired = true
+		}
+	}
+	if expired {
+		// A failure to persist only keeps expired entries on disk until the next change.
+		_ = b.save()
+	}
+
+	var out []*Participant
+	for _, participant := range participants.Participants() {
+		entry, ok := b.entries[participant.Address.String()]
+		if ok && entry.activeIn(roundID) {
+			continue
+		}
+		out = append(out, participant)
+	}
+	return NewParticipantSetFromList(out)
+}
+
+func (b *Blacklist) save() error {
+	if b.path == "" {
+		return nil
+	}
+	state := blacklistJSON{Entries: b.sortedEntries()}
+	for _, addr := range b.overrides {
+		state.Overrides = append(state.Overrides, addr)
+	}
+	sort.Slice(state.Overrides, func(i, j int) bool { return bytes.Compare(state.Overrides[i], state.Overrides[j]) < 0 })
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode blacklist: %v", err)
+	}
+	if err := cmn.WriteFileAtomic(b.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to persist blacklist: %v", err)
+	}
+	return nil
+}
+
+// EncodeBlacklistVote encodes the peers a participant votes to blacklist.
+func EncodeBlacklistVote(peers []crypto.Address) []byte {
+	sorted := append([]crypto.Address(nil), peers...)
+	sort.Slice(sorted, func(i, j int) bool { return bytes.Compare(sorted[i], sorted[j]) < 0 })
+	var buf bytes.Buffer
+	for _, peer := range sorted {
+		buf.Write(peer)
+	}
+	return buf.Bytes()
+}
+
+func DecodeBlacklistVote(data []byte) ([]crypto.Address, error) {
+	if len(data) == 0 || len(data)%crypto.AddressSize != 0 {
+		return nil, fmt.Errorf("invalid blacklist vote length: %d", len(data))
+	}
+	var peers []crypto.Address
+	for i := 0; i < len(data); i += crypto.AddressSize {
+		peers = append(peers, crypto.Address(data[i:i+crypto.AddressSize]))
+	}
+	return peers, nil
+}
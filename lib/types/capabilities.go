@@ -0,0 +1,113 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/corestario/dkglib/lib/alias"
+)
+
+// Capabilities is a bitfield of the protocols, curves and message versions a
+// node supports, advertised in its registration for a round.
+type Capabilities uint64
+
+const (
+	CapProtocolV1  Capabilities = 1 << iota // The DKG protocol of RoundProtocolVersion 1.
+	CapCurveBN256                           // BLS keys on the bn256 curves.
+	CapSignBytesV1                          // Messages signed with alias.SignBytesV1.
+	CapSignBytesV2                          // Messages signed with alias.SignBytesV2.
+)
+
+// LegacyCapabilities are assumed for participants that registered without
+// advertising theirs, i.e. run a version of the library predating them.
+const LegacyCapabilities = CapProtocolV1 | CapCurveBN256 | CapSignBytesV1 | CapSignBytesV2
+
+// capabilityGroup lists alternatives of which the participants of a round must
+// share at least one.
+type capabilityGroup struct {
+	name string
+	caps []Capabilities
+}
+
+var capabilityGroups = []capabilityGroup{
+	{name: "protocol", caps: []Capabilities{CapProtocolV1}},
+	{name: "curve", caps: []Capabilities{CapCurveBN256}},
+	{name: "message version", caps: []Capabilities{CapSignBytesV1, CapSignBytesV2}},
+}
+
+var capabilityNames = map[Capabilities]string{
+	CapProtocolV1:  "protocol_v1",
+	CapCurveBN256:  "bn256",
+	CapSignBytesV1: "sign_bytes_v1",
+	CapSignBytesV2: "sign_bytes_v2",
+}
+
+// LocalCapabilities returns the capabilities of a node signing its messages
+// with the sign-byte version; it only verifies signatures made with the same.
+func LocalCapabilities(signBytesVersion byte) Capabilities {
+	caps := CapProtocolV1 | CapCurveBN256
+	switch signBytesVersion {
+	case alias.SignBytesV1:
+		caps |= CapSignBytesV1
+	case alias.SignBytesV2:
+		caps |= CapSignBytesV2
+	}
+	return caps
+}
+
+func (c Capabilities) Has(other Capabilities) bool {
+	return c&other == other
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for capability, name := range capabilityNames {
+		if c.Has(capability) {
+			names = append(names, name)
+		}
+	}
+	if unknown := c &^ LegacyCapabilities; unknown != 0 {
+		names = append(names, fmt.Sprintf("unknown(%#x)", uint64(unknown)))
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	sort.Strings(names)
+	return strings.Join(names, "|")
+}
+
+// CompatibilityError tells why the participants of a round can't run it
+// together: none of the alternatives of a capability group is shared by all.
+type CompatibilityError struct {
+	Group        string
+	Capabilities map[string]Capabilities // Advertised capabilities by participant address.
+}
+
+func (e *CompatibilityError) Error() string {
+	var peers []string
+	for addr, caps := range e.Capabilities {
+		peers = append(peers, fmt.Sprintf("%s supports %s", addr, caps))
+	}
+	sort.Strings(peers)
+	return fmt.Sprintf("participants share no %s: %s", e.Group, strings.Join(peers, ", "))
+}
+
+// NegotiateCapabilities returns the capabilities shared by all participants, by
+// address, or a CompatibilityError if they share no alternative of a group.
+func NegotiateCapabilities(caps map[string]Capabilities) (Capabilities, error) {
+	common := ^Capabilities(0)
+	for _, c := range caps {
+		common &= c
+	}
+	for _, group := range capabilityGroups {
+		var shared bool
+		for _, capability := range group.caps {
+			shared = shared || common.Has(capability)
+		}
+		if !shared {
+			return 0, &CompatibilityError{Group: group.name, Capabilities: caps}
+		}
+	}
+	return common, nil
+}
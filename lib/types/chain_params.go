@@ -0,0 +1,59 @@
+package types
+
+// DKGParams are the DKG settings governance tunes on chain. A zero value keeps
+// the node's own setting.
+type DKGParams struct {
+	NumBlocks   int64 `json:"num_blocks"`   // Blocks between rounds.
+	BlocksAhead int64 `json:"blocks_ahead"` // Blocks between a round's success and the verifier change.
+	// The threshold of a round of n participants is n*ThresholdNumerator/ThresholdDenominator+1,
+	// at most n.
+	ThresholdNumerator   int64 `json:"threshold_numerator"`
+	ThresholdDenominator int64 `json:"threshold_denominator"`
+	// CommitteeSize caps the validators taking part in a round, keeping those
+	// with the most voting power.
+	CommitteeSize int64 `json:"committee_size"`
+}
+
+// Threshold returns the threshold of a round of n participants, or zero if no
+// threshold formula is set.
+func (p *DKGParams) Threshold(n int) int {
+	if p.ThresholdDenominator == 0 {
+		return 0
+	}
+	threshold := int(int64(n)*p.ThresholdNumerator/p.ThresholdDenominator) + 1
+	if threshold > n {
+		threshold = n
+	}
+	return threshold
+}
+
+// Validate returns a *ConfigError listing the invalid parameters.
+func (p *DKGParams) Validate() error {
+	problems := &ConfigError{}
+	if p.NumBlocks < 0 {
+		problems.Add("round interval must not be negative, got %d blocks", p.NumBlocks)
+	}
+	if p.BlocksAhead < 0 {
+		problems.Add("blocks ahead must not be negative, got %d", p.BlocksAhead)
+	}
+	if p.NumBlocks > 0 && p.BlocksAhead >= p.NumBlocks {
+		problems.Add("blocks ahead (%d) must be less than the round interval (%d)", p.BlocksAhead, p.NumBlocks)
+	}
+	if (p.ThresholdNumerator == 0) != (p.ThresholdDenominator == 0) {
+		problems.Add("threshold numerator and denominator must be set together")
+	}
+	if p.ThresholdNumerator < 0 || p.ThresholdDenominator < 0 || p.ThresholdNumerator > p.ThresholdDenominator {
+		problems.Add("threshold fraction %d/%d must be within [0, 1]", p.ThresholdNumerator, p.ThresholdDenominator)
+	}
+	if p.CommitteeSize < 0 {
+		problems.Add("committee size must not be negative, got %d", p.CommitteeSize)
+	}
+	return problems.Err()
+}
+
+// ParamsSource reads the DKG parameters decided on chain, e.g. by governance.
+type ParamsSource interface {
+	// DKGParams returns the parameters in effect at the height; zero means the
+	// latest height.
+	DKGParams(height int64) (*DKGParams, error)
+}
@@ -0,0 +1,15 @@
+package types
+
+import "fmt"
+
+// CommitteeError is returned when a round is not started because it could not
+// complete: its committee is too small or its threshold out of range.
+type CommitteeError struct {
+	Size      int // Number of participants.
+	Threshold int // Threshold of the dealt polynomials.
+	Reason    string
+}
+
+func (e *CommitteeError) Error() string {
+	return fmt.Sprintf("round of %d participants with threshold %d can't complete: %s", e.Size, e.Threshold, e.Reason)
+}
@@ -0,0 +1,29 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigError lists every problem found by a configuration check, so all of
+// them can be fixed at once instead of failing one by one at runtime.
+type ConfigError struct {
+	Problems []string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(e.Problems, "; "))
+}
+
+// Add records a problem.
+func (e *ConfigError) Add(format string, args ...interface{}) {
+	e.Problems = append(e.Problems, fmt.Sprintf(format, args...))
+}
+
+// Err returns the error, or nil if no problem was found.
+func (e *ConfigError) Err() error {
+	if len(e.Problems) == 0 {
+		return nil
+	}
+	return e
+}
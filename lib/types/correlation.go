@@ -0,0 +1,61 @@
+package types
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// CorrelationKey is the log key of the correlation IDs of rounds.
+const CorrelationKey = "correlation_id"
+
+// MessageCorrelationKey is the log key of the correlation IDs of messages.
+const MessageCorrelationKey = "msg_id"
+
+const correlationIDSize = 8
+
+// RoundCorrelationID identifies the round in logs, events and errors. It only
+// depends on the round ID, so every participant derives the same one and
+// grepping the logs of several validators for it yields the round's whole
+// story.
+func RoundCorrelationID(roundID int) string {
+	return correlationID([]byte("dkg-round/" + strconv.Itoa(roundID)))
+}
+
+// MessageCorrelationID identifies the message like RoundCorrelationID does a
+// round; the sender and the receivers derive the same one.
+func MessageCorrelationID(msg *alias.DKGData) string {
+	return correlationID(append(msg.ContentBytes(), msg.Signature...))
+}
+
+func correlationID(data []byte) string {
+	return hex.EncodeToString(tmhash.Sum(data)[:correlationIDSize])
+}
+
+// RoundError is an error of a round, tagged with its correlation ID.
+type RoundError struct {
+	RoundID       int
+	CorrelationID string
+	Err           error
+}
+
+// NewRoundError tags the error with the round's correlation ID, unless it is
+// already tagged.
+func NewRoundError(roundID int, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*RoundError); ok {
+		return err
+	}
+	return &RoundError{RoundID: roundID, CorrelationID: RoundCorrelationID(roundID), Err: err}
+}
+
+func (e *RoundError) Error() string {
+	return fmt.Sprintf("round %d [%s]: %v", e.RoundID, e.CorrelationID, e.Err)
+}
+
+func (e *RoundError) Unwrap() error { return e.Err }
@@ -0,0 +1,17 @@
+package types
+
+// DKGEvent describes a round or key change event for an EventPublisher.
+type DKGEvent struct {
+	Type          string `json:"type"` // EventDKGStart, EventDKGSuccessful, EventDKGFailed or EventDKGKeyChange.
+	RoundID       int    `json:"round_id"`
+	CorrelationID string `json:"correlation_id"`
+	Epoch         int    `json:"epoch"`                 // Round of the verifier in use after the event, -1 if there is none.
+	Height        int64  `json:"height"`                // Block height of the event; the change height for EventDKGSuccessful.
+	KeyPurpose    string `json:"key_purpose,omitempty"` // See offChain.WithKeyPurpose.
+}
+
+// EventPublisher receives the DKG events in addition to the event switch, e.g.
+// to bridge them to another event bus.
+type EventPublisher interface {
+	PublishDKGEvent(event DKGEvent) error
+}
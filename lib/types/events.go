@@ -0,0 +1,59 @@
+package types
+
+import "github.com/tendermint/tendermint/crypto"
+
+// Payloads of the DKG events fired on the event switch. EventDKGData carries
+// the *alias.DKGData to broadcast as is, since the consensus reactor gossips
+// it to the peers. The payloads of round events carry the round's
+// CorrelationID, see RoundCorrelationID.
+
+// DKGStartEvent is the payload of EventDKGStart.
+type DKGStartEvent struct {
+	RoundID       int
+	CorrelationID string
+}
+
+// DKGPhaseEvent is the payload of the events fired by dealers as the phases of
+// a round complete: EventDKGPubKeyReceived, EventDKGDealsProcessed,
+// EventDKGResponsesProcessed, EventDKGJustificationsProcessed,
+// EventDKGInstanceCertified, EventDKGCommitsProcessed,
+// EventDKGComplaintProcessed and EventDKGReconstructCommitsProcessed.
+type DKGPhaseEvent struct {
+	RoundID       int
+	CorrelationID string
+}
+
+// DKGSuccessfulEvent is the payload of EventDKGSuccessful, fired once the
+// participants agreed on the height their verifier takes over at.
+type DKGSuccessfulEvent struct {
+	RoundID       int
+	CorrelationID string
+	ChangeHeight  int64
+}
+
+// DKGFailedEvent is the payload of EventDKGFailed.
+type DKGFailedEvent struct {
+	RoundID       int
+	CorrelationID string
+}
+
+// DKGRoundEvictedEvent is the payload of EventDKGRoundEvicted.
+type DKGRoundEvictedEvent struct {
+	RoundID       int
+	CorrelationID string
+}
+
+// DKGKeyChangeEvent is the payload of EventDKGKeyChange.
+type DKGKeyChangeEvent struct {
+	Height   int64
+	Epoch    int               // Round of the verifier in use from the height.
+	GroupKey *VerifierSnapshot // Nil if the verifier's key can't be exported.
+}
+
+// DKGShareMigratedEvent is the payload of EventDKGShareMigrated, fired when a
+// participant moved its share of the epoch to a new consensus key.
+type DKGShareMigratedEvent struct {
+	Epoch   int
+	OldAddr crypto.Address
+	NewAddr crypto.Address
+}
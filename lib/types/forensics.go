@@ -0,0 +1,39 @@
+package types
+
+import (
+	"time"
+)
+
+// ForensicBundle gathers what is known about a failed round into a single
+// artifact operators can attach to bug reports.
+type ForensicBundle struct {
+	RoundID       int       `json:"round_id"`
+	CorrelationID string    `json:"correlation_id"` // See RoundCorrelationID.
+	CreatedAt     time.Time `json:"created_at"`
+	Height        int64     `json:"height"` // Last height seen when the round failed.
+	// Round is the snapshot of the round with its peer and phase progress;
+	// nil if the round had no dealer.
+	Round *RoundInfo `json:"round,omitempty"`
+	// Errors are the errors reported while running the round, the one that
+	// failed it last.
+	Errors   []string           `json:"errors"`
+	Messages []*ForensicMessage `json:"messages,omitempty"` // Excerpt of the WAL, if any.
+	Progress []string           `json:"progress,omitempty"` // Round progress, one phase per line.
+}
+
+// ForensicMessage describes a message of the round recorded in the WAL.
+type ForensicMessage struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // "in", "out" or "round_start".
+	Type      string    `json:"type,omitempty"`
+	From      string    `json:"from,omitempty"`
+	ToIndex   int       `json:"to_index"`
+	Size      int       `json:"size"`
+	NumChunks int       `json:"num_chunks,omitempty"`
+}
+
+// ForensicsProvider is implemented by DKG instances keeping the forensic
+// bundles of the last failed rounds.
+type ForensicsProvider interface {
+	ForensicBundle(roundID int) (*ForensicBundle, error)
+}
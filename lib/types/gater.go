@@ -0,0 +1,65 @@
+package types
+
+import "fmt"
+
+// RoundGater is consulted before a node starts a round, so the host
+// application can delay rounds during sensitive windows, e.g. while it is
+// upgrading, the chain is halted or a hard fork is near.
+type RoundGater interface {
+	// AllowRound returns nil if the round may start at the height, or why it
+	// must wait otherwise.
+	AllowRound(roundID int, height int64) error
+}
+
+// RoundGaterFunc adapts a function to RoundGater.
+type RoundGaterFunc func(roundID int, height int64) error
+
+func (f RoundGaterFunc) AllowRound(roundID int, height int64) error {
+	return f(roundID, height)
+}
+
+// RoundVetoError is returned when a RoundGater delays the start of a round.
+type RoundVetoError struct {
+	RoundID int
+	Height  int64
+	Reason  error
+}
+
+func (e *RoundVetoError) Error() string {
+	return fmt.Sprintf("start of round %d at height %d vetoed: %v", e.RoundID, e.Height, e.Reason)
+}
+
+// AdoptionGater is consulted before a node activates the verifier of a
+// successful round, so the host application can delay or veto the key change,
+// e.g. while the epoch anchored on chain isn't final yet. Its decisions must
+// only depend on data every node sees alike, such as committed chain state, or
+// the nodes end up signing with different keys.
+type AdoptionGater interface {
+	// AllowAdoption returns nil if the verifier of the epoch may be activated
+	// at the height, an error wrapped by VetoAdoption if it must be dropped, or
+	// why it must wait otherwise.
+	AllowAdoption(epoch int, height int64) error
+}
+
+// AdoptionGaterFunc adapts a function to AdoptionGater.
+type AdoptionGaterFunc func(epoch int, height int64) error
+
+func (f AdoptionGaterFunc) AllowAdoption(epoch int, height int64) error {
+	return f(epoch, height)
+}
+
+// AdoptionVetoError is returned by an AdoptionGater to drop the verifier rather
+// than delay its activation.
+type AdoptionVetoError struct {
+	Reason error
+}
+
+// VetoAdoption wraps the reason an AdoptionGater refuses a verifier for, so the
+// verifier is dropped.
+func VetoAdoption(reason error) error {
+	return &AdoptionVetoError{Reason: reason}
+}
+
+func (e *AdoptionVetoError) Error() string {
+	return fmt.Sprintf("verifier adoption vetoed: %v", e.Reason)
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type RoundResult string
+
+const (
+	RoundResultNone       RoundResult = "none"
+	RoundResultInProgress RoundResult = "in_progress"
+	RoundResultSuccess    RoundResult = "success"
+	RoundResultFailed     RoundResult = "failed"
+)
+
+// HealthStatus describes the state of the DKG subsystem.
+type HealthStatus struct {
+	VerifierReady   bool        `json:"verifier_ready"`
+	VerifierRoundID int         `json:"verifier_round_id"` // Round the current verifier was generated in, -1 if unknown.
+	LastRoundID     int         `json:"last_round_id"`
+	LastRoundResult RoundResult `json:"last_round_result"`
+	LastMessageAge  float64     `json:"last_message_age_seconds"` // Negative if no message was processed yet.
+	OnChain         bool        `json:"on_chain"`
+	Paused          bool        `json:"paused"` // DKG participation is paused, see Pauser.
+}
+
+type Healther interface {
+	Health() HealthStatus
+}
+
+// NewLivenessHandler returns an HTTP handler that always responds with the current HealthStatus.
+func NewLivenessHandler(h Healther) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, h.Health(), http.StatusOK)
+	})
+}
+
+// NewReadinessHandler returns an HTTP handler that responds with 503 until a verifier is loaded.
+func NewReadinessHandler(h Healther) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := h.Health()
+		code := http.StatusOK
+		if !status.VerifierReady {
+			code = http.StatusServiceUnavailable
+		}
+		writeHealth(w, status, code)
+	})
+}
+
+func writeHealth(w http.ResponseWriter, status HealthStatus, code int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(status)
+}
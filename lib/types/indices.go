@@ -0,0 +1,192 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+const storeIndices = "indices-%d.json"
+
+// IndexEntry is the DKG index of a participant, the index of its shares.
+type IndexEntry struct {
+	Index   int            `json:"index"`
+	Address crypto.Address `json:"address"`
+}
+
+// IndexAssignment maps the participants of a round to their DKG indices. All
+// honest nodes derive the same one from the committee, see AssignIndices.
+type IndexAssignment struct {
+	RoundID int          `json:"round_id"`
+	Entries []IndexEntry `json:"entries"`
+}
+
+// AssignIndices numbers the participants of the round from 0 in ascending
+// order of their address bytes, the order of the ParticipantSet.
+func AssignIndices(roundID int, participants *ParticipantSet) *IndexAssignment {
+	assignment := &IndexAssignment{RoundID: roundID}
+	for index, participant := range participants.Participants() {
+		assignment.Entries = append(assignment.Entries, IndexEntry{Index: index, Address: participant.Address})
+	}
+	return assignment
+}
+
+// Index returns the index of the participant, or -1 if it isn't one.
+func (a *IndexAssignment) Index(addr crypto.Address) int {
+	for _, entry := range a.Entries {
+		if bytes.Equal(entry.Address, addr) {
+			return entry.Index
+		}
+	}
+	return -1
+}
+
+// Hash identifies the assignment, so that participants can check they agree on it.
+func (a *IndexAssignment) Hash() []byte {
+	var buf bytes.Buffer
+	for _, entry := range a.Entries {
+		buf.WriteString(fmt.Sprintf("%d:", entry.Index))
+		buf.Write(entry.Address)
+	}
+	return tmhash.Sum(buf.Bytes())
+}
+
+// Diff describes the participants whose index differs in the other assignment.
+func (a *IndexAssignment) Diff(other *IndexAssignment) []string {
+	var diffs []string
+	indices := make(map[string]int)
+	for _, entry := range other.Entries {
+		indices[entry.Address.String()] = entry.Index
+	}
+	for _, entry := range a.Entries {
+		index, ok := indices[entry.Address.String()]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: index %d != none", entry.Address, entry.Index))
+		} else if index != entry.Index {
+			diffs = append(diffs, fmt.Sprintf("%s: index %d != %d", entry.Address, entry.Index, index))
+		}
+		delete(indices, entry.Address.String())
+	}
+	for addr, index := range indices {
+		diffs = append(diffs, fmt.Sprintf("%s: index none != %d", addr, index))
+	}
+	sort.Strings(diffs)
+	return diffs
+}
+
+// IndexMismatchError is returned when the indices of a round differ from
+// those persisted for it.
+type IndexMismatchError struct {
+	RoundID int
+	Diffs   []string
+}
+
+func (e *IndexMismatchError) Error() string {
+	return fmt.Sprintf("indices of round %d differ from the persisted ones: %s", e.RoundID, strings.Join(e.Diffs, "; "))
+}
+
+// IndexStore persists the index assignments of rounds in a directory, one
+// indices-<round ID>.json file per round, so they survive restarts.
+type IndexStore struct {
+	mtx    sync.Mutex
+	dir    string
+	retain int
+}
+
+// NewIndexStore opens the store in the directory, keeping the assignments of
+// the last retain rounds; zero keeps every round.
+func NewIndexStore(dir string, retain int) (*IndexStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create index store directory: %v", err)
+	}
+	if retain < 0 {
+		return nil, fmt.Errorf("retained rounds must not be negative, got %d", retain)
+	}
+	return &IndexStore{dir: dir, retain: retain}, nil
+}
+
+func (s *IndexStore) path(roundID int) string {
+	return filepath.Join(s.dir, fmt.Sprintf(storeIndices, roundID))
+}
+
+// Save persists the assignment; it fails with an IndexMismatchError if another
+// one was saved for the round.
+func (s *IndexStore) Save(assignment *IndexAssignment) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if saved, err := s.load(assignment.RoundID); err == nil {
+		if diffs := saved.Diff(assignment); len(diffs) > 0 {
+			return &IndexMismatchError{RoundID: assignment.RoundID, Diffs: diffs}
+		}
+		return nil
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	data, err := json.Marshal(assignment)
+	if err != nil {
+		return fmt.Errorf("failed to encode indices of round %d: %v", assignment.RoundID, err)
+	}
+	tmp := s.path(assignment.RoundID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write indices of round %d: %v", assignment.RoundID, err)
+	}
+	if err := os.Rename(tmp, s.path(assignment.RoundID)); err != nil {
+		return fmt.Errorf("failed to write indices of round %d: %v", assignment.RoundID, err)
+	}
+	return s.prune()
+}
+
+// Load returns the saved assignment of the round; the error satisfies
+// os.IsNotExist if there is none.
+func (s *IndexStore) Load(roundID int) (*IndexAssignment, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.load(roundID)
+}
+
+func (s *IndexStore) load(roundID int) (*IndexAssignment, error) {
+	data, err := ioutil.ReadFile(s.path(roundID))
+	if err != nil {
+		return nil, err
+	}
+	var assignment IndexAssignment
+	if err := json.Unmarshal(data, &assignment); err != nil {
+		return nil, fmt.Errorf("failed to decode indices of round %d: %v", roundID, err)
+	}
+	return &assignment, nil
+}
+
+func (s *IndexStore) prune() error {
+	if s.retain == 0 {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to list index store: %v", err)
+	}
+	var rounds []int
+	for _, entry := range entries {
+		var roundID int
+		if _, err := fmt.Sscanf(entry.Name(), storeIndices, &roundID); err == nil && fmt.Sprintf(storeIndices, roundID) == entry.Name() {
+			rounds = append(rounds, roundID)
+		}
+	}
+	sort.Ints(rounds)
+	for len(rounds) > s.retain {
+		if err := os.Remove(s.path(rounds[0])); err != nil {
+			return fmt.Errorf("failed to remove indices of round %d: %v", rounds[0], err)
+		}
+		rounds = rounds[1:]
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package types
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// KeyPurposePath returns the path of the file or directory storing the state of
+// the key purpose, given the path storing that of the default key: the purpose
+// is inserted before the extension, e.g. "data/dkg.wal" becomes
+// "data/dkg.bridge.wal". The default key keeps the path.
+func KeyPurposePath(path, purpose string) string {
+	if purpose == "" {
+		return path
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + purpose + ext
+}
@@ -0,0 +1,50 @@
+package types
+
+import (
+	"bytes"
+	"sort"
+
+	tmtypes "github.com/tendermint/tendermint/alias"
+)
+
+// Loser is a validator excluded from a round, with the blame of the dealer
+// which excluded it.
+type Loser struct {
+	Validator *tmtypes.Validator
+	Blame
+}
+
+// LoserReporter is implemented by DKG instances which explain their losers.
+// GetLosersWithReasons returns the losers GetLosers would, with their reasons.
+type LoserReporter interface {
+	GetLosersWithReasons() []*Loser
+}
+
+// SortLosers orders the losers by address and removes the repeated addresses,
+// keeping the first blame of each, so the validators punished on chain don't
+// depend on the order the dealers processed the messages in.
+func SortLosers(losers []*Loser) []*Loser {
+	seen := make(map[string]bool)
+	var out []*Loser
+	for _, loser := range losers {
+		key := string(loser.Validator.Address)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, loser)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		return bytes.Compare(out[i].Validator.Address, out[j].Validator.Address) < 0
+	})
+	return out
+}
+
+// LoserValidators returns the validators of the losers.
+func LoserValidators(losers []*Loser) []*tmtypes.Validator {
+	var out []*tmtypes.Validator
+	for _, loser := range losers {
+		out = append(out, loser.Validator)
+	}
+	return out
+}
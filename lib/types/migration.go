@@ -0,0 +1,110 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// ShareMigration moves the DKG shares a participant holds from its old
+// consensus key to a new one, e.g. after a key or operator address rotation,
+// so they aren't orphaned until a new round completes. It applies to the share
+// of the epoch and of the rounds running when it was made, and is signed by
+// both keys to prove control of each, for the chain the DKG runs on.
+type ShareMigration struct {
+	Epoch        int           `json:"epoch"`  // Round of the verifier in use when migrating.
+	Height       int64         `json:"height"` // Last height messages signed with the old key are accepted at.
+	OldPubKey    crypto.PubKey `json:"old_pub_key"`
+	NewPubKey    crypto.PubKey `json:"new_pub_key"`
+	OldSignature []byte        `json:"old_signature"`
+	NewSignature []byte        `json:"new_signature"`
+}
+
+// migrationDomain separates the sign bytes of migrations from those of other
+// messages signed with the consensus keys.
+const migrationDomain = "dkglib/ShareMigration"
+
+// NewShareMigration creates a migration of the epoch's share from the old
+// private validator to the new one, signed by both for the chain. The old key
+// is retired after the height.
+func NewShareMigration(chainID string, epoch int, height int64, oldPV, newPV tmtypes.PrivValidator) (*ShareMigration, error) {
+	m := &ShareMigration{Epoch: epoch, Height: height, OldPubKey: oldPV.GetPubKey(), NewPubKey: newPV.GetPubKey()}
+	if err := oldPV.SignData(chainID, migrationSigner{m, &m.OldSignature}); err != nil {
+		return nil, fmt.Errorf("failed to sign migration with the old key: %v", err)
+	}
+	if err := newPV.SignData(chainID, migrationSigner{m, &m.NewSignature}); err != nil {
+		return nil, fmt.Errorf("failed to sign migration with the new key: %v", err)
+	}
+	return m, nil
+}
+
+// SignBytes encodes the migration without its signatures after the domain
+// separator and the chain ID, each length-prefixed.
+func (m ShareMigration) SignBytes(chainID string) []byte {
+	m.OldSignature, m.NewSignature = nil, nil
+	var buf bytes.Buffer
+	for _, b := range [][]byte{[]byte(migrationDomain), []byte(chainID)} {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		buf.Write(n[:])
+		buf.Write(b)
+	}
+	buf.Write(alias.Cdc.MustMarshalBinaryLengthPrefixed(m))
+	return buf.Bytes()
+}
+
+func (m *ShareMigration) OldAddr() crypto.Address {
+	return m.OldPubKey.Address()
+}
+
+func (m *ShareMigration) NewAddr() crypto.Address {
+	return m.NewPubKey.Address()
+}
+
+// Verify checks that the migration is signed by both keys for the chain.
+func (m *ShareMigration) Verify(chainID string) error {
+	if m.OldPubKey == nil || m.NewPubKey == nil {
+		return fmt.Errorf("migration has no keys")
+	}
+	if bytes.Equal(m.OldAddr(), m.NewAddr()) {
+		return fmt.Errorf("migration to the same address %s", m.OldAddr())
+	}
+	signBytes := m.SignBytes(chainID)
+	if !m.OldPubKey.VerifyBytes(signBytes, m.OldSignature) {
+		return fmt.Errorf("invalid migration signature of the old key %s", m.OldAddr())
+	}
+	if !m.NewPubKey.VerifyBytes(signBytes, m.NewSignature) {
+		return fmt.Errorf("invalid migration signature of the new key %s", m.NewAddr())
+	}
+	return nil
+}
+
+func EncodeShareMigration(m *ShareMigration) ([]byte, error) {
+	return alias.Cdc.MarshalBinaryBare(m)
+}
+
+func DecodeShareMigration(data []byte) (*ShareMigration, error) {
+	var m ShareMigration
+	if err := alias.Cdc.UnmarshalBinaryBare(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to decode share migration: %v", err)
+	}
+	return &m, nil
+}
+
+// migrationSigner signs a migration with one of the keys.
+type migrationSigner struct {
+	m   *ShareMigration
+	sig *[]byte
+}
+
+func (s migrationSigner) SignBytes(chainID string) []byte {
+	return s.m.SignBytes(chainID)
+}
+
+func (s migrationSigner) SetSignature(sig []byte) {
+	*s.sig = sig
+}
@@ -0,0 +1,155 @@
+package types
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+type MisbehaviorType int
+
+const (
+	MisbehaviorInvalidSignature MisbehaviorType = iota
+	MisbehaviorMalformedMessage
+	MisbehaviorEquivocation
+)
+
+func (t MisbehaviorType) String() string {
+	switch t {
+	case MisbehaviorInvalidSignature:
+		return "invalid_signature"
+	case MisbehaviorMalformedMessage:
+		return "malformed_message"
+	case MisbehaviorEquivocation:
+		return "equivocation"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
+
+// MisbehaviorReport describes a single misbehavior of a peer detected while processing DKG messages.
+type MisbehaviorReport struct {
+	Type     MisbehaviorType
+	Addr     crypto.Address
+	RoundID  int
+	DataType alias.DKGDataType
+	Err      error
+	Evidence *EquivocationEvidence // Set for equivocation if the conflicting messages are known.
+	Message  *alias.DKGData        // The signed offending message of other misbehaviors, if known.
+}
+
+// VerifyEvidence checks that the report carries the evidence of the
+// misbehavior, signed in the domain by the key of the reported address. An
+// invalid signature can't be proven to others, so its reports never verify.
+func (r *MisbehaviorReport) VerifyEvidence(pubKey crypto.PubKey, domain alias.SignDomain) error {
+	if !bytes.Equal(r.Addr, pubKey.Address()) {
+		return fmt.Errorf("report is of %s, not of %s", r.Addr, pubKey.Address())
+	}
+	switch r.Type {
+	case MisbehaviorEquivocation:
+		if r.Evidence == nil {
+			return fmt.Errorf("equivocation report lacks evidence")
+		}
+		return r.Evidence.Verify(pubKey, domain)
+	case MisbehaviorMalformedMessage:
+		if r.Message == nil {
+			return fmt.Errorf("report lacks the offending message")
+		}
+		if !bytes.Equal(r.Message.Addr, r.Addr) || r.Message.RoundID != r.RoundID || r.Message.Type != r.DataType {
+			return fmt.Errorf("offending message doesn't match the report")
+		}
+		if !domain.Verify(pubKey, r.Message) {
+			return fmt.Errorf("invalid offending message signature")
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s can't be proven", r.Type)
+	}
+}
+
+// EquivocationEvidence holds two conflicting messages signed by the same
+// sender for the same round, which anyone knowing the sender's key can check.
+// Messages reassembled from chunks carry the signature of their last chunk
+// only, so their evidence doesn't verify.
+type EquivocationEvidence struct {
+	First  *alias.DKGData
+	Second *alias.DKGData
+}
+
+// Verify checks that both messages come from the address of the key within
+// the same round, are signed by it in the domain and differ.
+func (e *EquivocationEvidence) Verify(pubKey crypto.PubKey, domain alias.SignDomain) error {
+	if e.First == nil || e.Second == nil {
+		return fmt.Errorf("evidence lacks a message")
+	}
+	if !bytes.Equal(e.First.Addr, pubKey.Address()) || !bytes.Equal(e.Second.Addr, pubKey.Address()) {
+		return fmt.Errorf("evidence messages are not from %s", pubKey.Address())
+	}
+	if e.First.RoundID != e.Second.RoundID {
+		return fmt.Errorf("evidence messages are of rounds %d and %d", e.First.RoundID, e.Second.RoundID)
+	}
+	if bytes.Equal(e.First.ContentBytes(), e.Second.ContentBytes()) {
+		return fmt.Errorf("evidence messages are identical")
+	}
+	if !domain.Verify(pubKey, e.First) || !domain.Verify(pubKey, e.Second) {
+		return fmt.Errorf("invalid evidence signature")
+	}
+	return nil
+}
+
+func (r *MisbehaviorReport) String() string {
+	return fmt.Sprintf("[Misbehavior %s from %s, round %d, data type %d: %v]", r.Type, r.Addr, r.RoundID, r.DataType, r.Err)
+}
+
+// MisbehaviorSink receives misbehavior reports from the dealer and from OffChainDKG.
+type MisbehaviorSink interface {
+	ReportMisbehavior(report *MisbehaviorReport)
+}
+
+// InvalidMessageError is returned by the handlers of messages that can't be
+// decoded or are invalid: the fault of their sender, not of the node, and the
+// same on every node.
+type InvalidMessageError struct {
+	Addr     crypto.Address
+	RoundID  int
+	DataType alias.DKGDataType
+	Err      error
+}
+
+func NewInvalidMessageError(msg *alias.DKGData, err error) *InvalidMessageError {
+	return &InvalidMessageError{Addr: crypto.Address(msg.Addr), RoundID: msg.RoundID, DataType: msg.Type, Err: err}
+}
+
+func (e *InvalidMessageError) Error() string {
+	return e.Err.Error()
+}
+
+type NopMisbehaviorSink struct{}
+
+func (s NopMisbehaviorSink) ReportMisbehavior(*MisbehaviorReport) {}
+
+// LoggingMisbehaviorSink writes every report to the logger.
+type LoggingMisbehaviorSink struct {
+	logger logging.Logger
+}
+
+func NewLoggingMisbehaviorSink(logger logging.Logger) *LoggingMisbehaviorSink {
+	return &LoggingMisbehaviorSink{logger: logger}
+}
+
+func (s *LoggingMisbehaviorSink) ReportMisbehavior(report *MisbehaviorReport) {
+	s.logger.Error("DKG peer misbehavior", "type", report.Type, "addr", report.Addr,
+		"round_id", report.RoundID, "data_type", report.DataType, "error", report.Err)
+}
+
+// MultiMisbehaviorSink passes every report to all of its sinks.
+type MultiMisbehaviorSink []MisbehaviorSink
+
+func (s MultiMisbehaviorSink) ReportMisbehavior(report *MisbehaviorReport) {
+	for _, sink := range s {
+		sink.ReportMisbehavior(report)
+	}
+}
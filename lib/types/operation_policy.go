@@ -0,0 +1,138 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Operation names a sensitive operation guarded by a CoSignPolicy.
+type Operation string
+
+const (
+	OperationExportShare  Operation = "export_share"
+	OperationMigrateShare Operation = "migrate_share"
+	OperationReshare      Operation = "reshare" // Refresh of the shares of an epoch.
+	// Export of the state carried across a binary upgrade, which holds the key shares.
+	OperationExportUpgradeState Operation = "export_upgrade_state"
+)
+
+var ErrOperationDisabled = errors.New("operation is disabled: no co-sign policy configured")
+
+// OperationRequest is the statement operators sign to authorize a single
+// execution of an operation.
+type OperationRequest struct {
+	Operation Operation `json:"operation"`
+	RoundID   int       `json:"round_id"`
+	Nonce     []byte    `json:"nonce"`   // Unique per request, so signatures can't be replayed.
+	Expires   time.Time `json:"expires"` // Signatures are rejected after this time.
+}
+
+// SignBytes encodes the request with big-endian integers and length-prefixed
+// byte strings, like the DKG message sign bytes.
+func (r *OperationRequest) SignBytes() []byte {
+	var buf bytes.Buffer
+	writeBytes := func(b []byte) {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], uint32(len(b)))
+		buf.Write(n[:])
+		buf.Write(b)
+	}
+	writeBytes([]byte("dkglib/OperationRequest"))
+	writeBytes([]byte(r.Operation))
+	var n [8]byte
+	binary.BigEndian.PutUint64(n[:], uint64(r.RoundID))
+	buf.Write(n[:])
+	writeBytes(r.Nonce)
+	binary.BigEndian.PutUint64(n[:], uint64(r.Expires.UnixNano()))
+	buf.Write(n[:])
+	return buf.Bytes()
+}
+
+// OperatorSignature is an operator's signature of an OperationRequest.
+type OperatorSignature struct {
+	PubKey    crypto.PubKey `json:"pub_key"`
+	Signature []byte        `json:"signature"`
+}
+
+// CoSignPolicy requires signatures of threshold distinct operators of the admin
+// key set for every sensitive operation, so a single compromised operator can't
+// e.g. exfiltrate the key share.
+type CoSignPolicy struct {
+	threshold int
+	operators map[string]crypto.PubKey // By address.
+
+	mtx  sync.Mutex
+	used map[string]time.Time // Nonces of authorized requests until they expire.
+	now  func() time.Time
+}
+
+func NewCoSignPolicy(threshold int, operators ...crypto.PubKey) (*CoSignPolicy, error) {
+	p := &CoSignPolicy{
+		threshold: threshold,
+		operators: make(map[string]crypto.PubKey),
+		used:      make(map[string]time.Time),
+		now:       time.Now,
+	}
+	for _, operator := range operators {
+		p.operators[operator.Address().String()] = operator
+	}
+	if threshold < 1 || threshold > len(p.operators) {
+		return nil, fmt.Errorf("threshold %d out of range for %d operators", threshold, len(p.operators))
+	}
+	return p, nil
+}
+
+// Authorize checks the signatures of the request and consumes its nonce, so the
+// request authorizes exactly one execution.
+func (p *CoSignPolicy) Authorize(req *OperationRequest, signatures []OperatorSignature) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	now := p.now()
+	for nonce, expires := range p.used {
+		if now.After(expires) {
+			delete(p.used, nonce)
+		}
+	}
+	if now.After(req.Expires) {
+		return fmt.Errorf("%s request expired at %s", req.Operation, req.Expires)
+	}
+	if len(req.Nonce) == 0 {
+		return fmt.Errorf("%s request has no nonce", req.Operation)
+	}
+	nonce := hex.EncodeToString(req.Nonce)
+	if _, ok := p.used[nonce]; ok {
+		return fmt.Errorf("%s request %s was already used", req.Operation, nonce)
+	}
+
+	var (
+		signBytes = req.SignBytes()
+		signers   = make(map[string]bool)
+	)
+	for _, sig := range signatures {
+		if sig.PubKey == nil {
+			continue
+		}
+		addr := sig.PubKey.Address().String()
+		operator, ok := p.operators[addr]
+		if !ok || signers[addr] || !operator.Equals(sig.PubKey) {
+			continue
+		}
+		if operator.VerifyBytes(signBytes, sig.Signature) {
+			signers[addr] = true
+		}
+	}
+	if len(signers) < p.threshold {
+		return fmt.Errorf("%s requires %d operator signatures, got %d valid", req.Operation, p.threshold, len(signers))
+	}
+
+	p.used[nonce] = req.Expires
+	return nil
+}
@@ -0,0 +1,133 @@
+package types
+
+import (
+	"bytes"
+	"sort"
+
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// Participant is a member of a DKG committee: either a validator or an external
+// party (e.g. a custodian) identified by a registered public key.
+type Participant struct {
+	Address  crypto.Address `json:"address"`
+	PubKey   crypto.PubKey  `json:"pub_key"`
+	External bool           `json:"external"`
+}
+
+func NewExternalParticipant(pubKey crypto.PubKey) *Participant {
+	return &Participant{Address: pubKey.Address(), PubKey: pubKey, External: true}
+}
+
+// ParticipantSet is an immutable DKG committee ordered by address, which is
+// the order participant indices are assigned in, see AssignIndices.
+type ParticipantSet struct {
+	participants []*Participant
+}
+
+// NewParticipantSet builds a committee of the validators and the external
+// participants; external participants with the address of a validator are skipped.
+func NewParticipantSet(validators *tmtypes.ValidatorSet, external []*Participant) *ParticipantSet {
+	var participants []*Participant
+	if validators != nil {
+		for _, validator := range validators.Validators {
+			participants = append(participants, &Participant{Address: validator.Address, PubKey: validator.PubKey})
+		}
+	}
+	for _, participant := range external {
+		p := *participant
+		p.External = true
+		participants = append(participants, &p)
+	}
+
+	return NewParticipantSetFromList(participants)
+}
+
+// NewParticipantSetFromList builds a committee of the participants as they are,
+// e.g. restored from ParticipantSet.Participants. Of the participants sharing
+// an address, a validator is kept over an external one, then the one with the
+// lowest public key, so the committee doesn't depend on the list's order.
+func NewParticipantSetFromList(participants []*Participant) *ParticipantSet {
+	sorted := append([]*Participant(nil), participants...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if c := bytes.Compare(sorted[i].Address, sorted[j].Address); c != 0 {
+			return c < 0
+		}
+		if sorted[i].External != sorted[j].External {
+			return !sorted[i].External
+		}
+		return bytes.Compare(pubKeyBytes(sorted[i]), pubKeyBytes(sorted[j])) < 0
+	})
+	set := &ParticipantSet{}
+	for _, participant := range sorted {
+		if n := len(set.participants); n > 0 && bytes.Equal(set.participants[n-1].Address, participant.Address) {
+			continue
+		}
+		set.participants = append(set.participants, participant)
+	}
+
+	return set
+}
+
+func pubKeyBytes(p *Participant) []byte {
+	if p.PubKey == nil {
+		return nil
+	}
+	return p.PubKey.Bytes()
+}
+
+func (s *ParticipantSet) Size() int {
+	return len(s.participants)
+}
+
+// GetByAddress returns the index and the participant, or -1 and nil if there is none.
+func (s *ParticipantSet) GetByAddress(addr []byte) (int, *Participant) {
+	idx := sort.Search(len(s.participants), func(i int) bool {
+		return bytes.Compare(addr, s.participants[i].Address) <= 0
+	})
+	if idx < len(s.participants) && bytes.Equal(s.participants[idx].Address, addr) {
+		return idx, s.participants[idx]
+	}
+	return -1, nil
+}
+
+func (s *ParticipantSet) Participants() []*Participant {
+	return append([]*Participant(nil), s.participants...)
+}
+
+// External returns the external participants of the committee.
+func (s *ParticipantSet) External() []*Participant {
+	var out []*Participant
+	for _, p := range s.participants {
+		if p.External {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Hash identifies the committee, so that participants can check they run a round
+// with the same one.
+func (s *ParticipantSet) Hash() []byte {
+	var buf bytes.Buffer
+	for _, p := range s.participants {
+		buf.Write(p.Address)
+		buf.Write(p.PubKey.Bytes())
+	}
+	return tmhash.Sum(buf.Bytes())
+}
+
+// migrate returns the committee with the participant's key replaced by the
+// migration's new one.
+func (s *ParticipantSet) migrate(migration *ShareMigration) *ParticipantSet {
+	participants := make([]*Participant, len(s.participants))
+	for i, p := range s.participants {
+		participants[i] = p
+		if bytes.Equal(p.Address, migration.OldAddr()) {
+			participants[i] = &Participant{Address: migration.NewAddr(), PubKey: migration.NewPubKey, External: p.External}
+		}
+	}
+	return NewParticipantSetFromList(participants)
+}
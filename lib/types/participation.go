@@ -0,0 +1,54 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// Participation tells which messages of a participant were included on chain
+// for a round, so its participation can be audited without running a node.
+type Participation struct {
+	RoundID int            `json:"round_id"`
+	Addr    crypto.Address `json:"addr"`
+	// Included counts the included messages with a valid signature by type;
+	// chunked messages count once per chunk.
+	Included map[alias.DKGDataType]int `json:"included"`
+	// Invalid counts the included messages claiming to be from the participant
+	// whose signature doesn't verify with its key.
+	Invalid int `json:"invalid,omitempty"`
+	// Height is the height the messages were queried at.
+	Height int64 `json:"height"`
+}
+
+// Types returns the types of the included messages in ascending order.
+func (p *Participation) Types() []alias.DKGDataType {
+	types := make([]alias.DKGDataType, 0, len(p.Included))
+	for dataType := range p.Included {
+		types = append(types, dataType)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Missing returns the expected types without an included message.
+func (p *Participation) Missing(expected ...alias.DKGDataType) []alias.DKGDataType {
+	var missing []alias.DKGDataType
+	for _, dataType := range expected {
+		if p.Included[dataType] == 0 {
+			missing = append(missing, dataType)
+		}
+	}
+	return missing
+}
+
+func (p *Participation) String() string {
+	included := make([]string, 0, len(p.Included))
+	for _, dataType := range p.Types() {
+		included = append(included, fmt.Sprintf("%s=%d", dataType, p.Included[dataType]))
+	}
+	return fmt.Sprintf("round %d, %s: %s (invalid: %d)", p.RoundID, p.Addr, strings.Join(included, " "), p.Invalid)
+}
@@ -0,0 +1,13 @@
+package types
+
+// Pauser is implemented by DKG instances that can be taken out of DKG
+// participation at runtime, e.g. during a maintenance of the node.
+type Pauser interface {
+	// Pause makes the node an observer of the rounds started from now on: it
+	// neither deals nor sends messages in them. Rounds already running and the
+	// current verifier are not affected.
+	Pause()
+	// Resume makes the node participate again from the next round on.
+	Resume()
+	Paused() bool
+}
@@ -0,0 +1,139 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	tmtypes "github.com/tendermint/tendermint/alias"
+)
+
+// PenaltyEvidence is the evidence against a loser of a round: the validator,
+// the blame it was excluded with and the evidence hash of the offending
+// messages, if any.
+type PenaltyEvidence struct {
+	RoundID int
+	Loser   *Loser
+}
+
+// Penalty is the punishment a PenaltyPolicy decides for a loser.
+type Penalty struct {
+	Jail bool
+	// SlashFraction is the fraction of the stake to slash, a decimal in
+	// [0, 1] with at most 18 digits after the point, e.g. "0.05", as
+	// sdk.NewDecFromStr parses it; empty slashes nothing.
+	SlashFraction string
+}
+
+// PenaltyPolicy decides the penalty of the losers of the rounds. Every
+// validator runs it on the same evidence in the app's slashing path, so it
+// must be deterministic.
+type PenaltyPolicy interface {
+	// Penalty returns the penalty of the loser, or nil for none.
+	Penalty(evidence *PenaltyEvidence) *Penalty
+}
+
+// PenaltyPolicyFunc adapts a function to PenaltyPolicy.
+type PenaltyPolicyFunc func(evidence *PenaltyEvidence) *Penalty
+
+func (f PenaltyPolicyFunc) Penalty(evidence *PenaltyEvidence) *Penalty {
+	return f(evidence)
+}
+
+// NoPenalty never punishes the losers.
+func NoPenalty() PenaltyPolicy {
+	return PenaltyPolicyFunc(func(*PenaltyEvidence) *Penalty { return nil })
+}
+
+// JailOnly jails the losers without slashing them.
+func JailOnly() PenaltyPolicy {
+	return PenaltyPolicyFunc(func(*PenaltyEvidence) *Penalty { return &Penalty{Jail: true} })
+}
+
+// SlashFraction slashes the fraction of the losers' stake, and jails them if
+// jail is set.
+func SlashFraction(fraction string, jail bool) (PenaltyPolicy, error) {
+	if err := validateFraction(fraction); err != nil {
+		return nil, err
+	}
+	return PenaltyPolicyFunc(func(*PenaltyEvidence) *Penalty {
+		return &Penalty{Jail: jail, SlashFraction: fraction}
+	}), nil
+}
+
+// ParsePenaltyPolicy returns the policy named in a config: "none", "jail",
+// "slash:<fraction>" or "slash+jail:<fraction>", e.g. "slash:0.01".
+func ParsePenaltyPolicy(spec string) (PenaltyPolicy, error) {
+	switch {
+	case spec == "none":
+		return NoPenalty(), nil
+	case spec == "jail":
+		return JailOnly(), nil
+	case strings.HasPrefix(spec, "slash:"):
+		return SlashFraction(strings.TrimPrefix(spec, "slash:"), false)
+	case strings.HasPrefix(spec, "slash+jail:"):
+		return SlashFraction(strings.TrimPrefix(spec, "slash+jail:"), true)
+	}
+	return nil, fmt.Errorf("unknown penalty policy %q; supported: none, jail, slash:<fraction>, slash+jail:<fraction>", spec)
+}
+
+// validateFraction checks that the fraction is a decimal in [0, 1] with at
+// most 18 digits after the point, without going through floats.
+func validateFraction(fraction string) error {
+	parts := strings.SplitN(fraction, ".", 2)
+	integer, decimals := parts[0], ""
+	if len(parts) == 2 {
+		decimals = parts[1]
+		if decimals == "" || len(decimals) > 18 {
+			return fmt.Errorf("invalid slash fraction %q: 1 to 18 digits after the point", fraction)
+		}
+	}
+	for _, c := range decimals {
+		if c < '0' || c > '9' {
+			return fmt.Errorf("invalid slash fraction %q", fraction)
+		}
+	}
+	switch integer {
+	case "0":
+	case "1":
+		if strings.Trim(decimals, "0") != "" {
+			return fmt.Errorf("slash fraction %q exceeds 1", fraction)
+		}
+	default:
+		return fmt.Errorf("invalid slash fraction %q: must be within [0, 1]", fraction)
+	}
+	return nil
+}
+
+// Slasher applies penalties, e.g. with the app's slashing and staking keepers.
+type Slasher interface {
+	Jail(validator *tmtypes.Validator, evidence *PenaltyEvidence) error
+	Slash(validator *tmtypes.Validator, fraction string, evidence *PenaltyEvidence) error
+}
+
+// ApplyPenalties applies the policy's penalty of every loser of the round with
+// the slasher, e.g. to the losers of GetLosersWithReasons. The losers are
+// sorted with SortLosers first, so every validator applies the penalties in the
+// same order; a validator is slashed before it is jailed.
+func ApplyPenalties(policy PenaltyPolicy, slasher Slasher, roundID int, losers []*Loser) error {
+	for _, loser := range SortLosers(losers) {
+		evidence := &PenaltyEvidence{RoundID: roundID, Loser: loser}
+		penalty := policy.Penalty(evidence)
+		if penalty == nil {
+			continue
+		}
+		if penalty.SlashFraction != "" {
+			if err := validateFraction(penalty.SlashFraction); err != nil {
+				return err
+			}
+			if err := slasher.Slash(loser.Validator, penalty.SlashFraction, evidence); err != nil {
+				return fmt.Errorf("failed to slash %s: %v", loser.Validator.Address, err)
+			}
+		}
+		if penalty.Jail {
+			if err := slasher.Jail(loser.Validator, evidence); err != nil {
+				return fmt.Errorf("failed to jail %s: %v", loser.Validator.Address, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// PhaseProgress tells how many messages a phase of a round waits for and
+// which participants have not sent theirs yet.
+type PhaseProgress struct {
+	Phase     string           `json:"phase"`
+	Type      string           `json:"type"` // Type of the awaited messages.
+	Expected  int              `json:"expected"`
+	Received  int              `json:"received"`
+	Missing   []crypto.Address `json:"missing,omitempty"` // Participants that haven't sent all their messages.
+	Completed bool             `json:"completed"`
+}
+
+func (p PhaseProgress) String() string {
+	if p.Completed {
+		return fmt.Sprintf("%s: completed", p.Phase)
+	}
+	pending := p.Expected - p.Received
+	if pending < 0 {
+		pending = 0
+	}
+	out := fmt.Sprintf("%s: waiting on %d/%d %s messages", p.Phase, pending, p.Expected, p.Type)
+	if len(p.Missing) == 0 {
+		return out
+	}
+	missing := make([]string, len(p.Missing))
+	for i, addr := range p.Missing {
+		missing[i] = addr.String()
+	}
+	return fmt.Sprintf("%s from %s", out, strings.Join(missing, ", "))
+}
+
+// Waiting returns the first phase that has not completed, or nil if all did.
+func Waiting(progress []PhaseProgress) *PhaseProgress {
+	for i := range progress {
+		if !progress[i].Completed {
+			return &progress[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// CommitmentMatrix holds the public commitments to the polynomials the dealers
+// of a round share their secrets with, one row per dealer, e.g. to prove the
+// dealing correct outside the protocol. Off-chain rounds commit to the secret
+// polynomials in the commits phase, on-chain rounds before dealing. Points are
+// marshalled bn256 G2 points.
+type CommitmentMatrix struct {
+	RoundID int             `json:"round_id"`
+	Rows    []CommitmentRow `json:"rows"` // Ordered by index, only the dealers whose commitments were received.
+}
+
+type CommitmentRow struct {
+	Index       int            `json:"index"`
+	Addr        crypto.Address `json:"addr"`
+	Commitments [][]byte       `json:"commitments"` // Of the coefficients, the constant term first.
+}
+
+// RoundProof is an external proof about a round, e.g. a SNARK of correct
+// dealing, attached to the round's snapshot and attestation. Its content is
+// opaque to the library.
+type RoundProof struct {
+	Phase string `json:"phase"` // Phase after which the proof was generated.
+	Kind  string `json:"kind"`
+	Data  []byte `json:"data"`
+}
+
+// ProofHook generates proofs once a phase of a round completes, from the
+// commitments received so far. It is called synchronously from the dealer's
+// handlers, so slow provers delay the round.
+type ProofHook func(phase string, matrix *CommitmentMatrix) ([]*RoundProof, error)
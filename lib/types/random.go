@@ -18,6 +18,9 @@ const (
 	EventDKGReconstructCommitsProcessed = "DKGReconstructCommitsProcessed"
 	EventDKGSuccessful                  = "DKGSuccessful"
 	EventDKGKeyChange                   = "DKGKeyChange"
+	EventDKGRoundEvicted                = "DKGRoundEvicted"
+	EventDKGFailed                      = "DKGFailed"
+	EventDKGShareMigrated               = "DKGShareMigrated"
 )
 
 type Verifier interface {
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	cmn "github.com/tendermint/tendermint/libs/common"
+)
+
+// RoundCounter issues DKG round IDs for both the off-chain and the on-chain code paths,
+// so that round numbering stays consistent in hybrid operation.
+type RoundCounter struct {
+	mtx     sync.Mutex
+	current int
+	path    string // If set, the counter is persisted to this file after every change.
+}
+
+type roundCounterJSON struct {
+	RoundID int `json:"round_id"`
+}
+
+// NewRoundCounter creates a counter that is not persisted; the first round issued is current + 1.
+func NewRoundCounter(current int) *RoundCounter {
+	return &RoundCounter{current: current}
+}
+
+// LoadRoundCounter loads the counter from the file, or creates a new one if the file does not exist.
+func LoadRoundCounter(path string) (*RoundCounter, error) {
+	c := &RoundCounter{path: path}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read round counter: %v", err)
+	}
+
+	var state roundCounterJSON
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode round counter: %v", err)
+	}
+	if state.RoundID < 0 {
+		return nil, fmt.Errorf("invalid persisted round ID: %d", state.RoundID)
+	}
+	c.current = state.RoundID
+
+	return c, nil
+}
+
+// Current returns the last issued round ID.
+func (c *RoundCounter) Current() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.current
+}
+
+// Next issues a new round ID.
+func (c *RoundCounter) Next() (int, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if err := c.set(c.current + 1); err != nil {
+		return 0, err
+	}
+	return c.current, nil
+}
+
+// Advance validates an externally supplied round ID and makes it the current one.
+// Round IDs that have already been issued are rejected.
+func (c *RoundCounter) Advance(roundID int) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if roundID <= c.current {
+		return fmt.Errorf("round %d has already been used, current round is %d", roundID, c.current)
+	}
+	return c.set(roundID)
+}
+
+func (c *RoundCounter) set(roundID int) error {
+	if c.path != "" {
+		data, err := json.Marshal(roundCounterJSON{RoundID: roundID})
+		if err != nil {
+			return fmt.Errorf("failed to encode round counter: %v", err)
+		}
+		if err := cmn.WriteFileAtomic(c.path, data, 0600); err != nil {
+			return fmt.Errorf("failed to persist round counter: %v", err)
+		}
+	}
+	c.current = roundID
+	return nil
+}
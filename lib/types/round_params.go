@@ -0,0 +1,93 @@
+package types
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+// RoundProtocolVersion is the version of the DKG protocol run by this library;
+// nodes running different versions must not take part in the same round.
+const RoundProtocolVersion uint32 = 1
+
+const roundParamsHeaderSize = 4 + 4 + 8 + 8
+
+// RoundParams are the parameters of a round, signed by the round's proposer so
+// participants can check they agree on them before distributing secrets.
+type RoundParams struct {
+	ProtocolVersion  uint32
+	Threshold        int    // Number of shares needed to recover a signature.
+	ParticipantsHash []byte // ParticipantSet.Hash of the committee.
+	IndexHash        []byte // IndexAssignment.Hash of the committee; nil if not sent.
+	NumBlocks        int64  // Blocks between rounds, which bounds the round's duration.
+	BlocksAhead      int64  // Blocks between a round's success and the verifier change.
+}
+
+// Encode encodes the parameters in a fixed big-endian layout followed by the
+// participants hash and the index hash, if any.
+func (p *RoundParams) Encode() []byte {
+	buf := make([]byte, roundParamsHeaderSize, roundParamsHeaderSize+len(p.ParticipantsHash)+len(p.IndexHash))
+	binary.BigEndian.PutUint32(buf[0:], p.ProtocolVersion)
+	binary.BigEndian.PutUint32(buf[4:], uint32(p.Threshold))
+	binary.BigEndian.PutUint64(buf[8:], uint64(p.NumBlocks))
+	binary.BigEndian.PutUint64(buf[16:], uint64(p.BlocksAhead))
+	return append(append(buf, p.ParticipantsHash...), p.IndexHash...)
+}
+
+func DecodeRoundParams(data []byte) (*RoundParams, error) {
+	if len(data) < roundParamsHeaderSize {
+		return nil, fmt.Errorf("invalid round params length: %d", len(data))
+	}
+	params := &RoundParams{
+		ProtocolVersion:  binary.BigEndian.Uint32(data[0:]),
+		Threshold:        int(binary.BigEndian.Uint32(data[4:])),
+		NumBlocks:        int64(binary.BigEndian.Uint64(data[8:])),
+		BlocksAhead:      int64(binary.BigEndian.Uint64(data[16:])),
+		ParticipantsHash: data[roundParamsHeaderSize:],
+	}
+	// Proposers predating the index hash send the participants hash alone.
+	if len(params.ParticipantsHash) == 2*tmhash.Size {
+		params.ParticipantsHash, params.IndexHash = params.ParticipantsHash[:tmhash.Size], params.ParticipantsHash[tmhash.Size:]
+	}
+	return params, nil
+}
+
+// Diff describes the parameters that differ from the other ones.
+func (p *RoundParams) Diff(other *RoundParams) []string {
+	var diffs []string
+	if p.ProtocolVersion != other.ProtocolVersion {
+		diffs = append(diffs, fmt.Sprintf("protocol version %d != %d", p.ProtocolVersion, other.ProtocolVersion))
+	}
+	if p.Threshold != other.Threshold {
+		diffs = append(diffs, fmt.Sprintf("threshold %d != %d", p.Threshold, other.Threshold))
+	}
+	if !bytes.Equal(p.ParticipantsHash, other.ParticipantsHash) {
+		diffs = append(diffs, fmt.Sprintf("participants hash %X != %X", p.ParticipantsHash, other.ParticipantsHash))
+	}
+	if p.IndexHash != nil && other.IndexHash != nil && !bytes.Equal(p.IndexHash, other.IndexHash) {
+		diffs = append(diffs, fmt.Sprintf("index hash %X != %X", p.IndexHash, other.IndexHash))
+	}
+	if p.NumBlocks != other.NumBlocks {
+		diffs = append(diffs, fmt.Sprintf("round blocks %d != %d", p.NumBlocks, other.NumBlocks))
+	}
+	if p.BlocksAhead != other.BlocksAhead {
+		diffs = append(diffs, fmt.Sprintf("blocks ahead %d != %d", p.BlocksAhead, other.BlocksAhead))
+	}
+	return diffs
+}
+
+// RoundProposer returns the participant proposing the parameters of the round;
+// the role rotates over the committee with the round ID.
+func RoundProposer(participants *ParticipantSet, roundID int) *Participant {
+	list := participants.Participants()
+	if len(list) == 0 {
+		return nil
+	}
+	index := roundID % len(list)
+	if index < 0 {
+		index += len(list)
+	}
+	return list[index]
+}
@@ -0,0 +1,41 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/goldentest"
+	"github.com/tendermint/tendermint/crypto/tmhash"
+)
+
+func roundParams() *RoundParams {
+	return &RoundParams{
+		ProtocolVersion:  RoundProtocolVersion,
+		Threshold:        3,
+		ParticipantsHash: tmhash.Sum([]byte("participants")),
+		IndexHash:        tmhash.Sum([]byte("indices")),
+		NumBlocks:        100,
+		BlocksAhead:      20,
+	}
+}
+
+// TestRoundParamsGolden pins the encoding of the round params of the protocol
+// version; it must never change for a released version.
+func TestRoundParamsGolden(t *testing.T) {
+	legacy := roundParams()
+	legacy.IndexHash = nil
+
+	goldentest.Check(t, "round_params_v1", roundParams().Encode())
+	goldentest.Check(t, "round_params_v1_no_index_hash", legacy.Encode())
+}
+
+func TestRoundParamsRoundTrip(t *testing.T) {
+	params := roundParams()
+	decoded, err := DecodeRoundParams(params.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, params) {
+		t.Fatalf("decoded %+v, want %+v", decoded, params)
+	}
+}
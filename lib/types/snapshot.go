@@ -0,0 +1,91 @@
+package types
+
+import (
+	"time"
+
+	"github.com/tendermint/tendermint/crypto"
+)
+
+// PhaseInfo describes the protocol step a round is currently waiting for.
+type PhaseInfo struct {
+	Name      string `json:"name"`
+	Completed int    `json:"completed"` // Number of completed steps.
+	Total     int    `json:"total"`
+}
+
+// PeerInfo describes the messages received from a round participant.
+type PeerInfo struct {
+	Addr     crypto.Address `json:"addr"`
+	Index    int            `json:"index"`
+	External bool           `json:"external"` // Not a validator.
+	Messages map[string]int `json:"messages"` // Number of handled messages by type.
+	// Latency is the time since the round start at which the first message of
+	// each type arrived from the peer.
+	Latency  map[string]time.Duration `json:"latency"`
+	Liveness float64                  `json:"liveness"` // See ComputeLiveness.
+	Loser    bool                     `json:"loser"`
+	// Capabilities advertised in the peer's registration, if any.
+	Capabilities string `json:"capabilities,omitempty"`
+}
+
+// RoundInfo is a read-only snapshot of a DKG round.
+type RoundInfo struct {
+	RoundID       int         `json:"round_id"`
+	CorrelationID string      `json:"correlation_id"` // See RoundCorrelationID.
+	OnChain       bool        `json:"on_chain"`
+	KeyPurpose    string      `json:"key_purpose,omitempty"` // See offChain.WithKeyPurpose.
+	Result        RoundResult `json:"result"`
+	Phase         PhaseInfo   `json:"phase"`
+	Peers         []PeerInfo  `json:"peers"`
+	// Progress lists the messages awaited by every phase, see Dealer.Progress.
+	Progress []PhaseProgress `json:"progress,omitempty"`
+	// Capabilities shared by the participants, once they all registered.
+	Capabilities string `json:"capabilities,omitempty"`
+	// Proofs generated by the dealer's proof hook, see Dealer.SetProofHook.
+	Proofs []*RoundProof `json:"proofs,omitempty"`
+}
+
+// ComputeLiveness scores the peers of a round from 0 to 1 by how early their
+// messages arrived: for every message type received from any peer, the first
+// peer to send it scores 1, the last one 0 and the peers in between in
+// proportion to their latency; peers that didn't send it score 0. The liveness
+// is the average score over the message types.
+func ComputeLiveness(peers []PeerInfo) {
+	var (
+		earliest = make(map[string]time.Duration)
+		latest   = make(map[string]time.Duration)
+	)
+	for _, peer := range peers {
+		for dataType, latency := range peer.Latency {
+			if first, ok := earliest[dataType]; !ok || latency < first {
+				earliest[dataType] = latency
+			}
+			if latency > latest[dataType] {
+				latest[dataType] = latency
+			}
+		}
+	}
+
+	for i := range peers {
+		peers[i].Liveness = 0
+		if len(earliest) == 0 {
+			continue
+		}
+		var total float64
+		for dataType, first := range earliest {
+			latency, ok := peers[i].Latency[dataType]
+			switch {
+			case !ok:
+			case latest[dataType] == first:
+				total++
+			default:
+				total += 1 - float64(latency-first)/float64(latest[dataType]-first)
+			}
+		}
+		peers[i].Liveness = total / float64(len(earliest))
+	}
+}
+
+type Snapshotter interface {
+	Snapshot() []*RoundInfo
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+)
+
+const StateSnapshotVersion = 1
+
+// StateSnapshot is the public part of the DKG state a node joining via state sync
+// needs to validate threshold signatures. Private key shares are never exported,
+// so restored verifiers can verify but not sign until the node completes a round.
+type StateSnapshot struct {
+	Version      int               `json:"version"`
+	Verifier     *VerifierSnapshot `json:"verifier,omitempty"`
+	NextVerifier *VerifierSnapshot `json:"next_verifier,omitempty"`
+	ChangeHeight int64             `json:"change_height"`
+	RoundID      int               `json:"round_id"`              // Last round ID issued by the round counter.
+	ActiveRounds []int             `json:"active_rounds"`         // Rounds in progress, which the restored node can not join.
+	KeyPurpose   string            `json:"key_purpose,omitempty"` // See offChain.WithKeyPurpose.
+}
+
+type VerifierSnapshot struct {
+	RoundID      int    `json:"round_id"`
+	MasterPubKey string `json:"master_pub_key"` // Base64-encoded commits, see blsShare.DumpMasterPubKey.
+	T            int    `json:"t"`
+	N            int    `json:"n"`
+}
+
+// StateSyncer is implemented by DKG instances whose state can be exported into
+// and restored from the host application's state sync snapshots.
+type StateSyncer interface {
+	ExportState() ([]byte, error)
+	RestoreState(data []byte) error
+}
+
+// NewVerifierSnapshot exports the public key of a BLS verifier, which may be wrapped into a UsageVerifier.
+func NewVerifierSnapshot(verifier Verifier, roundID int) (*VerifierSnapshot, error) {
+	bls, ok := blsShare.UnwrapVerifier(verifier)
+	if !ok {
+		return nil, fmt.Errorf("verifier of type %T can not be exported", verifier)
+	}
+
+	masterPubKey, err := blsShare.DumpMasterPubKey(bls.MasterPubKey())
+	if err != nil {
+		return nil, err
+	}
+	t, n := bls.Threshold()
+
+	return &VerifierSnapshot{
+		RoundID:      roundID,
+		MasterPubKey: masterPubKey,
+		T:            t,
+		N:            n,
+	}, nil
+}
+
+// Verifier creates a verifier which can verify but not produce signatures.
+func (s *VerifierSnapshot) Verifier() (Verifier, error) {
+	masterPubKey, err := blsShare.LoadPubKey(s.MasterPubKey, s.T)
+	if err != nil {
+		return nil, err
+	}
+	return blsShare.NewBLSPublicVerifier(masterPubKey, s.T, s.N), nil
+}
@@ -0,0 +1,160 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultStreamHistory is the number of recent stream items an EventStream
+// replays to new subscribers.
+const DefaultStreamHistory = 64
+
+// Status is the state of the DKG subsystem served by NewStatusHandler.
+type Status struct {
+	Health HealthStatus `json:"health"`
+	Rounds []*RoundInfo `json:"rounds"`
+}
+
+// StatusSource is implemented by DKG instances that describe their rounds,
+// e.g. offChain.OffChainDKG and basic.DKGBasic.
+type StatusSource interface {
+	Healther
+	Snapshot() []*RoundInfo
+}
+
+// NewStatusHandler returns an HTTP handler that responds with the current Status.
+func NewStatusHandler(s StatusSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := Status{Health: s.Health(), Rounds: s.Snapshot()}
+		if status.Rounds == nil {
+			status.Rounds = []*RoundInfo{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(status)
+	})
+}
+
+// ErrorPublisher is implemented by EventPublishers that also receive the errors
+// reported while running the rounds.
+type ErrorPublisher interface {
+	PublishDKGError(roundID int, err error) error
+}
+
+// StreamItem is an event or error of an EventStream.
+type StreamItem struct {
+	Time    time.Time `json:"time"`
+	Event   *DKGEvent `json:"event,omitempty"`
+	RoundID int       `json:"round_id"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// EventStream serves the DKG events and round errors it is published as
+// server-sent events, each a StreamItem in JSON; pass it to
+// offChain.WithEventPublisher. New subscribers first receive the recent items.
+// Subscribers that don't keep up miss items.
+type EventStream struct {
+	mtx         sync.Mutex
+	history     []*StreamItem
+	size        int
+	subscribers map[chan *StreamItem]struct{}
+}
+
+var (
+	_ EventPublisher = &EventStream{}
+	_ ErrorPublisher = &EventStream{}
+	_ http.Handler   = &EventStream{}
+)
+
+// NewEventStream creates a stream replaying the last history items to new
+// subscribers.
+func NewEventStream(history int) *EventStream {
+	return &EventStream{
+		size:        history,
+		subscribers: make(map[chan *StreamItem]struct{}),
+	}
+}
+
+func (s *EventStream) PublishDKGEvent(event DKGEvent) error {
+	s.publish(&StreamItem{Time: time.Now(), Event: &event, RoundID: event.RoundID})
+	return nil
+}
+
+func (s *EventStream) PublishDKGError(roundID int, err error) error {
+	s.publish(&StreamItem{Time: time.Now(), RoundID: roundID, Error: err.Error()})
+	return nil
+}
+
+func (s *EventStream) publish(item *StreamItem) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.size > 0 {
+		if len(s.history) >= s.size {
+			s.history = s.history[1:]
+		}
+		s.history = append(s.history, item)
+	}
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- item:
+		default:
+		}
+	}
+}
+
+func (s *EventStream) subscribe() (chan *StreamItem, []*StreamItem) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	subscriber := make(chan *StreamItem, DefaultStreamHistory)
+	s.subscribers[subscriber] = struct{}{}
+	return subscriber, append([]*StreamItem(nil), s.history...)
+}
+
+func (s *EventStream) unsubscribe(subscriber chan *StreamItem) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.subscribers, subscriber)
+}
+
+// ServeHTTP streams the items to the client until it disconnects.
+func (s *EventStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	subscriber, history := s.subscribe()
+	defer s.unsubscribe(subscriber)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, item := range history {
+		if err := writeStreamItem(w, item); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+	for {
+		select {
+		case item := <-subscriber:
+			if err := writeStreamItem(w, item); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamItem(w http.ResponseWriter, item *StreamItem) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "data: %s\n\n", data)
+	return err
+}
@@ -0,0 +1,21 @@
+package types
+
+// TriggerParams are the parameters of a round started on demand, e.g. by a
+// governance decision, instead of by the height schedule.
+type TriggerParams struct {
+	// Participants is the committee of the round; the validators and the
+	// external participants if empty.
+	Participants []*Participant
+	// Threshold is the number of shares needed to recover a signature with
+	// the round's verifier; the default for the committee's size if zero.
+	Threshold int
+	// Height is the block the round was decided at, which every participant
+	// shares; the blacklist votes are tallied at the block before it. The
+	// node's last height if zero.
+	Height int64
+}
+
+// RoundTrigger is implemented by DKG instances whose rounds can be started on demand.
+type RoundTrigger interface {
+	TriggerRound(params TriggerParams) (roundID int, err error)
+}
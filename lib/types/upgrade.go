@@ -0,0 +1,236 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+	"go.dedis.ch/kyber/v3/pairing/bn256"
+)
+
+// UpgradeStateVersion is the version of the UpgradeState documents written by
+// this version of the library. Version 1 is the StateSnapshot of ExportState,
+// and version 2 holds the secrets in the clear.
+const UpgradeStateVersion = 3
+
+// UpgradeState is the DKG state a node carries across a binary upgrade: the
+// verifiers with the node's key shares, the verifier awaiting its change height
+// or approval, and the rounds in progress, which are resumed rather than run
+// again. The key shares and the round seeds are sealed into Secrets, see Seal;
+// they are only in the clear in memory and in states of version 2.
+type UpgradeState struct {
+	State             *StateSnapshot            `json:"state"`
+	Height            int64                     `json:"height"`              // Last block height seen by the node.
+	VerifierShare     *blsShare.BLSShareJSON    `json:"verifier_share"`      // Nil if the node holds no share of the verifier.
+	NextVerifierShare *blsShare.BLSShareJSON    `json:"next_verifier_share"` // Nil if the node holds no share of the next verifier.
+	Staged            *StagedVerifier           `json:"staged"`
+	Migrations        []*ShareMigration         `json:"migrations"`
+	Rounds            []*RoundState             `json:"rounds"`
+	InactiveRounds    []int                     `json:"inactive_rounds"` // Finished rounds, whose late messages are dropped.
+	Secrets           *blsShare.EncryptedSecret `json:"secrets"`         // Sealed UpgradeSecrets, nil in states of version 2.
+}
+
+// UpgradeSecrets are the secrets of an UpgradeState.
+type UpgradeSecrets struct {
+	VerifierShare     *blsShare.BLSShareJSON `json:"verifier_share"`
+	NextVerifierShare *blsShare.BLSShareJSON `json:"next_verifier_share"`
+	Seeds             map[int][]byte         `json:"seeds"` // Seeds of the rounds by ID.
+}
+
+// Seal encrypts the key shares and the round seeds of the state into Secrets
+// with a key derived from the passphrase and clears them.
+func (s *UpgradeState) Seal(passphrase []byte, options ...blsShare.KeystoreOption) error {
+	secrets := &UpgradeSecrets{
+		VerifierShare:     s.VerifierShare,
+		NextVerifierShare: s.NextVerifierShare,
+		Seeds:             make(map[int][]byte),
+	}
+	for _, round := range s.Rounds {
+		secrets.Seeds[round.RoundID] = round.Seed
+	}
+	data, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("failed to encode upgrade secrets: %v", err)
+	}
+	if s.Secrets, err = blsShare.EncryptSecret(data, passphrase, options...); err != nil {
+		return err
+	}
+
+	s.VerifierShare, s.NextVerifierShare = nil, nil
+	for _, round := range s.Rounds {
+		round.Seed = nil
+	}
+	return nil
+}
+
+// Unseal decrypts the Secrets of the state with the passphrase and sets the key
+// shares and the round seeds. States without Secrets keep theirs.
+func (s *UpgradeState) Unseal(passphrase []byte) error {
+	if s.Secrets == nil {
+		return nil
+	}
+	data, err := s.Secrets.Decrypt(passphrase)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt upgrade secrets: %v", err)
+	}
+	var secrets UpgradeSecrets
+	if err := json.Unmarshal(data, &secrets); err != nil {
+		return fmt.Errorf("failed to decode upgrade secrets: %v", err)
+	}
+
+	s.VerifierShare, s.NextVerifierShare = secrets.VerifierShare, secrets.NextVerifierShare
+	for _, round := range s.Rounds {
+		round.Seed = secrets.Seeds[round.RoundID]
+	}
+	s.Secrets = nil
+	return nil
+}
+
+// RoundState is a round in progress: the dealer's committee and seed, see
+// dealer.SetSeed, and the messages it was fed, in order. A dealer created with
+// the same committee and seed reaches the same state when fed the messages.
+type RoundState struct {
+	RoundID      int              `json:"round_id"`
+	Participants []*Participant   `json:"participants"`
+	Threshold    int              `json:"threshold"` // Zero for the default threshold.
+	Seed         []byte           `json:"seed"`
+	Messages     []*alias.DKGData `json:"messages"`
+}
+
+// UpgradeMigration converts an encoded UpgradeState of a version to the next one.
+type UpgradeMigration func(data []byte) ([]byte, error)
+
+// upgradeMigrations are the migrations by the version they convert from.
+var upgradeMigrations = map[int]UpgradeMigration{
+	1: migrateStateSnapshot,
+	2: migrateClearSecrets,
+}
+
+// upgradeEnvelope is the versioned encoding of an UpgradeState, whose body is
+// amino JSON to carry the participants' keys.
+type upgradeEnvelope struct {
+	Version int             `json:"version"`
+	State   json.RawMessage `json:"state"`
+}
+
+func EncodeUpgradeState(s *UpgradeState) ([]byte, error) {
+	body, err := alias.Cdc.MarshalJSON(s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode upgrade state: %v", err)
+	}
+	return json.Marshal(upgradeEnvelope{Version: UpgradeStateVersion, State: body})
+}
+
+// DecodeUpgradeState decodes an UpgradeState written by this or an older
+// version of the library, migrating it to the current version.
+func DecodeUpgradeState(data []byte) (*UpgradeState, error) {
+	version, err := upgradeVersion(data)
+	if err != nil {
+		return nil, err
+	}
+	for version < UpgradeStateVersion {
+		migrate, ok := upgradeMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration of upgrade state version %d", version)
+		}
+		if data, err = migrate(data); err != nil {
+			return nil, fmt.Errorf("failed to migrate upgrade state version %d: %v", version, err)
+		}
+		next, err := upgradeVersion(data)
+		if err != nil {
+			return nil, err
+		}
+		if next <= version {
+			return nil, fmt.Errorf("migration of upgrade state version %d produced version %d", version, next)
+		}
+		version = next
+	}
+	if version > UpgradeStateVersion {
+		return nil, fmt.Errorf("upgrade state version %d is newer than the supported version %d", version, UpgradeStateVersion)
+	}
+
+	var envelope upgradeEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode upgrade state: %v", err)
+	}
+	var s UpgradeState
+	if err := alias.Cdc.UnmarshalJSON(envelope.State, &s); err != nil {
+		return nil, fmt.Errorf("failed to decode upgrade state: %v", err)
+	}
+	return &s, nil
+}
+
+func upgradeVersion(data []byte) (int, error) {
+	var envelope struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return 0, fmt.Errorf("failed to decode upgrade state version: %v", err)
+	}
+	if envelope.Version <= 0 {
+		return 0, fmt.Errorf("upgrade state has no version")
+	}
+	return envelope.Version, nil
+}
+
+// migrateStateSnapshot converts the public StateSnapshot of a node running a
+// version without UpgradeState, which has no key shares or rounds to resume.
+func migrateStateSnapshot(data []byte) ([]byte, error) {
+	var snapshot StateSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	body, err := alias.Cdc.MarshalJSON(&UpgradeState{State: &snapshot})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(upgradeEnvelope{Version: 2, State: body})
+}
+
+// Upgrader is implemented by DKG instances whose full state, key shares and
+// rounds in progress included, can be carried across a binary upgrade.
+type Upgrader interface {
+	ExportUpgradeState(req *OperationRequest, signatures []OperatorSignature, passphrase []byte, options ...blsShare.KeystoreOption) ([]byte, error)
+	RestoreUpgradeState(data, passphrase []byte) error
+}
+
+// NewShareSnapshot exports the key share of a BLS verifier, which may be wrapped
+// into a UsageVerifier; it returns nil if the verifier holds no share.
+func NewShareSnapshot(verifier Verifier) (*blsShare.BLSShareJSON, error) {
+	bls, ok := blsShare.UnwrapVerifier(verifier)
+	if !ok || bls.Keypair == nil {
+		return nil, nil
+	}
+	return blsShare.NewBLSShareJSON(bls.Keypair)
+}
+
+// SignerVerifier creates a verifier which produces signatures with the key
+// share, which must be a share of the snapshot's master public key.
+func (s *VerifierSnapshot) SignerVerifier(sh *blsShare.BLSShareJSON) (Verifier, error) {
+	masterPubKey, err := blsShare.LoadPubKey(s.MasterPubKey, s.T)
+	if err != nil {
+		return nil, err
+	}
+	keypair, err := sh.Deserialize()
+	if err != nil {
+		return nil, err
+	}
+	keypair.ID = keypair.Priv.I
+	pub := bn256.NewSuiteG2().Point().Mul(keypair.Priv.V, nil)
+	if !masterPubKey.Eval(keypair.ID).V.Equal(pub) {
+		return nil, fmt.Errorf("share %d doesn't match the master public key of round %d", keypair.ID, s.RoundID)
+	}
+	return blsShare.NewBLSVerifier(masterPubKey, keypair, s.T, s.N), nil
+}
+
+// migrateClearSecrets converts a state of version 2, whose key shares and seeds
+// are in the clear; they are kept so, since there's no passphrase to seal them.
+func migrateClearSecrets(data []byte) ([]byte, error) {
+	var envelope upgradeEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	envelope.Version = 3
+	return json.Marshal(envelope)
+}
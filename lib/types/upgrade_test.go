@@ -0,0 +1,68 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/blsShare"
+)
+
+func testUpgradeState() *UpgradeState {
+	return &UpgradeState{
+		Height:        7,
+		VerifierShare: &blsShare.BLSShareJSON{Pub: "pub", Priv: "secret-share"},
+		Rounds:        []*RoundState{{RoundID: 2, Seed: []byte("secret-seed")}},
+	}
+}
+
+func TestUpgradeStateSeal(t *testing.T) {
+	state := testUpgradeState()
+	if err := state.Seal([]byte("passphrase"), blsShare.WithScrypt(1024, 8, 1)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeUpgradeState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret-share")) || bytes.Contains(data, []byte(base64.StdEncoding.EncodeToString([]byte("secret-seed")))) {
+		t.Fatal("sealed upgrade state holds secrets in the clear")
+	}
+
+	decoded, err := DecodeUpgradeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := decoded.Unseal([]byte("wrong")); err == nil {
+		t.Fatal("unsealed with a wrong passphrase")
+	}
+	if err := decoded.Unseal([]byte("passphrase")); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.VerifierShare == nil || decoded.VerifierShare.Priv != "secret-share" || string(decoded.Rounds[0].Seed) != "secret-seed" {
+		t.Fatalf("secrets not restored: %+v", decoded)
+	}
+}
+
+func TestUpgradeStateOfVersion2KeepsClearSecrets(t *testing.T) {
+	body, err := alias.Cdc.MarshalJSON(testUpgradeState())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(upgradeEnvelope{Version: 2, State: body})
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := DecodeUpgradeState(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Unseal(nil); err != nil {
+		t.Fatal(err)
+	}
+	if state.VerifierShare == nil || state.VerifierShare.Priv != "secret-share" || string(state.Rounds[0].Seed) != "secret-seed" {
+		t.Fatalf("secrets of version 2 lost: %+v", state)
+	}
+}
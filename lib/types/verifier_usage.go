@@ -0,0 +1,133 @@
+package types
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/go-kit/kit/metrics"
+)
+
+var (
+	ErrSigningRateLimited = errors.New("signing rate limit exceeded")
+)
+
+// DefaultSigningRateWindow is the window of a signing rate limit set without
+// a positive one, see WithSigningRateLimit.
+const DefaultSigningRateWindow = time.Minute
+
+// UsageVerifier counts the signature shares produced by the wrapped verifier
+// and optionally throttles signing.
+type UsageVerifier struct {
+	Verifier
+
+	epoch  int
+	logger logging.Logger
+
+	mtx       sync.Mutex
+	signCount uint64
+	recent    []time.Time // Signing times within the current window.
+
+	maxPerWindow   int
+	window         time.Duration
+	alertThreshold int
+	counter        metrics.Counter
+}
+
+// UsageOption sets an optional parameter on the UsageVerifier.
+type UsageOption func(*UsageVerifier)
+
+// WithSigningRateLimit rejects signing requests once maxPerWindow shares were produced within the window;
+// a limit set with a window that isn't positive uses DefaultSigningRateWindow.
+func WithSigningRateLimit(maxPerWindow int, window time.Duration) UsageOption {
+	return func(v *UsageVerifier) {
+		if maxPerWindow > 0 && window <= 0 {
+			window = DefaultSigningRateWindow
+		}
+		v.maxPerWindow, v.window = maxPerWindow, window
+	}
+}
+
+// WithSigningAlertThreshold logs an error every time the number of shares produced
+// within the window (or within the epoch if no window is set) exceeds the threshold.
+func WithSigningAlertThreshold(threshold int) UsageOption {
+	return func(v *UsageVerifier) { v.alertThreshold = threshold }
+}
+
+// WithSignCounter reports every produced share to the counter labeled by epoch.
+func WithSignCounter(counter metrics.Counter) UsageOption {
+	return func(v *UsageVerifier) { v.counter = counter }
+}
+
+func NewUsageVerifier(verifier Verifier, epoch int, logger logging.Logger, options ...UsageOption) *UsageVerifier {
+	v := &UsageVerifier{
+		Verifier: verifier,
+		epoch:    epoch,
+		logger:   logger,
+	}
+
+	for _, option := range options {
+		option(v)
+	}
+
+	return v
+}
+
+func (v *UsageVerifier) Sign(data []byte) ([]byte, error) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	now := time.Now()
+	if v.window > 0 {
+		var i int
+		for i < len(v.recent) && now.Sub(v.recent[i]) > v.window {
+			i++
+		}
+		v.recent = v.recent[i:]
+	}
+	if v.maxPerWindow > 0 && len(v.recent) >= v.maxPerWindow {
+		v.logger.Error("Verifier signing rate limit exceeded", "epoch", v.epoch, "window", v.window, "max", v.maxPerWindow)
+		return nil, ErrSigningRateLimited
+	}
+
+	sig, err := v.Verifier.Sign(data)
+	if err != nil {
+		return nil, err
+	}
+
+	v.signCount++
+	if v.window > 0 {
+		v.recent = append(v.recent, now)
+	}
+	if v.counter != nil {
+		v.counter.With("epoch", strconv.Itoa(v.epoch)).Add(1)
+	}
+
+	volume := int(v.signCount)
+	if v.window > 0 {
+		volume = len(v.recent)
+	}
+	if v.alertThreshold > 0 && volume > v.alertThreshold {
+		v.logger.Error("Anomalous verifier signing volume", "epoch", v.epoch, "volume", volume, "threshold", v.alertThreshold)
+	}
+
+	return sig, nil
+}
+
+// SignCount returns the number of shares produced in this epoch.
+func (v *UsageVerifier) SignCount() uint64 {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	return v.signCount
+}
+
+func (v *UsageVerifier) Epoch() int { return v.epoch }
+
+// UnwrapVerifier returns the wrapped verifier, see blsShare.UnwrapVerifier.
+func (v *UsageVerifier) UnwrapVerifier() interface{} { return v.Verifier }
+
+func (v *UsageVerifier) IsNil() bool {
+	return v == nil || v.Verifier == nil || v.Verifier.IsNil()
+}
@@ -0,0 +1,36 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/logging"
+)
+
+type signingVerifier struct{ Verifier }
+
+func (signingVerifier) Sign(data []byte) ([]byte, error) { return data, nil }
+
+func TestSigningRateLimitWithoutWindow(t *testing.T) {
+	v := NewUsageVerifier(signingVerifier{}, 1, logging.NewNopLogger(), WithSigningRateLimit(2, 0))
+	for i := 0; i < 2; i++ {
+		if _, err := v.Sign([]byte("data")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := v.Sign([]byte("data")); err != ErrSigningRateLimited {
+		t.Fatalf("third share within the default window: want %v, got %v", ErrSigningRateLimited, err)
+	}
+}
+
+func TestUnwrapUsageVerifier(t *testing.T) {
+	bls := blsShare.NewBLSVerifier(nil, nil, 1, 1)
+	inner := NewUsageVerifier(bls, 1, logging.NewNopLogger())
+	outer := NewUsageVerifier(inner, 2, logging.NewNopLogger())
+	if got, ok := blsShare.UnwrapVerifier(outer); !ok || got != bls {
+		t.Fatalf("want the wrapped BLS verifier, got %v, %v", got, ok)
+	}
+	if _, ok := blsShare.UnwrapVerifier(NewUsageVerifier(signingVerifier{}, 1, logging.NewNopLogger())); ok {
+		t.Fatal("unwrapped a verifier without a BLS verifier")
+	}
+}
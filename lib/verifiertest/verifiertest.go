@@ -0,0 +1,192 @@
+// Package verifiertest checks that a types.Verifier implementation behaves like
+// the BLS verifier dkglib consumers are built against, so it can replace it.
+package verifiertest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/blsShare"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+const (
+	threshold = 3
+	shares    = 4
+)
+
+// NewVerifiersFunc creates the verifiers holding the n shares of a fresh t-of-n
+// key, ordered by share index.
+type NewVerifiersFunc func(t, n int) ([]types.Verifier, error)
+
+// NewBLSVerifiers creates the verifiers of dkglib's BLS implementation.
+func NewBLSVerifiers(t, n int) ([]types.Verifier, error) {
+	keyring, err := blsShare.NewBLSKeyring(t, n)
+	if err != nil {
+		return nil, err
+	}
+	verifiers := make([]types.Verifier, n)
+	for i := range verifiers {
+		verifiers[i] = blsShare.NewBLSVerifier(keyring.MasterPubKey, keyring.Shares[i], t, n)
+	}
+	return verifiers, nil
+}
+
+// precommit is a vote carrying a signature share; votes with an empty hash are nil votes.
+type precommit struct {
+	hash []byte
+	sig  []byte
+}
+
+func (p *precommit) GetBLSSignature() []byte {
+	if p == nil {
+		return nil
+	}
+	return p.sig
+}
+
+func (p *precommit) GetHash() []byte {
+	if p == nil {
+		return nil
+	}
+	return p.hash
+}
+
+func votes(sigs ...[]byte) []blsShare.BLSSigner {
+	out := make([]blsShare.BLSSigner, len(sigs))
+	for i, sig := range sigs {
+		out[i] = &precommit{hash: []byte("block"), sig: sig}
+	}
+	return out
+}
+
+// tamper returns copies of the share with a bit flipped at the start, middle and
+// end; the start covers the share index prefix of threshold BLS encodings.
+func tamper(sig []byte) [][]byte {
+	var out [][]byte
+	for _, pos := range []int{0, 1, len(sig) / 2, len(sig) - 1} {
+		if pos < 0 || pos >= len(sig) {
+			continue
+		}
+		tampered := append([]byte(nil), sig...)
+		tampered[pos] ^= 1
+		out = append(out, tampered)
+	}
+	return out
+}
+
+// RunConformance runs the conformance suite against verifiers created by newVerifiers.
+func RunConformance(t *testing.T, newVerifiers NewVerifiersFunc) {
+	verifiers, err := newVerifiers(threshold, shares)
+	if err != nil {
+		t.Fatalf("failed to create verifiers: %v", err)
+	}
+	if len(verifiers) != shares {
+		t.Fatalf("expected %d verifiers, got %d", shares, len(verifiers))
+	}
+
+	msg := []byte("previous random data")
+	sigs := make([][]byte, shares)
+	for i, verifier := range verifiers {
+		if verifier.IsNil() {
+			t.Fatalf("verifier %d is nil", i)
+		}
+		if sigs[i], err = verifier.Sign(msg); err != nil {
+			t.Fatalf("verifier %d failed to sign: %v", i, err)
+		}
+	}
+
+	t.Run("VerifyShare", func(t *testing.T) {
+		for i, sig := range sigs {
+			for j, verifier := range verifiers {
+				if err := verifier.VerifyRandomShare("", msg, sig); err != nil {
+					t.Errorf("verifier %d rejected share %d: %v", j, i, err)
+				}
+			}
+		}
+	})
+
+	t.Run("ShareOfAnotherMessage", func(t *testing.T) {
+		if err := verifiers[1].VerifyRandomShare("", []byte("other data"), sigs[0]); err == nil {
+			t.Error("share verified for another message")
+		}
+	})
+
+	t.Run("EmptyMessage", func(t *testing.T) {
+		sig, err := verifiers[0].Sign(nil)
+		if err != nil {
+			t.Fatalf("failed to sign empty message: %v", err)
+		}
+		if err := verifiers[1].VerifyRandomShare("", []byte{}, sig); err != nil {
+			t.Errorf("share of empty message rejected: %v", err)
+		}
+		if err := verifiers[1].VerifyRandomShare("", msg, sig); err == nil {
+			t.Error("share of empty message verified for another message")
+		}
+	})
+
+	t.Run("RecoverThreshold", func(t *testing.T) {
+		first, err := verifiers[0].Recover(msg, votes(sigs[:threshold]...))
+		if err != nil {
+			t.Fatalf("failed to recover from %d shares: %v", threshold, err)
+		}
+		// Random data must not depend on which shares were collected, or nodes would disagree on it.
+		second, err := verifiers[shares-1].Recover(msg, votes(sigs[shares-threshold:]...))
+		if err != nil {
+			t.Fatalf("failed to recover from %d shares: %v", threshold, err)
+		}
+		if !bytes.Equal(first, second) {
+			t.Error("different sets of shares recovered different data")
+		}
+		for i, verifier := range verifiers {
+			if err := verifier.VerifyRandomData(msg, first); err != nil {
+				t.Errorf("verifier %d rejected recovered data: %v", i, err)
+			}
+		}
+		if err := verifiers[0].VerifyRandomData([]byte("other data"), first); err == nil {
+			t.Error("recovered data verified for another message")
+		}
+	})
+
+	t.Run("RecoverBelowThreshold", func(t *testing.T) {
+		if _, err := verifiers[0].Recover(msg, votes(sigs[:threshold-1]...)); err == nil {
+			t.Errorf("recovered from %d shares", threshold-1)
+		}
+	})
+
+	t.Run("RecoverDuplicateShares", func(t *testing.T) {
+		dup := append(append([][]byte(nil), sigs[:threshold-1]...), sigs[0])
+		if _, err := verifiers[0].Recover(msg, votes(dup...)); err == nil {
+			t.Errorf("recovered from %d distinct shares", threshold-1)
+		}
+	})
+
+	t.Run("WrongIndexOrCorruptShare", func(t *testing.T) {
+		for _, sig := range tamper(sigs[threshold-1]) {
+			if err := verifiers[0].VerifyRandomShare("", msg, sig); err == nil {
+				t.Errorf("tampered share %x verified", sig)
+			}
+			if _, err := verifiers[0].Recover(msg, votes(append(append([][]byte(nil), sigs[:threshold-1]...), sig)...)); err == nil {
+				t.Errorf("recovered with tampered share %x", sig)
+			}
+		}
+	})
+
+	t.Run("ShareIsNotRandomData", func(t *testing.T) {
+		if err := verifiers[0].VerifyRandomData(msg, sigs[0]); err == nil {
+			t.Error("share verified as random data")
+		}
+	})
+
+	t.Run("RecoverSkipsNilVotes", func(t *testing.T) {
+		precommits := append([]blsShare.BLSSigner{nil, (*precommit)(nil), &precommit{sig: sigs[threshold-1]}}, votes(sigs[:threshold]...)...)
+		if _, err := verifiers[0].Recover(msg, precommits); err != nil {
+			t.Errorf("failed to recover with nil votes: %v", err)
+		}
+		// A share of a nil vote must not count towards the threshold.
+		precommits = append([]blsShare.BLSSigner{&precommit{sig: sigs[threshold-1]}}, votes(sigs[:threshold-1]...)...)
+		if _, err := verifiers[0].Recover(msg, precommits); err == nil {
+			t.Error("recovered with a share of a nil vote")
+		}
+	})
+}
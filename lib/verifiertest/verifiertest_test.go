@@ -0,0 +1,29 @@
+package verifiertest
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/roundtest"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+func TestBLSConformance(t *testing.T) {
+	RunConformance(t, NewBLSVerifiers)
+}
+
+// TestRoundConformance runs the suite against the verifiers of a complete
+// in-memory DKG round.
+func TestRoundConformance(t *testing.T) {
+	RunConformance(t, func(threshold, n int) ([]types.Verifier, error) {
+		if threshold != dealer.Threshold(n) {
+			return nil, fmt.Errorf("rounds of %d have threshold %d, not %d", n, dealer.Threshold(n), threshold)
+		}
+		stats, err := roundtest.RunRound(n)
+		if err != nil {
+			return nil, err
+		}
+		return stats.Verifiers, nil
+	})
+}
@@ -0,0 +1,42 @@
+package wal
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CodecZstd is the ID of the zstd codec, see Zstd.
+const CodecZstd byte = 1
+
+// Codec compresses the WAL frames. Its ID is recorded in every frame, so a
+// reader decodes frames written with any registered codec.
+type Codec interface {
+	ID() byte // Zero is reserved for uncompressed frames.
+	Encode(data []byte) ([]byte, error)
+	Decode(data []byte) ([]byte, error)
+}
+
+var (
+	codecsMtx sync.RWMutex
+	codecs    = make(map[byte]Codec)
+)
+
+// RegisterCodec lets readers decode the frames written with the codec.
+func RegisterCodec(codec Codec) {
+	codecsMtx.Lock()
+	defer codecsMtx.Unlock()
+	codecs[codec.ID()] = codec
+}
+
+func codecByID(id byte) (Codec, error) {
+	codecsMtx.RLock()
+	defer codecsMtx.RUnlock()
+	codec, ok := codecs[id]
+	if !ok {
+		if id == CodecZstd {
+			return nil, fmt.Errorf("WAL frame is compressed with zstd, which requires building with the zstd tag")
+		}
+		return nil, fmt.Errorf("WAL frame is compressed with unknown codec %d", id)
+	}
+	return codec, nil
+}
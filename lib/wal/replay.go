@@ -0,0 +1,94 @@
+package wal
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/libs/events"
+)
+
+type replayConfig struct {
+	signDomain alias.SignDomain
+	seedKey    []byte
+}
+
+// ReplayOption sets an optional parameter of Replay.
+type ReplayOption func(*replayConfig)
+
+// WithReplaySignDomain verifies the messages in the domain the node ran with,
+// by default the empty chain ID with the latest version.
+func WithReplaySignDomain(domain alias.SignDomain) ReplayOption {
+	return func(c *replayConfig) { c.signDomain = domain }
+}
+
+// WithReplaySeedKey decrypts the dealer's seed recorded with the key, see
+// WithSeedKey.
+func WithReplaySeedKey(key []byte) ReplayOption {
+	return func(c *replayConfig) { c.seedKey = key }
+}
+
+// Replay feeds the incoming messages of a round recorded in the WAL into a fresh
+// dealer created with the committee of the round, see
+// dealer.NewDealerFromTranscript; a negative roundID replays the first
+// recorded round. Messages sent by the fresh dealer are dropped.
+//
+// With the seed recorded by a WAL with a seed key and WithReplaySeedKey, the
+// fresh dealer draws the ephemeral key and secret of the recorded run, so the
+// replay reaches the same state. Otherwise the dealer picks a new ephemeral
+// key and the outcome of handling deals that were encrypted to the original
+// key differs from the recorded run.
+func Replay(r *Reader, roundID int, pv tmtypes.PrivValidator, newDealer dealer.DKGDealerConstructor, logger logging.Logger, options ...ReplayOption) (*types.RoundInfo, error) {
+	config := &replayConfig{signDomain: alias.DefaultSignDomain("")}
+	for _, option := range options {
+		option(config)
+	}
+
+	var transcript *types.RoundState
+	for {
+		entry, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case entry.Kind == EntryRoundStart && transcript == nil && (roundID < 0 || entry.RoundID == roundID):
+			roundID = entry.RoundID
+			transcript = &types.RoundState{RoundID: roundID, Participants: entry.Participants}
+			if len(entry.Seed) > 0 && config.seedKey != nil {
+				if transcript.Seed, err = openSeed(config.seedKey, roundID, entry.Seed); err != nil {
+					return nil, fmt.Errorf("failed to decrypt seed of round %d: %v", roundID, err)
+				}
+			}
+		case entry.Kind == EntryIncoming && transcript != nil && entry.RoundID == roundID:
+			transcript.Messages = append(transcript.Messages, entry.Data)
+		}
+	}
+	if transcript == nil {
+		return nil, fmt.Errorf("round %d not found in WAL", roundID)
+	}
+
+	d, err := dealer.NewDealerFromTranscript(transcript, pv,
+		dealer.WithTranscriptLogger(logger),
+		dealer.WithTranscriptDealer(func(participants *types.ParticipantSet, roundID int) dealer.Dealer {
+			sendMsgCb := func([]*alias.DKGData) error { return nil }
+			d := newDealer(participants, pv, sendMsgCb, nopFirer{}, logger, roundID)
+			d.SetSignDomain(config.signDomain)
+			return d
+		}))
+	if err != nil {
+		return nil, err
+	}
+	return d.Snapshot(), nil
+}
+
+type nopFirer struct{}
+
+func (nopFirer) FireEvent(event string, data events.EventData) {}
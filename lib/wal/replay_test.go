@@ -0,0 +1,138 @@
+package wal
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/dealer"
+	"github.com/corestario/dkglib/lib/logging"
+	"github.com/corestario/dkglib/lib/types"
+	tmtypes "github.com/tendermint/tendermint/alias"
+	"github.com/tendermint/tendermint/crypto/ed25519"
+)
+
+// recordRound runs a round of seeded dealers, recording the round start and
+// the messages received by the first one to the WAL, and returns its key and
+// final state.
+func recordRound(t *testing.T, w *WAL, n int) (tmtypes.PrivValidator, *types.RoundInfo) {
+	var (
+		pvs        = make([]tmtypes.PrivValidator, n)
+		validators = make([]*tmtypes.Validator, n)
+	)
+	for i := range pvs {
+		pvs[i] = tmtypes.NewMockPVWithParams(ed25519.GenPrivKey(), false, false)
+		pubKey := pvs[i].GetPubKey()
+		validators[i] = &tmtypes.Validator{Address: pubKey.Address(), PubKey: pubKey, VotingPower: 1}
+	}
+	participants := types.NewParticipantSet(tmtypes.NewValidatorSet(validators), nil)
+
+	var (
+		queue   []*alias.DKGData
+		dealers = make([]dealer.Dealer, n)
+	)
+	for i, pv := range pvs {
+		pv := pv
+		sendMsgCb := func(data []*alias.DKGData) error {
+			for _, item := range data {
+				if err := pv.SignData("", item); err != nil {
+					return err
+				}
+				queue = append(queue, item)
+			}
+			return nil
+		}
+		dealers[i] = dealer.NewDKGDealer(participants, pv, sendMsgCb, nopFirer{}, logging.NewNopLogger(), 0)
+		seed := make([]byte, 32)
+		if _, err := rand.Read(seed); err != nil {
+			t.Fatal(err)
+		}
+		dealers[i].SetSeed(seed)
+		if i == 0 {
+			if err := w.WriteRoundStart(0, participants, seed); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for _, d := range dealers {
+		if err := d.Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for len(queue) > 0 {
+		msg := queue[0]
+		queue = queue[1:]
+		if err := w.WriteMessage(EntryIncoming, msg); err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range dealers {
+			if err := d.VerifyMessage(types.DKGDataMessage{Data: msg}); err != nil {
+				t.Fatal(err)
+			}
+			if handler := dealer.Handler(d, msg.Type); handler != nil {
+				if err := handler(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+	if _, err := dealers[0].GetVerifier(); err != nil {
+		t.Fatalf("recorded round failed: %v", err)
+	}
+	return pvs[0], dealers[0].Snapshot()
+}
+
+func TestReplayWithSeedReachesRecordedState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dkgwal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, SeedKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "wal")
+	w, err := Open(path, WithSeedKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv, recorded := recordRound(t, w, 4)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replay := func(options ...ReplayOption) (*types.RoundInfo, error) {
+		r, err := OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		return Replay(r, -1, pv, dealer.NewDKGDealer, logging.NewNopLogger(), options...)
+	}
+
+	info, err := replay(WithReplaySeedKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Phase != recorded.Phase {
+		t.Fatalf("replay with the seed ended in phase %+v, the recorded round in %+v", info.Phase, recorded.Phase)
+	}
+	for _, peer := range info.Peers {
+		if peer.Loser {
+			t.Fatalf("replay with the seed found loser %s", peer.Addr)
+		}
+	}
+	if info, err := replay(); err != nil || info.Phase == recorded.Phase {
+		t.Fatalf("replay without the seed ended with %v, %v", info, err)
+	}
+
+	wrongKey := make([]byte, SeedKeySize)
+	if _, err := replay(WithReplaySeedKey(wrongKey)); err == nil {
+		t.Fatal("replay with a wrong seed key didn't fail")
+	}
+}
@@ -0,0 +1,73 @@
+package wal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+)
+
+// SeedKeySize is the size of the keys the seeds are encrypted with, see
+// WithSeedKey.
+const SeedKeySize = 32
+
+// WithSeedKey records the seeds of the round's dealers, see dealer.SetSeed,
+// encrypted with the AES-256 key, so a replay with the key reaches the state of
+// the recorded run, see WithReplaySeedKey. The key must be kept as secret as
+// the node's shares: the seed derives the dealer's ephemeral key and secret.
+func WithSeedKey(key []byte) Option {
+	return func(w *WAL) { w.seedKey = key }
+}
+
+// RecordsSeeds reports whether the round starts record the dealers' seeds.
+func (w *WAL) RecordsSeeds() bool {
+	return w.seedKey != nil
+}
+
+// sealSeed encrypts the seed of the round; the nonce precedes the ciphertext.
+func sealSeed(key []byte, roundID int, seed []byte) ([]byte, error) {
+	gcm, err := newSeedCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(seed)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, seed, seedAD(roundID)), nil
+}
+
+// openSeed decrypts the seed of the round sealed by sealSeed.
+func openSeed(key []byte, roundID int, sealed []byte) ([]byte, error) {
+	gcm, err := newSeedCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("sealed seed of %d bytes is too short", len(sealed))
+	}
+	seed, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], seedAD(roundID))
+	if err != nil {
+		return nil, fmt.Errorf("wrong seed key or corrupted seed")
+	}
+	return seed, nil
+}
+
+func newSeedCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != SeedKeySize {
+		return nil, fmt.Errorf("seed key must have %d bytes, got %d", SeedKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seedAD binds a sealed seed to its round, so it can't be moved to another.
+func seedAD(roundID int) []byte {
+	ad := make([]byte, 8)
+	binary.BigEndian.PutUint64(ad, uint64(roundID))
+	return ad
+}
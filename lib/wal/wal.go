@@ -0,0 +1,651 @@
+package wal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/corestario/dkglib/lib/alias"
+	"github.com/corestario/dkglib/lib/types"
+)
+
+const (
+	// maxFrameSize protects the reader from corrupted length prefixes.
+	maxFrameSize = 64 << 20
+
+	// segmentHeader starts the segments of checksummed frames. Legacy
+	// segments, whose frames are bare amino entries, start with a non-zero
+	// frame length instead.
+	segmentHeader = "\x00DKGWAL\x02"
+	// frameHeaderSize is the size of the codec ID and the CRC-32C that precede
+	// the payload of a frame.
+	frameHeaderSize = 5
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+type EntryKind int
+
+const (
+	EntryIncoming EntryKind = iota
+	EntryOutgoing
+	// EntryRoundStart records the committee a dealer was created with.
+	EntryRoundStart
+)
+
+func (k EntryKind) String() string {
+	switch k {
+	case EntryIncoming:
+		return "in"
+	case EntryOutgoing:
+		return "out"
+	case EntryRoundStart:
+		return "round_start"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(k))
+	}
+}
+
+type Entry struct {
+	Time         time.Time
+	Kind         EntryKind
+	RoundID      int
+	Data         *alias.DKGData       // Not set for EntryRoundStart.
+	Participants []*types.Participant // Only set for EntryRoundStart.
+	Seed         []byte               // Sealed seed of the dealer, see WithSeedKey; only set for EntryRoundStart.
+}
+
+// CorruptionError is returned by a reader for a frame that is torn, fails its
+// checksum or doesn't decode.
+type CorruptionError struct {
+	Segment string
+	Offset  int64 // Of the frame in the segment.
+	Err     error
+}
+
+func (e *CorruptionError) Error() string {
+	return fmt.Sprintf("WAL segment %s is corrupted at offset %d: %v", e.Segment, e.Offset, e.Err)
+}
+
+// WAL is a log of entries split into segments: the head at path, which is
+// appended to, and the older ones at path.000001, path.000002 and so on. Every
+// segment is a header followed by frames of a length, the ID of the codec the
+// entry is compressed with, a CRC-32C and the amino-encoded entry.
+type WAL struct {
+	mtx  sync.Mutex
+	file *os.File
+	path string
+
+	codec        Codec
+	seedKey      []byte
+	segmentSize  int64
+	maxSize      int64
+	retainRounds int
+
+	headSize     int64
+	headMaxRound int
+	maxRounds    map[string]int // Highest round ID by older segment, -1 if it has no entries.
+	recovered    *CorruptionError
+}
+
+// Option sets an optional parameter of the WAL.
+type Option func(*WAL)
+
+// WithCompression compresses the entries written with the codec, e.g. Zstd.
+func WithCompression(codec Codec) Option {
+	return func(w *WAL) { w.codec = codec }
+}
+
+// WithSegmentSize starts a new head segment once the head would exceed the
+// size in bytes; zero never rotates.
+func WithSegmentSize(size int64) Option {
+	return func(w *WAL) { w.segmentSize = size }
+}
+
+// WithMaxSize removes the oldest segments, whatever rounds they hold, once all
+// segments exceed the size in bytes; the head is never removed. Zero keeps
+// every segment.
+func WithMaxSize(size int64) Option {
+	return func(w *WAL) { w.maxSize = size }
+}
+
+// WithRetainRounds removes the segments holding only rounds older than the
+// last n completed ones, see RoundCompleted. Zero keeps every segment.
+func WithRetainRounds(n int) Option {
+	return func(w *WAL) { w.retainRounds = n }
+}
+
+// Open opens the WAL at path for appending, creating it if needed. A torn or
+// corrupted tail of the head, e.g. left by a crash, is cut off, see Recovered;
+// a head written by a version without segments is kept as the newest older
+// segment.
+func Open(path string, options ...Option) (*WAL, error) {
+	w := &WAL{path: path, headMaxRound: -1, maxRounds: make(map[string]int)}
+	for _, option := range options {
+		option(w)
+	}
+	if w.codec != nil {
+		RegisterCodec(w.codec)
+	}
+	if w.seedKey != nil && len(w.seedKey) != SeedKeySize {
+		return nil, fmt.Errorf("seed key must have %d bytes, got %d", SeedKeySize, len(w.seedKey))
+	}
+
+	segments, err := olderSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, segment := range segments {
+		if w.maxRounds[segment], _, err = scanSegment(segment); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+	if err := w.openHead(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// recover cuts off the corrupted tail of the head, or sets a legacy head aside.
+func (w *WAL) recover() error {
+	info, err := os.Stat(w.path)
+	if os.IsNotExist(err) || (err == nil && info.Size() == 0) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %v", err)
+	}
+	framed, err := isFramed(w.path)
+	if err != nil {
+		return err
+	}
+	maxRound, corruption, err := scanSegment(w.path)
+	if err != nil {
+		return err
+	}
+	w.headMaxRound = maxRound
+	if !framed {
+		return w.seal()
+	}
+	if corruption != nil {
+		if err := os.Truncate(w.path, corruption.Offset); err != nil {
+			return fmt.Errorf("failed to cut off corrupted WAL tail: %v", err)
+		}
+		w.recovered = corruption
+	}
+	return nil
+}
+
+// Recovered returns the corruption at which the head was cut off when the WAL
+// was opened, or nil if it was intact.
+func (w *WAL) Recovered() *CorruptionError {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.recovered
+}
+
+func (w *WAL) openHead() error {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open WAL: %v", err)
+	}
+	w.file, w.headSize = file, info.Size()
+	if w.headSize == 0 {
+		if _, err := file.WriteString(segmentHeader); err != nil {
+			return fmt.Errorf("failed to write WAL header: %v", err)
+		}
+		w.headSize = int64(len(segmentHeader))
+	}
+	return nil
+}
+
+func (w *WAL) Write(entry *Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+	payload, err := alias.Cdc.MarshalBinaryBare(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL entry: %v", err)
+	}
+	var codecID byte
+	if w.codec != nil {
+		if payload, err = w.codec.Encode(payload); err != nil {
+			return fmt.Errorf("failed to compress WAL entry: %v", err)
+		}
+		codecID = w.codec.ID()
+	}
+	frame := encodeFrame(codecID, payload)
+
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.segmentSize > 0 && w.headSize > int64(len(segmentHeader)) && w.headSize+int64(len(frame)) > w.segmentSize {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.file.Write(frame); err != nil {
+		return fmt.Errorf("failed to write WAL entry: %v", err)
+	}
+	w.headSize += int64(len(frame))
+	if entry.RoundID > w.headMaxRound {
+		w.headMaxRound = entry.RoundID
+	}
+	return nil
+}
+
+func encodeFrame(codecID byte, payload []byte) []byte {
+	var (
+		frame = make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+frameHeaderSize+len(payload))
+		n     = binary.PutUvarint(frame, uint64(frameHeaderSize+len(payload)))
+	)
+	frame = append(frame[:n], codecID, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(frame[n+1:], frameChecksum(codecID, payload))
+	return append(frame, payload...)
+}
+
+func frameChecksum(codecID byte, payload []byte) uint32 {
+	return crc32.Update(crc32.Checksum([]byte{codecID}, crcTable), crcTable, payload)
+}
+
+// WriteMessage records a message received from or sent to the peers.
+func (w *WAL) WriteMessage(kind EntryKind, data *alias.DKGData) error {
+	return w.Write(&Entry{Kind: kind, RoundID: data.RoundID, Data: data})
+}
+
+// WriteRoundStart records the committee of a new round and, if the WAL has a
+// seed key, the seed of its dealer.
+func (w *WAL) WriteRoundStart(roundID int, participants *types.ParticipantSet, seed []byte) error {
+	entry := &Entry{Kind: EntryRoundStart, RoundID: roundID, Participants: participants.Participants()}
+	if w.seedKey != nil && len(seed) > 0 {
+		sealed, err := sealSeed(w.seedKey, roundID, seed)
+		if err != nil {
+			return fmt.Errorf("failed to seal seed: %v", err)
+		}
+		entry.Seed = sealed
+	}
+	return w.Write(entry)
+}
+
+// rotate seals the head as the newest older segment and starts a new head.
+func (w *WAL) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return fmt.Errorf("failed to close WAL segment: %v", err)
+	}
+	if err := w.seal(); err != nil {
+		return err
+	}
+	if err := w.openHead(); err != nil {
+		return err
+	}
+	return w.pruneSize()
+}
+
+func (w *WAL) seal() error {
+	segments, err := olderSegments(w.path)
+	if err != nil {
+		return err
+	}
+	seq := 1
+	if len(segments) > 0 {
+		seq = segmentSeq(w.path, segments[len(segments)-1]) + 1
+	}
+	sealed := fmt.Sprintf("%s.%06d", w.path, seq)
+	if err := os.Rename(w.path, sealed); err != nil {
+		return fmt.Errorf("failed to rotate WAL: %v", err)
+	}
+	w.maxRounds[sealed], w.headMaxRound = w.headMaxRound, -1
+	return nil
+}
+
+// pruneSize removes the oldest segments while the WAL exceeds its maximum size.
+func (w *WAL) pruneSize() error {
+	if w.maxSize <= 0 {
+		return nil
+	}
+	segments, err := olderSegments(w.path)
+	if err != nil {
+		return err
+	}
+	var (
+		sizes = make([]int64, len(segments))
+		total = w.headSize
+	)
+	for i, segment := range segments {
+		info, err := os.Stat(segment)
+		if err != nil {
+			return fmt.Errorf("failed to stat WAL segment: %v", err)
+		}
+		sizes[i] = info.Size()
+		total += sizes[i]
+	}
+	for i := 0; i < len(segments) && total > w.maxSize; i++ {
+		if err := w.remove(segments[i]); err != nil {
+			return err
+		}
+		total -= sizes[i]
+	}
+	return nil
+}
+
+// RoundCompleted removes the older segments holding only rounds older than the
+// last retained ones, see WithRetainRounds.
+func (w *WAL) RoundCompleted(roundID int) error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.retainRounds <= 0 {
+		return nil
+	}
+	segments, err := olderSegments(w.path)
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if w.maxRounds[segment] > roundID-w.retainRounds {
+			// Later segments hold later entries.
+			break
+		}
+		if err := w.remove(segment); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WAL) remove(segment string) error {
+	if err := os.Remove(segment); err != nil {
+		return fmt.Errorf("failed to remove WAL segment: %v", err)
+	}
+	delete(w.maxRounds, segment)
+	return nil
+}
+
+// Excerpt returns the last max entries of the round; zero means all of them.
+// The entries of corrupted segments are skipped and reported by the error,
+// along with the entries of the intact ones.
+func (w *WAL) Excerpt(roundID int, max int) ([]*Entry, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+
+	reader, err := OpenReader(w.path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var out []*Entry
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, err
+		}
+		if entry.RoundID != roundID {
+			continue
+		}
+		out = append(out, entry)
+		if max > 0 && len(out) > max {
+			out = out[1:]
+		}
+	}
+	if corruptions := reader.Corruptions(); len(corruptions) > 0 {
+		return out, corruptions[0]
+	}
+	return out, nil
+}
+
+func (w *WAL) Close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	return w.file.Close()
+}
+
+// olderSegments returns the paths of the segments older than the head at path,
+// oldest first.
+func olderSegments(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WAL segments: %v", err)
+	}
+	var segments []string
+	for _, match := range matches {
+		if segmentSeq(path, match) > 0 {
+			segments = append(segments, match)
+		}
+	}
+	sort.Slice(segments, func(i, j int) bool { return segmentSeq(path, segments[i]) < segmentSeq(path, segments[j]) })
+	return segments, nil
+}
+
+// segmentSeq returns the sequence number of the older segment, or zero if it
+// isn't one.
+func segmentSeq(path, segment string) int {
+	suffix := strings.TrimPrefix(segment, path+".")
+	if len(suffix) < 6 || suffix == segment {
+		return 0
+	}
+	seq, err := strconv.Atoi(suffix)
+	if err != nil || seq <= 0 {
+		return 0
+	}
+	return seq
+}
+
+func isFramed(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open WAL segment: %v", err)
+	}
+	defer file.Close()
+	header := make([]byte, len(segmentHeader))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false, nil
+	}
+	return string(header) == segmentHeader, nil
+}
+
+// scanSegment returns the highest round ID of the segment's entries and the
+// corruption the intact part of the segment ends at, if any.
+func scanSegment(path string) (maxRound int, corruption *CorruptionError, err error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to open WAL segment: %v", err)
+	}
+	defer file.Close()
+
+	maxRound = -1
+	reader := NewReader(file)
+	reader.name = path
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return maxRound, nil, nil
+		}
+		if corruption, ok := err.(*CorruptionError); ok {
+			return maxRound, corruption, nil
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		if entry.RoundID > maxRound {
+			maxRound = entry.RoundID
+		}
+	}
+}
+
+// Reader decodes the entries of a WAL in the order they were written.
+type Reader struct {
+	r       *bufio.Reader
+	name    string
+	offset  int64
+	started bool
+	framed  bool
+
+	file        *os.File
+	segments    []string // Segments left to read, see OpenReader.
+	corruptions []*CorruptionError
+}
+
+// NewReader reads the entries of a single segment, failing at its first
+// corrupted frame.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r), name: "stream"}
+}
+
+// OpenReader reads the entries of all segments of the WAL at path, oldest
+// first. It skips the rest of a segment from a corrupted frame on, see
+// Corruptions, and must be closed.
+func OpenReader(path string) (*Reader, error) {
+	segments, err := olderSegments(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		segments = append(segments, path)
+	}
+	reader := &Reader{segments: segments}
+	if !reader.advance() {
+		reader.r = bufio.NewReader(bytes.NewReader(nil))
+	}
+	return reader, nil
+}
+
+// Corruptions returns the corrupted frames whose segments' remainders were
+// skipped.
+func (r *Reader) Corruptions() []*CorruptionError {
+	return r.corruptions
+}
+
+func (r *Reader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	return r.file.Close()
+}
+
+// advance opens the next segment, recording the ones that can't be opened.
+func (r *Reader) advance() bool {
+	for len(r.segments) > 0 {
+		r.Close()
+		path := r.segments[0]
+		r.segments = r.segments[1:]
+		file, err := os.Open(path)
+		if err != nil {
+			r.corruptions = append(r.corruptions, &CorruptionError{Segment: path, Err: err})
+			continue
+		}
+		r.file, r.r, r.name, r.offset, r.started = file, bufio.NewReader(file), path, 0, false
+		return true
+	}
+	r.Close()
+	r.file = nil
+	return false
+}
+
+// Next returns the next entry or io.EOF at the end of the log.
+func (r *Reader) Next() (*Entry, error) {
+	for {
+		entry, err := r.next()
+		if corruption, ok := err.(*CorruptionError); ok && r.file != nil {
+			r.corruptions = append(r.corruptions, corruption)
+			err = io.EOF
+		}
+		if err == io.EOF && r.file != nil && r.advance() {
+			continue
+		}
+		return entry, err
+	}
+}
+
+func (r *Reader) next() (*Entry, error) {
+	if !r.started {
+		r.started = true
+		if header, _ := r.r.Peek(len(segmentHeader)); string(header) == segmentHeader {
+			r.r.Discard(len(segmentHeader))
+			r.offset, r.framed = int64(len(segmentHeader)), true
+		}
+	}
+
+	start := r.offset
+	size, err := binary.ReadUvarint(byteCounter{r})
+	if err == io.EOF && r.offset == start {
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, r.corrupted(start, fmt.Errorf("failed to read WAL frame length: %v", err))
+	}
+	if size > maxFrameSize {
+		return nil, r.corrupted(start, fmt.Errorf("WAL frame is too large: %d bytes", size))
+	}
+	frame := make([]byte, size)
+	n, err := io.ReadFull(r.r, frame)
+	r.offset += int64(n)
+	if err != nil {
+		return nil, r.corrupted(start, fmt.Errorf("WAL frame is torn: %d of %d bytes", n, size))
+	}
+
+	if !r.framed {
+		return r.decode(start, frame)
+	}
+	if size < frameHeaderSize {
+		return nil, r.corrupted(start, fmt.Errorf("WAL frame is too short: %d bytes", size))
+	}
+	codecID, payload := frame[0], frame[frameHeaderSize:]
+	if frameChecksum(codecID, payload) != binary.BigEndian.Uint32(frame[1:frameHeaderSize]) {
+		return nil, r.corrupted(start, fmt.Errorf("WAL frame checksum doesn't match"))
+	}
+	if codecID != 0 {
+		codec, err := codecByID(codecID)
+		if err != nil {
+			return nil, err
+		}
+		if payload, err = codec.Decode(payload); err != nil {
+			return nil, r.corrupted(start, fmt.Errorf("failed to decompress WAL frame: %v", err))
+		}
+	}
+	return r.decode(start, payload)
+}
+
+func (r *Reader) decode(start int64, payload []byte) (*Entry, error) {
+	entry := &Entry{}
+	if err := alias.Cdc.UnmarshalBinaryBare(payload, entry); err != nil {
+		return nil, r.corrupted(start, fmt.Errorf("failed to decode WAL entry: %v", err))
+	}
+	return entry, nil
+}
+
+func (r *Reader) corrupted(offset int64, err error) *CorruptionError {
+	return &CorruptionError{Segment: r.name, Offset: offset, Err: err}
+}
+
+// byteCounter counts the bytes of the frame lengths read.
+type byteCounter struct {
+	r *Reader
+}
+
+func (c byteCounter) ReadByte() (byte, error) {
+	b, err := c.r.r.ReadByte()
+	if err == nil {
+		c.r.offset++
+	}
+	return b, err
+}
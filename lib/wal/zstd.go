@@ -0,0 +1,42 @@
+//go:build zstd
+// +build zstd
+
+package wal
+
+import (
+	"github.com/klauspost/compress/zstd"
+)
+
+func init() {
+	RegisterCodec(newZstdCodec())
+}
+
+type zstdCodec struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// Zstd returns the zstd codec, see WithCompression. It requires building with
+// the zstd tag and github.com/klauspost/compress in the application's module.
+func Zstd() Codec {
+	codecsMtx.RLock()
+	defer codecsMtx.RUnlock()
+	return codecs[CodecZstd]
+}
+
+func newZstdCodec() *zstdCodec {
+	// Neither fails without options that could be invalid.
+	encoder, _ := zstd.NewWriter(nil)
+	decoder, _ := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(maxFrameSize))
+	return &zstdCodec{encoder: encoder, decoder: decoder}
+}
+
+func (c *zstdCodec) ID() byte { return CodecZstd }
+
+func (c *zstdCodec) Encode(data []byte) ([]byte, error) {
+	return c.encoder.EncodeAll(data, nil), nil
+}
+
+func (c *zstdCodec) Decode(data []byte) ([]byte, error) {
+	return c.decoder.DecodeAll(data, nil)
+}